@@ -1454,6 +1454,7 @@ func getEventWhitelist() []string {
 		"nwc_channel_ready",
 		"nwc_channel_closed",
 		"nwc_permission_denied",
+		"nwc_payment_approval_required",
 		"nwc_started",
 		"nwc_stopped",
 		"nwc_node_started",
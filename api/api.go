@@ -28,7 +28,10 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/apitokens"
 	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/backup"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
@@ -40,6 +43,9 @@ import (
 	"github.com/getAlby/hub/service"
 	"github.com/getAlby/hub/service/keys"
 	"github.com/getAlby/hub/swaps"
+	"github.com/getAlby/hub/totp"
+	"github.com/getAlby/hub/transactions"
+	"github.com/getAlby/hub/users"
 	"github.com/getAlby/hub/utils"
 	"github.com/getAlby/hub/version"
 )
@@ -47,6 +53,10 @@ import (
 type api struct {
 	db               *gorm.DB
 	appsSvc          apps.AppsService
+	apiTokensSvc     apitokens.ApiTokensService
+	usersSvc         users.UsersService
+	auditSvc         audit.AuditService
+	totpSvc          totp.TOTPService
 	cfg              config.Config
 	svc              service.Service
 	permissionsSvc   permissions.PermissionsService
@@ -65,6 +75,10 @@ func NewAPI(svc service.Service, gormDB *gorm.DB, config config.Config, keys key
 	return &api{
 		db:             gormDB,
 		appsSvc:        apps.NewAppsService(gormDB, eventPublisher, keys, config),
+		apiTokensSvc:   apitokens.NewApiTokensService(gormDB),
+		usersSvc:       users.NewUsersService(gormDB),
+		auditSvc:       audit.NewAuditService(gormDB, config.GetEnv().Workdir),
+		totpSvc:        totp.NewTOTPService(config),
 		cfg:            config,
 		svc:            svc,
 		permissionsSvc: permissions.NewPermissionsService(gormDB, eventPublisher),
@@ -75,13 +89,23 @@ func NewAPI(svc service.Service, gormDB *gorm.DB, config config.Config, keys key
 	}
 }
 
-func (api *api) CreateApp(createAppRequest *CreateAppRequest) (*CreateAppResponse, error) {
+func (api *api) CreateApp(ctx context.Context, createAppRequest *CreateAppRequest) (_ *CreateAppResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "create_app", createAppRequest, err)
+	}()
+
 	if slices.Contains(createAppRequest.Scopes, constants.SUPERUSER_SCOPE) {
 		if !api.cfg.CheckUnlockPassword(createAppRequest.UnlockPassword) {
 			return nil, fmt.Errorf(
 				"incorrect unlock password to create app with superuser permission")
 		}
 	}
+	if slices.Contains(createAppRequest.Scopes, constants.NODE_ADMIN_SCOPE) {
+		if !api.cfg.CheckUnlockPassword(createAppRequest.UnlockPassword) {
+			return nil, fmt.Errorf(
+				"incorrect unlock password to create app with node_admin permission")
+		}
+	}
 
 	maxAmountSat := uint64(0)
 	resolvedMaxAmountSat := ResolveToSat(createAppRequest.MaxAmountSat, createAppRequest.MaxAmountMsat, createAppRequest.MaxAmount, nil)
@@ -168,10 +192,14 @@ func buildReturnToUrl(returnTo string, relayUrls []string, walletPubkey string,
 	return returnToUrl.String()
 }
 
-func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) error {
+func (api *api) UpdateApp(ctx context.Context, userApp *db.App, updateAppRequest *UpdateAppRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "update_app", map[string]interface{}{"appId": userApp.ID, "request": updateAppRequest}, err)
+	}()
+
 	resolvedMaxAmountSat := ResolveToSat(updateAppRequest.MaxAmountSat, updateAppRequest.MaxAmountMsat, updateAppRequest.MaxAmount, nil)
 
-	err := api.db.Transaction(func(tx *gorm.DB) error {
+	err = api.db.Transaction(func(tx *gorm.DB) error {
 		// Initialize name with current app name, update if provided
 		name := userApp.Name
 
@@ -232,13 +260,23 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 			}
 		}
 
+		if updateAppRequest.SpendingPolicy != nil {
+			err := updateAppSpendingPolicy(tx, userApp.ID, updateAppRequest.SpendingPolicy)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Handle permissions updates only if any permission-related field is provided
 		if updateAppRequest.Scopes != nil || resolvedMaxAmountSat != nil ||
-			updateAppRequest.BudgetRenewal != nil || updateAppRequest.ExpiresAt != nil || updateAppRequest.UpdateExpiresAt {
+			updateAppRequest.BudgetRenewal != nil || updateAppRequest.BudgetRenewalPeriodHours != nil ||
+			updateAppRequest.BudgetTimezone != nil || updateAppRequest.ExpiresAt != nil || updateAppRequest.UpdateExpiresAt {
 
 			// Get current values or use provided ones
 			var maxAmountSat uint64
 			var budgetRenewal string
+			var budgetRenewalPeriodHours uint
+			var budgetTimezone string
 			var expiresAt *time.Time
 
 			// Get existing permissions to use as defaults
@@ -249,11 +287,13 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 
 			// Use existing values as defaults
 			if len(existingPermissions) > 0 {
-				// Find pay_invoice permission for budget-related fields
+				// Find a payment permission for budget-related fields
 				for _, perm := range existingPermissions {
-					if perm.Scope == constants.PAY_INVOICE_SCOPE {
+					if perm.Scope == constants.PAY_INVOICE_SCOPE || perm.Scope == constants.PAY_ONCHAIN_SCOPE {
 						maxAmountSat = uint64(perm.MaxAmountSat)
 						budgetRenewal = perm.BudgetRenewal
+						budgetRenewalPeriodHours = perm.BudgetRenewalPeriodHours
+						budgetTimezone = perm.BudgetTimezone
 						expiresAt = perm.ExpiresAt
 						break
 					}
@@ -266,6 +306,18 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 			}
 			if updateAppRequest.BudgetRenewal != nil {
 				budgetRenewal = *updateAppRequest.BudgetRenewal
+				if !slices.Contains(constants.GetBudgetRenewals(), budgetRenewal) {
+					return fmt.Errorf("invalid budget renewal. Must be one of %s", strings.Join(constants.GetBudgetRenewals(), ","))
+				}
+			}
+			if updateAppRequest.BudgetRenewalPeriodHours != nil {
+				budgetRenewalPeriodHours = *updateAppRequest.BudgetRenewalPeriodHours
+			}
+			if updateAppRequest.BudgetTimezone != nil {
+				budgetTimezone = *updateAppRequest.BudgetTimezone
+			}
+			if err := queries.ValidateBudgetWindow(budgetRenewal, budgetRenewalPeriodHours, budgetTimezone); err != nil {
+				return err
 			}
 			if updateAppRequest.ExpiresAt != nil {
 				parsedExpiresAt, err := api.parseExpiresAt(*updateAppRequest.ExpiresAt)
@@ -280,9 +332,11 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 
 			// Update existing permissions with new budget and expiry
 			err := tx.Model(&db.AppPermission{}).Where("app_id", userApp.ID).Updates(map[string]interface{}{
-				"ExpiresAt":     expiresAt,
-				"MaxAmountSat":  maxAmountSat,
-				"BudgetRenewal": budgetRenewal,
+				"ExpiresAt":                expiresAt,
+				"MaxAmountSat":             maxAmountSat,
+				"BudgetRenewal":            budgetRenewal,
+				"BudgetRenewalPeriodHours": budgetRenewalPeriodHours,
+				"BudgetTimezone":           budgetTimezone,
 			}).Error
 			if err != nil {
 				return err
@@ -304,15 +358,21 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 					return fmt.Errorf("cannot update app to add superuser permission")
 				}
 
+				if slices.Contains(updateAppRequest.Scopes, constants.NODE_ADMIN_SCOPE) && !existingScopeMap[constants.NODE_ADMIN_SCOPE] {
+					return fmt.Errorf("cannot update app to add node_admin permission")
+				}
+
 				// Add new permissions
 				for _, scope := range updateAppRequest.Scopes {
 					if !existingScopeMap[scope] {
 						perm := db.AppPermission{
-							App:           *userApp,
-							Scope:         scope,
-							ExpiresAt:     expiresAt,
-							MaxAmountSat:  int(maxAmountSat),
-							BudgetRenewal: budgetRenewal,
+							App:                      *userApp,
+							Scope:                    scope,
+							ExpiresAt:                expiresAt,
+							MaxAmountSat:             int(maxAmountSat),
+							BudgetRenewal:            budgetRenewal,
+							BudgetRenewalPeriodHours: budgetRenewalPeriodHours,
+							BudgetTimezone:           budgetTimezone,
 						}
 						if err := tx.Create(&perm).Error; err != nil {
 							return err
@@ -346,7 +406,15 @@ func (api *api) UpdateApp(userApp *db.App, updateAppRequest *UpdateAppRequest) e
 	return err
 }
 
-func (api *api) DeleteApp(userApp *db.App) error {
+func (api *api) DeleteApp(ctx context.Context, userApp *db.App, totpCode string) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "delete_app", map[string]interface{}{"appId": userApp.ID, "name": userApp.Name}, err)
+	}()
+
+	if err := api.totpSvc.VerifyStepUp(totpCode); err != nil {
+		return err
+	}
+
 	// Delete lightning address if one exists
 	if api.appsSvc.HasLightningAddress(userApp) {
 		err := api.DeleteLightningAddress(context.Background(), userApp.ID)
@@ -360,14 +428,18 @@ func (api *api) DeleteApp(userApp *db.App) error {
 	return api.appsSvc.DeleteApp(userApp)
 }
 
-func (api *api) CreateLightningAddress(ctx context.Context, createLightningAddressRequest *CreateLightningAddressRequest) error {
+func (api *api) CreateLightningAddress(ctx context.Context, createLightningAddressRequest *CreateLightningAddressRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "create_lightning_address", createLightningAddressRequest, err)
+	}()
+
 	app := api.appsSvc.GetAppById(createLightningAddressRequest.AppId)
 	if app == nil {
 		return errors.New("app not found")
 	}
 
 	var metadata map[string]interface{}
-	err := json.Unmarshal(app.Metadata, &metadata)
+	err = json.Unmarshal(app.Metadata, &metadata)
 	if err != nil {
 		logger.Logger.WithError(err).WithFields(logrus.Fields{
 			"app_id": app.ID,
@@ -391,14 +463,18 @@ func (api *api) CreateLightningAddress(ctx context.Context, createLightningAddre
 	return nil
 }
 
-func (api *api) DeleteLightningAddress(ctx context.Context, appId uint) error {
+func (api *api) DeleteLightningAddress(ctx context.Context, appId uint) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "delete_lightning_address", map[string]interface{}{"appId": appId}, err)
+	}()
+
 	app := api.appsSvc.GetAppById(appId)
 	if app == nil {
 		return errors.New("app not found")
 	}
 
 	var metadata map[string]interface{}
-	err := json.Unmarshal(app.Metadata, &metadata)
+	err = json.Unmarshal(app.Metadata, &metadata)
 	if err != nil {
 		logger.Logger.WithError(err).WithFields(logrus.Fields{
 			"app_id": app.ID,
@@ -433,6 +509,61 @@ func (api *api) DeleteLightningAddress(ctx context.Context, appId uint) error {
 	return nil
 }
 
+func updateAppSpendingPolicy(tx *gorm.DB, appId uint, spendingPolicy *AppSpendingPolicy) error {
+	if spendingPolicy.MaxAmountPerPaymentSat == 0 && spendingPolicy.MaxPaymentsPerMinute == 0 &&
+		spendingPolicy.MaxPaymentsPerHour == 0 && len(spendingPolicy.AllowedDestinations) == 0 &&
+		len(spendingPolicy.BlockedDestinations) == 0 && !spendingPolicy.RequireDescription &&
+		!spendingPolicy.ApprovalRequired && spendingPolicy.ApprovalThresholdSat == 0 {
+		return tx.Where("app_id = ?", appId).Delete(&db.AppSpendingPolicy{}).Error
+	}
+
+	normalizeDestinations := func(destinations []string) (datatypes.JSON, error) {
+		normalized := []string{}
+		for _, destination := range destinations {
+			destination, err := transactions.ValidateSpendingPolicyDestination(destination)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(normalized, destination) {
+				normalized = append(normalized, destination)
+			}
+		}
+		destinationsBytes, err := json.Marshal(normalized)
+		if err != nil {
+			return nil, err
+		}
+		return datatypes.JSON(destinationsBytes), nil
+	}
+
+	allowedDestinations, err := normalizeDestinations(spendingPolicy.AllowedDestinations)
+	if err != nil {
+		return err
+	}
+	blockedDestinations, err := normalizeDestinations(spendingPolicy.BlockedDestinations)
+	if err != nil {
+		return err
+	}
+
+	dbSpendingPolicy := db.AppSpendingPolicy{}
+	err = tx.Limit(1).Find(&dbSpendingPolicy, &db.AppSpendingPolicy{
+		AppId: appId,
+	}).Error
+	if err != nil {
+		return err
+	}
+	dbSpendingPolicy.AppId = appId
+	dbSpendingPolicy.MaxAmountPerPaymentSat = spendingPolicy.MaxAmountPerPaymentSat
+	dbSpendingPolicy.MaxPaymentsPerMinute = spendingPolicy.MaxPaymentsPerMinute
+	dbSpendingPolicy.MaxPaymentsPerHour = spendingPolicy.MaxPaymentsPerHour
+	dbSpendingPolicy.AllowedDestinations = allowedDestinations
+	dbSpendingPolicy.BlockedDestinations = blockedDestinations
+	dbSpendingPolicy.RequireDescription = spendingPolicy.RequireDescription
+	dbSpendingPolicy.ApprovalRequired = spendingPolicy.ApprovalRequired
+	dbSpendingPolicy.ApprovalThresholdSat = spendingPolicy.ApprovalThresholdSat
+
+	return tx.Omit("App").Save(&dbSpendingPolicy).Error
+}
+
 func (api *api) GetApp(dbApp *db.App) (*App, error) {
 
 	paySpecificPermission := db.AppPermission{}
@@ -448,8 +579,8 @@ func (api *api) GetApp(dbApp *db.App) (*App, error) {
 	requestMethods := []string{}
 	for _, appPerm := range appPermissions {
 		expiresAt = appPerm.ExpiresAt
-		if appPerm.Scope == constants.PAY_INVOICE_SCOPE {
-			// find the pay_invoice-specific permissions
+		if appPerm.Scope == constants.PAY_INVOICE_SCOPE || appPerm.Scope == constants.PAY_ONCHAIN_SCOPE {
+			// find the payment-specific permissions (the budget is the same on both)
 			paySpecificPermission = appPerm
 		}
 		requestMethods = append(requestMethods, appPerm.Scope)
@@ -498,12 +629,48 @@ func (api *api) GetApp(dbApp *db.App) (*App, error) {
 		BudgetUsageSat:           budgetUsageMsat / 1000,
 		BudgetUsageMsat:          budgetUsageMsat,
 		BudgetRenewal:            paySpecificPermission.BudgetRenewal,
+		BudgetRenewalPeriodHours: paySpecificPermission.BudgetRenewalPeriodHours,
+		BudgetTimezone:           paySpecificPermission.BudgetTimezone,
 		Isolated:                 dbApp.Isolated,
 		Metadata:                 metadata,
 		WalletPubkey:             walletPubkey,
 		UniqueWalletPubkey:       uniqueWalletPubkey,
 		LastUsedAt:               dbApp.LastUsedAt,
 		LastSettledTransactionAt: dbApp.LastSettledTransactionAt,
+		RequestCount:             dbApp.RequestCount,
+		RateLimitedCount:         dbApp.RateLimitedCount,
+		LastRateLimitedAt:        dbApp.LastRateLimitedAt,
+	}
+
+	var dbSpendingPolicy db.AppSpendingPolicy
+	result := api.db.Limit(1).Find(&dbSpendingPolicy, &db.AppSpendingPolicy{
+		AppId: dbApp.ID,
+	})
+	if result.Error != nil {
+		logger.Logger.WithError(result.Error).WithFields(logrus.Fields{
+			"app_id": dbApp.ID,
+		}).Error("Failed to get spending policy for app")
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		allowedDestinations, err := transactions.ParseSpendingPolicyDestinations(dbSpendingPolicy.AllowedDestinations)
+		if err != nil {
+			return nil, err
+		}
+		blockedDestinations, err := transactions.ParseSpendingPolicyDestinations(dbSpendingPolicy.BlockedDestinations)
+		if err != nil {
+			return nil, err
+		}
+		response.SpendingPolicy = &AppSpendingPolicy{
+			MaxAmountPerPaymentSat: dbSpendingPolicy.MaxAmountPerPaymentSat,
+			MaxPaymentsPerMinute:   dbSpendingPolicy.MaxPaymentsPerMinute,
+			MaxPaymentsPerHour:     dbSpendingPolicy.MaxPaymentsPerHour,
+			AllowedDestinations:    allowedDestinations,
+			BlockedDestinations:    blockedDestinations,
+			RequireDescription:     dbSpendingPolicy.RequireDescription,
+			ApprovalRequired:       dbSpendingPolicy.ApprovalRequired,
+			ApprovalThresholdSat:   dbSpendingPolicy.ApprovalThresholdSat,
+		}
 	}
 
 	if dbApp.Isolated {
@@ -630,6 +797,9 @@ func (api *api) ListApps(limit uint64, offset uint64, filters ListAppsFilters, o
 			UniqueWalletPubkey:       uniqueWalletPubkey,
 			LastUsedAt:               dbApp.LastUsedAt,
 			LastSettledTransactionAt: dbApp.LastSettledTransactionAt,
+			RequestCount:             dbApp.RequestCount,
+			RateLimitedCount:         dbApp.RateLimitedCount,
+			LastRateLimitedAt:        dbApp.LastRateLimitedAt,
 		}
 
 		if dbApp.Isolated {
@@ -648,8 +818,10 @@ func (api *api) ListApps(limit uint64, offset uint64, filters ListAppsFilters, o
 		for _, appPermission := range permissionsMap[dbApp.ID] {
 			apiApp.Scopes = append(apiApp.Scopes, appPermission.Scope)
 			apiApp.ExpiresAt = appPermission.ExpiresAt
-			if appPermission.Scope == constants.PAY_INVOICE_SCOPE {
+			if appPermission.Scope == constants.PAY_INVOICE_SCOPE || appPermission.Scope == constants.PAY_ONCHAIN_SCOPE {
 				apiApp.BudgetRenewal = appPermission.BudgetRenewal
+				apiApp.BudgetRenewalPeriodHours = appPermission.BudgetRenewalPeriodHours
+				apiApp.BudgetTimezone = appPermission.BudgetTimezone
 				apiApp.MaxAmount = uint64(appPermission.MaxAmountSat)
 				apiApp.MaxAmountSat = uint64(appPermission.MaxAmountSat)
 				apiApp.MaxAmountMsat = uint64(appPermission.MaxAmountSat) * 1000
@@ -781,12 +953,16 @@ func (api *api) GetLSPChannelOffer(ctx context.Context) (*alby.LSPChannelOffer,
 	return api.albyOAuthSvc.GetLSPChannelOffer(ctx)
 }
 
-func (api *api) ResetRouter(key string) error {
+func (api *api) ResetRouter(ctx context.Context, key string) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "reset_router", map[string]interface{}{"router": key}, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return ErrLNClientNotStarted
 	}
-	err := lnClient.ResetRouter(key)
+	err = lnClient.ResetRouter(key)
 	if err != nil {
 		return err
 	}
@@ -796,7 +972,11 @@ func (api *api) ResetRouter(key string) error {
 	return api.Stop()
 }
 
-func (api *api) ChangeUnlockPassword(changeUnlockPasswordRequest *ChangeUnlockPasswordRequest) error {
+func (api *api) ChangeUnlockPassword(ctx context.Context, changeUnlockPasswordRequest *ChangeUnlockPasswordRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "change_unlock_password", nil, err)
+	}()
+
 	if api.svc.GetLNClient() == nil {
 		return ErrLNClientNotStarted
 	}
@@ -816,17 +996,34 @@ func (api *api) ChangeUnlockPassword(changeUnlockPasswordRequest *ChangeUnlockPa
 		return err
 	}
 
+	// API tokens were issued under the old unlock password, which may have been
+	// changed because it leaked, so they have to be created again
+	err = api.apiTokensSvc.DeleteAllTokens()
+	if err != nil {
+		logger.Logger.WithError(err).Error("failed to revoke API tokens")
+		return fmt.Errorf("the unlock password was changed but API tokens could not be revoked: %w", err)
+	}
+
+	// backups must be restorable with the new unlock password
+	if backupService := api.svc.GetBackupService(); backupService != nil {
+		backupService.SetEncryptionKey(changeUnlockPasswordRequest.NewUnlockPassword)
+	}
+
 	// Because all the encrypted fields have changed
 	// we also need to stop the lnclient and ask the user to start it again
 	return api.Stop()
 }
 
-func (api *api) SetAutoUnlockPassword(unlockPassword string) error {
+func (api *api) SetAutoUnlockPassword(ctx context.Context, unlockPassword string) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "set_auto_unlock_password", map[string]interface{}{"enabled": unlockPassword != ""}, err)
+	}()
+
 	if api.svc.GetLNClient() == nil {
 		return ErrLNClientNotStarted
 	}
 
-	err := api.cfg.SetAutoUnlockPassword(unlockPassword)
+	err = api.cfg.SetAutoUnlockPassword(unlockPassword)
 
 	if err != nil {
 		logger.Logger.WithError(err).Error("failed to set auto unlock password")
@@ -871,7 +1068,11 @@ func (api *api) GetNodeConnectionInfo(ctx context.Context) (*NodeConnectionInfo,
 	}, nil
 }
 
-func (api *api) RefundSwap(refundSwapRequest *RefundSwapRequest) error {
+func (api *api) RefundSwap(ctx context.Context, refundSwapRequest *RefundSwapRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "refund_swap", refundSwapRequest, err)
+	}()
+
 	if api.svc.GetSwapsService() == nil {
 		return errors.New("SwapsService not started")
 	}
@@ -893,6 +1094,7 @@ func (api *api) GetAutoSwapConfig() (*GetAutoSwapConfigResponse, error) {
 
 	swapOutEnabled := swapOutBalanceThresholdStr != "" && swapOutAmountStr != ""
 	var swapOutBalanceThresholdSat, swapOutAmountSat uint64
+	var swapOutMaxFeeSat *uint64
 	if swapOutEnabled {
 		var err error
 		if swapOutBalanceThresholdSat, err = strconv.ParseUint(swapOutBalanceThresholdStr, 10, 64); err != nil {
@@ -901,6 +1103,13 @@ func (api *api) GetAutoSwapConfig() (*GetAutoSwapConfigResponse, error) {
 		if swapOutAmountSat, err = strconv.ParseUint(swapOutAmountStr, 10, 64); err != nil {
 			return nil, fmt.Errorf("invalid autoswap out amount: %w", err)
 		}
+		if swapOutMaxFeeSatStr, _ := api.cfg.Get(config.AutoSwapMaxFeeSatKey, ""); swapOutMaxFeeSatStr != "" {
+			maxFeeSat, err := strconv.ParseUint(swapOutMaxFeeSatStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid autoswap out max fee: %w", err)
+			}
+			swapOutMaxFeeSat = &maxFeeSat
+		}
 	}
 
 	return &GetAutoSwapConfigResponse{
@@ -911,6 +1120,7 @@ func (api *api) GetAutoSwapConfig() (*GetAutoSwapConfigResponse, error) {
 		SwapAmount:          swapOutAmountSat,
 		SwapAmountSat:       swapOutAmountSat,
 		Destination:         swapOutDestination,
+		MaxFeeSat:           swapOutMaxFeeSat,
 	}, nil
 }
 
@@ -1014,7 +1224,57 @@ func (api *api) GetSwapOutInfo() (*SwapInfoResponse, error) {
 	}, nil
 }
 
-func (api *api) InitiateSwapOut(ctx context.Context, initiateSwapOutRequest *InitiateSwapRequest) (*swaps.SwapResponse, error) {
+func (api *api) GetSwapInQuote(amountSat uint64) (*swaps.SwapQuote, error) {
+	if api.svc.GetSwapsService() == nil {
+		return nil, errors.New("SwapsService not started")
+	}
+	if amountSat == 0 {
+		return nil, errors.New("invalid swap amount")
+	}
+	return api.svc.GetSwapsService().GetSwapInQuote(amountSat)
+}
+
+func (api *api) GetSwapOutQuote(amountSat uint64) (*swaps.SwapQuote, error) {
+	if api.svc.GetSwapsService() == nil {
+		return nil, errors.New("SwapsService not started")
+	}
+	if amountSat == 0 {
+		return nil, errors.New("invalid swap amount")
+	}
+	return api.svc.GetSwapsService().GetSwapOutQuote(amountSat)
+}
+
+// resolveSwapQuote applies a swap quote to the swap amount and the maximum fee of a swap request:
+// the amount defaults to the quoted amount and the maximum fee to the quoted total fee
+func (api *api) resolveSwapQuote(quoteId string, swapType string, amountSat uint64, maxFeeSat *uint64) (uint64, *uint64, error) {
+	if quoteId == "" {
+		return amountSat, maxFeeSat, nil
+	}
+
+	quote, err := api.svc.GetSwapsService().GetSwapQuote(quoteId)
+	if err != nil {
+		return 0, nil, err
+	}
+	if quote.Type != swapType {
+		return 0, nil, fmt.Errorf("swap quote is not for a swap %s", swapType)
+	}
+	if amountSat == 0 {
+		amountSat = quote.ReceiveAmountSat
+	}
+	if amountSat != quote.ReceiveAmountSat {
+		return 0, nil, fmt.Errorf("swap amount does not match the quoted amount of %d sat", quote.ReceiveAmountSat)
+	}
+	if maxFeeSat == nil {
+		maxFeeSat = &quote.TotalFeeSat
+	}
+	return amountSat, maxFeeSat, nil
+}
+
+func (api *api) InitiateSwapOut(ctx context.Context, initiateSwapOutRequest *InitiateSwapRequest) (_ *swaps.SwapResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "initiate_swap_out", initiateSwapOutRequest, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -1031,15 +1291,21 @@ func (api *api) InitiateSwapOut(ctx context.Context, initiateSwapOutRequest *Ini
 	}
 	destination := initiateSwapOutRequest.Destination
 
+	amountSat, maxFeeSat, err := api.resolveSwapQuote(initiateSwapOutRequest.QuoteId, constants.SWAP_TYPE_OUT, amountSat, initiateSwapOutRequest.MaxFeeSat)
+	if err != nil {
+		return nil, err
+	}
+
 	if amountSat == 0 {
 		return nil, errors.New("invalid swap amount")
 	}
 
-	swapOutResponse, err := api.svc.GetSwapsService().SwapOut(amountSat, destination, false, false)
+	swapOutResponse, err := api.svc.GetSwapsService().SwapOut(amountSat, destination, false, false, maxFeeSat)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"amount_sat":  amountSat,
 			"destination": destination,
+			"max_fee_sat": maxFeeSat,
 		}).WithError(err).Error("Failed to initiate swap out")
 		return nil, err
 	}
@@ -1047,7 +1313,11 @@ func (api *api) InitiateSwapOut(ctx context.Context, initiateSwapOutRequest *Ini
 	return swapOutResponse, nil
 }
 
-func (api *api) InitiateSwapIn(ctx context.Context, initiateSwapInRequest *InitiateSwapRequest) (*swaps.SwapResponse, error) {
+func (api *api) InitiateSwapIn(ctx context.Context, initiateSwapInRequest *InitiateSwapRequest) (_ *swaps.SwapResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "initiate_swap_in", initiateSwapInRequest, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -1063,14 +1333,20 @@ func (api *api) InitiateSwapIn(ctx context.Context, initiateSwapInRequest *Initi
 		amountSat = *resolvedAmountSat
 	}
 
+	amountSat, maxFeeSat, err := api.resolveSwapQuote(initiateSwapInRequest.QuoteId, constants.SWAP_TYPE_IN, amountSat, initiateSwapInRequest.MaxFeeSat)
+	if err != nil {
+		return nil, err
+	}
+
 	if amountSat == 0 {
 		return nil, errors.New("invalid swap amount")
 	}
 
-	swapInResponse, err := api.svc.GetSwapsService().SwapIn(amountSat, false)
+	swapInResponse, err := api.svc.GetSwapsService().SwapIn(amountSat, false, maxFeeSat)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
-			"amount_sat": amountSat,
+			"amount_sat":  amountSat,
+			"max_fee_sat": maxFeeSat,
 		}).WithError(err).Error("Failed to initiate swap in")
 		return nil, err
 	}
@@ -1078,7 +1354,11 @@ func (api *api) InitiateSwapIn(ctx context.Context, initiateSwapInRequest *Initi
 	return swapInResponse, nil
 }
 
-func (api *api) EnableAutoSwapOut(ctx context.Context, enableAutoSwapsRequest *EnableAutoSwapRequest) error {
+func (api *api) EnableAutoSwapOut(ctx context.Context, enableAutoSwapsRequest *EnableAutoSwapRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "enable_auto_swap_out", enableAutoSwapsRequest, err)
+	}()
+
 	if api.svc.GetSwapsService() == nil {
 		return errors.New("SwapsService not started")
 	}
@@ -1109,7 +1389,7 @@ func (api *api) EnableAutoSwapOut(ctx context.Context, enableAutoSwapsRequest *E
 		balanceThresholdSat = *resolvedBalanceThresholdSat
 	}
 
-	err := api.cfg.SetUpdate(config.AutoSwapBalanceThresholdKey, strconv.FormatUint(balanceThresholdSat, 10), "")
+	err = api.cfg.SetUpdate(config.AutoSwapBalanceThresholdKey, strconv.FormatUint(balanceThresholdSat, 10), "")
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to save autoswap balance threshold to config")
 		return err
@@ -1133,11 +1413,25 @@ func (api *api) EnableAutoSwapOut(ctx context.Context, enableAutoSwapsRequest *E
 		return err
 	}
 
+	maxFeeSat := ""
+	if enableAutoSwapsRequest.MaxFeeSat != nil {
+		maxFeeSat = strconv.FormatUint(*enableAutoSwapsRequest.MaxFeeSat, 10)
+	}
+	err = api.cfg.SetUpdate(config.AutoSwapMaxFeeSatKey, maxFeeSat, "")
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to save autoswap max fee to config")
+		return err
+	}
+
 	return api.svc.GetSwapsService().EnableAutoSwapOut(enableAutoSwapsRequest.UnlockPassword)
 }
 
-func (api *api) DisableAutoSwap() error {
-	keys := []string{config.AutoSwapBalanceThresholdKey, config.AutoSwapAmountKey, config.AutoSwapDestinationKey}
+func (api *api) DisableAutoSwap(ctx context.Context) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "disable_auto_swap", nil, err)
+	}()
+
+	keys := []string{config.AutoSwapBalanceThresholdKey, config.AutoSwapAmountKey, config.AutoSwapDestinationKey, config.AutoSwapMaxFeeSatKey}
 
 	for _, key := range keys {
 		if err := api.cfg.SetUpdate(key, "", ""); err != nil {
@@ -1152,6 +1446,89 @@ func (api *api) DisableAutoSwap() error {
 	return nil
 }
 
+func (api *api) GetAutoSwapInConfig() (*GetAutoSwapInConfigResponse, error) {
+	autoSwapInConfig, err := swaps.GetAutoSwapInConfig(api.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if autoSwapInConfig == nil {
+		return &GetAutoSwapInConfigResponse{
+			MaxFeePercentage: swaps.DefaultAutoSwapInMaxFeePercentage,
+			CooldownHours:    swaps.DefaultAutoSwapInCooldownHours,
+		}, nil
+	}
+
+	return &GetAutoSwapInConfigResponse{
+		Enabled:             true,
+		BalanceThresholdSat: autoSwapInConfig.BalanceThresholdSat,
+		SwapAmountSat:       autoSwapInConfig.AmountSat,
+		MaxFeePercentage:    autoSwapInConfig.MaxFeePercentage,
+		CooldownHours:       autoSwapInConfig.CooldownHours,
+	}, nil
+}
+
+func (api *api) EnableAutoSwapIn(ctx context.Context, enableAutoSwapInRequest *EnableAutoSwapInRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "enable_auto_swap_in", enableAutoSwapInRequest, err)
+	}()
+
+	if api.svc.GetSwapsService() == nil {
+		return errors.New("SwapsService not started")
+	}
+
+	if enableAutoSwapInRequest.SwapAmountSat == 0 {
+		return errors.New("swap amount must be greater than 0")
+	}
+
+	maxFeePercentage := swaps.DefaultAutoSwapInMaxFeePercentage
+	if enableAutoSwapInRequest.MaxFeePercentage != nil {
+		maxFeePercentage = *enableAutoSwapInRequest.MaxFeePercentage
+	}
+	if maxFeePercentage <= 0 || maxFeePercentage > 100 {
+		return errors.New("max fee percentage must be between 0 and 100")
+	}
+
+	cooldownHours := uint64(swaps.DefaultAutoSwapInCooldownHours)
+	if enableAutoSwapInRequest.CooldownHours != nil {
+		cooldownHours = *enableAutoSwapInRequest.CooldownHours
+	}
+
+	values := map[string]string{
+		config.AutoSwapInBalanceThresholdKey: strconv.FormatUint(enableAutoSwapInRequest.BalanceThresholdSat, 10),
+		config.AutoSwapInAmountKey:           strconv.FormatUint(enableAutoSwapInRequest.SwapAmountSat, 10),
+		config.AutoSwapInMaxFeePercentageKey: strconv.FormatFloat(maxFeePercentage, 'f', -1, 64),
+		config.AutoSwapInCooldownHoursKey:    strconv.FormatUint(cooldownHours, 10),
+	}
+	for key, value := range values {
+		if err := api.cfg.SetUpdate(key, value, ""); err != nil {
+			logger.Logger.WithError(err).Errorf("Failed to save auto swap in config for key: %s", key)
+			return err
+		}
+	}
+
+	return api.svc.GetSwapsService().EnableAutoSwapIn()
+}
+
+func (api *api) DisableAutoSwapIn(ctx context.Context) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "disable_auto_swap_in", nil, err)
+	}()
+
+	keys := []string{config.AutoSwapInBalanceThresholdKey, config.AutoSwapInAmountKey, config.AutoSwapInMaxFeePercentageKey, config.AutoSwapInCooldownHoursKey}
+
+	for _, key := range keys {
+		if err := api.cfg.SetUpdate(key, "", ""); err != nil {
+			logger.Logger.WithError(err).Errorf("Failed to remove auto swap in config for key: %s", key)
+			return err
+		}
+	}
+
+	if api.svc.GetSwapsService() != nil {
+		api.svc.GetSwapsService().StopAutoSwapIn()
+	}
+	return nil
+}
+
 func (api *api) GetSwapMnemonic() string {
 	return api.keys.GetSwapMnemonic()
 }
@@ -1199,7 +1576,11 @@ func (api *api) ListPeers(ctx context.Context) ([]PeerDetails, error) {
 	return apiPeers, nil
 }
 
-func (api *api) ConnectPeer(ctx context.Context, connectPeerRequest *ConnectPeerRequest) error {
+func (api *api) ConnectPeer(ctx context.Context, connectPeerRequest *ConnectPeerRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "connect_peer", connectPeerRequest, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return ErrLNClientNotStarted
@@ -1211,7 +1592,11 @@ func (api *api) ConnectPeer(ctx context.Context, connectPeerRequest *ConnectPeer
 	})
 }
 
-func (api *api) OpenChannel(ctx context.Context, openChannelRequest *OpenChannelRequest) (*OpenChannelResponse, error) {
+func (api *api) OpenChannel(ctx context.Context, openChannelRequest *OpenChannelRequest) (_ *OpenChannelResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "open_channel", openChannelRequest, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -1229,7 +1614,11 @@ func (api *api) OpenChannel(ctx context.Context, openChannelRequest *OpenChannel
 	}, nil
 }
 
-func (api *api) DisconnectPeer(ctx context.Context, peerId string) error {
+func (api *api) DisconnectPeer(ctx context.Context, peerId string) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "disconnect_peer", map[string]interface{}{"peerId": peerId}, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return ErrLNClientNotStarted
@@ -1240,7 +1629,11 @@ func (api *api) DisconnectPeer(ctx context.Context, peerId string) error {
 	return lnClient.DisconnectPeer(ctx, peerId)
 }
 
-func (api *api) CloseChannel(ctx context.Context, peerId, channelId string, force bool) (*CloseChannelResponse, error) {
+func (api *api) CloseChannel(ctx context.Context, peerId, channelId string, force bool) (_ *CloseChannelResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "close_channel", map[string]interface{}{"peerId": peerId, "channelId": channelId, "force": force}, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -1250,7 +1643,7 @@ func (api *api) CloseChannel(ctx context.Context, peerId, channelId string, forc
 		"channel_id": channelId,
 		"force":      force,
 	}).Info("Closing channel")
-	err := lnClient.CloseChannel(ctx, &lnclient.CloseChannelRequest{
+	err = lnClient.CloseChannel(ctx, &lnclient.CloseChannelRequest{
 		NodeId:    peerId,
 		ChannelId: channelId,
 		Force:     force,
@@ -1261,7 +1654,11 @@ func (api *api) CloseChannel(ctx context.Context, peerId, channelId string, forc
 	return &CloseChannelResponse{}, nil
 }
 
-func (api *api) UpdateChannel(ctx context.Context, updateChannelRequest *UpdateChannelRequest) error {
+func (api *api) UpdateChannel(ctx context.Context, updateChannelRequest *UpdateChannelRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "update_channel", updateChannelRequest, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return ErrLNClientNotStarted
@@ -1363,7 +1760,18 @@ func (api *api) SignMessage(ctx context.Context, message string) (*SignMessageRe
 	}, nil
 }
 
-func (api *api) RedeemOnchainFunds(ctx context.Context, toAddress string, amountSat uint64, feeRate *uint64, sendAll bool) (*RedeemOnchainFundsResponse, error) {
+func (api *api) RedeemOnchainFunds(ctx context.Context, toAddress string, amountSat uint64, feeRate *uint64, sendAll bool, totpCode string) (_ *RedeemOnchainFundsResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "redeem_onchain_funds", map[string]interface{}{"toAddress": toAddress, "amountSat": amountSat, "feeRate": feeRate, "sendAll": sendAll}, err)
+	}()
+
+	// the amount of a send-all is only known once the transaction is built
+	if sendAll || amountSat > api.cfg.GetEnv().TOTPOnchainThresholdSat {
+		if err := api.totpSvc.VerifyStepUp(totpCode); err != nil {
+			return nil, err
+		}
+	}
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -1614,6 +2022,13 @@ func (api *api) GetInfo(ctx context.Context) (*InfoResponse, error) {
 
 	info.NextBackupReminder, _ = api.cfg.Get("NextBackupReminder", "")
 
+	totpStatus, err := api.totpSvc.GetStatus()
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to get two-factor authentication status")
+		return nil, err
+	}
+	info.TOTPEnabled = totpStatus.Enabled
+
 	info.NodeAlias, _ = api.cfg.Get("NodeAlias", "")
 
 	return &info, nil
@@ -1662,7 +2077,11 @@ func (api *api) setJitChannelsEnabled(enabled bool) error {
 	return nil
 }
 
-func (api *api) UpdateSettings(updateSettingsRequest *UpdateSettingsRequest) error {
+func (api *api) UpdateSettings(ctx context.Context, updateSettingsRequest *UpdateSettingsRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "update_settings", updateSettingsRequest, err)
+	}()
+
 	if updateSettingsRequest.Currency != "" {
 		err := api.setCurrency(updateSettingsRequest.Currency)
 		if err != nil {
@@ -1687,8 +2106,12 @@ func (api *api) UpdateSettings(updateSettingsRequest *UpdateSettingsRequest) err
 	return nil
 }
 
-func (api *api) SetNodeAlias(nodeAlias string) error {
-	err := api.cfg.SetUpdate("NodeAlias", nodeAlias, "")
+func (api *api) SetNodeAlias(ctx context.Context, nodeAlias string) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "set_node_alias", map[string]interface{}{"nodeAlias": nodeAlias}, err)
+	}()
+
+	err = api.cfg.SetUpdate("NodeAlias", nodeAlias, "")
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to save node alias to config")
 		return err
@@ -1697,10 +2120,13 @@ func (api *api) SetNodeAlias(nodeAlias string) error {
 	return nil
 }
 
-func (api *api) GetMnemonic(unlockPassword string) (*MnemonicResponse, error) {
+func (api *api) GetMnemonic(unlockPassword string, totpCode string) (*MnemonicResponse, error) {
 	if !api.cfg.CheckUnlockPassword(unlockPassword) {
 		return nil, fmt.Errorf("wrong password")
 	}
+	if err := api.totpSvc.VerifyUnlock(unlockPassword, totpCode); err != nil {
+		return nil, err
+	}
 
 	mnemonic, err := api.cfg.Get("Mnemonic", unlockPassword)
 	if err != nil {
@@ -1743,7 +2169,14 @@ func (api *api) startInternal(startRequest *StartRequest) (err error) {
 	return api.svc.StartApp(startRequest.UnlockPassword)
 }
 
-func (api *api) Setup(ctx context.Context, setupRequest *SetupRequest) error {
+func (api *api) Setup(ctx context.Context, setupRequest *SetupRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "setup", map[string]interface{}{
+			"backendType":      setupRequest.LNBackendType,
+			"importedMnemonic": setupRequest.Mnemonic != "",
+		}, err)
+	}()
+
 	if !startMutex.TryLock() {
 		// do not allow to start twice in case this is somehow called twice
 		return errors.New("app is busy")
@@ -2025,7 +2458,11 @@ func (api *api) GetWalletCapabilities(ctx context.Context) (*WalletCapabilitiesR
 	}, nil
 }
 
-func (api *api) MigrateNodeStorage(ctx context.Context, to string) error {
+func (api *api) MigrateNodeStorage(ctx context.Context, to string) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "migrate_node_storage", map[string]interface{}{"to": to}, err)
+	}()
+
 	if api.svc.GetLNClient() == nil {
 		return ErrLNClientNotStarted
 	}
@@ -2190,7 +2627,15 @@ func (api *api) Health(ctx context.Context) (*HealthResponse, error) {
 		}
 	}
 
-	return &HealthResponse{Alarms: alarms}, nil
+	var backupStatus *backup.Status
+	if backupService := api.svc.GetBackupService(); backupService != nil {
+		backupStatus = backupService.GetStatus()
+		if backupStatus.LastError != "" {
+			alarms = append(alarms, NewHealthAlarm(HealthAlarmKindBackupFailed, backupStatus.LastError))
+		}
+	}
+
+	return &HealthResponse{Alarms: alarms, Backup: backupStatus}, nil
 }
 
 func (api *api) GetCustomNodeCommands() (*CustomNodeCommandsResponse, error) {
@@ -2219,7 +2664,11 @@ func (api *api) GetCustomNodeCommands() (*CustomNodeCommandsResponse, error) {
 	return &CustomNodeCommandsResponse{Commands: commandDefs}, nil
 }
 
-func (api *api) ExecuteCustomNodeCommand(ctx context.Context, command string) (interface{}, error) {
+func (api *api) ExecuteCustomNodeCommand(ctx context.Context, command string) (_ interface{}, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "execute_custom_node_command", map[string]interface{}{"command": command}, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -2302,27 +2751,17 @@ func (api *api) parseExpiresAt(expiresAtString string) (*time.Time, error) {
 }
 
 func (api *api) GetForwards() (*GetForwardsResponse, error) {
-	var forwards []db.Forward
-	err := api.db.Find(&forwards).Error
+	stats, err := queries.GetForwardStats(api.db, time.Time{}, time.Time{})
 	if err != nil {
 		return nil, err
 	}
 
-	var totalOutboundAmountMsat uint64
-	var totalFeeEarnedMsat uint64
-
-	for _, forward := range forwards {
-		totalOutboundAmountMsat += forward.OutboundAmountForwardedMsat
-		totalFeeEarnedMsat += forward.TotalFeeEarnedMsat
-	}
-
-	numForwards := len(forwards)
-
 	return &GetForwardsResponse{
-		OutboundAmountForwardedSat:  totalOutboundAmountMsat / 1000,
-		OutboundAmountForwardedMsat: totalOutboundAmountMsat,
-		TotalFeeEarnedSat:           totalFeeEarnedMsat / 1000,
-		TotalFeeEarnedMsat:          totalFeeEarnedMsat,
-		NumForwards:                 uint64(numForwards),
+		OutboundAmountForwardedSat:  stats.OutboundAmountMsat / 1000,
+		OutboundAmountForwardedMsat: stats.OutboundAmountMsat,
+		TotalFeeEarnedSat:           stats.FeeEarnedMsat / 1000,
+		TotalFeeEarnedMsat:          stats.FeeEarnedMsat,
+		NumForwards:                 stats.NumForwards,
+		NumFailedForwards:           stats.NumFailedForwards,
 	}, nil
 }
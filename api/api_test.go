@@ -8,8 +8,10 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/getAlby/hub/audit"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/service"
+	test_db "github.com/getAlby/hub/tests/db"
 	"github.com/getAlby/hub/tests/mocks"
 )
 
@@ -52,7 +54,7 @@ func TestGetCustomNodeCommandDefinitions(t *testing.T) {
 	lnClient.On("GetCustomNodeCommandDefinitions").Return(mockLNCommandDefs)
 	svc.On("GetLNClient").Return(lnClient)
 
-	theAPI := instantiateAPIWithService(svc)
+	theAPI := instantiateAPIWithService(t, svc)
 
 	commands, err := theAPI.GetCustomNodeCommands()
 	require.NoError(t, err)
@@ -208,7 +210,7 @@ func TestExecuteCustomNodeCommand(t *testing.T) {
 
 			svc.On("GetLNClient").Return(lnClient)
 
-			theAPI := instantiateAPIWithService(svc)
+			theAPI := instantiateAPIWithService(t, svc)
 
 			response, err := theAPI.ExecuteCustomNodeCommand(context.TODO(), tc.apiCommandLine)
 			require.Equal(t, tc.apiExpectedResponse, response)
@@ -223,6 +225,11 @@ func TestExecuteCustomNodeCommand(t *testing.T) {
 
 // instantiateAPIWithService is a helper function that returns a partially
 // constructed API instance. It is only suitable for the simplest of test cases.
-func instantiateAPIWithService(s service.Service) *api {
-	return &api{svc: s}
+func instantiateAPIWithService(t *testing.T, s service.Service) *api {
+	gormDb, err := test_db.NewDB(t)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		test_db.CloseDB(gormDb)
+	})
+	return &api{svc: s, auditSvc: audit.NewAuditService(gormDb, t.TempDir())}
 }
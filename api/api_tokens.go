@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+func (api *api) ListApiTokens() ([]ApiToken, error) {
+	dbApiTokens, err := api.apiTokensSvc.ListTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	apiTokens := []ApiToken{}
+	for _, dbApiToken := range dbApiTokens {
+		apiTokens = append(apiTokens, *toApiToken(&dbApiToken))
+	}
+	return apiTokens, nil
+}
+
+func (api *api) CreateApiToken(ctx context.Context, createApiTokenRequest *CreateApiTokenRequest) (_ *CreateApiTokenResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "create_api_token", createApiTokenRequest, err)
+	}()
+
+	expiresAt, err := api.parseExpiresAt(createApiTokenRequest.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	dbApiToken, token, err := api.apiTokensSvc.CreateToken(createApiTokenRequest.Name, createApiTokenRequest.Scopes, createApiTokenRequest.AllowedIps, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateApiTokenResponse{
+		ApiToken: *toApiToken(dbApiToken),
+		Token:    token,
+	}, nil
+}
+
+func (api *api) DeleteApiToken(ctx context.Context, id uint) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "delete_api_token", map[string]interface{}{"id": id}, err)
+	}()
+
+	return api.apiTokensSvc.DeleteToken(id)
+}
+
+func toApiToken(dbApiToken *db.ApiToken) *ApiToken {
+	scopes := []string{}
+	allowedIps := []string{}
+	if dbApiToken.Scopes != nil {
+		if err := json.Unmarshal(dbApiToken.Scopes, &scopes); err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"api_token_id": dbApiToken.ID,
+			}).Error("Failed to deserialize API token scopes")
+		}
+	}
+	if dbApiToken.AllowedIps != nil {
+		if err := json.Unmarshal(dbApiToken.AllowedIps, &allowedIps); err != nil {
+			logger.Logger.WithError(err).WithFields(logrus.Fields{
+				"api_token_id": dbApiToken.ID,
+			}).Error("Failed to deserialize API token allowed IPs")
+		}
+	}
+
+	return &ApiToken{
+		ID:         dbApiToken.ID,
+		Name:       dbApiToken.Name,
+		Scopes:     scopes,
+		AllowedIps: allowedIps,
+		ExpiresAt:  dbApiToken.ExpiresAt,
+		LastUsedAt: dbApiToken.LastUsedAt,
+		CreatedAt:  dbApiToken.CreatedAt,
+	}
+}
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"context"
 	"testing"
 
+	"github.com/getAlby/hub/audit"
 	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	test_db "github.com/getAlby/hub/tests/db"
 	"github.com/getAlby/hub/tests/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,14 +40,25 @@ func TestBuildReturnToUrl(t *testing.T) {
 }
 
 func TestCreateApp_SuperuserScopeIncorrectPassword(t *testing.T) {
+	gormDb, err := test_db.NewDB(t)
+	require.NoError(t, err)
+	defer test_db.CloseDB(gormDb)
+
 	cfg := mocks.NewMockConfig(t)
 	cfg.On("CheckUnlockPassword", "").Return(false)
-	theAPI := &api{svc: mocks.NewMockService(t), cfg: cfg}
-	response, err := theAPI.CreateApp(&CreateAppRequest{
+	theAPI := &api{svc: mocks.NewMockService(t), cfg: cfg, auditSvc: audit.NewAuditService(gormDb, t.TempDir())}
+	response, err := theAPI.CreateApp(context.TODO(), &CreateAppRequest{
 		Scopes: []string{constants.SUPERUSER_SCOPE},
 	})
 
 	assert.Nil(t, response)
 	require.Error(t, err)
 	assert.Equal(t, "incorrect unlock password to create app with superuser permission", err.Error())
+
+	// failed attempts are audited too
+	var auditEvent db.AuditEvent
+	require.NoError(t, gormDb.First(&auditEvent).Error)
+	assert.Equal(t, "create_app", auditEvent.Action)
+	assert.Equal(t, constants.AUDIT_RESULT_ERROR, auditEvent.Result)
+	assert.Equal(t, err.Error(), auditEvent.Error)
 }
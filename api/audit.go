@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/constants"
+)
+
+// ParseListAuditEventsFilters parses audit log filter query parameters
+// shared by the HTTP and Wails transports. Invalid values return an error.
+func ParseListAuditEventsFilters(query url.Values) (ListAuditEventsFilters, error) {
+	filters := ListAuditEventsFilters{
+		Action: query.Get("action"),
+		Actor:  query.Get("actor"),
+	}
+
+	if source := query.Get("source"); source != "" {
+		if !slices.Contains([]string{constants.AUDIT_SOURCE_HTTP, constants.AUDIT_SOURCE_WAILS, constants.AUDIT_SOURCE_NIP47}, source) {
+			return filters, fmt.Errorf("invalid source: %s", source)
+		}
+		filters.Source = source
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"from", &filters.From},
+		{"until", &filters.Until},
+	} {
+		if paramValue := query.Get(param.name); paramValue != "" {
+			parsedValue, err := strconv.ParseUint(paramValue, 10, 64)
+			if err != nil {
+				return filters, fmt.Errorf("invalid %s: %s", param.name, paramValue)
+			}
+			*param.value = parsedValue
+		}
+	}
+
+	return filters, nil
+}
+
+func (api *api) ListAuditEvents(limit uint64, offset uint64, filters ListAuditEventsFilters) (*ListAuditEventsResponse, error) {
+	auditFilters := audit.ListEventsFilters{
+		Action: filters.Action,
+		Source: filters.Source,
+		Actor:  filters.Actor,
+	}
+	if filters.From > 0 {
+		from := time.Unix(int64(filters.From), 0)
+		auditFilters.From = &from
+	}
+	if filters.Until > 0 {
+		until := time.Unix(int64(filters.Until), 0)
+		auditFilters.Until = &until
+	}
+
+	dbAuditEvents, totalCount, err := api.auditSvc.ListEvents(auditFilters, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	auditEvents := []AuditEvent{}
+	for _, dbAuditEvent := range dbAuditEvents {
+		auditEvents = append(auditEvents, AuditEvent{
+			ID:        dbAuditEvent.ID,
+			Action:    dbAuditEvent.Action,
+			Source:    dbAuditEvent.Source,
+			Actor:     dbAuditEvent.Actor,
+			Params:    json.RawMessage(dbAuditEvent.Params),
+			Result:    dbAuditEvent.Result,
+			Error:     dbAuditEvent.Error,
+			PrevHash:  dbAuditEvent.PrevHash,
+			Hash:      dbAuditEvent.Hash,
+			CreatedAt: dbAuditEvent.CreatedAt,
+		})
+	}
+
+	return &ListAuditEventsResponse{
+		TotalCount: totalCount,
+		Events:     auditEvents,
+	}, nil
+}
+
+func (api *api) VerifyAuditLog() (*audit.VerifyResult, error) {
+	return api.auditSvc.Verify()
+}
@@ -1,11 +1,10 @@
 package api
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"strings"
 	"time"
 
@@ -13,60 +12,24 @@ import (
 	"os"
 	"path/filepath"
 
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/sha256"
-
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/backup"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/utils"
-	"golang.org/x/crypto/pbkdf2"
 )
 
-// zipMagic is the ZIP local file header signature "PK\x03\x04" — the first
-// four bytes of every ZIP file, and therefore of every archive produced by
-// CreateBackup. decryptingReader uses it to detect which cipher scheme the
-// backup file was created with.
-var zipMagic = []byte{'P', 'K', 0x03, 0x04}
-
-// backupCipher describes one of the cipher schemes used for backup files,
-// which are laid out as salt || iv || encrypted zip archive.
-type backupCipher struct {
-	saltSize  int
-	deriveKey func(password string, salt []byte) ([]byte, error)
-	newStream func(block cipher.Block, iv []byte) cipher.Stream
-}
-
-var backupCiphers = []backupCipher{
-	// current scheme, used for all new backup files
-	{
-		saltSize: 32,
-		deriveKey: func(password string, salt []byte) ([]byte, error) {
-			key, _, err := config.DeriveKey(password, salt)
-			return key, err
-		},
-		newStream: cipher.NewCTR,
-	},
-	// legacy scheme, kept to restore backup files created by older versions
-	{
-		saltSize: 8,
-		deriveKey: func(password string, salt []byte) ([]byte, error) {
-			return pbkdf2.Key([]byte(password), salt, 4096, 32, sha256.New), nil
-		},
-		//nolint:staticcheck // OFB is required to read files created by older versions
-		newStream: cipher.NewOFB,
-	},
-}
-
-func (api *api) CreateBackup(unlockPassword string, w io.Writer) error {
+func (api *api) CreateBackup(unlockPassword string, totpCode string, w io.Writer) error {
 	logger.Logger.Info("Creating backup to migrate Alby Hub to another device")
 	var err error
 
 	if !api.cfg.CheckUnlockPassword(unlockPassword) {
 		return errors.New("invalid unlock password")
 	}
+	if err := api.totpSvc.VerifyUnlock(unlockPassword, totpCode); err != nil {
+		return err
+	}
 
 	autoUnlockPassword, err := api.cfg.Get("AutoUnlockPassword", "")
 	if err != nil {
@@ -185,7 +148,15 @@ func (api *api) CreateBackup(unlockPassword string, w io.Writer) error {
 		filesToArchive = append(filesToArchive, lnFiles...)
 	}
 
-	cw, err := encryptingWriter(w, unlockPassword)
+	// The hub is stopped, so the audit log head matches the database being archived.
+	for _, auditFileName := range []string{audit.KeyFileName, audit.HeadFileName} {
+		auditFilePath := filepath.Join(workDir, auditFileName)
+		if _, err := os.Stat(auditFilePath); err == nil {
+			filesToArchive = append(filesToArchive, auditFilePath)
+		}
+	}
+
+	cw, err := backup.EncryptingWriter(w, unlockPassword)
 	if err != nil {
 		return fmt.Errorf("failed to create encrypted writer: %w", err)
 	}
@@ -248,7 +219,13 @@ func (api *api) CreateBackup(unlockPassword string, w io.Writer) error {
 	return nil
 }
 
-func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
+func (api *api) RestoreBackup(ctx context.Context, unlockPassword string, r io.Reader) (err error) {
+	// the restored database replaces this one on the next start, so a
+	// successful restore is recorded in the log of the replaced database
+	defer func() {
+		api.auditSvc.Record(ctx, "restore_backup", nil, err)
+	}()
+
 	logger.Logger.Info("Restoring migration backup file")
 
 	workDir, err := filepath.Abs(api.cfg.GetEnv().Workdir)
@@ -264,7 +241,7 @@ func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
 		return errors.New("migration to non-sqlite backend is currently not supported")
 	}
 
-	cr, err := decryptingReader(r, unlockPassword)
+	cr, err := backup.DecryptingReader(r, unlockPassword)
 	if err != nil {
 		return fmt.Errorf("failed to create decrypted reader: %w", err)
 	}
@@ -299,6 +276,19 @@ func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
 		return errors.New("backup file contains no files")
 	}
 
+	manifest, err := backup.ReadManifest(zr)
+	if err != nil {
+		return err
+	}
+	if manifest != nil && manifest.Scheduled && !manifest.VssEnabled {
+		// scheduled backups are not created with the node stopped, so their channel
+		// state may be outdated, which would lead to loss of funds if restored
+		if backup.ContainsNodeStorage(zr, config.LDKBackendType) {
+			return errors.New("refusing to restore channel state from a scheduled backup as it may be outdated and lead to loss of funds, please use a migration backup instead")
+		}
+		logger.Logger.Warn("Restoring a scheduled backup without channel state, the existing channel state of this hub is kept")
+	}
+
 	restoreDir := filepath.Join(workDir, "restore")
 
 	// Extract into a staging directory and only move it to the restore
@@ -353,6 +343,9 @@ func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
 
 	logger.Logger.WithField("count", len(zr.File)).Info("Extracting files")
 	for _, f := range zr.File {
+		if f.Name == backup.ManifestFileName {
+			continue
+		}
 		logger.Logger.WithField("file", f.Name).Info("Extracting file")
 		if err = extractZipEntry(f); err != nil {
 			return fmt.Errorf("failed to extract zip entry: %w", err)
@@ -386,102 +379,10 @@ func (api *api) RestoreBackup(unlockPassword string, r io.Reader) error {
 	return nil
 }
 
-func encryptingWriter(w io.Writer, password string) (io.Writer, error) {
-	scheme := backupCiphers[0]
-
-	salt := make([]byte, scheme.saltSize)
-	if _, err := rand.Read(salt); err != nil {
-		return nil, fmt.Errorf("failed to generate salt: %w", err)
-	}
-
-	encKey, err := scheme.deriveKey(password, salt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
-	}
-	block, err := aes.NewCipher(encKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
-	}
-
-	iv := make([]byte, aes.BlockSize)
-	if _, err = rand.Read(iv); err != nil {
-		return nil, fmt.Errorf("failed to generate IV: %w", err)
-	}
-
-	_, err = w.Write(salt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write salt: %w", err)
-	}
-
-	_, err = w.Write(iv)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write IV: %w", err)
-	}
-
-	cw := &cipher.StreamWriter{
-		S: scheme.newStream(block, iv),
-		W: w,
-	}
-
-	return cw, nil
-}
-
-func decryptingReader(r io.Reader, password string) (io.Reader, error) {
-	// Read the largest possible header (salt, IV and the first bytes of the
-	// archive) upfront, then trial-decrypt with each supported cipher scheme
-	// and pick the one that produces the ZIP signature.
-	maxHeaderSize := 0
-	minHeaderSize := math.MaxInt
-	for _, scheme := range backupCiphers {
-		headerSize := scheme.saltSize + aes.BlockSize + len(zipMagic)
-		maxHeaderSize = max(maxHeaderSize, headerSize)
-		minHeaderSize = min(minHeaderSize, headerSize)
-	}
-
-	// Read the full header with io.ReadFull rather than io.ReadAtLeast: the
-	// reader may deliver short reads (e.g. a network request body), and
-	// stopping early could truncate the header of a scheme with a larger
-	// salt. A short file is only acceptable if it still covers the smallest
-	// scheme header.
-	header := make([]byte, maxHeaderSize)
-	n, err := io.ReadFull(r, header)
-	if err != nil && !(errors.Is(err, io.ErrUnexpectedEOF) && n >= minHeaderSize) {
-		return nil, fmt.Errorf("failed to read backup header: %w", err)
-	}
-	header = header[:n]
-
-	for _, scheme := range backupCiphers {
-		if len(header) < scheme.saltSize+aes.BlockSize+len(zipMagic) {
-			continue
-		}
-		salt := header[:scheme.saltSize]
-		iv := header[scheme.saltSize : scheme.saltSize+aes.BlockSize]
-		encrypted := header[scheme.saltSize+aes.BlockSize:]
-
-		encKey, err := scheme.deriveKey(password, salt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to derive encryption key: %w", err)
-		}
-
-		block, err := aes.NewCipher(encKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create AES cipher: %w", err)
-		}
-
-		stream := scheme.newStream(block, iv)
-		decrypted := make([]byte, len(encrypted))
-		stream.XORKeyStream(decrypted, encrypted)
-		if !bytes.Equal(decrypted[:len(zipMagic)], zipMagic) {
-			continue
-		}
-
-		cr := &cipher.StreamReader{
-			S: stream,
-			R: r,
-		}
-
-		return io.MultiReader(bytes.NewReader(decrypted), cr), nil
-	}
+// VerifyBackup checks that a backup file can be decrypted with the unlock password
+// it was created with and restored, without changing the running hub
+func (api *api) VerifyBackup(unlockPassword string, r io.Reader) (*backup.VerificationResult, error) {
+	logger.Logger.Info("Verifying backup file")
 
-	return nil, errors.New("invalid unlock password or backup file")
+	return backup.Verify(r, unlockPassword, "")
 }
@@ -3,24 +3,27 @@ package api
 import (
 	"archive/zip"
 	"bytes"
-	"encoding/hex"
+	"context"
 	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
-	"testing/iotest"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"gorm.io/datatypes"
 
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/backup"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	test_db "github.com/getAlby/hub/tests/db"
 	"github.com/getAlby/hub/tests/mocks"
+	"github.com/getAlby/hub/totp"
 )
 
 // TestCreateBackup creates a backup from the test database (sqlite by
@@ -71,10 +74,11 @@ func TestCreateBackup(t *testing.T) {
 		cfg:          cfg,
 		svc:          svc,
 		albyOAuthSvc: albyOAuthSvc,
+		totpSvc:      totp.NewTOTPService(cfg),
 	}
 
 	var buf bytes.Buffer
-	err = theAPI.CreateBackup(unlockPassword, &buf)
+	err = theAPI.CreateBackup(unlockPassword, "", &buf)
 	require.NoError(t, err)
 
 	// The temporary database created when converting from postgres must
@@ -83,7 +87,7 @@ func TestCreateBackup(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, entries)
 
-	cr, err := decryptingReader(&buf, unlockPassword)
+	cr, err := backup.DecryptingReader(&buf, unlockPassword)
 	require.NoError(t, err)
 	decrypted, err := io.ReadAll(cr)
 	require.NoError(t, err)
@@ -135,9 +139,11 @@ func TestRestoreBackupRejectsPathTraversal(t *testing.T) {
 	cfg, err := config.NewConfig(appConfig, gormDB)
 	require.NoError(t, err)
 
+	auditSvc := audit.NewAuditService(gormDB, t.TempDir())
 	theAPI := &api{
-		db:  gormDB,
-		cfg: cfg,
+		db:       gormDB,
+		cfg:      cfg,
+		auditSvc: auditSvc,
 	}
 
 	unlockPassword := ""
@@ -148,7 +154,7 @@ func TestRestoreBackupRejectsPathTraversal(t *testing.T) {
 	escapeTarget := filepath.Join(workDir, "pwned.txt")
 
 	var buf bytes.Buffer
-	cw, err := encryptingWriter(&buf, unlockPassword)
+	cw, err := backup.EncryptingWriter(&buf, unlockPassword)
 	require.NoError(t, err)
 	zw := zip.NewWriter(cw)
 	// A valid entry before the malicious one, to verify that a partially
@@ -163,9 +169,14 @@ func TestRestoreBackupRejectsPathTraversal(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, zw.Close())
 
-	err = theAPI.RestoreBackup(unlockPassword, &buf)
+	err = theAPI.RestoreBackup(context.TODO(), unlockPassword, &buf)
 	require.ErrorContains(t, err, "refusing to extract zip entry outside restore directory")
 
+	auditEvents, _, err := auditSvc.ListEvents(audit.ListEventsFilters{Action: "restore_backup"}, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(auditEvents))
+	require.Equal(t, constants.AUDIT_RESULT_ERROR, auditEvents[0].Result)
+
 	_, statErr := os.Stat(escapeTarget)
 	require.True(t, os.IsNotExist(statErr), "traversal entry must not be written outside the restore directory")
 
@@ -181,81 +192,51 @@ func TestRestoreBackupRejectsPathTraversal(t *testing.T) {
 	}
 }
 
-// legacyBackupFixture is a backup file created with the encryption scheme
-// used by older versions (PBKDF2 key derivation), encrypted with the
-// password "test-unlock-password". Its archive contains a single "nwc.db"
-// entry with the contents "legacy backup contents".
-const legacyBackupFixture = "0102030405060708101112131415161718191a1b1c1d1e1f8eca79631915f679a00cdd95d3f20d8d169eb9aa5d52642ca13b93886c3c7d7ba4b759462bc9dd8deccf638edcc9b5b9fda3d23dcd904cf6e99bc57ac59c4df6be5aa676542b7cbc9998029420c0ae5a6986c735150ababde5b382560acaebd5894aa4420924f1ced63fde570adc60c43b32e9e14a0ef60c379da5cac1be0000845992ea072ead036e336c7b859e8d018c4ef61667e3f520fe01"
-
-// TestDecryptingReaderLegacyBackup verifies that backup files created by
-// older versions can still be decrypted.
-func TestDecryptingReaderLegacyBackup(t *testing.T) {
-	encrypted, err := hex.DecodeString(legacyBackupFixture)
-	require.NoError(t, err)
+// TestRestoreBackupRefusesScheduledChannelState verifies that the channel state
+// is not restored from a scheduled backup, which may be outdated.
+func TestRestoreBackupRefusesScheduledChannelState(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
 
-	cr, err := decryptingReader(bytes.NewReader(encrypted), "test-unlock-password")
+	gormDB, err := test_db.NewDB(t)
 	require.NoError(t, err)
+	defer test_db.CloseDB(gormDB)
 
-	decrypted, err := io.ReadAll(cr)
-	require.NoError(t, err)
+	if gormDB.Dialector.Name() != "sqlite" {
+		t.Skip("restore is only supported on sqlite")
+	}
 
-	zr, err := zip.NewReader(bytes.NewReader(decrypted), int64(len(decrypted)))
+	workDir := t.TempDir()
+	cfg, err := config.NewConfig(&config.AppConfig{
+		Workdir:     workDir,
+		DatabaseUri: test_db.GetTestDatabaseURI(),
+	}, gormDB)
 	require.NoError(t, err)
 
-	dbFile, err := zr.Open("nwc.db")
-	require.NoError(t, err)
-	dbContents, err := io.ReadAll(dbFile)
-	require.NoError(t, err)
-	require.NoError(t, dbFile.Close())
-	require.Equal(t, "legacy backup contents", string(dbContents))
-}
+	theAPI := &api{
+		db:       gormDB,
+		cfg:      cfg,
+		auditSvc: audit.NewAuditService(gormDB, t.TempDir()),
+	}
 
-// TestDecryptingReaderFragmentedReader verifies that a backup file is
-// decrypted correctly even when the reader delivers one byte at a time,
-// which would truncate the header if it were not read in full.
-func TestDecryptingReaderFragmentedReader(t *testing.T) {
 	var buf bytes.Buffer
-	cw, err := encryptingWriter(&buf, "test-unlock-password")
+	cw, err := backup.EncryptingWriter(&buf, "")
 	require.NoError(t, err)
-
 	zw := zip.NewWriter(cw)
-	entryWriter, err := zw.Create("nwc.db")
-	require.NoError(t, err)
-	_, err = entryWriter.Write([]byte("backup contents"))
-	require.NoError(t, err)
+	for entryName, contents := range map[string]string{
+		"nwc.db":                           "backup contents",
+		backup.ManifestFileName:            `{"scheduled":true,"vssEnabled":false}`,
+		"ldk/storage/ldk_node_data.sqlite": "channel state",
+	} {
+		entryWriter, err := zw.Create(entryName)
+		require.NoError(t, err)
+		_, err = entryWriter.Write([]byte(contents))
+		require.NoError(t, err)
+	}
 	require.NoError(t, zw.Close())
 
-	cr, err := decryptingReader(iotest.OneByteReader(bytes.NewReader(buf.Bytes())), "test-unlock-password")
-	require.NoError(t, err)
-
-	decrypted, err := io.ReadAll(cr)
-	require.NoError(t, err)
-
-	zr, err := zip.NewReader(bytes.NewReader(decrypted), int64(len(decrypted)))
-	require.NoError(t, err)
-
-	dbFile, err := zr.Open("nwc.db")
-	require.NoError(t, err)
-	dbContents, err := io.ReadAll(dbFile)
-	require.NoError(t, err)
-	require.NoError(t, dbFile.Close())
-	require.Equal(t, "backup contents", string(dbContents))
-}
-
-// TestDecryptingReaderWrongPassword verifies that decryption fails upfront
-// when the password does not match the backup file.
-func TestDecryptingReaderWrongPassword(t *testing.T) {
-	var buf bytes.Buffer
-	cw, err := encryptingWriter(&buf, "test-unlock-password")
-	require.NoError(t, err)
-
-	zw := zip.NewWriter(cw)
-	entryWriter, err := zw.Create("nwc.db")
-	require.NoError(t, err)
-	_, err = entryWriter.Write([]byte("backup contents"))
-	require.NoError(t, err)
-	require.NoError(t, zw.Close())
+	err = theAPI.RestoreBackup(context.TODO(), "", &buf)
+	require.ErrorContains(t, err, "refusing to restore channel state from a scheduled backup")
 
-	_, err = decryptingReader(bytes.NewReader(buf.Bytes()), "wrong-password")
-	require.Error(t, err)
+	_, statErr := os.Stat(filepath.Join(workDir, "restore"))
+	require.True(t, os.IsNotExist(statErr), "refused restore must not leave a restore directory")
 }
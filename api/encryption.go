@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+
+	"github.com/getAlby/hub/config"
+)
+
+func (api *api) GetEncryptionStatus() (*config.EncryptionStatus, error) {
+	return api.cfg.GetEncryptionStatus()
+}
+
+func (api *api) UpgradeKDF(ctx context.Context, upgradeKDFRequest *UpgradeKDFRequest) (err error) {
+	params := config.KDFParams{
+		Time:      upgradeKDFRequest.Time,
+		MemoryKiB: upgradeKDFRequest.MemoryKiB,
+		Threads:   upgradeKDFRequest.Threads,
+	}
+	defer func() {
+		api.auditSvc.Record(ctx, "upgrade_kdf", params, err)
+	}()
+
+	return api.cfg.UpgradeKDF(upgradeKDFRequest.UnlockPassword, params)
+}
+
+func (api *api) RotateDataKey(ctx context.Context, rotateDataKeyRequest *RotateDataKeyRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "rotate_data_key", nil, err)
+	}()
+
+	return api.cfg.RotateDataKey(rotateDataKeyRequest.UnlockPassword)
+}
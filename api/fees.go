@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/fees"
+)
+
+var errFeeManagerNotStarted = errors.New("fee manager not started")
+
+func (api *api) ListFeePolicies() ([]FeePolicy, error) {
+	var dbFeePolicies []db.FeePolicy
+	if err := api.db.Order("channel_id").Find(&dbFeePolicies).Error; err != nil {
+		return nil, err
+	}
+
+	feePolicies := []FeePolicy{}
+	for i := range dbFeePolicies {
+		feePolicies = append(feePolicies, *toFeePolicy(&dbFeePolicies[i]))
+	}
+	return feePolicies, nil
+}
+
+// SetFeePolicy creates or replaces the policy of a channel, or the default policy if no channel is given
+func (api *api) SetFeePolicy(ctx context.Context, setFeePolicyRequest *SetFeePolicyRequest) (_ *FeePolicy, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "set_fee_policy", setFeePolicyRequest, err)
+	}()
+
+	feePolicy := db.FeePolicy{
+		ChannelId:           setFeePolicyRequest.ChannelId,
+		Type:                setFeePolicyRequest.Type,
+		BaseFeeMsat:         setFeePolicyRequest.BaseFeeMsat,
+		FeePpm:              setFeePolicyRequest.FeePpm,
+		MinFeePpm:           setFeePolicyRequest.MinFeePpm,
+		MaxFeePpm:           setFeePolicyRequest.MaxFeePpm,
+		StepPpm:             setFeePolicyRequest.StepPpm,
+		DemandWindowHours:   setFeePolicyRequest.DemandWindowHours,
+		DemandThresholdMsat: setFeePolicyRequest.DemandThresholdMsat,
+	}
+	if err := fees.ValidatePolicy(&feePolicy); err != nil {
+		return nil, err
+	}
+
+	var existingFeePolicy db.FeePolicy
+	result := api.db.Limit(1).Find(&existingFeePolicy, "channel_id = ?", feePolicy.ChannelId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		feePolicy.ID = existingFeePolicy.ID
+		feePolicy.CreatedAt = existingFeePolicy.CreatedAt
+	}
+	if err := api.db.Save(&feePolicy).Error; err != nil {
+		return nil, err
+	}
+	return toFeePolicy(&feePolicy), nil
+}
+
+func (api *api) DeleteFeePolicy(ctx context.Context, id uint) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "delete_fee_policy", map[string]interface{}{"id": id}, err)
+	}()
+
+	result := api.db.Delete(&db.FeePolicy{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (api *api) ListFeeChanges(limit uint64, offset uint64) (*ListFeeChangesResponse, error) {
+	var totalCount int64
+	if err := api.db.Model(&db.FeeChange{}).Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	var dbFeeChanges []db.FeeChange
+	query := api.db.Order("created_at desc, id desc").Offset(int(offset))
+	if limit > 0 {
+		query = query.Limit(int(limit))
+	}
+	if err := query.Find(&dbFeeChanges).Error; err != nil {
+		return nil, err
+	}
+
+	return &ListFeeChangesResponse{
+		TotalCount: uint64(totalCount),
+		Changes:    toFeeChanges(dbFeeChanges),
+	}, nil
+}
+
+func (api *api) GetFeeManagerStatus() (*fees.Status, error) {
+	feeManager := api.svc.GetFeeManager()
+	if feeManager == nil {
+		return nil, errFeeManagerNotStarted
+	}
+	return feeManager.GetStatus(), nil
+}
+
+func (api *api) PreviewFeeChanges(ctx context.Context) ([]FeeChange, error) {
+	feeManager := api.svc.GetFeeManager()
+	if feeManager == nil {
+		return nil, errFeeManagerNotStarted
+	}
+	dbFeeChanges, err := feeManager.Preview(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toFeeChanges(dbFeeChanges), nil
+}
+
+func (api *api) RunFeeManager(ctx context.Context) (_ []FeeChange, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "run_fee_manager", nil, err)
+	}()
+
+	feeManager := api.svc.GetFeeManager()
+	if feeManager == nil {
+		return nil, errFeeManagerNotStarted
+	}
+	dbFeeChanges, err := feeManager.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toFeeChanges(dbFeeChanges), nil
+}
+
+func toFeePolicy(dbFeePolicy *db.FeePolicy) *FeePolicy {
+	return &FeePolicy{
+		ID:                  dbFeePolicy.ID,
+		ChannelId:           dbFeePolicy.ChannelId,
+		Type:                dbFeePolicy.Type,
+		BaseFeeMsat:         dbFeePolicy.BaseFeeMsat,
+		FeePpm:              dbFeePolicy.FeePpm,
+		MinFeePpm:           dbFeePolicy.MinFeePpm,
+		MaxFeePpm:           dbFeePolicy.MaxFeePpm,
+		StepPpm:             dbFeePolicy.StepPpm,
+		DemandWindowHours:   dbFeePolicy.DemandWindowHours,
+		DemandThresholdMsat: dbFeePolicy.DemandThresholdMsat,
+		CreatedAt:           dbFeePolicy.CreatedAt,
+		UpdatedAt:           dbFeePolicy.UpdatedAt,
+	}
+}
+
+func toFeeChanges(dbFeeChanges []db.FeeChange) []FeeChange {
+	feeChanges := []FeeChange{}
+	for _, dbFeeChange := range dbFeeChanges {
+		feeChanges = append(feeChanges, FeeChange{
+			ID:             dbFeeChange.ID,
+			ChannelId:      dbFeeChange.ChannelId,
+			PeerPubkey:     dbFeeChange.PeerPubkey,
+			FeePolicyId:    dbFeeChange.FeePolicyId,
+			PolicyType:     dbFeeChange.PolicyType,
+			OldBaseFeeMsat: dbFeeChange.OldBaseFeeMsat,
+			NewBaseFeeMsat: dbFeeChange.NewBaseFeeMsat,
+			OldFeePpm:      dbFeeChange.OldFeePpm,
+			NewFeePpm:      dbFeeChange.NewFeePpm,
+			Reason:         dbFeeChange.Reason,
+			DryRun:         dbFeeChange.DryRun,
+			Error:          dbFeeChange.Error,
+			CreatedAt:      dbFeeChange.CreatedAt,
+		})
+	}
+	return feeChanges
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/getAlby/hub/db/queries"
+)
+
+// ParseRoutingAnalyticsRange parses the from and until unix timestamp query
+// parameters shared by the HTTP and Wails transports. Missing values are 0.
+func ParseRoutingAnalyticsRange(query url.Values) (from uint64, until uint64, err error) {
+	for _, param := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"from", &from},
+		{"until", &until},
+	} {
+		if paramValue := query.Get(param.name); paramValue != "" {
+			parsedValue, err := strconv.ParseUint(paramValue, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid %s: %s", param.name, paramValue)
+			}
+			*param.value = parsedValue
+		}
+	}
+	if from > 0 && until > 0 && until <= from {
+		return 0, 0, fmt.Errorf("until must be after from")
+	}
+	return from, until, nil
+}
+
+func (api *api) GetRoutingAnalytics(from uint64, until uint64) (*GetRoutingAnalyticsResponse, error) {
+	var fromTime, untilTime time.Time
+	if from > 0 {
+		fromTime = time.Unix(int64(from), 0)
+	}
+	if until > 0 {
+		untilTime = time.Unix(int64(until), 0)
+	}
+
+	totals, err := queries.GetForwardStats(api.db, fromTime, untilTime)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := map[string]*ChannelRoutingStats{}
+	getChannel := func(stats queries.ForwardStats) *ChannelRoutingStats {
+		channel, ok := channels[stats.ChannelId]
+		if !ok {
+			channel = &ChannelRoutingStats{ChannelId: stats.ChannelId}
+			channels[stats.ChannelId] = channel
+		}
+		if channel.PeerPubkey == "" {
+			channel.PeerPubkey = stats.PeerPubkey
+		}
+		return channel
+	}
+
+	peers := map[string]*PeerRoutingStats{}
+	getPeer := func(stats queries.ForwardStats) *PeerRoutingStats {
+		peer, ok := peers[stats.PeerPubkey]
+		if !ok {
+			peer = &PeerRoutingStats{PeerPubkey: stats.PeerPubkey}
+			peers[stats.PeerPubkey] = peer
+		}
+		return peer
+	}
+
+	incomingChannelStats, err := queries.GetForwardStatsByChannel(api.db, fromTime, untilTime, queries.FORWARD_DIRECTION_INCOMING)
+	if err != nil {
+		return nil, err
+	}
+	for _, stats := range incomingChannelStats {
+		channel := getChannel(stats)
+		channel.NumForwardsIn += stats.NumForwards
+		channel.NumFailedForwardsIn += stats.NumFailedForwards
+		channel.InboundAmountMsat += stats.InboundAmountMsat
+	}
+
+	outgoingChannelStats, err := queries.GetForwardStatsByChannel(api.db, fromTime, untilTime, queries.FORWARD_DIRECTION_OUTGOING)
+	if err != nil {
+		return nil, err
+	}
+	for _, stats := range outgoingChannelStats {
+		channel := getChannel(stats)
+		channel.NumForwardsOut += stats.NumForwards
+		channel.NumFailedForwardsOut += stats.NumFailedForwards
+		channel.OutboundAmountMsat += stats.OutboundAmountMsat
+		// fees are earned by the channel the payment left through
+		channel.FeeEarnedMsat += stats.FeeEarnedMsat
+	}
+
+	incomingPeerStats, err := queries.GetForwardStatsByPeer(api.db, fromTime, untilTime, queries.FORWARD_DIRECTION_INCOMING)
+	if err != nil {
+		return nil, err
+	}
+	for _, stats := range incomingPeerStats {
+		peer := getPeer(stats)
+		peer.NumForwardsIn += stats.NumForwards
+		peer.NumFailedForwardsIn += stats.NumFailedForwards
+		peer.InboundAmountMsat += stats.InboundAmountMsat
+	}
+
+	outgoingPeerStats, err := queries.GetForwardStatsByPeer(api.db, fromTime, untilTime, queries.FORWARD_DIRECTION_OUTGOING)
+	if err != nil {
+		return nil, err
+	}
+	for _, stats := range outgoingPeerStats {
+		peer := getPeer(stats)
+		peer.NumForwardsOut += stats.NumForwards
+		peer.NumFailedForwardsOut += stats.NumFailedForwards
+		peer.OutboundAmountMsat += stats.OutboundAmountMsat
+		peer.FeeEarnedMsat += stats.FeeEarnedMsat
+	}
+
+	response := &GetRoutingAnalyticsResponse{
+		From:  from,
+		Until: until,
+		Totals: RoutingStats{
+			NumForwards:        totals.NumForwards,
+			NumFailedForwards:  totals.NumFailedForwards,
+			InboundAmountMsat:  totals.InboundAmountMsat,
+			OutboundAmountMsat: totals.OutboundAmountMsat,
+			FeeEarnedMsat:      totals.FeeEarnedMsat,
+		},
+		Channels: []ChannelRoutingStats{},
+		Peers:    []PeerRoutingStats{},
+	}
+	for _, channel := range channels {
+		response.Channels = append(response.Channels, *channel)
+	}
+	for _, peer := range peers {
+		response.Peers = append(response.Peers, *peer)
+	}
+	// highest earners first
+	sort.SliceStable(response.Channels, func(i, j int) bool {
+		if response.Channels[i].FeeEarnedMsat != response.Channels[j].FeeEarnedMsat {
+			return response.Channels[i].FeeEarnedMsat > response.Channels[j].FeeEarnedMsat
+		}
+		return response.Channels[i].ChannelId < response.Channels[j].ChannelId
+	})
+	sort.SliceStable(response.Peers, func(i, j int) bool {
+		if response.Peers[i].FeeEarnedMsat != response.Peers[j].FeeEarnedMsat {
+			return response.Peers[i].FeeEarnedMsat > response.Peers[j].FeeEarnedMsat
+		}
+		return response.Peers[i].PeerPubkey < response.Peers[j].PeerPubkey
+	})
+
+	return response, nil
+}
@@ -2,20 +2,27 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"time"
 
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/backup"
+	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/fees"
+	"github.com/getAlby/hub/rebalance"
 	"github.com/getAlby/hub/swaps"
+	"github.com/getAlby/hub/totp"
 )
 
 type API interface {
-	CreateApp(createAppRequest *CreateAppRequest) (*CreateAppResponse, error)
-	UpdateApp(app *db.App, updateAppRequest *UpdateAppRequest) error
+	CreateApp(ctx context.Context, createAppRequest *CreateAppRequest) (*CreateAppResponse, error)
+	UpdateApp(ctx context.Context, app *db.App, updateAppRequest *UpdateAppRequest) error
 	Transfer(ctx context.Context, fromAppId *uint, toAppId *uint, amountMsat uint64, description string) error
-	DeleteApp(app *db.App) error
+	DeleteApp(ctx context.Context, app *db.App, totpCode string) error
 	GetApp(app *db.App) (*App, error)
 	ListApps(limit uint64, offset uint64, filters ListAppsFilters, orderBy string) (*ListAppsResponse, error)
 	CreateLightningAddress(ctx context.Context, createLightningAddressRequest *CreateLightningAddressRequest) error
@@ -24,9 +31,9 @@ type API interface {
 	GetChannelPeerSuggestions(ctx context.Context) ([]alby.ChannelPeerSuggestion, error)
 	GetStories(ctx context.Context) ([]alby.Story, error)
 	GetLSPChannelOffer(ctx context.Context) (*alby.LSPChannelOffer, error)
-	ResetRouter(key string) error
-	ChangeUnlockPassword(changeUnlockPasswordRequest *ChangeUnlockPasswordRequest) error
-	SetAutoUnlockPassword(unlockPassword string) error
+	ResetRouter(ctx context.Context, key string) error
+	ChangeUnlockPassword(ctx context.Context, changeUnlockPasswordRequest *ChangeUnlockPasswordRequest) error
+	SetAutoUnlockPassword(ctx context.Context, unlockPassword string) error
 	Stop() error
 	GetNodeConnectionInfo(ctx context.Context) (*NodeConnectionInfo, error)
 	GetNodeStatus(ctx context.Context) (*NodeStatus, error)
@@ -41,17 +48,22 @@ type API interface {
 	GetNewOnchainAddress(ctx context.Context) (string, error)
 	GetUnusedOnchainAddress(ctx context.Context) (string, error)
 	SignMessage(ctx context.Context, message string) (*SignMessageResponse, error)
-	RedeemOnchainFunds(ctx context.Context, toAddress string, amountSat uint64, feeRate *uint64, sendAll bool) (*RedeemOnchainFundsResponse, error)
+	RedeemOnchainFunds(ctx context.Context, toAddress string, amountSat uint64, feeRate *uint64, sendAll bool, totpCode string) (*RedeemOnchainFundsResponse, error)
 	GetBalances(ctx context.Context) (*BalancesResponse, error)
 	ListTransactions(ctx context.Context, appId *uint, limit uint64, offset uint64, filters ListTransactionsFilters) (*ListTransactionsResponse, error)
 	ListOnchainTransactions(ctx context.Context) ([]OnchainTransaction, error)
+	ListActivity(ctx context.Context, limit uint64, offset uint64, filters ListActivityFilters) (*ListActivityResponse, error)
 	SendPayment(ctx context.Context, invoice string, amountMsat *uint64, metadata map[string]interface{}, fromAppId *uint) (*SendPaymentResponse, error)
+	PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string, metadata map[string]interface{}, fromAppId *uint) (*SendPaymentResponse, error)
 	CreateInvoice(ctx context.Context, amountMsat uint64, description string, toAppId *uint) (*MakeInvoiceResponse, error)
 	LookupInvoice(ctx context.Context, paymentHash string) (*LookupInvoiceResponse, error)
 	SetTransactionUserLabels(ctx context.Context, id uint, labels map[string]string) error
+	ListPaymentApprovals(ctx context.Context, state string) ([]PaymentApproval, error)
+	ApprovePayment(ctx context.Context, id uint) (*SendPaymentResponse, error)
+	RejectPayment(ctx context.Context, id uint) error
 	RequestMempoolApi(ctx context.Context, endpoint string) (interface{}, error)
 	GetInfo(ctx context.Context) (*InfoResponse, error)
-	GetMnemonic(unlockPassword string) (*MnemonicResponse, error)
+	GetMnemonic(unlockPassword string, totpCode string) (*MnemonicResponse, error)
 	SetNextBackupReminder(backupReminderRequest *BackupReminderRequest) error
 	Start(startRequest *StartRequest)
 	Setup(ctx context.Context, setupRequest *SetupRequest) error
@@ -59,57 +71,110 @@ type API interface {
 	SyncWallet() error
 	GetLogOutput(ctx context.Context, logType string, getLogRequest *GetLogOutputRequest) (*GetLogOutputResponse, error)
 	RequestLSPOrder(ctx context.Context, request *LSPOrderRequest) (*LSPOrderResponse, error)
-	CreateBackup(unlockPassword string, w io.Writer) error
-	RestoreBackup(unlockPassword string, r io.Reader) error
+	CreateBackup(unlockPassword string, totpCode string, w io.Writer) error
+	RestoreBackup(ctx context.Context, unlockPassword string, r io.Reader) error
+	VerifyBackup(unlockPassword string, r io.Reader) (*backup.VerificationResult, error)
 	MigrateNodeStorage(ctx context.Context, to string) error
 	GetWalletCapabilities(ctx context.Context) (*WalletCapabilitiesResponse, error)
 	Health(ctx context.Context) (*HealthResponse, error)
-	UpdateSettings(updateSettingsRequest *UpdateSettingsRequest) error
+	UpdateSettings(ctx context.Context, updateSettingsRequest *UpdateSettingsRequest) error
 	LookupSwap(swapId string) (*LookupSwapResponse, error)
 	ListSwaps() (*ListSwapsResponse, error)
 	GetSwapInInfo() (*SwapInfoResponse, error)
 	GetSwapOutInfo() (*SwapInfoResponse, error)
+	GetSwapInQuote(amountSat uint64) (*swaps.SwapQuote, error)
+	GetSwapOutQuote(amountSat uint64) (*swaps.SwapQuote, error)
 	InitiateSwapIn(ctx context.Context, initiateSwapInRequest *InitiateSwapRequest) (*swaps.SwapResponse, error)
 	InitiateSwapOut(ctx context.Context, initiateSwapOutRequest *InitiateSwapRequest) (*swaps.SwapResponse, error)
-	RefundSwap(refundSwapRequest *RefundSwapRequest) error
+	RefundSwap(ctx context.Context, refundSwapRequest *RefundSwapRequest) error
 	GetSwapMnemonic() string
 	GetAutoSwapConfig() (*GetAutoSwapConfigResponse, error)
 	EnableAutoSwapOut(ctx context.Context, autoSwapRequest *EnableAutoSwapRequest) error
-	DisableAutoSwap() error
-	SetNodeAlias(nodeAlias string) error
+	DisableAutoSwap(ctx context.Context) error
+	GetAutoSwapInConfig() (*GetAutoSwapInConfigResponse, error)
+	EnableAutoSwapIn(ctx context.Context, enableAutoSwapInRequest *EnableAutoSwapInRequest) error
+	DisableAutoSwapIn(ctx context.Context) error
+	SetNodeAlias(ctx context.Context, nodeAlias string) error
 	GetCustomNodeCommands() (*CustomNodeCommandsResponse, error)
 	ExecuteCustomNodeCommand(ctx context.Context, command string) (interface{}, error)
 	SendEvent(event string, properties interface{})
 	GetForwards() (*GetForwardsResponse, error)
+	GetRoutingAnalytics(from uint64, until uint64) (*GetRoutingAnalyticsResponse, error)
+	ListFeePolicies() ([]FeePolicy, error)
+	SetFeePolicy(ctx context.Context, setFeePolicyRequest *SetFeePolicyRequest) (*FeePolicy, error)
+	DeleteFeePolicy(ctx context.Context, id uint) error
+	ListFeeChanges(limit uint64, offset uint64) (*ListFeeChangesResponse, error)
+	GetFeeManagerStatus() (*fees.Status, error)
+	PreviewFeeChanges(ctx context.Context) ([]FeeChange, error)
+	RunFeeManager(ctx context.Context) ([]FeeChange, error)
+	CircularRebalance(ctx context.Context, circularRebalanceRequest *CircularRebalanceRequest) (*rebalance.Result, error)
+	GetRebalancerStatus() (*rebalance.Status, error)
+	PreviewRebalances(ctx context.Context) ([]rebalance.Rebalance, error)
+	RunRebalancer(ctx context.Context) ([]rebalance.Result, error)
+	ListApiTokens() ([]ApiToken, error)
+	CreateApiToken(ctx context.Context, createApiTokenRequest *CreateApiTokenRequest) (*CreateApiTokenResponse, error)
+	DeleteApiToken(ctx context.Context, id uint) error
+	ListUsers() ([]HubUser, error)
+	CreateUser(ctx context.Context, createUserRequest *CreateUserRequest) (*HubUser, error)
+	UpdateUser(ctx context.Context, id uint, updateUserRequest *UpdateUserRequest) (*HubUser, error)
+	DeleteUser(ctx context.Context, id uint) error
+	ListAuditEvents(limit uint64, offset uint64, filters ListAuditEventsFilters) (*ListAuditEventsResponse, error)
+	VerifyAuditLog() (*audit.VerifyResult, error)
+	GetTOTPStatus() (*totp.Status, error)
+	BeginTOTPEnrollment(ctx context.Context, beginTOTPEnrollmentRequest *BeginTOTPEnrollmentRequest) (*totp.Enrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, totpRequest *TOTPRequest) (*TOTPRecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, totpRequest *TOTPRequest) error
+	RegenerateTOTPRecoveryCodes(ctx context.Context, totpRequest *TOTPRequest) (*TOTPRecoveryCodesResponse, error)
+	VerifyUnlockTOTP(unlockPassword string, totpCode string) error
+	GetEncryptionStatus() (*config.EncryptionStatus, error)
+	UpgradeKDF(ctx context.Context, upgradeKDFRequest *UpgradeKDFRequest) error
+	RotateDataKey(ctx context.Context, rotateDataKeyRequest *RotateDataKeyRequest) error
 }
 
 var ErrLNClientNotStarted = errors.New("LNClient not started")
 
 type App struct {
-	ID                       uint       `json:"id"`
-	Name                     string     `json:"name"`
-	Description              string     `json:"description"`
-	AppPubkey                string     `json:"appPubkey"`
-	CreatedAt                time.Time  `json:"createdAt"`
-	UpdatedAt                time.Time  `json:"updatedAt"`
-	LastUsedAt               *time.Time `json:"lastUsedAt"`
-	LastSettledTransactionAt *time.Time `json:"lastSettledTransactionAt"`
-	ExpiresAt                *time.Time `json:"expiresAt"`
-	Scopes                   []string   `json:"scopes"`
-	MaxAmount                uint64     `json:"maxAmount"` // deprecated
-	MaxAmountSat             uint64     `json:"maxAmountSat"`
-	MaxAmountMsat            uint64     `json:"maxAmountMsat"`
-	BudgetUsage              uint64     `json:"budgetUsage"` // deprecated
-	BudgetUsageSat           uint64     `json:"budgetUsageSat"`
-	BudgetUsageMsat          uint64     `json:"budgetUsageMsat"`
-	BudgetRenewal            string     `json:"budgetRenewal"`
-	Isolated                 bool       `json:"isolated"`
-	WalletPubkey             string     `json:"walletPubkey"`
-	UniqueWalletPubkey       bool       `json:"uniqueWalletPubkey"`
-	Balance                  int64      `json:"balance"` // deprecated
-	BalanceSat               int64      `json:"balanceSat"`
-	BalanceMsat              int64      `json:"balanceMsat"`
-	Metadata                 Metadata   `json:"metadata,omitempty"`
+	ID                       uint               `json:"id"`
+	Name                     string             `json:"name"`
+	Description              string             `json:"description"`
+	AppPubkey                string             `json:"appPubkey"`
+	CreatedAt                time.Time          `json:"createdAt"`
+	UpdatedAt                time.Time          `json:"updatedAt"`
+	LastUsedAt               *time.Time         `json:"lastUsedAt"`
+	LastSettledTransactionAt *time.Time         `json:"lastSettledTransactionAt"`
+	RequestCount             uint64             `json:"requestCount"`
+	RateLimitedCount         uint64             `json:"rateLimitedCount"`
+	LastRateLimitedAt        *time.Time         `json:"lastRateLimitedAt"`
+	ExpiresAt                *time.Time         `json:"expiresAt"`
+	Scopes                   []string           `json:"scopes"`
+	MaxAmount                uint64             `json:"maxAmount"` // deprecated
+	MaxAmountSat             uint64             `json:"maxAmountSat"`
+	MaxAmountMsat            uint64             `json:"maxAmountMsat"`
+	BudgetUsage              uint64             `json:"budgetUsage"` // deprecated
+	BudgetUsageSat           uint64             `json:"budgetUsageSat"`
+	BudgetUsageMsat          uint64             `json:"budgetUsageMsat"`
+	BudgetRenewal            string             `json:"budgetRenewal"`
+	BudgetRenewalPeriodHours uint               `json:"budgetRenewalPeriodHours,omitempty"`
+	BudgetTimezone           string             `json:"budgetTimezone,omitempty"`
+	Isolated                 bool               `json:"isolated"`
+	WalletPubkey             string             `json:"walletPubkey"`
+	UniqueWalletPubkey       bool               `json:"uniqueWalletPubkey"`
+	Balance                  int64              `json:"balance"` // deprecated
+	BalanceSat               int64              `json:"balanceSat"`
+	BalanceMsat              int64              `json:"balanceMsat"`
+	Metadata                 Metadata           `json:"metadata,omitempty"`
+	SpendingPolicy           *AppSpendingPolicy `json:"spendingPolicy,omitempty"`
+}
+
+type AppSpendingPolicy struct {
+	MaxAmountPerPaymentSat uint64   `json:"maxAmountPerPaymentSat"`
+	MaxPaymentsPerMinute   uint     `json:"maxPaymentsPerMinute"`
+	MaxPaymentsPerHour     uint     `json:"maxPaymentsPerHour"`
+	AllowedDestinations    []string `json:"allowedDestinations"`
+	BlockedDestinations    []string `json:"blockedDestinations"`
+	RequireDescription     bool     `json:"requireDescription"`
+	ApprovalRequired       bool     `json:"approvalRequired"`
+	ApprovalThresholdSat   uint64   `json:"approvalThresholdSat"`
 }
 
 type ListAppsFilters struct {
@@ -128,16 +193,19 @@ type ListAppsResponse struct {
 }
 
 type UpdateAppRequest struct {
-	Name            *string   `json:"name"`
-	MaxAmount       *uint64   `json:"maxAmount"` // deprecated
-	MaxAmountSat    *uint64   `json:"maxAmountSat"`
-	MaxAmountMsat   *uint64   `json:"maxAmountMsat"`
-	BudgetRenewal   *string   `json:"budgetRenewal"`
-	ExpiresAt       *string   `json:"expiresAt"`
-	UpdateExpiresAt bool      `json:"updateExpiresAt"`
-	Scopes          []string  `json:"scopes"`
-	Metadata        *Metadata `json:"metadata"`
-	Isolated        *bool     `json:"isolated"`
+	Name                     *string            `json:"name"`
+	MaxAmount                *uint64            `json:"maxAmount"` // deprecated
+	MaxAmountSat             *uint64            `json:"maxAmountSat"`
+	MaxAmountMsat            *uint64            `json:"maxAmountMsat"`
+	BudgetRenewal            *string            `json:"budgetRenewal"`
+	BudgetRenewalPeriodHours *uint              `json:"budgetRenewalPeriodHours"` // only used for rolling_custom budgets
+	BudgetTimezone           *string            `json:"budgetTimezone"`           // IANA timezone e.g. "Europe/Berlin"
+	ExpiresAt                *string            `json:"expiresAt"`
+	UpdateExpiresAt          bool               `json:"updateExpiresAt"`
+	Scopes                   []string           `json:"scopes"`
+	Metadata                 *Metadata          `json:"metadata"`
+	Isolated                 *bool              `json:"isolated"`
+	SpendingPolicy           *AppSpendingPolicy `json:"spendingPolicy"` // an empty policy removes the existing policy
 }
 
 type TransferRequest struct {
@@ -172,6 +240,8 @@ type InitiateSwapRequest struct {
 	SwapAmount    *uint64 `json:"swapAmount"` // deprecated
 	SwapAmountSat *uint64 `json:"swapAmountSat"`
 	Destination   string  `json:"destination"`
+	QuoteId       string  `json:"quoteId"`
+	MaxFeeSat     *uint64 `json:"maxFeeSat"`
 }
 
 type RefundSwapRequest struct {
@@ -187,16 +257,33 @@ type EnableAutoSwapRequest struct {
 	Destination         string  `json:"destination"`
 	DestinationType     string  `json:"destinationType"`
 	UnlockPassword      string  `json:"unlockPassword"`
+	MaxFeeSat           *uint64 `json:"maxFeeSat"`
 }
 
 type GetAutoSwapConfigResponse struct {
-	Type                string `json:"type"`
-	Enabled             bool   `json:"enabled"`
-	BalanceThreshold    uint64 `json:"balanceThreshold"` // deprecated
-	BalanceThresholdSat uint64 `json:"balanceThresholdSat"`
-	SwapAmount          uint64 `json:"swapAmount"` // deprecated
-	SwapAmountSat       uint64 `json:"swapAmountSat"`
-	Destination         string `json:"destination"`
+	Type                string  `json:"type"`
+	Enabled             bool    `json:"enabled"`
+	BalanceThreshold    uint64  `json:"balanceThreshold"` // deprecated
+	BalanceThresholdSat uint64  `json:"balanceThresholdSat"`
+	SwapAmount          uint64  `json:"swapAmount"` // deprecated
+	SwapAmountSat       uint64  `json:"swapAmountSat"`
+	Destination         string  `json:"destination"`
+	MaxFeeSat           *uint64 `json:"maxFeeSat"`
+}
+
+type EnableAutoSwapInRequest struct {
+	BalanceThresholdSat uint64   `json:"balanceThresholdSat"`
+	SwapAmountSat       uint64   `json:"swapAmountSat"`
+	MaxFeePercentage    *float64 `json:"maxFeePercentage"`
+	CooldownHours       *uint64  `json:"cooldownHours"`
+}
+
+type GetAutoSwapInConfigResponse struct {
+	Enabled             bool    `json:"enabled"`
+	BalanceThresholdSat uint64  `json:"balanceThresholdSat"`
+	SwapAmountSat       uint64  `json:"swapAmountSat"`
+	MaxFeePercentage    float64 `json:"maxFeePercentage"`
+	CooldownHours       uint64  `json:"cooldownHours"`
 }
 
 type SwapInfoResponse struct {
@@ -240,10 +327,12 @@ type Swap struct {
 
 type StartRequest struct {
 	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
 }
 
 type UnlockRequest struct {
 	UnlockPassword  string  `json:"unlockPassword"`
+	TotpCode        string  `json:"totpCode"`
 	TokenExpiryDays *uint64 `json:"tokenExpiryDays"`
 	Permission      string  `json:"permission,omitempty"` // "full" or "readonly"
 }
@@ -339,6 +428,7 @@ type InfoResponse struct {
 	HideUpdateBanner              bool                `json:"hideUpdateBanner"`
 	SupportsBolt12                bool                `json:"supportsBolt12"`
 	NodeMigrationFileCreated      bool                `json:"nodeMigrationFileCreated"`
+	TOTPEnabled                   bool                `json:"totpEnabled"`
 }
 
 type UpdateSettingsRequest struct {
@@ -353,6 +443,7 @@ type SetNodeAliasRequest struct {
 
 type MnemonicRequest struct {
 	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
 }
 
 type MnemonicResponse struct {
@@ -440,12 +531,20 @@ type RebalanceChannelResponse struct {
 	TotalFeeMsat uint64 `json:"totalFeeMsat"`
 }
 
+type CircularRebalanceRequest struct {
+	SourceChannelId string  `json:"sourceChannelId"`
+	TargetChannelId string  `json:"targetChannelId"`
+	AmountSat       uint64  `json:"amountSat"`
+	MaxFeePpm       *uint64 `json:"maxFeePpm"`
+}
+
 type RedeemOnchainFundsRequest struct {
 	ToAddress string  `json:"toAddress"`
 	Amount    *uint64 `json:"amount"` // deprecated
 	AmountSat *uint64 `json:"amountSat"`
 	FeeRate   *uint64 `json:"feeRate"`
 	SendAll   bool    `json:"sendAll"`
+	TotpCode  string  `json:"totpCode"`
 }
 
 type RedeemOnchainFundsResponse struct {
@@ -496,6 +595,96 @@ type SendPaymentResponse = Transaction
 type MakeInvoiceResponse = Transaction
 type LookupInvoiceResponse = Transaction
 
+type PaymentApproval struct {
+	ID             uint       `json:"id"`
+	AppId          uint       `json:"appId"`
+	RequestEventId *uint      `json:"requestEventId"`
+	TransactionId  *uint      `json:"transactionId"`
+	State          string     `json:"state"`
+	Invoice        string     `json:"invoice"`
+	PaymentHash    string     `json:"paymentHash"`
+	AmountSat      uint64     `json:"amountSat"`
+	AmountMsat     uint64     `json:"amountMsat"`
+	Description    string     `json:"description"`
+	Metadata       Metadata   `json:"metadata,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type ApiToken struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIps []string   `json:"allowedIps"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateApiTokenRequest struct {
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	AllowedIps []string `json:"allowedIps"`
+	ExpiresAt  string   `json:"expiresAt"`
+}
+
+type CreateApiTokenResponse struct {
+	ApiToken
+	Token string `json:"token"` // only returned once
+}
+
+type HubUser struct {
+	ID          uint       `json:"id"`
+	Username    string     `json:"username"`
+	Role        string     `json:"role"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type UpdateUserRequest struct {
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
+
+type AuditEvent struct {
+	ID        uint            `json:"id"`
+	Action    string          `json:"action"`
+	Source    string          `json:"source"`
+	Actor     string          `json:"actor"`
+	Params    json.RawMessage `json:"params"`
+	Result    string          `json:"result"`
+	Error     string          `json:"error,omitempty"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type ListAuditEventsFilters struct {
+	Action string
+	Source string
+	Actor  string
+	From   uint64
+	Until  uint64
+}
+
+type ListAuditEventsResponse struct {
+	TotalCount uint64       `json:"totalCount"`
+	Events     []AuditEvent `json:"events"`
+}
+
+type LoginRequest struct {
+	Username        string  `json:"username"`
+	Password        string  `json:"password"`
+	TokenExpiryDays *uint64 `json:"tokenExpiryDays"`
+}
+
 type SetTransactionUserLabelsRequest struct {
 	Labels map[string]string `json:"labels"`
 }
@@ -505,11 +694,44 @@ type ListTransactionsFilters struct {
 	MinAmountMsat *uint64
 	HideFailed    bool
 	SearchTerm    string
+	Cursor        string
+	ChangedSince  bool
+}
+
+type ListActivityFilters struct {
+	Kinds []string
+	Type  *string
+	From  uint64
+	Until uint64
+}
+
+type ListActivityResponse struct {
+	TotalCount uint64         `json:"totalCount"`
+	Items      []ActivityItem `json:"items"`
+}
+
+// ActivityItem is an entry of the unified activity feed
+type ActivityItem struct {
+	Kind               string       `json:"kind"`
+	Type               string       `json:"type,omitempty"`
+	State              string       `json:"state"`
+	AmountSat          uint64       `json:"amountSat"`
+	AmountMsat         uint64       `json:"amountMsat"`
+	FeesPaidSat        uint64       `json:"feesPaidSat"`
+	FeesPaidMsat       uint64       `json:"feesPaidMsat"`
+	Description        string       `json:"description"`
+	CreatedAt          string       `json:"createdAt"`
+	SettledAt          *string      `json:"settledAt"`
+	TxId               string       `json:"txId,omitempty"`
+	SwapId             string       `json:"swapId,omitempty"`
+	CounterpartyNodeId string       `json:"counterpartyNodeId,omitempty"`
+	Transaction        *Transaction `json:"transaction,omitempty"`
 }
 
 type ListTransactionsResponse struct {
-	TotalCount   uint64        `json:"totalCount"`
+	TotalCount   uint64        `json:"totalCount"` // not counted for cursor-based requests
 	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"nextCursor,omitempty"`
 }
 
 // TODO: camelCase
@@ -535,6 +757,8 @@ type Transaction struct {
 	Metadata        Metadata    `json:"metadata,omitempty"`
 	Boostagram      *Boostagram `json:"boostagram,omitempty"`
 	FailureReason   string      `json:"failureReason"`
+	OnchainAddress  string      `json:"onchainAddress,omitempty"`
+	OnchainTxId     string      `json:"onchainTxId,omitempty"`
 }
 
 type Metadata = map[string]interface{}
@@ -587,6 +811,14 @@ type PayInvoiceRequest struct {
 	FromAppID  *uint    `json:"fromAppId"`
 }
 
+type PayOfferRequest struct {
+	AmountSat  *uint64  `json:"amountSat"`
+	AmountMsat *uint64  `json:"amountMsat"`
+	PayerNote  string   `json:"payerNote"`
+	Metadata   Metadata `json:"metadata"`
+	FromAppID  *uint    `json:"fromAppId"`
+}
+
 type MakeOfferRequest struct {
 	Description string `json:"description"`
 }
@@ -605,6 +837,7 @@ type ResetRouterRequest struct {
 
 type BasicBackupRequest struct {
 	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
 }
 
 type BasicRestoreWailsRequest struct {
@@ -681,6 +914,7 @@ const (
 	HealthAlarmKindChannelsOffline   HealthAlarmKind = "channels_offline"
 	HealthAlarmKindNostrRelayOffline HealthAlarmKind = "nostr_relay_offline"
 	HealthAlarmKindVssNoSubscription HealthAlarmKind = "vss_no_subscription"
+	HealthAlarmKindBackupFailed      HealthAlarmKind = "backup_failed"
 )
 
 type HealthAlarm struct {
@@ -696,7 +930,8 @@ func NewHealthAlarm(kind HealthAlarmKind, rawDetails any) HealthAlarm {
 }
 
 type HealthResponse struct {
-	Alarms []HealthAlarm `json:"alarms,omitempty"`
+	Alarms []HealthAlarm  `json:"alarms,omitempty"`
+	Backup *backup.Status `json:"backup,omitempty"`
 }
 
 type CustomNodeCommandArgDef struct {
@@ -724,6 +959,96 @@ type GetForwardsResponse struct {
 	TotalFeeEarnedSat           uint64 `json:"totalFeeEarnedSat"`
 	TotalFeeEarnedMsat          uint64 `json:"totalFeeEarnedMsat"`
 	NumForwards                 uint64 `json:"numForwards"`
+	NumFailedForwards           uint64 `json:"numFailedForwards"`
+}
+
+type RoutingStats struct {
+	NumForwards        uint64 `json:"numForwards"`
+	NumFailedForwards  uint64 `json:"numFailedForwards"`
+	InboundAmountMsat  uint64 `json:"inboundAmountMsat"`
+	OutboundAmountMsat uint64 `json:"outboundAmountMsat"`
+	FeeEarnedMsat      uint64 `json:"feeEarnedMsat"`
+}
+
+// In counts forwards which arrived through the channel or from the peer,
+// Out counts forwards which left through it. Fees are attributed to the outgoing side.
+type ChannelRoutingStats struct {
+	ChannelId            string `json:"channelId"`
+	PeerPubkey           string `json:"peerPubkey"`
+	NumForwardsIn        uint64 `json:"numForwardsIn"`
+	NumForwardsOut       uint64 `json:"numForwardsOut"`
+	NumFailedForwardsIn  uint64 `json:"numFailedForwardsIn"`
+	NumFailedForwardsOut uint64 `json:"numFailedForwardsOut"`
+	InboundAmountMsat    uint64 `json:"inboundAmountMsat"`
+	OutboundAmountMsat   uint64 `json:"outboundAmountMsat"`
+	FeeEarnedMsat        uint64 `json:"feeEarnedMsat"`
+}
+
+type PeerRoutingStats struct {
+	PeerPubkey           string `json:"peerPubkey"`
+	NumForwardsIn        uint64 `json:"numForwardsIn"`
+	NumForwardsOut       uint64 `json:"numForwardsOut"`
+	NumFailedForwardsIn  uint64 `json:"numFailedForwardsIn"`
+	NumFailedForwardsOut uint64 `json:"numFailedForwardsOut"`
+	InboundAmountMsat    uint64 `json:"inboundAmountMsat"`
+	OutboundAmountMsat   uint64 `json:"outboundAmountMsat"`
+	FeeEarnedMsat        uint64 `json:"feeEarnedMsat"`
+}
+
+type FeePolicy struct {
+	ID                  uint      `json:"id"`
+	ChannelId           string    `json:"channelId"`
+	Type                string    `json:"type"`
+	BaseFeeMsat         uint32    `json:"baseFeeMsat"`
+	FeePpm              uint32    `json:"feePpm"`
+	MinFeePpm           uint32    `json:"minFeePpm"`
+	MaxFeePpm           uint32    `json:"maxFeePpm"`
+	StepPpm             uint32    `json:"stepPpm"`
+	DemandWindowHours   uint32    `json:"demandWindowHours"`
+	DemandThresholdMsat uint64    `json:"demandThresholdMsat"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+type SetFeePolicyRequest struct {
+	ChannelId           string `json:"channelId"` // empty for the default policy
+	Type                string `json:"type"`
+	BaseFeeMsat         uint32 `json:"baseFeeMsat"`
+	FeePpm              uint32 `json:"feePpm"`
+	MinFeePpm           uint32 `json:"minFeePpm"`
+	MaxFeePpm           uint32 `json:"maxFeePpm"`
+	StepPpm             uint32 `json:"stepPpm"`
+	DemandWindowHours   uint32 `json:"demandWindowHours"`
+	DemandThresholdMsat uint64 `json:"demandThresholdMsat"`
+}
+
+type FeeChange struct {
+	ID             uint      `json:"id"`
+	ChannelId      string    `json:"channelId"`
+	PeerPubkey     string    `json:"peerPubkey"`
+	FeePolicyId    uint      `json:"feePolicyId"`
+	PolicyType     string    `json:"policyType"`
+	OldBaseFeeMsat uint32    `json:"oldBaseFeeMsat"`
+	NewBaseFeeMsat uint32    `json:"newBaseFeeMsat"`
+	OldFeePpm      uint32    `json:"oldFeePpm"`
+	NewFeePpm      uint32    `json:"newFeePpm"`
+	Reason         string    `json:"reason"`
+	DryRun         bool      `json:"dryRun"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type ListFeeChangesResponse struct {
+	TotalCount uint64      `json:"totalCount"`
+	Changes    []FeeChange `json:"changes"`
+}
+
+type GetRoutingAnalyticsResponse struct {
+	From     uint64                `json:"from"`
+	Until    uint64                `json:"until"`
+	Totals   RoutingStats          `json:"totals"`
+	Channels []ChannelRoutingStats `json:"channels"`
+	Peers    []PeerRoutingStats    `json:"peers"`
 }
 
 func ResolveToSat(satValue *uint64, msatValue *uint64, legacyValueSat *uint64, legacyValueMsat *uint64) (resolvedSatValue *uint64) {
@@ -769,3 +1094,31 @@ func ResolveToMsat(satValue *uint64, msatValue *uint64, legacyValueSat *uint64,
 
 	return resolvedMsatValue
 }
+
+type DeleteAppRequest struct {
+	TotpCode string `json:"totpCode"`
+}
+
+type BeginTOTPEnrollmentRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+}
+
+type TOTPRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	TotpCode       string `json:"totpCode"`
+}
+
+type TOTPRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type UpgradeKDFRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+	Time           uint32 `json:"time"`
+	MemoryKiB      uint32 `json:"memoryKiB"`
+	Threads        uint8  `json:"threads"`
+}
+
+type RotateDataKeyRequest struct {
+	UnlockPassword string `json:"unlockPassword"`
+}
@@ -12,12 +12,19 @@ import (
 
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/rebalance"
 	"github.com/getAlby/hub/version"
 	decodepay "github.com/nbd-wtf/ln-decodepay"
 	"github.com/sirupsen/logrus"
 )
 
-func (api *api) RebalanceChannel(ctx context.Context, rebalanceChannelRequest *RebalanceChannelRequest) (*RebalanceChannelResponse, error) {
+var errRebalancerNotStarted = errors.New("rebalancer not started")
+
+func (api *api) RebalanceChannel(ctx context.Context, rebalanceChannelRequest *RebalanceChannelRequest) (_ *RebalanceChannelResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "rebalance_channel", rebalanceChannelRequest, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -151,3 +158,66 @@ func (api *api) RebalanceChannel(ctx context.Context, rebalanceChannelRequest *R
 		TotalFeeMsat: totalFeeMsat,
 	}, nil
 }
+
+// CircularRebalance moves liquidity between two of our channels by paying an invoice of
+// our own node out through the source channel and back in through the target channel
+func (api *api) CircularRebalance(ctx context.Context, circularRebalanceRequest *CircularRebalanceRequest) (_ *rebalance.Result, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "circular_rebalance", circularRebalanceRequest, err)
+	}()
+
+	rebalancer := api.svc.GetRebalancer()
+	if rebalancer == nil {
+		return nil, errRebalancerNotStarted
+	}
+
+	maxFeePpm := api.cfg.GetEnv().RebalancerMaxFeePpm
+	if circularRebalanceRequest.MaxFeePpm != nil {
+		maxFeePpm = *circularRebalanceRequest.MaxFeePpm
+	}
+
+	result, err := rebalancer.Rebalance(ctx, &rebalance.RebalanceRequest{
+		SourceChannelId: circularRebalanceRequest.SourceChannelId,
+		TargetChannelId: circularRebalanceRequest.TargetChannelId,
+		AmountSat:       circularRebalanceRequest.AmountSat,
+		MaxFeePpm:       maxFeePpm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	api.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_rebalance_succeeded",
+		Properties: map[string]interface{}{},
+	})
+
+	return result, nil
+}
+
+func (api *api) GetRebalancerStatus() (*rebalance.Status, error) {
+	rebalancer := api.svc.GetRebalancer()
+	if rebalancer == nil {
+		return nil, errRebalancerNotStarted
+	}
+	return rebalancer.GetStatus(), nil
+}
+
+func (api *api) PreviewRebalances(ctx context.Context) ([]rebalance.Rebalance, error) {
+	rebalancer := api.svc.GetRebalancer()
+	if rebalancer == nil {
+		return nil, errRebalancerNotStarted
+	}
+	return rebalancer.Preview(ctx)
+}
+
+func (api *api) RunRebalancer(ctx context.Context) (_ []rebalance.Result, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "run_rebalancer", nil, err)
+	}()
+
+	rebalancer := api.svc.GetRebalancer()
+	if rebalancer == nil {
+		return nil, errRebalancerNotStarted
+	}
+	return rebalancer.Run(ctx)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+
+	"github.com/getAlby/hub/totp"
+)
+
+func (api *api) GetTOTPStatus() (*totp.Status, error) {
+	return api.totpSvc.GetStatus()
+}
+
+func (api *api) BeginTOTPEnrollment(ctx context.Context, beginTOTPEnrollmentRequest *BeginTOTPEnrollmentRequest) (_ *totp.Enrollment, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "begin_totp_enrollment", nil, err)
+	}()
+
+	return api.totpSvc.BeginEnrollment(beginTOTPEnrollmentRequest.UnlockPassword)
+}
+
+func (api *api) ConfirmTOTPEnrollment(ctx context.Context, totpRequest *TOTPRequest) (_ *TOTPRecoveryCodesResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "enable_totp", nil, err)
+	}()
+
+	recoveryCodes, err := api.totpSvc.ConfirmEnrollment(totpRequest.UnlockPassword, totpRequest.TotpCode)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTPRecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (api *api) DisableTOTP(ctx context.Context, totpRequest *TOTPRequest) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "disable_totp", nil, err)
+	}()
+
+	return api.totpSvc.Disable(totpRequest.UnlockPassword, totpRequest.TotpCode)
+}
+
+func (api *api) RegenerateTOTPRecoveryCodes(ctx context.Context, totpRequest *TOTPRequest) (_ *TOTPRecoveryCodesResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "regenerate_totp_recovery_codes", nil, err)
+	}()
+
+	recoveryCodes, err := api.totpSvc.RegenerateRecoveryCodes(totpRequest.UnlockPassword, totpRequest.TotpCode)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTPRecoveryCodesResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// VerifyUnlockTOTP checks the authentication code when the hub is started or unlocked.
+// It always succeeds if two-factor authentication is not enabled.
+func (api *api) VerifyUnlockTOTP(unlockPassword string, totpCode string) error {
+	return api.totpSvc.VerifyUnlock(unlockPassword, totpCode)
+}
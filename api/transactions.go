@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -49,6 +50,68 @@ func (api *api) SetTransactionUserLabels(ctx context.Context, id uint, labels ma
 	return api.svc.GetTransactionsService().SetTransactionUserLabels(ctx, id, labels)
 }
 
+func (api *api) ListPaymentApprovals(ctx context.Context, state string) ([]PaymentApproval, error) {
+	dbPaymentApprovals, err := api.svc.GetTransactionsService().ListPaymentApprovals(ctx, strings.ToUpper(state))
+	if err != nil {
+		return nil, err
+	}
+
+	paymentApprovals := []PaymentApproval{}
+	for _, dbPaymentApproval := range dbPaymentApprovals {
+		var metadata Metadata
+		if dbPaymentApproval.Metadata != nil {
+			jsonErr := json.Unmarshal(dbPaymentApproval.Metadata, &metadata)
+			if jsonErr != nil {
+				logger.Logger.WithError(jsonErr).WithFields(logrus.Fields{
+					"payment_approval_id": dbPaymentApproval.ID,
+				}).Error("Failed to deserialize payment approval metadata")
+			}
+		}
+
+		paymentApprovals = append(paymentApprovals, PaymentApproval{
+			ID:             dbPaymentApproval.ID,
+			AppId:          dbPaymentApproval.AppId,
+			RequestEventId: dbPaymentApproval.RequestEventId,
+			TransactionId:  dbPaymentApproval.TransactionId,
+			State:          strings.ToLower(dbPaymentApproval.State),
+			Invoice:        dbPaymentApproval.PaymentRequest,
+			PaymentHash:    dbPaymentApproval.PaymentHash,
+			AmountSat:      dbPaymentApproval.AmountMsat / 1000,
+			AmountMsat:     dbPaymentApproval.AmountMsat,
+			Description:    dbPaymentApproval.Description,
+			Metadata:       metadata,
+			ResolvedAt:     dbPaymentApproval.ResolvedAt,
+			ExpiresAt:      dbPaymentApproval.ExpiresAt,
+			CreatedAt:      dbPaymentApproval.CreatedAt,
+		})
+	}
+	return paymentApprovals, nil
+}
+
+func (api *api) ApprovePayment(ctx context.Context, id uint) (_ *SendPaymentResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "approve_payment", map[string]interface{}{"id": id}, err)
+	}()
+
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, ErrLNClientNotStarted
+	}
+	transaction, err := api.svc.GetTransactionsService().ApprovePayment(ctx, id, lnClient)
+	if err != nil {
+		return nil, err
+	}
+	return toApiTransaction(transaction), nil
+}
+
+func (api *api) RejectPayment(ctx context.Context, id uint) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "reject_payment", map[string]interface{}{"id": id}, err)
+	}()
+
+	return api.svc.GetTransactionsService().RejectPayment(ctx, id)
+}
+
 // ParseListTransactionsFilters parses transaction filter query parameters
 // shared by the HTTP and Wails transports. Invalid values return an error.
 func ParseListTransactionsFilters(query url.Values) (ListTransactionsFilters, error) {
@@ -85,6 +148,15 @@ func ParseListTransactionsFilters(query url.Values) (ListTransactionsFilters, er
 	}
 
 	filters.SearchTerm = strings.TrimSpace(query.Get("search"))
+	filters.Cursor = query.Get("cursor")
+
+	if changedSinceParam := query.Get("changedSince"); changedSinceParam != "" {
+		changedSince, err := strconv.ParseBool(changedSinceParam)
+		if err != nil {
+			return filters, fmt.Errorf("invalid changedSince: %s", changedSinceParam)
+		}
+		filters.ChangedSince = changedSince
+	}
 
 	return filters, nil
 }
@@ -105,6 +177,8 @@ func (api *api) ListTransactions(ctx context.Context, appId *uint, limit uint64,
 		MinAmountMsat: filters.MinAmountMsat,
 		HideFailed:    filters.HideFailed,
 		SearchTerm:    filters.SearchTerm,
+		Cursor:        filters.Cursor,
+		ChangedSince:  filters.ChangedSince,
 	})
 	if err != nil {
 		return nil, err
@@ -118,10 +192,91 @@ func (api *api) ListTransactions(ctx context.Context, appId *uint, limit uint64,
 	return &ListTransactionsResponse{
 		Transactions: apiTransactions,
 		TotalCount:   totalCount,
+		NextCursor:   transactions.NextTransactionsCursor(dbTransactions, filters.Cursor),
+	}, nil
+}
+
+// ParseListActivityFilters parses activity feed filter query parameters
+// shared by the HTTP and Wails transports. Invalid values return an error.
+func ParseListActivityFilters(query url.Values) (ListActivityFilters, error) {
+	filters := ListActivityFilters{}
+
+	if kindsParam := query.Get("kinds"); kindsParam != "" {
+		validKinds := []string{
+			constants.ACTIVITY_KIND_LIGHTNING,
+			constants.ACTIVITY_KIND_ONCHAIN,
+			constants.ACTIVITY_KIND_SWAP_IN,
+			constants.ACTIVITY_KIND_SWAP_OUT,
+			constants.ACTIVITY_KIND_CHANNEL_OPEN,
+			constants.ACTIVITY_KIND_CHANNEL_CLOSE,
+		}
+		for _, kind := range strings.Split(kindsParam, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(validKinds, kind) {
+				return filters, fmt.Errorf("invalid kind: %s", kind)
+			}
+			filters.Kinds = append(filters.Kinds, kind)
+		}
+	}
+
+	if transactionType := query.Get("type"); transactionType != "" {
+		if transactionType != constants.TRANSACTION_TYPE_INCOMING && transactionType != constants.TRANSACTION_TYPE_OUTGOING {
+			return filters, fmt.Errorf("invalid type: %s", transactionType)
+		}
+		filters.Type = &transactionType
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"from", &filters.From},
+		{"until", &filters.Until},
+	} {
+		if paramValue := query.Get(param.name); paramValue != "" {
+			parsedValue, err := strconv.ParseUint(paramValue, 10, 64)
+			if err != nil {
+				return filters, fmt.Errorf("invalid %s: %s", param.name, paramValue)
+			}
+			*param.value = parsedValue
+		}
+	}
+
+	return filters, nil
+}
+
+func (api *api) ListActivity(ctx context.Context, limit uint64, offset uint64, filters ListActivityFilters) (*ListActivityResponse, error) {
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, ErrLNClientNotStarted
+	}
+
+	activityItems, totalCount, err := api.svc.GetTransactionsService().ListActivity(ctx, limit, offset, lnClient, nil, false, &transactions.ListActivityFilters{
+		Kinds: filters.Kinds,
+		Type:  filters.Type,
+		From:  filters.From,
+		Until: filters.Until,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	apiActivityItems := []ActivityItem{}
+	for _, activityItem := range activityItems {
+		apiActivityItems = append(apiActivityItems, *toApiActivityItem(&activityItem))
+	}
+
+	return &ListActivityResponse{
+		Items:      apiActivityItems,
+		TotalCount: totalCount,
 	}, nil
 }
 
-func (api *api) SendPayment(ctx context.Context, invoice string, amountMsat *uint64, metadata map[string]interface{}, appId *uint) (*SendPaymentResponse, error) {
+func (api *api) SendPayment(ctx context.Context, invoice string, amountMsat *uint64, metadata map[string]interface{}, appId *uint) (_ *SendPaymentResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "send_payment", map[string]interface{}{"invoice": invoice, "amountMsat": amountMsat, "appId": appId}, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return nil, ErrLNClientNotStarted
@@ -134,6 +289,53 @@ func (api *api) SendPayment(ctx context.Context, invoice string, amountMsat *uin
 	return toApiTransaction(transaction), nil
 }
 
+func (api *api) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string, metadata map[string]interface{}, appId *uint) (_ *SendPaymentResponse, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "pay_offer", map[string]interface{}{"offer": offer, "amountMsat": amountMsat, "appId": appId}, err)
+	}()
+
+	lnClient := api.svc.GetLNClient()
+	if lnClient == nil {
+		return nil, ErrLNClientNotStarted
+	}
+
+	transaction, err := api.svc.GetTransactionsService().PayOffer(ctx, offer, amountMsat, payerNote, metadata, lnClient, appId, nil)
+	if err != nil {
+		return nil, err
+	}
+	return toApiTransaction(transaction), nil
+}
+
+func toApiActivityItem(activityItem *transactions.ActivityItem) *ActivityItem {
+	var settledAt *string
+	if activityItem.SettledAt != nil {
+		settledAtValue := activityItem.SettledAt.Format(time.RFC3339)
+		settledAt = &settledAtValue
+	}
+
+	var transaction *Transaction
+	if activityItem.Transaction != nil {
+		transaction = toApiTransaction(activityItem.Transaction)
+	}
+
+	return &ActivityItem{
+		Kind:               activityItem.Kind,
+		Type:               activityItem.Type,
+		State:              strings.ToLower(activityItem.State),
+		AmountSat:          activityItem.AmountMsat / 1000,
+		AmountMsat:         activityItem.AmountMsat,
+		FeesPaidSat:        activityItem.FeeMsat / 1000,
+		FeesPaidMsat:       activityItem.FeeMsat,
+		Description:        activityItem.Description,
+		CreatedAt:          activityItem.CreatedAt.Format(time.RFC3339),
+		SettledAt:          settledAt,
+		TxId:               activityItem.TxId,
+		SwapId:             activityItem.SwapId,
+		CounterpartyNodeId: activityItem.CounterpartyNodeId,
+		Transaction:        transaction,
+	}
+}
+
 func toApiTransaction(transaction *transactions.Transaction) *Transaction {
 
 	updatedAt := transaction.UpdatedAt.Format(time.RFC3339)
@@ -192,10 +394,16 @@ func toApiTransaction(transaction *transactions.Transaction) *Transaction {
 		Metadata:        metadata,
 		Boostagram:      boostagram,
 		FailureReason:   transaction.FailureReason,
+		OnchainAddress:  transaction.OnchainAddress,
+		OnchainTxId:     transaction.OnchainTxId,
 	}
 }
 
-func (api *api) Transfer(ctx context.Context, fromAppId *uint, toAppId *uint, amountMsat uint64, description string) error {
+func (api *api) Transfer(ctx context.Context, fromAppId *uint, toAppId *uint, amountMsat uint64, description string) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "transfer", map[string]interface{}{"fromAppId": fromAppId, "toAppId": toAppId, "amountMsat": amountMsat, "description": description}, err)
+	}()
+
 	lnClient := api.svc.GetLNClient()
 	if lnClient == nil {
 		return ErrLNClientNotStarted
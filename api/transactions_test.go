@@ -71,6 +71,8 @@ func TestParseListTransactionsFilters(t *testing.T) {
 		"minAmountSat": {"1000"},
 		"hideFailed":   {"true"},
 		"search":       {" coffee "},
+		"cursor":       {"abc"},
+		"changedSince": {"true"},
 	})
 	require.NoError(t, err)
 	assert.Equal(t, ListTransactionsFilters{
@@ -78,6 +80,8 @@ func TestParseListTransactionsFilters(t *testing.T) {
 		MinAmountMsat: &minAmountMsat,
 		HideFailed:    true,
 		SearchTerm:    "coffee",
+		Cursor:        "abc",
+		ChangedSince:  true,
 	}, filters)
 
 	filters, err = ParseListTransactionsFilters(url.Values{})
@@ -91,6 +95,7 @@ func TestParseListTransactionsFilters(t *testing.T) {
 		{"minAmountSat": {"0"}},
 		{"minAmountSat": {"18446744073709551615"}},
 		{"hideFailed": {"maybe"}},
+		{"changedSince": {"maybe"}},
 	} {
 		_, err = ParseListTransactionsFilters(invalidQuery)
 		assert.Error(t, err, "query: %v", invalidQuery)
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"github.com/getAlby/hub/db"
+)
+
+func (api *api) ListUsers() ([]HubUser, error) {
+	dbUsers, err := api.usersSvc.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	users := []HubUser{}
+	for _, dbUser := range dbUsers {
+		users = append(users, *toHubUser(&dbUser))
+	}
+	return users, nil
+}
+
+func (api *api) CreateUser(ctx context.Context, createUserRequest *CreateUserRequest) (_ *HubUser, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "create_user", createUserRequest, err)
+	}()
+
+	dbUser, err := api.usersSvc.CreateUser(createUserRequest.Username, createUserRequest.Password, createUserRequest.Role)
+	if err != nil {
+		return nil, err
+	}
+	return toHubUser(dbUser), nil
+}
+
+func (api *api) UpdateUser(ctx context.Context, id uint, updateUserRequest *UpdateUserRequest) (_ *HubUser, err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "update_user", map[string]interface{}{"id": id, "role": updateUserRequest.Role, "passwordChanged": updateUserRequest.Password != ""}, err)
+	}()
+
+	dbUser, err := api.usersSvc.UpdateUser(id, updateUserRequest.Role, updateUserRequest.Password)
+	if err != nil {
+		return nil, err
+	}
+	return toHubUser(dbUser), nil
+}
+
+func (api *api) DeleteUser(ctx context.Context, id uint) (err error) {
+	defer func() {
+		api.auditSvc.Record(ctx, "delete_user", map[string]interface{}{"id": id}, err)
+	}()
+
+	return api.usersSvc.DeleteUser(id)
+}
+
+func toHubUser(dbUser *db.User) *HubUser {
+	return &HubUser{
+		ID:          dbUser.ID,
+		Username:    dbUser.Username,
+		Role:        dbUser.Role,
+		LastLoginAt: dbUser.LastLoginAt,
+		CreatedAt:   dbUser.CreatedAt,
+	}
+}
@@ -0,0 +1,266 @@
+package apitokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// TokenPrefix distinguishes API tokens from session JWTs
+const TokenPrefix = "ahub_"
+
+// last used timestamps are only written once per interval to avoid a DB write per request
+const lastUsedUpdateInterval = time.Minute
+
+type invalidTokenError struct {
+}
+
+func NewInvalidTokenError() error {
+	return &invalidTokenError{}
+}
+
+func (err *invalidTokenError) Error() string {
+	return "invalid or expired API token"
+}
+
+type ipNotAllowedError struct {
+}
+
+func NewIpNotAllowedError() error {
+	return &ipNotAllowedError{}
+}
+
+func (err *ipNotAllowedError) Error() string {
+	return "API token is not allowed from this IP address"
+}
+
+type ApiTokensService interface {
+	CreateToken(name string, scopes []string, allowedIps []string, expiresAt *time.Time) (*db.ApiToken, string, error)
+	ListTokens() ([]db.ApiToken, error)
+	DeleteToken(id uint) error
+	DeleteAllTokens() error
+	Authenticate(token string, ip string) (*db.ApiToken, []string, error)
+}
+
+type apiTokensService struct {
+	db *gorm.DB
+}
+
+func NewApiTokensService(db *gorm.DB) *apiTokensService {
+	return &apiTokensService{
+		db: db,
+	}
+}
+
+// CreateToken stores a new API token and returns it. The plain token is only
+// available at creation time.
+func (svc *apiTokensService) CreateToken(name string, scopes []string, allowedIps []string, expiresAt *time.Time) (*db.ApiToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("no token name provided")
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if scope == constants.API_SCOPE_ADMIN {
+			// a leaked token must not be able to change the unlock password,
+			// reveal the recovery phrase or create further tokens
+			return nil, "", errors.New("the admin scope cannot be granted to API tokens")
+		}
+		if !slices.Contains(constants.GetApiScopes(), scope) {
+			return nil, "", fmt.Errorf("unknown scope: %s. Must be one of %s", scope, strings.Join(constants.GetApiScopes(), ","))
+		}
+	}
+	for _, allowedIp := range allowedIps {
+		if _, err := parseAllowedIp(allowedIp); err != nil {
+			return nil, "", err
+		}
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return nil, "", errors.New("expiry must be in the future")
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, "", err
+	}
+	token := TokenPrefix + hex.EncodeToString(tokenBytes)
+
+	scopesJson, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if allowedIps == nil {
+		allowedIps = []string{}
+	}
+	allowedIpsJson, err := json.Marshal(allowedIps)
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiToken := &db.ApiToken{
+		Name:       name,
+		TokenHash:  hashToken(token),
+		Scopes:     datatypes.JSON(scopesJson),
+		AllowedIps: datatypes.JSON(allowedIpsJson),
+		ExpiresAt:  expiresAt,
+	}
+	if err := svc.db.Create(apiToken).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to create API token")
+		return nil, "", err
+	}
+
+	logger.Logger.WithField("api_token_id", apiToken.ID).Info("Created API token")
+
+	return apiToken, token, nil
+}
+
+func (svc *apiTokensService) ListTokens() ([]db.ApiToken, error) {
+	apiTokens := []db.ApiToken{}
+	err := svc.db.Order("created_at desc").Find(&apiTokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return apiTokens, nil
+}
+
+func (svc *apiTokensService) DeleteToken(id uint) error {
+	result := svc.db.Delete(&db.ApiToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	logger.Logger.WithField("api_token_id", id).Info("Deleted API token")
+	return nil
+}
+
+// DeleteAllTokens revokes every API token, e.g. once the unlock password was changed
+func (svc *apiTokensService) DeleteAllTokens() error {
+	result := svc.db.Where("1 = 1").Delete(&db.ApiToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	logger.Logger.WithField("count", result.RowsAffected).Info("Deleted all API tokens")
+	return nil
+}
+
+// Authenticate checks an API token presented by a client at the given IP
+// address and returns the token together with its scopes
+func (svc *apiTokensService) Authenticate(token string, ip string) (*db.ApiToken, []string, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, nil, NewInvalidTokenError()
+	}
+
+	var apiToken db.ApiToken
+	result := svc.db.Limit(1).Find(&apiToken, &db.ApiToken{
+		TokenHash: hashToken(token),
+	})
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, NewInvalidTokenError()
+	}
+
+	now := time.Now()
+	if apiToken.ExpiresAt != nil && apiToken.ExpiresAt.Before(now) {
+		return nil, nil, NewInvalidTokenError()
+	}
+
+	allowed, err := isIpAllowed(&apiToken, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !allowed {
+		return nil, nil, NewIpNotAllowedError()
+	}
+
+	var scopes []string
+	if err := json.Unmarshal(apiToken.Scopes, &scopes); err != nil {
+		return nil, nil, err
+	}
+	// tokens created before the admin scope was refused do not keep it
+	scopes = slices.DeleteFunc(scopes, func(scope string) bool {
+		return scope == constants.API_SCOPE_ADMIN
+	})
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > lastUsedUpdateInterval {
+		err := svc.db.Model(&apiToken).Update("last_used_at", &now).Error
+		if err != nil {
+			logger.Logger.WithField("api_token_id", apiToken.ID).WithError(err).Error("Failed to update API token last used time")
+		}
+	}
+
+	return &apiToken, scopes, nil
+}
+
+func isIpAllowed(apiToken *db.ApiToken, ip string) (bool, error) {
+	var allowedIps []string
+	if len(apiToken.AllowedIps) > 0 {
+		if err := json.Unmarshal(apiToken.AllowedIps, &allowedIps); err != nil {
+			return false, err
+		}
+	}
+	if len(allowedIps) == 0 {
+		return true, nil
+	}
+
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return false, nil
+	}
+	for _, allowedIp := range allowedIps {
+		ipNet, err := parseAllowedIp(allowedIp)
+		if err != nil {
+			return false, err
+		}
+		if ipNet.Contains(parsedIp) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseAllowedIp parses a single IP address or a CIDR range
+func parseAllowedIp(allowedIp string) (*net.IPNet, error) {
+	if strings.Contains(allowedIp, "/") {
+		_, ipNet, err := net.ParseCIDR(allowedIp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP range: %s", allowedIp)
+		}
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(allowedIp)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", allowedIp)
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
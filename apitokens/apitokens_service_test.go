@@ -0,0 +1,154 @@
+package apitokens
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestCreateToken(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	apiTokensSvc := NewApiTokensService(svc.DB)
+
+	apiToken, token, err := apiTokensSvc.CreateToken(" CI ", []string{constants.API_SCOPE_READ, constants.API_SCOPE_INVOICES_CREATE}, []string{"10.0.0.0/8"}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, TokenPrefix))
+	assert.Equal(t, "CI", apiToken.Name)
+	assert.Equal(t, `["read","invoices:create"]`, string(apiToken.Scopes))
+	assert.Equal(t, `["10.0.0.0/8"]`, string(apiToken.AllowedIps))
+
+	// the token itself is not stored
+	var dbApiToken db.ApiToken
+	require.NoError(t, svc.DB.First(&dbApiToken, apiToken.ID).Error)
+	assert.NotEqual(t, token, dbApiToken.TokenHash)
+	assert.NotContains(t, dbApiToken.TokenHash, token)
+
+	apiTokens, err := apiTokensSvc.ListTokens()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(apiTokens))
+	assert.Equal(t, apiToken.ID, apiTokens[0].ID)
+}
+
+func TestCreateToken_Invalid(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	apiTokensSvc := NewApiTokensService(svc.DB)
+	pastExpiry := time.Now().Add(-time.Hour)
+
+	_, _, err = apiTokensSvc.CreateToken("", []string{constants.API_SCOPE_READ}, nil, nil)
+	assert.EqualError(t, err, "no token name provided")
+	_, _, err = apiTokensSvc.CreateToken("CI", []string{}, nil, nil)
+	assert.EqualError(t, err, "at least one scope is required")
+	_, _, err = apiTokensSvc.CreateToken("CI", []string{"superpowers"}, nil, nil)
+	assert.ErrorContains(t, err, "unknown scope: superpowers")
+	_, _, err = apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ, constants.API_SCOPE_ADMIN}, nil, nil)
+	assert.EqualError(t, err, "the admin scope cannot be granted to API tokens")
+	_, _, err = apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ}, []string{"localhost"}, nil)
+	assert.EqualError(t, err, "invalid IP address: localhost")
+	_, _, err = apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ}, []string{"10.0.0.0/99"}, nil)
+	assert.EqualError(t, err, "invalid IP range: 10.0.0.0/99")
+	_, _, err = apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ}, nil, &pastExpiry)
+	assert.EqualError(t, err, "expiry must be in the future")
+}
+
+func TestAuthenticate(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	apiTokensSvc := NewApiTokensService(svc.DB)
+	apiToken, token, err := apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ}, []string{"10.0.0.0/8", "192.168.1.10"}, nil)
+	require.NoError(t, err)
+
+	authenticatedToken, scopes, err := apiTokensSvc.Authenticate(token, "10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, apiToken.ID, authenticatedToken.ID)
+	assert.Equal(t, []string{constants.API_SCOPE_READ}, scopes)
+
+	var dbApiToken db.ApiToken
+	require.NoError(t, svc.DB.First(&dbApiToken, apiToken.ID).Error)
+	assert.NotNil(t, dbApiToken.LastUsedAt)
+
+	_, _, err = apiTokensSvc.Authenticate(token, "192.168.1.10")
+	assert.NoError(t, err)
+
+	_, _, err = apiTokensSvc.Authenticate(token, "192.168.1.11")
+	assert.ErrorIs(t, err, NewIpNotAllowedError())
+
+	_, _, err = apiTokensSvc.Authenticate(TokenPrefix+"unknown", "10.1.2.3")
+	assert.ErrorIs(t, err, NewInvalidTokenError())
+
+	// revoked tokens can no longer be used
+	require.NoError(t, apiTokensSvc.DeleteToken(apiToken.ID))
+	_, _, err = apiTokensSvc.Authenticate(token, "10.1.2.3")
+	assert.ErrorIs(t, err, NewInvalidTokenError())
+}
+
+func TestAuthenticate_Expired(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	apiTokensSvc := NewApiTokensService(svc.DB)
+	expiresAt := time.Now().Add(time.Hour)
+	apiToken, token, err := apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ}, nil, &expiresAt)
+	require.NoError(t, err)
+
+	_, _, err = apiTokensSvc.Authenticate(token, "127.0.0.1")
+	require.NoError(t, err)
+
+	err = svc.DB.Model(apiToken).Update("expires_at", time.Now().Add(-time.Minute)).Error
+	require.NoError(t, err)
+
+	_, _, err = apiTokensSvc.Authenticate(token, "127.0.0.1")
+	assert.ErrorIs(t, err, NewInvalidTokenError())
+}
+
+func TestAuthenticate_AdminScopeRemoved(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	apiTokensSvc := NewApiTokensService(svc.DB)
+	apiToken, token, err := apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ}, nil, nil)
+	require.NoError(t, err)
+	// a token created before the admin scope was refused
+	err = svc.DB.Model(apiToken).Update("scopes", `["read","admin"]`).Error
+	require.NoError(t, err)
+
+	_, scopes, err := apiTokensSvc.Authenticate(token, "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{constants.API_SCOPE_READ}, scopes)
+}
+
+func TestDeleteAllTokens(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	apiTokensSvc := NewApiTokensService(svc.DB)
+	_, token, err := apiTokensSvc.CreateToken("CI", []string{constants.API_SCOPE_READ}, nil, nil)
+	require.NoError(t, err)
+	_, _, err = apiTokensSvc.CreateToken("CD", []string{constants.API_SCOPE_READ}, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, apiTokensSvc.DeleteAllTokens())
+
+	apiTokens, err := apiTokensSvc.ListTokens()
+	require.NoError(t, err)
+	assert.Empty(t, apiTokens)
+	_, _, err = apiTokensSvc.Authenticate(token, "127.0.0.1")
+	assert.ErrorIs(t, err, NewInvalidTokenError())
+}
@@ -54,6 +54,10 @@ func (svc *appsService) CreateApp(name string, pubkey string, maxAmountSat uint6
 			// cannot sign messages because the isolated app is a custodial sub-wallet
 			return nil, "", errors.New("Sub-wallet app connection cannot have sign_message scope")
 		}
+		if slices.Contains(scopes, constants.NODE_ADMIN_SCOPE) {
+			// cannot manage the node because the isolated app is a custodial sub-wallet
+			return nil, "", errors.New("Sub-wallet app connection cannot have node_admin scope")
+		}
 
 		backendType, _ := svc.cfg.Get("LNBackendType", "")
 		if backendType != config.LDKBackendType &&
@@ -145,17 +149,12 @@ func (svc *appsService) CreateApp(name string, pubkey string, maxAmountSat uint6
 			}
 		}
 
-		appWalletPrivKey, err := svc.keys.GetAppWalletKey(app.ID)
-		if err != nil {
-			return fmt.Errorf("error generating wallet child private key: %w", err)
-		}
-
-		appWalletPubkey, err := nostr.GetPublicKey(appWalletPrivKey)
+		appWalletSigner, err := svc.keys.GetAppWalletSigner(app.ID)
 		if err != nil {
-			return fmt.Errorf("error generating wallet child public key: %w", err)
+			return fmt.Errorf("error generating wallet child key: %w", err)
 		}
 
-		err = tx.Model(&app).Update("wallet_pubkey", appWalletPubkey).Error
+		err = tx.Model(&app).Update("wallet_pubkey", appWalletSigner.GetPublicKey()).Error
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,393 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+const redactedValue = "[redacted]"
+
+// The key the entries are signed with and the hash of the latest entry are
+// kept in the work directory rather than the database, so that someone who
+// can only write to the database cannot rewrite the chain or remove its
+// latest entries unnoticed.
+const (
+	KeyFileName  = "audit.key"
+	HeadFileName = "audit.head"
+)
+
+// parameters with these words in their name are never stored
+var secretParamNames = []string{"password", "secret", "mnemonic", "totp", "token", "key", "seed"}
+
+// public keys identify nodes and apps and are kept despite containing "key"
+var publicKeyParamNames = []string{"pubkey", "publickey"}
+
+// returned from a batch to stop verifying at the first invalid entry
+var errStopVerify = errors.New("audit log verification stopped")
+
+// appending an entry reads the hash of the previous entry, so entries
+// must be written one at a time to keep the chain linear
+var appendMutex sync.Mutex
+
+type actorContextKey struct{}
+
+type actor struct {
+	source string
+	name   string
+}
+
+// WithActor returns a context that attributes audited calls made with it to
+// the given actor (e.g. "owner", "user:alice") coming from the given source
+func WithActor(ctx context.Context, source string, name string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, &actor{source: source, name: name})
+}
+
+type ListEventsFilters struct {
+	Action string
+	Source string
+	Actor  string
+	From   *time.Time
+	Until  *time.Time
+}
+
+type VerifyResult struct {
+	Valid          bool   `json:"valid"`
+	EventCount     uint64 `json:"eventCount"`
+	InvalidEventId uint   `json:"invalidEventId,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+type AuditService interface {
+	Record(ctx context.Context, action string, params interface{}, err error)
+	ListEvents(filters ListEventsFilters, limit uint64, offset uint64) ([]db.AuditEvent, uint64, error)
+	Verify() (*VerifyResult, error)
+}
+
+type auditService struct {
+	db      *gorm.DB
+	workDir string
+}
+
+func NewAuditService(db *gorm.DB, workDir string) *auditService {
+	return &auditService{
+		db:      db,
+		workDir: workDir,
+	}
+}
+
+// Record appends a state-changing call and its result to the audit log.
+// Failures are logged rather than returned so they never affect the call itself.
+func (svc *auditService) Record(ctx context.Context, action string, params interface{}, err error) {
+	auditEvent := &db.AuditEvent{
+		Action: action,
+		Result: constants.AUDIT_RESULT_SUCCESS,
+		// the hash only covers whole seconds as databases store timestamps with different precision
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+	if actor, ok := ctx.Value(actorContextKey{}).(*actor); ok {
+		auditEvent.Source = actor.source
+		auditEvent.Actor = actor.name
+	}
+	if err != nil {
+		auditEvent.Result = constants.AUDIT_RESULT_ERROR
+		auditEvent.Error = err.Error()
+	}
+
+	paramsJson, marshalErr := redactParams(params)
+	if marshalErr != nil {
+		logger.Logger.WithError(marshalErr).WithField("action", action).Error("Failed to serialize audit event params")
+	}
+	auditEvent.Params = paramsJson
+
+	appendMutex.Lock()
+	defer appendMutex.Unlock()
+
+	logFields := logrus.Fields{
+		"action": action,
+		"source": auditEvent.Source,
+		"actor":  auditEvent.Actor,
+		"result": auditEvent.Result,
+	}
+
+	key, keyErr := svc.loadOrCreateKey()
+	if keyErr != nil {
+		logger.Logger.WithFields(logFields).WithError(keyErr).Error("Failed to load audit log key")
+		return
+	}
+
+	dbErr := svc.db.Transaction(func(tx *gorm.DB) error {
+		var lastAuditEvent db.AuditEvent
+		if err := tx.Order("id desc").Limit(1).Find(&lastAuditEvent).Error; err != nil {
+			return err
+		}
+		auditEvent.PrevHash = lastAuditEvent.Hash
+		auditEvent.Hash = computeHash(key, auditEvent)
+		return tx.Create(auditEvent).Error
+	})
+	if dbErr != nil {
+		logger.Logger.WithFields(logFields).WithError(dbErr).Error("Failed to record audit event")
+		return
+	}
+
+	if err := svc.saveHead(auditEvent.Hash); err != nil {
+		logger.Logger.WithFields(logFields).WithError(err).Error("Failed to save audit log head")
+		return
+	}
+	logger.Logger.WithFields(logFields).Info("Recorded audit event")
+}
+
+func (svc *auditService) ListEvents(filters ListEventsFilters, limit uint64, offset uint64) ([]db.AuditEvent, uint64, error) {
+	query := svc.db.Model(&db.AuditEvent{})
+	if filters.Action != "" {
+		query = query.Where("action = ?", filters.Action)
+	}
+	if filters.Source != "" {
+		query = query.Where("source = ?", filters.Source)
+	}
+	if filters.Actor != "" {
+		query = query.Where("actor = ?", filters.Actor)
+	}
+	if filters.From != nil {
+		query = query.Where("created_at >= ?", *filters.From)
+	}
+	if filters.Until != nil {
+		query = query.Where("created_at < ?", *filters.Until)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, err
+	}
+
+	auditEvents := []db.AuditEvent{}
+	query = query.Order("id desc")
+	if limit > 0 {
+		query = query.Limit(int(limit)).Offset(int(offset))
+	}
+	if err := query.Find(&auditEvents).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return auditEvents, uint64(totalCount), nil
+}
+
+// Verify walks the whole audit log and checks that every entry matches its
+// hash and links to the entry before it, and that the latest entry is the
+// one recorded in the head file, so removed latest entries are detected too.
+func (svc *auditService) Verify() (*VerifyResult, error) {
+	result := &VerifyResult{
+		Valid: true,
+	}
+	prevHash := ""
+
+	key, err := svc.loadKey()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load audit log key: %w", err)
+	}
+	head, err := svc.loadHead()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load audit log head: %w", err)
+	}
+
+	auditEvents := []db.AuditEvent{}
+	err = svc.db.Order("id").FindInBatches(&auditEvents, 1000, func(tx *gorm.DB, batch int) error {
+		for _, auditEvent := range auditEvents {
+			if auditEvent.PrevHash != prevHash {
+				result.Valid = false
+				result.InvalidEventId = auditEvent.ID
+				result.Reason = "previous entry is missing or was modified"
+				return errStopVerify
+			}
+			if key == nil {
+				result.Valid = false
+				result.InvalidEventId = auditEvent.ID
+				result.Reason = "audit log key is missing"
+				return errStopVerify
+			}
+			if !hmac.Equal([]byte(auditEvent.Hash), []byte(computeHash(key, &auditEvent))) {
+				result.Valid = false
+				result.InvalidEventId = auditEvent.ID
+				result.Reason = "entry was modified"
+				return errStopVerify
+			}
+			prevHash = auditEvent.Hash
+			result.EventCount++
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errStopVerify) {
+		return nil, err
+	}
+
+	if result.Valid && prevHash != head {
+		result.Valid = false
+		result.Reason = "latest entries are missing or were added outside the hub"
+	}
+
+	return result, nil
+}
+
+func (svc *auditService) keyPath() string {
+	return filepath.Join(svc.workDir, KeyFileName)
+}
+
+func (svc *auditService) headPath() string {
+	return filepath.Join(svc.workDir, HeadFileName)
+}
+
+func (svc *auditService) loadKey() ([]byte, error) {
+	keyHex, err := os.ReadFile(svc.keyPath())
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(keyHex)))
+}
+
+// loadOrCreateKey must be called while holding the append mutex
+func (svc *auditService) loadOrCreateKey() ([]byte, error) {
+	key, err := svc.loadKey()
+	if !errors.Is(err, os.ErrNotExist) {
+		return key, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	// O_EXCL so that a key created by another process is never overwritten
+	keyFile, err := os.OpenFile(svc.keyPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer keyFile.Close()
+	if _, err := keyFile.WriteString(hex.EncodeToString(key)); err != nil {
+		return nil, err
+	}
+	if err := keyFile.Sync(); err != nil {
+		return nil, err
+	}
+	logger.Logger.WithField("path", svc.keyPath()).Info("Created audit log key")
+	return key, nil
+}
+
+func (svc *auditService) loadHead() (string, error) {
+	head, err := os.ReadFile(svc.headPath())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(head)), nil
+}
+
+// saveHead replaces the head file through a rename so it is never left half written
+func (svc *auditService) saveHead(hash string) error {
+	tmpFile, err := os.CreateTemp(svc.workDir, HeadFileName+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if _, err := tmpFile.WriteString(hash); err != nil {
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), svc.headPath())
+}
+
+func computeHash(key []byte, auditEvent *db.AuditEvent) string {
+	hash := hmac.New(sha256.New, key)
+	fields := []string{
+		auditEvent.PrevHash,
+		fmt.Sprintf("%d", auditEvent.CreatedAt.Unix()),
+		auditEvent.Source,
+		auditEvent.Actor,
+		auditEvent.Action,
+		string(auditEvent.Params),
+		auditEvent.Result,
+		auditEvent.Error,
+	}
+	for _, field := range fields {
+		// length-prefix each field so that different field values cannot produce the same input
+		fmt.Fprintf(hash, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func redactParams(params interface{}) (datatypes.JSON, error) {
+	if params == nil {
+		return nil, nil
+	}
+	paramsJson, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var decodedParams interface{}
+	if err := json.Unmarshal(paramsJson, &decodedParams); err != nil {
+		return nil, err
+	}
+	paramsJson, err = json.Marshal(redact(decodedParams))
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(paramsJson), nil
+}
+
+func redact(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nestedValue := range value {
+			if isSecretParam(key) {
+				value[key] = redactedValue
+				continue
+			}
+			value[key] = redact(nestedValue)
+		}
+		return value
+	case []interface{}:
+		for i, nestedValue := range value {
+			value[i] = redact(nestedValue)
+		}
+		return value
+	}
+	return value
+}
+
+func isSecretParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, publicKeyParamName := range publicKeyParamNames {
+		if strings.HasSuffix(name, publicKeyParamName) {
+			return false
+		}
+	}
+	for _, secretParamName := range secretParamNames {
+		if strings.Contains(name, secretParamName) {
+			return true
+		}
+	}
+	return false
+}
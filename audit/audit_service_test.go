@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestRecord(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	auditSvc := NewAuditService(svc.DB, svc.Cfg.GetEnv().Workdir)
+	ctx := WithActor(context.TODO(), constants.AUDIT_SOURCE_HTTP, "user:alice")
+
+	auditSvc.Record(ctx, "create_app", map[string]interface{}{
+		"name":           "Test",
+		"unlockPassword": "123",
+		"nested":         []interface{}{map[string]interface{}{"pairingSecret": "abc", "token": "xyz"}},
+	}, nil)
+	auditSvc.Record(context.TODO(), "delete_app", map[string]interface{}{"appId": 1}, errors.New("app not found"))
+
+	auditEvents, totalCount, err := auditSvc.ListEvents(ListEventsFilters{}, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), totalCount)
+
+	// newest first
+	assert.Equal(t, "delete_app", auditEvents[0].Action)
+	assert.Equal(t, constants.AUDIT_RESULT_ERROR, auditEvents[0].Result)
+	assert.Equal(t, "app not found", auditEvents[0].Error)
+	assert.Equal(t, "", auditEvents[0].Actor)
+	assert.Equal(t, auditEvents[1].Hash, auditEvents[0].PrevHash)
+
+	assert.Equal(t, "create_app", auditEvents[1].Action)
+	assert.Equal(t, constants.AUDIT_SOURCE_HTTP, auditEvents[1].Source)
+	assert.Equal(t, "user:alice", auditEvents[1].Actor)
+	assert.Equal(t, constants.AUDIT_RESULT_SUCCESS, auditEvents[1].Result)
+	assert.Equal(t, "", auditEvents[1].PrevHash)
+	assert.JSONEq(t, `{"name":"Test","unlockPassword":"[redacted]","nested":[{"pairingSecret":"[redacted]","token":"[redacted]"}]}`, string(auditEvents[1].Params))
+
+	auditEvents, totalCount, err = auditSvc.ListEvents(ListEventsFilters{Actor: "user:alice"}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), totalCount)
+	assert.Equal(t, "create_app", auditEvents[0].Action)
+
+	_, totalCount, err = auditSvc.ListEvents(ListEventsFilters{Action: "open_channel"}, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), totalCount)
+}
+
+func TestIsSecretParam(t *testing.T) {
+	for _, name := range []string{"unlockPassword", "pairingSecret", "mnemonic", "totpCode", "token", "accessToken", "apiKey", "privateKey", "seed", "seedPhrase"} {
+		assert.True(t, isSecretParam(name), name)
+	}
+	for _, name := range []string{"name", "appId", "pubkey", "pairingPublicKey", "nodeAlias", "amountSat"} {
+		assert.False(t, isSecretParam(name), name)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	auditSvc := NewAuditService(svc.DB, svc.Cfg.GetEnv().Workdir)
+
+	result, err := auditSvc.Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, uint64(0), result.EventCount)
+
+	for _, action := range []string{"create_app", "update_app", "delete_app"} {
+		auditSvc.Record(context.TODO(), action, map[string]interface{}{"appId": 1}, nil)
+	}
+
+	result, err = auditSvc.Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, uint64(3), result.EventCount)
+
+	var auditEvents []db.AuditEvent
+	require.NoError(t, svc.DB.Order("id").Find(&auditEvents).Error)
+
+	// modified entries are detected
+	require.NoError(t, svc.DB.Model(&auditEvents[1]).Update("actor", "someone else").Error)
+	result, err = auditSvc.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, auditEvents[1].ID, result.InvalidEventId)
+	assert.Equal(t, "entry was modified", result.Reason)
+
+	// deleted entries are detected
+	require.NoError(t, svc.DB.Delete(&auditEvents[1]).Error)
+	result, err = auditSvc.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, auditEvents[2].ID, result.InvalidEventId)
+	assert.Equal(t, "previous entry is missing or was modified", result.Reason)
+	assert.Equal(t, uint64(1), result.EventCount)
+}
+
+func TestVerify_LatestEntriesRemoved(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	auditSvc := NewAuditService(svc.DB, svc.Cfg.GetEnv().Workdir)
+	for _, action := range []string{"create_app", "update_app", "delete_app"} {
+		auditSvc.Record(context.TODO(), action, map[string]interface{}{"appId": 1}, nil)
+	}
+
+	var lastAuditEvent db.AuditEvent
+	require.NoError(t, svc.DB.Order("id desc").First(&lastAuditEvent).Error)
+	require.NoError(t, svc.DB.Delete(&lastAuditEvent).Error)
+
+	result, err := auditSvc.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint64(2), result.EventCount)
+	assert.Equal(t, "latest entries are missing or were added outside the hub", result.Reason)
+
+	// the head cannot be rolled back by deleting it either
+	require.NoError(t, os.Remove(filepath.Join(svc.Cfg.GetEnv().Workdir, HeadFileName)))
+	result, err = auditSvc.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, "latest entries are missing or were added outside the hub", result.Reason)
+}
+
+func TestVerify_RehashedWithoutKey(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	auditSvc := NewAuditService(svc.DB, svc.Cfg.GetEnv().Workdir)
+	auditSvc.Record(context.TODO(), "create_app", map[string]interface{}{"appId": 1}, nil)
+
+	// rewriting an entry and its hash requires the key kept outside the database
+	var auditEvent db.AuditEvent
+	require.NoError(t, svc.DB.First(&auditEvent).Error)
+	auditEvent.Actor = "someone else"
+	require.NoError(t, svc.DB.Model(&auditEvent).Updates(map[string]interface{}{
+		"actor": auditEvent.Actor,
+		"hash":  computeHash([]byte("guessed key"), &auditEvent),
+	}).Error)
+
+	result, err := auditSvc.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, auditEvent.ID, result.InvalidEventId)
+	assert.Equal(t, "entry was modified", result.Reason)
+
+	// the entries cannot be verified once the key is lost
+	require.NoError(t, os.Remove(filepath.Join(svc.Cfg.GetEnv().Workdir, KeyFileName)))
+	result, err = auditSvc.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, "audit log key is missing", result.Reason)
+}
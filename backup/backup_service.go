@@ -0,0 +1,520 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/scheduler"
+	"github.com/getAlby/hub/utils"
+)
+
+// when the last backup is older than the interval, wait a little after
+// startup so the node can finish launching first
+const overdueBackupDelay = 5 * time.Minute
+
+// ManifestFileName is the archive entry describing a scheduled backup.
+// Backups created to migrate the hub do not have one.
+const ManifestFileName = "backup.json"
+
+// Manifest describes the contents of a scheduled backup
+type Manifest struct {
+	Scheduled bool `json:"scheduled"`
+	// the LDK node storage is only included if the channel state is kept in VSS,
+	// as restoring an outdated copy of the channel state can lead to loss of funds
+	VssEnabled bool `json:"vssEnabled"`
+}
+
+type Status struct {
+	Enabled        bool       `json:"enabled"`
+	Target         string     `json:"target,omitempty"`
+	IntervalHours  uint64     `json:"intervalHours,omitempty"`
+	LastRunAt      *time.Time `json:"lastRunAt,omitempty"`
+	LastSuccessAt  *time.Time `json:"lastSuccessAt,omitempty"`
+	LastBackupName string     `json:"lastBackupName,omitempty"`
+	LastBackupSize int64      `json:"lastBackupSize,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextRunAt      *time.Time `json:"nextRunAt,omitempty"`
+}
+
+type BackupService interface {
+	GetStatus() *Status
+	RunBackup(ctx context.Context) (*StoredBackup, error)
+	SetEncryptionKey(encryptionKey string)
+}
+
+type backupService struct {
+	db             *gorm.DB
+	cfg            config.Config
+	eventPublisher events.EventPublisher
+	lnClient       lnclient.LNClient
+	target         Target
+	scheduler      *scheduler.Scheduler
+	runMutex       sync.Mutex
+	statusMutex    sync.Mutex
+	status         Status
+
+	encryptionKeyMutex sync.Mutex
+	encryptionKey      string
+}
+
+// NewBackupService creates the backup service and, if an interval is configured,
+// starts creating backups on a schedule until ctx is cancelled. Backups are
+// encrypted with the unlock password, like backups created manually, but only
+// contain the LDK channel state if it is stored in VSS.
+func NewBackupService(ctx context.Context, db *gorm.DB, cfg config.Config, eventPublisher events.EventPublisher, lnClient lnclient.LNClient, encryptionKey string) BackupService {
+	svc := &backupService{
+		db:             db,
+		cfg:            cfg,
+		eventPublisher: eventPublisher,
+		lnClient:       lnClient,
+		encryptionKey:  encryptionKey,
+	}
+
+	intervalHours := cfg.GetEnv().BackupIntervalHours
+	if intervalHours == 0 {
+		return svc
+	}
+
+	target, err := NewTarget(cfg.GetEnv())
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to configure scheduled backups")
+		svc.status.LastError = err.Error()
+		return svc
+	}
+	svc.target = target
+	svc.status.Enabled = true
+	svc.status.Target = target.Description()
+	svc.status.IntervalHours = intervalHours
+
+	interval := time.Duration(intervalHours) * time.Hour
+	svc.scheduler = scheduler.Start(ctx, "backup", interval, func() time.Time {
+		return svc.getFirstRunAt(ctx, interval)
+	}, func(ctx context.Context) {
+		// errors are recorded in the status and published as an event
+		svc.RunBackup(ctx)
+	})
+
+	return svc
+}
+
+// NewTarget returns the configured backup target: an S3-compatible bucket if one
+// is set, otherwise a local directory
+func NewTarget(appConfig *config.AppConfig) (Target, error) {
+	if appConfig.BackupS3Bucket != "" {
+		return NewS3Target(S3Config{
+			Endpoint:        appConfig.BackupS3Endpoint,
+			Region:          appConfig.BackupS3Region,
+			Bucket:          appConfig.BackupS3Bucket,
+			Prefix:          appConfig.BackupS3Prefix,
+			AccessKeyId:     appConfig.BackupS3AccessKeyId,
+			SecretAccessKey: appConfig.BackupS3SecretAccessKey,
+		})
+	}
+
+	dir := appConfig.BackupDir
+	if dir == "" {
+		dir = filepath.Join(appConfig.Workdir, "backups")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup directory: %w", err)
+	}
+	return NewLocalTarget(absDir), nil
+}
+
+// SetEncryptionKey replaces the key backups are encrypted with after the unlock password was changed
+func (svc *backupService) SetEncryptionKey(encryptionKey string) {
+	svc.encryptionKeyMutex.Lock()
+	defer svc.encryptionKeyMutex.Unlock()
+	svc.encryptionKey = encryptionKey
+}
+
+func (svc *backupService) getEncryptionKey() (string, error) {
+	svc.encryptionKeyMutex.Lock()
+	defer svc.encryptionKeyMutex.Unlock()
+	// the backup could not be restored with a password that is no longer the unlock password
+	if !svc.cfg.CheckUnlockPassword(svc.encryptionKey) {
+		return "", errors.New("the unlock password has changed, please restart the hub to continue creating backups")
+	}
+	return svc.encryptionKey, nil
+}
+
+func (svc *backupService) GetStatus() *Status {
+	svc.statusMutex.Lock()
+	defer svc.statusMutex.Unlock()
+	status := svc.status
+	if svc.scheduler != nil {
+		status.NextRunAt = svc.scheduler.NextRunAt()
+	}
+	return &status
+}
+
+// getFirstRunAt continues the schedule of the existing backups, so restarting the
+// hub does not delay or repeat backups
+func (svc *backupService) getFirstRunAt(ctx context.Context, interval time.Duration) time.Time {
+	storedBackups, err := svc.target.List(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list existing backups")
+		return time.Now().Add(overdueBackupDelay)
+	}
+	if len(storedBackups) == 0 {
+		return time.Now().Add(overdueBackupDelay)
+	}
+	nextRunAt := storedBackups[0].CreatedAt.Add(interval)
+	if time.Until(nextRunAt) < overdueBackupDelay {
+		nextRunAt = time.Now().Add(overdueBackupDelay)
+	}
+	return nextRunAt
+}
+
+// RunBackup creates a backup without stopping the hub, uploads it to the
+// target and deletes backups that are no longer retained
+func (svc *backupService) RunBackup(ctx context.Context) (_ *StoredBackup, err error) {
+	if svc.target == nil {
+		return nil, errors.New("scheduled backups are not enabled")
+	}
+	if !svc.runMutex.TryLock() {
+		return nil, errors.New("a backup is already in progress")
+	}
+	defer svc.runMutex.Unlock()
+
+	startedAt := time.Now()
+	name := backupFileName(startedAt)
+	var size int64
+
+	defer func() {
+		svc.statusMutex.Lock()
+		svc.status.LastRunAt = &startedAt
+		if err != nil {
+			svc.status.LastError = err.Error()
+		} else {
+			svc.status.LastError = ""
+			svc.status.LastSuccessAt = &startedAt
+			svc.status.LastBackupName = name
+			svc.status.LastBackupSize = size
+		}
+		svc.statusMutex.Unlock()
+
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to create backup")
+			svc.eventPublisher.Publish(&events.Event{
+				Event: "nwc_backup_failed",
+				Properties: map[string]interface{}{
+					"error": err.Error(),
+				},
+			})
+			return
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"name":     name,
+			"size":     size,
+			"duration": time.Since(startedAt).String(),
+		}).Info("Created backup")
+		svc.eventPublisher.Publish(&events.Event{
+			Event: "nwc_backup_completed",
+			Properties: map[string]interface{}{
+				"name": name,
+				"size": size,
+			},
+		})
+	}()
+
+	workDir, err := filepath.Abs(svc.cfg.GetEnv().Workdir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute workdir: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(workDir, "albyhub-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary backup directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, name)
+	size, err = svc.createArchive(ctx, workDir, tmpDir, archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.target.Upload(ctx, name, archivePath); err != nil {
+		return nil, fmt.Errorf("failed to upload backup to %s: %w", svc.target.Description(), err)
+	}
+
+	if err := svc.applyRetention(ctx); err != nil {
+		// the new backup was stored successfully, so this is not a failed backup
+		logger.Logger.WithError(err).Error("Failed to delete old backups")
+	}
+
+	return &StoredBackup{
+		Name:      name,
+		Size:      size,
+		CreatedAt: startedAt.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// createArchive writes an encrypted archive in the same format as a manual
+// backup, using consistent snapshots of the databases instead of stopping the hub
+func (svc *backupService) createArchive(ctx context.Context, workDir string, tmpDir string, archivePath string) (int64, error) {
+	encryptionKey, err := svc.getEncryptionKey()
+	if err != nil {
+		return 0, err
+	}
+
+	dbSnapshotPath := filepath.Join(tmpDir, "nwc.db")
+	if err := svc.snapshotDatabase(ctx, dbSnapshotPath); err != nil {
+		return 0, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	archiveFile, err := os.OpenFile(archivePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer archiveFile.Close()
+
+	cw, err := EncryptingWriter(archiveFile, encryptionKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create encrypted writer: %w", err)
+	}
+	zw := zip.NewWriter(cw)
+	defer zw.Close()
+
+	addFileToZip := func(fsPath, zipPath string) error {
+		inF, err := os.Open(fsPath)
+		if err != nil {
+			return fmt.Errorf("failed to open source file for reading: %w", err)
+		}
+		defer inF.Close()
+
+		outW, err := zw.Create(zipPath)
+		if err != nil {
+			return fmt.Errorf("failed to create zip entry: %w", err)
+		}
+
+		_, err = io.Copy(outW, inF)
+		return err
+	}
+
+	if err := addFileToZip(dbSnapshotPath, "nwc.db"); err != nil {
+		return 0, fmt.Errorf("failed to write nwc db file to zip: %w", err)
+	}
+
+	manifest, err := svc.getManifest()
+	if err != nil {
+		return 0, err
+	}
+	manifestJson, err := json.Marshal(manifest)
+	if err != nil {
+		return 0, err
+	}
+	manifestW, err := zw.Create(ManifestFileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create zip entry: %w", err)
+	}
+	if _, err := manifestW.Write(manifestJson); err != nil {
+		return 0, fmt.Errorf("failed to write manifest to zip: %w", err)
+	}
+
+	// The head is not included as entries may be recorded while the database is
+	// archived, but the key is needed to verify the entries that were archived.
+	auditKeyPath := filepath.Join(workDir, audit.KeyFileName)
+	if _, err := os.Stat(auditKeyPath); err == nil {
+		if err := addFileToZip(auditKeyPath, audit.KeyFileName); err != nil {
+			return 0, fmt.Errorf("failed to write audit log key to zip: %w", err)
+		}
+	}
+
+	lnFiles, err := svc.listNodeStorageFiles(workDir, manifest)
+	if err != nil {
+		return 0, err
+	}
+	for _, lnFile := range lnFiles {
+		relPath, err := filepath.Rel(workDir, lnFile)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get relative path of input file: %w", err)
+		}
+
+		fsPath := lnFile
+		if isSqliteFile(lnFile) {
+			// the node keeps writing to its database, so copy a consistent snapshot
+			fsPath = filepath.Join(tmpDir, "node-"+filepath.Base(lnFile))
+			if err := snapshotSqliteFile(ctx, lnFile, fsPath); err != nil {
+				return 0, fmt.Errorf("failed to snapshot node database %s: %w", relPath, err)
+			}
+		}
+
+		// Ensure forward slashes for zip format compatibility.
+		if err := addFileToZip(fsPath, filepath.ToSlash(relPath)); err != nil {
+			return 0, fmt.Errorf("failed to write input file to zip: %w", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to finalize backup archive: %w", err)
+	}
+	if err := archiveFile.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to flush backup file: %w", err)
+	}
+
+	info, err := archiveFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// snapshotDatabase copies the hub database into a new sqlite database at path,
+// which is the format restore expects
+func (svc *backupService) snapshotDatabase(ctx context.Context, path string) error {
+	switch svc.db.Dialector.Name() {
+	case "sqlite":
+		// VACUUM INTO creates a consistent copy while the database stays in use
+		if err := svc.db.WithContext(ctx).Exec("VACUUM INTO ?", path).Error; err != nil {
+			return err
+		}
+		snapshotDb, err := db.NewDB(path, svc.cfg.GetEnv().LogDBQueries)
+		if err != nil {
+			return err
+		}
+		return removeSecrets(snapshotDb)
+	case "postgres":
+		snapshotDb, err := db.NewDB(path, svc.cfg.GetEnv().LogDBQueries)
+		if err != nil {
+			return err
+		}
+		// read every table from the same snapshot of the database
+		tx := svc.db.WithContext(ctx).Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+		if tx.Error != nil {
+			db.Stop(snapshotDb)
+			return tx.Error
+		}
+		err = db.MigrateDB(tx, snapshotDb)
+		if err != nil {
+			tx.Rollback()
+			db.Stop(snapshotDb)
+			return err
+		}
+		if err := tx.Commit().Error; err != nil {
+			db.Stop(snapshotDb)
+			return err
+		}
+		return removeSecrets(snapshotDb)
+	}
+	return fmt.Errorf("backups with %s backend are currently not supported", svc.db.Dialector.Name())
+}
+
+// removeSecrets removes values from the snapshot which must not be stored next
+// to the backup, then closes it
+func removeSecrets(snapshotDb *gorm.DB) error {
+	// the auto-unlock password would allow anyone with the backup file to decrypt it
+	err := snapshotDb.Exec("DELETE FROM user_configs WHERE key = ?", "AutoUnlockPassword").Error
+	if stopErr := db.Stop(snapshotDb); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+func (svc *backupService) getManifest() (*Manifest, error) {
+	ldkVssEnabled, err := svc.cfg.Get("LdkVssEnabled", "")
+	if err != nil {
+		return nil, err
+	}
+	return &Manifest{
+		Scheduled:  true,
+		VssEnabled: ldkVssEnabled == "true",
+	}, nil
+}
+
+func (svc *backupService) listNodeStorageFiles(workDir string, manifest *Manifest) ([]string, error) {
+	if svc.lnClient == nil {
+		return nil, nil
+	}
+	backendType, err := svc.cfg.Get("LNBackendType", "")
+	if err != nil {
+		return nil, err
+	}
+	if backendType == config.LDKBackendType && !manifest.VssEnabled {
+		logger.Logger.Info("Not including the LDK channel state in the backup as it is not stored in VSS")
+		return nil, nil
+	}
+	lnStorageDir, err := svc.lnClient.GetStorageDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage dir: %w", err)
+	}
+	if lnStorageDir == "" {
+		return nil, nil
+	}
+
+	lnFiles, err := filepath.Glob(filepath.Join(workDir, lnStorageDir, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in the LNClient storage directory: %w", err)
+	}
+
+	// Avoid backing up log files and files sqlite recreates from the database.
+	return utils.Filter(lnFiles, func(s string) bool {
+		return filepath.Ext(s) != ".log" && !strings.HasSuffix(s, "-wal") && !strings.HasSuffix(s, "-shm")
+	}), nil
+}
+
+func isSqliteFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".sqlite" || ext == ".sqlite3" || ext == ".db"
+}
+
+func snapshotSqliteFile(ctx context.Context, sourcePath string, destPath string) error {
+	sourceDb, err := sql.Open("sqlite3", "file:"+sourcePath+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	defer sourceDb.Close()
+	_, err = sourceDb.ExecContext(ctx, "VACUUM INTO ?", destPath)
+	return err
+}
+
+// applyRetention deletes backups beyond the configured count or age.
+// The newest backup is always kept.
+func (svc *backupService) applyRetention(ctx context.Context) error {
+	retentionCount := svc.cfg.GetEnv().BackupRetentionCount
+	retentionDays := svc.cfg.GetEnv().BackupRetentionDays
+	if retentionCount == 0 && retentionDays == 0 {
+		return nil
+	}
+
+	storedBackups, err := svc.target.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, storedBackup := range storedBackups {
+		if i == 0 {
+			continue
+		}
+		expired := retentionDays > 0 && time.Since(storedBackup.CreatedAt) > time.Duration(retentionDays)*24*time.Hour
+		if (retentionCount == 0 || uint64(i) < retentionCount) && !expired {
+			continue
+		}
+		if err := svc.target.Delete(ctx, storedBackup.Name); err != nil {
+			return fmt.Errorf("failed to delete backup %s: %w", storedBackup.Name, err)
+		}
+		logger.Logger.WithField("name", storedBackup.Name).Info("Deleted old backup")
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/getAlby/hub/config"
+)
+
+// zipMagic is the ZIP local file header signature "PK\x03\x04" — the first
+// four bytes of every ZIP file, and therefore of every backup archive.
+// DecryptingReader uses it to detect which cipher scheme the backup file
+// was created with.
+var zipMagic = []byte{'P', 'K', 0x03, 0x04}
+
+// backupCipher describes one of the cipher schemes used for backup files,
+// which are laid out as salt || iv || encrypted zip archive.
+type backupCipher struct {
+	saltSize  int
+	deriveKey func(password string, salt []byte) ([]byte, error)
+	newStream func(block cipher.Block, iv []byte) cipher.Stream
+}
+
+var backupCiphers = []backupCipher{
+	// current scheme, used for all new backup files
+	{
+		saltSize: 32,
+		deriveKey: func(password string, salt []byte) ([]byte, error) {
+			key, _, err := config.DeriveKey(password, salt)
+			return key, err
+		},
+		newStream: cipher.NewCTR,
+	},
+	// legacy scheme, kept to restore backup files created by older versions
+	{
+		saltSize: 8,
+		deriveKey: func(password string, salt []byte) ([]byte, error) {
+			return pbkdf2.Key([]byte(password), salt, 4096, 32, sha256.New), nil
+		},
+		//nolint:staticcheck // OFB is required to read files created by older versions
+		newStream: cipher.NewOFB,
+	},
+}
+
+// EncryptingWriter writes the header of a new backup file to w and returns a
+// writer that encrypts the archive written to it with the given password
+func EncryptingWriter(w io.Writer, password string) (io.Writer, error) {
+	scheme := backupCiphers[0]
+
+	salt := make([]byte, scheme.saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	encKey, err := scheme.deriveKey(password, salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate IV: %w", err)
+	}
+
+	_, err = w.Write(salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write salt: %w", err)
+	}
+
+	_, err = w.Write(iv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write IV: %w", err)
+	}
+
+	cw := &cipher.StreamWriter{
+		S: scheme.newStream(block, iv),
+		W: w,
+	}
+
+	return cw, nil
+}
+
+// DecryptingReader reads the header of a backup file from r and returns a reader
+// of the decrypted archive, supporting all current and legacy cipher schemes
+func DecryptingReader(r io.Reader, password string) (io.Reader, error) {
+	// Read the largest possible header (salt, IV and the first bytes of the
+	// archive) upfront, then trial-decrypt with each supported cipher scheme
+	// and pick the one that produces the ZIP signature.
+	maxHeaderSize := 0
+	minHeaderSize := math.MaxInt
+	for _, scheme := range backupCiphers {
+		headerSize := scheme.saltSize + aes.BlockSize + len(zipMagic)
+		maxHeaderSize = max(maxHeaderSize, headerSize)
+		minHeaderSize = min(minHeaderSize, headerSize)
+	}
+
+	// Read the full header with io.ReadFull rather than io.ReadAtLeast: the
+	// reader may deliver short reads (e.g. a network request body), and
+	// stopping early could truncate the header of a scheme with a larger
+	// salt. A short file is only acceptable if it still covers the smallest
+	// scheme header.
+	header := make([]byte, maxHeaderSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !(errors.Is(err, io.ErrUnexpectedEOF) && n >= minHeaderSize) {
+		return nil, fmt.Errorf("failed to read backup header: %w", err)
+	}
+	header = header[:n]
+
+	for _, scheme := range backupCiphers {
+		if len(header) < scheme.saltSize+aes.BlockSize+len(zipMagic) {
+			continue
+		}
+		salt := header[:scheme.saltSize]
+		iv := header[scheme.saltSize : scheme.saltSize+aes.BlockSize]
+		encrypted := header[scheme.saltSize+aes.BlockSize:]
+
+		encKey, err := scheme.deriveKey(password, salt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+		}
+
+		block, err := aes.NewCipher(encKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+		}
+
+		stream := scheme.newStream(block, iv)
+		decrypted := make([]byte, len(encrypted))
+		stream.XORKeyStream(decrypted, encrypted)
+		if !bytes.Equal(decrypted[:len(zipMagic)], zipMagic) {
+			continue
+		}
+
+		cr := &cipher.StreamReader{
+			S: stream,
+			R: r,
+		}
+
+		return io.MultiReader(bytes.NewReader(decrypted), cr), nil
+	}
+
+	return nil, errors.New("invalid unlock password or backup file")
+}
@@ -0,0 +1,257 @@
+package backup
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyId     string
+	SecretAccessKey string
+}
+
+// s3Target stores backups in an S3-compatible bucket using path-style requests
+// signed with AWS Signature Version 4
+type s3Target struct {
+	config     S3Config
+	httpClient *http.Client
+}
+
+func NewS3Target(config S3Config) (*s3Target, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("an S3 endpoint and bucket are required")
+	}
+	if _, err := url.Parse(config.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Prefix != "" && !strings.HasSuffix(config.Prefix, "/") {
+		config.Prefix += "/"
+	}
+	return &s3Target{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Minute,
+		},
+	}, nil
+}
+
+func (target *s3Target) Description() string {
+	return strings.TrimSuffix(target.config.Endpoint, "/") + "/" + target.config.Bucket + "/" + target.config.Prefix
+}
+
+func (target *s3Target) Upload(ctx context.Context, name string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the payload hash is part of the signature, so the file is read twice
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := target.newRequest(ctx, http.MethodPut, target.config.Prefix+name, nil, file, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	_, err = target.do(req)
+	return err
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (target *s3Target) List(ctx context.Context) ([]StoredBackup, error) {
+	storedBackups := []StoredBackup{}
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", target.config.Prefix+backupFilePrefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		req, err := target.newRequest(ctx, http.MethodGet, "", query, nil, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		body, err := target.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse S3 list response: %w", err)
+		}
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, target.config.Prefix)
+			createdAt, ok := parseBackupFileName(name)
+			if !ok {
+				continue
+			}
+			storedBackups = append(storedBackups, StoredBackup{
+				Name:      name,
+				Size:      object.Size,
+				CreatedAt: createdAt,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+
+	sortNewestFirst(storedBackups)
+	return storedBackups, nil
+}
+
+func (target *s3Target) Delete(ctx context.Context, name string) error {
+	if _, ok := parseBackupFileName(name); !ok {
+		return fmt.Errorf("invalid backup name: %s", name)
+	}
+	req, err := target.newRequest(ctx, http.MethodDelete, target.config.Prefix+name, nil, nil, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	_, err = target.do(req)
+	return err
+}
+
+func (target *s3Target) newRequest(ctx context.Context, method string, key string, query url.Values, body io.Reader, payloadHash string) (*http.Request, error) {
+	requestUrl, err := url.Parse(strings.TrimSuffix(target.config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	requestUrl.Path += "/" + target.config.Bucket
+	if key != "" {
+		requestUrl.Path += "/" + key
+	}
+	requestUrl.RawQuery = encodeQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, requestUrl.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	target.sign(req, payloadHash, time.Now())
+	return req, nil
+}
+
+func (target *s3Target) do(req *http.Request) ([]byte, error) {
+	res, err := target.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("S3 request %s %s failed with status %d: %s", req.Method, req.URL.Path, res.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// sign adds an AWS Signature Version 4 authorization header to the request
+func (target *s3Target) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + target.config.Region + "/s3/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+target.config.SecretAccessKey), date)
+	signingKey = hmacSha256(signingKey, target.config.Region)
+	signingKey = hmacSha256(signingKey, "s3")
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		target.config.AccessKeyId, scope, signedHeaders, signature))
+}
+
+// encodeQuery encodes query parameters sorted by name with spaces as %20, as required by SigV4
+func encodeQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
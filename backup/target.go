@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupFilePrefix = "albyhub-backup-"
+	backupFileSuffix = ".bkp"
+	backupTimeFormat = "20060102T150405Z"
+)
+
+// StoredBackup is a backup file stored in a target
+type StoredBackup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Target is a location scheduled backups are written to
+type Target interface {
+	// Description identifies the target in logs and the backup status, without credentials
+	Description() string
+	Upload(ctx context.Context, name string, path string) error
+	// List returns the backups stored in the target, newest first
+	List(ctx context.Context) ([]StoredBackup, error)
+	Delete(ctx context.Context, name string) error
+}
+
+func backupFileName(createdAt time.Time) string {
+	return backupFilePrefix + createdAt.UTC().Format(backupTimeFormat) + backupFileSuffix
+}
+
+// parseBackupFileName returns the creation time of a backup file, or false if
+// the file was not created by the backup scheduler
+func parseBackupFileName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+		return time.Time{}, false
+	}
+	createdAt, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), backupFileSuffix))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return createdAt, true
+}
+
+func sortNewestFirst(storedBackups []StoredBackup) {
+	sort.Slice(storedBackups, func(i, j int) bool {
+		return storedBackups[i].CreatedAt.After(storedBackups[j].CreatedAt)
+	})
+}
+
+type localTarget struct {
+	dir string
+}
+
+func NewLocalTarget(dir string) *localTarget {
+	return &localTarget{
+		dir: dir,
+	}
+}
+
+func (target *localTarget) Description() string {
+	return target.dir
+}
+
+func (target *localTarget) Upload(ctx context.Context, name string, path string) error {
+	if err := os.MkdirAll(target.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	inF, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer inF.Close()
+
+	// write to a temporary file first so an interrupted copy never looks like a complete backup
+	outF, err := os.CreateTemp(target.dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	tmpName := outF.Name()
+	defer os.Remove(tmpName)
+
+	_, err = io.Copy(outF, inF)
+	if err == nil {
+		err = outF.Sync()
+	}
+	if closeErr := outF.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+
+	return os.Rename(tmpName, filepath.Join(target.dir, name))
+}
+
+func (target *localTarget) List(ctx context.Context) ([]StoredBackup, error) {
+	entries, err := os.ReadDir(target.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []StoredBackup{}, nil
+		}
+		return nil, err
+	}
+
+	storedBackups := []StoredBackup{}
+	for _, entry := range entries {
+		createdAt, ok := parseBackupFileName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		storedBackups = append(storedBackups, StoredBackup{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+	sortNewestFirst(storedBackups)
+	return storedBackups, nil
+}
+
+func (target *localTarget) Delete(ctx context.Context, name string) error {
+	if _, ok := parseBackupFileName(name); !ok {
+		return fmt.Errorf("invalid backup name: %s", name)
+	}
+	return os.Remove(filepath.Join(target.dir, name))
+}
@@ -0,0 +1,244 @@
+package backup
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// nodeStorageDirs are the directories (relative to the work dir) a backup must
+// contain to restore a node with the given backend. Backends not listed here
+// keep their state outside of Alby Hub.
+var nodeStorageDirs = map[string]string{
+	config.LDKBackendType: "ldk/storage",
+}
+
+// VerificationResult describes a backup which was decrypted and opened successfully
+type VerificationResult struct {
+	BackendType          string `json:"backendType"`
+	FileCount            int    `json:"fileCount"`
+	MigrationsApplied    int    `json:"migrationsApplied"`
+	AppCount             int64  `json:"appCount"`
+	TransactionCount     int64  `json:"transactionCount"`
+	SwapCount            int64  `json:"swapCount"`
+	NodeStorageDir       string `json:"nodeStorageDir,omitempty"`
+	NodeStorageFileCount int    `json:"nodeStorageFileCount"`
+	Scheduled            bool   `json:"scheduled"`
+}
+
+// ReadManifest returns the manifest of a scheduled backup, or nil if the
+// archive was created to migrate the hub
+func ReadManifest(zr *zip.Reader) (*Manifest, error) {
+	for _, zipFile := range zr.File {
+		if zipFile.Name != ManifestFileName {
+			continue
+		}
+		r, err := zipFile.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open backup manifest: %w", err)
+		}
+		defer r.Close()
+		manifest := &Manifest{}
+		if err := json.NewDecoder(r).Decode(manifest); err != nil {
+			return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+		}
+		return manifest, nil
+	}
+	return nil, nil
+}
+
+// ContainsNodeStorage returns true if the archive contains files of the node storage of the backend
+func ContainsNodeStorage(zr *zip.Reader, backendType string) bool {
+	storageDir, ok := nodeStorageDirs[backendType]
+	if !ok {
+		return false
+	}
+	for _, zipFile := range zr.File {
+		if strings.HasPrefix(zipFile.Name, storageDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks that a backup file can be restored by extracting it into a
+// temporary directory and migrating a copy of the contained database. Nothing
+// outside the temporary directory is modified. If backendType is empty, the
+// backend recorded in the backup is checked.
+func Verify(r io.Reader, unlockPassword string, backendType string) (*VerificationResult, error) {
+	cr, err := DecryptingReader(r, unlockPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "albyhub-verify-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "backup.zip")
+	archiveFile, err := os.OpenFile(archivePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer archiveFile.Close()
+
+	zipSize, err := io.Copy(archiveFile, cr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+
+	zr, err := zip.NewReader(archiveFile, zipSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup archive: %w", err)
+	}
+	if len(zr.File) == 0 {
+		return nil, errors.New("backup file contains no files")
+	}
+	manifest, err := ReadManifest(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	extractDir := filepath.Join(tmpDir, "restore")
+	for _, zipFile := range zr.File {
+		if err := extractZipEntry(zipFile, extractDir); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &VerificationResult{
+		FileCount: len(zr.File),
+		Scheduled: manifest != nil && manifest.Scheduled,
+	}
+
+	dbPath := filepath.Join(extractDir, "nwc.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, errors.New("backup does not contain a database")
+	}
+
+	migrationsBefore, err := countMigrations(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup database: %w", err)
+	}
+
+	// migrate the extracted copy in the same way as a restored hub would on startup
+	gormDb, err := db.NewDB(dbPath, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate backup database: %w", err)
+	}
+	defer db.Stop(gormDb)
+
+	var migrationsAfter int64
+	if err := gormDb.Table("migrations").Count(&migrationsAfter).Error; err != nil {
+		return nil, err
+	}
+	result.MigrationsApplied = int(migrationsAfter - migrationsBefore)
+
+	for table, count := range map[string]*int64{
+		"apps":         &result.AppCount,
+		"transactions": &result.TransactionCount,
+		"swaps":        &result.SwapCount,
+	} {
+		if err := gormDb.Table(table).Count(count).Error; err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+	}
+
+	var storedBackendType string
+	err = gormDb.Raw("SELECT value FROM user_configs WHERE key = ?", "LNBackendType").Scan(&storedBackendType).Error
+	if err != nil {
+		return nil, err
+	}
+	if backendType == "" {
+		backendType = storedBackendType
+	}
+	if backendType == "" {
+		return nil, errors.New("backup does not contain a configured node backend")
+	}
+	result.BackendType = backendType
+
+	// scheduled backups only contain the LDK channel state if it is stored in VSS
+	channelStateExcluded := result.Scheduled && !manifest.VssEnabled
+	if storageDir, ok := nodeStorageDirs[backendType]; ok && !channelStateExcluded {
+		result.NodeStorageDir = storageDir
+		storageFiles, err := filepath.Glob(filepath.Join(extractDir, filepath.FromSlash(storageDir), "*"))
+		if err != nil {
+			return nil, err
+		}
+		result.NodeStorageFileCount = len(storageFiles)
+		if len(storageFiles) == 0 {
+			return nil, fmt.Errorf("backup does not contain node storage for the %s backend", backendType)
+		}
+	}
+
+	logger.Logger.WithField("result", result).Info("Verified backup")
+
+	return result, nil
+}
+
+func extractZipEntry(zipFile *zip.File, dir string) error {
+	// Entry names come from the archive and must not be trusted.
+	entryName := filepath.FromSlash(zipFile.Name)
+	if !filepath.IsLocal(entryName) {
+		return fmt.Errorf("backup contains a file outside of the restore directory: %q", zipFile.Name)
+	}
+	fsFilePath := filepath.Join(dir, entryName)
+	if !strings.HasPrefix(fsFilePath, dir+string(os.PathSeparator)) {
+		return fmt.Errorf("backup contains a file outside of the restore directory: %q", zipFile.Name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fsFilePath), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for zip entry: %w", err)
+	}
+
+	inF, err := zipFile.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open zip entry %q: %w", zipFile.Name, err)
+	}
+	defer inF.Close()
+
+	outF, err := os.OpenFile(fsFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer outF.Close()
+
+	// the checksum of each entry is verified when it is read to the end
+	if _, err := io.Copy(outF, inF); err != nil {
+		return fmt.Errorf("failed to extract zip entry %q: %w", zipFile.Name, err)
+	}
+	return nil
+}
+
+// countMigrations returns the number of migrations applied to a database
+// before it is opened with db.NewDB, which migrates it
+func countMigrations(dbPath string) (int64, error) {
+	sqlDb, err := sql.Open("sqlite3", "file:"+dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer sqlDb.Close()
+
+	var tableCount int
+	if err := sqlDb.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'migrations'").Scan(&tableCount); err != nil {
+		return 0, err
+	}
+	if tableCount == 0 {
+		return 0, nil
+	}
+	var count int64
+	err = sqlDb.QueryRow("SELECT COUNT(*) FROM migrations").Scan(&count)
+	return count, err
+}
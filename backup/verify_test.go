@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func createTestBackup(t *testing.T, svc *tests.TestService) string {
+	target := NewLocalTarget(t.TempDir())
+	storedBackup, err := newTestBackupService(t, svc, target).RunBackup(context.TODO())
+	require.NoError(t, err)
+	return filepath.Join(target.dir, storedBackup.Name)
+}
+
+// removeTestManifest returns a copy of a backup without its manifest, like a backup
+// created to migrate the hub
+func removeTestManifest(t *testing.T, r io.Reader) []byte {
+	cr, err := DecryptingReader(r, unlockPassword)
+	require.NoError(t, err)
+	archive, err := io.ReadAll(cr)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	cw, err := EncryptingWriter(&buf, unlockPassword)
+	require.NoError(t, err)
+	zw := zip.NewWriter(cw)
+	for _, zipFile := range zr.File {
+		if zipFile.Name == ManifestFileName {
+			continue
+		}
+		require.NoError(t, zw.Copy(zipFile))
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestVerify(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate("LNBackendType", config.LNDBackendType, ""))
+	_, _, err = tests.CreateApp(svc)
+	require.NoError(t, err)
+	require.NoError(t, svc.DB.Create(&db.Transaction{State: "SETTLED", Type: "incoming", PaymentHash: "hash"}).Error)
+
+	backupFile, err := os.Open(createTestBackup(t, svc))
+	require.NoError(t, err)
+	defer backupFile.Close()
+
+	result, err := Verify(backupFile, unlockPassword, "")
+	require.NoError(t, err)
+	assert.Equal(t, config.LNDBackendType, result.BackendType)
+	assert.Equal(t, 2, result.FileCount)
+	assert.True(t, result.Scheduled)
+	assert.Equal(t, 0, result.MigrationsApplied)
+	assert.Equal(t, int64(1), result.AppCount)
+	assert.Equal(t, int64(1), result.TransactionCount)
+	assert.Equal(t, int64(0), result.SwapCount)
+	assert.Empty(t, result.NodeStorageDir)
+}
+
+func TestVerify_MissingNodeStorage(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate("LNBackendType", config.LDKBackendType, ""))
+	backupPath := createTestBackup(t, svc)
+
+	backupFile, err := os.Open(backupPath)
+	require.NoError(t, err)
+	defer backupFile.Close()
+
+	// scheduled backups do not contain the channel state unless it is stored in VSS
+	result, err := Verify(backupFile, unlockPassword, "")
+	require.NoError(t, err)
+	assert.True(t, result.Scheduled)
+	assert.Empty(t, result.NodeStorageDir)
+
+	// a migration backup without the manifest must contain the node storage
+	_, err = backupFile.Seek(0, 0)
+	require.NoError(t, err)
+	migrationBackup := removeTestManifest(t, backupFile)
+	_, err = Verify(bytes.NewReader(migrationBackup), unlockPassword, "")
+	assert.EqualError(t, err, "backup does not contain node storage for the LDK backend")
+
+	// the backend can be overridden
+	result, err = Verify(bytes.NewReader(migrationBackup), unlockPassword, config.PhoenixBackendType)
+	require.NoError(t, err)
+	assert.Equal(t, config.PhoenixBackendType, result.BackendType)
+}
+
+func TestVerify_WrongPassword(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	backupFile, err := os.Open(createTestBackup(t, svc))
+	require.NoError(t, err)
+	defer backupFile.Close()
+
+	_, err = Verify(backupFile, "wrong-password", "")
+	assert.EqualError(t, err, "invalid unlock password or backup file")
+}
+
+func TestVerify_InvalidArchive(t *testing.T) {
+	for name, entries := range map[string]map[string]string{
+		"no database":      {"ldk/storage/ldk_node_data.sqlite": "data"},
+		"path traversal":   {"../nwc.db": "data"},
+		"invalid database": {"nwc.db": "not a database"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cw, err := EncryptingWriter(&buf, unlockPassword)
+			require.NoError(t, err)
+			zw := zip.NewWriter(cw)
+			for entryName, contents := range entries {
+				w, err := zw.Create(entryName)
+				require.NoError(t, err)
+				_, err = w.Write([]byte(contents))
+				require.NoError(t, err)
+			}
+			require.NoError(t, zw.Close())
+
+			_, err = Verify(bytes.NewReader(buf.Bytes()), unlockPassword, "")
+			assert.Error(t, err)
+		})
+	}
+}
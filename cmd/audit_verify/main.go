@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// Verifies the hash chain of the audit log against the key and head stored in
+// the hub's work directory. Exits with status 1 if the database or work
+// directory cannot be read and with status 2 if the audit log was tampered with.
+func main() {
+	var dsn string
+	var workDir string
+
+	logger.Init(strconv.Itoa(int(logrus.InfoLevel)))
+
+	flag.StringVar(&dsn, "db", "", "database DSN (sqlite file path or postgres URI)")
+	flag.StringVar(&workDir, "workdir", "", "work directory of the hub containing the audit log key and head")
+
+	flag.Parse()
+
+	if dsn == "" {
+		flag.Usage()
+		logger.Logger.Error("missing DSN")
+		os.Exit(1)
+	}
+	if workDir == "" {
+		flag.Usage()
+		logger.Logger.Error("missing work directory")
+		os.Exit(1)
+	}
+
+	gormDB, err := db.NewDB(dsn, false)
+	if err != nil {
+		logger.Logger.WithError(err).Error("failed to open database")
+		os.Exit(1)
+	}
+
+	result, err := audit.NewAuditService(gormDB, workDir).Verify()
+	if stopErr := db.Stop(gormDB); stopErr != nil {
+		logger.Logger.WithError(stopErr).Error("failed to close database")
+	}
+	if err != nil {
+		logger.Logger.WithError(err).Error("failed to verify audit log")
+		os.Exit(1)
+	}
+
+	if !result.Valid {
+		logger.Logger.WithFields(logrus.Fields{
+			"verified_events":  result.EventCount,
+			"invalid_event_id": result.InvalidEventId,
+			"reason":           result.Reason,
+		}).Error("audit log verification failed")
+		os.Exit(2)
+	}
+
+	logger.Logger.WithField("verified_events", result.EventCount).Info("audit log is intact")
+}
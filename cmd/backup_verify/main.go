@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/backup"
+	"github.com/getAlby/hub/logger"
+)
+
+// Checks that a backup file can be decrypted and restored, without restoring it.
+// The unlock password is read from stdin if it is not passed as a flag.
+// Exits with status 1 if the backup cannot be verified.
+func main() {
+	var filePath string
+	var unlockPassword string
+	var backendType string
+
+	logger.Init(strconv.Itoa(int(logrus.InfoLevel)))
+
+	flag.StringVar(&filePath, "file", "", "path to the backup file")
+	flag.StringVar(&unlockPassword, "password", "", "unlock password the backup was created with")
+	flag.StringVar(&backendType, "backend", "", "node backend to check node storage for (default: the backend configured in the backup)")
+
+	flag.Parse()
+
+	if filePath == "" {
+		flag.Usage()
+		logger.Logger.Error("missing backup file")
+		os.Exit(1)
+	}
+
+	if unlockPassword == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			logger.Logger.WithError(err).Error("failed to read unlock password")
+			os.Exit(1)
+		}
+		unlockPassword = strings.TrimRight(line, "\r\n")
+	}
+
+	backupFile, err := os.Open(filePath)
+	if err != nil {
+		logger.Logger.WithError(err).Error("failed to open backup file")
+		os.Exit(1)
+	}
+	defer backupFile.Close()
+
+	result, err := backup.Verify(backupFile, unlockPassword, backendType)
+	if err != nil {
+		logger.Logger.WithError(err).Error("backup verification failed")
+		os.Exit(1)
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"backend_type":            result.BackendType,
+		"files":                   result.FileCount,
+		"migrations_applied":      result.MigrationsApplied,
+		"apps":                    result.AppCount,
+		"transactions":            result.TransactionCount,
+		"swaps":                   result.SwapCount,
+		"node_storage_dir":        result.NodeStorageDir,
+		"node_storage_file_count": result.NodeStorageFileCount,
+	}).Info("backup is valid")
+}
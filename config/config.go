@@ -25,6 +25,8 @@ type config struct {
 	cacheMutex     sync.Mutex
 	jwtSecret      string
 	jwtSecretMutex sync.Mutex
+	dataKeys       map[string][]byte // encryptionKeyHash -> unwrapped data key
+	dataKeyMutex   sync.Mutex        // held while encrypted values are read or written
 }
 
 const (
@@ -33,8 +35,9 @@ const (
 
 func NewConfig(env *AppConfig, db *gorm.DB) (*config, error) {
 	cfg := &config{
-		db:    db,
-		cache: map[string]map[string]string{},
+		db:       db,
+		cache:    map[string]map[string]string{},
+		dataKeys: map[string][]byte{},
 	}
 	err := cfg.init(env)
 	if err != nil {
@@ -267,6 +270,12 @@ func (cfg *config) Get(key string, encryptionKey string) (string, error) {
 }
 
 func (cfg *config) get(key string, encryptionKey string, gormDB *gorm.DB) (string, error) {
+	if encryptionKey != "" {
+		// the value must not be re-encrypted by a data key rotation after it is read
+		cfg.dataKeyMutex.Lock()
+		defer cfg.dataKeyMutex.Unlock()
+	}
+
 	var userConfig db.UserConfig
 	err := gormDB.Where(&db.UserConfig{Key: key}).Limit(1).Find(&userConfig).Error
 	if err != nil {
@@ -275,18 +284,52 @@ func (cfg *config) get(key string, encryptionKey string, gormDB *gorm.DB) (strin
 
 	value := userConfig.Value
 	if userConfig.Value != "" && encryptionKey != "" && userConfig.Encrypted {
-		decrypted, err := AesGcmDecryptWithPassword(value, encryptionKey)
+		var dataKey []byte
+		if isEnvelopeValue(value) {
+			dataKey, err = cfg.getDataKeyLocked(encryptionKey, false)
+			if err != nil {
+				return "", err
+			}
+		}
+		decrypted, err := decryptValue(value, encryptionKey, dataKey)
 		if err != nil {
 			return "", err
 		}
+		if dataKey == nil {
+			cfg.upgradeLegacyValue(key, value, decrypted, encryptionKey)
+		}
 		value = decrypted
 	}
 	return value, nil
 }
 
 func (cfg *config) set(key string, value string, clauses clause.OnConflict, encryptionKey string, gormDB *gorm.DB) error {
+	err := cfg.saveValue(key, value, clauses, encryptionKey, gormDB)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithField("key", key).Debug("clearing config cache")
+	cfg.cacheMutex.Lock()
+	defer cfg.cacheMutex.Unlock()
+	delete(cfg.cache, key)
+
+	return nil
+}
+
+// saveValue encrypts and saves the value. The data key mutex must be released
+// before the cache mutex is acquired, as Get acquires them the other way around.
+func (cfg *config) saveValue(key string, value string, clauses clause.OnConflict, encryptionKey string, gormDB *gorm.DB) error {
 	if encryptionKey != "" {
-		encrypted, err := AesGcmEncryptWithPassword(value, encryptionKey)
+		// the value must be saved before a data key rotation re-encrypts the values
+		cfg.dataKeyMutex.Lock()
+		defer cfg.dataKeyMutex.Unlock()
+
+		dataKey, err := cfg.getDataKeyLocked(encryptionKey, true)
+		if err != nil {
+			return fmt.Errorf("failed to get data key: %w", err)
+		}
+		encrypted, err := encryptWithDataKey(value, dataKey)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt: %v", err)
 		}
@@ -298,12 +341,6 @@ func (cfg *config) set(key string, value string, clauses clause.OnConflict, encr
 	if result.Error != nil {
 		return fmt.Errorf("failed to save key to config: %v", result.Error)
 	}
-
-	logger.Logger.WithField("key", key).Debug("clearing config cache")
-	cfg.cacheMutex.Lock()
-	defer cfg.cacheMutex.Unlock()
-	delete(cfg.cache, key)
-
 	return nil
 }
 
@@ -340,36 +377,13 @@ func (cfg *config) ChangeUnlockPassword(currentUnlockPassword string, newUnlockP
 	if !cfg.CheckUnlockPassword(currentUnlockPassword) {
 		return errors.New("incorrect password")
 	}
-	err := cfg.db.Transaction(func(tx *gorm.DB) error {
-
-		var encryptedUserConfigs []db.UserConfig
-		err := tx.Where(&db.UserConfig{Encrypted: true}).Find(&encryptedUserConfigs).Error
-		if err != nil {
-			return err
-		}
-
-		logger.Logger.WithField("count", len(encryptedUserConfigs)).Info("Updating encrypted entries")
-
-		for _, userConfig := range encryptedUserConfigs {
-			decryptedValue, err := cfg.get(userConfig.Key, currentUnlockPassword, tx)
-			if err != nil {
-				logger.Logger.WithField("key", userConfig.Key).WithError(err).Error("Failed to decrypt key")
-				return err
-			}
-			clauses := clause.OnConflict{
-				Columns:   []clause.Column{{Name: "key"}},
-				DoUpdates: clause.AssignmentColumns([]string{"value"}),
-			}
-			err = cfg.set(userConfig.Key, decryptedValue, clauses, newUnlockPassword, tx)
-			if err != nil {
-				logger.Logger.WithField("key", userConfig.Key).WithError(err).Error("Failed to encrypt key")
-				return err
-			}
-			logger.Logger.WithField("key", userConfig.Key).Info("re-encrypted key")
-		}
 
+	// a new data key is created rather than re-wrapping the current one, so that
+	// the old password cannot unwrap a data key which decrypts the current values
+	cfg.dataKeyMutex.Lock()
+	err := cfg.rotateDataKeyLocked(currentUnlockPassword, newUnlockPassword, func(tx *gorm.DB) error {
 		// delete the JWT secret so it will be re-generated on next unlock (to log all sessions out on password change)
-		err = tx.Where(&db.UserConfig{Key: "JWTSecret"}).Delete(&db.UserConfig{}).Error
+		err := tx.Where(&db.UserConfig{Key: "JWTSecret"}).Delete(&db.UserConfig{}).Error
 		if err != nil {
 			logger.Logger.WithError(err).Error("failed to remove JWT secret during password change transaction")
 			return fmt.Errorf("failed to delete new JWT secret: %w", err)
@@ -378,12 +392,18 @@ func (cfg *config) ChangeUnlockPassword(currentUnlockPassword string, newUnlockP
 		logger.Logger.Info("Successfully removed JWT secret as part of password change transaction")
 		return nil
 	})
+	cfg.dataKeyMutex.Unlock()
 
 	if err != nil {
 		logger.Logger.WithError(err).Error("failed to execute password change transaction")
 		return err
 	}
 
+	// cached values were decrypted with the old password
+	cfg.cacheMutex.Lock()
+	cfg.cache = map[string]map[string]string{}
+	cfg.cacheMutex.Unlock()
+
 	// JWT secret will be set on config unlock (required after password change)
 	cfg.jwtSecretMutex.Lock()
 	cfg.jwtSecret = ""
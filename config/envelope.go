@@ -0,0 +1,469 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// Encrypted config values are encrypted with a random data encryption key (DEK),
+// which is stored wrapped by a key derived from the unlock password. Changing the
+// password or the KDF parameters only re-wraps the DEK.
+//
+// Value formats:
+//   - legacy: <salt>-<nonce>-<ciphertext>, encrypted with a key derived from the password
+//   - v2: 2$<nonce>-<ciphertext>, encrypted with the DEK
+//
+// Older Hub versions can only read legacy values, so a database containing v2
+// values cannot be opened by a Hub version without data key support.
+//
+// Wrapped DEK format: 2$<time>$<memoryKiB>$<threads>$<salt>$<nonce>-<ciphertext>
+const (
+	dataKeyConfigKey         = "DataEncryptionKey"
+	envelopeVersionPrefix    = "2$"
+	dataKeyLength            = 32
+	minKDFTime               = 1
+	maxKDFTime               = 64
+	minKDFMemoryKiB          = 32 * 1024
+	maxKDFMemoryKiB          = 4 * 1024 * 1024
+	maxKDFThreads            = 64
+	envelopeValueLikePattern = envelopeVersionPrefix + "%"
+)
+
+// KDFParams are the Argon2id parameters used to derive the key which wraps the data key
+type KDFParams struct {
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// DefaultKDFParams are used when a data key is first created
+var DefaultKDFParams = KDFParams{
+	Time:      3,
+	MemoryKiB: 64 * 1024,
+	Threads:   1,
+}
+
+type EncryptionStatus struct {
+	DataKeyCreated bool      `json:"dataKeyCreated"`
+	KDF            KDFParams `json:"kdf"`
+	// LegacyValueCount is the number of values which will be re-encrypted with the
+	// data key when they are next read
+	LegacyValueCount int64 `json:"legacyValueCount"`
+}
+
+func (params KDFParams) validate() error {
+	if params.Time < minKDFTime || params.Time > maxKDFTime {
+		return fmt.Errorf("KDF time must be between %d and %d", minKDFTime, maxKDFTime)
+	}
+	if params.MemoryKiB < minKDFMemoryKiB || params.MemoryKiB > maxKDFMemoryKiB {
+		return fmt.Errorf("KDF memory must be between %d and %d KiB", minKDFMemoryKiB, maxKDFMemoryKiB)
+	}
+	if params.Threads < 1 || params.Threads > maxKDFThreads {
+		return fmt.Errorf("KDF threads must be between 1 and %d", maxKDFThreads)
+	}
+	return nil
+}
+
+// weakerThan returns true if the time or memory cost is lower than in other
+func (params KDFParams) weakerThan(other KDFParams) bool {
+	return params.Time < other.Time || params.MemoryKiB < other.MemoryKiB
+}
+
+func isEnvelopeValue(value string) bool {
+	return strings.HasPrefix(value, envelopeVersionPrefix)
+}
+
+func wrapDataKey(dataKey []byte, password string, params KDFParams) (string, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	wrappingKey := argon2.IDKey([]byte(password), salt, params.Time, params.MemoryKiB, params.Threads, 32)
+
+	ciphertext, err := AesGcmEncryptWithKey(hex.EncodeToString(dataKey), wrappingKey)
+	if err != nil {
+		return "", err
+	}
+
+	return envelopeVersionPrefix + strings.Join([]string{
+		strconv.FormatUint(uint64(params.Time), 10),
+		strconv.FormatUint(uint64(params.MemoryKiB), 10),
+		strconv.FormatUint(uint64(params.Threads), 10),
+		hex.EncodeToString(salt),
+		ciphertext,
+	}, "$"), nil
+}
+
+func parseWrappedDataKey(wrappedDataKey string) (KDFParams, []byte, string, error) {
+	parts := strings.Split(strings.TrimPrefix(wrappedDataKey, envelopeVersionPrefix), "$")
+	if !isEnvelopeValue(wrappedDataKey) || len(parts) != 5 {
+		return KDFParams{}, nil, "", errors.New("unsupported data key format")
+	}
+	time, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return KDFParams{}, nil, "", err
+	}
+	memoryKiB, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return KDFParams{}, nil, "", err
+	}
+	threads, err := strconv.ParseUint(parts[2], 10, 8)
+	if err != nil {
+		return KDFParams{}, nil, "", err
+	}
+	salt, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return KDFParams{}, nil, "", err
+	}
+	params := KDFParams{
+		Time:      uint32(time),
+		MemoryKiB: uint32(memoryKiB),
+		Threads:   uint8(threads),
+	}
+	return params, salt, parts[4], nil
+}
+
+func unwrapDataKey(wrappedDataKey string, password string) ([]byte, error) {
+	params, salt, ciphertext, err := parseWrappedDataKey(wrappedDataKey)
+	if err != nil {
+		return nil, err
+	}
+	wrappingKey := argon2.IDKey([]byte(password), salt, params.Time, params.MemoryKiB, params.Threads, 32)
+
+	dataKeyHex, err := AesGcmDecryptWithKey(ciphertext, wrappingKey)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(dataKeyHex)
+}
+
+func encryptWithDataKey(plaintext string, dataKey []byte) (string, error) {
+	ciphertext, err := AesGcmEncryptWithKey(plaintext, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return envelopeVersionPrefix + ciphertext, nil
+}
+
+func decryptWithDataKey(value string, dataKey []byte) (string, error) {
+	ciphertext := strings.TrimPrefix(value, envelopeVersionPrefix)
+	if strings.Count(ciphertext, "-") != 1 {
+		return "", errors.New("invalid encrypted value")
+	}
+	return AesGcmDecryptWithKey(ciphertext, dataKey)
+}
+
+// decryptValue decrypts a value in either format. The data key is only required
+// for v2 values.
+func decryptValue(value string, password string, dataKey []byte) (string, error) {
+	if isEnvelopeValue(value) {
+		if dataKey == nil {
+			return "", errors.New("data key required")
+		}
+		return decryptWithDataKey(value, dataKey)
+	}
+	if strings.Count(value, "-") != 2 {
+		return "", errors.New("invalid encrypted value")
+	}
+	return AesGcmDecryptWithPassword(value, password)
+}
+
+func (cfg *config) getWrappedDataKey(gormDB *gorm.DB) (string, error) {
+	var userConfig db.UserConfig
+	err := gormDB.Where(&db.UserConfig{Key: dataKeyConfigKey}).Limit(1).Find(&userConfig).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to get data key: %w", err)
+	}
+	return userConfig.Value, nil
+}
+
+func (cfg *config) saveWrappedDataKey(wrappedDataKey string, clauses clause.OnConflict, gormDB *gorm.DB) error {
+	userConfig := db.UserConfig{Key: dataKeyConfigKey, Value: wrappedDataKey, Encrypted: true}
+	return gormDB.Clauses(clauses).Create(&userConfig).Error
+}
+
+// getDataKey returns the data key, unwrapping it with the password if it is not
+// cached yet. If create is true and no data key exists, a new one is created.
+// It must not be called within a transaction, as the result is cached.
+func (cfg *config) getDataKey(password string, create bool) ([]byte, error) {
+	cfg.dataKeyMutex.Lock()
+	defer cfg.dataKeyMutex.Unlock()
+	return cfg.getDataKeyLocked(password, create)
+}
+
+// getDataKeyLocked is getDataKey for callers which already hold dataKeyMutex
+func (cfg *config) getDataKeyLocked(password string, create bool) ([]byte, error) {
+	passwordHash := cfg.getEncryptionKeyHash(password)
+	if dataKey, ok := cfg.dataKeys[passwordHash]; ok {
+		return dataKey, nil
+	}
+
+	wrappedDataKey, err := cfg.getWrappedDataKey(cfg.db)
+	if err != nil {
+		return nil, err
+	}
+
+	if wrappedDataKey == "" {
+		if !create {
+			return nil, errors.New("no data key exists")
+		}
+		if err := cfg.checkLegacyUnlockPassword(password); err != nil {
+			return nil, err
+		}
+
+		dataKey := make([]byte, dataKeyLength)
+		if _, err := rand.Read(dataKey); err != nil {
+			return nil, err
+		}
+		wrappedDataKey, err = wrapDataKey(dataKey, password, DefaultKDFParams)
+		if err != nil {
+			return nil, err
+		}
+		err = cfg.saveWrappedDataKey(wrappedDataKey, clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoNothing: true,
+		}, cfg.db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save data key: %w", err)
+		}
+		logger.Logger.Info("Created data encryption key")
+
+		// another instance may have created a data key at the same time
+		wrappedDataKey, err = cfg.getWrappedDataKey(cfg.db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dataKey, err := unwrapDataKey(wrappedDataKey, password)
+	if err != nil {
+		return nil, err
+	}
+	cfg.dataKeys[passwordHash] = dataKey
+	return dataKey, nil
+}
+
+// checkLegacyUnlockPassword ensures a data key is never wrapped with a different
+// password than the one the existing values are encrypted with
+func (cfg *config) checkLegacyUnlockPassword(password string) error {
+	var userConfig db.UserConfig
+	err := cfg.db.Where(&db.UserConfig{Key: "UnlockPasswordCheck"}).Limit(1).Find(&userConfig).Error
+	if err != nil {
+		return err
+	}
+	if userConfig.Value == "" || !userConfig.Encrypted || isEnvelopeValue(userConfig.Value) {
+		return nil
+	}
+	decrypted, err := decryptValue(userConfig.Value, password, nil)
+	if err != nil || decrypted != unlockPasswordCheck {
+		return errors.New("incorrect password")
+	}
+	return nil
+}
+
+// upgradeLegacyValue re-encrypts a legacy value with the data key. Failures are
+// logged and retried the next time the value is read. The caller must hold dataKeyMutex.
+func (cfg *config) upgradeLegacyValue(key string, legacyValue string, plaintext string, password string) {
+	dataKey, err := cfg.getDataKeyLocked(password, true)
+	if err != nil {
+		logger.Logger.WithField("key", key).WithError(err).Error("Failed to get data key to upgrade encrypted value")
+		return
+	}
+	encrypted, err := encryptWithDataKey(plaintext, dataKey)
+	if err != nil {
+		logger.Logger.WithField("key", key).WithError(err).Error("Failed to upgrade encrypted value")
+		return
+	}
+	// only replace the value if it was not changed in the meantime
+	err = cfg.db.Model(&db.UserConfig{}).
+		Where("key = ? AND value = ?", key, legacyValue).
+		Update("value", encrypted).Error
+	if err != nil {
+		logger.Logger.WithField("key", key).WithError(err).Error("Failed to save upgraded encrypted value")
+		return
+	}
+	logger.Logger.WithField("key", key).Debug("Upgraded encrypted value")
+}
+
+// reencryptValues re-encrypts encrypted values with newDataKey. If legacyOnly is true,
+// values already encrypted with the data key are skipped.
+func (cfg *config) reencryptValues(tx *gorm.DB, password string, dataKey []byte, newDataKey []byte, legacyOnly bool) error {
+	var encryptedUserConfigs []db.UserConfig
+	err := tx.Where(&db.UserConfig{Encrypted: true}).Where("key != ?", dataKeyConfigKey).Find(&encryptedUserConfigs).Error
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithField("count", len(encryptedUserConfigs)).Info("Updating encrypted entries")
+
+	for _, userConfig := range encryptedUserConfigs {
+		if userConfig.Value == "" || (legacyOnly && isEnvelopeValue(userConfig.Value)) {
+			continue
+		}
+		decryptedValue, err := decryptValue(userConfig.Value, password, dataKey)
+		if err != nil {
+			logger.Logger.WithField("key", userConfig.Key).WithError(err).Error("Failed to decrypt key")
+			return err
+		}
+		encrypted, err := encryptWithDataKey(decryptedValue, newDataKey)
+		if err != nil {
+			logger.Logger.WithField("key", userConfig.Key).WithError(err).Error("Failed to encrypt key")
+			return err
+		}
+		err = tx.Model(&db.UserConfig{}).Where("key = ?", userConfig.Key).Update("value", encrypted).Error
+		if err != nil {
+			return err
+		}
+		logger.Logger.WithField("key", userConfig.Key).Info("re-encrypted key")
+	}
+	return nil
+}
+
+func (cfg *config) GetEncryptionStatus() (*EncryptionStatus, error) {
+	status := &EncryptionStatus{}
+
+	wrappedDataKey, err := cfg.getWrappedDataKey(cfg.db)
+	if err != nil {
+		return nil, err
+	}
+	if wrappedDataKey != "" {
+		params, _, _, err := parseWrappedDataKey(wrappedDataKey)
+		if err != nil {
+			return nil, err
+		}
+		status.DataKeyCreated = true
+		status.KDF = params
+	}
+
+	err = cfg.db.Model(&db.UserConfig{}).
+		Where("encrypted = ? AND key != ? AND value != '' AND value NOT LIKE ?", true, dataKeyConfigKey, envelopeValueLikePattern).
+		Count(&status.LegacyValueCount).Error
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// UpgradeKDF re-wraps the data key with a key derived using stronger KDF parameters.
+// Encrypted values do not need to be re-encrypted.
+func (cfg *config) UpgradeKDF(unlockPassword string, params KDFParams) error {
+	if err := params.validate(); err != nil {
+		return err
+	}
+	if !cfg.CheckUnlockPassword(unlockPassword) {
+		return errors.New("incorrect password")
+	}
+
+	dataKey, err := cfg.getDataKey(unlockPassword, true)
+	if err != nil {
+		return err
+	}
+	wrappedDataKey, err := cfg.getWrappedDataKey(cfg.db)
+	if err != nil {
+		return err
+	}
+	currentParams, _, _, err := parseWrappedDataKey(wrappedDataKey)
+	if err != nil {
+		return err
+	}
+	if params.weakerThan(currentParams) {
+		return errors.New("KDF parameters can only be increased")
+	}
+
+	newWrappedDataKey, err := wrapDataKey(dataKey, unlockPassword, params)
+	if err != nil {
+		return err
+	}
+	err = cfg.db.Model(&db.UserConfig{}).
+		Where("key = ? AND value = ?", dataKeyConfigKey, wrappedDataKey).
+		Update("value", newWrappedDataKey).Error
+	if err != nil {
+		return fmt.Errorf("failed to save data key: %w", err)
+	}
+
+	logger.Logger.WithField("kdf", params).Info("Upgraded data key KDF parameters")
+	return nil
+}
+
+// RotateDataKey replaces the data key and re-encrypts every encrypted value with it.
+// The data key mutex is held for the whole rotation so that no value is encrypted
+// with the old data key after the values have been re-encrypted.
+func (cfg *config) RotateDataKey(unlockPassword string) error {
+	if !cfg.CheckUnlockPassword(unlockPassword) {
+		return errors.New("incorrect password")
+	}
+
+	cfg.dataKeyMutex.Lock()
+	defer cfg.dataKeyMutex.Unlock()
+
+	err := cfg.rotateDataKeyLocked(unlockPassword, unlockPassword, nil)
+	if err != nil {
+		logger.Logger.WithError(err).Error("failed to rotate data key")
+		return err
+	}
+
+	logger.Logger.Info("Rotated data encryption key")
+	return nil
+}
+
+// rotateDataKeyLocked creates a new data key, re-encrypts every encrypted value
+// with it and wraps it with newUnlockPassword. updateTx, if set, runs in the same
+// transaction. The caller must hold dataKeyMutex.
+func (cfg *config) rotateDataKeyLocked(unlockPassword string, newUnlockPassword string, updateTx func(tx *gorm.DB) error) error {
+	dataKey, err := cfg.getDataKeyLocked(unlockPassword, true)
+	if err != nil {
+		return err
+	}
+	wrappedDataKey, err := cfg.getWrappedDataKey(cfg.db)
+	if err != nil {
+		return err
+	}
+	params, _, _, err := parseWrappedDataKey(wrappedDataKey)
+	if err != nil {
+		return err
+	}
+
+	newDataKey := make([]byte, dataKeyLength)
+	if _, err := rand.Read(newDataKey); err != nil {
+		return err
+	}
+	newWrappedDataKey, err := wrapDataKey(newDataKey, newUnlockPassword, params)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.db.Transaction(func(tx *gorm.DB) error {
+		if err := cfg.reencryptValues(tx, unlockPassword, dataKey, newDataKey, false); err != nil {
+			return err
+		}
+		err := cfg.saveWrappedDataKey(newWrappedDataKey, clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "encrypted"}),
+		}, tx)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to save data key")
+			return err
+		}
+		if updateTx != nil {
+			return updateTx(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.dataKeys = map[string][]byte{}
+	return nil
+}
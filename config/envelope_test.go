@@ -0,0 +1,251 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/migrations"
+	"github.com/getAlby/hub/logger"
+)
+
+const testUnlockPassword = "123"
+
+func newTestConfig(t *testing.T) *config {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+
+	// each test uses its own in-memory database
+	gormDb, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, migrations.Migrate(gormDb))
+	t.Cleanup(func() {
+		sqlDb, _ := gormDb.DB()
+		sqlDb.Close()
+	})
+
+	cfg, err := NewConfig(&AppConfig{
+		Workdir: ".test",
+	}, gormDb)
+	require.NoError(t, err)
+	return cfg
+}
+
+// reopen returns a config without cached values or data keys
+func reopen(t *testing.T, cfg *config) *config {
+	reopened, err := NewConfig(cfg.Env, cfg.db)
+	require.NoError(t, err)
+	return reopened
+}
+
+// saveLegacyValue stores a value in the format used before envelope encryption
+func saveLegacyValue(t *testing.T, cfg *config, key string, value string, password string) string {
+	encrypted, err := AesGcmEncryptWithPassword(value, password)
+	require.NoError(t, err)
+	require.NoError(t, cfg.db.Create(&db.UserConfig{Key: key, Value: encrypted, Encrypted: true}).Error)
+	return encrypted
+}
+
+func getRawValue(t *testing.T, cfg *config, key string) string {
+	var userConfig db.UserConfig
+	require.NoError(t, cfg.db.Where(&db.UserConfig{Key: key}).First(&userConfig).Error)
+	return userConfig.Value
+}
+
+func TestEnvelope_SetUsesDataKey(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	require.NoError(t, cfg.SaveUnlockPasswordCheck(testUnlockPassword))
+	require.NoError(t, cfg.SetUpdate("Mnemonic", "mnemonic", testUnlockPassword))
+
+	assert.True(t, strings.HasPrefix(getRawValue(t, cfg, "Mnemonic"), envelopeVersionPrefix))
+	assert.True(t, strings.HasPrefix(getRawValue(t, cfg, dataKeyConfigKey), envelopeVersionPrefix))
+
+	cfg = reopen(t, cfg)
+	value, err := cfg.Get("Mnemonic", testUnlockPassword)
+	require.NoError(t, err)
+	assert.Equal(t, "mnemonic", value)
+
+	_, err = cfg.Get("Mnemonic", testUnlockPassword+"1")
+	assert.Error(t, err)
+
+	status, err := cfg.GetEncryptionStatus()
+	require.NoError(t, err)
+	assert.True(t, status.DataKeyCreated)
+	assert.Equal(t, DefaultKDFParams, status.KDF)
+	assert.Equal(t, int64(0), status.LegacyValueCount)
+}
+
+func TestEnvelope_LegacyValueUpgradedOnRead(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	saveLegacyValue(t, cfg, "UnlockPasswordCheck", unlockPasswordCheck, testUnlockPassword)
+	legacyValue := saveLegacyValue(t, cfg, "Mnemonic", "mnemonic", testUnlockPassword)
+
+	status, err := cfg.GetEncryptionStatus()
+	require.NoError(t, err)
+	assert.False(t, status.DataKeyCreated)
+	assert.Equal(t, int64(2), status.LegacyValueCount)
+
+	// a wrong password neither decrypts nor upgrades the value
+	_, err = cfg.Get("Mnemonic", testUnlockPassword+"1")
+	assert.Error(t, err)
+	assert.Equal(t, legacyValue, getRawValue(t, cfg, "Mnemonic"))
+
+	value, err := cfg.Get("Mnemonic", testUnlockPassword)
+	require.NoError(t, err)
+	assert.Equal(t, "mnemonic", value)
+	assert.True(t, strings.HasPrefix(getRawValue(t, cfg, "Mnemonic"), envelopeVersionPrefix))
+
+	status, err = cfg.GetEncryptionStatus()
+	require.NoError(t, err)
+	assert.True(t, status.DataKeyCreated)
+	assert.Equal(t, int64(1), status.LegacyValueCount)
+
+	// the upgraded value can still be read
+	cfg = reopen(t, cfg)
+	value, err = cfg.Get("Mnemonic", testUnlockPassword)
+	require.NoError(t, err)
+	assert.Equal(t, "mnemonic", value)
+	assert.True(t, cfg.CheckUnlockPassword(testUnlockPassword))
+}
+
+func TestEnvelope_DataKeyNotCreatedWithWrongPassword(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	saveLegacyValue(t, cfg, "UnlockPasswordCheck", unlockPasswordCheck, testUnlockPassword)
+
+	err := cfg.SetUpdate("Mnemonic", "mnemonic", testUnlockPassword+"1")
+	assert.Error(t, err)
+
+	status, err := cfg.GetEncryptionStatus()
+	require.NoError(t, err)
+	assert.False(t, status.DataKeyCreated)
+}
+
+func TestEnvelope_ChangeUnlockPassword(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	saveLegacyValue(t, cfg, "UnlockPasswordCheck", unlockPasswordCheck, testUnlockPassword)
+	saveLegacyValue(t, cfg, "LegacyKey", "legacy", testUnlockPassword)
+	require.NoError(t, cfg.SetUpdate("Mnemonic", "mnemonic", testUnlockPassword))
+	mnemonicValue := getRawValue(t, cfg, "Mnemonic")
+	wrappedDataKey := getRawValue(t, cfg, dataKeyConfigKey)
+
+	newUnlockPassword := "1234"
+	require.NoError(t, cfg.ChangeUnlockPassword(testUnlockPassword, newUnlockPassword))
+
+	// the data key is rotated, so the old password cannot decrypt the values
+	// even with a copy of the old wrapped data key
+	assert.NotEqual(t, mnemonicValue, getRawValue(t, cfg, "Mnemonic"))
+	assert.True(t, strings.HasPrefix(getRawValue(t, cfg, "LegacyKey"), envelopeVersionPrefix))
+	oldDataKey, err := unwrapDataKey(wrappedDataKey, testUnlockPassword)
+	require.NoError(t, err)
+	_, err = decryptWithDataKey(getRawValue(t, cfg, "Mnemonic"), oldDataKey)
+	assert.Error(t, err)
+
+	for _, c := range []*config{cfg, reopen(t, cfg)} {
+		assert.False(t, c.CheckUnlockPassword(testUnlockPassword))
+		assert.True(t, c.CheckUnlockPassword(newUnlockPassword))
+
+		value, err := c.Get("LegacyKey", newUnlockPassword)
+		require.NoError(t, err)
+		assert.Equal(t, "legacy", value)
+		value, err = c.Get("Mnemonic", newUnlockPassword)
+		require.NoError(t, err)
+		assert.Equal(t, "mnemonic", value)
+	}
+}
+
+func TestEnvelope_UpgradeKDF(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	require.NoError(t, cfg.SaveUnlockPasswordCheck(testUnlockPassword))
+	require.NoError(t, cfg.SetUpdate("Mnemonic", "mnemonic", testUnlockPassword))
+	mnemonicValue := getRawValue(t, cfg, "Mnemonic")
+
+	stronger := KDFParams{
+		Time:      DefaultKDFParams.Time + 1,
+		MemoryKiB: DefaultKDFParams.MemoryKiB,
+		Threads:   2,
+	}
+	assert.EqualError(t, cfg.UpgradeKDF(testUnlockPassword+"1", stronger), "incorrect password")
+	require.NoError(t, cfg.UpgradeKDF(testUnlockPassword, stronger))
+
+	status, err := cfg.GetEncryptionStatus()
+	require.NoError(t, err)
+	assert.Equal(t, stronger, status.KDF)
+	assert.Equal(t, mnemonicValue, getRawValue(t, cfg, "Mnemonic"))
+
+	assert.EqualError(t, cfg.UpgradeKDF(testUnlockPassword, DefaultKDFParams), "KDF parameters can only be increased")
+	assert.Error(t, cfg.UpgradeKDF(testUnlockPassword, KDFParams{Time: 3, MemoryKiB: 1024, Threads: 1}))
+
+	cfg = reopen(t, cfg)
+	value, err := cfg.Get("Mnemonic", testUnlockPassword)
+	require.NoError(t, err)
+	assert.Equal(t, "mnemonic", value)
+}
+
+func TestEnvelope_RotateDataKey(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	require.NoError(t, cfg.SaveUnlockPasswordCheck(testUnlockPassword))
+	require.NoError(t, cfg.SetUpdate("Mnemonic", "mnemonic", testUnlockPassword))
+	legacyValue := saveLegacyValue(t, cfg, "LegacyKey", "legacy", testUnlockPassword)
+	mnemonicValue := getRawValue(t, cfg, "Mnemonic")
+	wrappedDataKey := getRawValue(t, cfg, dataKeyConfigKey)
+
+	require.NoError(t, cfg.RotateDataKey(testUnlockPassword))
+
+	assert.NotEqual(t, mnemonicValue, getRawValue(t, cfg, "Mnemonic"))
+	assert.NotEqual(t, legacyValue, getRawValue(t, cfg, "LegacyKey"))
+	assert.NotEqual(t, wrappedDataKey, getRawValue(t, cfg, dataKeyConfigKey))
+
+	// the old data key can no longer decrypt values
+	oldDataKey, err := unwrapDataKey(wrappedDataKey, testUnlockPassword)
+	require.NoError(t, err)
+	_, err = decryptWithDataKey(getRawValue(t, cfg, "Mnemonic"), oldDataKey)
+	assert.Error(t, err)
+
+	for _, c := range []*config{cfg, reopen(t, cfg)} {
+		value, err := c.Get("Mnemonic", testUnlockPassword)
+		require.NoError(t, err)
+		assert.Equal(t, "mnemonic", value)
+		value, err = c.Get("LegacyKey", testUnlockPassword)
+		require.NoError(t, err)
+		assert.Equal(t, "legacy", value)
+	}
+}
+
+func TestEnvelope_RotateDataKey_ConcurrentSet(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	require.NoError(t, cfg.SaveUnlockPasswordCheck(testUnlockPassword))
+	require.NoError(t, cfg.SetUpdate("Mnemonic", "mnemonic", testUnlockPassword))
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, cfg.SetUpdate("Key"+strconv.Itoa(i), "value", testUnlockPassword))
+		}()
+	}
+	require.NoError(t, cfg.RotateDataKey(testUnlockPassword))
+	wg.Wait()
+
+	// values saved during the rotation must be encrypted with the new data key
+	reopened := reopen(t, cfg)
+	for i := range 10 {
+		value, err := reopened.Get("Key"+strconv.Itoa(i), testUnlockPassword)
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+	}
+}
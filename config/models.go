@@ -10,11 +10,16 @@ const (
 )
 
 const (
-	OnchainAddressKey           = "OnchainAddress"
-	AutoSwapBalanceThresholdKey = "AutoSwapBalanceThreshold"
-	AutoSwapAmountKey           = "AutoSwapAmount"
-	AutoSwapDestinationKey      = "AutoSwapDestination"
-	AutoSwapXpubIndexStart      = "AutoSwapXpubIndexStart"
+	OnchainAddressKey             = "OnchainAddress"
+	AutoSwapBalanceThresholdKey   = "AutoSwapBalanceThreshold"
+	AutoSwapAmountKey             = "AutoSwapAmount"
+	AutoSwapDestinationKey        = "AutoSwapDestination"
+	AutoSwapXpubIndexStart        = "AutoSwapXpubIndexStart"
+	AutoSwapMaxFeeSatKey          = "AutoSwapMaxFeeSat"
+	AutoSwapInBalanceThresholdKey = "AutoSwapInBalanceThreshold"
+	AutoSwapInAmountKey           = "AutoSwapInAmount"
+	AutoSwapInMaxFeePercentageKey = "AutoSwapInMaxFeePercentage"
+	AutoSwapInCooldownHoursKey    = "AutoSwapInCooldownHours"
 )
 
 type AppConfig struct {
@@ -57,6 +62,7 @@ type AppConfig struct {
 	PhoenixdAddress                    string `envconfig:"PHOENIXD_ADDRESS"`
 	PhoenixdAuthorization              string `envconfig:"PHOENIXD_AUTHORIZATION"`
 	GoProfilerAddr                     string `envconfig:"GO_PROFILER_ADDR"`
+	MetricsAddr                        string `envconfig:"METRICS_ADDR"`
 	EnableAdvancedSetup                bool   `envconfig:"ENABLE_ADVANCED_SETUP" default:"true"`
 	AutoUnlockPassword                 string `envconfig:"AUTO_UNLOCK_PASSWORD"`
 	LogDBQueries                       bool   `envconfig:"LOG_DB_QUERIES" default:"false"`
@@ -69,6 +75,32 @@ type AppConfig struct {
 	BarkEsploraServer                  string `envconfig:"BARK_ESPLORA_SERVER" default:"https://mempool.second.tech/api"`
 	BarkServerAccessToken              string `envconfig:"BARK_SERVER_ACCESS_TOKEN"`
 	BarkLogLevel                       string `envconfig:"BARK_LOG_LEVEL" default:"3"`
+	TOTPOnchainThresholdSat            uint64 `envconfig:"TOTP_ONCHAIN_THRESHOLD_SAT" default:"100000"`
+	BackupIntervalHours                uint64 `envconfig:"BACKUP_INTERVAL_HOURS" default:"0"`
+	BackupDir                          string `envconfig:"BACKUP_DIR"`
+	BackupRetentionCount               uint64 `envconfig:"BACKUP_RETENTION_COUNT" default:"7"`
+	BackupRetentionDays                uint64 `envconfig:"BACKUP_RETENTION_DAYS" default:"0"`
+	BackupS3Endpoint                   string `envconfig:"BACKUP_S3_ENDPOINT"`
+	BackupS3Region                     string `envconfig:"BACKUP_S3_REGION"`
+	BackupS3Bucket                     string `envconfig:"BACKUP_S3_BUCKET"`
+	BackupS3Prefix                     string `envconfig:"BACKUP_S3_PREFIX"`
+	BackupS3AccessKeyId                string `envconfig:"BACKUP_S3_ACCESS_KEY_ID"`
+	BackupS3SecretAccessKey            string `envconfig:"BACKUP_S3_SECRET_ACCESS_KEY"`
+	PKCS11Module                       string `envconfig:"PKCS11_MODULE"`
+	PKCS11TokenLabel                   string `envconfig:"PKCS11_TOKEN_LABEL"`
+	PKCS11Pin                          string `envconfig:"PKCS11_PIN"`
+	PKCS11SignMechanism                uint64 `envconfig:"PKCS11_SIGN_MECHANISM"`
+	Nip46BunkerUrl                     string `envconfig:"NIP46_BUNKER_URL"`
+	Nip46TimeoutSeconds                uint64 `envconfig:"NIP46_TIMEOUT_SECONDS" default:"30"`
+	FeeManagerIntervalMinutes          uint64 `envconfig:"FEE_MANAGER_INTERVAL_MINUTES" default:"0"`
+	FeeManagerDryRun                   bool   `envconfig:"FEE_MANAGER_DRY_RUN" default:"false"`
+	FeeManagerMinChangeIntervalMinutes uint64 `envconfig:"FEE_MANAGER_MIN_CHANGE_INTERVAL_MINUTES" default:"360"`
+	FeeManagerMinChangePpm             uint64 `envconfig:"FEE_MANAGER_MIN_CHANGE_PPM" default:"10"`
+	RebalancerIntervalMinutes          uint64 `envconfig:"REBALANCER_INTERVAL_MINUTES" default:"0"`
+	RebalancerMinLocalPercent          uint64 `envconfig:"REBALANCER_MIN_LOCAL_PERCENT" default:"20"`
+	RebalancerMaxLocalPercent          uint64 `envconfig:"REBALANCER_MAX_LOCAL_PERCENT" default:"80"`
+	RebalancerMaxFeePpm                uint64 `envconfig:"REBALANCER_MAX_FEE_PPM" default:"500"`
+	RebalancerMaxAmountSat             uint64 `envconfig:"REBALANCER_MAX_AMOUNT_SAT" default:"500000"`
 }
 
 func (c *AppConfig) IsDefaultClientId() bool {
@@ -96,6 +128,9 @@ type Config interface {
 	CheckUnlockPassword(password string) bool
 	IsUnlockPasswordCheckSet() (bool, error)
 	ChangeUnlockPassword(currentUnlockPassword string, newUnlockPassword string) error
+	GetEncryptionStatus() (*EncryptionStatus, error)
+	UpgradeKDF(unlockPassword string, params KDFParams) error
+	RotateDataKey(unlockPassword string) error
 	SetAutoUnlockPassword(unlockPassword string) error
 	SaveUnlockPasswordCheck(encryptionKey string) error
 	SetupCompleted() (bool, error)
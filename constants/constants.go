@@ -20,12 +20,40 @@ const (
 	SWAP_STATE_REFUNDED = "REFUNDED"
 )
 
+const (
+	CHANNEL_EVENT_TYPE_OPEN  = "open"
+	CHANNEL_EVENT_TYPE_CLOSE = "close"
+)
+
+// kinds of entries in the unified activity feed
+const (
+	ACTIVITY_KIND_LIGHTNING     = "lightning"
+	ACTIVITY_KIND_ONCHAIN       = "onchain"
+	ACTIVITY_KIND_SWAP_IN       = "swap_in"
+	ACTIVITY_KIND_SWAP_OUT      = "swap_out"
+	ACTIVITY_KIND_CHANNEL_OPEN  = "channel_open"
+	ACTIVITY_KIND_CHANNEL_CLOSE = "channel_close"
+)
+
+const (
+	PAYMENT_APPROVAL_STATE_PENDING  = "PENDING"
+	PAYMENT_APPROVAL_STATE_APPROVED = "APPROVED"
+	PAYMENT_APPROVAL_STATE_REJECTED = "REJECTED"
+	PAYMENT_APPROVAL_STATE_EXPIRED  = "EXPIRED"
+)
+
 const (
 	BUDGET_RENEWAL_DAILY   = "daily"
 	BUDGET_RENEWAL_WEEKLY  = "weekly"
 	BUDGET_RENEWAL_MONTHLY = "monthly"
 	BUDGET_RENEWAL_YEARLY  = "yearly"
 	BUDGET_RENEWAL_NEVER   = "never"
+
+	// rolling windows count payments made in the last N hours
+	// instead of resetting on calendar boundaries
+	BUDGET_RENEWAL_ROLLING_24H    = "rolling_24h"
+	BUDGET_RENEWAL_ROLLING_7D     = "rolling_7d"
+	BUDGET_RENEWAL_ROLLING_CUSTOM = "rolling_custom" // uses the app permission's BudgetRenewalPeriodHours
 )
 
 func GetBudgetRenewals() []string {
@@ -35,11 +63,14 @@ func GetBudgetRenewals() []string {
 		BUDGET_RENEWAL_MONTHLY,
 		BUDGET_RENEWAL_YEARLY,
 		BUDGET_RENEWAL_NEVER,
+		BUDGET_RENEWAL_ROLLING_24H,
+		BUDGET_RENEWAL_ROLLING_7D,
+		BUDGET_RENEWAL_ROLLING_CUSTOM,
 	}
 }
 
 const (
-	PAY_INVOICE_SCOPE       = "pay_invoice" // also covers pay_keysend and multi_* payment methods
+	PAY_INVOICE_SCOPE       = "pay_invoice" // also covers pay_keysend, pay_offer and multi_* payment methods
 	GET_BALANCE_SCOPE       = "get_balance"
 	GET_INFO_SCOPE          = "get_info"
 	MAKE_INVOICE_SCOPE      = "make_invoice"
@@ -48,6 +79,57 @@ const (
 	SIGN_MESSAGE_SCOPE      = "sign_message"
 	NOTIFICATIONS_SCOPE     = "notifications" // covers all notification types
 	SUPERUSER_SCOPE         = "superuser"
+	NODE_ADMIN_SCOPE        = "node_admin" // channel management and on-chain wallet access
+	PAY_ONCHAIN_SCOPE       = "pay_onchain"
+)
+
+// scopes of HTTP API tokens
+const (
+	API_SCOPE_READ               = "read" // all read-only endpoints
+	API_SCOPE_PAYMENTS_SEND      = "payments:send"
+	API_SCOPE_INVOICES_CREATE    = "invoices:create"
+	API_SCOPE_TRANSACTIONS_LABEL = "transactions:label"
+	API_SCOPE_ONCHAIN_MANAGE     = "onchain:manage"
+	API_SCOPE_CHANNELS_MANAGE    = "channels:manage"
+	API_SCOPE_APPS_MANAGE        = "apps:manage"
+	API_SCOPE_SWAPS_MANAGE       = "swaps:manage"
+	API_SCOPE_NODE_MANAGE        = "node:manage"
+	API_SCOPE_ADMIN              = "admin" // unlock password, recovery phrase and API tokens
+)
+
+func GetApiScopes() []string {
+	return []string{
+		API_SCOPE_READ,
+		API_SCOPE_PAYMENTS_SEND,
+		API_SCOPE_INVOICES_CREATE,
+		API_SCOPE_TRANSACTIONS_LABEL,
+		API_SCOPE_ONCHAIN_MANAGE,
+		API_SCOPE_CHANNELS_MANAGE,
+		API_SCOPE_APPS_MANAGE,
+		API_SCOPE_SWAPS_MANAGE,
+		API_SCOPE_NODE_MANAGE,
+		API_SCOPE_ADMIN,
+	}
+}
+
+// roles of hub users
+const (
+	USER_ROLE_OWNER      = "owner" // logs in with the unlock password
+	USER_ROLE_OPERATOR   = "operator"
+	USER_ROLE_BOOKKEEPER = "bookkeeper"
+	USER_ROLE_VIEWER     = "viewer"
+)
+
+// sources of audit log entries
+const (
+	AUDIT_SOURCE_HTTP  = "http"
+	AUDIT_SOURCE_WAILS = "wails"
+	AUDIT_SOURCE_NIP47 = "nip47"
+)
+
+const (
+	AUDIT_RESULT_SUCCESS = "success"
+	AUDIT_RESULT_ERROR   = "error"
 )
 
 // limit encoded metadata length, otherwise relays may have trouble listing multiple transactions
@@ -69,6 +151,8 @@ const (
 	ERROR_NOT_FOUND              = "NOT_FOUND"
 	ERROR_UNSUPPORTED_ENCRYPTION = "UNSUPPORTED_ENCRYPTION"
 	ERROR_OTHER                  = "OTHER"
+	ERROR_PENDING_APPROVAL       = "PENDING_APPROVAL"
+	ERROR_RATE_LIMITED           = "RATE_LIMITED"
 )
 
 const (
@@ -78,6 +162,16 @@ const (
 
 const METADATA_APPSTORE_APP_ID_KEY = "app_store_app_id"
 
+// per-app NIP-47 rate limit, stored in the app metadata.
+// Apps without a rate limit in their metadata use the defaults;
+// apps with 0 requests per minute are not rate limited.
+const (
+	METADATA_RATE_LIMIT_REQUESTS_PER_MINUTE_KEY = "rate_limit_requests_per_minute"
+	METADATA_RATE_LIMIT_BURST_KEY               = "rate_limit_burst"
+	DEFAULT_RATE_LIMIT_REQUESTS_PER_MINUTE      = 60
+	DEFAULT_RATE_LIMIT_BURST                    = 60
+)
+
 const SUBWALLET_APPSTORE_APP_ID = "uncle-jim"
 
 const (
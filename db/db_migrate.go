@@ -12,13 +12,21 @@ import (
 var expectedTables = []string{
 	"apps",
 	"app_permissions",
+	"app_spending_policies",
 	"request_events",
 	"response_events",
 	"transactions",
+	"payment_approvals",
 	"swaps",
 	"user_configs",
 	"migrations",
 	"forwards",
+	"channel_events",
+	"api_tokens",
+	"users",
+	"audit_events",
+	"fee_policies",
+	"fee_changes",
 }
 
 // MigrateDB copies all rows from one database to another. Both databases
@@ -71,6 +79,11 @@ func MigrateDB(from, to *gorm.DB) error {
 		return fmt.Errorf("failed to migrate app_permissions: %w", err)
 	}
 
+	logger.Logger.Info("migrating app_spending_policies...")
+	if err := migrateTable[AppSpendingPolicy](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate app_spending_policies: %w", err)
+	}
+
 	logger.Logger.Info("migrating request_events...")
 	if err := migrateTable[RequestEvent](from, tx); err != nil {
 		return fmt.Errorf("failed to migrate request_events: %w", err)
@@ -86,6 +99,11 @@ func MigrateDB(from, to *gorm.DB) error {
 		return fmt.Errorf("failed to migrate transactions: %w", err)
 	}
 
+	logger.Logger.Info("migrating payment_approvals...")
+	if err := migrateTable[PaymentApproval](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate payment_approvals: %w", err)
+	}
+
 	logger.Logger.Info("migrating swaps...")
 	if err := migrateTable[Swap](from, tx); err != nil {
 		return fmt.Errorf("failed to migrate swaps: %w", err)
@@ -96,6 +114,36 @@ func MigrateDB(from, to *gorm.DB) error {
 		return fmt.Errorf("failed to migrate forwards: %w", err)
 	}
 
+	logger.Logger.Info("migrating channel_events...")
+	if err := migrateTable[ChannelEvent](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate channel_events: %w", err)
+	}
+
+	logger.Logger.Info("migrating api_tokens...")
+	if err := migrateTable[ApiToken](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate api_tokens: %w", err)
+	}
+
+	logger.Logger.Info("migrating users...")
+	if err := migrateTable[User](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate users: %w", err)
+	}
+
+	logger.Logger.Info("migrating audit_events...")
+	if err := migrateTable[AuditEvent](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate audit_events: %w", err)
+	}
+
+	logger.Logger.Info("migrating fee_policies...")
+	if err := migrateTable[FeePolicy](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate fee_policies: %w", err)
+	}
+
+	logger.Logger.Info("migrating fee_changes...")
+	if err := migrateTable[FeeChange](from, tx); err != nil {
+		return fmt.Errorf("failed to migrate fee_changes: %w", err)
+	}
+
 	logger.Logger.Info("migrating user_configs...")
 	if err := migrateTable[UserConfig](from, tx); err != nil {
 		return fmt.Errorf("failed to migrate user_configs: %w", err)
@@ -208,11 +256,19 @@ func resetSequences(db *gorm.DB) error {
 	resetReqs := []resetReq{
 		{"apps", "apps_2_id_seq"},
 		{"app_permissions", "app_permissions_2_id_seq"},
+		{"app_spending_policies", "app_spending_policies_id_seq"},
 		{"request_events", "request_events_id_seq"},
 		{"response_events", "response_events_id_seq"},
 		{"transactions", "transactions_id_seq"},
+		{"payment_approvals", "payment_approvals_id_seq"},
 		{"swaps", "swaps_id_seq"},
 		{"forwards", "forwards_id_seq"},
+		{"channel_events", "channel_events_id_seq"},
+		{"api_tokens", "api_tokens_id_seq"},
+		{"users", "users_id_seq"},
+		{"audit_events", "audit_events_id_seq"},
+		{"fee_policies", "fee_policies_id_seq"},
+		{"fee_changes", "fee_changes_id_seq"},
 		{"user_configs", "user_configs_id_seq"},
 	}
 
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appSpendingPoliciesMigration = `
+CREATE TABLE app_spending_policies(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL UNIQUE,
+	max_amount_per_payment_sat bigint NOT NULL DEFAULT 0,
+	max_payments_per_minute integer NOT NULL DEFAULT 0,
+	max_payments_per_hour integer NOT NULL DEFAULT 0,
+	allowed_destinations text,
+	blocked_destinations text,
+	require_description boolean NOT NULL DEFAULT false,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_app_spending_policies_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+`
+
+var appSpendingPoliciesMigrationTmpl = template.Must(template.New("appSpendingPoliciesMigration").Parse(appSpendingPoliciesMigration))
+
+var _202610181200_app_spending_policies = &gormigrate.Migration{
+	ID: "202610181200_app_spending_policies",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appSpendingPoliciesMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
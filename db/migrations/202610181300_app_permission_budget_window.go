@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appPermissionBudgetWindowMigration = `
+ALTER TABLE app_permissions ADD COLUMN budget_renewal_period_hours integer NOT NULL DEFAULT 0;
+ALTER TABLE app_permissions ADD COLUMN budget_timezone text NOT NULL DEFAULT '';
+`
+
+var appPermissionBudgetWindowMigrationTmpl = template.Must(template.New("appPermissionBudgetWindowMigration").Parse(appPermissionBudgetWindowMigration))
+
+var _202610181300_app_permission_budget_window = &gormigrate.Migration{
+	ID: "202610181300_app_permission_budget_window",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appPermissionBudgetWindowMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
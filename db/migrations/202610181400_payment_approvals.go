@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const paymentApprovalsMigration = `
+ALTER TABLE app_spending_policies ADD COLUMN approval_required boolean NOT NULL DEFAULT false;
+ALTER TABLE app_spending_policies ADD COLUMN approval_threshold_sat bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE payment_approvals(
+	id {{ .AutoincrementPrimaryKey }},
+	app_id integer NOT NULL,
+	request_event_id integer,
+	transaction_id integer,
+	state text NOT NULL,
+	payment_request text NOT NULL,
+	payment_hash text NOT NULL,
+	amount_msat bigint NOT NULL,
+	description text,
+	metadata text,
+	resolved_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }},
+	CONSTRAINT fk_payment_approvals_app FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE,
+	CONSTRAINT fk_payment_approvals_request_event FOREIGN KEY (request_event_id) REFERENCES request_events(id) ON DELETE SET NULL,
+	CONSTRAINT fk_payment_approvals_transaction FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_payment_approvals_state ON payment_approvals(state);
+CREATE UNIQUE INDEX idx_payment_approvals_request_event_payment_hash ON payment_approvals(request_event_id, payment_hash);
+`
+
+var paymentApprovalsMigrationTmpl = template.Must(template.New("paymentApprovalsMigration").Parse(paymentApprovalsMigration))
+
+var _202610181400_payment_approvals = &gormigrate.Migration{
+	ID: "202610181400_payment_approvals",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, paymentApprovalsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const appRequestCountersMigration = `
+ALTER TABLE apps ADD COLUMN request_count integer NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN rate_limited_count integer NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN last_rate_limited_at {{ .Timestamp }};
+`
+
+var appRequestCountersMigrationTmpl = template.Must(template.New("appRequestCountersMigration").Parse(appRequestCountersMigration))
+
+var _202610181500_app_request_counters = &gormigrate.Migration{
+	ID: "202610181500_app_request_counters",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, appRequestCountersMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const transactionOnchainMigration = `
+ALTER TABLE transactions ADD COLUMN onchain_address text NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN onchain_tx_id text NOT NULL DEFAULT '';
+`
+
+var transactionOnchainMigrationTmpl = template.Must(template.New("transactionOnchainMigration").Parse(transactionOnchainMigration))
+
+var _202610181600_transaction_onchain = &gormigrate.Migration{
+	ID: "202610181600_transaction_onchain",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, transactionOnchainMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
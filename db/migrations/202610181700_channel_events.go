@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const channelEventsMigration = `
+CREATE TABLE channel_events(
+	id {{ .AutoincrementPrimaryKey }},
+	type text NOT NULL,
+	counterparty_node_id text,
+	amount_sat bigint NOT NULL DEFAULT 0,
+	funding_tx_id text,
+	is_outbound boolean NOT NULL DEFAULT false,
+	public boolean NOT NULL DEFAULT false,
+	reason text,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE INDEX idx_channel_events_created_at ON channel_events(created_at);
+`
+
+var channelEventsMigrationTmpl = template.Must(template.New("channelEventsMigration").Parse(channelEventsMigration))
+
+var _202610181700_channel_events = &gormigrate.Migration{
+	ID: "202610181700_channel_events",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, channelEventsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
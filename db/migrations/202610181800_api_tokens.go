@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const apiTokensMigration = `
+CREATE TABLE api_tokens(
+	id {{ .AutoincrementPrimaryKey }},
+	name text NOT NULL,
+	token_hash text NOT NULL UNIQUE,
+	scopes text,
+	allowed_ips text,
+	expires_at {{ .Timestamp }},
+	last_used_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+`
+
+var apiTokensMigrationTmpl = template.Must(template.New("apiTokensMigration").Parse(apiTokensMigration))
+
+var _202610181800_api_tokens = &gormigrate.Migration{
+	ID: "202610181800_api_tokens",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, apiTokensMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const usersMigration = `
+CREATE TABLE users(
+	id {{ .AutoincrementPrimaryKey }},
+	username text NOT NULL UNIQUE,
+	password_hash text NOT NULL,
+	role text NOT NULL,
+	last_login_at {{ .Timestamp }},
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+`
+
+var usersMigrationTmpl = template.Must(template.New("usersMigration").Parse(usersMigration))
+
+var _202610181900_users = &gormigrate.Migration{
+	ID: "202610181900_users",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, usersMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
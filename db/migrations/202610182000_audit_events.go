@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const auditEventsMigration = `
+CREATE TABLE audit_events(
+	id {{ .AutoincrementPrimaryKey }},
+	action text NOT NULL,
+	source text,
+	actor text,
+	params text,
+	result text,
+	error text,
+	prev_hash text,
+	hash text NOT NULL,
+	created_at {{ .Timestamp }}
+);
+
+CREATE INDEX idx_audit_events_action ON audit_events(action);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+`
+
+var auditEventsMigrationTmpl = template.Must(template.New("auditEventsMigration").Parse(auditEventsMigration))
+
+var _202610182000_audit_events = &gormigrate.Migration{
+	ID: "202610182000_audit_events",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, auditEventsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const forwardDetailsMigration = `
+ALTER TABLE forwards ADD COLUMN inbound_amount_msat bigint NOT NULL DEFAULT 0;
+ALTER TABLE forwards ADD COLUMN incoming_channel_id text NOT NULL DEFAULT '';
+ALTER TABLE forwards ADD COLUMN outgoing_channel_id text NOT NULL DEFAULT '';
+ALTER TABLE forwards ADD COLUMN incoming_peer_pubkey text NOT NULL DEFAULT '';
+ALTER TABLE forwards ADD COLUMN outgoing_peer_pubkey text NOT NULL DEFAULT '';
+ALTER TABLE forwards ADD COLUMN state text NOT NULL DEFAULT 'settled';
+ALTER TABLE forwards ADD COLUMN failure_reason text NOT NULL DEFAULT '';
+ALTER TABLE forwards ADD COLUMN resolved_at {{ .Timestamp }};
+
+UPDATE forwards SET resolved_at = created_at, inbound_amount_msat = outbound_amount_forwarded_msat + total_fee_earned_msat;
+
+CREATE INDEX idx_forwards_resolved_at ON forwards(resolved_at);
+`
+
+var forwardDetailsMigrationTmpl = template.Must(template.New("forwardDetailsMigration").Parse(forwardDetailsMigration))
+
+var _202610182100_forward_details = &gormigrate.Migration{
+	ID: "202610182100_forward_details",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, forwardDetailsMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const feeManagerMigration = `
+CREATE TABLE fee_policies(
+	id {{ .AutoincrementPrimaryKey }},
+	channel_id text NOT NULL DEFAULT '',
+	type text NOT NULL,
+	base_fee_msat bigint NOT NULL DEFAULT 0,
+	fee_ppm bigint NOT NULL DEFAULT 0,
+	min_fee_ppm bigint NOT NULL DEFAULT 0,
+	max_fee_ppm bigint NOT NULL DEFAULT 0,
+	step_ppm bigint NOT NULL DEFAULT 0,
+	demand_window_hours bigint NOT NULL DEFAULT 0,
+	demand_threshold_msat bigint NOT NULL DEFAULT 0,
+	created_at {{ .Timestamp }},
+	updated_at {{ .Timestamp }}
+);
+
+CREATE UNIQUE INDEX idx_fee_policies_channel_id ON fee_policies(channel_id);
+
+CREATE TABLE fee_changes(
+	id {{ .AutoincrementPrimaryKey }},
+	channel_id text NOT NULL,
+	peer_pubkey text,
+	fee_policy_id bigint,
+	policy_type text,
+	old_base_fee_msat bigint,
+	new_base_fee_msat bigint,
+	old_fee_ppm bigint,
+	new_fee_ppm bigint,
+	reason text,
+	dry_run boolean NOT NULL DEFAULT false,
+	error text,
+	created_at {{ .Timestamp }}
+);
+
+CREATE INDEX idx_fee_changes_channel_id ON fee_changes(channel_id);
+CREATE INDEX idx_fee_changes_created_at ON fee_changes(created_at);
+`
+
+var feeManagerMigrationTmpl = template.Must(template.New("feeManagerMigration").Parse(feeManagerMigration))
+
+var _202610182200_fee_manager = &gormigrate.Migration{
+	ID: "202610182200_fee_manager",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, feeManagerMigrationTmpl); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
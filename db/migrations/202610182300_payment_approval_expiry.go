@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	_ "embed"
+	"text/template"
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+const paymentApprovalExpiryMigration = `
+ALTER TABLE payment_approvals ADD COLUMN expires_at {{ .Timestamp }};
+ALTER TABLE request_events ADD COLUMN encryption text NOT NULL DEFAULT '';
+
+CREATE INDEX idx_payment_approvals_state_expires_at ON payment_approvals(state, expires_at);
+`
+
+var paymentApprovalExpiryMigrationTmpl = template.Must(template.New("paymentApprovalExpiryMigration").Parse(paymentApprovalExpiryMigration))
+
+var _202610182300_payment_approval_expiry = &gormigrate.Migration{
+	ID: "202610182300_payment_approval_expiry",
+	Migrate: func(tx *gorm.DB) error {
+
+		if err := exec(tx, paymentApprovalExpiryMigrationTmpl); err != nil {
+			return err
+		}
+
+		// existing approvals get a day to be resolved
+		if err := tx.Exec("UPDATE payment_approvals SET expires_at = ?", time.Now().Add(24*time.Hour)).Error; err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return nil
+	},
+}
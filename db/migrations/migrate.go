@@ -39,6 +39,18 @@ func Migrate(gormDB *gorm.DB) error {
 		_202508192137_forwards,
 		_202509031250_transactions_updated_at_index,
 		_202604081200_app_last_settled_transaction,
+		_202610181200_app_spending_policies,
+		_202610181300_app_permission_budget_window,
+		_202610181400_payment_approvals,
+		_202610181500_app_request_counters,
+		_202610181600_transaction_onchain,
+		_202610181700_channel_events,
+		_202610181800_api_tokens,
+		_202610181900_users,
+		_202610182000_audit_events,
+		_202610182100_forward_details,
+		_202610182200_fee_manager,
+		_202610182300_payment_approval_expiry,
 	})
 
 	return m.Migrate()
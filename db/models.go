@@ -27,18 +27,62 @@ type App struct {
 	LastSettledTransactionAt *time.Time
 	Isolated                 bool
 	Metadata                 datatypes.JSON
+	RequestCount             uint64 // NIP-47 requests received
+	RateLimitedCount         uint64 // NIP-47 requests rejected by the rate limiter
+	LastRateLimitedAt        *time.Time
 }
 
 type AppPermission struct {
-	ID            uint
-	AppId         uint `validate:"required"`
-	App           App
-	Scope         string `validate:"required"`
-	MaxAmountSat  int
-	BudgetRenewal string
-	ExpiresAt     *time.Time
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID                       uint
+	AppId                    uint `validate:"required"`
+	App                      App
+	Scope                    string `validate:"required"`
+	MaxAmountSat             int
+	BudgetRenewal            string
+	BudgetRenewalPeriodHours uint   // only used for rolling_custom budgets
+	BudgetTimezone           string // IANA timezone for calendar budgets, defaults to the server timezone
+	ExpiresAt                *time.Time
+	CreatedAt                time.Time
+	UpdatedAt                time.Time
+}
+
+// AppSpendingPolicy holds additional restrictions on outgoing payments
+// made by an app, on top of the budget stored on its pay_invoice permission.
+// Zero values mean no restriction.
+type AppSpendingPolicy struct {
+	ID                     uint
+	AppId                  uint `validate:"required"`
+	App                    App
+	MaxAmountPerPaymentSat uint64
+	MaxPaymentsPerMinute   uint
+	MaxPaymentsPerHour     uint
+	AllowedDestinations    datatypes.JSON // JSON array of node pubkeys and on-chain addresses
+	BlockedDestinations    datatypes.JSON // JSON array of node pubkeys and on-chain addresses
+	RequireDescription     bool
+	ApprovalRequired       bool   // all NWC payments must be approved by the owner
+	ApprovalThresholdSat   uint64 // NWC payments above this amount must be approved by the owner
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+}
+
+// PaymentApproval is an NWC payment parked until the owner approves or rejects it
+type PaymentApproval struct {
+	ID             uint
+	AppId          uint `validate:"required"`
+	App            App
+	RequestEventId *uint // unset once the request event has been cleaned up
+	RequestEvent   *RequestEvent
+	TransactionId  *uint
+	State          string
+	PaymentRequest string
+	PaymentHash    string
+	AmountMsat     uint64
+	Description    string
+	Metadata       datatypes.JSON
+	ResolvedAt     *time.Time
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
 type RequestEvent struct {
@@ -48,6 +92,7 @@ type RequestEvent struct {
 	NostrId     string `validate:"required"`
 	ContentData string
 	Method      string
+	Encryption  string
 	State       string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -89,6 +134,8 @@ type Transaction struct {
 	FailureReason   string
 	Hold            bool
 	SettleDeadline  *uint32 // block number for accepted hold invoices
+	OnchainAddress  string  // destination of on-chain payments
+	OnchainTxId     string  // set once an on-chain payment was broadcast
 }
 
 type Swap struct {
@@ -115,14 +162,116 @@ type Swap struct {
 	UpdatedAt          time.Time
 }
 
+// ChannelEvent records channel opens and closes reported by the LN backend
+type ChannelEvent struct {
+	ID                 uint
+	Type               string
+	CounterpartyNodeId string
+	AmountSat          uint64 // channel capacity when opened, balance returned on-chain when closed (if known)
+	FundingTxId        string
+	IsOutbound         bool
+	Public             bool
+	Reason             string // closure reason
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// ApiToken is a named, revocable token for the HTTP API.
+// Only a hash of the token is stored.
+type ApiToken struct {
+	ID         uint
+	Name       string         `validate:"required"`
+	TokenHash  string         `validate:"required"`
+	Scopes     datatypes.JSON // JSON array of API scopes
+	AllowedIps datatypes.JSON // JSON array of IPs or CIDR ranges, empty allows any IP
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// User is a local account of a shared hub. The owner is not stored here,
+// they log in with the unlock password.
+type User struct {
+	ID           uint
+	Username     string `validate:"required"`
+	PasswordHash string `validate:"required"` // bcrypt
+	Role         string `validate:"required"` // operator, bookkeeper or viewer
+	LastLoginAt  *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// AuditEvent is an entry of the append-only audit log. Each entry contains the
+// hash of the previous entry so that modified or deleted entries can be detected.
+type AuditEvent struct {
+	ID        uint
+	Action    string `validate:"required"`
+	Source    string // http, wails or nip47
+	Actor     string // e.g. owner, user:alice, api_token:CI or app:1
+	Params    datatypes.JSON
+	Result    string // success or error
+	Error     string
+	PrevHash  string
+	Hash      string
+	CreatedAt time.Time
+}
+
 type Forward struct {
 	ID                          uint
 	OutboundAmountForwardedMsat uint64
 	TotalFeeEarnedMsat          uint64
+	InboundAmountMsat           uint64
+	IncomingChannelId           string
+	OutgoingChannelId           string
+	IncomingPeerPubkey          string
+	OutgoingPeerPubkey          string
+	State                       string
+	FailureReason               string
+	ResolvedAt                  time.Time
 	CreatedAt                   time.Time
 	UpdatedAt                   time.Time
 }
 
+const (
+	FORWARD_STATE_SETTLED = "settled"
+	FORWARD_STATE_FAILED  = "failed"
+)
+
+// FeePolicy sets the forwarding fees of a channel, or of all channels without
+// their own policy if ChannelId is empty. Which fields are used depends on the type.
+type FeePolicy struct {
+	ID                  uint
+	ChannelId           string
+	Type                string // static, liquidity or demand
+	BaseFeeMsat         uint32
+	FeePpm              uint32 // static
+	MinFeePpm           uint32 // liquidity and demand
+	MaxFeePpm           uint32 // liquidity and demand
+	StepPpm             uint32 // demand
+	DemandWindowHours   uint32 // demand
+	DemandThresholdMsat uint64 // demand
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// FeeChange is an entry of the fee manager log. Dry run changes were not applied.
+type FeeChange struct {
+	ID             uint
+	ChannelId      string
+	PeerPubkey     string
+	FeePolicyId    uint
+	PolicyType     string
+	OldBaseFeeMsat uint32
+	NewBaseFeeMsat uint32
+	OldFeePpm      uint32
+	NewFeePpm      uint32
+	Reason         string
+	DryRun         bool
+	Error          string
+	CreatedAt      time.Time
+}
+
 const (
 	REQUEST_EVENT_STATE_HANDLER_EXECUTING = "executing"
 	REQUEST_EVENT_STATE_HANDLER_EXECUTED  = "executed"
@@ -1,6 +1,8 @@
 package queries
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/getAlby/hub/constants"
@@ -15,18 +17,64 @@ func GetBudgetUsageMsat(tx *gorm.DB, appPermission *db.AppPermission) (uint64, e
 	err := tx.
 		Table("transactions").
 		Select("SUM(amount_msat + fee_msat + fee_reserve_msat) as sum").
-		Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND created_at > ?", appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(appPermission.BudgetRenewal)).Scan(&result).Error
+		Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND created_at > ?", appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, getStartOfBudget(appPermission, time.Now())).Scan(&result).Error
 	if err != nil {
 		return 0, err
 	}
 	return result.Sum, nil
 }
 
-func getStartOfBudget(budget_type string) time.Time {
-	now := time.Now()
-	switch budget_type {
+// ValidateBudgetWindow checks the rolling period and timezone of a budget
+func ValidateBudgetWindow(budgetRenewal string, budgetRenewalPeriodHours uint, budgetTimezone string) error {
+	if budgetRenewal == constants.BUDGET_RENEWAL_ROLLING_CUSTOM && budgetRenewalPeriodHours == 0 {
+		return errors.New("a renewal period in hours must be provided for a custom rolling budget")
+	}
+	if budgetTimezone != "" {
+		if _, err := time.LoadLocation(budgetTimezone); err != nil {
+			return fmt.Errorf("invalid budget timezone: %s", budgetTimezone)
+		}
+	}
+	return nil
+}
+
+// getRollingBudgetPeriod returns the length of a rolling budget window,
+// or 0 if the budget renews on calendar boundaries
+func getRollingBudgetPeriod(appPermission *db.AppPermission) time.Duration {
+	switch appPermission.BudgetRenewal {
+	case constants.BUDGET_RENEWAL_ROLLING_24H:
+		return 24 * time.Hour
+	case constants.BUDGET_RENEWAL_ROLLING_7D:
+		return 7 * 24 * time.Hour
+	case constants.BUDGET_RENEWAL_ROLLING_CUSTOM:
+		if appPermission.BudgetRenewalPeriodHours == 0 {
+			return 24 * time.Hour
+		}
+		return time.Duration(appPermission.BudgetRenewalPeriodHours) * time.Hour
+	default:
+		return 0
+	}
+}
+
+// getBudgetLocation returns the timezone used for calendar budget windows,
+// falling back to the server location
+func getBudgetLocation(appPermission *db.AppPermission) *time.Location {
+	if appPermission.BudgetTimezone != "" {
+		location, err := time.LoadLocation(appPermission.BudgetTimezone)
+		if err == nil {
+			return location
+		}
+	}
+	return time.Local
+}
+
+func getStartOfBudget(appPermission *db.AppPermission, now time.Time) time.Time {
+	if rollingPeriod := getRollingBudgetPeriod(appPermission); rollingPeriod > 0 {
+		return now.Add(-rollingPeriod)
+	}
+
+	now = now.In(getBudgetLocation(appPermission))
+	switch appPermission.BudgetRenewal {
 	case constants.BUDGET_RENEWAL_DAILY:
-		// TODO: Use the location of the user, instead of the server
 		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	case constants.BUDGET_RENEWAL_WEEKLY:
 		weekday := now.Weekday()
@@ -46,25 +94,47 @@ func getStartOfBudget(budget_type string) time.Time {
 	}
 }
 
-func GetBudgetRenewsAt(budgetRenewal string) *uint64 {
-	budgetStart := getStartOfBudget(budgetRenewal)
-	switch budgetRenewal {
+// GetBudgetRenewsAt returns when budget next becomes available. For rolling
+// windows this is when the oldest payment in the window drops out of it, or
+// nil if no payments are in the window.
+func GetBudgetRenewsAt(tx *gorm.DB, appPermission *db.AppPermission) (*uint64, error) {
+	now := time.Now()
+
+	if rollingPeriod := getRollingBudgetPeriod(appPermission); rollingPeriod > 0 {
+		var oldestTransaction db.Transaction
+		result := tx.
+			Where("app_id = ? AND type = ? AND (state = ? OR state = ?) AND created_at > ?", appPermission.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_STATE_PENDING, now.Add(-rollingPeriod)).
+			Order("created_at ASC").
+			Limit(1).
+			Find(&oldestTransaction)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, nil
+		}
+		renewal := uint64(oldestTransaction.CreatedAt.Add(rollingPeriod).Unix())
+		return &renewal, nil
+	}
+
+	budgetStart := getStartOfBudget(appPermission, now)
+	switch appPermission.BudgetRenewal {
 	case constants.BUDGET_RENEWAL_DAILY:
 		renewal := uint64(budgetStart.AddDate(0, 0, 1).Unix())
-		return &renewal
+		return &renewal, nil
 	case constants.BUDGET_RENEWAL_WEEKLY:
 		renewal := uint64(budgetStart.AddDate(0, 0, 7).Unix())
-		return &renewal
+		return &renewal, nil
 
 	case constants.BUDGET_RENEWAL_MONTHLY:
 		renewal := uint64(budgetStart.AddDate(0, 1, 0).Unix())
-		return &renewal
+		return &renewal, nil
 
 	case constants.BUDGET_RENEWAL_YEARLY:
 		renewal := uint64(budgetStart.AddDate(1, 0, 0).Unix())
-		return &renewal
+		return &renewal, nil
 
 	default: //"never"
-		return nil
+		return nil, nil
 	}
 }
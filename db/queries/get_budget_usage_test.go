@@ -124,7 +124,7 @@ func TestGetBudgetUsage_BudgetWindowDaily(t *testing.T) {
 		BudgetRenewal: constants.BUDGET_RENEWAL_DAILY,
 	}
 
-	dailyStart := getStartOfBudget(constants.BUDGET_RENEWAL_DAILY)
+	dailyStart := getStartOfBudget(appPermissionDaily, time.Now())
 
 	require.NoError(t, svc.DB.Create(&db.Transaction{
 		AppId:          &app.ID,
@@ -166,7 +166,7 @@ func TestGetBudgetUsage_BudgetWindowWeekly(t *testing.T) {
 		BudgetRenewal: constants.BUDGET_RENEWAL_WEEKLY,
 	}
 
-	weeklyStart := getStartOfBudget(constants.BUDGET_RENEWAL_WEEKLY)
+	weeklyStart := getStartOfBudget(appPermissionWeekly, time.Now())
 
 	require.NoError(t, svc.DB.Create(&db.Transaction{
 		AppId:          &app.ID,
@@ -232,3 +232,116 @@ func TestGetBudgetUsage_BudgetWindowNever(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, uint64(11000), budgetUsageMsat)
 }
+
+func TestGetBudgetUsage_BudgetWindowRolling24h(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		BudgetRenewal: constants.BUDGET_RENEWAL_ROLLING_24H,
+	}
+
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat: 5000,
+		CreatedAt:  time.Now().Add(-25 * time.Hour),
+	}).Error)
+
+	oldestInWindow := time.Now().Add(-23 * time.Hour)
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat: 3000,
+		CreatedAt:  oldestInWindow,
+	}).Error)
+
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		State:      constants.TRANSACTION_STATE_PENDING,
+		AmountMsat: 2000,
+		CreatedAt:  time.Now().Add(-1 * time.Hour),
+	}).Error)
+
+	budgetUsageMsat, err := GetBudgetUsageMsat(svc.DB, appPermission)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5000), budgetUsageMsat)
+
+	renewsAt, err := GetBudgetRenewsAt(svc.DB, appPermission)
+	require.NoError(t, err)
+	require.NotNil(t, renewsAt)
+	assert.Equal(t, uint64(oldestInWindow.Add(24*time.Hour).Unix()), *renewsAt)
+}
+
+func TestGetBudgetRenewsAt_RollingNoPayments(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:                    app.ID,
+		App:                      *app,
+		Scope:                    constants.PAY_INVOICE_SCOPE,
+		BudgetRenewal:            constants.BUDGET_RENEWAL_ROLLING_CUSTOM,
+		BudgetRenewalPeriodHours: 6,
+	}
+
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat: 5000,
+		CreatedAt:  time.Now().Add(-7 * time.Hour),
+	}).Error)
+
+	renewsAt, err := GetBudgetRenewsAt(svc.DB, appPermission)
+	require.NoError(t, err)
+	assert.Nil(t, renewsAt)
+}
+
+func TestGetStartOfBudget_Rolling(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 15, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, now.Add(-24*time.Hour), getStartOfBudget(&db.AppPermission{BudgetRenewal: constants.BUDGET_RENEWAL_ROLLING_24H}, now))
+	assert.Equal(t, now.Add(-7*24*time.Hour), getStartOfBudget(&db.AppPermission{BudgetRenewal: constants.BUDGET_RENEWAL_ROLLING_7D}, now))
+	assert.Equal(t, now.Add(-36*time.Hour), getStartOfBudget(&db.AppPermission{BudgetRenewal: constants.BUDGET_RENEWAL_ROLLING_CUSTOM, BudgetRenewalPeriodHours: 36}, now))
+}
+
+func TestGetStartOfBudget_Timezone(t *testing.T) {
+	// 2024-03-10 02:00 UTC is still 2024-03-09 in New York
+	now := time.Date(2024, time.March, 10, 2, 0, 0, 0, time.UTC)
+
+	appPermission := &db.AppPermission{
+		BudgetRenewal:  constants.BUDGET_RENEWAL_DAILY,
+		BudgetTimezone: "America/New_York",
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, time.March, 9, 0, 0, 0, 0, newYork).Equal(getStartOfBudget(appPermission, now)))
+
+	appPermission.BudgetTimezone = "Asia/Tokyo"
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, time.March, 10, 0, 0, 0, 0, tokyo).Equal(getStartOfBudget(appPermission, now)))
+}
+
+func TestValidateBudgetWindow(t *testing.T) {
+	assert.NoError(t, ValidateBudgetWindow(constants.BUDGET_RENEWAL_DAILY, 0, "Europe/Berlin"))
+	assert.NoError(t, ValidateBudgetWindow(constants.BUDGET_RENEWAL_ROLLING_CUSTOM, 12, ""))
+	assert.Error(t, ValidateBudgetWindow(constants.BUDGET_RENEWAL_ROLLING_CUSTOM, 0, ""))
+	assert.Error(t, ValidateBudgetWindow(constants.BUDGET_RENEWAL_DAILY, 0, "Mars/Olympus_Mons"))
+}
@@ -0,0 +1,102 @@
+package queries
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/db"
+)
+
+const (
+	FORWARD_DIRECTION_INCOMING = "incoming"
+	FORWARD_DIRECTION_OUTGOING = "outgoing"
+)
+
+type ForwardStats struct {
+	// only set when grouped by channel or peer
+	ChannelId          string
+	PeerPubkey         string
+	NumForwards        uint64
+	NumFailedForwards  uint64
+	InboundAmountMsat  uint64
+	OutboundAmountMsat uint64
+	FeeEarnedMsat      uint64
+}
+
+const forwardStatsColumns = `
+COUNT(CASE WHEN state = @settled THEN 1 END) AS num_forwards,
+COUNT(CASE WHEN state = @failed THEN 1 END) AS num_failed_forwards,
+COALESCE(SUM(CASE WHEN state = @settled THEN inbound_amount_msat ELSE 0 END), 0) AS inbound_amount_msat,
+COALESCE(SUM(CASE WHEN state = @settled THEN outbound_amount_forwarded_msat ELSE 0 END), 0) AS outbound_amount_msat,
+COALESCE(SUM(CASE WHEN state = @settled THEN total_fee_earned_msat ELSE 0 END), 0) AS fee_earned_msat`
+
+// GetForwardStats sums the forwards resolved within [from, until).
+// A zero from or until leaves the range open.
+func GetForwardStats(tx *gorm.DB, from, until time.Time) (*ForwardStats, error) {
+	var stats ForwardStats
+	err := forwardsInRange(tx, from, until).
+		Select(forwardStatsColumns, forwardStatsArgs()).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetForwardStatsByChannel groups the forwards resolved within [from, until)
+// by the channel they arrived on (incoming) or left through (outgoing)
+func GetForwardStatsByChannel(tx *gorm.DB, from, until time.Time, direction string) ([]ForwardStats, error) {
+	if direction != FORWARD_DIRECTION_INCOMING && direction != FORWARD_DIRECTION_OUTGOING {
+		return nil, fmt.Errorf("invalid forward direction: %s", direction)
+	}
+
+	var stats []ForwardStats
+	err := forwardsInRange(tx, from, until).
+		Select(fmt.Sprintf("%[1]s_channel_id AS channel_id, %[1]s_peer_pubkey AS peer_pubkey, ", direction)+forwardStatsColumns, forwardStatsArgs()).
+		Where(fmt.Sprintf("%s_channel_id != ''", direction)).
+		Group(fmt.Sprintf("%[1]s_channel_id, %[1]s_peer_pubkey", direction)).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetForwardStatsByPeer groups the forwards resolved within [from, until)
+// by the peer they arrived from (incoming) or were sent to (outgoing)
+func GetForwardStatsByPeer(tx *gorm.DB, from, until time.Time, direction string) ([]ForwardStats, error) {
+	if direction != FORWARD_DIRECTION_INCOMING && direction != FORWARD_DIRECTION_OUTGOING {
+		return nil, fmt.Errorf("invalid forward direction: %s", direction)
+	}
+
+	var stats []ForwardStats
+	err := forwardsInRange(tx, from, until).
+		Select(fmt.Sprintf("%s_peer_pubkey AS peer_pubkey, ", direction)+forwardStatsColumns, forwardStatsArgs()).
+		Where(fmt.Sprintf("%s_peer_pubkey != ''", direction)).
+		Group(fmt.Sprintf("%s_peer_pubkey", direction)).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func forwardsInRange(tx *gorm.DB, from, until time.Time) *gorm.DB {
+	query := tx.Model(&db.Forward{})
+	if !from.IsZero() {
+		query = query.Where("resolved_at >= ?", from)
+	}
+	if !until.IsZero() {
+		query = query.Where("resolved_at < ?", until)
+	}
+	return query
+}
+
+func forwardStatsArgs() map[string]interface{} {
+	return map[string]interface{}{
+		"settled": db.FORWARD_STATE_SETTLED,
+		"failed":  db.FORWARD_STATE_FAILED,
+	}
+}
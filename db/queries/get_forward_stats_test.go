@@ -0,0 +1,108 @@
+package queries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestGetForwardStats(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	now := time.Now()
+	forwards := []db.Forward{
+		{
+			OutboundAmountForwardedMsat: 100000,
+			TotalFeeEarnedMsat:          100,
+			InboundAmountMsat:           100100,
+			IncomingChannelId:           "chan-a",
+			IncomingPeerPubkey:          "peer-a",
+			OutgoingChannelId:           "chan-b",
+			OutgoingPeerPubkey:          "peer-b",
+			State:                       db.FORWARD_STATE_SETTLED,
+			ResolvedAt:                  now.Add(-1 * time.Hour),
+		},
+		{
+			OutboundAmountForwardedMsat: 50000,
+			TotalFeeEarnedMsat:          50,
+			InboundAmountMsat:           50050,
+			IncomingChannelId:           "chan-a",
+			IncomingPeerPubkey:          "peer-a",
+			OutgoingChannelId:           "chan-c",
+			OutgoingPeerPubkey:          "peer-b",
+			State:                       db.FORWARD_STATE_SETTLED,
+			ResolvedAt:                  now.Add(-2 * time.Hour),
+		},
+		{
+			OutboundAmountForwardedMsat: 20000,
+			InboundAmountMsat:           20020,
+			IncomingChannelId:           "chan-a",
+			IncomingPeerPubkey:          "peer-a",
+			OutgoingChannelId:           "chan-b",
+			OutgoingPeerPubkey:          "peer-b",
+			State:                       db.FORWARD_STATE_FAILED,
+			FailureReason:               "insufficient balance",
+			ResolvedAt:                  now.Add(-3 * time.Hour),
+		},
+		{
+			// outside of the range below
+			OutboundAmountForwardedMsat: 10000,
+			TotalFeeEarnedMsat:          10,
+			InboundAmountMsat:           10010,
+			IncomingChannelId:           "chan-a",
+			IncomingPeerPubkey:          "peer-a",
+			OutgoingChannelId:           "chan-b",
+			OutgoingPeerPubkey:          "peer-b",
+			State:                       db.FORWARD_STATE_SETTLED,
+			ResolvedAt:                  now.Add(-48 * time.Hour),
+		},
+	}
+	require.NoError(t, svc.DB.Create(&forwards).Error)
+
+	from := now.Add(-24 * time.Hour)
+
+	stats, err := GetForwardStats(svc.DB, from, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.NumForwards)
+	assert.Equal(t, uint64(1), stats.NumFailedForwards)
+	assert.Equal(t, uint64(150150), stats.InboundAmountMsat)
+	assert.Equal(t, uint64(150000), stats.OutboundAmountMsat)
+	assert.Equal(t, uint64(150), stats.FeeEarnedMsat)
+
+	allStats, err := GetForwardStats(svc.DB, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), allStats.NumForwards)
+	assert.Equal(t, uint64(160), allStats.FeeEarnedMsat)
+
+	outgoingChannelStats, err := GetForwardStatsByChannel(svc.DB, from, time.Time{}, FORWARD_DIRECTION_OUTGOING)
+	require.NoError(t, err)
+	require.Len(t, outgoingChannelStats, 2)
+	statsByChannel := map[string]ForwardStats{}
+	for _, channelStats := range outgoingChannelStats {
+		statsByChannel[channelStats.ChannelId] = channelStats
+	}
+	assert.Equal(t, uint64(1), statsByChannel["chan-b"].NumForwards)
+	assert.Equal(t, uint64(1), statsByChannel["chan-b"].NumFailedForwards)
+	assert.Equal(t, uint64(100), statsByChannel["chan-b"].FeeEarnedMsat)
+	assert.Equal(t, "peer-b", statsByChannel["chan-b"].PeerPubkey)
+	assert.Equal(t, uint64(1), statsByChannel["chan-c"].NumForwards)
+	assert.Equal(t, uint64(50000), statsByChannel["chan-c"].OutboundAmountMsat)
+
+	incomingPeerStats, err := GetForwardStatsByPeer(svc.DB, from, now.Add(-90*time.Minute), FORWARD_DIRECTION_INCOMING)
+	require.NoError(t, err)
+	require.Len(t, incomingPeerStats, 1)
+	assert.Equal(t, "peer-a", incomingPeerStats[0].PeerPubkey)
+	assert.Equal(t, uint64(1), incomingPeerStats[0].NumForwards)
+	assert.Equal(t, uint64(1), incomingPeerStats[0].NumFailedForwards)
+	assert.Equal(t, uint64(50050), incomingPeerStats[0].InboundAmountMsat)
+
+	_, err = GetForwardStatsByPeer(svc.DB, from, time.Time{}, "sideways")
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/version"
@@ -14,6 +15,8 @@ type eventPublisher struct {
 	listeners        []EventSubscriber
 	subscriberMtx    sync.Mutex
 	globalProperties map[string]interface{}
+	// number of events still being consumed by asynchronous subscribers
+	pendingEvents atomic.Int64
 }
 
 func NewEventPublisher() *eventPublisher {
@@ -61,11 +64,19 @@ func (ep *eventPublisher) publish(event *Event, sync bool) {
 			listener.ConsumeEvent(context.Background(), event, ep.globalProperties)
 		} else {
 			// consume event without blocking thread
-			go listener.ConsumeEvent(context.Background(), event, ep.globalProperties)
+			ep.pendingEvents.Add(1)
+			go func() {
+				defer ep.pendingEvents.Add(-1)
+				listener.ConsumeEvent(context.Background(), event, ep.globalProperties)
+			}()
 		}
 	}
 }
 
+func (ep *eventPublisher) GetPendingEventsCount() int64 {
+	return ep.pendingEvents.Load()
+}
+
 func (ep *eventPublisher) SetGlobalProperty(key string, value interface{}) {
 	ep.globalProperties[key] = value
 }
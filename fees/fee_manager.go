@@ -0,0 +1,292 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/scheduler"
+)
+
+type Status struct {
+	Enabled                  bool       `json:"enabled"`
+	IntervalMinutes          uint64     `json:"intervalMinutes,omitempty"`
+	DryRun                   bool       `json:"dryRun"`
+	MinChangeIntervalMinutes uint64     `json:"minChangeIntervalMinutes"`
+	MinChangePpm             uint64     `json:"minChangePpm"`
+	LastRunAt                *time.Time `json:"lastRunAt,omitempty"`
+	LastRunChanges           int        `json:"lastRunChanges"`
+	LastError                string     `json:"lastError,omitempty"`
+	NextRunAt                *time.Time `json:"nextRunAt,omitempty"`
+}
+
+type FeeManager interface {
+	GetStatus() *Status
+	// Preview returns the changes the policies want right now, without applying or logging them
+	Preview(ctx context.Context) ([]db.FeeChange, error)
+	// Run applies the changes the policies want and logs them. In dry run mode they are only logged.
+	Run(ctx context.Context) ([]db.FeeChange, error)
+}
+
+type feeManager struct {
+	db                *gorm.DB
+	lnClient          lnclient.LNClient
+	dryRun            bool
+	minChangeInterval time.Duration
+	minChangePpm      uint32
+	scheduler         *scheduler.Scheduler
+	runMutex          sync.Mutex
+	statusMutex       sync.Mutex
+	status            Status
+}
+
+// NewFeeManager creates the fee manager and, if an interval is configured,
+// evaluates the fee policies on a schedule until ctx is cancelled
+func NewFeeManager(ctx context.Context, db *gorm.DB, cfg config.Config, lnClient lnclient.LNClient) FeeManager {
+	env := cfg.GetEnv()
+	svc := &feeManager{
+		db:                db,
+		lnClient:          lnClient,
+		dryRun:            env.FeeManagerDryRun,
+		minChangeInterval: time.Duration(env.FeeManagerMinChangeIntervalMinutes) * time.Minute,
+		minChangePpm:      uint32(env.FeeManagerMinChangePpm),
+		status: Status{
+			DryRun:                   env.FeeManagerDryRun,
+			MinChangeIntervalMinutes: env.FeeManagerMinChangeIntervalMinutes,
+			MinChangePpm:             env.FeeManagerMinChangePpm,
+		},
+	}
+
+	if env.FeeManagerIntervalMinutes == 0 {
+		return svc
+	}
+	svc.status.Enabled = true
+	svc.status.IntervalMinutes = env.FeeManagerIntervalMinutes
+
+	svc.scheduler = scheduler.Start(ctx, "fee_manager", time.Duration(env.FeeManagerIntervalMinutes)*time.Minute, nil, func(ctx context.Context) {
+		// errors are recorded in the status
+		svc.Run(ctx)
+	})
+
+	return svc
+}
+
+func (svc *feeManager) GetStatus() *Status {
+	svc.statusMutex.Lock()
+	defer svc.statusMutex.Unlock()
+	status := svc.status
+	if svc.scheduler != nil {
+		status.NextRunAt = svc.scheduler.NextRunAt()
+	}
+	return &status
+}
+
+func (svc *feeManager) Preview(ctx context.Context) ([]db.FeeChange, error) {
+	changes, err := svc.evaluate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		changes[i].DryRun = true
+	}
+	return changes, nil
+}
+
+func (svc *feeManager) Run(ctx context.Context) (_ []db.FeeChange, err error) {
+	if !svc.runMutex.TryLock() {
+		return nil, errors.New("the fee manager is already running")
+	}
+	defer svc.runMutex.Unlock()
+
+	startedAt := time.Now()
+	var changes []db.FeeChange
+
+	defer func() {
+		svc.statusMutex.Lock()
+		svc.status.LastRunAt = &startedAt
+		svc.status.LastRunChanges = len(changes)
+		if err != nil {
+			svc.status.LastError = err.Error()
+		} else {
+			svc.status.LastError = ""
+		}
+		svc.statusMutex.Unlock()
+
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to run fee manager")
+		}
+	}()
+
+	changes, err = svc.evaluate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		change.DryRun = svc.dryRun
+		if !svc.dryRun {
+			updateErr := svc.lnClient.UpdateChannel(ctx, &lnclient.UpdateChannelRequest{
+				ChannelId:                           change.ChannelId,
+				NodeId:                              change.PeerPubkey,
+				ForwardingFeeBaseMsat:               change.NewBaseFeeMsat,
+				ForwardingFeeProportionalMillionths: change.NewFeePpm,
+			})
+			if updateErr != nil {
+				change.Error = updateErr.Error()
+			}
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"channel_id":        change.ChannelId,
+			"peer_pubkey":       change.PeerPubkey,
+			"policy_type":       change.PolicyType,
+			"old_base_fee_msat": change.OldBaseFeeMsat,
+			"new_base_fee_msat": change.NewBaseFeeMsat,
+			"old_fee_ppm":       change.OldFeePpm,
+			"new_fee_ppm":       change.NewFeePpm,
+			"reason":            change.Reason,
+			"dry_run":           change.DryRun,
+			"error":             change.Error,
+		}).Info("Fee manager changed channel fees")
+
+		if err := svc.db.Create(change).Error; err != nil {
+			logger.Logger.WithError(err).Error("Failed to save fee change")
+		}
+	}
+
+	return changes, nil
+}
+
+// evaluate returns the fee changes wanted by the policies for every active channel,
+// leaving out channels which were changed too recently or where the change is too small
+func (svc *feeManager) evaluate(ctx context.Context) ([]db.FeeChange, error) {
+	if svc.lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+
+	var policies []db.FeePolicy
+	if err := svc.db.Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return []db.FeeChange{}, nil
+	}
+	var defaultPolicy *db.FeePolicy
+	channelPolicies := map[string]*db.FeePolicy{}
+	for i := range policies {
+		if policies[i].ChannelId == "" {
+			defaultPolicy = &policies[i]
+			continue
+		}
+		channelPolicies[policies[i].ChannelId] = &policies[i]
+	}
+
+	channels, err := svc.lnClient.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// outgoing forward stats by demand window and channel
+	demandStats := map[uint32]map[string]queries.ForwardStats{}
+	now := time.Now()
+
+	changes := []db.FeeChange{}
+	for i := range channels {
+		channel := &channels[i]
+		if channel.Id == "" || !channel.Active {
+			continue
+		}
+		policy := channelPolicies[channel.Id]
+		if policy == nil {
+			policy = defaultPolicy
+		}
+		if policy == nil {
+			continue
+		}
+
+		var outgoingStats queries.ForwardStats
+		if policy.Type == FEE_POLICY_TYPE_DEMAND {
+			statsByChannel, ok := demandStats[policy.DemandWindowHours]
+			if !ok {
+				from := now.Add(-time.Duration(policy.DemandWindowHours) * time.Hour)
+				stats, err := queries.GetForwardStatsByChannel(svc.db, from, time.Time{}, queries.FORWARD_DIRECTION_OUTGOING)
+				if err != nil {
+					return nil, err
+				}
+				statsByChannel = map[string]queries.ForwardStats{}
+				for _, channelStats := range stats {
+					statsByChannel[channelStats.ChannelId] = channelStats
+				}
+				demandStats[policy.DemandWindowHours] = statsByChannel
+			}
+			outgoingStats = statsByChannel[channel.Id]
+		}
+
+		baseFeeMsat, feePpm, reason := evaluatePolicy(policy, channel, &outgoingStats)
+		if baseFeeMsat == channel.ForwardingFeeBaseMsat && feePpm == channel.ForwardingFeeProportionalMillionths {
+			continue
+		}
+		// dynamic policies only change the fee rate once the difference is worth a channel update
+		if policy.Type != FEE_POLICY_TYPE_STATIC && baseFeeMsat == channel.ForwardingFeeBaseMsat &&
+			absDiff(feePpm, channel.ForwardingFeeProportionalMillionths) < svc.minChangePpm {
+			continue
+		}
+
+		rateLimited, err := svc.isRateLimited(channel.Id, now)
+		if err != nil {
+			return nil, err
+		}
+		if rateLimited {
+			logger.Logger.WithField("channel_id", channel.Id).Debug("Skipping fee change, channel fees were changed recently")
+			continue
+		}
+
+		changes = append(changes, db.FeeChange{
+			ChannelId:      channel.Id,
+			PeerPubkey:     channel.RemotePubkey,
+			FeePolicyId:    policy.ID,
+			PolicyType:     policy.Type,
+			OldBaseFeeMsat: channel.ForwardingFeeBaseMsat,
+			NewBaseFeeMsat: baseFeeMsat,
+			OldFeePpm:      channel.ForwardingFeeProportionalMillionths,
+			NewFeePpm:      feePpm,
+			Reason:         reason,
+		})
+	}
+
+	return changes, nil
+}
+
+// isRateLimited checks if the fees of the channel were changed within the minimum
+// change interval. In dry run mode the logged dry run changes count, so the same
+// change is not logged on every run.
+func (svc *feeManager) isRateLimited(channelId string, now time.Time) (bool, error) {
+	if svc.minChangeInterval == 0 {
+		return false, nil
+	}
+	var count int64
+	err := svc.db.Model(&db.FeeChange{}).
+		Where("channel_id = ? AND dry_run = ? AND (error IS NULL OR error = '') AND created_at > ?", channelId, svc.dryRun, now.Add(-svc.minChangeInterval)).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
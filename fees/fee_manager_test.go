@@ -0,0 +1,135 @@
+package fees
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func newTestFeeManager(svc *tests.TestService, lnClient lnclient.LNClient, dryRun bool) *feeManager {
+	return &feeManager{
+		db:                svc.DB,
+		lnClient:          lnClient,
+		dryRun:            dryRun,
+		minChangeInterval: 6 * time.Hour,
+		minChangePpm:      10,
+	}
+}
+
+func TestRun_AppliesPoliciesAndRateLimits(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.DB.Create(&db.FeePolicy{Type: FEE_POLICY_TYPE_LIQUIDITY, BaseFeeMsat: 1000, MinFeePpm: 100, MaxFeePpm: 1100}).Error)
+	require.NoError(t, svc.DB.Create(&db.FeePolicy{ChannelId: "chan-b", Type: FEE_POLICY_TYPE_STATIC, BaseFeeMsat: 0, FeePpm: 2000}).Error)
+
+	lnClient := svc.LNClient.(*tests.MockLn)
+	lnClient.Channels = tests.MockChannels
+
+	feeManager := newTestFeeManager(svc, lnClient, false)
+
+	changes, err := feeManager.Run(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, []*lnclient.UpdateChannelRequest{
+		{
+			ChannelId:                           "chan-a",
+			NodeId:                              "peer-a",
+			ForwardingFeeBaseMsat:               1000,
+			ForwardingFeeProportionalMillionths: 200,
+		},
+		{
+			ChannelId:                           "chan-b",
+			NodeId:                              "peer-b",
+			ForwardingFeeBaseMsat:               0,
+			ForwardingFeeProportionalMillionths: 2000,
+		},
+	}, lnClient.UpdateChannelRequests)
+	assert.Equal(t, FEE_POLICY_TYPE_LIQUIDITY, changes[0].PolicyType)
+	assert.Equal(t, uint32(500), changes[0].OldFeePpm)
+	assert.Equal(t, uint32(200), changes[0].NewFeePpm)
+	assert.Equal(t, FEE_POLICY_TYPE_STATIC, changes[1].PolicyType)
+
+	var feeChanges []db.FeeChange
+	require.NoError(t, svc.DB.Find(&feeChanges).Error)
+	require.Len(t, feeChanges, 2)
+	assert.False(t, feeChanges[0].DryRun)
+	assert.Empty(t, feeChanges[0].Error)
+
+	// the channels were just changed, so nothing is changed again
+	changes, err = feeManager.Run(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Len(t, lnClient.UpdateChannelRequests, 2)
+	assert.Equal(t, 0, feeManager.GetStatus().LastRunChanges)
+}
+
+func TestRun_DryRun(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.DB.Create(&db.FeePolicy{Type: FEE_POLICY_TYPE_STATIC, BaseFeeMsat: 1000, FeePpm: 750}).Error)
+
+	lnClient := svc.LNClient.(*tests.MockLn)
+	lnClient.Channels = tests.MockChannels
+
+	feeManager := newTestFeeManager(svc, lnClient, true)
+
+	preview, err := feeManager.Preview(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, preview, 2)
+	assert.True(t, preview[0].DryRun)
+
+	var count int64
+	require.NoError(t, svc.DB.Model(&db.FeeChange{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	changes, err := feeManager.Run(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, uint32(750), changes[0].NewFeePpm)
+
+	var feeChanges []db.FeeChange
+	require.NoError(t, svc.DB.Find(&feeChanges).Error)
+	require.Len(t, feeChanges, 2)
+	assert.True(t, feeChanges[0].DryRun)
+
+	assert.Empty(t, lnClient.UpdateChannelRequests)
+}
+
+func TestRun_IgnoresSmallChanges(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	// wants 505 ppm for a channel with a local balance of 50%
+	require.NoError(t, svc.DB.Create(&db.FeePolicy{Type: FEE_POLICY_TYPE_LIQUIDITY, BaseFeeMsat: 1000, MinFeePpm: 10, MaxFeePpm: 1000}).Error)
+
+	lnClient := svc.LNClient.(*tests.MockLn)
+	lnClient.Channels = []lnclient.Channel{
+		{
+			Id:                                  "chan-a",
+			RemotePubkey:                        "peer-a",
+			Active:                              true,
+			LocalBalanceMsat:                    500_000,
+			RemoteBalanceMsat:                   500_000,
+			ForwardingFeeBaseMsat:               1000,
+			ForwardingFeeProportionalMillionths: 500,
+		},
+	}
+
+	feeManager := newTestFeeManager(svc, lnClient, false)
+
+	changes, err := feeManager.Run(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -0,0 +1,79 @@
+package fees
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/lnclient"
+)
+
+const (
+	// STATIC sets a fixed base fee and fee rate
+	FEE_POLICY_TYPE_STATIC = "static"
+	// LIQUIDITY charges more the less outbound liquidity is left in the channel
+	FEE_POLICY_TYPE_LIQUIDITY = "liquidity"
+	// DEMAND raises the fee rate of busy channels and lowers it for idle ones
+	FEE_POLICY_TYPE_DEMAND = "demand"
+)
+
+// the highest fee rate the fee manager will set
+const maxFeePpm = 100_000
+
+func ValidatePolicy(policy *db.FeePolicy) error {
+	switch policy.Type {
+	case FEE_POLICY_TYPE_STATIC:
+		if policy.FeePpm > maxFeePpm {
+			return fmt.Errorf("fee rate must not exceed %d ppm", maxFeePpm)
+		}
+	case FEE_POLICY_TYPE_LIQUIDITY, FEE_POLICY_TYPE_DEMAND:
+		if policy.MaxFeePpm > maxFeePpm {
+			return fmt.Errorf("maximum fee rate must not exceed %d ppm", maxFeePpm)
+		}
+		if policy.MinFeePpm > policy.MaxFeePpm {
+			return errors.New("minimum fee rate must not exceed the maximum fee rate")
+		}
+		if policy.Type == FEE_POLICY_TYPE_DEMAND {
+			if policy.StepPpm == 0 {
+				return errors.New("step must be greater than 0")
+			}
+			if policy.DemandWindowHours == 0 {
+				return errors.New("demand window must be greater than 0")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown fee policy type: %s", policy.Type)
+	}
+	return nil
+}
+
+// evaluatePolicy returns the fees the policy wants for the channel and why.
+// outgoingStats are the forwards out of the channel within the demand window of the policy.
+func evaluatePolicy(policy *db.FeePolicy, channel *lnclient.Channel, outgoingStats *queries.ForwardStats) (baseFeeMsat uint32, feePpm uint32, reason string) {
+	switch policy.Type {
+	case FEE_POLICY_TYPE_LIQUIDITY:
+		capacityMsat := channel.LocalBalanceMsat + channel.RemoteBalanceMsat
+		if capacityMsat <= 0 {
+			return channel.ForwardingFeeBaseMsat, channel.ForwardingFeeProportionalMillionths, "channel has no balance"
+		}
+		localRatio := float64(channel.LocalBalanceMsat) / float64(capacityMsat)
+		feePpm = policy.MaxFeePpm - uint32(float64(policy.MaxFeePpm-policy.MinFeePpm)*localRatio)
+		return policy.BaseFeeMsat, feePpm, fmt.Sprintf("local balance is %.0f%% of the channel", localRatio*100)
+	case FEE_POLICY_TYPE_DEMAND:
+		feePpm = min(max(channel.ForwardingFeeProportionalMillionths, policy.MinFeePpm), policy.MaxFeePpm)
+		switch {
+		case outgoingStats.OutboundAmountMsat >= policy.DemandThresholdMsat && outgoingStats.NumForwards > 0:
+			feePpm = min(feePpm+policy.StepPpm, policy.MaxFeePpm)
+			reason = fmt.Sprintf("forwarded %d msat in %d forwards in the last %d hours", outgoingStats.OutboundAmountMsat, outgoingStats.NumForwards, policy.DemandWindowHours)
+		case outgoingStats.NumForwards == 0:
+			feePpm = max(feePpm, policy.MinFeePpm+policy.StepPpm) - policy.StepPpm
+			reason = fmt.Sprintf("no forwards in the last %d hours", policy.DemandWindowHours)
+		default:
+			reason = fmt.Sprintf("forwarded %d msat in the last %d hours, below the demand threshold", outgoingStats.OutboundAmountMsat, policy.DemandWindowHours)
+		}
+		return policy.BaseFeeMsat, feePpm, reason
+	default:
+		return policy.BaseFeeMsat, policy.FeePpm, "static policy"
+	}
+}
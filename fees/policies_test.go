@@ -0,0 +1,61 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/lnclient"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	assert.NoError(t, ValidatePolicy(&db.FeePolicy{Type: FEE_POLICY_TYPE_STATIC, FeePpm: 500}))
+	assert.NoError(t, ValidatePolicy(&db.FeePolicy{Type: FEE_POLICY_TYPE_LIQUIDITY, MinFeePpm: 10, MaxFeePpm: 1000}))
+	assert.NoError(t, ValidatePolicy(&db.FeePolicy{Type: FEE_POLICY_TYPE_DEMAND, MinFeePpm: 10, MaxFeePpm: 1000, StepPpm: 25, DemandWindowHours: 24}))
+
+	assert.Error(t, ValidatePolicy(&db.FeePolicy{Type: "random"}))
+	assert.Error(t, ValidatePolicy(&db.FeePolicy{Type: FEE_POLICY_TYPE_STATIC, FeePpm: maxFeePpm + 1}))
+	assert.Error(t, ValidatePolicy(&db.FeePolicy{Type: FEE_POLICY_TYPE_LIQUIDITY, MinFeePpm: 1000, MaxFeePpm: 10}))
+	assert.Error(t, ValidatePolicy(&db.FeePolicy{Type: FEE_POLICY_TYPE_DEMAND, MinFeePpm: 10, MaxFeePpm: 1000, DemandWindowHours: 24}))
+	assert.Error(t, ValidatePolicy(&db.FeePolicy{Type: FEE_POLICY_TYPE_DEMAND, MinFeePpm: 10, MaxFeePpm: 1000, StepPpm: 25}))
+}
+
+func TestEvaluatePolicy_Liquidity(t *testing.T) {
+	policy := &db.FeePolicy{Type: FEE_POLICY_TYPE_LIQUIDITY, BaseFeeMsat: 1000, MinFeePpm: 100, MaxFeePpm: 1100}
+
+	baseFeeMsat, feePpm, _ := evaluatePolicy(policy, &lnclient.Channel{LocalBalanceMsat: 750_000, RemoteBalanceMsat: 250_000}, &queries.ForwardStats{})
+	assert.Equal(t, uint32(1000), baseFeeMsat)
+	assert.Equal(t, uint32(350), feePpm)
+
+	_, feePpm, _ = evaluatePolicy(policy, &lnclient.Channel{LocalBalanceMsat: 0, RemoteBalanceMsat: 1_000_000}, &queries.ForwardStats{})
+	assert.Equal(t, uint32(1100), feePpm)
+
+	_, feePpm, _ = evaluatePolicy(policy, &lnclient.Channel{LocalBalanceMsat: 1_000_000, RemoteBalanceMsat: 0}, &queries.ForwardStats{})
+	assert.Equal(t, uint32(100), feePpm)
+}
+
+func TestEvaluatePolicy_Demand(t *testing.T) {
+	policy := &db.FeePolicy{Type: FEE_POLICY_TYPE_DEMAND, MinFeePpm: 100, MaxFeePpm: 500, StepPpm: 50, DemandWindowHours: 24, DemandThresholdMsat: 1_000_000}
+
+	// busy channel
+	_, feePpm, _ := evaluatePolicy(policy, &lnclient.Channel{ForwardingFeeProportionalMillionths: 200}, &queries.ForwardStats{NumForwards: 3, OutboundAmountMsat: 2_000_000})
+	assert.Equal(t, uint32(250), feePpm)
+	_, feePpm, _ = evaluatePolicy(policy, &lnclient.Channel{ForwardingFeeProportionalMillionths: 480}, &queries.ForwardStats{NumForwards: 3, OutboundAmountMsat: 2_000_000})
+	assert.Equal(t, uint32(500), feePpm)
+
+	// some forwards, but below the threshold
+	_, feePpm, _ = evaluatePolicy(policy, &lnclient.Channel{ForwardingFeeProportionalMillionths: 200}, &queries.ForwardStats{NumForwards: 1, OutboundAmountMsat: 1000})
+	assert.Equal(t, uint32(200), feePpm)
+
+	// idle channel
+	_, feePpm, _ = evaluatePolicy(policy, &lnclient.Channel{ForwardingFeeProportionalMillionths: 200}, &queries.ForwardStats{})
+	assert.Equal(t, uint32(150), feePpm)
+	_, feePpm, _ = evaluatePolicy(policy, &lnclient.Channel{ForwardingFeeProportionalMillionths: 120}, &queries.ForwardStats{})
+	assert.Equal(t, uint32(100), feePpm)
+
+	// fee rate outside of the policy range is moved into it first
+	_, feePpm, _ = evaluatePolicy(policy, &lnclient.Channel{ForwardingFeeProportionalMillionths: 0}, &queries.ForwardStats{NumForwards: 1, OutboundAmountMsat: 1000})
+	assert.Equal(t, uint32(100), feePpm)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/service"
 	"github.com/labstack/echo/v4"
@@ -28,16 +29,16 @@ func NewAlbyHttpService(svc service.Service, albySvc alby.AlbyService, albyOAuth
 	}
 }
 
-func (albyHttpSvc *AlbyHttpService) RegisterSharedRoutes(readOnlyApiGroup *echo.Group, fullAccessApiGroup *echo.Group, e *echo.Echo) {
+func (albyHttpSvc *AlbyHttpService) RegisterSharedRoutes(readOnlyApiGroup *echo.Group, restrictedApiGroup *echo.Group, e *echo.Echo) {
 	e.GET("/api/alby/callback", albyHttpSvc.albyCallbackHandler)
 	e.GET("/api/alby/info", albyHttpSvc.albyInfoHandler)
 	e.GET("/api/alby/rates/:currency", albyHttpSvc.albyBitcoinRateHandler)
 	e.GET("/api/alby/currencies", albyHttpSvc.albyCurrenciesHandler)
 	e.GET("/api/alby/stories", albyHttpSvc.albyStoriesHandler)
 	readOnlyApiGroup.GET("/alby/me", albyHttpSvc.albyMeHandler)
-	fullAccessApiGroup.POST("/alby/link-account", albyHttpSvc.albyLinkAccountHandler)
-	fullAccessApiGroup.POST("/alby/auto-channel", albyHttpSvc.autoChannelHandler)
-	fullAccessApiGroup.POST("/alby/unlink-account", albyHttpSvc.unlinkHandler)
+	restrictedApiGroup.POST("/alby/link-account", albyHttpSvc.albyLinkAccountHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/alby/auto-channel", albyHttpSvc.autoChannelHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/alby/unlink-account", albyHttpSvc.unlinkHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
 }
 
 func (albyHttpSvc *AlbyHttpService) autoChannelHandler(c echo.Context) error {
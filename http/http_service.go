@@ -18,11 +18,19 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
+	"github.com/getAlby/hub/apitokens"
 	"github.com/getAlby/hub/apps"
+	"github.com/getAlby/hub/audit"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/rebalance"
 	"github.com/getAlby/hub/service"
+	"github.com/getAlby/hub/totp"
+	hubtransactions "github.com/getAlby/hub/transactions"
+	"github.com/getAlby/hub/users"
 
 	"github.com/getAlby/hub/api"
 	"github.com/getAlby/hub/frontend"
@@ -37,9 +45,18 @@ type jwtCustomClaims struct {
 	// Name  string `json:"name"`
 	// Admin bool   `json:"admin"`
 	Permission string `json:"permission,omitempty"` // "full" or "readonly"
+	Role       string `json:"role,omitempty"`       // empty for tokens issued before users were added, which are owner tokens
+	UserId     uint   `json:"userId,omitempty"`     // set for all roles except the owner
+	Username   string `json:"username,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// context key of the scopes of the authenticated token
+const scopesContextKey = "scopes"
+
+// context key of a description of who made the request, e.g. "user:alice"
+const actorContextKey = "actor"
+
 type HttpService struct {
 	api            api.API
 	albyHttpSvc    *AlbyHttpService
@@ -47,6 +64,8 @@ type HttpService struct {
 	eventPublisher events.EventPublisher
 	db             *gorm.DB
 	appsSvc        apps.AppsService
+	apiTokensSvc   apitokens.ApiTokensService
+	usersSvc       users.UsersService
 }
 
 func NewHttpService(svc service.Service, eventPublisher events.EventPublisher) *HttpService {
@@ -57,6 +76,8 @@ func NewHttpService(svc service.Service, eventPublisher events.EventPublisher) *
 		eventPublisher: eventPublisher,
 		db:             svc.GetDB(),
 		appsSvc:        apps.NewAppsService(svc.GetDB(), eventPublisher, svc.GetKeys(), svc.GetConfig()),
+		apiTokensSvc:   apitokens.NewApiTokensService(svc.GetDB()),
+		usersSvc:       users.NewUsersService(svc.GetDB()),
 	}
 }
 
@@ -78,14 +99,19 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 		LogHost:      true,
 		LogRequestID: true,
 		LogValuesFunc: func(c echo.Context, values middleware.RequestLoggerValues) error {
-			logger.Logger.WithFields(logrus.Fields{
+			fields := logrus.Fields{
 				"uri":        values.URI,
 				"status":     values.Status,
 				"remote_ip":  values.RemoteIP,
 				"user_agent": values.UserAgent,
 				"host":       values.Host,
 				"request_id": values.RequestID,
-			}).Info("handled API request")
+			}
+			// record who performed the request on authenticated routes
+			if actor := c.Get(actorContextKey); actor != nil {
+				fields["actor"] = actor
+			}
+			logger.Logger.WithFields(fields).Info("handled API request")
 			return nil
 		},
 	}))
@@ -98,8 +124,8 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	e.POST("/api/restore", httpSvc.restoreBackupHandler)
 
 	// A single global rate limiter (one bucket for all callers, not per-IP)
-	// shared by every endpoint that verifies the unlock password, to bound how
-	// fast the password can be guessed.
+	// shared by every endpoint that verifies the unlock password or an
+	// authentication code, to bound how fast either can be guessed.
 	unlockRateLimiter := middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
 		Store: middleware.NewRateLimiterMemoryStoreWithConfig(
 			// burst of 2 so unlocking and then immediately acting is not blocked
@@ -111,6 +137,7 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	})
 	e.POST("/api/start", httpSvc.startHandler, unlockRateLimiter)
 	e.POST("/api/unlock", httpSvc.unlockHandler, unlockRateLimiter)
+	e.POST("/api/login", httpSvc.loginHandler, unlockRateLimiter)
 	e.POST("/api/backup", httpSvc.createBackupHandler, unlockRateLimiter)
 	e.GET("/logout", httpSvc.logoutHandler)
 
@@ -131,9 +158,12 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 			return []byte(secret), nil
 		},
 	}
-	// Read-only API group - accessible to both full and readonly tokens
+	authMiddleware := httpSvc.authenticate(echojwt.WithConfig(jwtConfig))
+
+	// Read-only API group - accessible to session tokens and API tokens with the read scope
 	readOnlyApiGroup := e.Group("/api")
-	readOnlyApiGroup.Use(echojwt.WithConfig(jwtConfig))
+	readOnlyApiGroup.Use(authMiddleware)
+	readOnlyApiGroup.Use(requireScope(constants.API_SCOPE_READ))
 
 	readOnlyApiGroup.GET("/apps", httpSvc.appsListHandler)
 	readOnlyApiGroup.GET("/apps/:pubkey", httpSvc.appsShowByPubkeyHandler)
@@ -149,6 +179,7 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	readOnlyApiGroup.GET("/wallet/address", httpSvc.onchainAddressHandler)
 	readOnlyApiGroup.GET("/wallet/capabilities", httpSvc.capabilitiesHandler)
 	readOnlyApiGroup.GET("/transactions", httpSvc.listTransactionsHandler)
+	readOnlyApiGroup.GET("/activity", httpSvc.listActivityHandler)
 	readOnlyApiGroup.GET("/transactions/:paymentHash", httpSvc.lookupTransactionHandler)
 	readOnlyApiGroup.GET("/balances", httpSvc.balancesHandler)
 	readOnlyApiGroup.GET("/mempool", httpSvc.mempoolApiHandler)
@@ -158,55 +189,94 @@ func (httpSvc *HttpService) RegisterSharedRoutes(e *echo.Echo) {
 	readOnlyApiGroup.GET("/swaps/:swapId", httpSvc.lookupSwapHandler)
 	readOnlyApiGroup.GET("/swaps/out/info", httpSvc.getSwapOutInfoHandler)
 	readOnlyApiGroup.GET("/swaps/in/info", httpSvc.getSwapInInfoHandler)
+	readOnlyApiGroup.GET("/swaps/out/quote", httpSvc.getSwapOutQuoteHandler)
+	readOnlyApiGroup.GET("/swaps/in/quote", httpSvc.getSwapInQuoteHandler)
 	readOnlyApiGroup.GET("/autoswap", httpSvc.getAutoSwapConfigHandler)
+	readOnlyApiGroup.GET("/autoswap/in", httpSvc.getAutoSwapInConfigHandler)
 	readOnlyApiGroup.GET("/forwards", httpSvc.forwardsHandler)
-
-	// Full access API group - requires a token with full permissions
-	fullAccessApiGroup := e.Group("/api")
-	fullAccessApiGroup.Use(echojwt.WithConfig(jwtConfig))
-	fullAccessApiGroup.Use(httpSvc.requireFullAccess)
-
-	fullAccessApiGroup.POST("/event", httpSvc.eventHandler)
-	fullAccessApiGroup.PATCH("/unlock-password", httpSvc.changeUnlockPasswordHandler, unlockRateLimiter)
-	fullAccessApiGroup.PATCH("/auto-unlock", httpSvc.autoUnlockHandler, unlockRateLimiter)
-	fullAccessApiGroup.PATCH("/settings", httpSvc.updateSettingsHandler)
-	fullAccessApiGroup.PATCH("/apps/:pubkey", httpSvc.appsUpdateHandler)
-	fullAccessApiGroup.PATCH("/transactions/:id/labels", httpSvc.setTransactionUserLabelsHandler)
-	fullAccessApiGroup.DELETE("/apps/:pubkey", httpSvc.appsDeleteHandler)
-	fullAccessApiGroup.POST("/transfers", httpSvc.transfersHandler)
-	fullAccessApiGroup.POST("/apps", httpSvc.appsCreateHandler, unlockRateLimiter)
-	fullAccessApiGroup.POST("/lightning-addresses", httpSvc.lightningAddressesCreateHandler)
-	fullAccessApiGroup.DELETE("/lightning-addresses/:appId", httpSvc.lightningAddressesDeleteHandler)
-	fullAccessApiGroup.POST("/mnemonic", httpSvc.mnemonicHandler, unlockRateLimiter)
-	fullAccessApiGroup.PATCH("/backup-reminder", httpSvc.backupReminderHandler)
-	fullAccessApiGroup.POST("/channels", httpSvc.openChannelHandler)
-	fullAccessApiGroup.POST("/channels/rebalance", httpSvc.rebalanceChannelHandler)
-	fullAccessApiGroup.POST("/lsp-orders", httpSvc.newInstantChannelInvoiceHandler)
-	fullAccessApiGroup.POST("/node/migrate-storage", httpSvc.migrateNodeStorageHandler)
-	fullAccessApiGroup.POST("/peers", httpSvc.connectPeerHandler)
-	fullAccessApiGroup.DELETE("/peers/:peerId", httpSvc.disconnectPeerHandler)
-	fullAccessApiGroup.DELETE("/peers/:peerId/channels/:channelId", httpSvc.closeChannelHandler)
-	fullAccessApiGroup.PATCH("/peers/:peerId/channels/:channelId", httpSvc.updateChannelHandler)
-	fullAccessApiGroup.POST("/wallet/new-address", httpSvc.newOnchainAddressHandler)
-	fullAccessApiGroup.POST("/wallet/redeem-onchain-funds", httpSvc.redeemOnchainFundsHandler)
-	fullAccessApiGroup.POST("/wallet/sign-message", httpSvc.signMessageHandler)
-	fullAccessApiGroup.POST("/wallet/sync", httpSvc.walletSyncHandler)
-	fullAccessApiGroup.POST("/payments/:invoice", httpSvc.sendPaymentHandler)
-	fullAccessApiGroup.POST("/invoices", httpSvc.makeInvoiceHandler)
-	fullAccessApiGroup.POST("/offers", httpSvc.makeOfferHandler)
-	fullAccessApiGroup.POST("/reset-router", httpSvc.resetRouterHandler)
-	fullAccessApiGroup.POST("/stop", httpSvc.stopHandler)
-	fullAccessApiGroup.POST("/command", httpSvc.execCustomNodeCommandHandler)
-	fullAccessApiGroup.POST("/swaps/out", httpSvc.initiateSwapOutHandler)
-	fullAccessApiGroup.POST("/swaps/in", httpSvc.initiateSwapInHandler)
-	fullAccessApiGroup.POST("/swaps/refund", httpSvc.refundSwapHandler)
-	fullAccessApiGroup.GET("/swaps/mnemonic", httpSvc.swapMnemonicHandler)
-	fullAccessApiGroup.GET("/log/:type", httpSvc.getLogOutputHandler)
-	fullAccessApiGroup.POST("/autoswap", httpSvc.enableAutoSwapOutHandler, unlockRateLimiter)
-	fullAccessApiGroup.DELETE("/autoswap", httpSvc.disableAutoSwapOutHandler)
-	fullAccessApiGroup.POST("/node/alias", httpSvc.setNodeAliasHandler)
-
-	httpSvc.albyHttpSvc.RegisterSharedRoutes(readOnlyApiGroup, fullAccessApiGroup, e)
+	readOnlyApiGroup.GET("/forwards/analytics", httpSvc.routingAnalyticsHandler)
+	readOnlyApiGroup.GET("/fees/policies", httpSvc.listFeePoliciesHandler)
+	readOnlyApiGroup.GET("/fees/changes", httpSvc.listFeeChangesHandler)
+	readOnlyApiGroup.GET("/fees/status", httpSvc.feeManagerStatusHandler)
+	readOnlyApiGroup.GET("/fees/preview", httpSvc.previewFeeChangesHandler)
+	readOnlyApiGroup.GET("/rebalancer/status", httpSvc.rebalancerStatusHandler)
+	readOnlyApiGroup.GET("/rebalancer/preview", httpSvc.previewRebalancesHandler)
+	readOnlyApiGroup.GET("/approvals", httpSvc.listPaymentApprovalsHandler)
+
+	// Restricted API group - each route requires a specific scope.
+	// Full session tokens have all scopes.
+	restrictedApiGroup := e.Group("/api")
+	restrictedApiGroup.Use(authMiddleware)
+
+	restrictedApiGroup.POST("/event", httpSvc.eventHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.PATCH("/unlock-password", httpSvc.changeUnlockPasswordHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.PATCH("/auto-unlock", httpSvc.autoUnlockHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.PATCH("/settings", httpSvc.updateSettingsHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.PATCH("/apps/:pubkey", httpSvc.appsUpdateHandler, requireScope(constants.API_SCOPE_APPS_MANAGE))
+	restrictedApiGroup.PATCH("/transactions/:id/labels", httpSvc.setTransactionUserLabelsHandler, requireScope(constants.API_SCOPE_TRANSACTIONS_LABEL))
+	restrictedApiGroup.DELETE("/apps/:pubkey", httpSvc.appsDeleteHandler, requireScope(constants.API_SCOPE_APPS_MANAGE), unlockRateLimiter)
+	restrictedApiGroup.POST("/transfers", httpSvc.transfersHandler, requireScope(constants.API_SCOPE_PAYMENTS_SEND))
+	restrictedApiGroup.POST("/apps", httpSvc.appsCreateHandler, requireScope(constants.API_SCOPE_APPS_MANAGE), unlockRateLimiter)
+	restrictedApiGroup.POST("/lightning-addresses", httpSvc.lightningAddressesCreateHandler, requireScope(constants.API_SCOPE_APPS_MANAGE))
+	restrictedApiGroup.DELETE("/lightning-addresses/:appId", httpSvc.lightningAddressesDeleteHandler, requireScope(constants.API_SCOPE_APPS_MANAGE))
+	restrictedApiGroup.POST("/mnemonic", httpSvc.mnemonicHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.PATCH("/backup-reminder", httpSvc.backupReminderHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/backup/verify", httpSvc.verifyBackupHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.POST("/channels", httpSvc.openChannelHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/channels/rebalance", httpSvc.rebalanceChannelHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/channels/rebalance/circular", httpSvc.circularRebalanceHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/lsp-orders", httpSvc.newInstantChannelInvoiceHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/node/migrate-storage", httpSvc.migrateNodeStorageHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/peers", httpSvc.connectPeerHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.DELETE("/peers/:peerId", httpSvc.disconnectPeerHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.DELETE("/peers/:peerId/channels/:channelId", httpSvc.closeChannelHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.PATCH("/peers/:peerId/channels/:channelId", httpSvc.updateChannelHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/fees/policies", httpSvc.setFeePolicyHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.DELETE("/fees/policies/:id", httpSvc.deleteFeePolicyHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/fees/run", httpSvc.runFeeManagerHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/rebalancer/run", httpSvc.runRebalancerHandler, requireScope(constants.API_SCOPE_CHANNELS_MANAGE))
+	restrictedApiGroup.POST("/wallet/new-address", httpSvc.newOnchainAddressHandler, requireScope(constants.API_SCOPE_ONCHAIN_MANAGE))
+	restrictedApiGroup.POST("/wallet/redeem-onchain-funds", httpSvc.redeemOnchainFundsHandler, requireScope(constants.API_SCOPE_ONCHAIN_MANAGE), unlockRateLimiter)
+	restrictedApiGroup.POST("/wallet/sign-message", httpSvc.signMessageHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/wallet/sync", httpSvc.walletSyncHandler, requireScope(constants.API_SCOPE_ONCHAIN_MANAGE))
+	restrictedApiGroup.POST("/payments/:invoice", httpSvc.sendPaymentHandler, requireScope(constants.API_SCOPE_PAYMENTS_SEND))
+	restrictedApiGroup.POST("/offers/:offer/payments", httpSvc.payOfferHandler, requireScope(constants.API_SCOPE_PAYMENTS_SEND))
+	restrictedApiGroup.POST("/approvals/:id/approve", httpSvc.approvePaymentHandler, requireScope(constants.API_SCOPE_PAYMENTS_SEND))
+	restrictedApiGroup.POST("/approvals/:id/reject", httpSvc.rejectPaymentHandler, requireScope(constants.API_SCOPE_PAYMENTS_SEND))
+	restrictedApiGroup.POST("/invoices", httpSvc.makeInvoiceHandler, requireScope(constants.API_SCOPE_INVOICES_CREATE))
+	restrictedApiGroup.POST("/offers", httpSvc.makeOfferHandler, requireScope(constants.API_SCOPE_INVOICES_CREATE))
+	restrictedApiGroup.POST("/reset-router", httpSvc.resetRouterHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/stop", httpSvc.stopHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/command", httpSvc.execCustomNodeCommandHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/swaps/out", httpSvc.initiateSwapOutHandler, requireScope(constants.API_SCOPE_SWAPS_MANAGE))
+	restrictedApiGroup.POST("/swaps/in", httpSvc.initiateSwapInHandler, requireScope(constants.API_SCOPE_SWAPS_MANAGE))
+	restrictedApiGroup.POST("/swaps/refund", httpSvc.refundSwapHandler, requireScope(constants.API_SCOPE_SWAPS_MANAGE))
+	restrictedApiGroup.GET("/swaps/mnemonic", httpSvc.swapMnemonicHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.GET("/log/:type", httpSvc.getLogOutputHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.POST("/autoswap", httpSvc.enableAutoSwapOutHandler, requireScope(constants.API_SCOPE_SWAPS_MANAGE), unlockRateLimiter)
+	restrictedApiGroup.DELETE("/autoswap", httpSvc.disableAutoSwapOutHandler, requireScope(constants.API_SCOPE_SWAPS_MANAGE))
+	restrictedApiGroup.POST("/autoswap/in", httpSvc.enableAutoSwapInHandler, requireScope(constants.API_SCOPE_SWAPS_MANAGE))
+	restrictedApiGroup.DELETE("/autoswap/in", httpSvc.disableAutoSwapInHandler, requireScope(constants.API_SCOPE_SWAPS_MANAGE))
+	restrictedApiGroup.POST("/node/alias", httpSvc.setNodeAliasHandler, requireScope(constants.API_SCOPE_NODE_MANAGE))
+	restrictedApiGroup.GET("/tokens", httpSvc.listApiTokensHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.POST("/tokens", httpSvc.createApiTokenHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.DELETE("/tokens/:id", httpSvc.deleteApiTokenHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.GET("/users", httpSvc.listUsersHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.POST("/users", httpSvc.createUserHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.PATCH("/users/:id", httpSvc.updateUserHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.DELETE("/users/:id", httpSvc.deleteUserHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.GET("/audit", httpSvc.listAuditEventsHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.GET("/audit/verify", httpSvc.verifyAuditLogHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.GET("/totp", httpSvc.totpStatusHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.POST("/totp/enroll", httpSvc.beginTOTPEnrollmentHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.POST("/totp/confirm", httpSvc.confirmTOTPEnrollmentHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.POST("/totp/disable", httpSvc.disableTOTPHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.POST("/totp/recovery-codes", httpSvc.regenerateTOTPRecoveryCodesHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.GET("/encryption", httpSvc.encryptionStatusHandler, requireScope(constants.API_SCOPE_ADMIN))
+	restrictedApiGroup.POST("/encryption/kdf", httpSvc.upgradeKDFHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+	restrictedApiGroup.POST("/encryption/rotate", httpSvc.rotateDataKeyHandler, requireScope(constants.API_SCOPE_ADMIN), unlockRateLimiter)
+
+	httpSvc.albyHttpSvc.RegisterSharedRoutes(readOnlyApiGroup, restrictedApiGroup, e)
 }
 
 func (httpSvc *HttpService) infoHandler(c echo.Context) error {
@@ -261,9 +331,14 @@ func (httpSvc *HttpService) mnemonicHandler(c echo.Context) error {
 		})
 	}
 
-	responseBody, err := httpSvc.api.GetMnemonic(mnemonicRequest.UnlockPassword)
+	responseBody, err := httpSvc.api.GetMnemonic(mnemonicRequest.UnlockPassword, mnemonicRequest.TotpCode)
 
 	if err != nil {
+		if totp.IsCodeError(err) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: err.Error(),
 		})
@@ -304,6 +379,12 @@ func (httpSvc *HttpService) startHandler(c echo.Context) error {
 		})
 	}
 
+	if err := httpSvc.api.VerifyUnlockTOTP(startRequest.UnlockPassword, startRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
 	err := httpSvc.cfg.LoadJWTSecret(startRequest.UnlockPassword)
 
 	if err != nil {
@@ -312,7 +393,7 @@ func (httpSvc *HttpService) startHandler(c echo.Context) error {
 		})
 	}
 
-	token, err := httpSvc.createJWT(nil, "full")
+	token, err := httpSvc.createJWT(nil, "full", nil)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -341,6 +422,12 @@ func (httpSvc *HttpService) unlockHandler(c echo.Context) error {
 		})
 	}
 
+	if err := httpSvc.api.VerifyUnlockTOTP(unlockRequest.UnlockPassword, unlockRequest.TotpCode); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
 	if unlockRequest.Permission == "" {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: "Permission field is required",
@@ -366,7 +453,7 @@ func (httpSvc *HttpService) unlockHandler(c echo.Context) error {
 		})
 	}
 
-	token, err := httpSvc.createJWT(unlockRequest.TokenExpiryDays, unlockRequest.Permission)
+	token, err := httpSvc.createJWT(unlockRequest.TokenExpiryDays, unlockRequest.Permission, nil)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
@@ -382,19 +469,147 @@ func (httpSvc *HttpService) unlockHandler(c echo.Context) error {
 	})
 }
 
-func (httpSvc *HttpService) requireFullAccess(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		token := c.Get("user").(*jwt.Token)
-		claims := token.Claims.(*jwtCustomClaims)
+// loginHandler issues a session token to a user of a shared hub. Users can only
+// log in once the owner has unlocked the hub with the unlock password.
+func (httpSvc *HttpService) loginHandler(c echo.Context) error {
+	var loginRequest api.LoginRequest
+	if err := c.Bind(&loginRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
-		// Allow if no permission specified (backward compatibility) or if full access
-		if claims.Permission == "" || claims.Permission == "full" {
-			return next(c)
+	if _, err := httpSvc.cfg.GetJWTSecret(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Hub is locked, the owner must unlock it first.",
+		})
+	}
+
+	user, err := httpSvc.usersSvc.Authenticate(loginRequest.Username, loginRequest.Password)
+	if err != nil {
+		if errors.Is(err, users.NewInvalidCredentialsError()) {
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Message: err.Error(),
+			})
 		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to log in: %s", err.Error()),
+		})
+	}
 
-		return c.JSON(http.StatusForbidden, ErrorResponse{
-			Message: "This operation requires full access permissions",
+	token, err := httpSvc.createJWT(loginRequest.TokenExpiryDays, "full", user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to save session: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, &authTokenResponse{
+		Token: token,
+	})
+}
+
+// authenticate accepts either a session JWT or an API token and stores the
+// scopes of the token and the actor in the request context
+func (httpSvc *HttpService) authenticate(jwtMiddleware echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		jwtHandler := jwtMiddleware(func(c echo.Context) error {
+			claims := c.Get("user").(*jwt.Token).Claims.(*jwtCustomClaims)
+			scopes, err := httpSvc.getJWTScopes(claims)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return c.JSON(http.StatusUnauthorized, ErrorResponse{
+						Message: "User no longer exists",
+					})
+				}
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{
+					Message: fmt.Sprintf("Failed to authenticate user: %s", err.Error()),
+				})
+			}
+
+			c.Set(scopesContextKey, scopes)
+			if claims.UserId != 0 {
+				setActor(c, "user:"+claims.Username)
+			} else {
+				setActor(c, constants.USER_ROLE_OWNER)
+			}
+			return next(c)
 		})
+		return func(c echo.Context) error {
+			token, found := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+			if !found || !strings.HasPrefix(token, apitokens.TokenPrefix) {
+				return jwtHandler(c)
+			}
+
+			// like sessions, API tokens only work while the hub is unlocked
+			if _, err := httpSvc.cfg.GetJWTSecret(); err != nil {
+				return c.JSON(http.StatusUnauthorized, ErrorResponse{
+					Message: "Hub is locked, the owner must unlock it first.",
+				})
+			}
+
+			// use the address of the direct peer, forwarded-for headers can be spoofed
+			ip := echo.ExtractIPDirect()(c.Request())
+			apiToken, scopes, err := httpSvc.apiTokensSvc.Authenticate(token, ip)
+			if err != nil {
+				if errors.Is(err, apitokens.NewInvalidTokenError()) {
+					return c.JSON(http.StatusUnauthorized, ErrorResponse{
+						Message: err.Error(),
+					})
+				}
+				if errors.Is(err, apitokens.NewIpNotAllowedError()) {
+					return c.JSON(http.StatusForbidden, ErrorResponse{
+						Message: err.Error(),
+					})
+				}
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{
+					Message: fmt.Sprintf("Failed to authenticate API token: %s", err.Error()),
+				})
+			}
+
+			c.Set(scopesContextKey, scopes)
+			setActor(c, "api_token:"+apiToken.Name)
+			return next(c)
+		}
+	}
+}
+
+// setActor records who made the request for the request log and for audited API calls
+func setActor(c echo.Context, actor string) {
+	c.Set(actorContextKey, actor)
+	c.SetRequest(c.Request().WithContext(audit.WithActor(c.Request().Context(), constants.AUDIT_SOURCE_HTTP, actor)))
+}
+
+func (httpSvc *HttpService) getJWTScopes(claims *jwtCustomClaims) ([]string, error) {
+	scopes := constants.GetApiScopes()
+	if claims.UserId != 0 {
+		// use the current role so role changes and deleted users take effect immediately
+		user, err := httpSvc.usersSvc.GetUser(claims.UserId)
+		if err != nil {
+			return nil, err
+		}
+		scopes = users.GetRoleScopes(user.Role)
+	}
+
+	// no permission specified (backward compatibility) or full access
+	if claims.Permission == "" || claims.Permission == "full" {
+		return scopes, nil
+	}
+	return []string{constants.API_SCOPE_READ}, nil
+}
+
+func requireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scopes, _ := c.Get(scopesContextKey).([]string)
+			if slices.Contains(scopes, scope) {
+				return next(c)
+			}
+
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: fmt.Sprintf("This operation requires the %s scope", scope),
+			})
+		}
 	}
 }
 
@@ -406,7 +621,7 @@ func (httpSvc *HttpService) changeUnlockPasswordHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.ChangeUnlockPassword(&changeUnlockPasswordRequest)
+	err := httpSvc.api.ChangeUnlockPassword(c.Request().Context(), &changeUnlockPasswordRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to change unlock password: %s", err.Error()),
@@ -424,7 +639,7 @@ func (httpSvc *HttpService) updateSettingsHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.UpdateSettings(&updateSettingsRequest)
+	err := httpSvc.api.UpdateSettings(c.Request().Context(), &updateSettingsRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to update settings: %s", err.Error()),
@@ -442,7 +657,7 @@ func (httpSvc *HttpService) autoUnlockHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.SetAutoUnlockPassword(autoUnlockRequest.UnlockPassword)
+	err := httpSvc.api.SetAutoUnlockPassword(c.Request().Context(), autoUnlockRequest.UnlockPassword)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to set auto unlock password: %s", err.Error()),
@@ -452,7 +667,8 @@ func (httpSvc *HttpService) autoUnlockHandler(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) createJWT(tokenExpiryDays *uint64, permission string) (string, error) {
+// createJWT creates a session token for the given user, or for the owner if user is nil
+func (httpSvc *HttpService) createJWT(tokenExpiryDays *uint64, permission string, user *db.User) (string, error) {
 	if !slices.Contains([]string{"full", "readonly"}, permission) {
 		return "", errors.New("invalid token permission")
 	}
@@ -465,10 +681,16 @@ func (httpSvc *HttpService) createJWT(tokenExpiryDays *uint64, permission string
 	// Set custom claims
 	claims := &jwtCustomClaims{
 		Permission: permission,
+		Role:       constants.USER_ROLE_OWNER,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * time.Duration(expiryDays))),
 		},
 	}
+	if user != nil {
+		claims.Role = user.Role
+		claims.UserId = user.ID
+		claims.Username = user.Username
+	}
 
 	// Create token with claims
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -539,7 +761,7 @@ func (httpSvc *HttpService) resetRouterHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.ResetRouter(resetRouterRequest.Key)
+	err := httpSvc.api.ResetRouter(c.Request().Context(), resetRouterRequest.Key)
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -584,166 +806,723 @@ func (httpSvc *HttpService) nodeStatusHandler(c echo.Context) error {
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+func (httpSvc *HttpService) nodeNetworkGraphHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	nodeIds := strings.Split(c.QueryParam("nodeIds"), ",")
+
+	info, err := httpSvc.api.GetNetworkGraph(ctx, nodeIds)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+func (httpSvc *HttpService) migrateNodeStorageHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	var migrateNodeStorageRequest api.MigrateNodeStorageRequest
+	if err := c.Bind(&migrateNodeStorageRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.MigrateNodeStorage(ctx, migrateNodeStorageRequest.To)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) balancesHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	balances, err := httpSvc.api.GetBalances(ctx)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, balances)
+}
+
+func (httpSvc *HttpService) sendPaymentHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var payInvoiceRequest api.PayInvoiceRequest
+	if err := c.Bind(&payInvoiceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+	amountMsat := api.ResolveToMsat(payInvoiceRequest.AmountSat, payInvoiceRequest.AmountMsat, nil, payInvoiceRequest.Amount)
+
+	paymentResponse, err := httpSvc.api.SendPayment(ctx, c.Param("invoice"), amountMsat, payInvoiceRequest.Metadata, payInvoiceRequest.FromAppID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, paymentResponse)
+}
+
+func (httpSvc *HttpService) payOfferHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var payOfferRequest api.PayOfferRequest
+	if err := c.Bind(&payOfferRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	amountMsat := uint64(0)
+	resolvedAmountMsat := api.ResolveToMsat(payOfferRequest.AmountSat, payOfferRequest.AmountMsat, nil, nil)
+	if resolvedAmountMsat != nil {
+		amountMsat = *resolvedAmountMsat
+	}
+
+	paymentResponse, err := httpSvc.api.PayOffer(ctx, c.Param("offer"), amountMsat, payOfferRequest.PayerNote, payOfferRequest.Metadata, payOfferRequest.FromAppID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, paymentResponse)
+}
+
+func (httpSvc *HttpService) makeOfferHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var makeOfferRequest api.MakeOfferRequest
+	if err := c.Bind(&makeOfferRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	offer, err := httpSvc.api.MakeOffer(ctx, makeOfferRequest.Description)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to generate BOLT-12 offer: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, offer)
+}
+
+func (httpSvc *HttpService) makeInvoiceHandler(c echo.Context) error {
+	var makeInvoiceRequest api.MakeInvoiceRequest
+	if err := c.Bind(&makeInvoiceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	amountMsat := uint64(0)
+	resolvedAmountMsat := api.ResolveToMsat(makeInvoiceRequest.AmountSat, makeInvoiceRequest.AmountMsat, nil, makeInvoiceRequest.Amount)
+	if resolvedAmountMsat != nil {
+		amountMsat = *resolvedAmountMsat
+	}
+
+	invoice, err := httpSvc.api.CreateInvoice(c.Request().Context(), amountMsat, makeInvoiceRequest.Description, makeInvoiceRequest.ToAppID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, invoice)
+}
+
+func (httpSvc *HttpService) lookupTransactionHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	transaction, err := httpSvc.api.LookupInvoice(ctx, c.Param("paymentHash"))
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, transaction)
+}
+
+func (httpSvc *HttpService) setTransactionUserLabelsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var requestData api.SetTransactionUserLabelsRequest
+	if err := c.Bind(&requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	transactionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid transaction ID",
+		})
+	}
+
+	err = httpSvc.api.SetTransactionUserLabels(ctx, uint(transactionID), requestData.Labels)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listPaymentApprovalsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	paymentApprovals, err := httpSvc.api.ListPaymentApprovals(ctx, c.QueryParam("state"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list payment approvals: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, paymentApprovals)
+}
+
+func (httpSvc *HttpService) approvePaymentHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	approvalID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid payment approval ID",
+		})
+	}
+
+	paymentResponse, err := httpSvc.api.ApprovePayment(ctx, uint(approvalID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, paymentResponse)
+}
+
+func (httpSvc *HttpService) rejectPaymentHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	approvalID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid payment approval ID",
+		})
+	}
+
+	err = httpSvc.api.RejectPayment(ctx, uint(approvalID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listApiTokensHandler(c echo.Context) error {
+	apiTokens, err := httpSvc.api.ListApiTokens()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list API tokens: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, apiTokens)
+}
+
+func (httpSvc *HttpService) createApiTokenHandler(c echo.Context) error {
+	var createApiTokenRequest api.CreateApiTokenRequest
+	if err := c.Bind(&createApiTokenRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	responseBody, err := httpSvc.api.CreateApiToken(c.Request().Context(), &createApiTokenRequest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create API token: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, responseBody)
+}
+
+func (httpSvc *HttpService) listUsersHandler(c echo.Context) error {
+	users, err := httpSvc.api.ListUsers()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list users: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+func (httpSvc *HttpService) createUserHandler(c echo.Context) error {
+	var createUserRequest api.CreateUserRequest
+	if err := c.Bind(&createUserRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	responseBody, err := httpSvc.api.CreateUser(c.Request().Context(), &createUserRequest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to create user: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, responseBody)
+}
+
+func (httpSvc *HttpService) updateUserHandler(c echo.Context) error {
+	userId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid user ID",
+		})
+	}
+
+	var updateUserRequest api.UpdateUserRequest
+	if err := c.Bind(&updateUserRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	responseBody, err := httpSvc.api.UpdateUser(c.Request().Context(), uint(userId), &updateUserRequest)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "User not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to update user: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, responseBody)
+}
+
+func (httpSvc *HttpService) deleteUserHandler(c echo.Context) error {
+	userId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid user ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteUser(c.Request().Context(), uint(userId))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "User not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete user: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listFeePoliciesHandler(c echo.Context) error {
+	feePolicies, err := httpSvc.api.ListFeePolicies()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list fee policies: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feePolicies)
+}
+
+func (httpSvc *HttpService) setFeePolicyHandler(c echo.Context) error {
+	var setFeePolicyRequest api.SetFeePolicyRequest
+	if err := c.Bind(&setFeePolicyRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	feePolicy, err := httpSvc.api.SetFeePolicy(c.Request().Context(), &setFeePolicyRequest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to set fee policy: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feePolicy)
+}
+
+func (httpSvc *HttpService) deleteFeePolicyHandler(c echo.Context) error {
+	feePolicyId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "Invalid fee policy ID",
+		})
+	}
+
+	err = httpSvc.api.DeleteFeePolicy(c.Request().Context(), uint(feePolicyId))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "Fee policy not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to delete fee policy: %s", err.Error()),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) listFeeChangesHandler(c echo.Context) error {
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	feeChanges, err := httpSvc.api.ListFeeChanges(limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list fee changes: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feeChanges)
+}
+
+func (httpSvc *HttpService) feeManagerStatusHandler(c echo.Context) error {
+	status, err := httpSvc.api.GetFeeManagerStatus()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get fee manager status: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+func (httpSvc *HttpService) previewFeeChangesHandler(c echo.Context) error {
+	feeChanges, err := httpSvc.api.PreviewFeeChanges(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to preview fee changes: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feeChanges)
+}
+
+func (httpSvc *HttpService) runFeeManagerHandler(c echo.Context) error {
+	feeChanges, err := httpSvc.api.RunFeeManager(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to run fee manager: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, feeChanges)
+}
+
+func (httpSvc *HttpService) rebalancerStatusHandler(c echo.Context) error {
+	status, err := httpSvc.api.GetRebalancerStatus()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get rebalancer status: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+func (httpSvc *HttpService) previewRebalancesHandler(c echo.Context) error {
+	rebalances, err := httpSvc.api.PreviewRebalances(c.Request().Context())
+	if err != nil {
+		if errors.Is(err, rebalance.ErrNotSupported) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to preview rebalances: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, rebalances)
+}
+
+func (httpSvc *HttpService) runRebalancerHandler(c echo.Context) error {
+	results, err := httpSvc.api.RunRebalancer(c.Request().Context())
+	if err != nil {
+		if errors.Is(err, rebalance.ErrNotSupported) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to run rebalancer: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
+
+func (httpSvc *HttpService) listAuditEventsHandler(c echo.Context) error {
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	filters, err := api.ParseListAuditEventsFilters(c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	auditEvents, err := httpSvc.api.ListAuditEvents(limit, offset, filters)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to list audit events: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, info)
+	return c.JSON(http.StatusOK, auditEvents)
 }
 
-func (httpSvc *HttpService) nodeNetworkGraphHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	nodeIds := strings.Split(c.QueryParam("nodeIds"), ",")
+func (httpSvc *HttpService) verifyAuditLogHandler(c echo.Context) error {
+	verifyResult, err := httpSvc.api.VerifyAuditLog()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to verify audit log: %s", err.Error()),
+		})
+	}
 
-	info, err := httpSvc.api.GetNetworkGraph(ctx, nodeIds)
+	return c.JSON(http.StatusOK, verifyResult)
+}
 
+func (httpSvc *HttpService) totpStatusHandler(c echo.Context) error {
+	totpStatus, err := httpSvc.api.GetTOTPStatus()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to get two-factor authentication status: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, info)
+	return c.JSON(http.StatusOK, totpStatus)
 }
 
-func (httpSvc *HttpService) migrateNodeStorageHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-	var migrateNodeStorageRequest api.MigrateNodeStorageRequest
-	if err := c.Bind(&migrateNodeStorageRequest); err != nil {
+func (httpSvc *HttpService) beginTOTPEnrollmentHandler(c echo.Context) error {
+	var beginTOTPEnrollmentRequest api.BeginTOTPEnrollmentRequest
+	if err := c.Bind(&beginTOTPEnrollmentRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	err := httpSvc.api.MigrateNodeStorage(ctx, migrateNodeStorageRequest.To)
-
+	enrollment, err := httpSvc.api.BeginTOTPEnrollment(c.Request().Context(), &beginTOTPEnrollmentRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to begin two-factor authentication enrollment: %s", err.Error()),
 		})
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusOK, enrollment)
 }
 
-func (httpSvc *HttpService) balancesHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	balances, err := httpSvc.api.GetBalances(ctx)
+func (httpSvc *HttpService) confirmTOTPEnrollmentHandler(c echo.Context) error {
+	var totpRequest api.TOTPRequest
+	if err := c.Bind(&totpRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
+	recoveryCodesResponse, err := httpSvc.api.ConfirmTOTPEnrollment(c.Request().Context(), &totpRequest)
 	if err != nil {
+		if totp.IsCodeError(err) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to enable two-factor authentication: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, balances)
+	return c.JSON(http.StatusOK, recoveryCodesResponse)
 }
 
-func (httpSvc *HttpService) sendPaymentHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var payInvoiceRequest api.PayInvoiceRequest
-	if err := c.Bind(&payInvoiceRequest); err != nil {
+func (httpSvc *HttpService) disableTOTPHandler(c echo.Context) error {
+	var totpRequest api.TOTPRequest
+	if err := c.Bind(&totpRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
-	amountMsat := api.ResolveToMsat(payInvoiceRequest.AmountSat, payInvoiceRequest.AmountMsat, nil, payInvoiceRequest.Amount)
-
-	paymentResponse, err := httpSvc.api.SendPayment(ctx, c.Param("invoice"), amountMsat, payInvoiceRequest.Metadata, payInvoiceRequest.FromAppID)
 
+	err := httpSvc.api.DisableTOTP(c.Request().Context(), &totpRequest)
 	if err != nil {
+		if totp.IsCodeError(err) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to disable two-factor authentication: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, paymentResponse)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) makeOfferHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var makeOfferRequest api.MakeOfferRequest
-	if err := c.Bind(&makeOfferRequest); err != nil {
+func (httpSvc *HttpService) regenerateTOTPRecoveryCodesHandler(c echo.Context) error {
+	var totpRequest api.TOTPRequest
+	if err := c.Bind(&totpRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	offer, err := httpSvc.api.MakeOffer(ctx, makeOfferRequest.Description)
+	recoveryCodesResponse, err := httpSvc.api.RegenerateTOTPRecoveryCodes(c.Request().Context(), &totpRequest)
+	if err != nil {
+		if totp.IsCodeError(err) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to regenerate recovery codes: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, recoveryCodesResponse)
+}
 
+func (httpSvc *HttpService) encryptionStatusHandler(c echo.Context) error {
+	encryptionStatus, err := httpSvc.api.GetEncryptionStatus()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: fmt.Sprintf("Failed to generate BOLT-12 offer: %s", err.Error()),
+			Message: fmt.Sprintf("Failed to get encryption status: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, offer)
+	return c.JSON(http.StatusOK, encryptionStatus)
 }
 
-func (httpSvc *HttpService) makeInvoiceHandler(c echo.Context) error {
-	var makeInvoiceRequest api.MakeInvoiceRequest
-	if err := c.Bind(&makeInvoiceRequest); err != nil {
+func (httpSvc *HttpService) upgradeKDFHandler(c echo.Context) error {
+	var upgradeKDFRequest api.UpgradeKDFRequest
+	if err := c.Bind(&upgradeKDFRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Message: fmt.Sprintf("Bad request: %s", err.Error()),
 		})
 	}
 
-	amountMsat := uint64(0)
-	resolvedAmountMsat := api.ResolveToMsat(makeInvoiceRequest.AmountSat, makeInvoiceRequest.AmountMsat, nil, makeInvoiceRequest.Amount)
-	if resolvedAmountMsat != nil {
-		amountMsat = *resolvedAmountMsat
-	}
-
-	invoice, err := httpSvc.api.CreateInvoice(c.Request().Context(), amountMsat, makeInvoiceRequest.Description, makeInvoiceRequest.ToAppID)
-
+	err := httpSvc.api.UpgradeKDF(c.Request().Context(), &upgradeKDFRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to upgrade key derivation parameters: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, invoice)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) lookupTransactionHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	transaction, err := httpSvc.api.LookupInvoice(ctx, c.Param("paymentHash"))
+func (httpSvc *HttpService) rotateDataKeyHandler(c echo.Context) error {
+	var rotateDataKeyRequest api.RotateDataKeyRequest
+	if err := c.Bind(&rotateDataKeyRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
 
+	err := httpSvc.api.RotateDataKey(c.Request().Context(), &rotateDataKeyRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to rotate data encryption key: %s", err.Error()),
 		})
 	}
 
-	return c.JSON(http.StatusOK, transaction)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (httpSvc *HttpService) setTransactionUserLabelsHandler(c echo.Context) error {
-	ctx := c.Request().Context()
-
-	var requestData api.SetTransactionUserLabelsRequest
-	if err := c.Bind(&requestData); err != nil {
+func (httpSvc *HttpService) deleteApiTokenHandler(c echo.Context) error {
+	apiTokenId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+			Message: "Invalid API token ID",
 		})
 	}
 
-	transactionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid transaction ID",
+	err = httpSvc.api.DeleteApiToken(c.Request().Context(), uint(apiTokenId))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "API token not found",
 		})
 	}
-
-	err = httpSvc.api.SetTransactionUserLabels(ctx, uint(transactionID), requestData.Labels)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: err.Error(),
+			Message: fmt.Sprintf("Failed to delete API token: %s", err.Error()),
 		})
 	}
 
@@ -785,6 +1564,11 @@ func (httpSvc *HttpService) listTransactionsHandler(c echo.Context) error {
 
 	transactions, err := httpSvc.api.ListTransactions(ctx, appId, limit, offset, filters)
 
+	if errors.Is(err, hubtransactions.NewInvalidCursorError()) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: err.Error(),
@@ -794,6 +1578,41 @@ func (httpSvc *HttpService) listTransactionsHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, transactions)
 }
 
+func (httpSvc *HttpService) listActivityHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	limit := uint64(20)
+	offset := uint64(0)
+
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	filters, err := api.ParseListActivityFilters(c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	activity, err := httpSvc.api.ListActivity(ctx, limit, offset, filters)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, activity)
+}
+
 func (httpSvc *HttpService) listOnchainTransactionsHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
@@ -919,6 +1738,31 @@ func (httpSvc *HttpService) rebalanceChannelHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, rebalanceChannelResponse)
 }
 
+func (httpSvc *HttpService) circularRebalanceHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var circularRebalanceRequest api.CircularRebalanceRequest
+	if err := c.Bind(&circularRebalanceRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	result, err := httpSvc.api.CircularRebalance(ctx, &circularRebalanceRequest)
+	if err != nil {
+		if errors.Is(err, rebalance.ErrNotSupported) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to rebalance channels: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
 func (httpSvc *HttpService) disconnectPeerHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 
@@ -1036,9 +1880,14 @@ func (httpSvc *HttpService) redeemOnchainFundsHandler(c echo.Context) error {
 		amountSat = *resolvedAmountSat
 	}
 
-	redeemOnchainFundsResponse, err := httpSvc.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, amountSat, redeemOnchainFundsRequest.FeeRate, redeemOnchainFundsRequest.SendAll)
+	redeemOnchainFundsResponse, err := httpSvc.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, amountSat, redeemOnchainFundsRequest.FeeRate, redeemOnchainFundsRequest.SendAll, redeemOnchainFundsRequest.TotpCode)
 
 	if err != nil {
+		if totp.IsCodeError(err) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to redeem onchain funds: %s", err.Error()),
 		})
@@ -1176,7 +2025,7 @@ func (httpSvc *HttpService) appsUpdateHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.UpdateApp(dbApp, &requestData)
+	err := httpSvc.api.UpdateApp(c.Request().Context(), dbApp, &requestData)
 
 	if err != nil {
 		logger.Logger.WithError(err).Error("Failed to update app")
@@ -1222,7 +2071,19 @@ func (httpSvc *HttpService) appsDeleteHandler(c echo.Context) error {
 		})
 	}
 
-	if err := httpSvc.api.DeleteApp(dbApp); err != nil {
+	var deleteAppRequest api.DeleteAppRequest
+	if err := c.Bind(&deleteAppRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	if err := httpSvc.api.DeleteApp(c.Request().Context(), dbApp, deleteAppRequest.TotpCode); err != nil {
+		if totp.IsCodeError(err) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: "Failed to delete app",
 		})
@@ -1238,7 +2099,7 @@ func (httpSvc *HttpService) appsCreateHandler(c echo.Context) error {
 		})
 	}
 
-	responseBody, err := httpSvc.api.CreateApp(&requestData)
+	responseBody, err := httpSvc.api.CreateApp(c.Request().Context(), &requestData)
 
 	if err != nil {
 		logger.Logger.WithField("appName", requestData.Name).WithError(err).Error("Failed to save app")
@@ -1304,7 +2165,9 @@ func (httpSvc *HttpService) setupHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.Setup(c.Request().Context(), &setupRequest)
+	// whoever completes the setup becomes the owner of the hub
+	ctx := audit.WithActor(c.Request().Context(), constants.AUDIT_SOURCE_HTTP, constants.USER_ROLE_OWNER)
+	err := httpSvc.api.Setup(ctx, &setupRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to setup node: %s", err.Error()),
@@ -1392,8 +2255,13 @@ func (httpSvc *HttpService) createBackupHandler(c echo.Context) error {
 	}
 
 	var buffer bytes.Buffer
-	err := httpSvc.api.CreateBackup(backupRequest.UnlockPassword, &buffer)
+	err := httpSvc.api.CreateBackup(backupRequest.UnlockPassword, backupRequest.TotpCode, &buffer)
 	if err != nil {
+		if totp.IsCodeError(err) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Message: err.Error(),
+			})
+		}
 		return c.String(500, fmt.Sprintf("Failed to create backup: %v", err))
 	}
 
@@ -1430,7 +2298,9 @@ func (httpSvc *HttpService) restoreBackupHandler(c echo.Context) error {
 	}
 	defer file.Close()
 
-	err = httpSvc.api.RestoreBackup(password, file)
+	// restoring requires the unlock password of the backup
+	ctx := audit.WithActor(c.Request().Context(), constants.AUDIT_SOURCE_HTTP, constants.USER_ROLE_OWNER)
+	err = httpSvc.api.RestoreBackup(ctx, password, file)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to restore backup: %v", err),
@@ -1440,6 +2310,34 @@ func (httpSvc *HttpService) restoreBackupHandler(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (httpSvc *HttpService) verifyBackupHandler(c echo.Context) error {
+	password := c.FormValue("unlockPassword")
+
+	fileHeader, err := c.FormFile("backup")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get backup file header: %v", err),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to open backup file: %v", err),
+		})
+	}
+	defer file.Close()
+
+	verificationResult, err := httpSvc.api.VerifyBackup(password, file)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Failed to verify backup: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, verificationResult)
+}
+
 func (httpSvc *HttpService) healthHandler(c echo.Context) error {
 	healthResponse, err := httpSvc.api.Health(c.Request().Context())
 	if err != nil {
@@ -1514,6 +2412,42 @@ func (httpSvc *HttpService) initiateSwapOutHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, swapOutResponse)
 }
 
+func (httpSvc *HttpService) getSwapOutQuoteHandler(c echo.Context) error {
+	amountSat, err := strconv.ParseUint(c.QueryParam("amountSat"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid amountSat: %s", err.Error()),
+		})
+	}
+
+	swapOutQuote, err := httpSvc.api.GetSwapOutQuote(amountSat)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap out quote: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapOutQuote)
+}
+
+func (httpSvc *HttpService) getSwapInQuoteHandler(c echo.Context) error {
+	amountSat, err := strconv.ParseUint(c.QueryParam("amountSat"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid amountSat: %s", err.Error()),
+		})
+	}
+
+	swapInQuote, err := httpSvc.api.GetSwapInQuote(amountSat)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap in quote: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, swapInQuote)
+}
+
 func (httpSvc *HttpService) initiateSwapInHandler(c echo.Context) error {
 	var initiateSwapInRequest api.InitiateSwapRequest
 	if err := c.Bind(&initiateSwapInRequest); err != nil {
@@ -1540,7 +2474,7 @@ func (httpSvc *HttpService) refundSwapHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.RefundSwap(&refundSwapInRequest)
+	err := httpSvc.api.RefundSwap(c.Request().Context(), &refundSwapInRequest)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: err.Error(),
@@ -1585,7 +2519,48 @@ func (httpSvc *HttpService) enableAutoSwapOutHandler(c echo.Context) error {
 }
 
 func (httpSvc *HttpService) disableAutoSwapOutHandler(c echo.Context) error {
-	err := httpSvc.api.DisableAutoSwap()
+	err := httpSvc.api.DisableAutoSwap(c.Request().Context())
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) getAutoSwapInConfigHandler(c echo.Context) error {
+	getAutoSwapInConfigResponse, err := httpSvc.api.GetAutoSwapInConfig()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get swap in settings: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, getAutoSwapInConfigResponse)
+}
+
+func (httpSvc *HttpService) enableAutoSwapInHandler(c echo.Context) error {
+	var enableAutoSwapInRequest api.EnableAutoSwapInRequest
+	if err := c.Bind(&enableAutoSwapInRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Bad request: %s", err.Error()),
+		})
+	}
+
+	err := httpSvc.api.EnableAutoSwapIn(c.Request().Context(), &enableAutoSwapInRequest)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to save swap in settings: %v", err),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (httpSvc *HttpService) disableAutoSwapInHandler(c echo.Context) error {
+	err := httpSvc.api.DisableAutoSwapIn(c.Request().Context())
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -1604,7 +2579,7 @@ func (httpSvc *HttpService) setNodeAliasHandler(c echo.Context) error {
 		})
 	}
 
-	err := httpSvc.api.SetNodeAlias(setNodeAliasRequest.NodeAlias)
+	err := httpSvc.api.SetNodeAlias(c.Request().Context(), setNodeAliasRequest.NodeAlias)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: fmt.Sprintf("Failed to set node alias: %s", err.Error()),
@@ -1624,3 +2599,21 @@ func (httpSvc *HttpService) forwardsHandler(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, forwards)
 }
+
+func (httpSvc *HttpService) routingAnalyticsHandler(c echo.Context) error {
+	from, until, err := api.ParseRoutingAnalyticsRange(c.QueryParams())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: fmt.Sprintf("Invalid routing analytics range: %s", err.Error()),
+		})
+	}
+
+	analytics, err := httpSvc.api.GetRoutingAnalytics(from, until)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: fmt.Sprintf("Failed to get routing analytics: %s", err.Error()),
+		})
+	}
+
+	return c.JSON(http.StatusOK, analytics)
+}
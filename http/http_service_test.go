@@ -3,20 +3,25 @@ package http
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
 
+	"github.com/getAlby/go-nostr"
 	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/apitokens"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/service/keys"
 	"github.com/getAlby/hub/tests/db"
 	"github.com/getAlby/hub/tests/mocks"
+	"github.com/getAlby/hub/users"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -71,6 +76,7 @@ func TestUnlock_UnknownPermission(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
 	mockSvc.On("GetConfig").Return(mockConfig)
@@ -223,6 +229,7 @@ func TestUnlock_NodeNotStarted(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
 	mockSvc.On("GetConfig").Return(mockConfig)
@@ -253,6 +260,53 @@ func TestUnlock_NodeNotStarted(t *testing.T) {
 	mockConfig.AssertNotCalled(t, "GetJWTSecret")
 }
 
+func TestUnlock_TOTPRequired(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockEventPublisher := events.NewEventPublisher()
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("encrypted secret", nil)
+	mockConfig.On("Get", "TOTPSecret", "123").Return("JBSWY3DPEHPK3PXP", nil)
+	mockConfig.On("Get", "TOTPRecoveryCodes", "").Return("[]", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, mockEventPublisher)
+	httpSvc.RegisterSharedRoutes(e)
+
+	for totpCode, expectedMessage := range map[string]string{
+		"":             "authentication code required",
+		"not-a-code-1": "invalid authentication code",
+	} {
+		requestBody := api.UnlockRequest{UnlockPassword: "123", TotpCode: totpCode, Permission: "full"}
+		jsonBody, _ := json.Marshal(requestBody)
+		req := httptest.NewRequest(http.MethodPost, "/api/unlock", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		var response ErrorResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.Equal(t, expectedMessage, response.Message)
+	}
+	mockConfig.AssertNotCalled(t, "GetJWTSecret")
+}
+
 func TestGetApps_ReadonlyPermission(t *testing.T) {
 	e := echo.New()
 	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
@@ -266,6 +320,7 @@ func TestGetApps_ReadonlyPermission(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
@@ -322,6 +377,7 @@ func TestGetApps_FullPermission(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
@@ -410,11 +466,14 @@ func TestCreateApp_FullPermission(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
 	mockConfig.On("GetRelayUrls").Return([]string{})
 
+	appWalletSigner, err := keys.NewSoftwareSigner(nostr.GeneratePrivateKey())
+	require.NoError(t, err)
 	mockKeys := mocks.NewMockKeys(t)
-	mockKeys.On("GetAppWalletKey", uint(1)).Return("", nil)
+	mockKeys.On("GetAppWalletSigner", uint(1)).Return(appWalletSigner, nil)
 
 	mockAlbyOAuthService := mocks.NewMockAlbyOAuthService(t)
 	mockAlbyOAuthService.On("GetLightningAddress").Return("", nil)
@@ -477,6 +536,7 @@ func TestCreateApp_ReadonlyPermission(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
 
 	mockKeys := mocks.NewMockKeys(t)
@@ -541,6 +601,7 @@ func TestGetLogOutput_ReadonlyPermission(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
@@ -597,6 +658,7 @@ func TestGetLogOutput_FullPermission(t *testing.T) {
 	mockConfig := mocks.NewMockConfig(t)
 	mockConfig.On("GetEnv").Return(&config.AppConfig{})
 	mockConfig.On("CheckUnlockPassword", "123").Return(true)
+	mockConfig.On("Get", "TOTPSecret", "").Return("", nil)
 	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
 
 	mockSvc.On("GetDB").Return(gormDb)
@@ -643,3 +705,157 @@ func TestGetLogOutput_FullPermission(t *testing.T) {
 	err = json.Unmarshal(rec2.Body.Bytes(), &logResponse)
 	require.NoError(t, err)
 }
+
+func TestApiToken_Scopes(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockEventPublisher := events.NewEventPublisher()
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, mockEventPublisher)
+	httpSvc.RegisterSharedRoutes(e)
+
+	apiTokensSvc := apitokens.NewApiTokensService(gormDb)
+	_, readToken, err := apiTokensSvc.CreateToken("read", []string{constants.API_SCOPE_READ}, nil, nil)
+	require.NoError(t, err)
+	// httptest requests come from 192.0.2.1
+	_, restrictedToken, err := apiTokensSvc.CreateToken("restricted", []string{constants.API_SCOPE_READ}, []string{"10.0.0.0/8"}, nil)
+	require.NoError(t, err)
+
+	send := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/apps", readToken))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/api/apps", readToken))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/tokens", readToken))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/apps", restrictedToken))
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/api/apps", apitokens.TokenPrefix+"unknown"))
+}
+
+func TestApiToken_Locked(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockEventPublisher := events.NewEventPublisher()
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("GetJWTSecret").Return("", errors.New("config not unlocked"))
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, mockEventPublisher)
+	httpSvc.RegisterSharedRoutes(e)
+
+	apiTokensSvc := apitokens.NewApiTokensService(gormDb)
+	_, readToken, err := apiTokensSvc.CreateToken("read", []string{constants.API_SCOPE_READ}, nil, nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/apps", nil)
+	req.Header.Set("Authorization", "Bearer "+readToken)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestLogin_UserRoles(t *testing.T) {
+	e := echo.New()
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	mockSvc := mocks.NewMockService(t)
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mockEventPublisher := events.NewEventPublisher()
+
+	mockConfig := mocks.NewMockConfig(t)
+	mockConfig.On("GetEnv").Return(&config.AppConfig{})
+	mockConfig.On("GetJWTSecret").Return("dummy secret", nil)
+
+	mockSvc.On("GetDB").Return(gormDb)
+	mockSvc.On("GetConfig").Return(mockConfig)
+	mockSvc.On("GetKeys").Return(mocks.NewMockKeys(t))
+	mockSvc.On("GetAlbySvc").Return(mocks.NewMockAlbyService(t))
+	mockSvc.On("GetAlbyOAuthSvc").Return(mocks.NewMockAlbyOAuthService(t))
+
+	httpSvc := NewHttpService(mockSvc, mockEventPublisher)
+	httpSvc.RegisterSharedRoutes(e)
+
+	usersSvc := users.NewUsersService(gormDb)
+	viewer, err := usersSvc.CreateUser("viewer", "password123", constants.USER_ROLE_VIEWER)
+	require.NoError(t, err)
+	_, err = usersSvc.CreateUser("bookkeeper", "password123", constants.USER_ROLE_BOOKKEEPER)
+	require.NoError(t, err)
+
+	login := func(username, password string) (int, string) {
+		body, err := json.Marshal(&api.LoginRequest{Username: username, Password: password})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var authTokenResponse authTokenResponse
+		json.Unmarshal(rec.Body.Bytes(), &authTokenResponse)
+		return rec.Code, authTokenResponse.Token
+	}
+	send := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	status, _ := login("viewer", "wrong password")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, viewerToken := login("viewer", "password123")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/apps", viewerToken))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/api/apps", viewerToken))
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/users", viewerToken))
+
+	// bookkeepers can label transactions but not send payments
+	_, bookkeeperToken := login("bookkeeper", "password123")
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/api/payments/lnbc1", bookkeeperToken))
+	// passes the scope check and fails on the invalid transaction ID
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPatch, "/api/transactions/abc/labels", bookkeeperToken))
+
+	// only the owner can manage users
+	ownerToken, err := httpSvc.createJWT(nil, "full", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/users", ownerToken))
+
+	// deleting a user revokes their existing sessions
+	require.NoError(t, usersSvc.DeleteUser(viewer.ID))
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/api/apps", viewerToken))
+}
@@ -675,6 +675,10 @@ func (bs *BarkService) Shutdown() error {
 
 // --- unsupported / stubbed methods ---
 
+func (bs *BarkService) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (bs *BarkService) SendKeysend(amountMsat uint64, destination string, customRecords []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	return nil, errors.New("keysend not supported")
 }
@@ -757,6 +761,10 @@ func (bs *BarkService) MakeOffer(ctx context.Context, description string) (strin
 	return "", errors.New("not supported")
 }
 
+func (bs *BarkService) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (bs *BarkService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
 	return nil, errors.ErrUnsupported
 }
@@ -13,8 +13,10 @@ import (
 	"github.com/elnosh/gonuts/wallet/storage"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/notifications"
 	decodepay "github.com/nbd-wtf/ln-decodepay"
 	"github.com/sirupsen/logrus"
 )
@@ -23,13 +25,17 @@ const nodeCommandRestore = "restore"
 const nodeCommandCheckMnemonic = "checkmnemonic"
 const nodeCommandResetWallet = "reset"
 
+// how often unpaid invoices and pending payments are checked with the mint
+const paymentPollInterval = 10 * time.Second
+
 type CashuService struct {
 	wallet               *wallet.Wallet
 	workDir              string
 	hasDifferentMnemonic bool
+	eventPublisher       events.EventPublisher
 }
 
-func NewCashuService(cfg config.Config, workDir, mnemonic, mintUrl string) (result lnclient.LNClient, err error) {
+func NewCashuService(ctx context.Context, cfg config.Config, eventPublisher events.EventPublisher, workDir, mnemonic, mintUrl string) (result lnclient.LNClient, err error) {
 	if workDir == "" {
 		return nil, errors.New("one or more required cashu configuration are missing")
 	}
@@ -55,8 +61,9 @@ func NewCashuService(cfg config.Config, workDir, mnemonic, mintUrl string) (resu
 	}
 
 	cs := CashuService{
-		wallet:  cashuWallet,
-		workDir: workDir,
+		wallet:         cashuWallet,
+		workDir:        workDir,
+		eventPublisher: eventPublisher,
 	}
 
 	if cs.wallet.Mnemonic() != mnemonic {
@@ -64,9 +71,37 @@ func NewCashuService(cfg config.Config, workDir, mnemonic, mintUrl string) (resu
 		cs.hasDifferentMnemonic = true
 	}
 
+	go cs.pollPayments(ctx)
+
 	return &cs, nil
 }
 
+// pollPayments periodically checks unpaid invoices and pending payments so
+// payment notifications are published without waiting for a lookup
+func (cs *CashuService) pollPayments(ctx context.Context) {
+	ticker := time.NewTicker(paymentPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now().Unix()
+			for _, mintQuote := range cs.wallet.GetMintQuotes() {
+				if mintQuote.State == nut04.Unpaid && int64(mintQuote.QuoteExpiry) > now {
+					cs.checkIncomingPayment(&mintQuote)
+				}
+			}
+			for _, meltQuote := range cs.wallet.GetMeltQuotes() {
+				if meltQuote.State == nut05.Pending {
+					cs.checkOutgoingPayment(&meltQuote)
+				}
+			}
+		}
+	}
+}
+
 func (cs *CashuService) Shutdown() error {
 	return cs.wallet.Shutdown()
 }
@@ -94,12 +129,39 @@ func (cs *CashuService) SendPaymentSync(invoice string, amountMsat *uint64) (res
 	}
 	fee := meltResponse.FeeReserve - meltResponse.Change.Amount()
 
+	// note: decoding already succeeded when requesting the melt quote
+	paymentRequest, _ := decodepay.Decodepay(invoice)
+	paidAmountMsat := paymentRequest.MSatoshi
+	if paidAmountMsat == 0 {
+		paidAmountMsat = int64(meltResponse.Amount * 1000)
+	}
+	settledAt := time.Now().Unix()
+	cs.eventPublisher.Publish(&events.Event{
+		Event: "nwc_lnclient_payment_sent",
+		Properties: &lnclient.Transaction{
+			Type:            constants.TRANSACTION_TYPE_OUTGOING,
+			Invoice:         invoice,
+			PaymentHash:     paymentRequest.PaymentHash,
+			Preimage:        meltResponse.Preimage,
+			AmountMsat:      paidAmountMsat,
+			FeesPaidMsat:    int64(fee * 1000),
+			CreatedAt:       int64(paymentRequest.CreatedAt),
+			Description:     paymentRequest.Description,
+			DescriptionHash: paymentRequest.DescriptionHash,
+			SettledAt:       &settledAt,
+		},
+	})
+
 	return &lnclient.PayInvoiceResponse{
 		Preimage: meltResponse.Preimage,
 		FeeMsat:  fee * 1000,
 	}, nil
 }
 
+func (cs *CashuService) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (cs *CashuService) SendKeysend(amountMsat uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	return nil, errors.New("keysend not supported")
 }
@@ -281,13 +343,19 @@ func (cs *CashuService) cashuMintQuoteToTransaction(mintQuote *storage.MintQuote
 	description := paymentRequest.Description
 	descriptionHash := paymentRequest.DescriptionHash
 
+	// zero-amount invoices are minted with the amount of the quote
+	amountMsat := paymentRequest.MSatoshi
+	if amountMsat == 0 {
+		amountMsat = int64(mintQuote.Amount * 1000)
+	}
+
 	return &lnclient.Transaction{
 		Type:        constants.TRANSACTION_TYPE_INCOMING,
 		Invoice:     mintQuote.PaymentRequest,
 		PaymentHash: paymentRequest.PaymentHash,
 		// note: setting dummy preimage so that it gets marked as settled
 		Preimage:        paymentRequest.PaymentHash,
-		AmountMsat:      paymentRequest.MSatoshi,
+		AmountMsat:      amountMsat,
 		CreatedAt:       int64(paymentRequest.CreatedAt),
 		ExpiresAt:       expiresAt,
 		Description:     description,
@@ -312,11 +380,16 @@ func (cs *CashuService) cashuMeltQuoteToTransaction(meltQuote *storage.MeltQuote
 	description := paymentRequest.Description
 	descriptionHash := paymentRequest.DescriptionHash
 
+	amountMsat := paymentRequest.MSatoshi
+	if amountMsat == 0 {
+		amountMsat = int64(meltQuote.Amount * 1000)
+	}
+
 	return &lnclient.Transaction{
 		Type:            constants.TRANSACTION_TYPE_OUTGOING,
 		Invoice:         meltQuote.PaymentRequest,
 		PaymentHash:     paymentRequest.PaymentHash,
-		AmountMsat:      paymentRequest.MSatoshi,
+		AmountMsat:      amountMsat,
 		CreatedAt:       int64(paymentRequest.CreatedAt),
 		ExpiresAt:       expiresAt,
 		Description:     description,
@@ -387,6 +460,14 @@ func (cs *CashuService) checkIncomingPayment(mintQuote *storage.MintQuote) {
 					"paymentHash": bolt11.PaymentHash,
 					"amount":      amountMinted,
 				}).Info("sats successfully minted")
+
+				if updatedMintQuote := cs.wallet.GetMintQuoteById(mintQuote.QuoteId); updatedMintQuote != nil {
+					mintQuote = updatedMintQuote
+				}
+				cs.eventPublisher.Publish(&events.Event{
+					Event:      "nwc_lnclient_payment_received",
+					Properties: cs.cashuMintQuoteToTransaction(mintQuote),
+				})
 			}
 		}
 	}
@@ -396,13 +477,25 @@ func (cs *CashuService) checkOutgoingPayment(meltQuote *storage.MeltQuote) {
 	bolt11, _ := decodepay.Decodepay(meltQuote.PaymentRequest)
 
 	if meltQuote.State != nut05.Paid {
-		_, err := cs.wallet.CheckMeltQuoteState(meltQuote.QuoteId)
+		meltQuoteState, err := cs.wallet.CheckMeltQuoteState(meltQuote.QuoteId)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"paymentHash": bolt11.PaymentHash,
 			}).WithError(err).Warn("failed to check invoice state")
+			return
 		}
 
+		if meltQuoteState.State == nut05.Paid {
+			meltQuote.State = meltQuoteState.State
+			meltQuote.Preimage = meltQuoteState.Preimage
+			if meltQuote.SettledAt == 0 {
+				meltQuote.SettledAt = time.Now().Unix()
+			}
+			cs.eventPublisher.Publish(&events.Event{
+				Event:      "nwc_lnclient_payment_sent",
+				Properties: cs.cashuMeltQuoteToTransaction(meltQuote),
+			})
+		}
 	}
 
 }
@@ -412,7 +505,7 @@ func (cs *CashuService) GetSupportedNIP47Methods() []string {
 }
 
 func (cs *CashuService) GetSupportedNIP47NotificationTypes() []string {
-	return []string{}
+	return []string{notifications.PAYMENT_RECEIVED_NOTIFICATION, notifications.PAYMENT_SENT_NOTIFICATION}
 }
 
 func (svc *CashuService) GetPubkey() string {
@@ -520,6 +613,10 @@ func (svc *CashuService) MakeOffer(ctx context.Context, description string) (str
 	return "", errors.New("not supported")
 }
 
+func (svc *CashuService) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (cs *CashuService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
 	return nil, errors.ErrUnsupported
 }
@@ -1,6 +1,7 @@
 package cln
 
 import (
+	"bytes"
 	"context"
 	crand "crypto/rand"
 	"crypto/sha256"
@@ -519,11 +520,17 @@ func (svc *CLNService) trackForwardedPayments(ctx context.Context) {
 					}
 				}
 
-				if forwardNotif.Status != clngrpc.ForwardEventNotification_SETTLED {
+				var event string
+				switch forwardNotif.Status {
+				case clngrpc.ForwardEventNotification_SETTLED:
+					event = "nwc_payment_forwarded"
+				case clngrpc.ForwardEventNotification_FAILED, clngrpc.ForwardEventNotification_LOCAL_FAILED:
+					event = "nwc_payment_forward_failed"
+				default:
 					continue
 				}
 
-				if forwardNotif.FeeMsat == nil || forwardNotif.OutMsat == nil {
+				if event == "nwc_payment_forwarded" && (forwardNotif.FeeMsat == nil || forwardNotif.OutMsat == nil) {
 					logger.Logger.WithFields(logrus.Fields{
 						"earned_msat":                    forwardNotif.FeeMsat,
 						"outbound_amount_forwarded_msat": forwardNotif.OutMsat,
@@ -531,18 +538,47 @@ func (svc *CLNService) trackForwardedPayments(ctx context.Context) {
 					continue
 				}
 
+				properties := &lnclient.PaymentForwardedEventProperties{
+					TotalFeeEarnedMsat:          forwardNotif.FeeMsat.GetMsat(),
+					OutboundAmountForwardedMsat: forwardNotif.OutMsat.GetMsat(),
+					InboundAmountMsat:           forwardNotif.InMsat.GetMsat(),
+					FailureReason:               forwardNotif.GetFailreason(),
+				}
+				properties.IncomingChannelId, properties.IncomingPeerPubkey = svc.lookupForwardChannel(ctx, forwardNotif.InChannel)
+				if forwardNotif.OutChannel != nil {
+					properties.OutgoingChannelId, properties.OutgoingPeerPubkey = svc.lookupForwardChannel(ctx, *forwardNotif.OutChannel)
+				}
+				if forwardNotif.ResolvedTime != nil {
+					resolvedAt := time.UnixMilli(int64(*forwardNotif.ResolvedTime * 1000))
+					properties.ResolvedAt = &resolvedAt
+				}
+				if event == "nwc_payment_forward_failed" && properties.FailureReason == "" {
+					properties.FailureReason = forwardNotif.Status.String()
+				}
+
 				svc.eventPublisher.Publish(&events.Event{
-					Event: "nwc_payment_forwarded",
-					Properties: &lnclient.PaymentForwardedEventProperties{
-						TotalFeeEarnedMsat:          forwardNotif.FeeMsat.Msat,
-						OutboundAmountForwardedMsat: forwardNotif.OutMsat.Msat,
-					},
+					Event:      event,
+					Properties: properties,
 				})
 			}
 		}
 	}
 }
 
+// lookupForwardChannel maps the short channel id of a forward to the
+// channel id and peer returned by ListChannels. Closed channels may no longer be found.
+func (svc *CLNService) lookupForwardChannel(ctx context.Context, shortChannelId string) (channelId string, peerPubkey string) {
+	if shortChannelId == "" {
+		return "", ""
+	}
+	channels, err := svc.client.ListPeerChannels(ctx, &clngrpc.ListpeerchannelsRequest{ShortChannelId: &shortChannelId})
+	if err != nil || len(channels.Channels) != 1 {
+		logger.Logger.WithError(err).WithField("short_channel_id", shortChannelId).Warn("Failed to look up forward channel")
+		return "", ""
+	}
+	return hex.EncodeToString(channels.Channels[0].ChannelId), hex.EncodeToString(channels.Channels[0].PeerId)
+}
+
 func (svc *CLNService) subscribeChannelEvents(ctx context.Context) {
 	for {
 		select {
@@ -601,6 +637,7 @@ func (svc *CLNService) subscribeChannelEvents(ctx context.Context) {
 				}
 				isOutbound := channel.Opener == clngrpc.ChannelSide_LOCAL
 				public := !channel.GetPrivate()
+				fundingTxId := hex.EncodeToString(channel.FundingTxid)
 
 				switch event.NewState {
 				case clngrpc.ChannelState_ChanneldNormal:
@@ -619,12 +656,20 @@ func (svc *CLNService) subscribeChannelEvents(ctx context.Context) {
 							"public":               public,
 							"capacity":             capacity,
 							"is_outbound":          isOutbound,
+							"funding_tx_id":        fundingTxId,
 						},
 					})
 				case clngrpc.ChannelState_Onchain:
+					// our balance at the time of the close is what returns to the on-chain wallet
+					var pendingBalance uint64
+					if channel.ToUsMsat != nil {
+						pendingBalance = channel.ToUsMsat.Msat / 1000
+					}
+
 					logger.Logger.WithFields(logrus.Fields{
 						"counterparty_node_id": peerId,
 						"reason":               channel.Status,
+						"pending_balance":      pendingBalance,
 					}).Info("Channel closed")
 
 					svc.eventPublisher.Publish(&events.Event{
@@ -634,6 +679,8 @@ func (svc *CLNService) subscribeChannelEvents(ctx context.Context) {
 							"counterparty_node_url": "https://amboss.space/node/" + peerId,
 							"reason":                channel.Status,
 							"node_type":             config.CLNBackendType,
+							"pending_balance":       pendingBalance,
+							"funding_tx_id":         fundingTxId,
 						},
 					})
 				}
@@ -1458,6 +1505,14 @@ func (c *CLNService) GetSupportedNIP47Methods() []string {
 		models.MULTI_PAY_INVOICE_METHOD,
 		models.MULTI_PAY_KEYSEND_METHOD,
 		models.SIGN_MESSAGE_METHOD,
+		models.PAY_OFFER_METHOD,
+		models.LIST_CHANNELS_METHOD,
+		models.OPEN_CHANNEL_METHOD,
+		models.CLOSE_CHANNEL_METHOD,
+		models.UPDATE_CHANNEL_FEES_METHOD,
+		models.GET_ONCHAIN_BALANCE_METHOD,
+		models.GET_NEW_ADDRESS_METHOD,
+		models.PAY_ONCHAIN_METHOD,
 	}
 
 	if c.holdEnabled {
@@ -1634,6 +1689,21 @@ func (c *CLNService) ListOnchainTransactions(ctx context.Context) ([]lnclient.On
 
 	transactions := make([]lnclient.OnchainTransaction, 0)
 
+	// fees are recorded as separate events of the transaction
+	feesMsat := map[string]int64{}
+	for _, event := range bkpr.Events {
+		if event.ItemType != clngrpc.BkprlistaccounteventsEvents_ONCHAIN_FEE {
+			continue
+		}
+		txId := hex.EncodeToString(event.Txid)
+		if event.DebitMsat != nil {
+			feesMsat[txId] += int64(event.DebitMsat.Msat)
+		}
+		if event.CreditMsat != nil {
+			feesMsat[txId] -= int64(event.CreditMsat.Msat)
+		}
+	}
+
 	for _, event := range bkpr.Events {
 		if event.ItemType != clngrpc.BkprlistaccounteventsEvents_CHAIN {
 			continue
@@ -1653,8 +1723,14 @@ func (c *CLNService) ListOnchainTransactions(ctx context.Context) ([]lnclient.On
 
 		TxIdHex := hex.EncodeToString(event.Txid)
 
+		var feeSat uint64
+		if transactionType == "outgoing" && feesMsat[TxIdHex] > 0 {
+			feeSat = uint64(feesMsat[TxIdHex]) / 1000
+		}
+
 		transactions = append(transactions, lnclient.OnchainTransaction{
 			AmountSat:        AmountSat,
+			FeeSat:           feeSat,
 			CreatedAt:        uint64(event.Timestamp),
 			State:            "confirmed",
 			Type:             transactionType,
@@ -2161,6 +2237,55 @@ func (c *CLNService) MakeOffer(ctx context.Context, description string) (string,
 	return resp.Bolt12, nil
 }
 
+func (c *CLNService) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
+	logger.Logger.WithFields(logrus.Fields{
+		"offer":       offer,
+		"amount_msat": amountMsat,
+	}).Debug("Pay Offer")
+
+	fetchInvoiceReq := &clngrpc.FetchinvoiceRequest{
+		Offer: offer,
+	}
+	if amountMsat > 0 {
+		fetchInvoiceReq.AmountMsat = &clngrpc.Amount{
+			Msat: amountMsat,
+		}
+	}
+	if payerNote != "" {
+		fetchInvoiceReq.PayerNote = &payerNote
+	}
+
+	fetchInvoiceResp, err := c.client.FetchInvoice(ctx, fetchInvoiceReq)
+	if err != nil {
+		logger.Logger.WithError(err).Error("fetchinvoice failed")
+		return nil, fmt.Errorf("fetchinvoice failed: %w", err)
+	}
+	if fetchInvoiceResp == nil || fetchInvoiceResp.Invoice == "" {
+		return nil, fmt.Errorf("empty fetchinvoice response")
+	}
+
+	resp, err := c.client.Xpay(ctx, &clngrpc.XpayRequest{
+		Invstring: fetchInvoiceResp.Invoice,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("xpay failed")
+		return nil, fmt.Errorf("xpay failed: %w", err)
+	}
+
+	feePaidMsat := uint64(0)
+	if resp.AmountSentMsat != nil && resp.AmountMsat != nil {
+		feePaidMsat = resp.AmountSentMsat.Msat - resp.AmountMsat.Msat
+	}
+
+	paymentHash := sha256.Sum256(resp.PaymentPreimage)
+
+	return &lnclient.PayOfferResponse{
+		Preimage:    hex.EncodeToString(resp.PaymentPreimage),
+		FeeMsat:     feePaidMsat,
+		PaymentHash: hex.EncodeToString(paymentHash[:]),
+	}, nil
+}
+
 func (c *CLNService) OpenChannel(ctx context.Context, openChannelRequest *lnclient.OpenChannelRequest) (*lnclient.OpenChannelResponse, error) {
 	logger.Logger.WithFields(logrus.Fields{
 		"openChannelRequest": openChannelRequest,
@@ -2249,6 +2374,214 @@ func (c *CLNService) ResetRouter(key string) error {
 	return nil
 }
 
+// circularPaymentTimeout is the number of seconds to wait for a circular payment to complete
+const circularPaymentTimeout = 60
+
+// channelPolicy is the fee policy a node applies to forward through a channel
+type channelPolicy struct {
+	feeBaseMsat     uint64
+	feePpm          uint64
+	cltvExpiryDelta uint32
+}
+
+func (policy channelPolicy) fee(amountMsat uint64) uint64 {
+	return policy.feeBaseMsat + amountMsat*policy.feePpm/1_000_000
+}
+
+// SendCircularPayment pays an invoice of our own node out through the outgoing channel and
+// back in through the incoming channel. The CLN pay commands cannot restrict the first and last hop, so
+// the route between the two peers is found with getroute and the payment is sent with sendpay.
+func (c *CLNService) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	decodeResp, err := c.client.Decode(ctx, &clngrpc.DecodeRequest{String_: circularPaymentRequest.PaymentRequest})
+	if err != nil {
+		logger.Logger.WithError(err).Error("decode failed")
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if !decodeResp.Valid || decodeResp.AmountMsat == nil || len(decodeResp.PaymentHash) == 0 {
+		return nil, errors.New("invalid circular payment invoice")
+	}
+	amountMsat := decodeResp.AmountMsat.Msat
+	finalCltv := uint32(18)
+	if decodeResp.MinFinalCltvExpiry != nil {
+		finalCltv = *decodeResp.MinFinalCltvExpiry
+	}
+
+	lastHopPubkey, err := hex.DecodeString(circularPaymentRequest.LastHopPubkey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid last hop pubkey: %w", err)
+	}
+	ourPubkey, err := hex.DecodeString(c.pubkey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node pubkey: %w", err)
+	}
+
+	peerChannelsResp, err := c.client.ListPeerChannels(ctx, &clngrpc.ListpeerchannelsRequest{})
+	if err != nil {
+		logger.Logger.WithError(err).Error("listpeerchannels failed")
+		return nil, err
+	}
+	var outgoingChannel, incomingChannel *clngrpc.ListpeerchannelsChannels
+	for _, channel := range peerChannelsResp.Channels {
+		if channel == nil || channel.ShortChannelId == nil || channel.State != clngrpc.ChannelState_ChanneldNormal {
+			continue
+		}
+		switch hex.EncodeToString(channel.ChannelId) {
+		case circularPaymentRequest.OutgoingChannelId:
+			outgoingChannel = channel
+		case circularPaymentRequest.IncomingChannelId:
+			incomingChannel = channel
+		}
+	}
+	if outgoingChannel == nil {
+		return nil, fmt.Errorf("outgoing channel %s not found", circularPaymentRequest.OutgoingChannelId)
+	}
+	if incomingChannel == nil || incomingChannel.Updates == nil || incomingChannel.Updates.Remote == nil {
+		return nil, fmt.Errorf("incoming channel %s not found", circularPaymentRequest.IncomingChannelId)
+	}
+	if !bytes.Equal(incomingChannel.PeerId, lastHopPubkey) {
+		return nil, fmt.Errorf("incoming channel %s is not with the last hop %s", circularPaymentRequest.IncomingChannelId, circularPaymentRequest.LastHopPubkey)
+	}
+
+	remoteUpdate := incomingChannel.Updates.Remote
+	lastHopPolicy := channelPolicy{
+		feeBaseMsat:     uint64(msatInt64(remoteUpdate.FeeBaseMsat)),
+		feePpm:          uint64(remoteUpdate.FeeProportionalMillionths),
+		cltvExpiryDelta: remoteUpdate.CltvExpiryDelta,
+	}
+	lastHopAmountMsat := amountMsat + lastHopPolicy.fee(amountMsat)
+	lastHopDelay := finalCltv + lastHopPolicy.cltvExpiryDelta
+
+	// the route between the peers must not go through our node or the two channels
+	var peerRoute []*clngrpc.GetrouteRoute
+	var firstHopPolicy channelPolicy
+	if !bytes.Equal(outgoingChannel.PeerId, lastHopPubkey) {
+		getRouteResp, err := c.client.GetRoute(ctx, &clngrpc.GetrouteRequest{
+			Id:         lastHopPubkey,
+			Fromid:     outgoingChannel.PeerId,
+			AmountMsat: &clngrpc.Amount{Msat: lastHopAmountMsat},
+			Cltv:       &lastHopDelay,
+			Riskfactor: 10,
+			Exclude: []string{
+				c.pubkey,
+				*outgoingChannel.ShortChannelId + "/0",
+				*outgoingChannel.ShortChannelId + "/1",
+				*incomingChannel.ShortChannelId + "/0",
+				*incomingChannel.ShortChannelId + "/1",
+			},
+		})
+		if err != nil {
+			logger.Logger.WithError(err).Error("getroute failed")
+			return nil, fmt.Errorf("no route found between the channel peers: %w", err)
+		}
+		peerRoute = getRouteResp.Route
+		if len(peerRoute) == 0 {
+			return nil, errors.New("no route found between the channel peers")
+		}
+		firstHopPolicy, err = c.getChannelPolicy(ctx, peerRoute[0].Channel, outgoingChannel.PeerId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	route := buildCircularRoute(ourPubkey, outgoingChannel.PeerId, *outgoingChannel.ShortChannelId, firstHopPolicy, peerRoute, *incomingChannel.ShortChannelId, amountMsat, finalCltv, lastHopAmountMsat, lastHopDelay)
+	feeMsat := route[0].AmountMsat.Msat - amountMsat
+	if feeMsat > circularPaymentRequest.MaxFeeMsat {
+		return nil, fmt.Errorf("route fee of %d msat exceeds the maximum of %d msat", feeMsat, circularPaymentRequest.MaxFeeMsat)
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"bolt11":   circularPaymentRequest.PaymentRequest,
+		"hops":     len(route),
+		"fee_msat": feeMsat,
+	}).Debug("Sending circular payment")
+
+	_, err = c.client.SendPay(ctx, &clngrpc.SendpayRequest{
+		Route:         route,
+		PaymentHash:   decodeResp.PaymentHash,
+		PaymentSecret: decodeResp.PaymentSecret,
+		Bolt11:        &circularPaymentRequest.PaymentRequest,
+		AmountMsat:    &clngrpc.Amount{Msat: amountMsat},
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("sendpay failed")
+		return nil, fmt.Errorf("sendpay failed: %w", err)
+	}
+
+	timeout := uint32(circularPaymentTimeout)
+	waitResp, err := c.client.WaitSendPay(ctx, &clngrpc.WaitsendpayRequest{
+		PaymentHash: decodeResp.PaymentHash,
+		Timeout:     &timeout,
+	})
+	if err != nil {
+		logger.Logger.WithField("bolt11", circularPaymentRequest.PaymentRequest).WithError(err).Error("Circular payment not successful")
+		return nil, err
+	}
+	if waitResp.Status != clngrpc.WaitsendpayResponse_COMPLETE || len(waitResp.PaymentPreimage) == 0 {
+		return nil, errors.New("no preimage in response")
+	}
+
+	return &lnclient.PayInvoiceResponse{
+		Preimage: hex.EncodeToString(waitResp.PaymentPreimage),
+		FeeMsat:  feeMsat,
+	}, nil
+}
+
+// buildCircularRoute returns the sendpay route from our node to the first peer through the outgoing
+// channel, along the route between the peers and back to our node through the incoming channel.
+// Each hop has the amount and delay of the HTLC the node of the hop receives.
+func buildCircularRoute(ourPubkey []byte, firstPeerPubkey []byte, outgoingScid string, firstHopPolicy channelPolicy, peerRoute []*clngrpc.GetrouteRoute, incomingScid string, amountMsat uint64, finalCltv uint32, lastHopAmountMsat uint64, lastHopDelay uint32) []*clngrpc.SendpayRoute {
+	// the first peer forwards the payment along the route between the peers, or directly
+	// back to us if both channels are with the same peer
+	firstHopAmountMsat := lastHopAmountMsat
+	firstHopDelay := lastHopDelay
+	if len(peerRoute) > 0 {
+		firstHopAmountMsat = peerRoute[0].AmountMsat.Msat + firstHopPolicy.fee(peerRoute[0].AmountMsat.Msat)
+		firstHopDelay = peerRoute[0].Delay + firstHopPolicy.cltvExpiryDelta
+	}
+
+	route := []*clngrpc.SendpayRoute{{
+		Id:         firstPeerPubkey,
+		Channel:    outgoingScid,
+		AmountMsat: &clngrpc.Amount{Msat: firstHopAmountMsat},
+		Delay:      firstHopDelay,
+	}}
+	for _, hop := range peerRoute {
+		route = append(route, &clngrpc.SendpayRoute{
+			Id:         hop.Id,
+			Channel:    hop.Channel,
+			AmountMsat: hop.AmountMsat,
+			Delay:      hop.Delay,
+		})
+	}
+	return append(route, &clngrpc.SendpayRoute{
+		Id:         ourPubkey,
+		Channel:    incomingScid,
+		AmountMsat: &clngrpc.Amount{Msat: amountMsat},
+		Delay:      finalCltv,
+	})
+}
+
+// getChannelPolicy returns the fee policy the source node applies to the channel
+func (c *CLNService) getChannelPolicy(ctx context.Context, shortChannelId string, source []byte) (channelPolicy, error) {
+	resp, err := c.client.ListChannels(ctx, &clngrpc.ListchannelsRequest{
+		ShortChannelId: &shortChannelId,
+		Source:         source,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("listchannels failed")
+		return channelPolicy{}, err
+	}
+	if len(resp.Channels) == 0 {
+		return channelPolicy{}, fmt.Errorf("channel %s not found in the network graph", shortChannelId)
+	}
+	channel := resp.Channels[0]
+	return channelPolicy{
+		feeBaseMsat:     uint64(channel.BaseFeeMillisatoshi),
+		feePpm:          uint64(channel.FeePerMillionth),
+		cltvExpiryDelta: channel.Delay,
+	}, nil
+}
+
 func (c *CLNService) SendKeysend(amount uint64, destination string, customRecords []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	logger.Logger.WithFields(logrus.Fields{
 		"amount":        amount,
@@ -0,0 +1,48 @@
+package cln
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/lnclient/cln/clngrpc"
+)
+
+func TestBuildCircularRoute(t *testing.T) {
+	ourPubkey := []byte{1}
+	firstPeerPubkey := []byte{2}
+	middlePubkey := []byte{3}
+	lastPeerPubkey := []byte{4}
+
+	// the first peer charges 1000 msat + 100 ppm and a delta of 40 to forward to the middle node
+	firstHopPolicy := channelPolicy{feeBaseMsat: 1000, feePpm: 100, cltvExpiryDelta: 40}
+	peerRoute := []*clngrpc.GetrouteRoute{
+		{Id: middlePubkey, Channel: "1x1x1", AmountMsat: &clngrpc.Amount{Msat: 1_002_500}, Delay: 98},
+		{Id: lastPeerPubkey, Channel: "2x2x2", AmountMsat: &clngrpc.Amount{Msat: 1_001_500}, Delay: 58},
+	}
+
+	route := buildCircularRoute(ourPubkey, firstPeerPubkey, "0x0x0", firstHopPolicy, peerRoute, "3x3x3", 1_000_000, 18, 1_001_500, 58)
+
+	assert.Len(t, route, 4)
+	assert.Equal(t, firstPeerPubkey, route[0].Id)
+	assert.Equal(t, "0x0x0", route[0].Channel)
+	assert.Equal(t, uint64(1_002_500+1000+100), route[0].AmountMsat.Msat)
+	assert.Equal(t, uint32(138), route[0].Delay)
+	assert.Equal(t, middlePubkey, route[1].Id)
+	assert.Equal(t, lastPeerPubkey, route[2].Id)
+	assert.Equal(t, ourPubkey, route[3].Id)
+	assert.Equal(t, "3x3x3", route[3].Channel)
+	assert.Equal(t, uint64(1_000_000), route[3].AmountMsat.Msat)
+	assert.Equal(t, uint32(18), route[3].Delay)
+}
+
+func TestBuildCircularRoute_SamePeer(t *testing.T) {
+	route := buildCircularRoute([]byte{1}, []byte{2}, "0x0x0", channelPolicy{}, nil, "3x3x3", 1_000_000, 18, 1_001_100, 58)
+
+	assert.Len(t, route, 2)
+	assert.Equal(t, []byte{2}, route[0].Id)
+	assert.Equal(t, uint64(1_001_100), route[0].AmountMsat.Msat)
+	assert.Equal(t, uint32(58), route[0].Delay)
+	assert.Equal(t, []byte{1}, route[1].Id)
+	assert.Equal(t, uint64(1_000_000), route[1].AmountMsat.Msat)
+}
@@ -692,6 +692,10 @@ func (ls *LDKService) SendPaymentSync(invoice string, amountMsat *uint64) (*lncl
 	}
 }
 
+func (ls *LDKService) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (ls *LDKService) SendKeysend(amountMsat uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	paymentStart := time.Now()
 	customTlvs := []ldk_node.CustomTlvRecord{}
@@ -933,6 +937,10 @@ func (ls *LDKService) ListOnchainTransactions(ctx context.Context) ([]lnclient.O
 		if payment.AmountMsat != nil {
 			amountMsat = *payment.AmountMsat
 		}
+		var feeMsat uint64
+		if payment.FeePaidMsat != nil && payment.Direction == ldk_node.PaymentDirectionOutbound {
+			feeMsat = *payment.FeePaidMsat
+		}
 		var status string
 		var height uint32
 		var numConfirmations uint32
@@ -953,6 +961,7 @@ func (ls *LDKService) ListOnchainTransactions(ctx context.Context) ([]lnclient.O
 
 		transactions = append(transactions, lnclient.OnchainTransaction{
 			AmountSat:        amountMsat / 1000,
+			FeeSat:           feeMsat / 1000,
 			CreatedAt:        createdAt,
 			State:            status,
 			Type:             transactionType,
@@ -1542,6 +1551,7 @@ func (ls *LDKService) ldkPaymentToTransaction(payment *ldk_node.PaymentDetails)
 
 		offer := map[string]interface{}{}
 		offer["id"] = bolt12PaymentKind.OfferId
+		offer["payment_id"] = string(payment.Id)
 
 		if bolt12PaymentKind.PayerNote != nil {
 			offer["payer_note"] = *bolt12PaymentKind.PayerNote
@@ -1700,6 +1710,11 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 		isTrusted := eventType.CounterpartyNodeId != nil &&
 			(slices.Contains(ls.node.Config().AnchorChannelsConfig.TrustedPeersNoReserve, *eventType.CounterpartyNodeId) || isJit)
 
+		var fundingTxId string
+		if channel.FundingTxo != nil {
+			fundingTxId = channel.FundingTxo.Txid
+		}
+
 		ls.eventPublisher.Publish(&events.Event{
 			Event: "nwc_channel_ready",
 			Properties: map[string]interface{}{
@@ -1710,6 +1725,7 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 				"capacity":             channel.ChannelValueSats,
 				"is_outbound":          channel.IsOutbound,
 				"trusted":              isTrusted,
+				"funding_tx_id":        fundingTxId,
 			},
 		})
 
@@ -1856,8 +1872,28 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 
 		transaction, err := ls.ldkPaymentToTransaction(payment)
 		if err != nil {
-			logger.Logger.WithField("payment_id", *eventType.PaymentId).Error("failed to convert LDK payment to transaction")
-			return
+			bolt12PaymentKind, isBolt12PaymentKind := payment.Kind.(ldk_node.PaymentKindBolt12Offer)
+			if !isBolt12PaymentKind || bolt12PaymentKind.Hash != nil {
+				logger.Logger.WithField("payment_id", *eventType.PaymentId).Error("failed to convert LDK payment to transaction")
+				return
+			}
+			// the invoice was never fetched from the offer, so there is no payment hash
+			// to match the pending payment with. Publish the offer payment details instead.
+			var amountMsat uint64
+			if payment.AmountMsat != nil {
+				amountMsat = *payment.AmountMsat
+			}
+			transaction = &lnclient.Transaction{
+				Type:       "outgoing",
+				AmountMsat: int64(amountMsat),
+				CreatedAt:  int64(payment.CreatedAt),
+				Metadata: map[string]interface{}{
+					"offer": map[string]interface{}{
+						"id":         bolt12PaymentKind.OfferId,
+						"payment_id": string(*eventType.PaymentId),
+					},
+				},
+			}
 		}
 
 		reason := ls.getPaymentFailReason(&eventType)
@@ -1881,12 +1917,27 @@ func (ls *LDKService) handleLdkEvent(event *ldk_node.Event) {
 			}).Error("forwarded payment has missing required fields")
 			return
 		}
+		properties := &lnclient.PaymentForwardedEventProperties{
+			TotalFeeEarnedMsat:          *eventType.TotalFeeEarnedMsat,
+			OutboundAmountForwardedMsat: *eventType.OutboundAmountForwardedMsat,
+			InboundAmountMsat:           *eventType.OutboundAmountForwardedMsat + *eventType.TotalFeeEarnedMsat,
+		}
+		// ListChannels returns the user channel id as the channel id
+		if eventType.PrevUserChannelId != nil {
+			properties.IncomingChannelId = *eventType.PrevUserChannelId
+		}
+		if eventType.NextUserChannelId != nil {
+			properties.OutgoingChannelId = *eventType.NextUserChannelId
+		}
+		if eventType.PrevNodeId != nil {
+			properties.IncomingPeerPubkey = *eventType.PrevNodeId
+		}
+		if eventType.NextNodeId != nil {
+			properties.OutgoingPeerPubkey = *eventType.NextNodeId
+		}
 		ls.eventPublisher.Publish(&events.Event{
-			Event: "nwc_payment_forwarded",
-			Properties: &lnclient.PaymentForwardedEventProperties{
-				TotalFeeEarnedMsat:          *eventType.TotalFeeEarnedMsat,
-				OutboundAmountForwardedMsat: *eventType.OutboundAmountForwardedMsat,
-			},
+			Event:      "nwc_payment_forwarded",
+			Properties: properties,
 		})
 
 	case ldk_node.EventPaymentClaimable:
@@ -2159,6 +2210,14 @@ func (ls *LDKService) GetSupportedNIP47Methods() []string {
 		models.MAKE_HOLD_INVOICE_METHOD,
 		models.SETTLE_HOLD_INVOICE_METHOD,
 		models.CANCEL_HOLD_INVOICE_METHOD,
+		models.PAY_OFFER_METHOD,
+		models.LIST_CHANNELS_METHOD,
+		models.OPEN_CHANNEL_METHOD,
+		models.CLOSE_CHANNEL_METHOD,
+		models.UPDATE_CHANNEL_FEES_METHOD,
+		models.GET_ONCHAIN_BALANCE_METHOD,
+		models.GET_NEW_ADDRESS_METHOD,
+		models.PAY_ONCHAIN_METHOD,
 	}
 }
 
@@ -2244,15 +2303,22 @@ func (ls *LDKService) GetPubkey() string {
 	return ls.pubkey
 }
 
-func (ls *LDKService) PayOfferSync(ctx context.Context, offer string, amount uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
-	// TODO: this is only for testing MakeOffer and needs improvements
-	// (+ BOLT-12 payments need to go through transactions service)
-	// TODO: send liquidity event if amount too large
+func (ls *LDKService) PayOffer(ctx context.Context, offer string, amount uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
 	offerObj, err := ldk_node.OfferFromStr(offer)
 	if err != nil {
 		return nil, err
 	}
 
+	maxSpendable := ls.getMaxSpendable()
+	if amount > maxSpendable {
+		ls.eventPublisher.Publish(&events.Event{
+			Event: "nwc_outgoing_liquidity_required",
+			Properties: map[string]interface{}{
+				"node_type": config.LDKBackendType,
+			},
+		})
+	}
+
 	paymentStart := time.Now()
 	ldkEventSubscription := ls.ldkEventBroadcaster.Subscribe()
 	defer ls.ldkEventBroadcaster.CancelSubscription(ldkEventSubscription)
@@ -2331,6 +2397,18 @@ func (ls *LDKService) PayOfferSync(ctx context.Context, offer string, amount uin
 		}
 	}
 
+	if preimage == "" {
+		// the payment may still complete: its result is published as a payment event
+		logger.Logger.WithField("payment_id", paymentId).Warn("Timed out waiting for BOLT-12 payment result")
+		payment := ls.node.Payment(paymentId)
+		if payment != nil {
+			if bolt12PaymentKind, ok := payment.Kind.(ldk_node.PaymentKindBolt12Offer); ok && bolt12PaymentKind.Hash != nil {
+				paymentHash = *bolt12PaymentKind.Hash
+			}
+		}
+		return nil, lnclient.NewPaymentPendingError(paymentHash, string(paymentId))
+	}
+
 	logger.Logger.WithFields(logrus.Fields{
 		"duration": time.Since(paymentStart).Milliseconds(),
 		"feeMsat":  feeMsat,
@@ -2401,7 +2479,7 @@ func (ls *LDKService) ExecuteCustomNodeCommand(ctx context.Context, command *lnc
 			return nil, err
 		}
 
-		payOfferResponse, err := ls.PayOfferSync(ctx, offer, amount, payerNote)
+		payOfferResponse, err := ls.PayOffer(ctx, offer, amount, payerNote)
 
 		if err != nil {
 			return nil, err
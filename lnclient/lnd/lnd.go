@@ -99,6 +99,7 @@ func NewLNDService(ctx context.Context, eventPublisher events.EventPublisher, ln
 	go lndService.subscribeChannelEvents(lndCtx)
 	go lndService.subscribeOpenHoldInvoices(lndCtx)
 	go lndService.trackForwardedPayments(lndCtx)
+	go lndService.subscribeFailedForwards(lndCtx)
 
 	logger.Logger.WithField("alias", nodeInfo.Alias).Info("Connected to LND")
 
@@ -123,12 +124,23 @@ func (svc *LNDService) trackForwardedPayments(ctx context.Context) {
 				logger.Logger.WithError(err).Error("failed to read forwarding history")
 				continue
 			}
+			var channelPeers map[uint64]string
+			if len(forwardedPayments.ForwardingEvents) > 0 {
+				channelPeers = svc.getChannelPeers(ctx)
+			}
 			for _, forwardingEvent := range forwardedPayments.ForwardingEvents {
+				resolvedAt := time.Unix(0, int64(forwardingEvent.TimestampNs))
 				svc.eventPublisher.Publish(&events.Event{
 					Event: "nwc_payment_forwarded",
 					Properties: &lnclient.PaymentForwardedEventProperties{
 						TotalFeeEarnedMsat:          forwardingEvent.FeeMsat,
 						OutboundAmountForwardedMsat: forwardingEvent.AmtOutMsat,
+						InboundAmountMsat:           forwardingEvent.AmtInMsat,
+						IncomingChannelId:           strconv.FormatUint(forwardingEvent.ChanIdIn, 10),
+						OutgoingChannelId:           strconv.FormatUint(forwardingEvent.ChanIdOut, 10),
+						IncomingPeerPubkey:          channelPeers[forwardingEvent.ChanIdIn],
+						OutgoingPeerPubkey:          channelPeers[forwardingEvent.ChanIdOut],
+						ResolvedAt:                  &resolvedAt,
 					},
 				})
 			}
@@ -137,6 +149,150 @@ func (svc *LNDService) trackForwardedPayments(ctx context.Context) {
 	}
 }
 
+// getChannelPeers maps channel ids to the remote pubkey of the open channels
+func (svc *LNDService) getChannelPeers(ctx context.Context) map[uint64]string {
+	channelPeers := map[uint64]string{}
+	resp, err := svc.client.ListChannels(ctx, &lnrpc.ListChannelsRequest{})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list channels for forwards")
+		return channelPeers
+	}
+	for _, channel := range resp.Channels {
+		channelPeers[channel.ChanId] = channel.RemotePubkey
+	}
+	return channelPeers
+}
+
+type htlcKey struct {
+	channelId uint64
+	htlcId    uint64
+}
+
+// pendingForwardMaxAge is how long the amounts of a forward are kept while waiting for
+// it to settle or fail, so that forwards whose resolution was missed are not kept forever
+const pendingForwardMaxAge = 24 * time.Hour
+
+type pendingForward struct {
+	info       *routerrpc.HtlcInfo
+	receivedAt time.Time
+}
+
+// pendingForwards holds the amounts of in-flight forwards until they are resolved
+type pendingForwards struct {
+	forwards   map[htlcKey]pendingForward
+	lastPruned time.Time
+}
+
+func newPendingForwards() *pendingForwards {
+	return &pendingForwards{forwards: map[htlcKey]pendingForward{}, lastPruned: time.Now()}
+}
+
+func (pf *pendingForwards) add(key htlcKey, info *routerrpc.HtlcInfo, now time.Time) {
+	pf.forwards[key] = pendingForward{info: info, receivedAt: now}
+	if now.Sub(pf.lastPruned) < time.Hour {
+		return
+	}
+	for key, forward := range pf.forwards {
+		if now.Sub(forward.receivedAt) > pendingForwardMaxAge {
+			delete(pf.forwards, key)
+		}
+	}
+	pf.lastPruned = now
+}
+
+// remove returns the amounts of the forward, or nil if it is unknown
+func (pf *pendingForwards) remove(key htlcKey) *routerrpc.HtlcInfo {
+	forward, ok := pf.forwards[key]
+	if !ok {
+		return nil
+	}
+	delete(pf.forwards, key)
+	return forward.info
+}
+
+// subscribeFailedForwards publishes forwards which failed. The forwarding history only
+// contains settled forwards, so the amounts are taken from the preceding forward event.
+// NOTE: like trackForwardedPayments this only tracks forwards while hub is online
+func (svc *LNDService) subscribeFailedForwards(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			htlcStream, err := svc.client.SubscribeHtlcEvents(ctx, &routerrpc.SubscribeHtlcEventsRequest{})
+			if err != nil {
+				logger.Logger.WithError(err).Error("Error subscribing to htlc events")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(10 * time.Second):
+					continue
+				}
+			}
+			pendingForwards := newPendingForwards()
+		htlcLoop:
+			for {
+				htlcEvent, err := htlcStream.Recv()
+				if err != nil {
+					logger.Logger.WithError(err).Error("Failed to receive htlc event")
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(2 * time.Second):
+						break htlcLoop
+					}
+				}
+				if htlcEvent.EventType != routerrpc.HtlcEvent_FORWARD {
+					continue
+				}
+
+				key := htlcKey{channelId: htlcEvent.IncomingChannelId, htlcId: htlcEvent.IncomingHtlcId}
+				var info *routerrpc.HtlcInfo
+				var failureReason string
+				switch event := htlcEvent.Event.(type) {
+				case *routerrpc.HtlcEvent_ForwardEvent:
+					pendingForwards.add(key, event.ForwardEvent.GetInfo(), time.Now())
+					continue
+				case *routerrpc.HtlcEvent_SettleEvent:
+					pendingForwards.remove(key)
+					continue
+				case *routerrpc.HtlcEvent_ForwardFailEvent:
+					info = pendingForwards.remove(key)
+					failureReason = "downstream failure"
+				case *routerrpc.HtlcEvent_LinkFailEvent:
+					info = event.LinkFailEvent.GetInfo()
+					failureReason = event.LinkFailEvent.GetFailureString()
+					if failureReason == "" {
+						failureReason = event.LinkFailEvent.GetFailureDetail().String()
+					}
+				default:
+					continue
+				}
+
+				resolvedAt := time.Unix(0, int64(htlcEvent.TimestampNs))
+				properties := &lnclient.PaymentForwardedEventProperties{
+					InboundAmountMsat:           info.GetIncomingAmtMsat(),
+					OutboundAmountForwardedMsat: info.GetOutgoingAmtMsat(),
+					IncomingChannelId:           strconv.FormatUint(htlcEvent.IncomingChannelId, 10),
+					ResolvedAt:                  &resolvedAt,
+					FailureReason:               failureReason,
+				}
+				if htlcEvent.OutgoingChannelId != 0 {
+					properties.OutgoingChannelId = strconv.FormatUint(htlcEvent.OutgoingChannelId, 10)
+				}
+				channelPeers := svc.getChannelPeers(ctx)
+				properties.IncomingPeerPubkey = channelPeers[htlcEvent.IncomingChannelId]
+				properties.OutgoingPeerPubkey = channelPeers[htlcEvent.OutgoingChannelId]
+
+				svc.eventPublisher.Publish(&events.Event{
+					Event:      "nwc_payment_forward_failed",
+					Properties: properties,
+				})
+			}
+		}
+	}
+}
+
 func (svc *LNDService) subscribePayments(ctx context.Context) {
 	for {
 		select {
@@ -284,6 +440,10 @@ func (svc *LNDService) subscribeChannelEvents(ctx context.Context) {
 				switch update := event.Channel.(type) {
 				case *lnrpc.ChannelEventUpdate_OpenChannel:
 					channel := update.OpenChannel
+					var fundingTxId string
+					if channelPoint, err := svc.parseChannelPoint(channel.ChannelPoint); err == nil {
+						fundingTxId = channelPoint.GetFundingTxidStr()
+					}
 					logger.Logger.WithFields(logrus.Fields{
 						"counterparty_node_id": channel.RemotePubkey,
 						"public":               !channel.Private,
@@ -299,15 +459,23 @@ func (svc *LNDService) subscribeChannelEvents(ctx context.Context) {
 							"public":               !channel.Private,
 							"capacity":             channel.Capacity,
 							"is_outbound":          channel.Initiator,
+							"funding_tx_id":        fundingTxId,
 						},
 					})
 				case *lnrpc.ChannelEventUpdate_ClosedChannel:
 					closureReason := update.ClosedChannel.CloseType.String()
 					counterpartyNodeId := update.ClosedChannel.RemotePubkey
+					// settled and time locked balances both return to the on-chain wallet
+					pendingBalance := update.ClosedChannel.SettledBalance + update.ClosedChannel.TimeLockedBalance
+					var fundingTxId string
+					if channelPoint, err := svc.parseChannelPoint(update.ClosedChannel.ChannelPoint); err == nil {
+						fundingTxId = channelPoint.GetFundingTxidStr()
+					}
 
 					logger.Logger.WithFields(logrus.Fields{
 						"counterparty_node_id": counterpartyNodeId,
 						"reason":               closureReason,
+						"pending_balance":      pendingBalance,
 					}).Info("Channel closed")
 
 					svc.eventPublisher.Publish(&events.Event{
@@ -317,6 +485,8 @@ func (svc *LNDService) subscribeChannelEvents(ctx context.Context) {
 							"counterparty_node_url": "https://amboss.space/node/" + counterpartyNodeId,
 							"reason":                closureReason,
 							"node_type":             config.LNDBackendType,
+							"pending_balance":       pendingBalance,
+							"funding_tx_id":         fundingTxId,
 						},
 					})
 				}
@@ -494,6 +664,130 @@ func (svc *LNDService) SendPaymentSync(payReq string, amountMsat *uint64) (*lncl
 	}, nil
 }
 
+// SendCircularPayment pays an invoice of our own node out through the outgoing channel and
+// back in through the incoming channel. SendPaymentV2 can only restrict the last hop to a
+// node, so the route to the last hop is found with QueryRoutes and the hop through the
+// incoming channel is added to it before it is sent with SendToRouteV2.
+func (svc *LNDService) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	outgoingChanId, err := strconv.ParseUint(circularPaymentRequest.OutgoingChannelId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outgoing channel id: %w", err)
+	}
+	incomingChanId, err := strconv.ParseUint(circularPaymentRequest.IncomingChannelId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid incoming channel id: %w", err)
+	}
+
+	payReq, err := svc.client.DecodePayReq(ctx, &lnrpc.PayReqString{PayReq: circularPaymentRequest.PaymentRequest})
+	if err != nil {
+		logger.Logger.WithField("bolt11", circularPaymentRequest.PaymentRequest).WithError(err).Error("DecodePayReq failed")
+		return nil, err
+	}
+	paymentHash, err := hex.DecodeString(payReq.PaymentHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment hash: %w", err)
+	}
+
+	channelEdge, err := svc.client.GetChanInfo(ctx, &lnrpc.ChanInfoRequest{ChanId: incomingChanId})
+	if err != nil {
+		logger.Logger.WithField("channel_id", incomingChanId).WithError(err).Error("GetChanInfo failed")
+		return nil, err
+	}
+	// the last hop forwards to us with its own policy of the incoming channel
+	lastHopPubkey, lastHopPolicy := channelEdge.Node1Pub, channelEdge.Node1Policy
+	if lastHopPubkey == svc.GetPubkey() {
+		lastHopPubkey, lastHopPolicy = channelEdge.Node2Pub, channelEdge.Node2Policy
+	}
+	if lastHopPolicy == nil {
+		return nil, fmt.Errorf("no policy of the last hop for channel %d", incomingChanId)
+	}
+	if lastHopPubkey != circularPaymentRequest.LastHopPubkey {
+		return nil, fmt.Errorf("incoming channel %d is not with the last hop %s", incomingChanId, circularPaymentRequest.LastHopPubkey)
+	}
+
+	lastHopFeeMsat := lastHopPolicy.FeeBaseMsat + payReq.NumMsat*lastHopPolicy.FeeRateMilliMsat/1_000_000
+	queryRoutesResp, err := svc.client.QueryRoutes(ctx, &lnrpc.QueryRoutesRequest{
+		PubKey:            lastHopPubkey,
+		AmtMsat:           payReq.NumMsat + lastHopFeeMsat,
+		FinalCltvDelta:    int32(payReq.CltvExpiry) + int32(lastHopPolicy.TimeLockDelta),
+		OutgoingChanIds:   []uint64{outgoingChanId},
+		UseMissionControl: true,
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("QueryRoutes failed")
+		return nil, fmt.Errorf("no route found to the last hop: %w", err)
+	}
+	if len(queryRoutesResp.Routes) == 0 {
+		return nil, errors.New("no route found to the last hop")
+	}
+
+	route := appendCircularLastHop(queryRoutesResp.Routes[0], incomingChanId, svc.GetPubkey(), payReq.NumMsat, lastHopFeeMsat, lastHopPolicy.TimeLockDelta, payReq.PaymentAddr)
+	if uint64(route.TotalFeesMsat) > circularPaymentRequest.MaxFeeMsat {
+		return nil, fmt.Errorf("route fee of %d msat exceeds the maximum of %d msat", route.TotalFeesMsat, circularPaymentRequest.MaxFeeMsat)
+	}
+
+	htlcAttempt, err := svc.client.SendToRoute(ctx, &routerrpc.SendToRouteRequest{
+		PaymentHash: paymentHash,
+		Route:       route,
+	})
+	if err != nil {
+		logger.Logger.WithField("bolt11", circularPaymentRequest.PaymentRequest).WithError(err).Error("SendToRouteV2 failed")
+		return nil, err
+	}
+
+	if htlcAttempt.Status != lnrpc.HTLCAttempt_SUCCEEDED {
+		failureReasonMessage := htlcAttempt.Status.String()
+		if htlcAttempt.Failure != nil {
+			failureReasonMessage = htlcAttempt.Failure.Code.String()
+		}
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11": circularPaymentRequest.PaymentRequest,
+			"reason": failureReasonMessage,
+		}).Error("Circular payment not successful")
+		return nil, errors.New(failureReasonMessage)
+	}
+
+	if len(htlcAttempt.Preimage) == 0 {
+		return nil, errors.New("no preimage in response")
+	}
+
+	return &lnclient.PayInvoiceResponse{
+		Preimage: hex.EncodeToString(htlcAttempt.Preimage),
+		FeeMsat:  uint64(route.TotalFeesMsat),
+	}, nil
+}
+
+// appendCircularLastHop turns a route to the last hop, which delivers amountMsat plus the
+// last hop fee with the final CLTV delta plus the last hop delta, into a route that
+// continues through the incoming channel to our node
+func appendCircularLastHop(route *lnrpc.Route, incomingChanId uint64, ourPubkey string, amountMsat int64, lastHopFeeMsat int64, lastHopTimeLockDelta uint32, paymentAddr []byte) *lnrpc.Route {
+	lastHop := route.Hops[len(route.Hops)-1]
+	finalExpiry := lastHop.Expiry - lastHopTimeLockDelta
+
+	lastHop.AmtToForwardMsat = amountMsat
+	lastHop.AmtToForward = amountMsat / 1000
+	lastHop.FeeMsat = lastHopFeeMsat
+	lastHop.Fee = lastHopFeeMsat / 1000
+	lastHop.Expiry = finalExpiry
+	lastHop.MppRecord = nil
+
+	route.Hops = append(route.Hops, &lnrpc.Hop{
+		ChanId:           incomingChanId,
+		AmtToForwardMsat: amountMsat,
+		AmtToForward:     amountMsat / 1000,
+		Expiry:           finalExpiry,
+		PubKey:           ourPubkey,
+		TlvPayload:       true,
+		MppRecord: &lnrpc.MPPRecord{
+			PaymentAddr:  paymentAddr,
+			TotalAmtMsat: amountMsat,
+		},
+	})
+	route.TotalFeesMsat += lastHopFeeMsat
+	route.TotalFees = route.TotalFeesMsat / 1000
+	return route
+}
+
 func (svc *LNDService) SendKeysend(amountMsat uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	destBytes, err := hex.DecodeString(destination)
 	if err != nil {
@@ -1510,6 +1804,13 @@ func (svc *LNDService) GetSupportedNIP47Methods() []string {
 		models.MAKE_HOLD_INVOICE_METHOD,
 		models.SETTLE_HOLD_INVOICE_METHOD,
 		models.CANCEL_HOLD_INVOICE_METHOD,
+		models.LIST_CHANNELS_METHOD,
+		models.OPEN_CHANNEL_METHOD,
+		models.CLOSE_CHANNEL_METHOD,
+		models.UPDATE_CHANNEL_FEES_METHOD,
+		models.GET_ONCHAIN_BALANCE_METHOD,
+		models.GET_NEW_ADDRESS_METHOD,
+		models.PAY_ONCHAIN_METHOD,
 	}
 }
 
@@ -1636,6 +1937,10 @@ func (svc *LNDService) MakeOffer(ctx context.Context, description string) (strin
 	return "", errors.New("not supported")
 }
 
+func (svc *LNDService) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (svc *LNDService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
 	resp, err := svc.client.GetTransactions(ctx, &lnrpc.GetTransactionsRequest{})
 	if err != nil {
@@ -1652,13 +1957,16 @@ func (svc *LNDService) ListOnchainTransactions(ctx context.Context) ([]lnclient.
 
 		amountSat := tx.Amount
 		txType := "incoming"
+		var feeSat uint64
 		if tx.Amount < 0 {
 			amountSat = -amountSat
 			txType = "outgoing"
+			feeSat = uint64(tx.TotalFees)
 		}
 
 		transactions = append(transactions, lnclient.OnchainTransaction{
 			AmountSat:        uint64(amountSat),
+			FeeSat:           feeSat,
 			CreatedAt:        uint64(tx.TimeStamp),
 			State:            state,
 			Type:             txType,
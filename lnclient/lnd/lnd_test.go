@@ -0,0 +1,63 @@
+package lnd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendCircularLastHop(t *testing.T) {
+	// route to the last hop through one intermediate node, delivering 1_000_000 msat plus
+	// the 1_100 msat last hop fee with a final CLTV delta of 18 + 40
+	route := &lnrpc.Route{
+		TotalTimeLock: 1098,
+		TotalFeesMsat: 2_000,
+		TotalAmtMsat:  1_003_100,
+		Hops: []*lnrpc.Hop{
+			{ChanId: 1, PubKey: "peer-a", AmtToForwardMsat: 1_001_100, FeeMsat: 2_000, Expiry: 1058},
+			{ChanId: 2, PubKey: "peer-b", AmtToForwardMsat: 1_001_100, Expiry: 1058, MppRecord: &lnrpc.MPPRecord{TotalAmtMsat: 1_001_100}},
+		},
+	}
+
+	route = appendCircularLastHop(route, 3, "our-pubkey", 1_000_000, 1_100, 40, []byte{1, 2, 3})
+
+	assert.Len(t, route.Hops, 3)
+	assert.Equal(t, int64(3_100), route.TotalFeesMsat)
+	assert.Equal(t, int64(1_003_100), route.TotalAmtMsat)
+	assert.Equal(t, uint32(1098), route.TotalTimeLock)
+
+	lastHop := route.Hops[1]
+	assert.Equal(t, int64(1_000_000), lastHop.AmtToForwardMsat)
+	assert.Equal(t, int64(1_100), lastHop.FeeMsat)
+	assert.Equal(t, uint32(1018), lastHop.Expiry)
+	assert.Nil(t, lastHop.MppRecord)
+
+	ourHop := route.Hops[2]
+	assert.Equal(t, uint64(3), ourHop.ChanId)
+	assert.Equal(t, "our-pubkey", ourHop.PubKey)
+	assert.Equal(t, int64(1_000_000), ourHop.AmtToForwardMsat)
+	assert.Equal(t, uint32(1018), ourHop.Expiry)
+	assert.Equal(t, []byte{1, 2, 3}, ourHop.MppRecord.PaymentAddr)
+	assert.Equal(t, int64(1_000_000), ourHop.MppRecord.TotalAmtMsat)
+}
+
+func TestPendingForwards(t *testing.T) {
+	pendingForwards := newPendingForwards()
+	now := time.Now()
+
+	info := &routerrpc.HtlcInfo{IncomingAmtMsat: 1_001_000, OutgoingAmtMsat: 1_000_000}
+	pendingForwards.add(htlcKey{channelId: 1, htlcId: 1}, info, now)
+	pendingForwards.add(htlcKey{channelId: 1, htlcId: 2}, info, now)
+
+	assert.Equal(t, info, pendingForwards.remove(htlcKey{channelId: 1, htlcId: 1}))
+	assert.Nil(t, pendingForwards.remove(htlcKey{channelId: 1, htlcId: 1}))
+
+	// forwards that were never resolved are dropped once they are older than the max age
+	pendingForwards.add(htlcKey{channelId: 2, htlcId: 1}, info, now.Add(pendingForwardMaxAge+time.Minute))
+	assert.Len(t, pendingForwards.forwards, 1)
+	assert.Nil(t, pendingForwards.remove(htlcKey{channelId: 1, htlcId: 2}))
+	assert.Equal(t, info, pendingForwards.remove(htlcKey{channelId: 2, htlcId: 1}))
+}
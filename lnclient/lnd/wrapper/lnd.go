@@ -117,6 +117,18 @@ func (wrapper *LNDWrapper) SendPayment(ctx context.Context, req *routerrpc.SendP
 	return wrapper.routerClient.SendPaymentV2(ctx, req, options...)
 }
 
+func (wrapper *LNDWrapper) SendToRoute(ctx context.Context, req *routerrpc.SendToRouteRequest, options ...grpc.CallOption) (*lnrpc.HTLCAttempt, error) {
+	return wrapper.routerClient.SendToRouteV2(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) QueryRoutes(ctx context.Context, req *lnrpc.QueryRoutesRequest, options ...grpc.CallOption) (*lnrpc.QueryRoutesResponse, error) {
+	return wrapper.client.QueryRoutes(ctx, req, options...)
+}
+
+func (wrapper *LNDWrapper) DecodePayReq(ctx context.Context, req *lnrpc.PayReqString, options ...grpc.CallOption) (*lnrpc.PayReq, error) {
+	return wrapper.client.DecodePayReq(ctx, req, options...)
+}
+
 func (wrapper *LNDWrapper) AddInvoice(ctx context.Context, req *lnrpc.Invoice, options ...grpc.CallOption) (*lnrpc.AddInvoiceResponse, error) {
 	return wrapper.client.AddInvoice(ctx, req, options...)
 }
@@ -145,6 +157,10 @@ func (wrapper *LNDWrapper) SubscribePayments(ctx context.Context, req *routerrpc
 	return wrapper.routerClient.TrackPayments(ctx, req, options...)
 }
 
+func (wrapper *LNDWrapper) SubscribeHtlcEvents(ctx context.Context, req *routerrpc.SubscribeHtlcEventsRequest, options ...grpc.CallOption) (routerrpc.Router_SubscribeHtlcEventsClient, error) {
+	return wrapper.routerClient.SubscribeHtlcEvents(ctx, req, options...)
+}
+
 func (wrapper *LNDWrapper) ListInvoices(ctx context.Context, req *lnrpc.ListInvoiceRequest, options ...grpc.CallOption) (*lnrpc.ListInvoiceResponse, error) {
 	return wrapper.client.ListInvoices(ctx, req, options...)
 }
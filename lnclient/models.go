@@ -3,6 +3,7 @@ package lnclient
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 // TLVRecord JSON tags are kept because values flow through the freeform
@@ -44,6 +45,7 @@ type Transaction struct {
 
 type OnchainTransaction struct {
 	AmountSat        uint64
+	FeeSat           uint64 // fee paid by the wallet, only set for outgoing transactions
 	CreatedAt        uint64
 	State            string
 	Type             string
@@ -60,6 +62,7 @@ type NodeConnectionInfo struct {
 type LNClient interface {
 	SendPaymentSync(payReq string, amountMsat *uint64) (*PayInvoiceResponse, error)
 	SendKeysend(amountMsat uint64, destination string, customRecords []TLVRecord, preimage string) (*PayKeysendResponse, error)
+	SendCircularPayment(ctx context.Context, circularPaymentRequest *CircularPaymentRequest) (*PayInvoiceResponse, error)
 	GetPubkey() string
 	GetInfo(ctx context.Context) (info *NodeInfo, err error)
 	MakeInvoice(ctx context.Context, amountMsat int64, description string, descriptionHash string, expiry int64, throughNodePubkey *string) (transaction *Transaction, err error)
@@ -78,6 +81,7 @@ type LNClient interface {
 	UpdateChannel(ctx context.Context, updateChannelRequest *UpdateChannelRequest) error
 	DisconnectPeer(ctx context.Context, peerId string) error
 	MakeOffer(ctx context.Context, description string) (string, error)
+	PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*PayOfferResponse, error)
 	GetNewOnchainAddress(ctx context.Context) (string, error)
 	ResetRouter(key string) error
 	GetOnchainBalance(ctx context.Context) (*OnchainBalanceResponse, error)
@@ -151,6 +155,16 @@ type UpdateChannelRequest struct {
 	MaxDustHtlcExposureFromFeeRateMultiplier uint64
 }
 
+// CircularPaymentRequest pays an invoice of our own node out through OutgoingChannelId
+// and back in through IncomingChannelId with LastHopPubkey, to move liquidity between channels
+type CircularPaymentRequest struct {
+	PaymentRequest    string
+	OutgoingChannelId string
+	IncomingChannelId string
+	LastHopPubkey     string
+	MaxFeeMsat        uint64
+}
+
 type PendingBalanceDetails struct {
 	ChannelId     string
 	NodeId        string
@@ -214,6 +228,16 @@ type PaymentFailedEventProperties struct {
 type PaymentForwardedEventProperties struct {
 	TotalFeeEarnedMsat          uint64
 	OutboundAmountForwardedMsat uint64
+	InboundAmountMsat           uint64
+	// channel ids as returned by ListChannels, empty if unknown
+	IncomingChannelId  string
+	OutgoingChannelId  string
+	IncomingPeerPubkey string
+	OutgoingPeerPubkey string
+	// time the forward was settled or failed, nil if it was just now
+	ResolvedAt *time.Time
+	// only set for "nwc_payment_forward_failed" events
+	FailureReason string
 }
 
 type CustomNodeCommandArgDef struct {
@@ -262,3 +286,21 @@ func NewHoldInvoiceCanceledError() error {
 func (err *holdInvoiceCanceledError) Error() string {
 	return "Hold invoice canceled"
 }
+
+// PaymentPendingError is returned when the result of a payment is not known in time.
+// The payment may still succeed, so it must not be treated as failed.
+type PaymentPendingError struct {
+	PaymentHash string // empty if not known yet
+	// PaymentId is the backend's id of the payment. It is published in the
+	// offer metadata of payment events, so the payment can be matched while
+	// its payment hash is not known.
+	PaymentId string
+}
+
+func NewPaymentPendingError(paymentHash string, paymentId string) error {
+	return &PaymentPendingError{PaymentHash: paymentHash, PaymentId: paymentId}
+}
+
+func (err *PaymentPendingError) Error() string {
+	return "payment is still in flight"
+}
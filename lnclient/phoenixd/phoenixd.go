@@ -13,10 +13,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gorilla/websocket"
 	decodepay "github.com/nbd-wtf/ln-decodepay"
 
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/notifications"
 
 	"github.com/sirupsen/logrus"
 )
@@ -70,22 +74,30 @@ type BalanceResponse struct {
 	FeeCreditSat int64 `json:"feeCreditSat"`
 }
 
+// WebsocketEvent is a notification sent by phoenixd over its websocket
+type WebsocketEvent struct {
+	Type        string `json:"type"`
+	AmountSat   int64  `json:"amountSat"`
+	PaymentHash string `json:"paymentHash"`
+}
+
 type PhoenixService struct {
-	Address       string
-	Authorization string
-	pubkey        string
-	nodeInfo      *lnclient.NodeInfo
-	ctx           context.Context
+	Address        string
+	Authorization  string
+	pubkey         string
+	nodeInfo       *lnclient.NodeInfo
+	ctx            context.Context
+	eventPublisher events.EventPublisher
 }
 
-func NewPhoenixService(ctx context.Context, address string, authorization string) (result lnclient.LNClient, err error) {
+func NewPhoenixService(ctx context.Context, eventPublisher events.EventPublisher, address string, authorization string) (result lnclient.LNClient, err error) {
 	authorizationBase64 := b64.StdEncoding.EncodeToString([]byte(":" + authorization))
 	// some environments (e.g. in a cloud environment like render.com) can only get the address and the port but not the protocol
 	// in those cases we default to http for local requests
 	if !strings.HasPrefix(address, "http") {
 		address = "http://" + address
 	}
-	phoenixService := &PhoenixService{ctx: ctx, Address: address, Authorization: authorizationBase64}
+	phoenixService := &PhoenixService{ctx: ctx, eventPublisher: eventPublisher, Address: address, Authorization: authorizationBase64}
 
 	info, err := fetchNodeInfo(ctx, phoenixService)
 	if err != nil {
@@ -94,9 +106,96 @@ func NewPhoenixService(ctx context.Context, address string, authorization string
 	phoenixService.nodeInfo = info
 	phoenixService.pubkey = info.Pubkey
 
+	go phoenixService.subscribePayments(ctx)
+
 	return phoenixService, nil
 }
 
+// subscribePayments listens to the phoenixd websocket and publishes
+// incoming payments, reconnecting until the context is cancelled
+func (svc *PhoenixService) subscribePayments(ctx context.Context) {
+	// http -> ws, https -> wss
+	websocketUrl := "ws" + strings.TrimPrefix(svc.Address, "http") + "/websocket"
+	header := http.Header{}
+	header.Add("Authorization", "Basic "+svc.Authorization)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, websocketUrl, header)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to connect to phoenixd websocket")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Second):
+				continue
+			}
+		}
+
+		logger.Logger.Info("Subscribed to phoenixd websocket")
+		svc.readWebsocketEvents(ctx, conn)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func (svc *PhoenixService) readWebsocketEvents(ctx context.Context, conn *websocket.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// unblock ReadMessage on shutdown
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Logger.WithError(err).Error("Failed to read from phoenixd websocket")
+			}
+			return
+		}
+
+		var websocketEvent WebsocketEvent
+		if err := json.Unmarshal(message, &websocketEvent); err != nil {
+			logger.Logger.WithError(err).WithField("message", string(message)).Error("Failed to decode phoenixd websocket event")
+			continue
+		}
+		if websocketEvent.Type != "payment_received" {
+			continue
+		}
+
+		transaction, err := svc.lookupIncomingPayment(ctx, websocketEvent.PaymentHash)
+		if err != nil {
+			logger.Logger.WithError(err).WithField("paymentHash", websocketEvent.PaymentHash).Error("Failed to lookup received phoenixd payment")
+			continue
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"paymentHash": websocketEvent.PaymentHash,
+			"amountSat":   websocketEvent.AmountSat,
+		}).Info("Received phoenixd payment")
+
+		svc.eventPublisher.Publish(&events.Event{
+			Event:      "nwc_lnclient_payment_received",
+			Properties: transaction,
+		})
+	}
+}
+
 func (svc *PhoenixService) GetBalances(ctx context.Context, includeInactiveChannels bool) (*lnclient.BalancesResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, svc.Address+"/getbalance", nil)
 	if err != nil {
@@ -376,12 +475,26 @@ func (svc *PhoenixService) SendPaymentSync(payReq string, amountMsat *uint64) (*
 		return nil, err
 	}
 
+	transaction, err := payResponseToTransaction(payReq, &payRes)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("paymentHash", payRes.PaymentHash).Error("Failed to convert phoenixd payment to transaction")
+	} else {
+		svc.eventPublisher.Publish(&events.Event{
+			Event:      "nwc_lnclient_payment_sent",
+			Properties: transaction,
+		})
+	}
+
 	return &lnclient.PayInvoiceResponse{
 		Preimage: payRes.PaymentPreimage,
 		FeeMsat:  uint64(payRes.RoutingFeeSat) * 1000,
 	}, nil
 }
 
+func (svc *PhoenixService) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (svc *PhoenixService) SendKeysend(amountMsat uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	return nil, errors.New("not implemented")
 }
@@ -494,7 +607,7 @@ func (svc *PhoenixService) GetSupportedNIP47Methods() []string {
 }
 
 func (svc *PhoenixService) GetSupportedNIP47NotificationTypes() []string {
-	return []string{}
+	return []string{notifications.PAYMENT_RECEIVED_NOTIFICATION, notifications.PAYMENT_SENT_NOTIFICATION}
 }
 
 func (svc *PhoenixService) GetPubkey() string {
@@ -575,6 +688,32 @@ func outgoingPaymentToTransaction(payment *OutgoingPaymentResponse) (*lnclient.T
 	}, nil
 }
 
+// payResponseToTransaction converts the response of a successful
+// /payinvoice request to an lnclient.Transaction.
+func payResponseToTransaction(payReq string, payRes *PayResponse) (*lnclient.Transaction, error) {
+	paymentRequest, err := decodepay.Decodepay(payReq)
+	if err != nil {
+		return nil, fmt.Errorf("decode phoenixd payment bolt11: %w", err)
+	}
+
+	expiresAt := time.UnixMilli(int64(paymentRequest.CreatedAt) * 1000).Add(time.Duration(paymentRequest.Expiry) * time.Second).Unix()
+	settledAt := time.Now().Unix()
+
+	return &lnclient.Transaction{
+		Type:            constants.TRANSACTION_TYPE_OUTGOING,
+		Invoice:         payReq,
+		Preimage:        payRes.PaymentPreimage,
+		PaymentHash:     payRes.PaymentHash,
+		AmountMsat:      paymentRequest.MSatoshi,
+		FeesPaidMsat:    payRes.RoutingFeeSat * 1000,
+		CreatedAt:       int64(paymentRequest.CreatedAt),
+		Description:     paymentRequest.Description,
+		SettledAt:       &settledAt,
+		ExpiresAt:       &expiresAt,
+		DescriptionHash: paymentRequest.DescriptionHash,
+	}, nil
+}
+
 func (svc *PhoenixService) GetCustomNodeCommandDefinitions() []lnclient.CustomNodeCommandDef {
 	return nil
 }
@@ -587,6 +726,10 @@ func (svc *PhoenixService) MakeOffer(ctx context.Context, description string) (s
 	return "", errors.New("not supported")
 }
 
+func (svc *PhoenixService) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
+	return nil, errors.New("not supported")
+}
+
 func (svc *PhoenixService) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
 	return nil, errors.ErrUnsupported
 }
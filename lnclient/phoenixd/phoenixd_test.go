@@ -0,0 +1,93 @@
+package phoenixd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func newMockPhoenixd(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/getinfo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&InfoResponse{NodeId: "nodeid"})
+	})
+	mux.HandleFunc("/payments/incoming/"+tests.MockPaymentHash, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&InvoiceResponse{
+			PaymentHash: tests.MockPaymentHash,
+			Preimage:    "preimage",
+			Invoice:     tests.MockInvoice,
+			IsPaid:      true,
+			ReceivedSat: 123,
+			CompletedAt: 1_700_000_000_000,
+			CreatedAt:   1_700_000_000_000,
+		})
+	})
+	mux.HandleFunc("/payinvoice", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&PayResponse{
+			PaymentHash:     tests.MockPaymentHash,
+			PaymentPreimage: "preimage",
+			RoutingFeeSat:   2,
+		})
+	})
+	mux.HandleFunc("/websocket", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Basic OnBhc3N3b3Jk", r.Header.Get("Authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		err = conn.WriteJSON(&WebsocketEvent{
+			Type:        "payment_received",
+			AmountSat:   123,
+			PaymentHash: tests.MockPaymentHash,
+		})
+		require.NoError(t, err)
+		// keep the connection open until the client disconnects
+		conn.ReadMessage()
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestPhoenixService_PaymentNotifications(t *testing.T) {
+	server := newMockPhoenixd(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventPublisher := events.NewEventPublisher()
+	mockEventConsumer := tests.NewMockEventConsumer()
+	eventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	phoenixService, err := NewPhoenixService(ctx, eventPublisher, server.URL, "password")
+	require.NoError(t, err)
+
+	consumedEvents := mockEventConsumer.WaitForConsumedEvents(1)
+	require.Equal(t, 1, len(consumedEvents))
+	assert.Equal(t, "nwc_lnclient_payment_received", consumedEvents[0].Event)
+	receivedTransaction := consumedEvents[0].Properties.(*lnclient.Transaction)
+	assert.Equal(t, tests.MockPaymentHash, receivedTransaction.PaymentHash)
+	assert.Equal(t, "preimage", receivedTransaction.Preimage)
+	assert.NotNil(t, receivedTransaction.SettledAt)
+
+	_, err = phoenixService.SendPaymentSync(tests.MockInvoice, nil)
+	require.NoError(t, err)
+
+	consumedEvents = mockEventConsumer.WaitForConsumedEvents(2)
+	require.Equal(t, 2, len(consumedEvents))
+	assert.Equal(t, "nwc_lnclient_payment_sent", consumedEvents[1].Event)
+	sentTransaction := consumedEvents[1].Properties.(*lnclient.Transaction)
+	assert.Equal(t, tests.MockPaymentHash, sentTransaction.PaymentHash)
+	assert.Equal(t, "preimage", sentTransaction.Preimage)
+	assert.Equal(t, int64(123_000), sentTransaction.AmountMsat)
+	assert.Equal(t, int64(2_000), sentTransaction.FeesPaidMsat)
+}
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/logger"
+)
+
+const namespace = "albyhub"
+
+// metrics are always recorded but only exposed when the metrics server is enabled
+var (
+	registry = prometheus.NewRegistry()
+
+	nip47Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "nip47",
+		Name:      "requests_total",
+		Help:      "NIP-47 requests by method, app and result (the error code, or OK). Multi-pay requests count each payment.",
+	}, []string{"method", "app_id", "result"})
+
+	nip47ResponsePublishDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "nip47",
+		Name:      "response_publish_duration_seconds",
+		Help:      "Time taken to publish a NIP-47 response to the relays.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	nip47ResponsePublishFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "nip47",
+		Name:      "response_publish_failures_total",
+		Help:      "NIP-47 responses which could not be published to any relay.",
+	})
+
+	payments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "payments",
+		Name:      "total",
+		Help:      "Lightning payments by direction (incoming, outgoing) and result (settled, failed).",
+	}, []string{"direction", "result"})
+
+	paymentAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "payments",
+		Name:      "amount_msat_total",
+		Help:      "Amount of settled lightning payments by direction.",
+	}, []string{"direction"})
+
+	paymentFees = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "payments",
+		Name:      "fees_msat_total",
+		Help:      "Routing fees paid for settled outgoing lightning payments.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		nip47Requests,
+		nip47ResponsePublishDuration,
+		nip47ResponsePublishFailures,
+		payments,
+		paymentAmount,
+		paymentFees,
+	)
+}
+
+// Register adds a collector which is evaluated on every scrape (e.g. for node balances)
+func Register(collector prometheus.Collector) error {
+	return registry.Register(collector)
+}
+
+func RecordNip47Request(method string, appId uint, errorCode string) {
+	result := "OK"
+	if errorCode != "" {
+		result = errorCode
+	}
+	if method == "" {
+		method = "unknown"
+	}
+	nip47Requests.WithLabelValues(method, strconv.FormatUint(uint64(appId), 10), result).Inc()
+}
+
+func RecordNip47ResponsePublish(duration time.Duration, published bool) {
+	nip47ResponsePublishDuration.Observe(duration.Seconds())
+	if !published {
+		nip47ResponsePublishFailures.Inc()
+	}
+}
+
+// StartServer serves the metrics on /metrics on a separate listener,
+// so that they are not exposed together with the API
+func StartServer(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		err := server.Shutdown(context.Background())
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to shut down metrics server")
+		}
+	}()
+
+	go func() {
+		logger.Logger.WithField("addr", addr).Info("Starting metrics server")
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Logger.WithError(err).Error("Metrics server failed")
+		}
+	}()
+}
+
+type paymentsConsumer struct {
+}
+
+// NewPaymentsConsumer counts settled and failed payments published by the transactions service
+func NewPaymentsConsumer() events.EventSubscriber {
+	return &paymentsConsumer{}
+}
+
+func (consumer *paymentsConsumer) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
+	var result string
+	switch event.Event {
+	case "nwc_payment_sent", "nwc_payment_received":
+		result = "settled"
+	case "nwc_payment_failed":
+		result = "failed"
+	default:
+		return
+	}
+
+	transaction, ok := event.Properties.(*db.Transaction)
+	if !ok {
+		logger.Logger.WithField("event", event).Error("Failed to cast event")
+		return
+	}
+
+	payments.WithLabelValues(transaction.Type, result).Inc()
+	if result != "settled" {
+		return
+	}
+	paymentAmount.WithLabelValues(transaction.Type).Add(float64(transaction.AmountMsat))
+	if transaction.Type == constants.TRANSACTION_TYPE_OUTGOING {
+		paymentFees.Add(float64(transaction.FeeMsat))
+	}
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+)
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	metric := &dto.Metric{}
+	require.NoError(t, counter.Write(metric))
+	return metric.GetCounter().GetValue()
+}
+
+func TestPaymentsConsumer(t *testing.T) {
+	consumer := NewPaymentsConsumer()
+	settledBefore := counterValue(t, payments.WithLabelValues(constants.TRANSACTION_TYPE_OUTGOING, "settled"))
+	failedBefore := counterValue(t, payments.WithLabelValues(constants.TRANSACTION_TYPE_OUTGOING, "failed"))
+	feesBefore := counterValue(t, paymentFees)
+
+	consumer.ConsumeEvent(context.Background(), &events.Event{
+		Event: "nwc_payment_sent",
+		Properties: &db.Transaction{
+			Type:       constants.TRANSACTION_TYPE_OUTGOING,
+			AmountMsat: 100_000,
+			FeeMsat:    1_000,
+		},
+	}, map[string]interface{}{})
+	consumer.ConsumeEvent(context.Background(), &events.Event{
+		Event: "nwc_payment_failed",
+		Properties: &db.Transaction{
+			Type:       constants.TRANSACTION_TYPE_OUTGOING,
+			AmountMsat: 100_000,
+		},
+	}, map[string]interface{}{})
+	consumer.ConsumeEvent(context.Background(), &events.Event{
+		Event: "nwc_app_created",
+	}, map[string]interface{}{})
+
+	assert.Equal(t, settledBefore+1, counterValue(t, payments.WithLabelValues(constants.TRANSACTION_TYPE_OUTGOING, "settled")))
+	assert.Equal(t, failedBefore+1, counterValue(t, payments.WithLabelValues(constants.TRANSACTION_TYPE_OUTGOING, "failed")))
+	assert.Equal(t, feesBefore+1_000, counterValue(t, paymentFees))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	RecordNip47Request("get_balance", 1, "")
+	RecordNip47Request("pay_invoice", 1, constants.ERROR_INSUFFICIENT_BALANCE)
+	RecordNip47ResponsePublish(100*time.Millisecond, false)
+
+	recorder := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	assert.Contains(t, body, `albyhub_nip47_requests_total{app_id="1",method="get_balance",result="OK"} 1`)
+	assert.Contains(t, body, `albyhub_nip47_requests_total{app_id="1",method="pay_invoice",result="INSUFFICIENT_BALANCE"} 1`)
+	assert.Contains(t, body, "albyhub_nip47_response_publish_failures_total 1")
+	assert.Contains(t, body, "albyhub_nip47_response_publish_duration_seconds_count 1")
+}
@@ -5,7 +5,9 @@ import (
 
 	"github.com/getAlby/go-nostr/nip04"
 	"github.com/getAlby/go-nostr/nip44"
+
 	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/service/keys"
 )
 
 const (
@@ -16,41 +18,63 @@ const (
 type Nip47Cipher struct {
 	encryption      string
 	pubkey          string
-	privkey         string
 	sharedSecret    []byte
 	conversationKey [32]byte
+	// set when the signer cannot compute shared secrets (e.g. a NIP-46 bunker)
+	signer keys.Signer
 }
 
 func NewNip47Cipher(encryption, pubkey, privkey string) (*Nip47Cipher, error) {
+	signer, err := keys.NewSoftwareSigner(privkey)
+	if err != nil {
+		return nil, err
+	}
+	return NewNip47CipherWithSigner(encryption, pubkey, signer)
+}
+
+// NewNip47CipherWithSigner creates a cipher for a key which may not be held in memory.
+// The shared secret is derived through the signer if it supports ECDH,
+// otherwise each message is encrypted and decrypted by the signer.
+func NewNip47CipherWithSigner(encryption, pubkey string, signer keys.Signer) (*Nip47Cipher, error) {
 	_, err := isEncryptionSupported(encryption)
 	if err != nil {
 		return nil, err
 	}
 
+	ecdhSigner, ok := signer.(keys.ECDHSigner)
+	if !ok {
+		return &Nip47Cipher{
+			encryption: encryption,
+			pubkey:     pubkey,
+			signer:     signer,
+		}, nil
+	}
+
+	sharedX, err := ecdhSigner.ECDH(pubkey)
+	if err != nil {
+		return nil, err
+	}
+
 	var ss []byte
 	var ck [32]byte
 	if encryption == constants.ENCRYPTION_TYPE_NIP04 {
-		ss, err = nip04.ComputeSharedSecret(pubkey, privkey)
-		if err != nil {
-			return nil, err
-		}
+		ss = sharedX
 	} else {
-		ck, err = nip44.GenerateConversationKey(pubkey, privkey)
-		if err != nil {
-			return nil, err
-		}
+		ck = keys.NIP44ConversationKey(sharedX)
 	}
 
 	return &Nip47Cipher{
 		encryption:      encryption,
 		pubkey:          pubkey,
-		privkey:         privkey,
 		sharedSecret:    ss,
 		conversationKey: ck,
 	}, nil
 }
 
 func (c *Nip47Cipher) Encrypt(message string) (msg string, err error) {
+	if c.signer != nil {
+		return c.signer.Encrypt(c.encryption, c.pubkey, message)
+	}
 	if c.encryption == constants.ENCRYPTION_TYPE_NIP04 {
 		msg, err = nip04.Encrypt(message, c.sharedSecret)
 		if err != nil {
@@ -66,6 +90,9 @@ func (c *Nip47Cipher) Encrypt(message string) (msg string, err error) {
 }
 
 func (c *Nip47Cipher) Decrypt(content string) (payload string, err error) {
+	if c.signer != nil {
+		return c.signer.Decrypt(c.encryption, c.pubkey, content)
+	}
 	if c.encryption == constants.ENCRYPTION_TYPE_NIP04 {
 		payload, err = nip04.Decrypt(content, c.sharedSecret)
 		if err != nil {
@@ -5,7 +5,10 @@ import (
 	"testing"
 
 	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/go-nostr/nip04"
+	"github.com/getAlby/go-nostr/nip44"
 	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/service/keys"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,3 +49,66 @@ func doTestCipher_UnsupportedEncrptions(t *testing.T, encryption string) {
 	assert.Error(t, err)
 	assert.Equal(t, fmt.Sprintf("invalid encryption: %s", encryption), err.Error())
 }
+
+func TestCipher_WithSigner(t *testing.T) {
+	walletSecretKey := nostr.GeneratePrivateKey()
+	walletSigner, err := keys.NewSoftwareSigner(walletSecretKey)
+	assert.NoError(t, err)
+
+	clientSecretKey := nostr.GeneratePrivateKey()
+	clientPubkey, err := nostr.GetPublicKey(clientSecretKey)
+	assert.NoError(t, err)
+
+	payload := "test payload"
+
+	// NIP-04 shared secret matches the one computed from the secret key
+	nip04Cipher, err := NewNip47CipherWithSigner(constants.ENCRYPTION_TYPE_NIP04, clientPubkey, walletSigner)
+	assert.NoError(t, err)
+	sharedSecret, err := nip04.ComputeSharedSecret(walletSigner.GetPublicKey(), clientSecretKey)
+	assert.NoError(t, err)
+	msg, err := nip04.Encrypt(payload, sharedSecret)
+	assert.NoError(t, err)
+	decrypted, err := nip04Cipher.Decrypt(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, decrypted)
+
+	// NIP-44 conversation key matches the one computed from the secret key
+	nip44Cipher, err := NewNip47CipherWithSigner(constants.ENCRYPTION_TYPE_NIP44_V2, clientPubkey, walletSigner)
+	assert.NoError(t, err)
+	conversationKey, err := nip44.GenerateConversationKey(walletSigner.GetPublicKey(), clientSecretKey)
+	assert.NoError(t, err)
+	msg, err = nip44.Encrypt(payload, conversationKey)
+	assert.NoError(t, err)
+	decrypted, err = nip44Cipher.Decrypt(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, decrypted)
+}
+
+// encryptOnlySigner hides ECDH from the wrapped signer, like a NIP-46 bunker
+type encryptOnlySigner struct {
+	keys.Signer
+}
+
+func TestCipher_WithEncryptOnlySigner(t *testing.T) {
+	walletSigner, err := keys.NewSoftwareSigner(nostr.GeneratePrivateKey())
+	assert.NoError(t, err)
+
+	clientSecretKey := nostr.GeneratePrivateKey()
+	clientPubkey, err := nostr.GetPublicKey(clientSecretKey)
+	assert.NoError(t, err)
+
+	nip44Cipher, err := NewNip47CipherWithSigner(constants.ENCRYPTION_TYPE_NIP44_V2, clientPubkey, &encryptOnlySigner{walletSigner})
+	assert.NoError(t, err)
+
+	msg, err := nip44Cipher.Encrypt("test payload")
+	assert.NoError(t, err)
+	conversationKey, err := nip44.GenerateConversationKey(walletSigner.GetPublicKey(), clientSecretKey)
+	assert.NoError(t, err)
+	decrypted, err := nip44.Decrypt(msg, conversationKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "test payload", decrypted)
+
+	decrypted, err = nip44Cipher.Decrypt(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "test payload", decrypted)
+}
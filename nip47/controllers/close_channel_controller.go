@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+type closeChannelParams struct {
+	Id     string `json:"id"`
+	NodeId string `json:"node_id"`
+	Force  bool   `json:"force"`
+}
+
+type closeChannelResponse struct{}
+
+func (controller *nip47Controller) HandleCloseChannelEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, publishResponse publishFunc) {
+	closeChannelParams := &closeChannelParams{}
+	resp := decodeRequest(nip47Request, closeChannelParams)
+	if resp != nil {
+		publishResponse(resp, nostr.Tags{})
+		return
+	}
+
+	if closeChannelParams.Id == "" || closeChannelParams.NodeId == "" {
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error: &models.Error{
+				Code:    constants.ERROR_BAD_REQUEST,
+				Message: "id and node_id are required",
+			},
+		}, nostr.Tags{})
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"channel_id":       closeChannelParams.Id,
+		"node_id":          closeChannelParams.NodeId,
+		"force":            closeChannelParams.Force,
+	}).Info("Closing channel")
+
+	err := controller.lnClient.CloseChannel(ctx, &lnclient.CloseChannelRequest{
+		ChannelId: closeChannelParams.Id,
+		NodeId:    closeChannelParams.NodeId,
+		Force:     closeChannelParams.Force,
+	})
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to close channel")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     closeChannelResponse{},
+	}, nostr.Tags{})
+}
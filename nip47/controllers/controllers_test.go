@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/audit"
 	"github.com/getAlby/hub/nip47/permissions"
 	"github.com/getAlby/hub/tests"
 	"github.com/getAlby/hub/transactions"
@@ -11,5 +12,6 @@ func NewTestNip47Controller(svc *tests.TestService) *nip47Controller {
 	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
 	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
 	albyOAuthSvc := alby.NewAlbyOAuthService(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
-	return NewNip47Controller(svc.LNClient, svc.DB, svc.EventPublisher, permissionsSvc, transactionsSvc, svc.AppsService, albyOAuthSvc)
+	auditSvc := audit.NewAuditService(svc.DB, svc.Cfg.GetEnv().Workdir)
+	return NewNip47Controller(svc.LNClient, svc.DB, svc.EventPublisher, permissionsSvc, transactionsSvc, svc.AppsService, albyOAuthSvc, auditSvc)
 }
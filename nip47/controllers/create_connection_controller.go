@@ -115,6 +115,18 @@ func (controller *nip47Controller) HandleCreateConnectionEvent(ctx context.Conte
 		return
 	}
 
+	if slices.Contains(scopes, constants.NODE_ADMIN_SCOPE) {
+		// node admin connections must be created by the owner with the unlock password
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error: &models.Error{
+				Code:    constants.ERROR_RESTRICTED,
+				Message: "Connections with the node_admin scope cannot be created by another connection",
+			},
+		}, nostr.Tags{})
+		return
+	}
+
 	supportedNotificationTypes := controller.lnClient.GetSupportedNIP47NotificationTypes()
 	if len(params.NotificationTypes) > 0 {
 		if slices.ContainsFunc(params.NotificationTypes, func(method string) bool {
@@ -133,6 +145,7 @@ func (controller *nip47Controller) HandleCreateConnectionEvent(ctx context.Conte
 	}
 
 	app, _, err := controller.appsService.CreateApp(params.Name, params.Pubkey, maxAmountSat, params.BudgetRenewal, expiresAt, scopes, params.Isolated, params.Metadata)
+	controller.auditService.Record(ctx, "create_app", params, err)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"request_event_id": requestEventId,
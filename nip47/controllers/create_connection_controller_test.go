@@ -83,6 +83,12 @@ func TestHandleCreateConnectionEvent(t *testing.T) {
 	assert.True(t, app.Isolated)
 	assert.Equal(t, 100_000, permissions[1].MaxAmountSat)
 	assert.Equal(t, constants.BUDGET_RENEWAL_MONTHLY, permissions[1].BudgetRenewal)
+
+	auditEvent := db.AuditEvent{}
+	err = svc.DB.First(&auditEvent).Error
+	assert.NoError(t, err)
+	assert.Equal(t, "create_app", auditEvent.Action)
+	assert.Equal(t, constants.AUDIT_RESULT_SUCCESS, auditEvent.Result)
 }
 
 func TestHandleCreateConnectionEvent_IsolatedUnsupportedBackendType(t *testing.T) {
@@ -12,14 +12,32 @@ import (
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/transactions"
 	"github.com/sirupsen/logrus"
 )
 
 type getBudgetResponse struct {
-	UsedBudget    uint64  `json:"used_budget"`
-	TotalBudget   uint64  `json:"total_budget"`
-	RenewsAt      *uint64 `json:"renews_at,omitempty"`
-	RenewalPeriod string  `json:"renewal_period"`
+	UsedBudget     uint64                  `json:"used_budget"`
+	TotalBudget    uint64                  `json:"total_budget"`
+	RenewsAt       *uint64                 `json:"renews_at,omitempty"`
+	RenewalPeriod  string                  `json:"renewal_period"`
+	SpendingPolicy *spendingPolicyResponse `json:"spending_policy,omitempty"`
+}
+
+// returned when the app has a spending policy but no budget
+type getSpendingPolicyOnlyResponse struct {
+	SpendingPolicy *spendingPolicyResponse `json:"spending_policy"`
+}
+
+type spendingPolicyResponse struct {
+	MaxAmountPerPayment  uint64   `json:"max_amount_per_payment,omitempty"`
+	MaxPaymentsPerMinute uint     `json:"max_payments_per_minute,omitempty"`
+	MaxPaymentsPerHour   uint     `json:"max_payments_per_hour,omitempty"`
+	AllowedDestinations  []string `json:"allowed_destinations,omitempty"`
+	BlockedDestinations  []string `json:"blocked_destinations,omitempty"`
+	RequireDescription   bool     `json:"require_description"`
+	ApprovalRequired     bool     `json:"approval_required"`
+	ApprovalThreshold    uint64   `json:"approval_threshold,omitempty"`
 }
 
 func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc) {
@@ -29,11 +47,11 @@ func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip
 	}).Debug("Getting budget")
 
 	appPermission := db.AppPermission{}
-	result := controller.db.Where("app_id = ? AND scope = ?", app.ID, constants.PAY_INVOICE_SCOPE).First(&appPermission)
+	result := controller.db.Where("app_id = ? AND scope IN ?", app.ID, []string{constants.PAY_INVOICE_SCOPE, constants.PAY_ONCHAIN_SCOPE}).First(&appPermission)
 	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		logger.Logger.WithFields(logrus.Fields{
 			"request_event_id": requestEventId,
-		}).WithError(result.Error).Error("Failed to fetch pay_invoice permission")
+		}).WithError(result.Error).Error("Failed to fetch payment permission")
 		publishResponse(&models.Response{
 			ResultType: nip47Request.Method,
 			Error:      mapNip47Error(result.Error),
@@ -41,14 +59,32 @@ func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip
 		return
 	}
 
+	spendingPolicy, err := controller.getSpendingPolicy(app.ID)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to fetch spending policy")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
 	// On ErrRecordNotFound appPermission stays zero-valued and maxAmountSat == 0,
 	// which returns the same empty "no budget" response as a permission with no
 	// budget set.
 	maxAmountSat := appPermission.MaxAmountSat
 	if maxAmountSat == 0 {
+		var result interface{} = struct{}{}
+		if spendingPolicy != nil {
+			result = &getSpendingPolicyOnlyResponse{
+				SpendingPolicy: spendingPolicy,
+			}
+		}
 		publishResponse(&models.Response{
 			ResultType: nip47Request.Method,
-			Result:     struct{}{},
+			Result:     result,
 		}, nostr.Tags{})
 		return
 	}
@@ -65,11 +101,24 @@ func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip
 		return
 	}
 
+	renewsAt, err := queries.GetBudgetRenewsAt(controller.db, &appPermission)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to fetch budget renewal time")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
 	responsePayload := &getBudgetResponse{
-		TotalBudget:   uint64(maxAmountSat * 1000),
-		UsedBudget:    usedBudgetMsat,
-		RenewalPeriod: appPermission.BudgetRenewal,
-		RenewsAt:      queries.GetBudgetRenewsAt(appPermission.BudgetRenewal),
+		TotalBudget:    uint64(maxAmountSat * 1000),
+		UsedBudget:     usedBudgetMsat,
+		RenewalPeriod:  appPermission.BudgetRenewal,
+		RenewsAt:       renewsAt,
+		SpendingPolicy: spendingPolicy,
 	}
 
 	publishResponse(&models.Response{
@@ -77,3 +126,36 @@ func (controller *nip47Controller) HandleGetBudgetEvent(ctx context.Context, nip
 		Result:     responsePayload,
 	}, nostr.Tags{})
 }
+
+func (controller *nip47Controller) getSpendingPolicy(appId uint) (*spendingPolicyResponse, error) {
+	spendingPolicy := db.AppSpendingPolicy{}
+	result := controller.db.Limit(1).Find(&spendingPolicy, &db.AppSpendingPolicy{
+		AppId: appId,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	allowedDestinations, err := transactions.ParseSpendingPolicyDestinations(spendingPolicy.AllowedDestinations)
+	if err != nil {
+		return nil, err
+	}
+	blockedDestinations, err := transactions.ParseSpendingPolicyDestinations(spendingPolicy.BlockedDestinations)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spendingPolicyResponse{
+		MaxAmountPerPayment:  spendingPolicy.MaxAmountPerPaymentSat * 1000,
+		MaxPaymentsPerMinute: spendingPolicy.MaxPaymentsPerMinute,
+		MaxPaymentsPerHour:   spendingPolicy.MaxPaymentsPerHour,
+		AllowedDestinations:  allowedDestinations,
+		BlockedDestinations:  blockedDestinations,
+		RequireDescription:   spendingPolicy.RequireDescription,
+		ApprovalRequired:     spendingPolicy.ApprovalRequired,
+		ApprovalThreshold:    spendingPolicy.ApprovalThresholdSat * 1000,
+	}, nil
+}
@@ -271,3 +271,111 @@ func TestHandleGetBudgetEvent_NoPayInvoicePermission(t *testing.T) {
 	assert.Equal(t, struct{}{}, publishedResponse.Result)
 	assert.Nil(t, publishedResponse.Error)
 }
+
+func TestHandleGetBudgetEvent_SpendingPolicy(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetBudgetJson), nip47Request)
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  400,
+		BudgetRenewal: constants.BUDGET_RENEWAL_NEVER,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	spendingPolicy := &db.AppSpendingPolicy{
+		AppId:                  app.ID,
+		App:                    *app,
+		MaxAmountPerPaymentSat: 100,
+		MaxPaymentsPerHour:     5,
+		BlockedDestinations:    []byte(`["02e89ca9e8da72b33d896bae51d20e7e6675aa971f7557500b6591b15429e717f1"]`),
+		RequireDescription:     true,
+	}
+	err = svc.DB.Create(spendingPolicy).Error
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleGetBudgetEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	assert.Nil(t, publishedResponse.Error)
+	assert.Equal(t, uint64(400000), publishedResponse.Result.(*getBudgetResponse).TotalBudget)
+	responseSpendingPolicy := publishedResponse.Result.(*getBudgetResponse).SpendingPolicy
+	require.NotNil(t, responseSpendingPolicy)
+	assert.Equal(t, uint64(100000), responseSpendingPolicy.MaxAmountPerPayment)
+	assert.Equal(t, uint(0), responseSpendingPolicy.MaxPaymentsPerMinute)
+	assert.Equal(t, uint(5), responseSpendingPolicy.MaxPaymentsPerHour)
+	assert.Equal(t, []string{}, responseSpendingPolicy.AllowedDestinations)
+	assert.Equal(t, []string{"02e89ca9e8da72b33d896bae51d20e7e6675aa971f7557500b6591b15429e717f1"}, responseSpendingPolicy.BlockedDestinations)
+	assert.True(t, responseSpendingPolicy.RequireDescription)
+}
+
+func TestHandleGetBudgetEvent_SpendingPolicyNoBudget(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetBudgetJson), nip47Request)
+	assert.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	spendingPolicy := &db.AppSpendingPolicy{
+		AppId:                app.ID,
+		App:                  *app,
+		MaxPaymentsPerMinute: 2,
+	}
+	err = svc.DB.Create(spendingPolicy).Error
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleGetBudgetEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	assert.Nil(t, publishedResponse.Error)
+	responseSpendingPolicy := publishedResponse.Result.(*getSpendingPolicyOnlyResponse).SpendingPolicy
+	require.NotNil(t, responseSpendingPolicy)
+	assert.Equal(t, uint(2), responseSpendingPolicy.MaxPaymentsPerMinute)
+	assert.False(t, responseSpendingPolicy.RequireDescription)
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+type getNewAddressResponse struct {
+	Address string `json:"address"`
+}
+
+func (controller *nip47Controller) HandleGetNewAddressEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, publishResponse publishFunc) {
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+	}).Info("Getting new onchain address")
+
+	address, err := controller.lnClient.GetNewOnchainAddress(ctx)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to get new onchain address")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result: &getNewAddressResponse{
+			Address: address,
+		},
+	}, nostr.Tags{})
+}
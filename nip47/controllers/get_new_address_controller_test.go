@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+)
+
+const nip47GetNewAddressJson = `
+{
+	"method": "get_new_address"
+}
+`
+
+func TestHandleGetNewAddressEvent(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetNewAddressJson), nip47Request)
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleGetNewAddressEvent(ctx, nip47Request, dbRequestEvent.ID, publishResponse)
+
+	require.Nil(t, publishedResponse.Error)
+	assert.Equal(t, tests.MockOnchainAddress, publishedResponse.Result.(*getNewAddressResponse).Address)
+}
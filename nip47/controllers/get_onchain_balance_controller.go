@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+// amounts are in msat, like all other NIP-47 amounts
+type getOnchainBalanceResponse struct {
+	Spendable                          int64  `json:"spendable"`
+	Total                              int64  `json:"total"`
+	Reserved                           int64  `json:"reserved"`
+	PendingBalancesFromChannelClosures uint64 `json:"pending_balances_from_channel_closures"`
+}
+
+func (controller *nip47Controller) HandleGetOnchainBalanceEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, publishResponse publishFunc) {
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+	}).Debug("Getting onchain balance")
+
+	onchainBalance, err := controller.lnClient.GetOnchainBalance(ctx)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to fetch onchain balance")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	responsePayload := &getOnchainBalanceResponse{}
+	if onchainBalance != nil {
+		responsePayload.Spendable = onchainBalance.SpendableSat * MSAT_PER_SAT
+		responsePayload.Total = onchainBalance.TotalSat * MSAT_PER_SAT
+		responsePayload.Reserved = onchainBalance.ReservedSat * MSAT_PER_SAT
+		responsePayload.PendingBalancesFromChannelClosures = onchainBalance.PendingBalancesFromChannelClosuresSat * MSAT_PER_SAT
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     responsePayload,
+	}, nostr.Tags{})
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+)
+
+const nip47GetOnchainBalanceJson = `
+{
+	"method": "get_onchain_balance"
+}
+`
+
+func TestHandleGetOnchainBalanceEvent(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47GetOnchainBalanceJson), nip47Request)
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleGetOnchainBalanceEvent(ctx, nip47Request, dbRequestEvent.ID, publishResponse)
+
+	require.Nil(t, publishedResponse.Error)
+	result := publishedResponse.Result.(*getOnchainBalanceResponse)
+	assert.Equal(t, int64(50_000_000), result.Spendable)
+	assert.Equal(t, int64(60_000_000), result.Total)
+	assert.Equal(t, int64(10_000_000), result.Reserved)
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+type listChannelsResponse struct {
+	Channels []channelDetails `json:"channels"`
+}
+
+type channelDetails struct {
+	Id                                  string  `json:"id"`
+	RemotePubkey                        string  `json:"remote_pubkey"`
+	FundingTxId                         string  `json:"funding_tx_id"`
+	FundingTxVout                       uint32  `json:"funding_tx_vout"`
+	Active                              bool    `json:"active"`
+	Public                              bool    `json:"public"`
+	IsOutbound                          bool    `json:"is_outbound"`
+	LocalBalance                        int64   `json:"local_balance"`
+	LocalSpendableBalance               int64   `json:"local_spendable_balance"`
+	RemoteBalance                       int64   `json:"remote_balance"`
+	Confirmations                       *uint32 `json:"confirmations,omitempty"`
+	ConfirmationsRequired               *uint32 `json:"confirmations_required,omitempty"`
+	ForwardingFeeBaseMsat               uint32  `json:"forwarding_fee_base_msat"`
+	ForwardingFeeProportionalMillionths uint32  `json:"forwarding_fee_proportional_millionths"`
+	Error                               *string `json:"error,omitempty"`
+}
+
+func (controller *nip47Controller) HandleListChannelsEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, publishResponse publishFunc) {
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+	}).Debug("Listing channels")
+
+	lnClientChannels, err := controller.lnClient.ListChannels(ctx)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to list channels")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	responsePayload := &listChannelsResponse{
+		Channels: []channelDetails{},
+	}
+	for _, lnClientChannel := range lnClientChannels {
+		responsePayload.Channels = append(responsePayload.Channels, channelDetails{
+			Id:                                  lnClientChannel.Id,
+			RemotePubkey:                        lnClientChannel.RemotePubkey,
+			FundingTxId:                         lnClientChannel.FundingTxId,
+			FundingTxVout:                       lnClientChannel.FundingTxVout,
+			Active:                              lnClientChannel.Active,
+			Public:                              lnClientChannel.Public,
+			IsOutbound:                          lnClientChannel.IsOutbound,
+			LocalBalance:                        lnClientChannel.LocalBalanceMsat,
+			LocalSpendableBalance:               lnClientChannel.LocalSpendableBalanceMsat,
+			RemoteBalance:                       lnClientChannel.RemoteBalanceMsat,
+			Confirmations:                       lnClientChannel.Confirmations,
+			ConfirmationsRequired:               lnClientChannel.ConfirmationsRequired,
+			ForwardingFeeBaseMsat:               lnClientChannel.ForwardingFeeBaseMsat,
+			ForwardingFeeProportionalMillionths: lnClientChannel.ForwardingFeeProportionalMillionths,
+			Error:                               lnClientChannel.Error,
+		})
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     responsePayload,
+	}, nostr.Tags{})
+}
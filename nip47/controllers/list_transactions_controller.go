@@ -19,11 +19,17 @@ type listTransactionsParams struct {
 	UnpaidOutgoing bool   `json:"unpaid_outgoing,omitempty"`
 	UnpaidIncoming bool   `json:"unpaid_incoming,omitempty"`
 	Type           string `json:"type,omitempty"`
+	Cursor         string `json:"cursor,omitempty"`
+	ChangedSince   bool   `json:"changed_since,omitempty"` // list transactions changed after the cursor, oldest first
+	// opt-in to the unified activity feed including on-chain transactions, swaps and channel events
+	IncludeActivity bool     `json:"include_activity,omitempty"`
+	Kinds           []string `json:"kinds,omitempty"`
 }
 
 type listTransactionsResponse struct {
 	Transactions []models.Transaction `json:"transactions"`
 	TotalCount   uint64               `json:"total_count"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
 }
 
 func (controller *nip47Controller) HandleListTransactionsEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, appId uint, publishResponse publishFunc) {
@@ -51,8 +57,15 @@ func (controller *nip47Controller) HandleListTransactionsEvent(ctx context.Conte
 		transactionType = &listParams.Type
 	}
 
+	if listParams.IncludeActivity {
+		controller.listActivity(ctx, nip47Request, requestEventId, appId, listParams, limit, transactionType, publishResponse)
+		return
+	}
+
 	dbTransactions, totalCount, err := controller.transactionsService.ListTransactions(ctx, listParams.From, listParams.Until, limit, listParams.Offset, listParams.Unpaid || listParams.UnpaidOutgoing, listParams.Unpaid || listParams.UnpaidIncoming, controller.lnClient, &appId, false, &transactions.ListTransactionsFilters{
-		Type: transactionType,
+		Type:         transactionType,
+		Cursor:       listParams.Cursor,
+		ChangedSince: listParams.ChangedSince,
 	})
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
@@ -67,6 +80,8 @@ func (controller *nip47Controller) HandleListTransactionsEvent(ctx context.Conte
 		return
 	}
 
+	nextCursor := transactions.NextTransactionsCursor(dbTransactions, listParams.Cursor)
+
 	transactions := []models.Transaction{}
 	for _, dbTransaction := range dbTransactions {
 		transactions = append(transactions, *models.ToNip47Transaction(&dbTransaction))
@@ -75,6 +90,7 @@ func (controller *nip47Controller) HandleListTransactionsEvent(ctx context.Conte
 	responsePayload := &listTransactionsResponse{
 		Transactions: transactions,
 		TotalCount:   totalCount,
+		NextCursor:   nextCursor,
 	}
 
 	publishResponse(&models.Response{
@@ -82,3 +98,37 @@ func (controller *nip47Controller) HandleListTransactionsEvent(ctx context.Conte
 		Result:     responsePayload,
 	}, nostr.Tags{})
 }
+
+func (controller *nip47Controller) listActivity(ctx context.Context, nip47Request *models.Request, requestEventId uint, appId uint, listParams *listTransactionsParams, limit uint64, transactionType *string, publishResponse publishFunc) {
+	activityItems, totalCount, err := controller.transactionsService.ListActivity(ctx, limit, listParams.Offset, controller.lnClient, &appId, false, &transactions.ListActivityFilters{
+		Kinds: listParams.Kinds,
+		Type:  transactionType,
+		From:  listParams.From,
+		Until: listParams.Until,
+	})
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"params":           listParams,
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to fetch activity")
+
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	transactions := []models.Transaction{}
+	for _, activityItem := range activityItems {
+		transactions = append(transactions, *models.ToNip47ActivityItem(&activityItem))
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result: &listTransactionsResponse{
+			Transactions: transactions,
+			TotalCount:   totalCount,
+		},
+	}, nostr.Tags{})
+}
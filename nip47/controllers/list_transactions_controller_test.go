@@ -339,3 +339,152 @@ func TestHandleListTransactionsEvent_Paid(t *testing.T) {
 }
 
 // TODO: add tests for pagination args
+
+func TestHandleListTransactionsEvent_IncludeActivity(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	const nip47ListTransactionsJson = `
+{
+	"method": "list_transactions",
+	"params": {
+		"limit": 10,
+		"include_activity": true
+	}
+}
+`
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47ListTransactionsJson), nip47Request)
+	require.NoError(t, err)
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{
+		AppId: &app.ID,
+	}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	settledAt := time.Now().Add(-1 * time.Hour)
+	err = svc.DB.Create(&db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat:  21_000,
+		PaymentHash: tests.MockPaymentHash,
+		Preimage:    &tests.MockLNClientTransaction.Preimage,
+		SettledAt:   &settledAt,
+		AppId:       &app.ID,
+	}).Error
+	require.NoError(t, err)
+
+	err = svc.DB.Create(&db.Swap{
+		SwapId:           "swap1",
+		Type:             constants.SWAP_TYPE_IN,
+		State:            constants.SWAP_STATE_PENDING,
+		SendAmountSat:    100_000,
+		ReceiveAmountSat: 99_000,
+		LockupTxId:       "lockuptxid",
+	}).Error
+	require.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleListTransactionsEvent(ctx, nip47Request, dbRequestEvent.ID, *dbRequestEvent.AppId, publishResponse)
+
+	require.Nil(t, publishedResponse.Error)
+
+	result := publishedResponse.Result.(*listTransactionsResponse)
+	assert.Equal(t, uint64(2), result.TotalCount)
+	require.Equal(t, 2, len(result.Transactions))
+	assert.Equal(t, constants.ACTIVITY_KIND_SWAP_IN, result.Transactions[0].Kind)
+	assert.Equal(t, "pending", result.Transactions[0].State)
+	assert.Equal(t, "lockuptxid", result.Transactions[0].TxId)
+	assert.Equal(t, int64(99_000_000), result.Transactions[0].Amount)
+	assert.Equal(t, int64(1_000_000), result.Transactions[0].FeesPaid)
+	assert.Equal(t, constants.ACTIVITY_KIND_LIGHTNING, result.Transactions[1].Kind)
+	assert.Equal(t, tests.MockPaymentHash, result.Transactions[1].PaymentHash)
+}
+
+func TestHandleListTransactionsEvent_Cursor(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{
+		AppId: &app.ID,
+	}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		err = svc.DB.Create(&db.Transaction{
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			AmountMsat:  uint64(i) * 1000,
+			PaymentHash: tests.MockPaymentHash,
+			Preimage:    &tests.MockLNClientTransaction.Preimage,
+			UpdatedAt:   time.Now().Add(time.Duration(i) * time.Minute),
+		}).Error
+		require.NoError(t, err)
+	}
+
+	var publishedResponse *models.Response
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	listTransactions := func(params map[string]interface{}) {
+		nip47Request := &models.Request{
+			Method: models.LIST_TRANSACTIONS_METHOD,
+		}
+		nip47Request.Params, err = json.Marshal(params)
+		require.NoError(t, err)
+		NewTestNip47Controller(svc).
+			HandleListTransactionsEvent(ctx, nip47Request, dbRequestEvent.ID, *dbRequestEvent.AppId, publishResponse)
+	}
+
+	listTransactions(map[string]interface{}{"limit": 2})
+	require.Nil(t, publishedResponse.Error)
+	result := publishedResponse.Result.(*listTransactionsResponse)
+	require.Equal(t, 2, len(result.Transactions))
+	assert.Equal(t, int64(3000), result.Transactions[0].Amount)
+	assert.Equal(t, int64(2000), result.Transactions[1].Amount)
+	require.NotEmpty(t, result.NextCursor)
+
+	listTransactions(map[string]interface{}{"limit": 2, "cursor": result.NextCursor})
+	require.Nil(t, publishedResponse.Error)
+	result = publishedResponse.Result.(*listTransactionsResponse)
+	require.Equal(t, 1, len(result.Transactions))
+	assert.Equal(t, int64(1000), result.Transactions[0].Amount)
+
+	// nothing changed since the last sync, the cursor is returned unchanged
+	listTransactions(map[string]interface{}{"changed_since": true})
+	require.Nil(t, publishedResponse.Error)
+	result = publishedResponse.Result.(*listTransactionsResponse)
+	require.Equal(t, 3, len(result.Transactions))
+	syncCursor := result.NextCursor
+	require.NotEmpty(t, syncCursor)
+
+	listTransactions(map[string]interface{}{"changed_since": true, "cursor": syncCursor})
+	require.Nil(t, publishedResponse.Error)
+	result = publishedResponse.Result.(*listTransactionsResponse)
+	assert.Equal(t, 0, len(result.Transactions))
+	assert.Equal(t, syncCursor, result.NextCursor)
+
+	listTransactions(map[string]interface{}{"cursor": "invalid"})
+	require.NotNil(t, publishedResponse.Error)
+	assert.Equal(t, constants.ERROR_BAD_REQUEST, publishedResponse.Error.Code)
+}
@@ -21,6 +21,15 @@ func mapNip47Error(err error) *models.Error {
 	if errors.Is(err, transactions.NewQuotaExceededError()) {
 		code = constants.ERROR_QUOTA_EXCEEDED
 	}
+	if errors.Is(err, transactions.NewSpendingPolicyError("")) {
+		code = constants.ERROR_RESTRICTED
+	}
+	if errors.Is(err, transactions.NewInvalidCursorError()) {
+		code = constants.ERROR_BAD_REQUEST
+	}
+	if errors.Is(err, transactions.NewPaymentApprovalRequiredError()) {
+		code = constants.ERROR_PENDING_APPROVAL
+	}
 	if errors.Is(err, gorm.ErrDuplicatedKey) {
 		// avoid leaking raw driver/SQL error details (e.g. constraint names) to NWC apps
 		message = gorm.ErrDuplicatedKey.Error()
@@ -19,6 +19,7 @@ import (
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/nip47/models"
 	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
 )
 
 const nip47MultiPayJson = `
@@ -513,3 +514,87 @@ EXECUTE PROCEDURE slow_down_query();`).Error
 		assert.Equal(t, constants.ERROR_INSUFFICIENT_BALANCE, response.Error.Code)
 	}
 }
+
+func TestHandleMultiPayInvoiceEvent_ApprovalRequired(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	spendingPolicy := &db.AppSpendingPolicy{
+		AppId:            app.ID,
+		App:              *app,
+		ApprovalRequired: true,
+	}
+	err = svc.DB.Create(spendingPolicy).Error
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47MultiPayJson), nip47Request)
+	assert.NoError(t, err)
+
+	responses := []*models.Response{}
+	dTags := []nostr.Tags{}
+
+	var mu sync.Mutex
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		mu.Lock()
+		defer mu.Unlock()
+		responses = append(responses, response)
+		dTags = append(dTags, tags)
+	}
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	NewTestNip47Controller(svc).
+		HandleMultiPayInvoiceEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	assert.Equal(t, 2, len(responses))
+	for _, response := range responses {
+		assert.Nil(t, response.Result)
+		assert.Equal(t, constants.ERROR_PENDING_APPROVAL, response.Error.Code)
+	}
+
+	// each invoice of the request needs its own approval
+	var paymentApprovals []db.PaymentApproval
+	err = svc.DB.Order("payment_hash").Find(&paymentApprovals).Error
+	require.NoError(t, err)
+	require.Equal(t, 2, len(paymentApprovals))
+	assert.Equal(t, "23277d5e13fce5534f9752c62fcf9337a2a6b0ebea9d21fa816a4c9d054cf93b", paymentApprovals[0].PaymentHash)
+	assert.Equal(t, "ac1b93f8b65a46e9aea3cc56ac5ac35d163d350cd612d922dd1f8b550f98c338", paymentApprovals[1].PaymentHash)
+	for _, paymentApproval := range paymentApprovals {
+		assert.Equal(t, dbRequestEvent.ID, *paymentApproval.RequestEventId)
+		assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_PENDING, paymentApproval.State)
+	}
+
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsSvc.ApprovePayment(ctx, paymentApprovals[0].ID, svc.LNClient)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, paymentApprovals[0].PaymentHash, transaction.PaymentHash)
+
+	// approving one invoice does not approve the rest of the batch
+	err = svc.DB.First(&paymentApprovals[1], paymentApprovals[1].ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_PENDING, paymentApprovals[1].State)
+
+	var outgoingTransactionCount int64
+	err = svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&outgoingTransactionCount).Error
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), outgoingTransactionCount)
+}
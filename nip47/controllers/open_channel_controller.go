@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+type openChannelParams struct {
+	Pubkey string `json:"pubkey"`
+	Host   string `json:"host"`   // optional "address:port" of the peer to connect to before opening the channel
+	Amount uint64 `json:"amount"` // msat
+	Public bool   `json:"public"`
+}
+
+type openChannelResponse struct {
+	FundingTxId string `json:"funding_tx_id"`
+}
+
+func (controller *nip47Controller) HandleOpenChannelEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, publishResponse publishFunc) {
+	openChannelParams := &openChannelParams{}
+	resp := decodeRequest(nip47Request, openChannelParams)
+	if resp != nil {
+		publishResponse(resp, nostr.Tags{})
+		return
+	}
+
+	if openChannelParams.Pubkey == "" || openChannelParams.Amount < 1000 {
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error: &models.Error{
+				Code:    constants.ERROR_BAD_REQUEST,
+				Message: "pubkey and an amount of at least 1000 msat are required",
+			},
+		}, nostr.Tags{})
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"pubkey":           openChannelParams.Pubkey,
+		"amount":           openChannelParams.Amount,
+		"public":           openChannelParams.Public,
+	}).Info("Opening channel")
+
+	if openChannelParams.Host != "" {
+		address, portString, err := net.SplitHostPort(openChannelParams.Host)
+		var port uint64
+		if err == nil {
+			port, err = strconv.ParseUint(portString, 10, 16)
+		}
+		if err != nil {
+			publishResponse(&models.Response{
+				ResultType: nip47Request.Method,
+				Error: &models.Error{
+					Code:    constants.ERROR_BAD_REQUEST,
+					Message: "invalid host: " + err.Error(),
+				},
+			}, nostr.Tags{})
+			return
+		}
+
+		err = controller.lnClient.ConnectPeer(ctx, &lnclient.ConnectPeerRequest{
+			Pubkey:  openChannelParams.Pubkey,
+			Address: address,
+			Port:    uint16(port),
+		})
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"request_event_id": requestEventId,
+			}).WithError(err).Error("Failed to connect to peer")
+			publishResponse(&models.Response{
+				ResultType: nip47Request.Method,
+				Error:      mapNip47Error(err),
+			}, nostr.Tags{})
+			return
+		}
+	}
+
+	openChannelResp, err := controller.lnClient.OpenChannel(ctx, &lnclient.OpenChannelRequest{
+		Pubkey:     openChannelParams.Pubkey,
+		AmountSats: int64(openChannelParams.Amount / 1000),
+		Public:     openChannelParams.Public,
+	})
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to open channel")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	responsePayload := &openChannelResponse{}
+	if openChannelResp != nil {
+		responsePayload.FundingTxId = openChannelResp.FundingTxId
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     responsePayload,
+	}, nostr.Tags{})
+}
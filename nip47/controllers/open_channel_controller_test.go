@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+)
+
+const nip47OpenChannelJson = `
+{
+	"method": "open_channel",
+	"params": {
+		"pubkey": "02e89ca9e8da72b33d896bae51d20e7e6675aa971f7557500b6591b15429e717f1",
+		"host": "127.0.0.1:9735",
+		"amount": 100000000,
+		"public": true
+	}
+}
+`
+
+const nip47OpenChannelInvalidHostJson = `
+{
+	"method": "open_channel",
+	"params": {
+		"pubkey": "02e89ca9e8da72b33d896bae51d20e7e6675aa971f7557500b6591b15429e717f1",
+		"host": "127.0.0.1",
+		"amount": 100000000
+	}
+}
+`
+
+func TestHandleOpenChannelEvent(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47OpenChannelJson), nip47Request)
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleOpenChannelEvent(ctx, nip47Request, dbRequestEvent.ID, publishResponse)
+
+	require.Nil(t, publishedResponse.Error)
+	assert.Equal(t, tests.MockFundingTxId, publishedResponse.Result.(*openChannelResponse).FundingTxId)
+}
+
+func TestHandleOpenChannelEvent_InvalidHost(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47OpenChannelInvalidHostJson), nip47Request)
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandleOpenChannelEvent(ctx, nip47Request, dbRequestEvent.ID, publishResponse)
+
+	assert.Nil(t, publishedResponse.Result)
+	require.NotNil(t, publishedResponse.Error)
+	assert.Equal(t, constants.ERROR_BAD_REQUEST, publishedResponse.Error.Code)
+}
@@ -224,3 +224,56 @@ func TestHandlePayInvoiceEvent_ExpiredInvoice(t *testing.T) {
 	assert.Equal(t, constants.ERROR_INTERNAL, publishedResponse.Error.Code)
 	assert.Equal(t, "this invoice has expired", publishedResponse.Error.Message)
 }
+
+func TestHandlePayInvoiceEvent_ApprovalRequired(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	spendingPolicy := &db.AppSpendingPolicy{
+		AppId:                app.ID,
+		App:                  *app,
+		ApprovalThresholdSat: 100,
+	}
+	err = svc.DB.Create(spendingPolicy).Error
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47PayInvoiceJson), nip47Request)
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePayInvoiceEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse, nostr.Tags{})
+
+	assert.Nil(t, publishedResponse.Result)
+	assert.Equal(t, constants.ERROR_PENDING_APPROVAL, publishedResponse.Error.Code)
+	assert.Equal(t, transactions.NewPaymentApprovalRequiredError().Error(), publishedResponse.Error.Message)
+
+	var paymentApproval db.PaymentApproval
+	err = svc.DB.First(&paymentApproval).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_PENDING, paymentApproval.State)
+	assert.Equal(t, dbRequestEvent.ID, *paymentApproval.RequestEventId)
+}
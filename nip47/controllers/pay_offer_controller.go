@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+type payOfferParams struct {
+	Offer     string                 `json:"offer"`
+	Amount    uint64                 `json:"amount"`
+	PayerNote string                 `json:"payer_note"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func (controller *nip47Controller) HandlePayOfferEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc, tags nostr.Tags) {
+	payOfferParams := &payOfferParams{}
+	resp := decodeRequest(nip47Request, payOfferParams)
+	if resp != nil {
+		publishResponse(resp, tags)
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"app_id":           app.ID,
+		"offer":            payOfferParams.Offer,
+		"amount":           payOfferParams.Amount,
+	}).Info("Paying BOLT-12 offer")
+
+	transaction, err := controller.transactionsService.PayOffer(ctx, payOfferParams.Offer, payOfferParams.Amount, payOfferParams.PayerNote, payOfferParams.Metadata, controller.lnClient, &app.ID, &requestEventId)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+			"app_id":           app.ID,
+			"offer":            payOfferParams.Offer,
+		}).WithError(err).Error("Failed to pay BOLT-12 offer")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, tags)
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result: payResponse{
+			Preimage: *transaction.Preimage,
+			FeesPaid: transaction.FeeMsat,
+		},
+	}, tags)
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
+)
+
+const nip47PayOfferJson = `
+{
+	"method": "pay_offer",
+	"params": {
+		"offer": "` + tests.MockOffer + `",
+		"amount": 123000,
+		"payer_note": "thanks",
+		"metadata": {"a": 123}
+	}
+}
+`
+
+const nip47PayOfferJsonNoAmount = `
+{
+	"method": "pay_offer",
+	"params": {
+		"offer": "` + tests.MockOffer + `"
+	}
+}
+`
+
+func TestHandlePayOfferEvent(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47PayOfferJson), nip47Request)
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePayOfferEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse, nostr.Tags{})
+
+	assert.Nil(t, publishedResponse.Error)
+	assert.Equal(t, "123preimage", publishedResponse.Result.(payResponse).Preimage)
+	assert.Equal(t, uint64(1), publishedResponse.Result.(payResponse).FeesPaid)
+
+	transactionType := constants.TRANSACTION_TYPE_OUTGOING
+	transactionsSvc := transactions.NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsSvc.LookupTransaction(ctx, tests.MockOfferPaymentHash, &transactionType, svc.LNClient, &app.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, uint64(123000), transaction.AmountMsat)
+	assert.Equal(t, tests.MockOffer, transaction.PaymentRequest)
+	assert.Equal(t, "thanks", transaction.Description)
+	assert.Equal(t, app.ID, *transaction.AppId)
+	assert.Equal(t, dbRequestEvent.ID, *transaction.RequestEventId)
+
+	type dummyMetadata struct {
+		A     int `json:"a"`
+		Offer struct {
+			PayerNote string `json:"payer_note"`
+		} `json:"offer"`
+	}
+	var decodedMetadata dummyMetadata
+	err = json.Unmarshal(transaction.Metadata, &decodedMetadata)
+	assert.NoError(t, err)
+	assert.Equal(t, 123, decodedMetadata.A)
+	assert.Equal(t, "thanks", decodedMetadata.Offer.PayerNote)
+}
+
+func TestHandlePayOfferEvent_NoAmount(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47PayOfferJsonNoAmount), nip47Request)
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePayOfferEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse, nostr.Tags{})
+
+	assert.Nil(t, publishedResponse.Result)
+	assert.Equal(t, constants.ERROR_INTERNAL, publishedResponse.Error.Code)
+	assert.Equal(t, "an amount must be provided to pay a BOLT-12 offer", publishedResponse.Error.Message)
+}
+
+func TestHandlePayOfferEvent_QuotaExceeded(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	assert.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_INVOICE_SCOPE,
+		MaxAmountSat:  100,
+		BudgetRenewal: constants.BUDGET_RENEWAL_NEVER,
+	}
+	err = svc.DB.Create(appPermission).Error
+	assert.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47PayOfferJson), nip47Request)
+	assert.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	assert.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePayOfferEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse, nostr.Tags{})
+
+	assert.Nil(t, publishedResponse.Result)
+	assert.Equal(t, constants.ERROR_QUOTA_EXCEEDED, publishedResponse.Error.Code)
+}
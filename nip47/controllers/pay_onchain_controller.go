@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+type payOnchainParams struct {
+	Address  string                 `json:"address"`
+	Amount   uint64                 `json:"amount"`             // msat
+	FeeRate  *uint64                `json:"fee_rate,omitempty"` // sat/vB
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type payOnchainResponse struct {
+	TxId string `json:"txid"`
+}
+
+func (controller *nip47Controller) HandlePayOnchainEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, app *db.App, publishResponse publishFunc) {
+	payOnchainParams := &payOnchainParams{}
+	resp := decodeRequest(nip47Request, payOnchainParams)
+	if resp != nil {
+		publishResponse(resp, nostr.Tags{})
+		return
+	}
+
+	if payOnchainParams.Address == "" || payOnchainParams.Amount < 1000 || payOnchainParams.Amount%1000 != 0 {
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error: &models.Error{
+				Code:    constants.ERROR_BAD_REQUEST,
+				Message: "address and an amount of whole satoshis are required",
+			},
+		}, nostr.Tags{})
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"app_id":           app.ID,
+		"address":          payOnchainParams.Address,
+		"amount":           payOnchainParams.Amount,
+	}).Info("Making on-chain payment")
+
+	transaction, err := controller.transactionsService.PayOnchain(ctx, payOnchainParams.Address, payOnchainParams.Amount/1000, payOnchainParams.FeeRate, payOnchainParams.Metadata, controller.lnClient, &app.ID, &requestEventId)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+			"app_id":           app.ID,
+			"address":          payOnchainParams.Address,
+		}).WithError(err).Error("Failed to make on-chain payment")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result: &payOnchainResponse{
+			TxId: transaction.OnchainTxId,
+		},
+	}, nostr.Tags{})
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests"
+)
+
+const nip47PayOnchainJson = `
+{
+	"method": "pay_onchain",
+	"params": {
+		"address": "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080",
+		"amount": 10000000,
+		"fee_rate": 2
+	}
+}
+`
+
+const nip47PayOnchainPartialSatJson = `
+{
+	"method": "pay_onchain",
+	"params": {
+		"address": "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080",
+		"amount": 10000500
+	}
+}
+`
+
+func TestHandlePayOnchainEvent(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_ONCHAIN_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	require.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47PayOnchainJson), nip47Request)
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePayOnchainEvent(ctx, nip47Request, dbRequestEvent.ID, app, publishResponse)
+
+	require.Nil(t, publishedResponse.Error)
+	assert.Equal(t, tests.MockOnchainTxId, publishedResponse.Result.(*payOnchainResponse).TxId)
+
+	var transaction db.Transaction
+	err = svc.DB.First(&transaction).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, transaction.State)
+	assert.Equal(t, uint64(10_000_000), transaction.AmountMsat)
+	assert.Equal(t, dbRequestEvent.ID, *transaction.RequestEventId)
+}
+
+func TestHandlePayOnchainEvent_PartialSatoshis(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	nip47Request := &models.Request{}
+	err = json.Unmarshal([]byte(nip47PayOnchainPartialSatJson), nip47Request)
+	require.NoError(t, err)
+
+	var publishedResponse *models.Response
+
+	publishResponse := func(response *models.Response, tags nostr.Tags) {
+		publishedResponse = response
+	}
+
+	NewTestNip47Controller(svc).
+		HandlePayOnchainEvent(ctx, nip47Request, 0, app, publishResponse)
+
+	require.NotNil(t, publishedResponse.Error)
+	assert.Equal(t, constants.ERROR_BAD_REQUEST, publishedResponse.Error.Code)
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/sirupsen/logrus"
+)
+
+type updateChannelFeesParams struct {
+	Id                                  string `json:"id"`
+	NodeId                              string `json:"node_id"`
+	ForwardingFeeBaseMsat               uint32 `json:"forwarding_fee_base_msat"`
+	ForwardingFeeProportionalMillionths uint32 `json:"forwarding_fee_proportional_millionths"`
+}
+
+type updateChannelFeesResponse struct{}
+
+func (controller *nip47Controller) HandleUpdateChannelFeesEvent(ctx context.Context, nip47Request *models.Request, requestEventId uint, publishResponse publishFunc) {
+	updateChannelFeesParams := &updateChannelFeesParams{}
+	resp := decodeRequest(nip47Request, updateChannelFeesParams)
+	if resp != nil {
+		publishResponse(resp, nostr.Tags{})
+		return
+	}
+
+	if updateChannelFeesParams.Id == "" || updateChannelFeesParams.NodeId == "" {
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error: &models.Error{
+				Code:    constants.ERROR_BAD_REQUEST,
+				Message: "id and node_id are required",
+			},
+		}, nostr.Tags{})
+		return
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"request_event_id": requestEventId,
+		"params":           updateChannelFeesParams,
+	}).Info("Updating channel fees")
+
+	// the max dust HTLC exposure is left unchanged
+	err := controller.lnClient.UpdateChannel(ctx, &lnclient.UpdateChannelRequest{
+		ChannelId:                           updateChannelFeesParams.Id,
+		NodeId:                              updateChannelFeesParams.NodeId,
+		ForwardingFeeBaseMsat:               updateChannelFeesParams.ForwardingFeeBaseMsat,
+		ForwardingFeeProportionalMillionths: updateChannelFeesParams.ForwardingFeeProportionalMillionths,
+	})
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"request_event_id": requestEventId,
+		}).WithError(err).Error("Failed to update channel fees")
+		publishResponse(&models.Response{
+			ResultType: nip47Request.Method,
+			Error:      mapNip47Error(err),
+		}, nostr.Tags{})
+		return
+	}
+
+	publishResponse(&models.Response{
+		ResultType: nip47Request.Method,
+		Result:     updateChannelFeesResponse{},
+	}, nostr.Tags{})
+}
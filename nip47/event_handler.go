@@ -9,16 +9,19 @@ import (
 	"time"
 
 	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/audit"
 	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
 	"github.com/getAlby/hub/nip47/cipher"
 	"github.com/getAlby/hub/nip47/controllers"
 	"github.com/getAlby/hub/nip47/models"
 	"github.com/getAlby/hub/nip47/permissions"
 	nostrmodels "github.com/getAlby/hub/nostr/models"
+	"github.com/getAlby/hub/service/keys"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -54,6 +57,16 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 		return
 	}
 
+	// rate limit requests before anything is stored or decrypted
+	rateLimitApp := db.App{}
+	result := svc.db.Limit(1).Find(&rateLimitApp, &db.App{
+		AppPubkey: event.PubKey,
+	})
+	if result.Error == nil && result.RowsAffected > 0 && !svc.rateLimiter.allow(rateLimitApp.ID, getAppRateLimit(&rateLimitApp), time.Now()) {
+		svc.handleRateLimitedRequest(ctx, pool, event, &rateLimitApp)
+		return
+	}
+
 	// store request event
 	requestEvent := db.RequestEvent{AppId: nil, NostrId: event.ID, State: db.REQUEST_EVENT_STATE_HANDLER_EXECUTING}
 	err = svc.db.Create(&requestEvent).Error
@@ -82,7 +95,10 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 	}
 
 	now := time.Now()
-	err = svc.db.Model(&app).Update("last_used_at", &now).Error
+	err = svc.db.Model(&app).Updates(map[string]interface{}{
+		"last_used_at":  &now,
+		"request_count": gorm.Expr("request_count + 1"),
+	}).Error
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"app_id": app.ID,
@@ -95,11 +111,11 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 		"appId":               app.ID,
 	}).Debug("App found for nostr event")
 
-	appWalletPrivKey := svc.keys.GetNostrSecretKey()
+	appWalletSigner := svc.keys.GetNostrSigner()
 
 	if app.WalletPubkey != nil {
 		// This is a new child key derived from master using app ID as index
-		appWalletPrivKey, err = svc.keys.GetAppWalletKey(app.ID)
+		appWalletSigner, err = svc.keys.GetAppWalletSigner(app.ID)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"appId": app.ID,
@@ -114,7 +130,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 		encryption = encryptionTag[1]
 	}
 
-	nip47Cipher, err := cipher.NewNip47Cipher(encryption, app.AppPubkey, appWalletPrivKey)
+	nip47Cipher, err := cipher.NewNip47CipherWithSigner(encryption, app.AppPubkey, appWalletSigner)
 	if err != nil {
 		cipherErr := err
 		logger.Logger.WithFields(logrus.Fields{
@@ -136,7 +152,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 
 		// whenever we are unable to handle the request encryption, we always respond with our preferred encryption
 		// re-create the cipher with NIP-44 to send an error response
-		nip47Cipher, err := cipher.NewNip47Cipher(constants.ENCRYPTION_TYPE_NIP44_V2, app.AppPubkey, appWalletPrivKey)
+		nip47Cipher, err := cipher.NewNip47CipherWithSigner(constants.ENCRYPTION_TYPE_NIP44_V2, app.AppPubkey, appWalletSigner)
 
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
@@ -154,8 +170,9 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 				Message: cipherErr.Error(),
 			},
 		}
+		metrics.RecordNip47Request("", app.ID, nip47Response.Error.Code)
 
-		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, nip47Cipher, appWalletPrivKey)
+		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, nip47Cipher, appWalletSigner)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -182,7 +199,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 				Message: fmt.Sprintf("Failed to save app to nostr event: %s", err.Error()),
 			},
 		}
-		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, nip47Cipher, appWalletPrivKey)
+		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, nip47Cipher, appWalletSigner)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -225,7 +242,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 
 		// whenever we are unable to handle the request encryption, we always respond with our preferred encryption
 		// re-create the cipher with NIP-44 to send an error response
-		nip47Cipher, err := cipher.NewNip47Cipher(constants.ENCRYPTION_TYPE_NIP44_V2, app.AppPubkey, appWalletPrivKey)
+		nip47Cipher, err := cipher.NewNip47CipherWithSigner(constants.ENCRYPTION_TYPE_NIP44_V2, app.AppPubkey, appWalletSigner)
 
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
@@ -243,8 +260,9 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 				Message: fmt.Sprintf("failed to decrypt: %s", decryptionErr.Error()),
 			},
 		}
+		metrics.RecordNip47Request("", app.ID, nip47Response.Error.Code)
 
-		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, nip47Cipher, appWalletPrivKey)
+		resp, err := svc.CreateResponse(event, nip47Response, nostr.Tags{}, nip47Cipher, appWalletSigner)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -276,16 +294,23 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 		return
 	}
 
-	// we ignore potential DB errors here as this only saves the method and content data
+	// we ignore potential DB errors here as this only saves the method, content data and encryption
 	svc.db.Model(&requestEvent).Updates(map[string]interface{}{
 		"method":       nip47Request.Method,
 		"content_data": payload,
+		"encryption":   encryption,
 	})
 	// TODO: replace with a channel
 	// TODO: update all previous occurrences of svc.publishResponseEvent to also use the channel
 	publishResponse := func(nip47Response *models.Response, tags nostr.Tags) {
+		var errorCode string
+		if nip47Response.Error != nil {
+			errorCode = nip47Response.Error.Code
+		}
+		metrics.RecordNip47Request(nip47Request.Method, app.ID, errorCode)
+
 		var state string
-		resp, err := svc.CreateResponse(event, nip47Response, tags, nip47Cipher, appWalletPrivKey)
+		resp, err := svc.CreateResponse(event, nip47Response, tags, nip47Cipher, appWalletSigner)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"requestEventNostrId": event.ID,
@@ -349,7 +374,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 		// as it makes sure we can respond even after a downtime or network issue.
 		// but we should check the creation date of a request and ignore too old requests
 		// for payments and invoice creation.
-		if (scope == constants.PAY_INVOICE_SCOPE || scope == constants.MAKE_INVOICE_SCOPE) && time.Since(event.CreatedAt.Time()).Hours() > 6 {
+		if (scope == constants.PAY_INVOICE_SCOPE || scope == constants.PAY_ONCHAIN_SCOPE || scope == constants.MAKE_INVOICE_SCOPE) && time.Since(event.CreatedAt.Time()).Hours() > 6 {
 			logger.Logger.WithFields(logrus.Fields{
 				"request_event_id": requestEvent.ID,
 				"app_id":           app.ID,
@@ -390,7 +415,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 		}
 	}
 
-	controller := controllers.NewNip47Controller(lnClient, svc.db, svc.eventPublisher, svc.permissionsService, svc.transactionsService, svc.appsService, svc.albyOAuthSvc)
+	controller := controllers.NewNip47Controller(lnClient, svc.db, svc.eventPublisher, svc.permissionsService, svc.transactionsService, svc.appsService, svc.albyOAuthSvc, audit.NewAuditService(svc.db, svc.cfg.GetEnv().Workdir))
 
 	switch nip47Request.Method {
 	case models.MULTI_PAY_INVOICE_METHOD:
@@ -405,6 +430,12 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 	case models.PAY_KEYSEND_METHOD:
 		controller.
 			HandlePayKeysendEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse, nostr.Tags{})
+	case models.PAY_OFFER_METHOD:
+		controller.
+			HandlePayOfferEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse, nostr.Tags{})
+	case models.PAY_ONCHAIN_METHOD:
+		controller.
+			HandlePayOnchainEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse)
 	case models.GET_BALANCE_METHOD:
 		controller.
 			HandleGetBalanceEvent(ctx, nip47Request, requestEvent.ID, &app, publishResponse)
@@ -428,7 +459,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 			HandleSignMessageEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
 	case models.CREATE_CONNECTION_METHOD:
 		controller.
-			HandleCreateConnectionEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
+			HandleCreateConnectionEvent(audit.WithActor(ctx, constants.AUDIT_SOURCE_NIP47, fmt.Sprintf("app:%d", app.ID)), nip47Request, requestEvent.ID, publishResponse)
 	case models.MAKE_HOLD_INVOICE_METHOD:
 		controller.
 			HandleMakeHoldInvoiceEvent(ctx, nip47Request, requestEvent.ID, app.ID, publishResponse)
@@ -438,6 +469,24 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 	case models.SETTLE_HOLD_INVOICE_METHOD:
 		controller.
 			HandleSettleHoldInvoiceEvent(ctx, nip47Request, requestEvent.ID, app.ID, publishResponse)
+	case models.LIST_CHANNELS_METHOD:
+		controller.
+			HandleListChannelsEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
+	case models.OPEN_CHANNEL_METHOD:
+		controller.
+			HandleOpenChannelEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
+	case models.CLOSE_CHANNEL_METHOD:
+		controller.
+			HandleCloseChannelEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
+	case models.UPDATE_CHANNEL_FEES_METHOD:
+		controller.
+			HandleUpdateChannelFeesEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
+	case models.GET_ONCHAIN_BALANCE_METHOD:
+		controller.
+			HandleGetOnchainBalanceEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
+	case models.GET_NEW_ADDRESS_METHOD:
+		controller.
+			HandleGetNewAddressEvent(ctx, nip47Request, requestEvent.ID, publishResponse)
 	default:
 		publishResponse(&models.Response{
 			ResultType: nip47Request.Method,
@@ -449,7 +498,7 @@ func (svc *nip47Service) HandleEvent(ctx context.Context, pool nostrmodels.Simpl
 	}
 }
 
-func (svc *nip47Service) CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, cipher *cipher.Nip47Cipher, appWalletPrivKey string) (result *nostr.Event, err error) {
+func (svc *nip47Service) CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, cipher *cipher.Nip47Cipher, appWalletSigner keys.Signer) (result *nostr.Event, err error) {
 	payloadBytes, err := json.Marshal(content)
 	if err != nil {
 		return nil, err
@@ -463,26 +512,92 @@ func (svc *nip47Service) CreateResponse(initialEvent *nostr.Event, content inter
 	allTags := nostr.Tags{[]string{"p", initialEvent.PubKey}, []string{"e", initialEvent.ID}}
 	allTags = append(allTags, tags...)
 
-	appWalletPubKey, err := nostr.GetPublicKey(appWalletPrivKey)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Error converting nostr privkey to pubkey")
-		return
-	}
-
 	resp := &nostr.Event{
-		PubKey:    appWalletPubKey,
+		PubKey:    appWalletSigner.GetPublicKey(),
 		CreatedAt: nostr.Now(),
 		Kind:      models.RESPONSE_KIND,
 		Tags:      allTags,
 		Content:   msg,
 	}
-	err = resp.Sign(appWalletPrivKey)
+	err = appWalletSigner.SignEvent(resp)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
+// handleRateLimitedRequest responds to a request of an app which exceeded its rate limit.
+// The request is not stored and its content is not decrypted, so the response has no result type.
+func (svc *nip47Service) handleRateLimitedRequest(ctx context.Context, pool nostrmodels.SimplePool, event *nostr.Event, app *db.App) {
+	logger.Logger.WithFields(logrus.Fields{
+		"requestEventNostrId": event.ID,
+		"app_id":              app.ID,
+	}).Warn("App exceeded its request rate limit")
+
+	now := time.Now()
+	err := svc.db.Model(app).Updates(map[string]interface{}{
+		"rate_limited_count":   gorm.Expr("rate_limited_count + 1"),
+		"last_rate_limited_at": &now,
+	}).Error
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"app_id": app.ID,
+		}).WithError(err).Error("Failed to update app rate limited count")
+	}
+	metrics.RecordNip47Request("", app.ID, constants.ERROR_RATE_LIMITED)
+
+	appWalletSigner := svc.keys.GetNostrSigner()
+	if app.WalletPubkey != nil {
+		appWalletSigner, err = svc.keys.GetAppWalletSigner(app.ID)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"appId": app.ID,
+			}).WithError(err).Error("error deriving child key")
+			return
+		}
+	}
+
+	encryption := constants.ENCRYPTION_TYPE_NIP04
+	encryptionTag := event.Tags.Find("encryption")
+	if encryptionTag != nil {
+		encryption = encryptionTag[1]
+	}
+	nip47Cipher, err := cipher.NewNip47CipherWithSigner(encryption, app.AppPubkey, appWalletSigner)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"requestEventNostrId": event.ID,
+			"appId":               app.ID,
+			"encryption":          encryption,
+		}).WithError(err).Error("Failed to initialize cipher")
+		return
+	}
+
+	resp, err := svc.CreateResponse(event, &models.Response{
+		Error: &models.Error{
+			Code:    constants.ERROR_RATE_LIMITED,
+			Message: "Too many requests. Please try again later.",
+		},
+	}, nostr.Tags{}, nip47Cipher, appWalletSigner)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"requestEventNostrId": event.ID,
+			"appId":               app.ID,
+		}).WithError(err).Error("Failed to create response")
+		return
+	}
+
+	for result := range pool.PublishMany(ctx, svc.cfg.GetRelayUrls(), *resp) {
+		if result.Error != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"requestEventNostrId":  event.ID,
+				"responseNostrEventId": resp.ID,
+				"appId":                app.ID,
+				"relay":                result.RelayURL,
+			}).WithError(result.Error).Error("failed to publish response event to relay")
+		}
+	}
+}
+
 func (svc *nip47Service) publishResponseEvent(ctx context.Context, pool nostrmodels.SimplePool, requestEvent *db.RequestEvent, resp *nostr.Event, app *db.App) error {
 	var appId *uint
 	if app != nil {
@@ -500,6 +615,7 @@ func (svc *nip47Service) publishResponseEvent(ctx context.Context, pool nostrmod
 	}
 
 	updateColumns := make(map[string]interface{})
+	publishStart := time.Now()
 	publishResultChannel := pool.PublishMany(ctx, svc.cfg.GetRelayUrls(), *resp)
 
 	publishSuccessful := false
@@ -518,6 +634,8 @@ func (svc *nip47Service) publishResponseEvent(ctx context.Context, pool nostrmod
 		}
 	}
 
+	metrics.RecordNip47ResponsePublish(time.Since(publishStart), publishSuccessful)
+
 	if !publishSuccessful {
 		updateColumns["state"] = db.RESPONSE_EVENT_STATE_PUBLISH_FAILED
 		logger.Logger.WithFields(logrus.Fields{
@@ -51,7 +51,7 @@ func doTestCreateResponse(t *testing.T, svc *tests.TestService, nip47Encryption
 
 	reqEvent.ID = "12345"
 
-	nip47Cipher, err := cipher.NewNip47Cipher(nip47Encryption, reqPubkey, svc.Keys.GetNostrSecretKey())
+	nip47Cipher, err := cipher.NewNip47CipherWithSigner(nip47Encryption, reqPubkey, svc.Keys.GetNostrSigner())
 	assert.NoError(t, err)
 
 	type dummyResponse struct {
@@ -68,7 +68,7 @@ func doTestCreateResponse(t *testing.T, svc *tests.TestService, nip47Encryption
 	albyOAuthSvc := alby.NewAlbyOAuthService(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
 	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher, albyOAuthSvc)
 
-	res, err := nip47svc.CreateResponse(reqEvent, nip47Response, nostr.Tags{}, nip47Cipher, svc.Keys.GetNostrSecretKey())
+	res, err := nip47svc.CreateResponse(reqEvent, nip47Response, nostr.Tags{}, nip47Cipher, svc.Keys.GetNostrSigner())
 	assert.NoError(t, err)
 	assert.Equal(t, reqPubkey, res.Tags.Find("p")[1])
 	assert.Equal(t, reqEvent.ID, res.Tags.Find("e")[1])
@@ -666,3 +666,135 @@ func doTestHandleResponse_EncryptionTagDoesNotMatchPayload(t *testing.T, svc *te
 	assert.Equal(t, constants.ERROR_BAD_REQUEST, unmarshalledResponse.Error.Code)
 	assert.Contains(t, unmarshalledResponse.Error.Message, "failed to decrypt:")
 }
+
+func TestHandleResponse_RateLimited(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	albyOAuthSvc := alby.NewAlbyOAuthService(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher, albyOAuthSvc)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	reqPubkey, err := nostr.GetPublicKey(reqPrivateKey)
+	require.NoError(t, err)
+
+	app, cipher, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey, constants.ENCRYPTION_TYPE_NIP44_V2)
+	require.NoError(t, err)
+
+	err = svc.DB.Model(app).Update("metadata", `{"rate_limit_requests_per_minute": 1, "rate_limit_burst": 1}`).Error
+	require.NoError(t, err)
+
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"method": models.GET_INFO_METHOD,
+	})
+	require.NoError(t, err)
+
+	pool := tests.NewMockSimplePool()
+
+	for i := 0; i < 2; i++ {
+		msg, err := cipher.Encrypt(string(payloadBytes))
+		require.NoError(t, err)
+
+		reqEvent := &nostr.Event{
+			Kind:      models.REQUEST_KIND,
+			PubKey:    reqPubkey,
+			CreatedAt: nostr.Now(),
+			Tags:      nostr.Tags{[]string{"encryption", constants.ENCRYPTION_TYPE_NIP44_V2}},
+			Content:   msg,
+		}
+		err = reqEvent.Sign(reqPrivateKey)
+		require.NoError(t, err)
+
+		nip47svc.HandleEvent(context.TODO(), pool, reqEvent, svc.LNClient)
+	}
+
+	require.Equal(t, 2, len(pool.PublishedEvents))
+
+	decrypted, err := cipher.Decrypt(pool.PublishedEvents[0].Content)
+	require.NoError(t, err)
+	response := models.Response{}
+	err = json.Unmarshal([]byte(decrypted), &response)
+	require.NoError(t, err)
+	assert.Nil(t, response.Error)
+
+	decrypted, err = cipher.Decrypt(pool.PublishedEvents[1].Content)
+	require.NoError(t, err)
+	response = models.Response{}
+	err = json.Unmarshal([]byte(decrypted), &response)
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, constants.ERROR_RATE_LIMITED, response.Error.Code)
+
+	// the rate limited request is not stored
+	var dbApp db.App
+	err = svc.DB.First(&dbApp, app.ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), dbApp.RequestCount)
+	assert.Equal(t, uint64(1), dbApp.RateLimitedCount)
+	assert.NotNil(t, dbApp.LastRateLimitedAt)
+
+	var requestEventCount int64
+	err = svc.DB.Model(&db.RequestEvent{}).Count(&requestEventCount).Error
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requestEventCount)
+}
+
+func TestHandleResponse_RateLimitedByDefault(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	albyOAuthSvc := alby.NewAlbyOAuthService(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
+	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher, albyOAuthSvc)
+
+	reqPrivateKey := nostr.GeneratePrivateKey()
+	reqPubkey, err := nostr.GetPublicKey(reqPrivateKey)
+	require.NoError(t, err)
+
+	_, cipher, err := tests.CreateAppWithPrivateKey(svc, reqPrivateKey, constants.ENCRYPTION_TYPE_NIP44_V2)
+	require.NoError(t, err)
+
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"method": models.GET_INFO_METHOD,
+	})
+	require.NoError(t, err)
+
+	pool := tests.NewMockSimplePool()
+
+	// a few more requests than the burst, as tokens are refilled while the requests are handled
+	requestCount := constants.DEFAULT_RATE_LIMIT_BURST + 5
+	for i := 0; i < requestCount; i++ {
+		msg, err := cipher.Encrypt(string(payloadBytes))
+		require.NoError(t, err)
+
+		reqEvent := &nostr.Event{
+			Kind:      models.REQUEST_KIND,
+			PubKey:    reqPubkey,
+			CreatedAt: nostr.Now(),
+			Tags:      nostr.Tags{[]string{"encryption", constants.ENCRYPTION_TYPE_NIP44_V2}},
+			Content:   msg,
+		}
+		err = reqEvent.Sign(reqPrivateKey)
+		require.NoError(t, err)
+
+		nip47svc.HandleEvent(context.TODO(), pool, reqEvent, svc.LNClient)
+	}
+
+	// the app has no rate limit in its metadata, so the default burst applies
+	require.Equal(t, requestCount, len(pool.PublishedEvents))
+	for i, publishedEvent := range pool.PublishedEvents {
+		decrypted, err := cipher.Decrypt(publishedEvent.Content)
+		require.NoError(t, err)
+		response := models.Response{}
+		err = json.Unmarshal([]byte(decrypted), &response)
+		require.NoError(t, err)
+		if i < constants.DEFAULT_RATE_LIMIT_BURST {
+			assert.Nil(t, response.Error)
+		}
+		if i == requestCount-1 {
+			require.NotNil(t, response.Error)
+			assert.Equal(t, constants.ERROR_RATE_LIMITED, response.Error.Code)
+		}
+	}
+}
@@ -27,6 +27,16 @@ const (
 	MAKE_HOLD_INVOICE_METHOD   = "make_hold_invoice"
 	CANCEL_HOLD_INVOICE_METHOD = "cancel_hold_invoice"
 	SETTLE_HOLD_INVOICE_METHOD = "settle_hold_invoice"
+	PAY_OFFER_METHOD           = "pay_offer"
+	PAY_ONCHAIN_METHOD         = "pay_onchain"
+
+	// node admin methods
+	LIST_CHANNELS_METHOD       = "list_channels"
+	OPEN_CHANNEL_METHOD        = "open_channel"
+	CLOSE_CHANNEL_METHOD       = "close_channel"
+	UPDATE_CHANNEL_FEES_METHOD = "update_channel_fees"
+	GET_ONCHAIN_BALANCE_METHOD = "get_onchain_balance"
+	GET_NEW_ADDRESS_METHOD     = "get_new_address"
 )
 
 type Transaction struct {
@@ -44,6 +54,9 @@ type Transaction struct {
 	SettledAt       *int64      `json:"settled_at"`
 	SettleDeadline  *uint32     `json:"settle_deadline"` // block number for accepted hold invoices
 	Metadata        interface{} `json:"metadata,omitempty"`
+	Address         string      `json:"address,omitempty"` // on-chain payments only
+	TxId            string      `json:"txid,omitempty"`    // on-chain payments only
+	Kind            string      `json:"kind,omitempty"`    // only set when listing the unified activity feed
 }
 
 type PayRequest struct {
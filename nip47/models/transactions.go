@@ -22,7 +22,10 @@ func ToNip47Transaction(transaction *transactions.Transaction) *Transaction {
 	if transaction.SettledAt != nil {
 		settledAtUnix := transaction.SettledAt.Unix()
 		settledAt = &settledAtUnix
-		preimage = *transaction.Preimage
+		// on-chain payments have no preimage
+		if transaction.Preimage != nil {
+			preimage = *transaction.Preimage
+		}
 	}
 
 	state := strings.ToLower(transaction.State)
@@ -56,5 +59,35 @@ func ToNip47Transaction(transaction *transactions.Transaction) *Transaction {
 		SettledAt:       settledAt,
 		Metadata:        metadata,
 		SettleDeadline:  transaction.SettleDeadline,
+		Address:         transaction.OnchainAddress,
+		TxId:            transaction.OnchainTxId,
+	}
+}
+
+// ToNip47ActivityItem converts an entry of the unified activity feed.
+// Entries which are not stored as transactions (e.g. swaps) have no invoice or preimage.
+func ToNip47ActivityItem(item *transactions.ActivityItem) *Transaction {
+	if item.Transaction != nil {
+		transaction := ToNip47Transaction(item.Transaction)
+		transaction.Kind = item.Kind
+		return transaction
+	}
+
+	var settledAt *int64
+	if item.SettledAt != nil {
+		settledAtUnix := item.SettledAt.Unix()
+		settledAt = &settledAtUnix
+	}
+
+	return &Transaction{
+		Kind:        item.Kind,
+		Type:        item.Type,
+		State:       strings.ToLower(item.State),
+		Description: item.Description,
+		Amount:      int64(item.AmountMsat),
+		FeesPaid:    int64(item.FeeMsat),
+		CreatedAt:   item.CreatedAt.Unix(),
+		SettledAt:   settledAt,
+		TxId:        item.TxId,
 	}
 }
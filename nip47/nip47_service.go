@@ -33,6 +33,7 @@ type nip47Service struct {
 	keys                   keys.Keys
 	db                     *gorm.DB
 	eventPublisher         events.EventPublisher
+	rateLimiter            *appRateLimiter
 }
 
 type Nip47Service interface {
@@ -41,10 +42,10 @@ type Nip47Service interface {
 	StartNip47InfoPublisher(ctx context.Context, pool *nostr.SimplePool, lnClient lnclient.LNClient)
 	HandleEvent(ctx context.Context, pool nostrmodels.SimplePool, event *nostr.Event, lnClient lnclient.LNClient)
 	GetNip47Info(ctx context.Context, pool nostrmodels.SimplePool, appWalletPubKey string) (*nostr.Event, error)
-	PublishNip47Info(ctx context.Context, pool nostrmodels.SimplePool, appId uint, appWalletPubKey string, appWalletPrivKey string, relayUrl string, lnClient lnclient.LNClient) (*nostr.Event, error)
-	PublishNip47InfoDeletion(ctx context.Context, pool nostrmodels.SimplePool, appWalletPubKey string, appWalletPrivKey string, infoEventId string) error
-	CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, cipher *cipher.Nip47Cipher, walletPrivKey string) (result *nostr.Event, err error)
-	EnqueueNip47InfoPublishRequest(appId uint, appWalletPubKey, appWalletPrivKey, relayUrl string)
+	PublishNip47Info(ctx context.Context, pool nostrmodels.SimplePool, appId uint, appWalletPubKey string, appWalletSigner keys.Signer, relayUrl string, lnClient lnclient.LNClient) (*nostr.Event, error)
+	PublishNip47InfoDeletion(ctx context.Context, pool nostrmodels.SimplePool, appWalletPubKey string, appWalletSigner keys.Signer, infoEventId string) error
+	CreateResponse(initialEvent *nostr.Event, content interface{}, tags nostr.Tags, cipher *cipher.Nip47Cipher, walletSigner keys.Signer) (result *nostr.Event, err error)
+	EnqueueNip47InfoPublishRequest(appId uint, appWalletPubKey string, appWalletSigner keys.Signer, relayUrl string)
 }
 
 func NewNip47Service(db *gorm.DB, cfg config.Config, keys keys.Keys, eventPublisher events.EventPublisher, albyOAuthSvc alby.AlbyOAuthService) *nip47Service {
@@ -59,6 +60,7 @@ func NewNip47Service(db *gorm.DB, cfg config.Config, keys keys.Keys, eventPublis
 		eventPublisher:         eventPublisher,
 		keys:                   keys,
 		albyOAuthSvc:           albyOAuthSvc,
+		rateLimiter:            newAppRateLimiter(),
 	}
 }
 
@@ -91,17 +93,17 @@ func (svc *nip47Service) StartNotifier(ctx context.Context, pool *nostr.SimplePo
 	}()
 }
 
-func (svc *nip47Service) EnqueueNip47InfoPublishRequest(appId uint, appWalletPubKey, appWalletPrivKey, relayUrl string) {
-	svc.enqueueNip47InfoPublishRequestWithAttempt(appId, appWalletPubKey, appWalletPrivKey, relayUrl, 0)
+func (svc *nip47Service) EnqueueNip47InfoPublishRequest(appId uint, appWalletPubKey string, appWalletSigner keys.Signer, relayUrl string) {
+	svc.enqueueNip47InfoPublishRequestWithAttempt(appId, appWalletPubKey, appWalletSigner, relayUrl, 0)
 }
 
-func (svc *nip47Service) enqueueNip47InfoPublishRequestWithAttempt(appId uint, appWalletPubKey, appWalletPrivKey, relayUrl string, attempt uint32) {
+func (svc *nip47Service) enqueueNip47InfoPublishRequestWithAttempt(appId uint, appWalletPubKey string, appWalletSigner keys.Signer, relayUrl string, attempt uint32) {
 	svc.nip47InfoPublishQueue.AddToQueue(&Nip47InfoPublishRequest{
-		AppId:            appId,
-		AppWalletPubKey:  appWalletPubKey,
-		AppWalletPrivKey: appWalletPrivKey,
-		RelayUrl:         relayUrl,
-		Attempt:          attempt,
+		AppId:           appId,
+		AppWalletPubKey: appWalletPubKey,
+		AppWalletSigner: appWalletSigner,
+		RelayUrl:        relayUrl,
+		Attempt:         attempt,
 	})
 }
 
@@ -113,7 +115,7 @@ func (svc *nip47Service) StartNip47InfoPublisher(ctx context.Context, pool *nost
 				// relay disconnected
 				return
 			case req := <-svc.nip47InfoPublishQueue.Channel():
-				_, err := svc.PublishNip47Info(ctx, pool, req.AppId, req.AppWalletPubKey, req.AppWalletPrivKey, req.RelayUrl, lnClient)
+				_, err := svc.PublishNip47Info(ctx, pool, req.AppId, req.AppWalletPubKey, req.AppWalletSigner, req.RelayUrl, lnClient)
 				if err != nil {
 					// the app connection no longer exists (e.g. it was deleted),
 					// so the info event can never be published - drop the item
@@ -137,7 +139,7 @@ func (svc *nip47Service) StartNip47InfoPublisher(ctx context.Context, pool *nost
 					// the publishing of newly created app connections
 					go func() {
 						time.Sleep((5 * time.Duration(req.Attempt+1)) * time.Second)
-						svc.enqueueNip47InfoPublishRequestWithAttempt(req.AppId, req.AppWalletPubKey, req.AppWalletPrivKey, req.RelayUrl, req.Attempt+1)
+						svc.enqueueNip47InfoPublishRequestWithAttempt(req.AppId, req.AppWalletPubKey, req.AppWalletSigner, req.RelayUrl, req.Attempt+1)
 					}()
 				}
 			}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/getAlby/go-nostr"
 	"github.com/getAlby/hub/config"
@@ -89,10 +91,155 @@ func (notifier *Nip47Notifier) ConsumeEvent(ctx context.Context, event *events.E
 			Notification:     notification,
 			NotificationType: HOLD_INVOICE_ACCEPTED_NOTIFICATION,
 		}, nostr.Tags{}, dbTransaction.AppId)
+
+	case "nwc_payment_approval_rejected":
+		paymentApproval, ok := event.Properties.(*db.PaymentApproval)
+		if !ok {
+			logger.Logger.WithField("event", event).Error("Failed to cast event properties to db.PaymentApproval")
+			return errors.New("failed to cast event")
+		}
+
+		return notifier.answerRejectedPaymentApproval(ctx, paymentApproval)
 	}
 	return nil
 }
 
+// answerRejectedPaymentApproval responds to the request of a payment which was
+// held for approval, once the owner rejected it or the approval expired.
+// The app was already told that the payment is pending approval.
+func (notifier *Nip47Notifier) answerRejectedPaymentApproval(ctx context.Context, paymentApproval *db.PaymentApproval) error {
+	if paymentApproval.RequestEventId == nil {
+		// the request event was cleaned up, so there is nothing to answer
+		return nil
+	}
+
+	var requestEvent db.RequestEvent
+	err := notifier.db.Limit(1).Find(&requestEvent, *paymentApproval.RequestEventId).Error
+	if err != nil {
+		return err
+	}
+	var app db.App
+	result := notifier.db.Limit(1).Find(&app, paymentApproval.AppId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 || requestEvent.ID == 0 {
+		return nil
+	}
+
+	message := "The payment was rejected"
+	if paymentApproval.State == constants.PAYMENT_APPROVAL_STATE_EXPIRED {
+		message = "The payment approval expired"
+	}
+	response := &models.Response{
+		ResultType: requestEvent.Method,
+		Error: &models.Error{
+			Code:    constants.ERROR_RESTRICTED,
+			Message: message,
+		},
+	}
+
+	tags := nostr.Tags{[]string{"p", app.AppPubkey}, []string{"e", requestEvent.NostrId}}
+	if requestEvent.Method == models.MULTI_PAY_INVOICE_METHOD {
+		tags = append(tags, []string{"d", getMultiPayInvoiceDTag(requestEvent.ContentData, paymentApproval)})
+	}
+
+	appWalletSigner := notifier.keys.GetNostrSigner()
+	if app.WalletPubkey != nil {
+		appWalletSigner, err = notifier.keys.GetAppWalletSigner(app.ID)
+		if err != nil {
+			logger.Logger.WithField("appId", app.ID).WithError(err).Error("error deriving child key")
+			return errors.New("failed to derive child key")
+		}
+	}
+
+	encryption := requestEvent.Encryption
+	if encryption == "" {
+		encryption = constants.ENCRYPTION_TYPE_NIP04
+	}
+	nip47Cipher, err := cipher.NewNip47CipherWithSigner(encryption, app.AppPubkey, appWalletSigner)
+	if err != nil {
+		return err
+	}
+
+	payloadBytes, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	msg, err := nip47Cipher.Encrypt(string(payloadBytes))
+	if err != nil {
+		return err
+	}
+
+	resp := &nostr.Event{
+		PubKey:    appWalletSigner.GetPublicKey(),
+		CreatedAt: nostr.Now(),
+		Kind:      models.RESPONSE_KIND,
+		Tags:      tags,
+		Content:   msg,
+	}
+	err = appWalletSigner.SignEvent(resp)
+	if err != nil {
+		return err
+	}
+
+	publishSuccessful := false
+	for result := range notifier.pool.PublishMany(ctx, notifier.cfg.GetRelayUrls(), *resp) {
+		if result.Error == nil {
+			publishSuccessful = true
+		} else {
+			logger.Logger.WithFields(logrus.Fields{
+				"payment_approval_id": paymentApproval.ID,
+				"appId":               app.ID,
+				"relay":               result.RelayURL,
+			}).WithError(result.Error).Error("failed to publish payment approval response to relay")
+		}
+	}
+	if !publishSuccessful {
+		return errors.New("failed to publish payment approval response")
+	}
+
+	responseEvent := db.ResponseEvent{
+		NostrId:   resp.ID,
+		RequestId: requestEvent.ID,
+		State:     db.RESPONSE_EVENT_STATE_PUBLISH_CONFIRMED,
+		RepliedAt: time.Now(),
+	}
+	err = notifier.db.Create(&responseEvent).Error
+	if err != nil {
+		logger.Logger.WithField("payment_approval_id", paymentApproval.ID).WithError(err).Error("Failed to save payment approval response event")
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"payment_approval_id":  paymentApproval.ID,
+		"appId":                app.ID,
+		"responseNostrEventId": resp.ID,
+	}).Info("Answered request of rejected payment")
+	return nil
+}
+
+// getMultiPayInvoiceDTag returns the id the app gave the invoice of the approval,
+// or its payment hash, as used by the multi_pay_invoice controller
+func getMultiPayInvoiceDTag(contentData string, paymentApproval *db.PaymentApproval) string {
+	request := struct {
+		Params struct {
+			Invoices []struct {
+				Invoice string `json:"invoice"`
+				Id      string `json:"id"`
+			} `json:"invoices"`
+		} `json:"params"`
+	}{}
+	err := json.Unmarshal([]byte(contentData), &request)
+	if err == nil {
+		for _, invoice := range request.Params.Invoices {
+			if invoice.Id != "" && strings.EqualFold(invoice.Invoice, paymentApproval.PaymentRequest) {
+				return invoice.Id
+			}
+		}
+	}
+	return paymentApproval.PaymentHash
+}
+
 func (notifier *Nip47Notifier) notifySubscribers(ctx context.Context, notification *Notification, tags nostr.Tags, appId *uint) error {
 	apps := []db.App{}
 
@@ -113,9 +260,9 @@ func (notifier *Nip47Notifier) notifySubscribers(ctx context.Context, notificati
 			continue
 		}
 
-		appWalletPrivKey := notifier.keys.GetNostrSecretKey()
+		appWalletSigner := notifier.keys.GetNostrSigner()
 		if app.WalletPubkey != nil {
-			appWalletPrivKey, err = notifier.keys.GetAppWalletKey(app.ID)
+			appWalletSigner, err = notifier.keys.GetAppWalletSigner(app.ID)
 			if err != nil {
 				logger.Logger.WithFields(logrus.Fields{
 					"notification": notification,
@@ -125,21 +272,12 @@ func (notifier *Nip47Notifier) notifySubscribers(ctx context.Context, notificati
 			}
 		}
 
-		appWalletPubKey, err := nostr.GetPublicKey(appWalletPrivKey)
-		if err != nil {
-			logger.Logger.WithFields(logrus.Fields{
-				"notification": notification,
-				"appId":        app.ID,
-			}).WithError(err).Error("Failed to calculate app wallet pub key")
-			return errors.New("failed to calculate app wallet pubkey")
-		}
-
-		err = notifier.notifySubscriber(ctx, &app, notification, tags, appWalletPubKey, appWalletPrivKey, constants.ENCRYPTION_TYPE_NIP04)
+		err = notifier.notifySubscriber(ctx, &app, notification, tags, appWalletSigner, constants.ENCRYPTION_TYPE_NIP04)
 		if err != nil {
 			logger.Logger.WithError(err).Error("failed to notify subscriber (NIP-04)")
 			return err
 		}
-		err = notifier.notifySubscriber(ctx, &app, notification, tags, appWalletPubKey, appWalletPrivKey, constants.ENCRYPTION_TYPE_NIP44_V2)
+		err = notifier.notifySubscriber(ctx, &app, notification, tags, appWalletSigner, constants.ENCRYPTION_TYPE_NIP44_V2)
 		if err != nil {
 			logger.Logger.WithError(err).Error("failed to notify subscriber (NIP-44)")
 			return err
@@ -148,7 +286,7 @@ func (notifier *Nip47Notifier) notifySubscribers(ctx context.Context, notificati
 	return nil
 }
 
-func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App, notification *Notification, tags nostr.Tags, appWalletPubKey, appWalletPrivKey string, encryption string) error {
+func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App, notification *Notification, tags nostr.Tags, appWalletSigner keys.Signer, encryption string) error {
 	logger.Logger.WithFields(logrus.Fields{
 		"notification": notification,
 		"appId":        app.ID,
@@ -167,7 +305,7 @@ func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App
 		return err
 	}
 
-	nip47Cipher, err := cipher.NewNip47Cipher(encryption, app.AppPubkey, appWalletPrivKey)
+	nip47Cipher, err := cipher.NewNip47CipherWithSigner(encryption, app.AppPubkey, appWalletSigner)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"notification": notification,
@@ -191,7 +329,7 @@ func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App
 	allTags = append(allTags, tags...)
 
 	event := &nostr.Event{
-		PubKey:    appWalletPubKey,
+		PubKey:    appWalletSigner.GetPublicKey(),
 		CreatedAt: nostr.Now(),
 		Kind:      models.NOTIFICATION_KIND,
 		Tags:      allTags,
@@ -202,7 +340,7 @@ func (notifier *Nip47Notifier) notifySubscriber(ctx context.Context, app *db.App
 		event.Kind = models.LEGACY_NOTIFICATION_KIND
 	}
 
-	err = event.Sign(appWalletPrivKey)
+	err = appWalletSigner.SignEvent(event)
 	if err != nil {
 		logger.Logger.WithFields(logrus.Fields{
 			"notification": notification,
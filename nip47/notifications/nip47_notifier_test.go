@@ -14,6 +14,7 @@ import (
 	"github.com/getAlby/hub/db"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/nip47/models"
 	"github.com/getAlby/hub/nip47/permissions"
 	"github.com/getAlby/hub/tests"
 )
@@ -310,3 +311,90 @@ func TestSendNotification_SharedWalletPubkey_NoPermission(t *testing.T) {
 	assert.NoError(t, err)
 	doTestSendNotificationNoPermission(t, svc)
 }
+
+func doTestAnswerRejectedPaymentApproval(t *testing.T, svc *tests.TestService, nip47Encryption string, method string, contentData string, expectedDTag string) {
+	ctx := context.TODO()
+
+	app, cipher, err := tests.CreateAppWithPrivateKey(svc, nostr.GeneratePrivateKey(), nip47Encryption)
+	require.NoError(t, err)
+
+	requestEvent := db.RequestEvent{
+		AppId:       &app.ID,
+		NostrId:     "request-nostr-id",
+		Method:      method,
+		ContentData: contentData,
+		Encryption:  nip47Encryption,
+	}
+	err = svc.DB.Create(&requestEvent).Error
+	require.NoError(t, err)
+
+	paymentApproval := db.PaymentApproval{
+		AppId:          app.ID,
+		RequestEventId: &requestEvent.ID,
+		State:          constants.PAYMENT_APPROVAL_STATE_EXPIRED,
+		PaymentRequest: tests.MockLNClientTransaction.Invoice,
+		PaymentHash:    tests.MockPaymentHash,
+		AmountMsat:     123000,
+		ExpiresAt:      time.Now(),
+	}
+	err = svc.DB.Omit("App", "RequestEvent").Create(&paymentApproval).Error
+	require.NoError(t, err)
+
+	pool := tests.NewMockSimplePool()
+	permissionsSvc := permissions.NewPermissionsService(svc.DB, svc.EventPublisher)
+	notifier := NewNip47Notifier(pool, svc.DB, svc.Cfg, svc.Keys, permissionsSvc)
+	err = notifier.ConsumeEvent(ctx, &events.Event{
+		Event:      "nwc_payment_approval_rejected",
+		Properties: &paymentApproval,
+	})
+	require.NoError(t, err)
+
+	// only the held request is answered, without notifying subscribers
+	require.Equal(t, 1, len(pool.PublishedEvents))
+	publishedEvent := pool.PublishedEvents[0]
+	assert.Equal(t, models.RESPONSE_KIND, publishedEvent.Kind)
+	assert.Equal(t, requestEvent.NostrId, publishedEvent.Tags.Find("e")[1])
+	assert.Equal(t, app.AppPubkey, publishedEvent.Tags.Find("p")[1])
+	if expectedDTag != "" {
+		assert.Equal(t, expectedDTag, publishedEvent.Tags.Find("d")[1])
+	}
+
+	decrypted, err := cipher.Decrypt(publishedEvent.Content)
+	require.NoError(t, err)
+	response := models.Response{}
+	err = json.Unmarshal([]byte(decrypted), &response)
+	require.NoError(t, err)
+	assert.Equal(t, method, response.ResultType)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, constants.ERROR_RESTRICTED, response.Error.Code)
+	assert.Equal(t, "The payment approval expired", response.Error.Message)
+
+	var responseEventCount int64
+	svc.DB.Model(&db.ResponseEvent{}).Where("request_id = ?", requestEvent.ID).Count(&responseEventCount)
+	assert.Equal(t, int64(1), responseEventCount)
+}
+
+func TestAnswerRejectedPaymentApproval_Nip04(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	doTestAnswerRejectedPaymentApproval(t, svc, constants.ENCRYPTION_TYPE_NIP04, models.PAY_INVOICE_METHOD, "", "")
+}
+
+func TestAnswerRejectedPaymentApproval_Nip44(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	doTestAnswerRejectedPaymentApproval(t, svc, constants.ENCRYPTION_TYPE_NIP44_V2, models.PAY_INVOICE_METHOD, "", "")
+}
+
+func TestAnswerRejectedPaymentApproval_MultiPayInvoice(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	contentData := `{"method":"multi_pay_invoice","params":{"invoices":[{"invoice":"` + tests.MockLNClientTransaction.Invoice + `","id":"invoice-1"}]}}`
+	doTestAnswerRejectedPaymentApproval(t, svc, constants.ENCRYPTION_TYPE_NIP44_V2, models.MULTI_PAY_INVOICE_METHOD, contentData, "invoice-1")
+}
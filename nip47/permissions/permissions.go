@@ -117,7 +117,7 @@ func scopesToRequestMethods(scopes []string) []string {
 func scopeToRequestMethods(scope string) []string {
 	switch scope {
 	case constants.PAY_INVOICE_SCOPE:
-		return []string{models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD}
+		return []string{models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD, models.PAY_OFFER_METHOD}
 	case constants.GET_BALANCE_SCOPE:
 		return []string{models.GET_BALANCE_METHOD}
 	case constants.GET_INFO_SCOPE:
@@ -132,6 +132,10 @@ func scopeToRequestMethods(scope string) []string {
 		return []string{models.SIGN_MESSAGE_METHOD}
 	case constants.SUPERUSER_SCOPE:
 		return []string{models.CREATE_CONNECTION_METHOD}
+	case constants.NODE_ADMIN_SCOPE:
+		return GetNodeAdminMethods()
+	case constants.PAY_ONCHAIN_SCOPE:
+		return []string{models.PAY_ONCHAIN_METHOD}
 	}
 	return []string{}
 }
@@ -153,7 +157,7 @@ func RequestMethodsToScopes(requestMethods []string) ([]string, error) {
 
 func RequestMethodToScope(requestMethod string) (string, error) {
 	switch requestMethod {
-	case models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD:
+	case models.PAY_INVOICE_METHOD, models.PAY_KEYSEND_METHOD, models.MULTI_PAY_INVOICE_METHOD, models.MULTI_PAY_KEYSEND_METHOD, models.PAY_OFFER_METHOD:
 		return constants.PAY_INVOICE_SCOPE, nil
 	case models.GET_BALANCE_METHOD:
 		return constants.GET_BALANCE_SCOPE, nil
@@ -173,6 +177,10 @@ func RequestMethodToScope(requestMethod string) (string, error) {
 		return constants.MAKE_INVOICE_SCOPE, nil
 	case models.CREATE_CONNECTION_METHOD:
 		return constants.SUPERUSER_SCOPE, nil
+	case models.LIST_CHANNELS_METHOD, models.OPEN_CHANNEL_METHOD, models.CLOSE_CHANNEL_METHOD, models.UPDATE_CHANNEL_FEES_METHOD, models.GET_ONCHAIN_BALANCE_METHOD, models.GET_NEW_ADDRESS_METHOD:
+		return constants.NODE_ADMIN_SCOPE, nil
+	case models.PAY_ONCHAIN_METHOD:
+		return constants.PAY_ONCHAIN_SCOPE, nil
 	}
 	logger.Logger.WithField("request_method", requestMethod).Error("Unsupported request method")
 	return "", fmt.Errorf("unsupported request method: %s", requestMethod)
@@ -189,9 +197,24 @@ func AllScopes() []string {
 		constants.SIGN_MESSAGE_SCOPE,
 		constants.NOTIFICATIONS_SCOPE,
 		constants.SUPERUSER_SCOPE,
+		constants.NODE_ADMIN_SCOPE,
+		constants.PAY_ONCHAIN_SCOPE,
 	}
 }
 
 func GetAlwaysGrantedMethods() []string {
 	return []string{models.GET_INFO_METHOD, models.GET_BUDGET_METHOD}
 }
+
+// GetNodeAdminMethods returns the methods which give an app control over
+// the node's channels and on-chain funds
+func GetNodeAdminMethods() []string {
+	return []string{
+		models.LIST_CHANNELS_METHOD,
+		models.OPEN_CHANNEL_METHOD,
+		models.CLOSE_CHANNEL_METHOD,
+		models.UPDATE_CHANNEL_FEES_METHOD,
+		models.GET_ONCHAIN_BALANCE_METHOD,
+		models.GET_NEW_ADDRESS_METHOD,
+	}
+}
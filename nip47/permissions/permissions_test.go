@@ -162,3 +162,10 @@ func TestGetPermittedMethods_PayInvoiceScopeGivesAllPaymentMethods(t *testing.T)
 	assert.Contains(t, result, models.MULTI_PAY_INVOICE_METHOD)
 	assert.Contains(t, result, models.MULTI_PAY_KEYSEND_METHOD)
 }
+
+func TestRequestMethodsToScopes_NodeAdmin(t *testing.T) {
+	scopes, err := RequestMethodsToScopes(GetNodeAdminMethods())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{constants.NODE_ADMIN_SCOPE}, scopes)
+	assert.Equal(t, GetNodeAdminMethods(), scopeToRequestMethods(constants.NODE_ADMIN_SCOPE))
+}
@@ -13,15 +13,16 @@ import (
 	"github.com/getAlby/hub/nip47/cipher"
 	"github.com/getAlby/hub/nip47/models"
 	nostrmodels "github.com/getAlby/hub/nostr/models"
+	"github.com/getAlby/hub/service/keys"
 	"github.com/sirupsen/logrus"
 )
 
 type Nip47InfoPublishRequest struct {
-	AppId            uint
-	AppWalletPubKey  string
-	AppWalletPrivKey string
-	RelayUrl         string
-	Attempt          uint32
+	AppId           uint
+	AppWalletPubKey string
+	AppWalletSigner keys.Signer
+	RelayUrl        string
+	Attempt         uint32
 }
 
 type nip47InfoPublishQueue struct {
@@ -60,7 +61,7 @@ func (svc *nip47Service) GetNip47Info(ctx context.Context, pool nostrmodels.Simp
 	return relayEvent.Event, nil
 }
 
-func (svc *nip47Service) PublishNip47Info(ctx context.Context, pool nostrmodels.SimplePool, appId uint, appWalletPubKey string, appWalletPrivKey string, relayUrl string, lnClient lnclient.LNClient) (*nostr.Event, error) {
+func (svc *nip47Service) PublishNip47Info(ctx context.Context, pool nostrmodels.SimplePool, appId uint, appWalletPubKey string, appWalletSigner keys.Signer, relayUrl string, lnClient lnclient.LNClient) (*nostr.Event, error) {
 	var capabilities []string
 	var permitsNotifications bool
 	tags := nostr.Tags{[]string{"encryption", cipher.SUPPORTED_ENCRYPTIONS}}
@@ -95,7 +96,7 @@ func (svc *nip47Service) PublishNip47Info(ctx context.Context, pool nostrmodels.
 	ev.CreatedAt = nostr.Now()
 	ev.PubKey = appWalletPubKey
 	ev.Tags = tags
-	err := ev.Sign(appWalletPrivKey)
+	err := appWalletSigner.SignEvent(ev)
 	if err != nil {
 		return nil, err
 	}
@@ -121,14 +122,14 @@ func (svc *nip47Service) PublishNip47Info(ctx context.Context, pool nostrmodels.
 	return ev, nil
 }
 
-func (svc *nip47Service) PublishNip47InfoDeletion(ctx context.Context, pool nostrmodels.SimplePool, appWalletPubKey string, appWalletPrivKey string, infoEventId string) error {
+func (svc *nip47Service) PublishNip47InfoDeletion(ctx context.Context, pool nostrmodels.SimplePool, appWalletPubKey string, appWalletSigner keys.Signer, infoEventId string) error {
 	ev := &nostr.Event{}
 	ev.Kind = nostr.KindDeletion
 	ev.Content = "deleting nip47 info since app connection for this key was deleted"
 	ev.Tags = nostr.Tags{[]string{"e", infoEventId}, []string{"k", strconv.Itoa(models.INFO_EVENT_KIND)}}
 	ev.CreatedAt = nostr.Now()
 	ev.PubKey = appWalletPubKey
-	err := ev.Sign(appWalletPrivKey)
+	err := appWalletSigner.SignEvent(ev)
 	if err != nil {
 		return err
 	}
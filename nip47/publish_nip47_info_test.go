@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/service/keys"
 	"github.com/getAlby/hub/tests"
 )
 
@@ -24,13 +25,12 @@ func TestPublishNip47Info_AppNotFound(t *testing.T) {
 	albyOAuthSvc := alby.NewAlbyOAuthService(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher)
 	nip47svc := NewNip47Service(svc.DB, svc.Cfg, svc.Keys, svc.EventPublisher, albyOAuthSvc)
 
-	walletPrivKey := nostr.GeneratePrivateKey()
-	walletPubKey, err := nostr.GetPublicKey(walletPrivKey)
+	walletSigner, err := keys.NewSoftwareSigner(nostr.GeneratePrivateKey())
 	require.NoError(t, err)
 
 	// app id 9999999 does not exist; the DB lookup fails before the relay pool
 	// is ever used, so a nil pool/lnClient is fine here.
-	_, err = nip47svc.PublishNip47Info(context.Background(), nil, 9999999, walletPubKey, walletPrivKey, "wss://relay.example.com", nil)
+	_, err = nip47svc.PublishNip47Info(context.Background(), nil, 9999999, walletSigner.GetPublicKey(), walletSigner, "wss://relay.example.com", nil)
 	require.Error(t, err)
 	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
 }
@@ -0,0 +1,111 @@
+package nip47
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+	"github.com/sirupsen/logrus"
+)
+
+type appRateLimit struct {
+	requestsPerMinute uint
+	burst             uint
+}
+
+type tokenBucket struct {
+	limit      appRateLimit
+	tokens     float64
+	lastRefill time.Time
+}
+
+// appRateLimiter keeps an in-memory token bucket per app to limit
+// how many NIP-47 requests a single connection can make
+type appRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*tokenBucket
+}
+
+func newAppRateLimiter() *appRateLimiter {
+	return &appRateLimiter{
+		buckets: make(map[uint]*tokenBucket),
+	}
+}
+
+// allow takes a token from the app's bucket, returning false if the bucket is empty
+func (l *appRateLimiter) allow(appId uint, limit appRateLimit, now time.Time) bool {
+	if limit.requestsPerMinute == 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[appId]
+	// start with a full bucket, also when the app's rate limit was changed
+	if !ok || bucket.limit != limit {
+		bucket = &tokenBucket{
+			limit:      limit,
+			tokens:     float64(limit.burst),
+			lastRefill: now,
+		}
+		l.buckets[appId] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.lastRefill); elapsed > 0 {
+		bucket.tokens = math.Min(float64(limit.burst), bucket.tokens+elapsed.Minutes()*float64(limit.requestsPerMinute))
+		bucket.lastRefill = now
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// getAppRateLimit reads the rate limit from the app metadata, falling back to the defaults
+func getAppRateLimit(app *db.App) appRateLimit {
+	limit := appRateLimit{
+		requestsPerMinute: constants.DEFAULT_RATE_LIMIT_REQUESTS_PER_MINUTE,
+		burst:             constants.DEFAULT_RATE_LIMIT_BURST,
+	}
+	if app.Metadata == nil {
+		return limit
+	}
+
+	var metadata map[string]interface{}
+	err := json.Unmarshal(app.Metadata, &metadata)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("app_id", app.ID).Error("Failed to deserialize app metadata")
+		return limit
+	}
+
+	readLimit := func(key string, value *uint) {
+		rawValue, ok := metadata[key]
+		if !ok {
+			return
+		}
+		number, ok := rawValue.(float64)
+		if !ok || number < 0 {
+			logger.Logger.WithFields(logrus.Fields{
+				"app_id": app.ID,
+				"key":    key,
+				"value":  rawValue,
+			}).Warn("Ignoring invalid rate limit in app metadata")
+			return
+		}
+		*value = uint(number)
+	}
+	readLimit(constants.METADATA_RATE_LIMIT_REQUESTS_PER_MINUTE_KEY, &limit.requestsPerMinute)
+	readLimit(constants.METADATA_RATE_LIMIT_BURST_KEY, &limit.burst)
+
+	if limit.burst == 0 {
+		limit.burst = 1
+	}
+	return limit
+}
@@ -0,0 +1,50 @@
+package nip47
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+)
+
+func TestAppRateLimiter_Refill(t *testing.T) {
+	rateLimiter := newAppRateLimiter()
+	limit := appRateLimit{requestsPerMinute: 60, burst: 2}
+	now := time.Now()
+
+	assert.True(t, rateLimiter.allow(1, limit, now))
+	assert.True(t, rateLimiter.allow(1, limit, now))
+	assert.False(t, rateLimiter.allow(1, limit, now))
+
+	// other apps have their own bucket
+	assert.True(t, rateLimiter.allow(2, limit, now))
+
+	// one request per second is refilled
+	assert.True(t, rateLimiter.allow(1, limit, now.Add(time.Second)))
+	assert.False(t, rateLimiter.allow(1, limit, now.Add(time.Second)))
+}
+
+func TestAppRateLimiter_Disabled(t *testing.T) {
+	rateLimiter := newAppRateLimiter()
+	limit := getAppRateLimit(&db.App{Metadata: []byte(`{"rate_limit_requests_per_minute": 0}`)})
+	now := time.Now()
+
+	for i := 0; i < constants.DEFAULT_RATE_LIMIT_BURST*2; i++ {
+		assert.True(t, rateLimiter.allow(1, limit, now))
+	}
+}
+
+func TestGetAppRateLimit(t *testing.T) {
+	assert.Equal(t, appRateLimit{
+		requestsPerMinute: constants.DEFAULT_RATE_LIMIT_REQUESTS_PER_MINUTE,
+		burst:             constants.DEFAULT_RATE_LIMIT_BURST,
+	}, getAppRateLimit(&db.App{}))
+
+	assert.Equal(t, appRateLimit{
+		requestsPerMinute: 10,
+		burst:             constants.DEFAULT_RATE_LIMIT_BURST,
+	}, getAppRateLimit(&db.App{Metadata: []byte(`{"rate_limit_requests_per_minute": 10, "rate_limit_burst": "invalid"}`)}))
+}
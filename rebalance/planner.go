@@ -0,0 +1,78 @@
+package rebalance
+
+import (
+	"sort"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+// Rebalance is a circular payment from the source channel to the target channel
+type Rebalance struct {
+	SourceChannelId string `json:"sourceChannelId"`
+	TargetChannelId string `json:"targetChannelId"`
+	AmountSat       uint64 `json:"amountSat"`
+}
+
+type channelLiquidity struct {
+	channelId  string
+	amountMsat uint64
+}
+
+// planRebalances pairs the channels with less than minLocalPercent of local balance
+// (targets) with the channels with more than maxLocalPercent (sources). Both are moved
+// towards the middle of the bounds, the targets with the biggest shortfall first.
+func planRebalances(channels []lnclient.Channel, minLocalPercent, maxLocalPercent, maxAmountSat uint64) []Rebalance {
+	targetPercent := (minLocalPercent + maxLocalPercent) / 2
+
+	var sources, targets []channelLiquidity
+	for _, channel := range channels {
+		if channel.Id == "" || !channel.Active || channel.LocalBalanceMsat < 0 || channel.RemoteBalanceMsat < 0 {
+			continue
+		}
+		localBalanceMsat := uint64(channel.LocalBalanceMsat)
+		capacityMsat := localBalanceMsat + uint64(channel.RemoteBalanceMsat)
+		if capacityMsat == 0 {
+			continue
+		}
+		targetBalanceMsat := capacityMsat * targetPercent / 100
+
+		switch localPercent := localBalanceMsat * 100 / capacityMsat; {
+		case localPercent > maxLocalPercent:
+			excessMsat := min(localBalanceMsat-targetBalanceMsat, uint64(max(channel.LocalSpendableBalanceMsat, 0)))
+			sources = append(sources, channelLiquidity{channelId: channel.Id, amountMsat: excessMsat})
+		case localPercent < minLocalPercent:
+			targets = append(targets, channelLiquidity{channelId: channel.Id, amountMsat: targetBalanceMsat - localBalanceMsat})
+		}
+	}
+
+	sortByAmount(targets)
+
+	rebalances := []Rebalance{}
+	for _, target := range targets {
+		sortByAmount(sources)
+		if len(sources) == 0 || sources[0].amountMsat == 0 {
+			break
+		}
+		source := &sources[0]
+		amountSat := min(target.amountMsat, source.amountMsat, maxAmountSat*1000) / 1000
+		if amountSat == 0 {
+			continue
+		}
+		source.amountMsat -= amountSat * 1000
+		rebalances = append(rebalances, Rebalance{
+			SourceChannelId: source.channelId,
+			TargetChannelId: target.channelId,
+			AmountSat:       amountSat,
+		})
+	}
+	return rebalances
+}
+
+func sortByAmount(channels []channelLiquidity) {
+	sort.SliceStable(channels, func(i, j int) bool {
+		if channels[i].amountMsat != channels[j].amountMsat {
+			return channels[i].amountMsat > channels[j].amountMsat
+		}
+		return channels[i].channelId < channels[j].channelId
+	})
+}
@@ -0,0 +1,61 @@
+package rebalance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+func TestPlanRebalances(t *testing.T) {
+	channels := []lnclient.Channel{
+		// 90% local, can send 400k sat to get to 50%
+		{Id: "source-a", Active: true, LocalBalanceMsat: 900_000_000, LocalSpendableBalanceMsat: 890_000_000, RemoteBalanceMsat: 100_000_000},
+		// 85% local, can send 70k sat
+		{Id: "source-b", Active: true, LocalBalanceMsat: 170_000_000, LocalSpendableBalanceMsat: 160_000_000, RemoteBalanceMsat: 30_000_000},
+		// 50% local, within bounds
+		{Id: "balanced", Active: true, LocalBalanceMsat: 500_000_000, RemoteBalanceMsat: 500_000_000},
+		// 10% local, needs 200k sat
+		{Id: "target-a", Active: true, LocalBalanceMsat: 50_000_000, RemoteBalanceMsat: 450_000_000},
+		// 0% local, needs 50k sat
+		{Id: "target-b", Active: true, LocalBalanceMsat: 0, RemoteBalanceMsat: 100_000_000},
+		// inactive channels are ignored
+		{Id: "inactive", Active: false, LocalBalanceMsat: 0, RemoteBalanceMsat: 100_000_000},
+	}
+
+	rebalances := planRebalances(channels, 20, 80, 150_000)
+	assert.Equal(t, []Rebalance{
+		{SourceChannelId: "source-a", TargetChannelId: "target-a", AmountSat: 150_000},
+		{SourceChannelId: "source-a", TargetChannelId: "target-b", AmountSat: 50_000},
+	}, rebalances)
+
+	rebalances = planRebalances(channels, 20, 80, 1_000_000)
+	assert.Equal(t, []Rebalance{
+		{SourceChannelId: "source-a", TargetChannelId: "target-a", AmountSat: 200_000},
+		{SourceChannelId: "source-a", TargetChannelId: "target-b", AmountSat: 50_000},
+	}, rebalances)
+}
+
+func TestPlanRebalances_LimitedBySource(t *testing.T) {
+	channels := []lnclient.Channel{
+		// 85% local, can send 70k sat, but only 20k sat are spendable
+		{Id: "source", Active: true, LocalBalanceMsat: 170_000_000, LocalSpendableBalanceMsat: 20_000_000, RemoteBalanceMsat: 30_000_000},
+		{Id: "target-a", Active: true, LocalBalanceMsat: 0, RemoteBalanceMsat: 100_000_000},
+		{Id: "target-b", Active: true, LocalBalanceMsat: 0, RemoteBalanceMsat: 40_000_000},
+	}
+
+	rebalances := planRebalances(channels, 20, 80, 1_000_000)
+	assert.Equal(t, []Rebalance{
+		{SourceChannelId: "source", TargetChannelId: "target-a", AmountSat: 20_000},
+	}, rebalances)
+}
+
+func TestPlanRebalances_NothingToDo(t *testing.T) {
+	channels := []lnclient.Channel{
+		{Id: "depleted", Active: true, LocalBalanceMsat: 0, RemoteBalanceMsat: 100_000_000},
+		{Id: "balanced", Active: true, LocalBalanceMsat: 500_000_000, RemoteBalanceMsat: 500_000_000},
+	}
+	assert.Empty(t, planRebalances(channels, 20, 80, 1_000_000))
+	assert.Empty(t, planRebalances(nil, 20, 80, 1_000_000))
+}
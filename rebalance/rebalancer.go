@@ -0,0 +1,309 @@
+package rebalance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/scheduler"
+	"github.com/getAlby/hub/transactions"
+)
+
+// ErrNotSupported is returned when the LN backend cannot make circular payments
+var ErrNotSupported = errors.New("circular rebalancing is only supported with the LND and CLN backends")
+
+// supportedBackendTypes are the LN backends that implement SendCircularPayment
+var supportedBackendTypes = []string{config.LNDBackendType, config.CLNBackendType}
+
+type Status struct {
+	Supported         bool       `json:"supported"`
+	Enabled           bool       `json:"enabled"`
+	IntervalMinutes   uint64     `json:"intervalMinutes,omitempty"`
+	MinLocalPercent   uint64     `json:"minLocalPercent"`
+	MaxLocalPercent   uint64     `json:"maxLocalPercent"`
+	MaxFeePpm         uint64     `json:"maxFeePpm"`
+	MaxAmountSat      uint64     `json:"maxAmountSat"`
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty"`
+	LastRunRebalances int        `json:"lastRunRebalances"`
+	LastRunFailures   int        `json:"lastRunFailures"`
+	LastError         string     `json:"lastError,omitempty"`
+	NextRunAt         *time.Time `json:"nextRunAt,omitempty"`
+}
+
+type RebalanceRequest struct {
+	SourceChannelId string
+	TargetChannelId string
+	AmountSat       uint64
+	MaxFeePpm       uint64
+}
+
+type Result struct {
+	Rebalance
+	MaxFeeMsat    uint64 `json:"maxFeeMsat"`
+	FeeMsat       uint64 `json:"feeMsat"`
+	TransactionId uint   `json:"transactionId,omitempty"`
+	PaymentHash   string `json:"paymentHash,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type Rebalancer interface {
+	GetStatus() *Status
+	// Preview returns the rebalances needed to bring the channels within the local balance bounds
+	Preview(ctx context.Context) ([]Rebalance, error)
+	// Rebalance moves liquidity from the source channel to the target channel with a circular payment
+	Rebalance(ctx context.Context, request *RebalanceRequest) (*Result, error)
+	// Run makes the rebalances returned by Preview
+	Run(ctx context.Context) ([]Result, error)
+}
+
+type rebalancer struct {
+	lnClient            lnclient.LNClient
+	transactionsService transactions.TransactionsService
+	minLocalPercent     uint64
+	maxLocalPercent     uint64
+	maxFeePpm           uint64
+	maxAmountSat        uint64
+	supported           bool
+	scheduler           *scheduler.Scheduler
+	runMutex            sync.Mutex
+	statusMutex         sync.Mutex
+	status              Status
+}
+
+// NewRebalancer creates the rebalancer and, if an interval is configured,
+// keeps the channels within the local balance bounds until ctx is cancelled
+func NewRebalancer(ctx context.Context, cfg config.Config, lnClient lnclient.LNClient, transactionsService transactions.TransactionsService) Rebalancer {
+	env := cfg.GetEnv()
+	backendType, _ := cfg.Get("LNBackendType", "")
+	supported := slices.Contains(supportedBackendTypes, backendType)
+	svc := &rebalancer{
+		lnClient:            lnClient,
+		transactionsService: transactionsService,
+		minLocalPercent:     env.RebalancerMinLocalPercent,
+		maxLocalPercent:     env.RebalancerMaxLocalPercent,
+		maxFeePpm:           env.RebalancerMaxFeePpm,
+		maxAmountSat:        env.RebalancerMaxAmountSat,
+		supported:           supported,
+		status: Status{
+			Supported:       supported,
+			MinLocalPercent: env.RebalancerMinLocalPercent,
+			MaxLocalPercent: env.RebalancerMaxLocalPercent,
+			MaxFeePpm:       env.RebalancerMaxFeePpm,
+			MaxAmountSat:    env.RebalancerMaxAmountSat,
+		},
+	}
+
+	if env.RebalancerIntervalMinutes == 0 {
+		return svc
+	}
+	if !supported {
+		logger.Logger.WithField("backend_type", backendType).Warn("LN backend does not support circular rebalancing, not starting scheduled rebalances")
+		return svc
+	}
+	if env.RebalancerMinLocalPercent >= env.RebalancerMaxLocalPercent || env.RebalancerMaxLocalPercent > 100 {
+		logger.Logger.WithFields(logrus.Fields{
+			"min_local_percent": env.RebalancerMinLocalPercent,
+			"max_local_percent": env.RebalancerMaxLocalPercent,
+		}).Error("Invalid rebalancer local balance bounds, not starting scheduled rebalances")
+		return svc
+	}
+	svc.status.Enabled = true
+	svc.status.IntervalMinutes = env.RebalancerIntervalMinutes
+
+	svc.scheduler = scheduler.Start(ctx, "rebalancer", time.Duration(env.RebalancerIntervalMinutes)*time.Minute, nil, func(ctx context.Context) {
+		// errors are recorded in the status
+		svc.Run(ctx)
+	})
+
+	return svc
+}
+
+func (svc *rebalancer) GetStatus() *Status {
+	svc.statusMutex.Lock()
+	defer svc.statusMutex.Unlock()
+	status := svc.status
+	if svc.scheduler != nil {
+		status.NextRunAt = svc.scheduler.NextRunAt()
+	}
+	return &status
+}
+
+func (svc *rebalancer) Preview(ctx context.Context) ([]Rebalance, error) {
+	if !svc.supported {
+		return nil, ErrNotSupported
+	}
+	if svc.lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	channels, err := svc.lnClient.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return planRebalances(channels, svc.minLocalPercent, svc.maxLocalPercent, svc.maxAmountSat), nil
+}
+
+func (svc *rebalancer) Rebalance(ctx context.Context, request *RebalanceRequest) (*Result, error) {
+	if !svc.supported {
+		return nil, ErrNotSupported
+	}
+	if !svc.runMutex.TryLock() {
+		return nil, errors.New("a rebalance is already running")
+	}
+	defer svc.runMutex.Unlock()
+
+	return svc.rebalance(ctx, request)
+}
+
+func (svc *rebalancer) Run(ctx context.Context) (_ []Result, err error) {
+	if !svc.supported {
+		return nil, ErrNotSupported
+	}
+	if !svc.runMutex.TryLock() {
+		return nil, errors.New("a rebalance is already running")
+	}
+	defer svc.runMutex.Unlock()
+
+	startedAt := time.Now()
+	results := []Result{}
+
+	defer func() {
+		failures := 0
+		for _, result := range results {
+			if result.Error != "" {
+				failures++
+			}
+		}
+		svc.statusMutex.Lock()
+		svc.status.LastRunAt = &startedAt
+		svc.status.LastRunRebalances = len(results) - failures
+		svc.status.LastRunFailures = failures
+		if err != nil {
+			svc.status.LastError = err.Error()
+		} else {
+			svc.status.LastError = ""
+		}
+		svc.statusMutex.Unlock()
+
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to run rebalancer")
+		}
+	}()
+
+	rebalances, err := svc.Preview(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rebalance := range rebalances {
+		result, rebalanceErr := svc.rebalance(ctx, &RebalanceRequest{
+			SourceChannelId: rebalance.SourceChannelId,
+			TargetChannelId: rebalance.TargetChannelId,
+			AmountSat:       rebalance.AmountSat,
+			MaxFeePpm:       svc.maxFeePpm,
+		})
+		if rebalanceErr != nil {
+			results = append(results, Result{
+				Rebalance: rebalance,
+				Error:     rebalanceErr.Error(),
+			})
+			continue
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// rebalance creates an invoice with a route hint through the peer of the target channel
+// and pays it out through the source channel and back in through the target channel, within a fee budget of MaxFeePpm of the amount
+func (svc *rebalancer) rebalance(ctx context.Context, request *RebalanceRequest) (*Result, error) {
+	if svc.lnClient == nil {
+		return nil, errors.New("LNClient not started")
+	}
+	if request.AmountSat == 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+	if request.SourceChannelId == request.TargetChannelId {
+		return nil, errors.New("source and target channel must be different")
+	}
+
+	channels, err := svc.lnClient.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var source, target *lnclient.Channel
+	for i := range channels {
+		switch channels[i].Id {
+		case request.SourceChannelId:
+			source = &channels[i]
+		case request.TargetChannelId:
+			target = &channels[i]
+		}
+	}
+	if source == nil || !source.Active {
+		return nil, fmt.Errorf("source channel %s not found or not active", request.SourceChannelId)
+	}
+	if target == nil || !target.Active {
+		return nil, fmt.Errorf("target channel %s not found or not active", request.TargetChannelId)
+	}
+
+	amountMsat := request.AmountSat * 1000
+	if source.LocalSpendableBalanceMsat < int64(amountMsat) {
+		return nil, fmt.Errorf("source channel can only send %d msat", source.LocalSpendableBalanceMsat)
+	}
+	if target.RemoteBalanceMsat < int64(amountMsat) {
+		return nil, fmt.Errorf("target channel can only receive %d msat", target.RemoteBalanceMsat)
+	}
+
+	result := &Result{
+		Rebalance: Rebalance{
+			SourceChannelId: source.Id,
+			TargetChannelId: target.Id,
+			AmountSat:       request.AmountSat,
+		},
+		MaxFeeMsat: amountMsat * request.MaxFeePpm / 1_000_000,
+	}
+
+	metadata := map[string]interface{}{
+		"rebalance_source_channel_id": source.Id,
+		"rebalance_target_channel_id": target.Id,
+		"rebalance_max_fee_msat":      result.MaxFeeMsat,
+	}
+
+	invoice, err := svc.transactionsService.MakeInvoice(ctx, amountMsat, "Alby Hub Rebalance through "+target.RemotePubkey, "", 0, metadata, svc.lnClient, nil, nil, &target.RemotePubkey)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create circular rebalance invoice")
+		return nil, err
+	}
+	result.PaymentHash = invoice.PaymentHash
+
+	transaction, err := svc.transactionsService.SendCircularPayment(ctx, invoice.PaymentRequest, source.Id, target.Id, target.RemotePubkey, result.MaxFeeMsat, metadata, svc.lnClient)
+	if err != nil {
+		logger.Logger.WithError(err).WithFields(logrus.Fields{
+			"source_channel_id": source.Id,
+			"target_channel_id": target.Id,
+			"amount_sat":        request.AmountSat,
+			"max_fee_msat":      result.MaxFeeMsat,
+		}).Error("Circular rebalance failed")
+		return nil, err
+	}
+	result.TransactionId = transaction.ID
+	result.FeeMsat = transaction.FeeMsat
+
+	logger.Logger.WithFields(logrus.Fields{
+		"source_channel_id": source.Id,
+		"target_channel_id": target.Id,
+		"amount_sat":        request.AmountSat,
+		"fee_msat":          result.FeeMsat,
+	}).Info("Rebalanced channels with a circular payment")
+
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package rebalance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+	"github.com/getAlby/hub/transactions"
+)
+
+func newTestRebalancer(svc *tests.TestService) *rebalancer {
+	return &rebalancer{
+		lnClient:            svc.LNClient,
+		transactionsService: transactions.NewTransactionsService(svc.DB, svc.EventPublisher),
+		minLocalPercent:     20,
+		maxLocalPercent:     80,
+		maxFeePpm:           500,
+		maxAmountSat:        100_000,
+		supported:           true,
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := svc.LNClient.(*tests.MockLn)
+	lnClient.Channels = tests.MockChannels
+	lnClient.PayInvoiceResponses = []*lnclient.PayInvoiceResponse{{Preimage: "123preimage", FeeMsat: 120}}
+	lnClient.PayInvoiceErrors = []error{nil}
+
+	rebalancer := newTestRebalancer(svc)
+	result, err := rebalancer.Rebalance(context.TODO(), &RebalanceRequest{
+		SourceChannelId: "chan-a",
+		TargetChannelId: "chan-b",
+		AmountSat:       1000,
+		MaxFeePpm:       500,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "chan-a", result.SourceChannelId)
+	assert.Equal(t, "chan-b", result.TargetChannelId)
+	assert.Equal(t, uint64(500), result.MaxFeeMsat)
+	assert.Equal(t, uint64(120), result.FeeMsat)
+	assert.Equal(t, tests.MockPaymentHash, result.PaymentHash)
+
+	assert.Equal(t, &lnclient.CircularPaymentRequest{
+		PaymentRequest:    tests.MockInvoice,
+		OutgoingChannelId: "chan-a",
+		IncomingChannelId: "chan-b",
+		LastHopPubkey:     "peer-b",
+		MaxFeeMsat:        500,
+	}, lnClient.LastCircularPaymentRequest)
+
+	var transactions []db.Transaction
+	require.NoError(t, svc.DB.Order("id").Find(&transactions).Error)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, constants.TRANSACTION_TYPE_INCOMING, transactions[0].Type)
+	assert.Equal(t, constants.TRANSACTION_TYPE_OUTGOING, transactions[1].Type)
+	assert.Equal(t, result.TransactionId, transactions[1].ID)
+	for _, transaction := range transactions {
+		assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+		assert.True(t, transaction.SelfPayment)
+	}
+	assert.Equal(t, uint64(120), transactions[1].FeeMsat)
+}
+
+func TestRebalance_InvalidChannels(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	svc.LNClient.(*tests.MockLn).Channels = tests.MockChannels
+	rebalancer := newTestRebalancer(svc)
+
+	_, err = rebalancer.Rebalance(context.TODO(), &RebalanceRequest{SourceChannelId: "chan-a", TargetChannelId: "chan-c", AmountSat: 1000})
+	assert.EqualError(t, err, "target channel chan-c not found or not active")
+
+	_, err = rebalancer.Rebalance(context.TODO(), &RebalanceRequest{SourceChannelId: "chan-b", TargetChannelId: "chan-a", AmountSat: 200_000})
+	assert.EqualError(t, err, "source channel can only send 0 msat")
+
+	_, err = rebalancer.Rebalance(context.TODO(), &RebalanceRequest{SourceChannelId: "chan-a", TargetChannelId: "chan-a", AmountSat: 1000})
+	assert.EqualError(t, err, "source and target channel must be different")
+
+	var count int64
+	require.NoError(t, svc.DB.Model(&db.Transaction{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestRun(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	lnClient := svc.LNClient.(*tests.MockLn)
+	lnClient.Channels = tests.MockChannels
+	lnClient.PayInvoiceResponses = []*lnclient.PayInvoiceResponse{nil}
+	lnClient.PayInvoiceErrors = []error{errors.New("FAILURE_REASON_NO_ROUTE")}
+
+	rebalancer := newTestRebalancer(svc)
+
+	preview, err := rebalancer.Preview(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []Rebalance{{SourceChannelId: "chan-a", TargetChannelId: "chan-b", AmountSat: 100_000}}, preview)
+
+	results, err := rebalancer.Run(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, preview[0], results[0].Rebalance)
+	assert.Equal(t, "FAILURE_REASON_NO_ROUTE", results[0].Error)
+	assert.Equal(t, uint64(50_000), lnClient.LastCircularPaymentRequest.MaxFeeMsat)
+
+	status := rebalancer.GetStatus()
+	assert.NotNil(t, status.LastRunAt)
+	assert.Equal(t, 0, status.LastRunRebalances)
+	assert.Equal(t, 1, status.LastRunFailures)
+}
+
+func TestNewRebalancer_NotSupported(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	require.NoError(t, svc.Cfg.SetUpdate("LNBackendType", config.LDKBackendType, ""))
+	svc.Cfg.GetEnv().RebalancerIntervalMinutes = 60
+	svc.Cfg.GetEnv().RebalancerMinLocalPercent = 20
+	svc.Cfg.GetEnv().RebalancerMaxLocalPercent = 80
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rebalancer := NewRebalancer(ctx, svc.Cfg, svc.LNClient, transactions.NewTransactionsService(svc.DB, svc.EventPublisher))
+
+	status := rebalancer.GetStatus()
+	assert.False(t, status.Supported)
+	assert.False(t, status.Enabled)
+	assert.Nil(t, status.NextRunAt)
+
+	_, err = rebalancer.Preview(ctx)
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = rebalancer.Run(ctx)
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = rebalancer.Rebalance(ctx, &RebalanceRequest{SourceChannelId: "chan-a", TargetChannelId: "chan-b", AmountSat: 1000})
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/logger"
+)
+
+// Scheduler runs a job on an interval in the background until its context is cancelled
+type Scheduler struct {
+	name      string
+	interval  time.Duration
+	job       func(ctx context.Context)
+	mutex     sync.Mutex
+	nextRunAt time.Time
+}
+
+// Start runs the job every interval until ctx is cancelled. If getFirstRunAt is set,
+// it is called in the background to decide when the job first runs.
+// Errors of the job must be handled by the job, e.g. by recording them in a status.
+func Start(ctx context.Context, name string, interval time.Duration, getFirstRunAt func() time.Time, job func(ctx context.Context)) *Scheduler {
+	scheduler := &Scheduler{
+		name:      name,
+		interval:  interval,
+		job:       job,
+		nextRunAt: time.Now().Add(interval),
+	}
+	go scheduler.run(ctx, getFirstRunAt)
+	return scheduler
+}
+
+// NextRunAt returns when the job runs next
+func (scheduler *Scheduler) NextRunAt() *time.Time {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	nextRunAt := scheduler.nextRunAt
+	return &nextRunAt
+}
+
+func (scheduler *Scheduler) run(ctx context.Context, getFirstRunAt func() time.Time) {
+	nextRunAt := scheduler.NextRunAt()
+	if getFirstRunAt != nil {
+		*nextRunAt = getFirstRunAt()
+	}
+
+	for {
+		scheduler.mutex.Lock()
+		scheduler.nextRunAt = *nextRunAt
+		scheduler.mutex.Unlock()
+
+		logger.Logger.WithFields(logrus.Fields{
+			"scheduler":   scheduler.name,
+			"next_run_at": *nextRunAt,
+		}).Debug("Scheduled next run")
+
+		select {
+		case <-ctx.Done():
+			logger.Logger.WithField("scheduler", scheduler.name).Info("Stopped scheduler")
+			return
+		case <-time.After(time.Until(*nextRunAt)):
+			scheduler.job(ctx)
+			*nextRunAt = time.Now().Add(scheduler.interval)
+		}
+	}
+}
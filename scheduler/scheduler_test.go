@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/logger"
+)
+
+// the logger is only initialized once, as schedulers of previous tests may still be logging
+var initLoggerOnce sync.Once
+
+func initLogger() {
+	initLoggerOnce.Do(func() {
+		logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	})
+}
+
+func TestStart(t *testing.T) {
+	initLogger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	scheduler := Start(ctx, "test", 10*time.Millisecond, nil, func(ctx context.Context) {
+		runs.Add(1)
+	})
+	assert.WithinDuration(t, time.Now().Add(10*time.Millisecond), *scheduler.NextRunAt(), 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return runs.Load() >= 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	stoppedRuns := runs.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, stoppedRuns, runs.Load())
+}
+
+func TestStart_FirstRunAt(t *testing.T) {
+	initLogger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	firstRunAt := time.Now().Add(time.Hour)
+	scheduler := Start(ctx, "test", time.Minute, func() time.Time { return firstRunAt }, func(ctx context.Context) {
+		t.Error("job must not run before the first run time")
+	})
+
+	assert.Eventually(t, func() bool { return scheduler.NextRunAt().Equal(firstRunAt) }, time.Second, time.Millisecond)
+}
@@ -43,18 +43,14 @@ func (s *createAppConsumer) ConsumeEvent(ctx context.Context, event *events.Even
 		return
 	}
 
-	walletPrivKey, err := s.svc.keys.GetAppWalletKey(id)
+	walletSigner, err := s.svc.keys.GetAppWalletSigner(id)
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to calculate app wallet priv key")
-		return
-	}
-	walletPubKey, err := nostr.GetPublicKey(walletPrivKey)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to calculate app wallet pub key")
+		logger.Logger.WithError(err).Error("Failed to calculate app wallet key")
 		return
 	}
+	walletPubKey := walletSigner.GetPublicKey()
 	for _, relayUrl := range s.svc.cfg.GetRelayUrls() {
-		s.svc.nip47Service.EnqueueNip47InfoPublishRequest(id, walletPubKey, walletPrivKey, relayUrl)
+		s.svc.nip47Service.EnqueueNip47InfoPublishRequest(id, walletPubKey, walletSigner, relayUrl)
 	}
 
 	go s.svc.startAppWalletSubscription(ctx, s.pool, walletPubKey)
@@ -46,9 +46,9 @@ func (s *deleteAppConsumer) ConsumeEvent(ctx context.Context, event *events.Even
 	// remove this consumer as subscriber in eventPublisher
 	s.svc.eventPublisher.RemoveSubscriber(s)
 
-	walletPrivKey, err := s.svc.keys.GetAppWalletKey(id)
+	walletSigner, err := s.svc.keys.GetAppWalletSigner(id)
 	if err != nil {
-		logger.Logger.WithError(err).WithField("id", id).Error("Failed to calculate app wallet priv key")
+		logger.Logger.WithError(err).WithField("id", id).Error("Failed to calculate app wallet key")
 		return
 	}
 
@@ -60,7 +60,7 @@ func (s *deleteAppConsumer) ConsumeEvent(ctx context.Context, event *events.Even
 		return
 	}
 	if nip47InfoEvent != nil {
-		err = s.svc.nip47Service.PublishNip47InfoDeletion(ctx, s.pool, walletPubKey, walletPrivKey, nip47InfoEvent.ID)
+		err = s.svc.nip47Service.PublishNip47InfoDeletion(ctx, s.pool, walletPubKey, walletSigner, nip47InfoEvent.ID)
 		if err != nil {
 			logger.Logger.WithError(err).WithField("event", event).Error("Failed to publish nip47 info deletion")
 		}
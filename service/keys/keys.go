@@ -1,12 +1,15 @@
 package keys
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"errors"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
 
@@ -21,12 +24,12 @@ type Keys interface {
 	Init(cfg config.Config, encryptionKey string) error
 	// Wallet Service Nostr pubkey (DEPRECATED)
 	GetNostrPublicKey() string
-	// Wallet Service Nostr secret key (DEPRECATED)
-	GetNostrSecretKey() string
+	// Signer for the Wallet Service Nostr key (DEPRECATED)
+	GetNostrSigner() Signer
 	// Swap rescue key derived from master key using BIP-85
 	GetSwapMnemonic() string
-	// Derives a BIP32 child key from appKey derived child dedicated for app wallet keys
-	GetAppWalletKey(childIndex uint) (string, error)
+	// Signer for a BIP32 child key derived from appKey derived child dedicated for app wallet keys
+	GetAppWalletSigner(childIndex uint) (Signer, error)
 	// Derives a child BIP-32 key from the app key (derived from the mnemonic)
 	DeriveKey(path []uint32) (*bip32.Key, error)
 	// Derives a BIP32 child key from appKey derived child dedicated for swaps
@@ -34,39 +37,53 @@ type Keys interface {
 }
 
 type keys struct {
-	nostrSecretKey string
+	// cancelled when the hub shuts down
+	ctx            context.Context
+	nostrSigner    Signer
 	nostrPublicKey string
 	appKey         *bip32.Key
 	swapKey        *hdkeychain.ExtendedKey
 	swapMnemonic   string
+	// set when Nostr keys are held in a PKCS#11 token
+	pkcs11Token *pkcs11Token
+	// set when the wallet service Nostr key is held by a NIP-46 bunker
+	nip46Signer *nip46Signer
+	// closes the relay connections of nip46Signer
+	nip46Cancel context.CancelFunc
 }
 
-func NewKeys() *keys {
-	return &keys{}
+func NewKeys(ctx context.Context) *keys {
+	return &keys{ctx: ctx}
 }
 
 func (keys *keys) Init(cfg config.Config, encryptionKey string) error {
-	nostrSecretKey, err := cfg.Get("NostrSecretKey", encryptionKey)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to decrypt nostr secret key")
-		return err
-	}
-
-	if nostrSecretKey == "" {
-		nostrSecretKey = nostr.GeneratePrivateKey()
-		err = cfg.SetUpdate("NostrSecretKey", nostrSecretKey, encryptionKey)
+	if cfg.GetEnv().PKCS11Module != "" && keys.pkcs11Token == nil {
+		pkcs11Token, err := openPKCS11Token(cfg.GetEnv().PKCS11Module, cfg.GetEnv().PKCS11TokenLabel, cfg.GetEnv().PKCS11Pin)
 		if err != nil {
-			logger.Logger.WithError(err).Error("Failed to save generated nostr secret key")
+			logger.Logger.WithError(err).Error("Failed to open PKCS#11 token")
 			return err
 		}
+		err = pkcs11Token.setSignMechanism(uint(cfg.GetEnv().PKCS11SignMechanism))
+		if err != nil {
+			pkcs11Token.close()
+			logger.Logger.WithError(err).Error("Failed to set PKCS#11 sign mechanism")
+			return err
+		}
+		keys.pkcs11Token = pkcs11Token
+	}
+
+	var nostrSigner Signer
+	var err error
+	if cfg.GetEnv().Nip46BunkerUrl != "" {
+		nostrSigner, err = keys.initNip46Signer(cfg, encryptionKey)
+	} else {
+		nostrSigner, err = keys.initLocalNostrSigner(cfg, encryptionKey)
 	}
-	nostrPublicKey, err := nostr.GetPublicKey(nostrSecretKey)
 	if err != nil {
-		logger.Logger.WithError(err).Error("Error converting nostr privkey to pubkey")
 		return err
 	}
-	keys.nostrSecretKey = nostrSecretKey
-	keys.nostrPublicKey = nostrPublicKey
+	keys.nostrSigner = nostrSigner
+	keys.nostrPublicKey = nostrSigner.GetPublicKey()
 
 	mnemonic, err := cfg.Get("Mnemonic", encryptionKey)
 	if err != nil {
@@ -130,6 +147,154 @@ func (keys *keys) Init(cfg config.Config, encryptionKey string) error {
 	return nil
 }
 
+func (keys *keys) initLocalNostrSigner(cfg config.Config, encryptionKey string) (Signer, error) {
+	nostrSecretKey, err := cfg.Get("NostrSecretKey", encryptionKey)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to decrypt nostr secret key")
+		return nil, err
+	}
+	pkcs11NostrPublicKey, err := cfg.Get(pkcs11NostrPublicKeyConfigKey, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if nostrSecretKey == "" {
+		// do not silently replace a wallet service key which is only held by a token
+		if pkcs11NostrPublicKey != "" && keys.pkcs11Token == nil {
+			return nil, errors.New("the nostr secret key is held by a PKCS#11 token, please set PKCS11_MODULE")
+		}
+		if pkcs11NostrPublicKey == "" {
+			nostrSecretKey = nostr.GeneratePrivateKey()
+			err = cfg.SetUpdate("NostrSecretKey", nostrSecretKey, encryptionKey)
+			if err != nil {
+				logger.Logger.WithError(err).Error("Failed to save generated nostr secret key")
+				return nil, err
+			}
+		}
+	}
+
+	if keys.pkcs11Token != nil {
+		return keys.initPKCS11NostrSigner(cfg, nostrSecretKey, pkcs11NostrPublicKey)
+	}
+
+	nostrSigner, err := NewSoftwareSigner(nostrSecretKey)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create nostr signer")
+		return nil, err
+	}
+	return nostrSigner, nil
+}
+
+// initPKCS11NostrSigner imports the nostr secret key into the token. The secret key
+// stays in the database encrypted with the unlock password, so that the wallet
+// service key can be recovered by unsetting PKCS11_MODULE if the token is lost.
+func (keys *keys) initPKCS11NostrSigner(cfg config.Config, nostrSecretKey string, nostrPublicKey string) (Signer, error) {
+	nostrSigner, err := keys.pkcs11Token.signer(pkcs11NostrKeyLabel, func() (string, []byte, error) {
+		if nostrSecretKey == "" {
+			return nostrPublicKey, nil, nil
+		}
+		secretKey, err := hex.DecodeString(nostrSecretKey)
+		if err != nil {
+			return "", nil, err
+		}
+		publicKey, err := nostr.GetPublicKey(nostrSecretKey)
+		if err != nil {
+			return "", nil, err
+		}
+		return publicKey, secretKey, nil
+	})
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to create nostr signer")
+		return nil, err
+	}
+
+	if nostrSigner.GetPublicKey() != nostrPublicKey {
+		err = cfg.SetUpdate(pkcs11NostrPublicKeyConfigKey, nostrSigner.GetPublicKey(), "")
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to save nostr public key")
+			return nil, err
+		}
+	}
+	return nostrSigner, nil
+}
+
+// initNip46Signer connects to the configured bunker. The hub only holds the client key
+// for the bunker connection; the wallet service key never leaves the bunker.
+func (keys *keys) initNip46Signer(cfg config.Config, encryptionKey string) (Signer, error) {
+	bunkerUrl := cfg.GetEnv().Nip46BunkerUrl
+	if keys.nip46Signer != nil && keys.nip46Signer.bunkerUrl == bunkerUrl {
+		return keys.nip46Signer, nil
+	}
+
+	clientSecretKey, err := cfg.Get("Nip46ClientSecretKey", encryptionKey)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to decrypt NIP-46 client secret key")
+		return nil, err
+	}
+	if clientSecretKey == "" {
+		clientSecretKey = nostr.GeneratePrivateKey()
+		err = cfg.SetUpdate("Nip46ClientSecretKey", clientSecretKey, encryptionKey)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to save generated NIP-46 client secret key")
+			return nil, err
+		}
+	}
+
+	timeout := time.Duration(cfg.GetEnv().Nip46TimeoutSeconds) * time.Second
+	ctx, cancel := context.WithCancel(keys.ctx)
+	pool := nostr.NewSimplePool(ctx)
+	nip46Signer, err := NewNip46Signer(ctx, pool, bunkerUrl, clientSecretKey, timeout)
+	if err != nil {
+		cancel()
+		logger.Logger.WithError(err).Error("Failed to create NIP-46 signer")
+		return nil, err
+	}
+	success := false
+	defer func() {
+		if !success {
+			cancel()
+		}
+	}()
+
+	// the bunker connection is only established once. Afterwards the cached
+	// public key is used so that the hub can start while the bunker is unreachable.
+	remoteSignerPubkey, err := cfg.Get("Nip46RemoteSignerPubkey", "")
+	if err != nil {
+		return nil, err
+	}
+	userPubkey, err := cfg.Get("Nip46UserPubkey", "")
+	if err != nil {
+		return nil, err
+	}
+	if userPubkey != "" && remoteSignerPubkey == nip46Signer.remotePubkey {
+		nip46Signer.userPubkey = userPubkey
+	} else {
+		err = nip46Signer.Connect()
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to connect to NIP-46 bunker")
+			return nil, err
+		}
+		err = cfg.SetUpdate("Nip46RemoteSignerPubkey", nip46Signer.remotePubkey, "")
+		if err != nil {
+			return nil, err
+		}
+		err = cfg.SetUpdate("Nip46UserPubkey", nip46Signer.userPubkey, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	logger.Logger.WithField("pubkey", nip46Signer.userPubkey).Info("Using NIP-46 bunker for the wallet service nostr key")
+
+	// the bunker URL changed, close the connections of the previous signer
+	if keys.nip46Cancel != nil {
+		keys.nip46Cancel()
+	}
+	keys.nip46Signer = nip46Signer
+	keys.nip46Cancel = cancel
+	success = true
+	return nip46Signer, nil
+}
+
 func (keys *keys) GetSwapMnemonic() string {
 	return keys.swapMnemonic
 }
@@ -138,18 +303,39 @@ func (keys *keys) GetNostrPublicKey() string {
 	return keys.nostrPublicKey
 }
 
-func (keys *keys) GetNostrSecretKey() string {
-	return keys.nostrSecretKey
+func (keys *keys) GetNostrSigner() Signer {
+	return keys.nostrSigner
+}
+
+func (keys *keys) GetAppWalletSigner(appID uint) (Signer, error) {
+	if keys.pkcs11Token != nil {
+		// the key is only derived the first time it is used and cleared once it is in the token
+		return keys.pkcs11Token.signer(pkcs11AppWalletKeyLabel(appID), func() (string, []byte, error) {
+			appWalletKey, err := keys.getAppWalletKey(appID)
+			if err != nil {
+				return "", nil, err
+			}
+			_, publicKey := btcec.PrivKeyFromBytes(appWalletKey)
+			return hex.EncodeToString(schnorr.SerializePubKey(publicKey)), appWalletKey, nil
+		})
+	}
+	appWalletKey, err := keys.getAppWalletKey(appID)
+	if err != nil {
+		return nil, err
+	}
+	return NewSoftwareSigner(hex.EncodeToString(appWalletKey))
 }
 
-func (keys *keys) GetAppWalletKey(appID uint) (string, error) {
+func (keys *keys) getAppWalletKey(appID uint) ([]byte, error) {
 	path := []uint32{bip32.FirstHardenedChild + 1, bip32.FirstHardenedChild + uint32(appID)}
 	key, err := keys.DeriveKey(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer clear(key.Key)
 	childPrivKey, _ := btcec.PrivKeyFromBytes(key.Key)
-	return hex.EncodeToString(childPrivKey.Serialize()), nil
+	defer childPrivKey.Zero()
+	return childPrivKey.Serialize(), nil
 }
 
 func (keys *keys) DeriveKey(path []uint32) (*bip32.Key, error) {
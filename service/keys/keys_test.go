@@ -1,6 +1,7 @@
 package keys
 
 import (
+	"context"
 	"strconv"
 	"strings"
 	"testing"
@@ -11,7 +12,6 @@ import (
 	"github.com/getAlby/hub/logger"
 	"github.com/getAlby/hub/tests/db"
 
-	"github.com/getAlby/go-nostr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tyler-smith/go-bip32"
@@ -31,7 +31,7 @@ func TestUseExistingMnemonic(t *testing.T) {
 	require.NoError(t, err)
 	config.SetUpdate("Mnemonic", mnemonic, unlockPassword)
 
-	keys := NewKeys()
+	keys := NewKeys(context.Background())
 	err = keys.Init(config, unlockPassword)
 	require.NoError(t, err)
 
@@ -55,12 +55,10 @@ func TestUseExistingMnemonic(t *testing.T) {
 	assert.Equal(t, encryptedChannelsBackupKey.String(), derivedKeyFromKeys.String())
 
 	// get a wallet key for app ID 2, expect it is derived correctly
-	appWalletPrivateKey, err := keys.GetAppWalletKey(2)
-	require.NoError(t, err)
-	appWalletPubkey, err := nostr.GetPublicKey(appWalletPrivateKey)
+	appWalletSigner, err := keys.GetAppWalletSigner(2)
 	require.NoError(t, err)
 
-	assert.Equal(t, "dd9e304d24f29f3481d5cf18a76c85ca3e95931aee3c997a27f267e975e72976", appWalletPubkey)
+	assert.Equal(t, "dd9e304d24f29f3481d5cf18a76c85ca3e95931aee3c997a27f267e975e72976", appWalletSigner.GetPublicKey())
 }
 
 func TestGenerateNewMnemonic(t *testing.T) {
@@ -74,7 +72,7 @@ func TestGenerateNewMnemonic(t *testing.T) {
 	config, err := config.NewConfig(&config.AppConfig{}, gormDb)
 	require.NoError(t, err)
 
-	keys := NewKeys()
+	keys := NewKeys(context.Background())
 	err = keys.Init(config, unlockPassword)
 	require.NoError(t, err)
 
@@ -85,7 +83,7 @@ func TestGenerateNewMnemonic(t *testing.T) {
 	assert.Equal(t, 12, len(strings.Split(mnemonicFromConfig, " ")))
 
 	// re-create keys, ensure same mnemonic is used
-	keys = NewKeys()
+	keys = NewKeys(context.Background())
 	err = keys.Init(config, unlockPassword)
 	require.NoError(t, err)
 
@@ -122,7 +120,7 @@ func TestInit_WrongPasswordDoesNotOverwriteNostrKey(t *testing.T) {
 	require.NoError(t, err)
 
 	// initialise keys under the correct password, storing an encrypted NostrSecretKey
-	keys := NewKeys()
+	keys := NewKeys(context.Background())
 	err = keys.Init(cfg, unlockPassword)
 	require.NoError(t, err)
 
@@ -132,7 +130,7 @@ func TestInit_WrongPasswordDoesNotOverwriteNostrKey(t *testing.T) {
 
 	// a wrong password must abort instead of mistaking the failed decrypt for
 	// "no key yet" and overwriting the stored key with a freshly generated one
-	keys2 := NewKeys()
+	keys2 := NewKeys(context.Background())
 	err = keys2.Init(cfg, "wrong")
 	require.Error(t, err)
 
@@ -155,7 +153,7 @@ func TestGenerateSwapMnemonic(t *testing.T) {
 	require.NoError(t, err)
 	config.SetUpdate("Mnemonic", mnemonic, unlockPassword)
 
-	keys := NewKeys()
+	keys := NewKeys(context.Background())
 	err = keys.Init(config, unlockPassword)
 	require.NoError(t, err)
 
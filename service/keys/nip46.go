@@ -0,0 +1,405 @@
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/sirupsen/logrus"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/logger"
+)
+
+const NIP46_KIND = 24133
+
+var errNip46Timeout = errors.New("NIP-46 request timed out")
+
+// errNip46DecryptFailed is returned when the bunker could not decrypt a request
+var errNip46DecryptFailed = errors.New("NIP-46 bunker could not decrypt the request")
+
+// Nip46Pool is the subset of nostr.SimplePool used to talk to a NIP-46 bunker
+type Nip46Pool interface {
+	PublishMany(ctx context.Context, urls []string, event nostr.Event) chan nostr.PublishResult
+	SubscribeMany(ctx context.Context, urls []string, filter nostr.Filter, opts ...nostr.SubscriptionOption) chan nostr.RelayEvent
+}
+
+type nip46Request struct {
+	ID     string   `json:"id"`
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
+type nip46Response struct {
+	ID     string `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+type nip46UnsignedEvent struct {
+	Kind      int             `json:"kind"`
+	Content   string          `json:"content"`
+	Tags      nostr.Tags      `json:"tags"`
+	CreatedAt nostr.Timestamp `json:"created_at"`
+}
+
+// nip46Signer delegates signing and encryption to a remote signer (bunker).
+// Requests are published to all relays of the bunker URL and the first response wins.
+type nip46Signer struct {
+	ctx          context.Context
+	pool         Nip46Pool
+	bunkerUrl    string
+	relayUrls    []string
+	remotePubkey string
+	secret       string
+	clientSigner ECDHSigner
+	timeout      time.Duration
+	userPubkey   string
+
+	mutex sync.Mutex
+	// encryption used for requests to the bunker, NIP-44 unless the bunker only understands NIP-04
+	transportEncryption string
+	pendingRequests     map[string]pendingNip46Request
+}
+
+type pendingNip46Request struct {
+	encryption string
+	response   chan nip46Response
+}
+
+// NewNip46Signer creates a signer for the bunker URL (bunker://<remote-signer-pubkey>?relay=<url>&secret=<secret>)
+// and subscribes to its responses. Connect must be called before the signer is used
+// unless the user public key is already known.
+func NewNip46Signer(ctx context.Context, pool Nip46Pool, bunkerUrl string, clientSecretKey string, timeout time.Duration) (*nip46Signer, error) {
+	parsedUrl, err := url.Parse(bunkerUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bunker URL: %w", err)
+	}
+	if parsedUrl.Scheme != "bunker" {
+		return nil, fmt.Errorf("invalid bunker URL scheme: %s", parsedUrl.Scheme)
+	}
+	remotePubkey := parsedUrl.Host
+	if !isValidNostrPubkey(remotePubkey) {
+		return nil, errors.New("invalid remote signer pubkey in bunker URL")
+	}
+	relayUrls := parsedUrl.Query()["relay"]
+	if len(relayUrls) == 0 {
+		return nil, errors.New("bunker URL has no relays")
+	}
+
+	clientSigner, err := NewSoftwareSigner(clientSecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := &nip46Signer{
+		ctx:                 ctx,
+		pool:                pool,
+		bunkerUrl:           bunkerUrl,
+		relayUrls:           relayUrls,
+		remotePubkey:        remotePubkey,
+		secret:              parsedUrl.Query().Get("secret"),
+		clientSigner:        clientSigner,
+		timeout:             timeout,
+		transportEncryption: constants.ENCRYPTION_TYPE_NIP44_V2,
+		pendingRequests:     map[string]pendingNip46Request{},
+	}
+	// subscribe before returning so that no response can be missed
+	eventsChannel := pool.SubscribeMany(ctx, relayUrls, signer.responseFilter())
+	go signer.watchResponses(eventsChannel)
+	return signer, nil
+}
+
+// Connect connects to the bunker and fetches the user public key
+func (signer *nip46Signer) Connect() error {
+	params := []string{signer.remotePubkey}
+	if signer.secret != "" {
+		params = append(params, signer.secret)
+	}
+	result, err := signer.request("connect", params...)
+	if err != nil {
+		return err
+	}
+	if result != "ack" && (signer.secret == "" || result != signer.secret) {
+		return fmt.Errorf("unexpected NIP-46 connect response: %s", result)
+	}
+
+	userPubkey, err := signer.request("get_public_key")
+	if err != nil {
+		return err
+	}
+	if !isValidNostrPubkey(userPubkey) {
+		return fmt.Errorf("NIP-46 bunker returned an invalid public key: %s", userPubkey)
+	}
+	signer.userPubkey = userPubkey
+	return nil
+}
+
+func (signer *nip46Signer) GetPublicKey() string {
+	return signer.userPubkey
+}
+
+func (signer *nip46Signer) SignEvent(event *nostr.Event) error {
+	tags := event.Tags
+	if tags == nil {
+		tags = nostr.Tags{}
+	}
+	unsignedEvent, err := json.Marshal(nip46UnsignedEvent{
+		Kind:      event.Kind,
+		Content:   event.Content,
+		Tags:      tags,
+		CreatedAt: event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := signer.request("sign_event", string(unsignedEvent))
+	if err != nil {
+		return err
+	}
+
+	signedEvent := nostr.Event{}
+	err = json.Unmarshal([]byte(result), &signedEvent)
+	if err != nil {
+		return fmt.Errorf("failed to parse event signed by NIP-46 bunker: %w", err)
+	}
+
+	// the bunker must sign exactly the requested event with the user key
+	event.PubKey = signer.userPubkey
+	if signedEvent.PubKey != signer.userPubkey || signedEvent.ID != event.GetID() {
+		return errors.New("NIP-46 bunker signed a different event")
+	}
+	valid, err := signedEvent.CheckSignature()
+	if err != nil || !valid {
+		return errors.New("NIP-46 bunker returned an invalid signature")
+	}
+
+	event.ID = signedEvent.ID
+	event.Sig = signedEvent.Sig
+	return nil
+}
+
+func (signer *nip46Signer) Encrypt(encryption, pubkey, plaintext string) (string, error) {
+	method, err := nip46EncryptionMethod(encryption, "encrypt")
+	if err != nil {
+		return "", err
+	}
+	return signer.request(method, pubkey, plaintext)
+}
+
+func (signer *nip46Signer) Decrypt(encryption, pubkey, ciphertext string) (string, error) {
+	method, err := nip46EncryptionMethod(encryption, "decrypt")
+	if err != nil {
+		return "", err
+	}
+	return signer.request(method, pubkey, ciphertext)
+}
+
+func nip46EncryptionMethod(encryption, operation string) (string, error) {
+	switch encryption {
+	case constants.ENCRYPTION_TYPE_NIP04:
+		return "nip04_" + operation, nil
+	case constants.ENCRYPTION_TYPE_NIP44_V2:
+		return "nip44_" + operation, nil
+	default:
+		return "", fmt.Errorf("invalid encryption: %s", encryption)
+	}
+}
+
+// request sends a request to the bunker. If the bunker reports that it could not decrypt
+// a NIP-44 request, it is retried once with NIP-04 for bunkers which do not support NIP-44
+// yet. Timeouts are not retried, as an unreachable bunker would only double the wait.
+func (signer *nip46Signer) request(method string, params ...string) (string, error) {
+	signer.mutex.Lock()
+	transportEncryption := signer.transportEncryption
+	signer.mutex.Unlock()
+
+	result, err := signer.sendRequest(transportEncryption, method, params)
+	if errors.Is(err, errNip46DecryptFailed) && transportEncryption == constants.ENCRYPTION_TYPE_NIP44_V2 {
+		logger.Logger.WithError(err).WithField("method", method).Warn("NIP-46 bunker could not decrypt NIP-44 request, retrying with NIP-04")
+		result, err = signer.sendRequest(constants.ENCRYPTION_TYPE_NIP04, method, params)
+		if err == nil {
+			signer.mutex.Lock()
+			signer.transportEncryption = constants.ENCRYPTION_TYPE_NIP04
+			signer.mutex.Unlock()
+		}
+	}
+	return result, err
+}
+
+func (signer *nip46Signer) sendRequest(encryption, method string, params []string) (string, error) {
+	idBytes := make([]byte, 16)
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return "", err
+	}
+	requestId := hex.EncodeToString(idBytes)
+
+	payload, err := json.Marshal(nip46Request{
+		ID:     requestId,
+		Method: method,
+		Params: params,
+	})
+	if err != nil {
+		return "", err
+	}
+	content, err := signer.clientSigner.Encrypt(encryption, signer.remotePubkey, string(payload))
+	if err != nil {
+		return "", err
+	}
+	event := nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      NIP46_KIND,
+		Tags:      nostr.Tags{[]string{"p", signer.remotePubkey}},
+		Content:   content,
+	}
+	err = signer.clientSigner.SignEvent(&event)
+	if err != nil {
+		return "", err
+	}
+
+	responseChannel := make(chan nip46Response, 1)
+	signer.mutex.Lock()
+	signer.pendingRequests[requestId] = pendingNip46Request{encryption: encryption, response: responseChannel}
+	signer.mutex.Unlock()
+	defer func() {
+		signer.mutex.Lock()
+		delete(signer.pendingRequests, requestId)
+		signer.mutex.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(signer.ctx, signer.timeout)
+	defer cancel()
+
+	publishFailed := make(chan error, 1)
+	go func() {
+		published := false
+		for result := range signer.pool.PublishMany(ctx, signer.relayUrls, event) {
+			if result.Error != nil {
+				logger.Logger.WithError(result.Error).WithFields(logrus.Fields{
+					"relay":  result.RelayURL,
+					"method": method,
+				}).Warn("Failed to publish NIP-46 request")
+				continue
+			}
+			published = true
+		}
+		if !published {
+			publishFailed <- errors.New("failed to publish NIP-46 request to any relay")
+		}
+	}()
+
+	select {
+	case response := <-responseChannel:
+		if response.ID == "" {
+			return "", fmt.Errorf("%w: %s", errNip46DecryptFailed, response.Error)
+		}
+		if response.Error != "" {
+			return "", fmt.Errorf("NIP-46 bunker returned an error for %s: %s", method, response.Error)
+		}
+		return response.Result, nil
+	case err := <-publishFailed:
+		return "", err
+	case <-ctx.Done():
+		if signer.ctx.Err() != nil {
+			return "", signer.ctx.Err()
+		}
+		return "", fmt.Errorf("%w: %s after %s", errNip46Timeout, method, signer.timeout)
+	}
+}
+
+func (signer *nip46Signer) responseFilter() nostr.Filter {
+	return nostr.Filter{
+		Kinds:   []int{NIP46_KIND},
+		Authors: []string{signer.remotePubkey},
+		Tags:    nostr.TagMap{"p": []string{signer.clientSigner.GetPublicKey()}},
+	}
+}
+
+// watchResponses handles responses from the bunker and resubscribes if the subscription ends
+func (signer *nip46Signer) watchResponses(eventsChannel chan nostr.RelayEvent) {
+	for {
+		for relayEvent := range eventsChannel {
+			signer.handleResponse(relayEvent.Event)
+		}
+
+		select {
+		case <-signer.ctx.Done():
+			return
+		case <-time.After(3 * time.Second):
+			logger.Logger.Info("NIP-46 subscription ended, resubscribing")
+		}
+		eventsChannel = signer.pool.SubscribeMany(signer.ctx, signer.relayUrls, signer.responseFilter())
+	}
+}
+
+func (signer *nip46Signer) handleResponse(event *nostr.Event) {
+	if event == nil || event.PubKey != signer.remotePubkey {
+		return
+	}
+	valid, err := event.CheckSignature()
+	if err != nil || !valid {
+		logger.Logger.WithField("event_id", event.ID).Warn("Ignoring NIP-46 response with invalid signature")
+		return
+	}
+
+	encryption := constants.ENCRYPTION_TYPE_NIP44_V2
+	if strings.Contains(event.Content, "?iv=") {
+		encryption = constants.ENCRYPTION_TYPE_NIP04
+	}
+	payload, err := signer.clientSigner.Decrypt(encryption, signer.remotePubkey, event.Content)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("event_id", event.ID).Warn("Failed to decrypt NIP-46 response")
+		return
+	}
+
+	response := nip46Response{}
+	err = json.Unmarshal([]byte(payload), &response)
+	if err != nil {
+		logger.Logger.WithError(err).WithField("event_id", event.ID).Warn("Failed to parse NIP-46 response")
+		return
+	}
+
+	if response.Result == "auth_url" {
+		// the request stays pending until the user approves it or it times out
+		logger.Logger.WithField("auth_url", response.Error).Warn("NIP-46 bunker requires authorization, open the URL to approve the request")
+		return
+	}
+
+	signer.mutex.Lock()
+	defer signer.mutex.Unlock()
+
+	// a bunker which cannot decrypt a request does not know its id, so the error applies
+	// to all pending requests sent with another encryption than the one it answered with
+	if response.ID == "" && response.Error != "" {
+		for requestId, pendingRequest := range signer.pendingRequests {
+			if pendingRequest.encryption == encryption {
+				continue
+			}
+			delete(signer.pendingRequests, requestId)
+			pendingRequest.response <- response
+		}
+		return
+	}
+
+	pendingRequest, ok := signer.pendingRequests[response.ID]
+	// relays deliver the same response more than once
+	delete(signer.pendingRequests, response.ID)
+	if ok {
+		pendingRequest.response <- response
+	}
+}
+
+func isValidNostrPubkey(pubkey string) bool {
+	pubkeyBytes, err := hex.DecodeString(pubkey)
+	return err == nil && len(pubkeyBytes) == 32
+}
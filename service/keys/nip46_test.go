@@ -0,0 +1,142 @@
+package keys_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/go-nostr/nip44"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/nip47/cipher"
+	"github.com/getAlby/hub/service/keys"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestNip46Signer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := tests.NewMockSimplePool()
+	bunker, err := tests.NewMockBunker(pool, "bunker-secret")
+	require.NoError(t, err)
+
+	signer, err := keys.NewNip46Signer(ctx, pool, bunker.BunkerUrl(), nostr.GeneratePrivateKey(), 5*time.Second)
+	require.NoError(t, err)
+	require.NoError(t, signer.Connect())
+	assert.Equal(t, bunker.UserSigner.GetPublicKey(), signer.GetPublicKey())
+
+	event := &nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindTextNote,
+		Tags:      nostr.Tags{[]string{"t", "test"}},
+		Content:   "test",
+	}
+	require.NoError(t, signer.SignEvent(event))
+	assert.Equal(t, bunker.UserSigner.GetPublicKey(), event.PubKey)
+	valid, err := event.CheckSignature()
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// NIP-47 messages are encrypted by the bunker
+	clientSecretKey := nostr.GeneratePrivateKey()
+	clientPubkey, err := nostr.GetPublicKey(clientSecretKey)
+	require.NoError(t, err)
+	nip47Cipher, err := cipher.NewNip47CipherWithSigner(constants.ENCRYPTION_TYPE_NIP44_V2, clientPubkey, signer)
+	require.NoError(t, err)
+	msg, err := nip47Cipher.Encrypt("test payload")
+	require.NoError(t, err)
+	conversationKey, err := nip44.GenerateConversationKey(signer.GetPublicKey(), clientSecretKey)
+	require.NoError(t, err)
+	decrypted, err := nip44.Decrypt(msg, conversationKey)
+	require.NoError(t, err)
+	assert.Equal(t, "test payload", decrypted)
+	decrypted, err = nip47Cipher.Decrypt(msg)
+	require.NoError(t, err)
+	assert.Equal(t, "test payload", decrypted)
+
+	assert.Equal(t, []string{"connect", "get_public_key", "sign_event", "nip44_encrypt", "nip44_decrypt"}, bunker.GetReceivedMethods())
+}
+
+func TestNip46Signer_WrongSecret(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := tests.NewMockSimplePool()
+	bunker, err := tests.NewMockBunker(pool, "bunker-secret")
+	require.NoError(t, err)
+	bunker.Secret = "other-secret"
+
+	signer, err := keys.NewNip46Signer(ctx, pool, bunker.BunkerUrl(), nostr.GeneratePrivateKey(), 5*time.Second)
+	require.NoError(t, err)
+	bunker.Secret = "bunker-secret"
+
+	err = signer.Connect()
+	assert.EqualError(t, err, "NIP-46 bunker returned an error for connect: invalid secret")
+}
+
+func TestNip46Signer_Timeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := tests.NewMockSimplePool()
+	bunker, err := tests.NewMockBunker(pool, "")
+	require.NoError(t, err)
+	bunker.Offline = true
+
+	signer, err := keys.NewNip46Signer(ctx, pool, bunker.BunkerUrl(), nostr.GeneratePrivateKey(), 100*time.Millisecond)
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = signer.Connect()
+	assert.EqualError(t, err, "NIP-46 request timed out: connect after 100ms")
+	// a timeout is not retried with NIP-04
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+	assert.Len(t, pool.PublishedEvents, 1)
+}
+
+func TestNip46Signer_Nip04Fallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := tests.NewMockSimplePool()
+	bunker, err := tests.NewMockBunker(pool, "")
+	require.NoError(t, err)
+	bunker.Nip04Only = true
+
+	signer, err := keys.NewNip46Signer(ctx, pool, bunker.BunkerUrl(), nostr.GeneratePrivateKey(), 5*time.Second)
+	require.NoError(t, err)
+	// the bunker reports that it cannot decrypt the NIP-44 request, so it is retried without waiting for the timeout
+	start := time.Now()
+	require.NoError(t, signer.Connect())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, bunker.UserSigner.GetPublicKey(), signer.GetPublicKey())
+
+	// once the bunker responded to NIP-04, later requests are sent with NIP-04 directly
+	publishedEvents := len(pool.PublishedEvents)
+	event := &nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindTextNote,
+		Content:   "test",
+	}
+	require.NoError(t, signer.SignEvent(event))
+	assert.Len(t, pool.PublishedEvents, publishedEvents+1)
+	assert.Equal(t, []string{"connect", "get_public_key", "sign_event"}, bunker.GetReceivedMethods())
+}
+
+func TestNewNip46Signer_InvalidUrl(t *testing.T) {
+	clientSecretKey := nostr.GeneratePrivateKey()
+
+	_, err := keys.NewNip46Signer(context.Background(), tests.NewMockSimplePool(), "nostrconnect://abc", clientSecretKey, time.Second)
+	assert.EqualError(t, err, "invalid bunker URL scheme: nostrconnect")
+
+	_, err = keys.NewNip46Signer(context.Background(), tests.NewMockSimplePool(), "bunker://abc?relay=wss://relay.example.com", clientSecretKey, time.Second)
+	assert.EqualError(t, err, "invalid remote signer pubkey in bunker URL")
+
+	_, err = keys.NewNip46Signer(context.Background(), tests.NewMockSimplePool(), "bunker://"+strings.Repeat("a", 64), clientSecretKey, time.Second)
+	assert.EqualError(t, err, "bunker URL has no relays")
+}
@@ -0,0 +1,551 @@
+package keys
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/miekg/pkcs11"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	pkcs11NostrKeyLabel = "albyhub-nostr"
+	// AES key generated inside the token to encrypt the keys used for signing
+	pkcs11WrapKeyLabel = "albyhub-wrap"
+	// config key of the wallet service public key once its secret key is held by the token
+	pkcs11NostrPublicKeyConfigKey = "PKCS11NostrPublicKey"
+	uncompressedPubKeyLen         = 65
+	aesBlockSize                  = 16
+)
+
+// DER-encoded OID of the secp256k1 curve (1.3.132.0.10)
+var secp256k1ECParams = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+func pkcs11AppWalletKeyLabel(appID uint) string {
+	return fmt.Sprintf("albyhub-app-%d", appID)
+}
+
+// label of the data object holding the encrypted copy of the key with the given label
+func pkcs11WrappedKeyLabel(label string) string {
+	return label + "-wrapped"
+}
+
+// pkcs11Token holds Nostr keys in a PKCS#11 token (e.g. SoftHSM).
+// Keys are imported into the token the first time they are used
+// and are afterwards referenced by their label.
+type pkcs11Token struct {
+	ctx      *pkcs11.Ctx
+	slotID   uint
+	session  pkcs11.SessionHandle
+	finalize bool
+	// vendor-defined mechanism used to create BIP-340 signatures, if the token has one
+	signMechanism uint
+	// AES key used to encrypt the keys used for signing if there is no sign mechanism
+	wrapKey    pkcs11.ObjectHandle
+	sessionMtx sync.Mutex
+	signers    map[string]*pkcs11Signer
+	signersMtx sync.Mutex
+}
+
+func openPKCS11Token(modulePath string, tokenLabel string, pin string) (*pkcs11Token, error) {
+	ctx := pkcs11.New(modulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", modulePath)
+	}
+
+	finalize := true
+	err := ctx.Initialize()
+	if errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		// the module is shared with another token in this process
+		finalize = false
+		err = nil
+	}
+	if err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module: %w", err)
+	}
+
+	token := &pkcs11Token{
+		ctx:      ctx,
+		finalize: finalize,
+		signers:  map[string]*pkcs11Signer{},
+	}
+
+	token.slotID, err = token.findSlot(tokenLabel)
+	if err != nil {
+		token.close()
+		return nil, err
+	}
+
+	token.session, err = ctx.OpenSession(token.slotID, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		token.close()
+		return nil, fmt.Errorf("failed to open PKCS#11 session: %w", err)
+	}
+
+	err = ctx.Login(token.session, pkcs11.CKU_USER, pin)
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+		token.close()
+		return nil, fmt.Errorf("failed to log in to PKCS#11 token: %w", err)
+	}
+
+	logger.Logger.WithField("token", tokenLabel).Info("Opened PKCS#11 token")
+	return token, nil
+}
+
+func (token *pkcs11Token) findSlot(tokenLabel string) (uint, error) {
+	slots, err := token.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+	for _, slotID := range slots {
+		tokenInfo, err := token.ctx.GetTokenInfo(slotID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get PKCS#11 token info: %w", err)
+		}
+		if tokenInfo.Label == tokenLabel {
+			return slotID, nil
+		}
+	}
+	return 0, fmt.Errorf("PKCS#11 token %q not found", tokenLabel)
+}
+
+// setSignMechanism selects how events are signed. PKCS#11 does not define a
+// mechanism for BIP-340 signatures, so events are only signed inside the token
+// if the vendor-defined mechanism of the token is configured. Otherwise (e.g.
+// with SoftHSM) the token keeps a copy of each key encrypted with an AES key
+// that never leaves the token, and decrypts it only to sign an event.
+func (token *pkcs11Token) setSignMechanism(signMechanism uint) error {
+	mechanisms, err := token.ctx.GetMechanismList(token.slotID)
+	if err != nil {
+		return fmt.Errorf("failed to list PKCS#11 mechanisms: %w", err)
+	}
+	isSupported := func(mechanism uint) bool {
+		for _, supportedMechanism := range mechanisms {
+			if supportedMechanism.Mechanism == mechanism {
+				return true
+			}
+		}
+		return false
+	}
+
+	if signMechanism != 0 {
+		if !isSupported(signMechanism) {
+			return fmt.Errorf("PKCS#11 token does not support the sign mechanism 0x%x", signMechanism)
+		}
+		token.signMechanism = signMechanism
+		return nil
+	}
+
+	for _, mechanism := range []uint{pkcs11.CKM_AES_KEY_GEN, pkcs11.CKM_AES_CBC_PAD} {
+		if !isSupported(mechanism) {
+			return fmt.Errorf("PKCS#11 token does not support AES encryption (mechanism 0x%x), please set PKCS11_SIGN_MECHANISM to the BIP-340 mechanism of your token", mechanism)
+		}
+	}
+
+	token.sessionMtx.Lock()
+	defer token.sessionMtx.Unlock()
+
+	wrapKey, found, err := token.findObject(pkcs11.CKO_SECRET_KEY, pkcs11WrapKeyLabel)
+	if err != nil {
+		return err
+	}
+	if !found {
+		wrapKey, err = token.ctx.GenerateKey(token.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)}, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+			pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, pkcs11WrapKeyLabel),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to generate AES key in PKCS#11 token: %w", err)
+		}
+		logger.Logger.WithField("label", pkcs11WrapKeyLabel).Info("Generated AES key in PKCS#11 token")
+	}
+	token.wrapKey = wrapKey
+	return nil
+}
+
+func (token *pkcs11Token) close() {
+	if token.session != 0 {
+		token.ctx.CloseSession(token.session)
+	}
+	if token.finalize {
+		token.ctx.Finalize()
+	}
+	token.ctx.Destroy()
+}
+
+// signer returns a signer for the key with the given label. getKey returns the
+// x-only public key of the key and its secret key, which may be nil if only the
+// public key is known. If the token does not contain the key yet, it is imported.
+// The secret key is cleared afterwards.
+func (token *pkcs11Token) signer(label string, getKey func() (string, []byte, error)) (ECDHSigner, error) {
+	token.signersMtx.Lock()
+	defer token.signersMtx.Unlock()
+
+	if signer, ok := token.signers[label]; ok {
+		return signer, nil
+	}
+
+	publicKey, secretKey, err := getKey()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(secretKey)
+
+	var privateKey *btcec.PrivateKey
+	if secretKey != nil {
+		if len(secretKey) != btcec.PrivKeyBytesLen {
+			return nil, errors.New("invalid secret key")
+		}
+		privateKey, _ = btcec.PrivKeyFromBytes(secretKey)
+		defer privateKey.Zero()
+		if hex.EncodeToString(schnorr.SerializePubKey(privateKey.PubKey())) != publicKey {
+			return nil, fmt.Errorf("secret key does not match public key for label %s", label)
+		}
+	}
+
+	token.sessionMtx.Lock()
+	defer token.sessionMtx.Unlock()
+
+	handle, found, err := token.findObject(pkcs11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		storedPublicKey, err := token.getPublicKey(label)
+		if err != nil {
+			return nil, err
+		}
+		if hex.EncodeToString(schnorr.SerializePubKey(storedPublicKey)) != publicKey {
+			return nil, fmt.Errorf("PKCS#11 token contains a different key with label %s", label)
+		}
+	} else {
+		if privateKey == nil {
+			return nil, fmt.Errorf("PKCS#11 token does not contain the key with label %s", label)
+		}
+		handle, err = token.importKey(label, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		logger.Logger.WithField("label", label).Info("Imported key into PKCS#11 token")
+	}
+
+	var wrappedKeyHandle pkcs11.ObjectHandle
+	if token.signMechanism == 0 {
+		wrappedKeyHandle, err = token.getOrImportWrappedKey(label, privateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signer := &pkcs11Signer{
+		token:            token,
+		handle:           handle,
+		wrappedKeyHandle: wrappedKeyHandle,
+		publicKey:        publicKey,
+	}
+	token.signers[label] = signer
+	return signer, nil
+}
+
+func (token *pkcs11Token) findObject(class uint, label string) (pkcs11.ObjectHandle, bool, error) {
+	err := token.ctx.FindObjectsInit(token.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to find PKCS#11 object: %w", err)
+	}
+	defer token.ctx.FindObjectsFinal(token.session)
+
+	handles, _, err := token.ctx.FindObjects(token.session, 1)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to find PKCS#11 object: %w", err)
+	}
+	if len(handles) == 0 {
+		return 0, false, nil
+	}
+	return handles[0], true, nil
+}
+
+func (token *pkcs11Token) getPublicKey(label string) (*btcec.PublicKey, error) {
+	handle, found, err := token.findObject(pkcs11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("PKCS#11 token does not contain a public key with label %s", label)
+	}
+	attributes, err := token.ctx.GetAttributeValue(token.session, handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key from PKCS#11 token: %w", err)
+	}
+	ecPoint := attributes[0].Value
+	// CKA_EC_POINT is a DER-encoded octet string
+	if len(ecPoint) == uncompressedPubKeyLen+2 && ecPoint[0] == 0x04 {
+		ecPoint = ecPoint[2:]
+	}
+	return btcec.ParsePubKey(ecPoint)
+}
+
+func (token *pkcs11Token) importKey(label string, privateKey *btcec.PrivateKey) (pkcs11.ObjectHandle, error) {
+	ecPoint := append([]byte{0x04, uncompressedPubKeyLen}, privateKey.PubKey().SerializeUncompressed()...)
+	_, err := token.ctx.CreateObject(token.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, secp256k1ECParams),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, ecPoint),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import public key into PKCS#11 token: %w", err)
+	}
+
+	// the key value can never be read from the token
+	secretKey := privateKey.Serialize()
+	defer clear(secretKey)
+	handle, err := token.ctx.CreateObject(token.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_DERIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, secp256k1ECParams),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, secretKey),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import private key into PKCS#11 token: %w", err)
+	}
+	return handle, nil
+}
+
+// getOrImportWrappedKey returns the data object holding the key with the given label
+// encrypted with the AES key of the token, which is created if privateKey is set.
+// The data object can be read, but only decrypted inside the token.
+func (token *pkcs11Token) getOrImportWrappedKey(label string, privateKey *btcec.PrivateKey) (pkcs11.ObjectHandle, error) {
+	handle, found, err := token.findObject(pkcs11.CKO_DATA, pkcs11WrappedKeyLabel(label))
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return handle, nil
+	}
+	if privateKey == nil {
+		return 0, fmt.Errorf("PKCS#11 token does not contain the encrypted signing key with label %s", label)
+	}
+
+	iv := make([]byte, aesBlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return 0, err
+	}
+	secretKey := privateKey.Serialize()
+	defer clear(secretKey)
+	err = token.ctx.EncryptInit(token.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_CBC_PAD, iv)}, token.wrapKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt key in PKCS#11 token: %w", err)
+	}
+	ciphertext, err := token.ctx.Encrypt(token.session, secretKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt key in PKCS#11 token: %w", err)
+	}
+
+	handle, err = token.ctx.CreateObject(token.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_DATA),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, pkcs11WrappedKeyLabel(label)),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, append(iv, ciphertext...)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import encrypted key into PKCS#11 token: %w", err)
+	}
+	logger.Logger.WithField("label", label).Info("Imported encrypted signing key into PKCS#11 token")
+	return handle, nil
+}
+
+type pkcs11Signer struct {
+	token  *pkcs11Token
+	handle pkcs11.ObjectHandle
+	// set if the token has no BIP-340 sign mechanism
+	wrappedKeyHandle pkcs11.ObjectHandle
+	publicKey        string
+}
+
+func (signer *pkcs11Signer) GetPublicKey() string {
+	return signer.publicKey
+}
+
+// ECDH derives the shared secret inside the token
+func (signer *pkcs11Signer) ECDH(pubkey string) ([]byte, error) {
+	pubkeyBytes, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	peerPublicKey, err := schnorr.ParsePubKey(pubkeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	token := signer.token
+	token.sessionMtx.Lock()
+	defer token.sessionMtx.Unlock()
+
+	mechanism := pkcs11.NewMechanism(pkcs11.CKM_ECDH1_DERIVE, pkcs11.NewECDH1DeriveParams(pkcs11.CKD_NULL, nil, peerPublicKey.SerializeUncompressed()))
+	sharedSecretHandle, err := token.ctx.DeriveKey(token.session, []*pkcs11.Mechanism{mechanism}, signer.handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret in PKCS#11 token: %w", err)
+	}
+	defer token.ctx.DestroyObject(token.session, sharedSecretHandle)
+
+	attributes, err := token.ctx.GetAttributeValue(token.session, sharedSecretHandle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared secret from PKCS#11 token: %w", err)
+	}
+	return attributes[0].Value, nil
+}
+
+func (signer *pkcs11Signer) Encrypt(encryption, pubkey, plaintext string) (string, error) {
+	return ecdhEncrypt(signer, encryption, pubkey, plaintext)
+}
+
+func (signer *pkcs11Signer) Decrypt(encryption, pubkey, ciphertext string) (string, error) {
+	return ecdhDecrypt(signer, encryption, pubkey, ciphertext)
+}
+
+// SignEvent signs the event id inside the token with the configured BIP-340
+// mechanism, or with the key decrypted by the token if there is none.
+// The signature is verified before it is used.
+func (signer *pkcs11Signer) SignEvent(event *nostr.Event) error {
+	event.PubKey = signer.publicKey
+	id := event.GetID()
+	idBytes, err := hex.DecodeString(id)
+	if err != nil {
+		return err
+	}
+
+	var sig []byte
+	if signer.token.signMechanism != 0 {
+		sig, err = signer.signInToken(idBytes)
+	} else {
+		sig, err = signer.signWithWrappedKey(idBytes)
+	}
+	if err != nil {
+		return err
+	}
+
+	pubkeyBytes, err := hex.DecodeString(signer.publicKey)
+	if err != nil {
+		return err
+	}
+	publicKey, err := schnorr.ParsePubKey(pubkeyBytes)
+	if err != nil {
+		return err
+	}
+	signature, err := schnorr.ParseSignature(sig)
+	if err != nil || !signature.Verify(idBytes, publicKey) {
+		return errors.New("PKCS#11 token returned an invalid BIP-340 signature")
+	}
+
+	event.ID = id
+	event.Sig = hex.EncodeToString(sig)
+	return nil
+}
+
+func (signer *pkcs11Signer) signInToken(hash []byte) ([]byte, error) {
+	token := signer.token
+	token.sessionMtx.Lock()
+	defer token.sessionMtx.Unlock()
+
+	err := token.ctx.SignInit(token.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(token.signMechanism, nil)}, signer.handle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign event in PKCS#11 token: %w", err)
+	}
+	sig, err := token.ctx.Sign(token.session, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign event in PKCS#11 token: %w", err)
+	}
+	return sig, nil
+}
+
+// signWithWrappedKey lets the token decrypt the key for a single BIP-340
+// signature. The decrypted key is cleared once the event is signed.
+func (signer *pkcs11Signer) signWithWrappedKey(hash []byte) ([]byte, error) {
+	secretKey, err := signer.decryptWrappedKey()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(secretKey)
+	if len(secretKey) != btcec.PrivKeyBytesLen {
+		return nil, errors.New("PKCS#11 token returned an invalid signing key")
+	}
+	privateKey, _ := btcec.PrivKeyFromBytes(secretKey)
+	defer privateKey.Zero()
+	if hex.EncodeToString(schnorr.SerializePubKey(privateKey.PubKey())) != signer.publicKey {
+		return nil, errors.New("PKCS#11 token returned a signing key that does not match the public key")
+	}
+
+	sig, err := schnorr.Sign(privateKey, hash)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Serialize(), nil
+}
+
+func (signer *pkcs11Signer) decryptWrappedKey() ([]byte, error) {
+	token := signer.token
+	token.sessionMtx.Lock()
+	defer token.sessionMtx.Unlock()
+
+	attributes, err := token.ctx.GetAttributeValue(token.session, signer.wrappedKeyHandle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted signing key from PKCS#11 token: %w", err)
+	}
+	wrappedKey := attributes[0].Value
+	if len(wrappedKey) <= aesBlockSize {
+		return nil, errors.New("PKCS#11 token contains an invalid encrypted signing key")
+	}
+
+	err = token.ctx.DecryptInit(token.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_CBC_PAD, wrappedKey[:aesBlockSize])}, token.wrapKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key in PKCS#11 token: %w", err)
+	}
+	secretKey, err := token.ctx.Decrypt(token.session, wrappedKey[aesBlockSize:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key in PKCS#11 token: %w", err)
+	}
+	return secretKey, nil
+}
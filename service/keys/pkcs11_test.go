@@ -0,0 +1,308 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/go-nostr/nip04"
+	"github.com/getAlby/go-nostr/nip44"
+	"github.com/miekg/pkcs11"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/tests/db"
+)
+
+const (
+	softHSMTokenLabel = "albyhub-test"
+	softHSMPin        = "1234"
+)
+
+var softHSMModulePaths = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+// setupSoftHSM creates an empty SoftHSM token in a temporary directory
+// and returns the path of the SoftHSM module
+func setupSoftHSM(t *testing.T) string {
+	modulePath := os.Getenv("SOFTHSM2_MODULE")
+	if modulePath == "" {
+		for _, path := range softHSMModulePaths {
+			if _, err := os.Stat(path); err == nil {
+				modulePath = path
+				break
+			}
+		}
+	}
+	if modulePath == "" {
+		t.Skip("SoftHSM is not installed, set SOFTHSM2_MODULE to run PKCS#11 tests")
+	}
+
+	tokenDir := t.TempDir()
+	configPath := filepath.Join(tokenDir, "softhsm2.conf")
+	require.NoError(t, os.WriteFile(configPath, []byte("directories.tokendir = "+tokenDir+"\nobjectstore.backend = file\n"), 0600))
+	t.Setenv("SOFTHSM2_CONF", configPath)
+
+	ctx := pkcs11.New(modulePath)
+	require.NotNil(t, ctx)
+	defer ctx.Destroy()
+	require.NoError(t, ctx.Initialize())
+	defer ctx.Finalize()
+
+	slots, err := ctx.GetSlotList(true)
+	require.NoError(t, err)
+	require.NotEmpty(t, slots)
+	require.NoError(t, ctx.InitToken(slots[0], "so-pin", softHSMTokenLabel))
+
+	// SoftHSM moves the initialized token to a new slot
+	token := &pkcs11Token{ctx: ctx}
+	slotID, err := token.findSlot(softHSMTokenLabel)
+	require.NoError(t, err)
+	session, err := ctx.OpenSession(slotID, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	require.NoError(t, err)
+	defer ctx.CloseSession(session)
+	require.NoError(t, ctx.Login(session, pkcs11.CKU_SO, "so-pin"))
+	require.NoError(t, ctx.InitPIN(session, softHSMPin))
+	require.NoError(t, ctx.Logout(session))
+
+	return modulePath
+}
+
+func TestPKCS11Keys(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	modulePath := setupSoftHSM(t)
+
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	mnemonic := "thought turkey ask pottery head say catalog desk pledge elbow naive mimic"
+	unlockPassword := "123"
+
+	cfg, err := config.NewConfig(&config.AppConfig{
+		PKCS11Module:     modulePath,
+		PKCS11TokenLabel: softHSMTokenLabel,
+		PKCS11Pin:        softHSMPin,
+	}, gormDb)
+	require.NoError(t, err)
+	require.NoError(t, cfg.SetUpdate("Mnemonic", mnemonic, unlockPassword))
+	nostrSecretKey := nostr.GeneratePrivateKey()
+	require.NoError(t, cfg.SetUpdate("NostrSecretKey", nostrSecretKey, unlockPassword))
+	nostrPublicKey, err := nostr.GetPublicKey(nostrSecretKey)
+	require.NoError(t, err)
+
+	keys := NewKeys(context.Background())
+	require.NoError(t, keys.Init(cfg, unlockPassword))
+	assert.Equal(t, nostrPublicKey, keys.GetNostrPublicKey())
+
+	// the secret key is kept in the database as a recovery copy
+	storedSecretKey, err := cfg.Get("NostrSecretKey", unlockPassword)
+	require.NoError(t, err)
+	assert.Equal(t, nostrSecretKey, storedSecretKey)
+	storedPublicKey, err := cfg.Get(pkcs11NostrPublicKeyConfigKey, "")
+	require.NoError(t, err)
+	assert.Equal(t, nostrPublicKey, storedPublicKey)
+
+	// the key value cannot be read from the token
+	handle, found, err := keys.pkcs11Token.findObject(pkcs11.CKO_PRIVATE_KEY, pkcs11NostrKeyLabel)
+	require.NoError(t, err)
+	require.True(t, found)
+	_, err = keys.pkcs11Token.ctx.GetAttributeValue(keys.pkcs11Token.session, handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	assert.Error(t, err)
+
+	// SoftHSM has no BIP-340 mechanism, so events are signed with the key decrypted by the token
+	event := &nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindTextNote,
+		Content:   "test",
+	}
+	require.NoError(t, keys.GetNostrSigner().SignEvent(event))
+	assert.Equal(t, nostrPublicKey, event.PubKey)
+	valid, err := event.CheckSignature()
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// app wallet keys are derived in the same way as without a token
+	appWalletSigner, err := keys.GetAppWalletSigner(2)
+	require.NoError(t, err)
+	assert.Equal(t, "dd9e304d24f29f3481d5cf18a76c85ca3e95931aee3c997a27f267e975e72976", appWalletSigner.GetPublicKey())
+
+	// the shared secret derived in the token matches the other side
+	clientSecretKey := nostr.GeneratePrivateKey()
+	clientPubkey, err := nostr.GetPublicKey(clientSecretKey)
+	require.NoError(t, err)
+	sharedSecret, err := appWalletSigner.(ECDHSigner).ECDH(clientPubkey)
+	require.NoError(t, err)
+	expectedSharedSecret, err := nip04.ComputeSharedSecret(appWalletSigner.GetPublicKey(), clientSecretKey)
+	require.NoError(t, err)
+	assert.Equal(t, expectedSharedSecret, sharedSecret)
+
+	keys.pkcs11Token.close()
+
+	// the keys imported into the token are reused
+	keys = NewKeys(context.Background())
+	require.NoError(t, keys.Init(cfg, unlockPassword))
+	defer keys.pkcs11Token.close()
+	assert.Equal(t, nostrPublicKey, keys.GetNostrPublicKey())
+	_, found, err = keys.pkcs11Token.findObject(pkcs11.CKO_PRIVATE_KEY, pkcs11AppWalletKeyLabel(2))
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	// the wallet service key is recovered from the database when the token is no longer configured
+	cfgWithoutToken, err := config.NewConfig(&config.AppConfig{}, gormDb)
+	require.NoError(t, err)
+	keysWithoutToken := NewKeys(context.Background())
+	require.NoError(t, keysWithoutToken.Init(cfgWithoutToken, unlockPassword))
+	assert.Equal(t, nostrPublicKey, keysWithoutToken.GetNostrPublicKey())
+
+	// the wallet service key is not replaced if only the token holds it
+	require.NoError(t, cfgWithoutToken.SetUpdate("NostrSecretKey", "", ""))
+	err = NewKeys(context.Background()).Init(cfgWithoutToken, unlockPassword)
+	assert.EqualError(t, err, "the nostr secret key is held by a PKCS#11 token, please set PKCS11_MODULE")
+}
+
+func TestPKCS11Keys_SignNip47Response(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	modulePath := setupSoftHSM(t)
+
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	unlockPassword := "123"
+	cfg, err := config.NewConfig(&config.AppConfig{
+		PKCS11Module:     modulePath,
+		PKCS11TokenLabel: softHSMTokenLabel,
+		PKCS11Pin:        softHSMPin,
+	}, gormDb)
+	require.NoError(t, err)
+	require.NoError(t, cfg.SetUpdate("Mnemonic", "thought turkey ask pottery head say catalog desk pledge elbow naive mimic", unlockPassword))
+
+	keys := NewKeys(context.Background())
+	require.NoError(t, keys.Init(cfg, unlockPassword))
+	defer keys.pkcs11Token.close()
+
+	appWalletSigner, err := keys.GetAppWalletSigner(1)
+	require.NoError(t, err)
+
+	clientSecretKey := nostr.GeneratePrivateKey()
+	clientPubkey, err := nostr.GetPublicKey(clientSecretKey)
+	require.NoError(t, err)
+	requestEventId := "e4f2c4b5a7a1d5c52bdc5fb5b2c7c2e3f6f0a4b7f6e8d3c2b1a0f9e8d7c6b5a4"
+
+	// encrypted and signed in the same way as responses of the NIP-47 service
+	responsePayload, err := json.Marshal(&models.Response{
+		ResultType: models.GET_BALANCE_METHOD,
+		Result:     map[string]interface{}{"balance": 21000},
+	})
+	require.NoError(t, err)
+	content, err := appWalletSigner.Encrypt(constants.ENCRYPTION_TYPE_NIP44_V2, clientPubkey, string(responsePayload))
+	require.NoError(t, err)
+	response := &nostr.Event{
+		PubKey:    appWalletSigner.GetPublicKey(),
+		CreatedAt: nostr.Now(),
+		Kind:      models.RESPONSE_KIND,
+		Tags:      nostr.Tags{[]string{"p", clientPubkey}, []string{"e", requestEventId}},
+		Content:   content,
+	}
+	require.NoError(t, appWalletSigner.SignEvent(response))
+
+	// the client verifies the signature and decrypts the response
+	valid, err := response.CheckSignature()
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, appWalletSigner.GetPublicKey(), response.PubKey)
+	conversationKey, err := nip44.GenerateConversationKey(response.PubKey, clientSecretKey)
+	require.NoError(t, err)
+	decrypted, err := nip44.Decrypt(response.Content, conversationKey)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"result_type":"get_balance","result":{"balance":21000}}`, decrypted)
+
+	// the signing key is only stored encrypted with the AES key of the token
+	_, found, err := keys.pkcs11Token.findObject(pkcs11.CKO_DATA, pkcs11WrappedKeyLabel(pkcs11AppWalletKeyLabel(1)))
+	require.NoError(t, err)
+	assert.True(t, found)
+	wrapKey, found, err := keys.pkcs11Token.findObject(pkcs11.CKO_SECRET_KEY, pkcs11WrapKeyLabel)
+	require.NoError(t, err)
+	require.True(t, found)
+	_, err = keys.pkcs11Token.ctx.GetAttributeValue(keys.pkcs11Token.session, wrapKey, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	assert.Error(t, err)
+}
+
+func TestPKCS11Keys_SignMechanism(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	modulePath := setupSoftHSM(t)
+
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(gormDb)
+
+	appConfig := &config.AppConfig{
+		PKCS11Module:     modulePath,
+		PKCS11TokenLabel: softHSMTokenLabel,
+		PKCS11Pin:        softHSMPin,
+	}
+	cfg, err := config.NewConfig(appConfig, gormDb)
+	require.NoError(t, err)
+
+	// without a sign mechanism, events are signed with keys encrypted by the token
+	keys := NewKeys(context.Background())
+	require.NoError(t, keys.Init(cfg, "123"))
+	assert.NotZero(t, keys.pkcs11Token.wrapKey)
+	keys.pkcs11Token.close()
+
+	appConfig.PKCS11SignMechanism = pkcs11.CKM_VENDOR_DEFINED + 1
+	err = NewKeys(context.Background()).Init(cfg, "123")
+	assert.ErrorContains(t, err, "PKCS#11 token does not support the sign mechanism")
+}
+
+func TestPKCS11Keys_DifferentKeyInToken(t *testing.T) {
+	logger.Init(strconv.Itoa(int(logrus.DebugLevel)))
+	modulePath := setupSoftHSM(t)
+
+	unlockPassword := "123"
+	appConfig := &config.AppConfig{
+		PKCS11Module:     modulePath,
+		PKCS11TokenLabel: softHSMTokenLabel,
+		PKCS11Pin:        softHSMPin,
+	}
+
+	gormDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	cfg, err := config.NewConfig(appConfig, gormDb)
+	require.NoError(t, err)
+
+	keys := NewKeys(context.Background())
+	require.NoError(t, keys.Init(cfg, unlockPassword))
+	keys.pkcs11Token.close()
+	db.CloseDB(gormDb)
+
+	// another hub with a different nostr key must not use the key in the token
+	otherDb, err := db.NewDB(t)
+	require.NoError(t, err)
+	defer db.CloseDB(otherDb)
+	otherCfg, err := config.NewConfig(appConfig, otherDb)
+	require.NoError(t, err)
+
+	otherKeys := NewKeys(context.Background())
+	err = otherKeys.Init(otherCfg, unlockPassword)
+	assert.EqualError(t, err, "PKCS#11 token contains a different key with label albyhub-nostr")
+	otherKeys.pkcs11Token.close()
+}
@@ -0,0 +1,108 @@
+package keys
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/getAlby/go-nostr"
+	"github.com/getAlby/go-nostr/nip04"
+	"github.com/getAlby/go-nostr/nip44"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/getAlby/hub/constants"
+)
+
+// Signer signs Nostr events and encrypts messages for a Nostr key
+// which is not necessarily held in process memory (e.g. in a PKCS#11 token or a NIP-46 bunker)
+type Signer interface {
+	GetPublicKey() string
+	// Sets the pubkey, id and signature of the event
+	SignEvent(event *nostr.Event) error
+	// Encrypts the plaintext for the given pubkey using the given encryption (nip04 or nip44_v2)
+	Encrypt(encryption, pubkey, plaintext string) (string, error)
+	// Decrypts a ciphertext from the given pubkey using the given encryption (nip04 or nip44_v2)
+	Decrypt(encryption, pubkey, ciphertext string) (string, error)
+}
+
+// ECDHSigner is a Signer which can compute shared secrets,
+// allowing encryption keys to be derived once per conversation
+type ECDHSigner interface {
+	Signer
+	// Returns the x coordinate of the ECDH point shared with the given public key,
+	// used to derive NIP-04 and NIP-44 encryption keys
+	ECDH(pubkey string) ([]byte, error)
+}
+
+// NIP44ConversationKey derives the NIP-44 v2 conversation key from an ECDH shared x coordinate
+func NIP44ConversationKey(sharedX []byte) [32]byte {
+	var conversationKey [32]byte
+	copy(conversationKey[:], hkdf.Extract(sha256.New, sharedX, []byte("nip44-v2")))
+	return conversationKey
+}
+
+func ecdhEncrypt(signer ECDHSigner, encryption, pubkey, plaintext string) (string, error) {
+	sharedX, err := signer.ECDH(pubkey)
+	if err != nil {
+		return "", err
+	}
+	switch encryption {
+	case constants.ENCRYPTION_TYPE_NIP04:
+		return nip04.Encrypt(plaintext, sharedX)
+	case constants.ENCRYPTION_TYPE_NIP44_V2:
+		return nip44.Encrypt(plaintext, NIP44ConversationKey(sharedX))
+	default:
+		return "", fmt.Errorf("invalid encryption: %s", encryption)
+	}
+}
+
+func ecdhDecrypt(signer ECDHSigner, encryption, pubkey, ciphertext string) (string, error) {
+	sharedX, err := signer.ECDH(pubkey)
+	if err != nil {
+		return "", err
+	}
+	switch encryption {
+	case constants.ENCRYPTION_TYPE_NIP04:
+		return nip04.Decrypt(ciphertext, sharedX)
+	case constants.ENCRYPTION_TYPE_NIP44_V2:
+		return nip44.Decrypt(ciphertext, NIP44ConversationKey(sharedX))
+	default:
+		return "", fmt.Errorf("invalid encryption: %s", encryption)
+	}
+}
+
+type softwareSigner struct {
+	secretKey string
+	publicKey string
+}
+
+// NewSoftwareSigner creates a signer for a hex-encoded secret key held in memory
+func NewSoftwareSigner(secretKey string) (ECDHSigner, error) {
+	publicKey, err := nostr.GetPublicKey(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return &softwareSigner{
+		secretKey: secretKey,
+		publicKey: publicKey,
+	}, nil
+}
+
+func (signer *softwareSigner) GetPublicKey() string {
+	return signer.publicKey
+}
+
+func (signer *softwareSigner) SignEvent(event *nostr.Event) error {
+	return event.Sign(signer.secretKey)
+}
+
+func (signer *softwareSigner) ECDH(pubkey string) ([]byte, error) {
+	return nip04.ComputeSharedSecret(pubkey, signer.secretKey)
+}
+
+func (signer *softwareSigner) Encrypt(encryption, pubkey, plaintext string) (string, error) {
+	return ecdhEncrypt(signer, encryption, pubkey, plaintext)
+}
+
+func (signer *softwareSigner) Decrypt(encryption, pubkey, ciphertext string) (string, error) {
+	return ecdhDecrypt(signer, encryption, pubkey, ciphertext)
+}
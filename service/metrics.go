@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// metricsCollector reads the relay, channel and swap state on every scrape
+type metricsCollector struct {
+	svc                  *service
+	getPendingEventCount func() int64
+
+	relayConnected       *prometheus.Desc
+	channelLocalBalance  *prometheus.Desc
+	channelRemoteBalance *prometheus.Desc
+	channelActive        *prometheus.Desc
+	swaps                *prometheus.Desc
+	pendingEvents        *prometheus.Desc
+}
+
+func newMetricsCollector(svc *service, getPendingEventCount func() int64) *metricsCollector {
+	return &metricsCollector{
+		svc:                  svc,
+		getPendingEventCount: getPendingEventCount,
+		relayConnected: prometheus.NewDesc("albyhub_relay_connected",
+			"Whether the hub is connected to the relay (1) or not (0).", []string{"url"}, nil),
+		channelLocalBalance: prometheus.NewDesc("albyhub_channel_local_balance_msat",
+			"Local balance of the channel.", []string{"channel_id", "peer_pubkey"}, nil),
+		channelRemoteBalance: prometheus.NewDesc("albyhub_channel_remote_balance_msat",
+			"Remote balance of the channel.", []string{"channel_id", "peer_pubkey"}, nil),
+		channelActive: prometheus.NewDesc("albyhub_channel_active",
+			"Whether the channel is active (1) or not (0).", []string{"channel_id", "peer_pubkey"}, nil),
+		swaps: prometheus.NewDesc("albyhub_swaps",
+			"Number of swaps by type and state.", []string{"type", "state"}, nil),
+		pendingEvents: prometheus.NewDesc("albyhub_event_publisher_pending_events",
+			"Number of events which are still being consumed by subscribers.", nil, nil),
+	}
+}
+
+func (collector *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.relayConnected
+	ch <- collector.channelLocalBalance
+	ch <- collector.channelRemoteBalance
+	ch <- collector.channelActive
+	ch <- collector.swaps
+	ch <- collector.pendingEvents
+}
+
+func (collector *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, relayStatus := range collector.svc.GetRelayStatuses() {
+		ch <- prometheus.MustNewConstMetric(collector.relayConnected, prometheus.GaugeValue, boolToFloat(relayStatus.Online), relayStatus.Url)
+	}
+
+	ch <- prometheus.MustNewConstMetric(collector.pendingEvents, prometheus.GaugeValue, float64(collector.getPendingEventCount()))
+
+	var swapCounts []struct {
+		Type  string
+		State string
+		Count int64
+	}
+	err := collector.svc.db.Model(&db.Swap{}).Select("type, state, count(*) as count").Group("type, state").Scan(&swapCounts).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to count swaps for metrics")
+	}
+	for _, swapCount := range swapCounts {
+		ch <- prometheus.MustNewConstMetric(collector.swaps, prometheus.GaugeValue, float64(swapCount.Count), swapCount.Type, swapCount.State)
+	}
+
+	lnClient := collector.svc.GetLNClient()
+	if lnClient == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(collector.svc.ctx, 10*time.Second)
+	defer cancel()
+	channels, err := lnClient.ListChannels(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list channels for metrics")
+		return
+	}
+	for _, channel := range channels {
+		ch <- prometheus.MustNewConstMetric(collector.channelLocalBalance, prometheus.GaugeValue, float64(channel.LocalBalanceMsat), channel.Id, channel.RemotePubkey)
+		ch <- prometheus.MustNewConstMetric(collector.channelRemoteBalance, prometheus.GaugeValue, float64(channel.RemoteBalanceMsat), channel.Id, channel.RemotePubkey)
+		ch <- prometheus.MustNewConstMetric(collector.channelActive, prometheus.GaugeValue, boolToFloat(channel.Active), channel.Id, channel.RemotePubkey)
+	}
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -4,9 +4,12 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/backup"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/fees"
 	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/rebalance"
 	"github.com/getAlby/hub/service/keys"
 	"github.com/getAlby/hub/swaps"
 	"github.com/getAlby/hub/transactions"
@@ -29,6 +32,9 @@ type Service interface {
 	GetLNClient() lnclient.LNClient
 	GetTransactionsService() transactions.TransactionsService
 	GetSwapsService() swaps.SwapsService
+	GetBackupService() backup.BackupService
+	GetFeeManager() fees.FeeManager
+	GetRebalancer() rebalance.Rebalancer
 	GetDB() *gorm.DB
 	GetConfig() config.Config
 	GetKeys() keys.Keys
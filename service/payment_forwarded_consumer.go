@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -16,9 +17,15 @@ type paymentForwardedConsumer struct {
 	db *gorm.DB
 }
 
-// When a new app is created, subscribe to it on the relay
+// Stores settled and failed forwards for routing analytics
 func (c *paymentForwardedConsumer) ConsumeEvent(ctx context.Context, event *events.Event, globalProperties map[string]interface{}) {
-	if event.Event != "nwc_payment_forwarded" {
+	var state string
+	switch event.Event {
+	case "nwc_payment_forwarded":
+		state = db.FORWARD_STATE_SETTLED
+	case "nwc_payment_forward_failed":
+		state = db.FORWARD_STATE_FAILED
+	default:
 		return
 	}
 
@@ -27,9 +34,21 @@ func (c *paymentForwardedConsumer) ConsumeEvent(ctx context.Context, event *even
 		logger.Logger.WithField("event", event).Error("Failed to cast event.Properties to payment forwarded event properties")
 		return
 	}
+	resolvedAt := time.Now()
+	if properties.ResolvedAt != nil {
+		resolvedAt = *properties.ResolvedAt
+	}
 	forward := &db.Forward{
 		OutboundAmountForwardedMsat: properties.OutboundAmountForwardedMsat,
 		TotalFeeEarnedMsat:          properties.TotalFeeEarnedMsat,
+		InboundAmountMsat:           properties.InboundAmountMsat,
+		IncomingChannelId:           properties.IncomingChannelId,
+		OutgoingChannelId:           properties.OutgoingChannelId,
+		IncomingPeerPubkey:          properties.IncomingPeerPubkey,
+		OutgoingPeerPubkey:          properties.OutgoingPeerPubkey,
+		State:                       state,
+		FailureReason:               properties.FailureReason,
+		ResolvedAt:                  resolvedAt,
 	}
 	err := c.db.Create(forward).Error
 	if err != nil {
@@ -17,8 +17,12 @@ import (
 	"github.com/kelseyhightower/envconfig"
 
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/backup"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/fees"
 	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/metrics"
+	"github.com/getAlby/hub/rebalance"
 	"github.com/getAlby/hub/service/keys"
 	"github.com/getAlby/hub/swaps"
 	"github.com/getAlby/hub/transactions"
@@ -38,6 +42,9 @@ type service struct {
 	lnClientShuttingDown atomic.Bool
 	transactionsService  transactions.TransactionsService
 	swapsService         swaps.SwapsService
+	backupService        backup.BackupService
+	feeManager           fees.FeeManager
+	rebalancer           rebalance.Rebalancer
 	albySvc              alby.AlbyService
 	albyOAuthSvc         alby.AlbyOAuthService
 	eventPublisher       events.EventPublisher
@@ -115,7 +122,7 @@ func NewService(ctx context.Context) (*service, error) {
 
 	eventPublisher := events.NewEventPublisher()
 
-	keys := keys.NewKeys()
+	keys := keys.NewKeys(ctx)
 
 	albySvc := alby.NewAlbyService()
 	albyOAuthSvc := alby.NewAlbyOAuthService(gormDB, cfg, keys, eventPublisher)
@@ -142,6 +149,7 @@ func NewService(ctx context.Context) (*service, error) {
 	eventPublisher.RegisterSubscriber(&paymentForwardedConsumer{
 		db: gormDB,
 	})
+	eventPublisher.RegisterSubscriber(metrics.NewPaymentsConsumer())
 
 	eventPublisher.Publish(&events.Event{
 		Event: "nwc_started",
@@ -154,6 +162,14 @@ func NewService(ctx context.Context) (*service, error) {
 		startProfiler(ctx, appConfig.GoProfilerAddr)
 	}
 
+	if appConfig.MetricsAddr != "" {
+		err = metrics.Register(newMetricsCollector(svc, eventPublisher.GetPendingEventsCount))
+		if err != nil {
+			return nil, err
+		}
+		metrics.StartServer(ctx, appConfig.MetricsAddr)
+	}
+
 	if autoUnlockPassword != "" {
 		nodeLastStartTime, _ := cfg.Get("NodeLastStartTime", "")
 		if nodeLastStartTime != "" {
@@ -272,6 +288,18 @@ func (svc *service) GetSwapsService() swaps.SwapsService {
 	return svc.swapsService
 }
 
+func (svc *service) GetBackupService() backup.BackupService {
+	return svc.backupService
+}
+
+func (svc *service) GetFeeManager() fees.FeeManager {
+	return svc.feeManager
+}
+
+func (svc *service) GetRebalancer() rebalance.Rebalancer {
+	return svc.rebalancer
+}
+
 func (svc *service) GetKeys() keys.Keys {
 	return svc.keys
 }
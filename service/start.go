@@ -9,8 +9,12 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/getAlby/hub/backup"
 	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/fees"
 	"github.com/getAlby/hub/nip47/models"
+	"github.com/getAlby/hub/rebalance"
+	"github.com/getAlby/hub/scheduler"
 	"github.com/getAlby/hub/swaps"
 	"github.com/getAlby/hub/version"
 
@@ -147,7 +151,7 @@ func (svc *service) publishAllAppInfoEvents() {
 		if legacyAppCount > 0 {
 			logger.Logger.WithField("legacy_app_count", legacyAppCount).Debug("Enqueuing publish of legacy info event")
 			for _, relayUrl := range svc.cfg.GetRelayUrls() {
-				svc.nip47Service.EnqueueNip47InfoPublishRequest(0 /* unused */, svc.keys.GetNostrPublicKey(), svc.keys.GetNostrSecretKey(), relayUrl)
+				svc.nip47Service.EnqueueNip47InfoPublishRequest(0 /* unused */, svc.keys.GetNostrPublicKey(), svc.keys.GetNostrSigner(), relayUrl)
 			}
 		}
 	}()
@@ -162,7 +166,7 @@ func (svc *service) publishAllAppInfoEvents() {
 	for _, app := range apps {
 		func(app db.App) {
 			// queue info event publish request for all existing apps
-			walletPrivKey, err := svc.keys.GetAppWalletKey(app.ID)
+			walletSigner, err := svc.keys.GetAppWalletSigner(app.ID)
 			if err != nil {
 				logger.Logger.WithError(err).WithFields(logrus.Fields{
 					"app_id": app.ID}).Error("Could not get app wallet key")
@@ -170,7 +174,7 @@ func (svc *service) publishAllAppInfoEvents() {
 			}
 			logger.Logger.WithField("app_id", app.ID).Debug("Enqueuing publish of app info event")
 			for _, relayUrl := range svc.cfg.GetRelayUrls() {
-				svc.nip47Service.EnqueueNip47InfoPublishRequest(app.ID, *app.WalletPubkey, walletPrivKey, relayUrl)
+				svc.nip47Service.EnqueueNip47InfoPublishRequest(app.ID, *app.WalletPubkey, walletSigner, relayUrl)
 			}
 		}(app)
 	}
@@ -313,6 +317,12 @@ func (svc *service) StartApp(encryptionKey string) error {
 	}
 
 	svc.swapsService = swaps.NewSwapsService(ctx, svc.db, svc.cfg, svc.keys, svc.eventPublisher, svc.GetLNClient(), svc.transactionsService, encryptionKey)
+	svc.backupService = backup.NewBackupService(ctx, svc.db, svc.cfg, svc.eventPublisher, svc.GetLNClient(), encryptionKey)
+	svc.feeManager = fees.NewFeeManager(ctx, svc.db, svc.cfg, svc.GetLNClient())
+	svc.rebalancer = rebalance.NewRebalancer(ctx, svc.cfg, svc.GetLNClient(), svc.transactionsService)
+
+	svc.startOnchainPaymentsWatcher(ctx)
+	scheduler.Start(ctx, "payment_approvals", 1*time.Minute, nil, svc.transactionsService.ExpirePaymentApprovals)
 
 	svc.publishAllAppInfoEvents()
 
@@ -328,6 +338,25 @@ func (svc *service) StartApp(encryptionKey string) error {
 	return nil
 }
 
+// startOnchainPaymentsWatcher periodically settles NWC on-chain payments
+// once they have been confirmed
+func (svc *service) startOnchainPaymentsWatcher(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(1 * time.Minute):
+				lnClient := svc.GetLNClient()
+				if lnClient == nil {
+					continue
+				}
+				svc.transactionsService.UpdatePendingOnchainPayments(ctx, lnClient)
+			}
+		}
+	}()
+}
+
 func (svc *service) launchLNBackend(ctx context.Context, encryptionKey string) error {
 	if svc.lnClient != nil {
 		logger.Logger.Error("LNClient already started")
@@ -381,13 +410,13 @@ func (svc *service) launchLNBackend(ctx context.Context, encryptionKey string) e
 		PhoenixdAddress, _ := svc.cfg.Get("PhoenixdAddress", encryptionKey)
 		PhoenixdAuthorization, _ := svc.cfg.Get("PhoenixdAuthorization", encryptionKey)
 
-		lnClient, err = phoenixd.NewPhoenixService(ctx, PhoenixdAddress, PhoenixdAuthorization)
+		lnClient, err = phoenixd.NewPhoenixService(ctx, svc.eventPublisher, PhoenixdAddress, PhoenixdAuthorization)
 	case config.CashuBackendType:
 		mnemonic, _ := svc.cfg.Get("Mnemonic", encryptionKey)
 		cashuMintUrl, _ := svc.cfg.Get("CashuMintUrl", encryptionKey)
 		cashuWorkdir := path.Join(svc.cfg.GetEnv().Workdir, "cashu")
 
-		lnClient, err = cashu.NewCashuService(svc.cfg, cashuWorkdir, mnemonic, cashuMintUrl)
+		lnClient, err = cashu.NewCashuService(ctx, svc.cfg, svc.eventPublisher, cashuWorkdir, mnemonic, cashuMintUrl)
 	case config.BarkBackendType:
 		mnemonic, _ := svc.cfg.Get("Mnemonic", encryptionKey)
 		env := svc.cfg.GetEnv()
@@ -3,7 +3,6 @@ package service
 import (
 	"context"
 
-	"github.com/getAlby/go-nostr"
 	"github.com/getAlby/hub/events"
 	"github.com/getAlby/hub/logger"
 )
@@ -29,22 +28,18 @@ func (s *updateAppConsumer) ConsumeEvent(ctx context.Context, event *events.Even
 		logger.Logger.WithField("event", event).Error("Failed to get app id")
 		return
 	}
-	walletPrivKey, err := s.svc.keys.GetAppWalletKey(id)
+	walletSigner, err := s.svc.keys.GetAppWalletSigner(id)
 	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to calculate app wallet priv key")
-		return
-	}
-	walletPubKey, err := nostr.GetPublicKey(walletPrivKey)
-	if err != nil {
-		logger.Logger.WithError(err).Error("Failed to calculate app wallet pub key")
+		logger.Logger.WithError(err).Error("Failed to calculate app wallet key")
 		return
 	}
+	walletPubKey := walletSigner.GetPublicKey()
 
 	if s.svc.keys.GetNostrPublicKey() != walletPubKey {
 		// only need to re-publish the nip47 event info if it is not a legacy app connection (shared wallet pubkey)
 		// (legacy app connection can be used for multiple apps - so it cannot be app-specific)
 		for _, relayUrl := range s.svc.cfg.GetRelayUrls() {
-			s.svc.nip47Service.EnqueueNip47InfoPublishRequest(id, walletPubKey, walletPrivKey, relayUrl)
+			s.svc.nip47Service.EnqueueNip47InfoPublishRequest(id, walletPubKey, walletSigner, relayUrl)
 		}
 	}
 }
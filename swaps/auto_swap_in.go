@@ -0,0 +1,227 @@
+package swaps
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	autoSwapInCheckInterval           = 15 * time.Minute
+	DefaultAutoSwapInCooldownHours    = 24
+	DefaultAutoSwapInMaxFeePercentage = 2.0
+)
+
+type AutoSwapInConfig struct {
+	BalanceThresholdSat uint64
+	AmountSat           uint64
+	MaxFeePercentage    float64
+	CooldownHours       uint64
+}
+
+// GetAutoSwapInConfig reads the auto swap in config keys. It returns nil if auto swap in is not enabled.
+func GetAutoSwapInConfig(cfg config.Config) (*AutoSwapInConfig, error) {
+	balanceThresholdStr, _ := cfg.Get(config.AutoSwapInBalanceThresholdKey, "")
+	amountStr, _ := cfg.Get(config.AutoSwapInAmountKey, "")
+	if balanceThresholdStr == "" || amountStr == "" {
+		return nil, nil
+	}
+
+	autoSwapInConfig := &AutoSwapInConfig{
+		MaxFeePercentage: DefaultAutoSwapInMaxFeePercentage,
+		CooldownHours:    DefaultAutoSwapInCooldownHours,
+	}
+	var err error
+	if autoSwapInConfig.BalanceThresholdSat, err = strconv.ParseUint(balanceThresholdStr, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid auto swap in balance threshold: %w", err)
+	}
+	if autoSwapInConfig.AmountSat, err = strconv.ParseUint(amountStr, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid auto swap in amount: %w", err)
+	}
+	if maxFeePercentageStr, _ := cfg.Get(config.AutoSwapInMaxFeePercentageKey, ""); maxFeePercentageStr != "" {
+		if autoSwapInConfig.MaxFeePercentage, err = strconv.ParseFloat(maxFeePercentageStr, 64); err != nil {
+			return nil, fmt.Errorf("invalid auto swap in max fee percentage: %w", err)
+		}
+	}
+	if cooldownHoursStr, _ := cfg.Get(config.AutoSwapInCooldownHoursKey, ""); cooldownHoursStr != "" {
+		if autoSwapInConfig.CooldownHours, err = strconv.ParseUint(cooldownHoursStr, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid auto swap in cooldown: %w", err)
+		}
+	}
+	return autoSwapInConfig, nil
+}
+
+func (svc *swapsService) StopAutoSwapIn() {
+	if svc.autoSwapInCancelFn != nil {
+		logger.Logger.Info("Stopping auto swap in service...")
+		svc.autoSwapInCancelFn()
+		svc.autoSwapInCancelFn = nil
+		logger.Logger.Info("Auto swap in service stopped")
+	}
+}
+
+func (svc *swapsService) EnableAutoSwapIn() error {
+	svc.StopAutoSwapIn()
+
+	autoSwapInConfig, err := GetAutoSwapInConfig(svc.cfg)
+	if err != nil {
+		return err
+	}
+	if autoSwapInConfig == nil {
+		logger.Logger.Info("Auto swap in not configured")
+		return nil
+	}
+
+	logger.Logger.Info("Starting auto swap in workflow")
+
+	ctx, cancelFn := context.WithCancel(svc.ctx)
+	go func() {
+		for {
+			select {
+			case <-time.After(autoSwapInCheckInterval):
+				err := svc.autoSwapIn(ctx, autoSwapInConfig)
+				if err != nil {
+					logger.Logger.WithError(err).Error("Auto swap in failed")
+				}
+			case <-ctx.Done():
+				logger.Logger.Info("Stopping auto swap in workflow")
+				return
+			}
+		}
+	}()
+
+	svc.autoSwapInCancelFn = cancelFn
+
+	return nil
+}
+
+// autoSwapIn swaps on-chain funds of the node wallet to lightning if the spendable
+// lightning balance dropped below the threshold and the swap fees are acceptable
+func (svc *swapsService) autoSwapIn(ctx context.Context, autoSwapInConfig *AutoSwapInConfig) error {
+	logger.Logger.Debug("Checking to see if we can swap in")
+
+	var lastAutoSwapIn db.Swap
+	result := svc.db.Where("type = ? AND auto_swap = ?", constants.SWAP_TYPE_IN, true).Order("created_at desc").Limit(1).Find(&lastAutoSwapIn)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		if lastAutoSwapIn.State == constants.SWAP_STATE_PENDING {
+			logger.Logger.WithField("swapId", lastAutoSwapIn.SwapId).Debug("Previous auto swap in is still pending, ignoring")
+			return nil
+		}
+		if time.Since(lastAutoSwapIn.CreatedAt) < time.Duration(autoSwapInConfig.CooldownHours)*time.Hour {
+			logger.Logger.Debug("Auto swap in cooldown has not passed yet, ignoring")
+			return nil
+		}
+	}
+
+	balances, err := svc.lnClient.GetBalances(ctx, false)
+	if err != nil {
+		return fmt.Errorf("failed to get balances: %w", err)
+	}
+
+	swapInInfo, err := svc.GetSwapInInfo()
+	if err != nil {
+		return err
+	}
+
+	if reason := checkAutoSwapIn(autoSwapInConfig, balances, swapInInfo); reason != "" {
+		logger.Logger.WithField("reason", reason).Debug("Requirements not met for auto swap in, ignoring")
+		return nil
+	}
+
+	amountSat := autoSwapInConfig.AmountSat
+	logger.Logger.WithFields(logrus.Fields{
+		"amountSat":       amountSat,
+		"estimatedFeeSat": estimateSwapInFeeSat(amountSat, swapInInfo),
+	}).Info("Initiating auto swap in")
+
+	// the fees are only final once the swap is created, SwapIn checks them again before returning it
+	maxFeeSat := calculateMaxFeeSat(amountSat, autoSwapInConfig.MaxFeePercentage)
+	swapResponse, err := svc.SwapIn(amountSat, true, &maxFeeSat)
+	if err != nil {
+		return fmt.Errorf("failed to initiate swap in: %w", err)
+	}
+
+	swap, err := svc.GetSwap(swapResponse.SwapId)
+	if err != nil {
+		return err
+	}
+
+	if uint64(max(balances.Onchain.SpendableSat, 0)) < swap.SendAmountSat {
+		svc.markSwapState(swap, constants.SWAP_STATE_FAILED)
+		return fmt.Errorf("not enough on-chain funds to send %d sat", swap.SendAmountSat)
+	}
+
+	lockupTxId, err := svc.lnClient.RedeemOnchainFunds(ctx, swap.LockupAddress, swap.SendAmountSat, nil, false)
+	if err != nil {
+		svc.markSwapState(swap, constants.SWAP_STATE_FAILED)
+		return fmt.Errorf("failed to fund swap in: %w", err)
+	}
+
+	err = svc.db.Model(swap).Updates(&db.Swap{
+		LockupTxId: lockupTxId,
+	}).Error
+	if err != nil {
+		logger.Logger.WithError(err).WithField("swapId", swap.SwapId).Error("Failed to save lockup txid to swap")
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"swapId":        swap.SwapId,
+		"sendAmountSat": swap.SendAmountSat,
+		"lockupTxId":    lockupTxId,
+	}).Info("Funded auto swap in from the node wallet")
+
+	return nil
+}
+
+// checkAutoSwapIn returns why an auto swap in should not be made, or an empty string if it should
+func checkAutoSwapIn(autoSwapInConfig *AutoSwapInConfig, balances *lnclient.BalancesResponse, swapInInfo *SwapInfo) string {
+	amountSat := autoSwapInConfig.AmountSat
+	if uint64(max(balances.Lightning.TotalSpendableMsat, 0)) >= autoSwapInConfig.BalanceThresholdSat*1000 {
+		return "spendable lightning balance is above the threshold"
+	}
+	if uint64(max(balances.Lightning.TotalReceivableMsat, 0)) < amountSat*1000 {
+		return "not enough receiving capacity"
+	}
+	if amountSat < swapInInfo.MinAmountSat || amountSat > swapInInfo.MaxAmountSat {
+		return fmt.Sprintf("swap amount must be between %d and %d sat", swapInInfo.MinAmountSat, swapInInfo.MaxAmountSat)
+	}
+	estimatedFeeSat := estimateSwapInFeeSat(amountSat, swapInInfo)
+	if !isFeeAcceptable(estimatedFeeSat, amountSat, autoSwapInConfig.MaxFeePercentage) {
+		return fmt.Sprintf("estimated fee of %d sat exceeds %.2f%%", estimatedFeeSat, autoSwapInConfig.MaxFeePercentage)
+	}
+	if uint64(max(balances.Onchain.SpendableSat, 0)) < amountSat+estimatedFeeSat {
+		return "not enough on-chain funds"
+	}
+	return ""
+}
+
+// estimateSwapInFeeSat returns the Alby and Boltz fees for receiving amountSat
+// (excluding the fee of the on-chain lockup transaction)
+func estimateSwapInFeeSat(amountSat uint64, swapInInfo *SwapInfo) uint64 {
+	serviceFeeSat := uint64(math.Round(float64(amountSat) * (swapInInfo.AlbyServiceFee + swapInInfo.BoltzServiceFee) / 100))
+	return serviceFeeSat + swapInInfo.BoltzNetworkFeeSat
+}
+
+// calculateMaxFeeSat returns maxFeePercentage of amountSat, rounded down
+func calculateMaxFeeSat(amountSat uint64, maxFeePercentage float64) uint64 {
+	return uint64(math.Floor(float64(amountSat) * maxFeePercentage / 100))
+}
+
+func isFeeAcceptable(feeSat uint64, amountSat uint64, maxFeePercentage float64) bool {
+	if amountSat == 0 {
+		return false
+	}
+	return float64(feeSat)/float64(amountSat)*100 <= maxFeePercentage
+}
@@ -0,0 +1,71 @@
+package swaps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getAlby/hub/lnclient"
+)
+
+func TestEstimateSwapInFeeSat(t *testing.T) {
+	swapInInfo := &SwapInfo{
+		AlbyServiceFee:     1,
+		BoltzServiceFee:    0.1,
+		BoltzNetworkFeeSat: 300,
+	}
+	assert.Equal(t, uint64(1100+300), estimateSwapInFeeSat(100_000, swapInInfo))
+	assert.Equal(t, uint64(11+300), estimateSwapInFeeSat(1_001, swapInInfo))
+}
+
+func TestIsFeeAcceptable(t *testing.T) {
+	assert.True(t, isFeeAcceptable(2_000, 100_000, 2))
+	assert.False(t, isFeeAcceptable(2_001, 100_000, 2))
+	assert.False(t, isFeeAcceptable(0, 0, 2))
+}
+
+func TestCheckAutoSwapIn(t *testing.T) {
+	autoSwapInConfig := &AutoSwapInConfig{
+		BalanceThresholdSat: 50_000,
+		AmountSat:           100_000,
+		MaxFeePercentage:    2,
+	}
+	swapInInfo := &SwapInfo{
+		AlbyServiceFee:     1,
+		BoltzServiceFee:    0.1,
+		BoltzNetworkFeeSat: 300,
+		MinAmountSat:       25_000,
+		MaxAmountSat:       1_000_000,
+	}
+	makeBalances := func(lightningSpendableSat, lightningReceivableSat, onchainSpendableSat int64) *lnclient.BalancesResponse {
+		balances := &lnclient.BalancesResponse{}
+		balances.Lightning.TotalSpendableMsat = lightningSpendableSat * 1000
+		balances.Lightning.TotalReceivableMsat = lightningReceivableSat * 1000
+		balances.Onchain.SpendableSat = onchainSpendableSat
+		return balances
+	}
+
+	assert.Empty(t, checkAutoSwapIn(autoSwapInConfig, makeBalances(10_000, 500_000, 200_000), swapInInfo))
+
+	assert.Equal(t, "spendable lightning balance is above the threshold",
+		checkAutoSwapIn(autoSwapInConfig, makeBalances(50_000, 500_000, 200_000), swapInInfo))
+	assert.Equal(t, "not enough receiving capacity",
+		checkAutoSwapIn(autoSwapInConfig, makeBalances(10_000, 99_999, 200_000), swapInInfo))
+	assert.Equal(t, "not enough on-chain funds",
+		checkAutoSwapIn(autoSwapInConfig, makeBalances(10_000, 500_000, 101_000), swapInInfo))
+
+	expensiveSwapInInfo := *swapInInfo
+	expensiveSwapInInfo.BoltzNetworkFeeSat = 1_000
+	assert.Equal(t, "estimated fee of 2100 sat exceeds 2.00%",
+		checkAutoSwapIn(autoSwapInConfig, makeBalances(10_000, 500_000, 200_000), &expensiveSwapInInfo))
+
+	smallSwapInInfo := *swapInInfo
+	smallSwapInInfo.MaxAmountSat = 50_000
+	assert.Equal(t, "swap amount must be between 25000 and 50000 sat",
+		checkAutoSwapIn(autoSwapInConfig, makeBalances(10_000, 500_000, 200_000), &smallSwapInInfo))
+}
+
+func TestCalculateMaxFeeSat(t *testing.T) {
+	assert.Equal(t, uint64(2_000), calculateMaxFeeSat(100_000, 2))
+	assert.Equal(t, uint64(20), calculateMaxFeeSat(1_001, 2))
+}
@@ -0,0 +1,182 @@
+package swaps
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/BoltzExchange/boltz-client/v2/pkg/boltz"
+	"github.com/getAlby/hub/constants"
+)
+
+const swapQuoteExpiry = 5 * time.Minute
+
+// swapInLockupTxVsize is the approximate size of a lockup transaction funded from
+// the node wallet (one P2WPKH input, the P2TR lockup output and a change output)
+const swapInLockupTxVsize = 154
+
+var ErrSwapFeeTooHigh = errors.New("swap fee exceeds the maximum fee")
+
+// SwapQuote is the cost of a swap of a specific amount at the current fees. For swaps out,
+// the lightning routing fee of paying the swap invoice is not included in the quoted fees.
+type SwapQuote struct {
+	Id               string    `json:"id"`
+	Type             string    `json:"type"`
+	SendAmountSat    uint64    `json:"sendAmountSat"`
+	ReceiveAmountSat uint64    `json:"receiveAmountSat"`
+	AlbyFeeSat       uint64    `json:"albyFeeSat"`
+	BoltzFeeSat      uint64    `json:"boltzFeeSat"`
+	LockupFeeSat     uint64    `json:"lockupFeeSat"`
+	ClaimFeeSat      uint64    `json:"claimFeeSat"`
+	TotalFeeSat      uint64    `json:"totalFeeSat"` // excludes the routing fee of swaps out
+	FeeRate          float64   `json:"feeRate"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+}
+
+func (svc *swapsService) GetSwapOutQuote(amountSat uint64) (*SwapQuote, error) {
+	reversePairs, err := svc.boltzApi.GetReversePairs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get reverse pairs: %s", err)
+	}
+
+	pair := boltz.Pair{From: boltz.CurrencyBtc, To: boltz.CurrencyBtc}
+	pairInfo, err := boltz.FindPair(pair, reversePairs)
+	if err != nil {
+		return nil, fmt.Errorf("could not find reverse pair: %s", err)
+	}
+
+	if amountSat < pairInfo.Limits.Minimal || amountSat > pairInfo.Limits.Maximal {
+		return nil, fmt.Errorf("swap amount must be between %d and %d sat", pairInfo.Limits.Minimal, pairInfo.Limits.Maximal)
+	}
+
+	feeRate, err := svc.getFeeRate()
+	if err != nil {
+		return nil, fmt.Errorf("could not get fee rate: %s", err)
+	}
+
+	fees := pairInfo.Fees
+	quote, err := calculateSwapOutQuote(amountSat, fees.Percentage, fees.MinerFees.Lockup, fees.MinerFees.Claim)
+	if err != nil {
+		return nil, err
+	}
+	quote.FeeRate = feeRate
+
+	return svc.saveSwapQuote(quote)
+}
+
+func (svc *swapsService) GetSwapInQuote(amountSat uint64) (*SwapQuote, error) {
+	submarinePairs, err := svc.boltzApi.GetSubmarinePairs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get submarine pairs: %s", err)
+	}
+
+	pair := boltz.Pair{From: boltz.CurrencyBtc, To: boltz.CurrencyBtc}
+	pairInfo, err := boltz.FindPair(pair, submarinePairs)
+	if err != nil {
+		return nil, fmt.Errorf("could not find submarine pair: %s", err)
+	}
+
+	if amountSat < pairInfo.Limits.Minimal || amountSat > pairInfo.Limits.Maximal {
+		return nil, fmt.Errorf("swap amount must be between %d and %d sat", pairInfo.Limits.Minimal, pairInfo.Limits.Maximal)
+	}
+
+	feeRate, err := svc.getFeeRate()
+	if err != nil {
+		return nil, fmt.Errorf("could not get fee rate: %s", err)
+	}
+
+	fees := pairInfo.Fees
+	quote := calculateSwapInQuote(amountSat, fees.Percentage, fees.MinerFees, feeRate)
+
+	return svc.saveSwapQuote(quote)
+}
+
+// GetSwapQuote returns a quote created with GetSwapOutQuote or GetSwapInQuote that has not expired yet
+func (svc *swapsService) GetSwapQuote(quoteId string) (*SwapQuote, error) {
+	svc.swapQuotesLock.Lock()
+	defer svc.swapQuotesLock.Unlock()
+
+	quote, ok := svc.swapQuotes[quoteId]
+	if !ok || time.Now().After(quote.ExpiresAt) {
+		return nil, errors.New("swap quote not found or expired")
+	}
+	return quote, nil
+}
+
+func (svc *swapsService) saveSwapQuote(quote *SwapQuote) (*SwapQuote, error) {
+	quoteId := make([]byte, 16)
+	_, err := rand.Read(quoteId)
+	if err != nil {
+		return nil, err
+	}
+	quote.Id = hex.EncodeToString(quoteId)
+	quote.ExpiresAt = time.Now().Add(swapQuoteExpiry)
+
+	svc.swapQuotesLock.Lock()
+	defer svc.swapQuotesLock.Unlock()
+
+	for id, existingQuote := range svc.swapQuotes {
+		if time.Now().After(existingQuote.ExpiresAt) {
+			delete(svc.swapQuotes, id)
+		}
+	}
+	svc.swapQuotes[quote.Id] = quote
+
+	return quote, nil
+}
+
+// calculateSwapOutQuote splits the invoice amount paid for receiving amountSat on-chain
+// into the Alby fee, the Boltz fee and the miner fees of the lockup and claim transactions
+func calculateSwapOutQuote(amountSat uint64, boltzFeePercentage float64, lockupFeeSat uint64, claimFeeSat uint64) (*SwapQuote, error) {
+	sendAmountSat := calculateSwapOutSendAmountSat(amountSat, boltzFeePercentage, lockupFeeSat, claimFeeSat)
+	if sendAmountSat == 0 {
+		return nil, errors.New("invalid swap out fees")
+	}
+	albyFeeSat := uint64(math.Round(float64(sendAmountSat) * AlbySwapServiceFeePercentage / 100))
+
+	return &SwapQuote{
+		Type:             constants.SWAP_TYPE_OUT,
+		SendAmountSat:    sendAmountSat,
+		ReceiveAmountSat: amountSat,
+		AlbyFeeSat:       albyFeeSat,
+		BoltzFeeSat:      sendAmountSat - amountSat - lockupFeeSat - claimFeeSat - albyFeeSat,
+		LockupFeeSat:     lockupFeeSat,
+		ClaimFeeSat:      claimFeeSat,
+		TotalFeeSat:      sendAmountSat - amountSat,
+	}, nil
+}
+
+// calculateSwapInQuote returns the on-chain amount to send for receiving amountSat over
+// lightning; the lockup transaction is paid from the sending wallet at feeRate (sat/vB)
+func calculateSwapInQuote(amountSat uint64, boltzFeePercentage float64, claimFeeSat uint64, feeRate float64) *SwapQuote {
+	albyFeeSat := uint64(math.Round(float64(amountSat) * AlbySwapServiceFeePercentage / 100))
+	boltzFeeSat := uint64(math.Round(float64(amountSat) * boltzFeePercentage / 100))
+	sendAmountSat := amountSat + albyFeeSat + boltzFeeSat + claimFeeSat
+	lockupFeeSat := estimateSwapInLockupFeeSat(feeRate)
+
+	return &SwapQuote{
+		Type:             constants.SWAP_TYPE_IN,
+		SendAmountSat:    sendAmountSat,
+		ReceiveAmountSat: amountSat,
+		AlbyFeeSat:       albyFeeSat,
+		BoltzFeeSat:      boltzFeeSat,
+		LockupFeeSat:     lockupFeeSat,
+		ClaimFeeSat:      claimFeeSat,
+		TotalFeeSat:      sendAmountSat - amountSat + lockupFeeSat,
+		FeeRate:          feeRate,
+	}
+}
+
+func estimateSwapInLockupFeeSat(feeRate float64) uint64 {
+	return uint64(math.Ceil(feeRate * swapInLockupTxVsize))
+}
+
+func checkSwapFee(feeSat uint64, maxFeeSat *uint64) error {
+	if maxFeeSat != nil && feeSat > *maxFeeSat {
+		return fmt.Errorf("%w: fee of %d sat is more than %d sat", ErrSwapFeeTooHigh, feeSat, *maxFeeSat)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package swaps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+)
+
+func TestCalculateSwapOutQuote(t *testing.T) {
+	// ceil(100_800 / 0.985) = 102_336 is paid, of which 1% (1_023) goes to alby
+	// and the rest of the fee (513) to boltz
+	quote, err := calculateSwapOutQuote(100_000, 0.5, 500, 300)
+	require.NoError(t, err)
+	assert.Equal(t, constants.SWAP_TYPE_OUT, quote.Type)
+	assert.Equal(t, uint64(102_336), quote.SendAmountSat)
+	assert.Equal(t, uint64(100_000), quote.ReceiveAmountSat)
+	assert.Equal(t, uint64(1_023), quote.AlbyFeeSat)
+	assert.Equal(t, uint64(513), quote.BoltzFeeSat)
+	assert.Equal(t, uint64(500), quote.LockupFeeSat)
+	assert.Equal(t, uint64(300), quote.ClaimFeeSat)
+	assert.Equal(t, uint64(2_336), quote.TotalFeeSat)
+	assert.Equal(t, quote.TotalFeeSat, quote.AlbyFeeSat+quote.BoltzFeeSat+quote.LockupFeeSat+quote.ClaimFeeSat)
+
+	_, err = calculateSwapOutQuote(100_000, 100, 500, 300)
+	require.Error(t, err)
+}
+
+func TestCalculateSwapInQuote(t *testing.T) {
+	// 1% alby fee, 0.1% boltz fee and the boltz claim fee are added to the amount,
+	// the lockup transaction is estimated at 154 vbytes * 2.5 sat/vB
+	quote := calculateSwapInQuote(100_000, 0.1, 300, 2.5)
+	assert.Equal(t, constants.SWAP_TYPE_IN, quote.Type)
+	assert.Equal(t, uint64(101_400), quote.SendAmountSat)
+	assert.Equal(t, uint64(100_000), quote.ReceiveAmountSat)
+	assert.Equal(t, uint64(1_000), quote.AlbyFeeSat)
+	assert.Equal(t, uint64(100), quote.BoltzFeeSat)
+	assert.Equal(t, uint64(385), quote.LockupFeeSat)
+	assert.Equal(t, uint64(300), quote.ClaimFeeSat)
+	assert.Equal(t, uint64(1_785), quote.TotalFeeSat)
+	assert.Equal(t, 2.5, quote.FeeRate)
+}
+
+func TestCheckSwapFee(t *testing.T) {
+	maxFeeSat := uint64(1_000)
+	assert.NoError(t, checkSwapFee(1_000, &maxFeeSat))
+	assert.NoError(t, checkSwapFee(1_000_000, nil))
+
+	err := checkSwapFee(1_001, &maxFeeSat)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrSwapFeeTooHigh))
+}
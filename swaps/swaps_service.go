@@ -38,6 +38,7 @@ type Swap = db.Swap
 
 type swapsService struct {
 	autoSwapOutCancelFn      context.CancelFunc
+	autoSwapInCancelFn       context.CancelFunc
 	db                       *gorm.DB
 	ctx                      context.Context
 	lnClient                 lnclient.LNClient
@@ -51,15 +52,22 @@ type swapsService struct {
 	swapListenersLock        sync.Mutex
 	autoSwapOutXpubLock      sync.Mutex
 	autoSwapOutDecryptedXpub string
+	swapQuotes               map[string]*SwapQuote
+	swapQuotesLock           sync.Mutex
 }
 
 type SwapsService interface {
 	StopAutoSwapOut()
 	EnableAutoSwapOut(encryptionKey string) error
-	SwapOut(amountSat uint64, destination string, autoSwap, usedXpubDerivation bool) (*SwapResponse, error)
-	SwapIn(amountSat uint64, autoSwap bool) (*SwapResponse, error)
+	StopAutoSwapIn()
+	EnableAutoSwapIn() error
+	SwapOut(amountSat uint64, destination string, autoSwap, usedXpubDerivation bool, maxFeeSat *uint64) (*SwapResponse, error)
+	SwapIn(amountSat uint64, autoSwap bool, maxFeeSat *uint64) (*SwapResponse, error)
 	GetSwapOutInfo() (*SwapInfo, error)
 	GetSwapInInfo() (*SwapInfo, error)
+	GetSwapOutQuote(amountSat uint64) (*SwapQuote, error)
+	GetSwapInQuote(amountSat uint64) (*SwapQuote, error)
+	GetSwapQuote(quoteId string) (*SwapQuote, error)
 	RefundSwap(swapId, address string, enableRetries bool) error
 	GetSwap(swapId string) (*Swap, error)
 	ListSwaps() ([]Swap, error)
@@ -101,6 +109,7 @@ func NewSwapsService(ctx context.Context, db *gorm.DB, cfg config.Config, keys k
 		boltzApi:            boltzApi,
 		boltzWs:             boltzWs,
 		swapListeners:       make(map[string]chan boltz.SwapUpdate),
+		swapQuotes:          make(map[string]*SwapQuote),
 	}
 
 	go func() {
@@ -127,6 +136,11 @@ func NewSwapsService(ctx context.Context, db *gorm.DB, cfg config.Config, keys k
 		logger.Logger.WithError(err).Error("Couldn't enable auto swaps")
 	}
 
+	err = svc.EnableAutoSwapIn()
+	if err != nil {
+		logger.Logger.WithError(err).Error("Couldn't enable auto swap in")
+	}
+
 	go svc.subscribePendingSwaps()
 
 	return svc
@@ -181,6 +195,16 @@ func (svc *swapsService) EnableAutoSwapOut(encryptionKey string) error {
 		return errors.New("invalid auto swap configuration")
 	}
 
+	var maxFeeSat *uint64
+	if maxFeeSatStr, _ := svc.cfg.Get(config.AutoSwapMaxFeeSatKey, ""); maxFeeSatStr != "" {
+		parsedMaxFeeSat, err := strconv.ParseUint(maxFeeSatStr, 10, 64)
+		if err != nil {
+			cancelFn()
+			return errors.New("invalid auto swap configuration")
+		}
+		maxFeeSat = &parsedMaxFeeSat
+	}
+
 	logger.Logger.Info("Starting auto swap workflow")
 
 	go func() {
@@ -219,7 +243,7 @@ func (svc *swapsService) EnableAutoSwapOut(encryptionKey string) error {
 					"amountSat":   amountSat,
 					"destination": actualDestination,
 				}).Info("Initiating swap")
-				_, err = svc.SwapOut(amountSat, actualDestination, true, usedXpubDerivation)
+				_, err = svc.SwapOut(amountSat, actualDestination, true, usedXpubDerivation, maxFeeSat)
 				if err != nil {
 					logger.Logger.WithError(err).Error("Failed to initiate swap")
 					continue
@@ -236,7 +260,7 @@ func (svc *swapsService) EnableAutoSwapOut(encryptionKey string) error {
 	return nil
 }
 
-func (svc *swapsService) SwapOut(amountSat uint64, destination string, autoSwap, usedXpubDerivation bool) (*SwapResponse, error) {
+func (svc *swapsService) SwapOut(amountSat uint64, destination string, autoSwap, usedXpubDerivation bool, maxFeeSat *uint64) (*SwapResponse, error) {
 	if destination == "" {
 		var err error
 		destination, err = svc.lnClient.GetNewOnchainAddress(svc.ctx)
@@ -275,6 +299,12 @@ func (svc *swapsService) SwapOut(amountSat uint64, destination string, autoSwap,
 		"networkFeeSat": networkFeeSat,
 	}).Info("Calculated fees for swap out")
 
+	// the routing fee of the invoice payment is not known before it is paid and is not part of the swap fee
+	expectedSendAmountSat := calculateSwapOutSendAmountSat(amountSat, fees.Percentage, fees.MinerFees.Lockup, fees.MinerFees.Claim)
+	if err = checkSwapFee(expectedSendAmountSat-amountSat, maxFeeSat); err != nil {
+		return nil, err
+	}
+
 	albyFee := &boltz.ExtraFees{
 		Percentage: AlbySwapServiceFeePercentage,
 		Id:         "albyServiceFee",
@@ -340,6 +370,10 @@ func (svc *swapsService) SwapOut(amountSat uint64, destination string, autoSwap,
 		if err != nil {
 			return fmt.Errorf("invalid swap invoice: %w", err)
 		}
+		// the invoice is not paid yet, so the swap can still be abandoned if the fees moved
+		if err := checkSwapFee(sendAmountSat-amountSat, maxFeeSat); err != nil {
+			return err
+		}
 
 		err = tx.Model(&dbSwap).Updates(&db.Swap{
 			SwapId:             swap.Id,
@@ -386,17 +420,25 @@ func (svc *swapsService) SwapOut(amountSat uint64, destination string, autoSwap,
 const swapOutInvoiceToleranceSat = 10
 
 // calculateMaxSwapOutSendAmountSat returns the maximum invoice amount accepted for
-// a swap out: the requested on-chain amount plus the quoted miner fees, marked up
-// by the quoted percentage fees (which are charged on the invoice amount), plus a
-// small rounding tolerance.
+// a swap out: the expected invoice amount plus a small rounding tolerance.
 func calculateMaxSwapOutSendAmountSat(receiveAmountSat uint64, serviceFeePercentage float64, lockupFeeSat uint64, claimFeeSat uint64) uint64 {
+	expectedSendAmountSat := calculateSwapOutSendAmountSat(receiveAmountSat, serviceFeePercentage, lockupFeeSat, claimFeeSat)
+	if expectedSendAmountSat == 0 {
+		return 0
+	}
+	return expectedSendAmountSat + swapOutInvoiceToleranceSat
+}
+
+// calculateSwapOutSendAmountSat returns the expected invoice amount of a swap out:
+// the requested on-chain amount plus the quoted miner fees, marked up by the quoted
+// percentage fees (which are charged on the invoice amount).
+func calculateSwapOutSendAmountSat(receiveAmountSat uint64, serviceFeePercentage float64, lockupFeeSat uint64, claimFeeSat uint64) uint64 {
 	totalFeePercentage := serviceFeePercentage + AlbySwapServiceFeePercentage
 	if totalFeePercentage >= 100 {
 		return 0
 	}
 	onchainAmountSat := float64(receiveAmountSat + claimFeeSat + lockupFeeSat)
-	expectedSendAmountSat := math.Ceil(onchainAmountSat / (1 - totalFeePercentage/100))
-	return uint64(expectedSendAmountSat) + swapOutInvoiceToleranceSat
+	return uint64(math.Ceil(onchainAmountSat / (1 - totalFeePercentage/100)))
 }
 
 // verifySwapOutInvoice checks that a swap out invoice is bound to the swap's
@@ -419,13 +461,7 @@ func verifySwapOutInvoice(invoice string, expectedPaymentHash string, maxSendAmo
 	return sendAmountSat, nil
 }
 
-func (svc *swapsService) SwapIn(amountSat uint64, autoSwap bool) (*SwapResponse, error) {
-	amountMsat := amountSat * 1000
-	invoice, err := svc.transactionsService.MakeInvoice(svc.ctx, amountMsat, "On-chain to lightning swap", "", 0, nil, svc.lnClient, nil, nil, nil)
-	if err != nil {
-		return nil, err
-	}
-
+func (svc *swapsService) SwapIn(amountSat uint64, autoSwap bool, maxFeeSat *uint64) (*SwapResponse, error) {
 	submarinePairs, err := svc.boltzApi.GetSubmarinePairs()
 	if err != nil {
 		return nil, fmt.Errorf("could not get submarine pairs: %s", err)
@@ -448,6 +484,23 @@ func (svc *swapsService) SwapIn(amountSat uint64, autoSwap bool) (*SwapResponse,
 		"networkFeeSat": networkFeeSat,
 	}).Info("Calculated fees for swap in")
 
+	// the lockup transaction is paid from the sending wallet, so its fee is part of the swap fee
+	feeRate, err := svc.getFeeRate()
+	if err != nil {
+		return nil, fmt.Errorf("could not get fee rate: %s", err)
+	}
+	quote := calculateSwapInQuote(amountSat, fees.Percentage, fees.MinerFees, feeRate)
+	if err := checkSwapFee(quote.TotalFeeSat, maxFeeSat); err != nil {
+		return nil, err
+	}
+	lockupFeeSat := quote.LockupFeeSat
+
+	amountMsat := amountSat * 1000
+	invoice, err := svc.transactionsService.MakeInvoice(svc.ctx, amountMsat, "On-chain to lightning swap", "", 0, nil, svc.lnClient, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	albyFee := &boltz.ExtraFees{
 		Percentage: AlbySwapServiceFeePercentage,
 		Id:         "albyServiceFee",
@@ -495,6 +548,14 @@ func (svc *swapsService) SwapIn(amountSat uint64, autoSwap bool) (*SwapResponse,
 			return fmt.Errorf("could not create swap: %s", err)
 		}
 
+		// the lockup transaction is not sent yet, so the swap can still be abandoned if the fees moved
+		if swap.ExpectedAmount < amountSat {
+			return fmt.Errorf("unexpected swap in amount: %d sat", swap.ExpectedAmount)
+		}
+		if err := checkSwapFee(swap.ExpectedAmount-amountSat+lockupFeeSat, maxFeeSat); err != nil {
+			return err
+		}
+
 		swapTreeJson, err := json.Marshal(swap.SwapTree)
 		if err != nil {
 			return err
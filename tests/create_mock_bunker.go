@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/getAlby/go-nostr"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/logger"
+	"github.com/getAlby/hub/service/keys"
+)
+
+// mockBunker is a NIP-46 remote signer which responds to requests published to a mock pool
+type mockBunker struct {
+	pool         *mockSimplePool
+	RemoteSigner keys.ECDHSigner
+	UserSigner   keys.ECDHSigner
+	Secret       string
+	// fail to decrypt NIP-44 encrypted requests, like older bunkers
+	Nip04Only bool
+	// ignore all requests
+	Offline bool
+
+	mutex           sync.Mutex
+	ReceivedMethods []string
+}
+
+func NewMockBunker(pool *mockSimplePool, secret string) (*mockBunker, error) {
+	remoteSigner, err := keys.NewSoftwareSigner(nostr.GeneratePrivateKey())
+	if err != nil {
+		return nil, err
+	}
+	userSigner, err := keys.NewSoftwareSigner(nostr.GeneratePrivateKey())
+	if err != nil {
+		return nil, err
+	}
+	bunker := &mockBunker{
+		pool:         pool,
+		RemoteSigner: remoteSigner,
+		UserSigner:   userSigner,
+		Secret:       secret,
+	}
+	pool.OnPublish = bunker.handleRequest
+	return bunker, nil
+}
+
+func (bunker *mockBunker) BunkerUrl() string {
+	return fmt.Sprintf("bunker://%s?relay=%s&secret=%s", bunker.RemoteSigner.GetPublicKey(), url.QueryEscape("wss://fakerelay.com"), bunker.Secret)
+}
+
+func (bunker *mockBunker) GetReceivedMethods() []string {
+	bunker.mutex.Lock()
+	defer bunker.mutex.Unlock()
+	return append([]string{}, bunker.ReceivedMethods...)
+}
+
+func (bunker *mockBunker) handleRequest(event *nostr.Event) {
+	if event.Kind != keys.NIP46_KIND || bunker.Offline {
+		return
+	}
+
+	encryption := constants.ENCRYPTION_TYPE_NIP44_V2
+	if strings.Contains(event.Content, "?iv=") {
+		encryption = constants.ENCRYPTION_TYPE_NIP04
+	}
+	if bunker.Nip04Only && encryption != constants.ENCRYPTION_TYPE_NIP04 {
+		// the request id is unknown, so the error is sent without it
+		bunker.respond(event.PubKey, constants.ENCRYPTION_TYPE_NIP04, map[string]string{
+			"id":     "",
+			"result": "",
+			"error":  "failed to decrypt request",
+		})
+		return
+	}
+
+	payload, err := bunker.RemoteSigner.Decrypt(encryption, event.PubKey, event.Content)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Mock bunker failed to decrypt request")
+		return
+	}
+	request := struct {
+		ID     string   `json:"id"`
+		Method string   `json:"method"`
+		Params []string `json:"params"`
+	}{}
+	err = json.Unmarshal([]byte(payload), &request)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Mock bunker failed to parse request")
+		return
+	}
+
+	bunker.mutex.Lock()
+	bunker.ReceivedMethods = append(bunker.ReceivedMethods, request.Method)
+	bunker.mutex.Unlock()
+
+	result, err := bunker.handleMethod(request.Method, request.Params)
+	response := map[string]string{
+		"id":     request.ID,
+		"result": result,
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	bunker.respond(event.PubKey, encryption, response)
+}
+
+func (bunker *mockBunker) respond(clientPubkey, encryption string, response map[string]string) {
+	responsePayload, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	content, err := bunker.RemoteSigner.Encrypt(encryption, clientPubkey, string(responsePayload))
+	if err != nil {
+		return
+	}
+	responseEvent := &nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      keys.NIP46_KIND,
+		Tags:      nostr.Tags{[]string{"p", clientPubkey}},
+		Content:   content,
+	}
+	err = bunker.RemoteSigner.SignEvent(responseEvent)
+	if err != nil {
+		return
+	}
+	go bunker.pool.Deliver(responseEvent)
+}
+
+func (bunker *mockBunker) handleMethod(method string, params []string) (string, error) {
+	switch method {
+	case "connect":
+		if len(params) < 1 || params[0] != bunker.RemoteSigner.GetPublicKey() {
+			return "", errors.New("wrong remote signer pubkey")
+		}
+		if bunker.Secret != "" && (len(params) < 2 || params[1] != bunker.Secret) {
+			return "", errors.New("invalid secret")
+		}
+		return "ack", nil
+	case "get_public_key":
+		return bunker.UserSigner.GetPublicKey(), nil
+	case "ping":
+		return "pong", nil
+	case "sign_event":
+		event := nostr.Event{}
+		err := json.Unmarshal([]byte(params[0]), &event)
+		if err != nil {
+			return "", err
+		}
+		err = bunker.UserSigner.SignEvent(&event)
+		if err != nil {
+			return "", err
+		}
+		signedEvent, err := json.Marshal(event)
+		return string(signedEvent), err
+	case "nip04_encrypt":
+		return bunker.UserSigner.Encrypt(constants.ENCRYPTION_TYPE_NIP04, params[0], params[1])
+	case "nip04_decrypt":
+		return bunker.UserSigner.Decrypt(constants.ENCRYPTION_TYPE_NIP04, params[0], params[1])
+	case "nip44_encrypt":
+		return bunker.UserSigner.Encrypt(constants.ENCRYPTION_TYPE_NIP44_V2, params[0], params[1])
+	case "nip44_decrypt":
+		return bunker.UserSigner.Decrypt(constants.ENCRYPTION_TYPE_NIP44_V2, params[0], params[1])
+	default:
+		return "", fmt.Errorf("unsupported method: %s", method)
+	}
+}
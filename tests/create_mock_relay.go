@@ -2,13 +2,27 @@ package tests
 
 import (
 	"context"
+	"slices"
+	"sync"
 
 	"github.com/getAlby/go-nostr"
 	"github.com/getAlby/hub/logger"
 )
 
+type mockSubscription struct {
+	filter  nostr.Filter
+	channel chan nostr.RelayEvent
+	mutex   sync.Mutex
+	closed  bool
+}
+
 type mockSimplePool struct {
 	PublishedEvents []*nostr.Event
+	// called for each published event, e.g. by a mock bunker
+	OnPublish func(event *nostr.Event)
+
+	mutex         sync.Mutex
+	subscriptions []*mockSubscription
 }
 
 func NewMockSimplePool() *mockSimplePool {
@@ -17,7 +31,14 @@ func NewMockSimplePool() *mockSimplePool {
 
 func (relay *mockSimplePool) PublishMany(ctx context.Context, relayUrls []string, event nostr.Event) chan nostr.PublishResult {
 	logger.Logger.WithField("event", event).Info("Mock Publishing event")
+	relay.mutex.Lock()
 	relay.PublishedEvents = append(relay.PublishedEvents, &event)
+	onPublish := relay.OnPublish
+	relay.mutex.Unlock()
+
+	if onPublish != nil {
+		onPublish(&event)
+	}
 
 	channel := make(chan nostr.PublishResult)
 	go func() {
@@ -29,6 +50,69 @@ func (relay *mockSimplePool) PublishMany(ctx context.Context, relayUrls []string
 	return channel
 }
 
+func (relay *mockSimplePool) SubscribeMany(ctx context.Context, urls []string, filter nostr.Filter, opts ...nostr.SubscriptionOption) chan nostr.RelayEvent {
+	subscription := &mockSubscription{
+		filter:  filter,
+		channel: make(chan nostr.RelayEvent),
+	}
+	relay.mutex.Lock()
+	relay.subscriptions = append(relay.subscriptions, subscription)
+	relay.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		relay.mutex.Lock()
+		relay.subscriptions = slices.DeleteFunc(relay.subscriptions, func(s *mockSubscription) bool {
+			return s == subscription
+		})
+		relay.mutex.Unlock()
+
+		subscription.mutex.Lock()
+		subscription.closed = true
+		close(subscription.channel)
+		subscription.mutex.Unlock()
+	}()
+	return subscription.channel
+}
+
+// Deliver sends the event to all subscriptions with a matching kind, author and p tag
+func (relay *mockSimplePool) Deliver(event *nostr.Event) {
+	relay.mutex.Lock()
+	var subscriptions []*mockSubscription
+	for _, subscription := range relay.subscriptions {
+		if mockFilterMatches(subscription.filter, event) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	relay.mutex.Unlock()
+
+	for _, subscription := range subscriptions {
+		subscription.mutex.Lock()
+		if !subscription.closed {
+			subscription.channel <- nostr.RelayEvent{Event: event}
+		}
+		subscription.mutex.Unlock()
+	}
+}
+
+func mockFilterMatches(filter nostr.Filter, event *nostr.Event) bool {
+	if len(filter.Kinds) > 0 && !slices.Contains(filter.Kinds, event.Kind) {
+		return false
+	}
+	if len(filter.Authors) > 0 && !slices.Contains(filter.Authors, event.PubKey) {
+		return false
+	}
+	if pubkeys, ok := filter.Tags["p"]; ok {
+		for _, tag := range event.Tags {
+			if len(tag) >= 2 && tag[0] == "p" && slices.Contains(pubkeys, tag[1]) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
 func (relay *mockSimplePool) QuerySingle(
 	ctx context.Context,
 	urls []string,
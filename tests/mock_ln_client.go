@@ -18,6 +18,9 @@ const MockPaymentHash = "23277d5e13fce5534f9752c62fcf9337a2a6b0ebea9d21fa816a4c9
 const MockZeroAmountInvoice = "lntbs1pnkjfgudqjd3hkueeqv4u8q6tj0ynp4qws83mqzuqptu5kfvxeles7qmyhsj6u2s6zyuft26mcr4tdmcupuupp533y9nwnsaktr9zlvyxmv97ta23faerygh3t9xvsfwytsr28lgggssp5mku3023z3kdxlpx6vrwtfxvvrxpffrquy6veex4ndk7rxhdtslhq9qyysgqcqpcxqxfvltyqva6y7k89jwtcljx399jl6wsq4lkq29vnm3rj4jxmapc6vcs358sx8mtpgh93rdc6ccqpxwwfga59zrla5m55zwzck2y2rsrxumu852sqkvpcm7"
 const MockZeroAmountPaymentHash = "8c4859ba70ed96328bec21b6c2f97d5453dc8c88bc56533209711701a8ff4211"
 
+const MockOffer = "lno1qgsqvgnwgcg35z6ee2h3yczraddm72xrfua9uve2rlrm9deu7xyfzrcgqgn3qzsyvfkx26qkyypvr5hfx60h9w9k934lt8s2n6zc0wwtgqlulw7dythr83dqx8tzumg"
+const MockOfferPaymentHash = "6c9a1b6e0e4d9ea13d1bd6e5ffbad0eb4a0b7e2a4c5a7e6f1d2b3c4d5e6f7a8b"
+
 var MockNodeInfo = lnclient.NodeInfo{
 	Alias:       "bob",
 	Color:       "#3399FF",
@@ -33,6 +36,46 @@ var MockLNClientBalances = lnclient.BalancesResponse{
 	},
 }
 
+var MockLNClientOnchainBalance = lnclient.OnchainBalanceResponse{
+	SpendableSat: 50000,
+	TotalSat:     60000,
+	ReservedSat:  10000,
+}
+
+// MockChannels has a channel with mostly local balance, a channel with mostly
+// remote balance and an inactive channel
+var MockChannels = []lnclient.Channel{
+	{
+		Id:                                  "chan-a",
+		RemotePubkey:                        "peer-a",
+		Active:                              true,
+		LocalBalanceMsat:                    900_000_000,
+		LocalSpendableBalanceMsat:           890_000_000,
+		RemoteBalanceMsat:                   100_000_000,
+		ForwardingFeeBaseMsat:               1000,
+		ForwardingFeeProportionalMillionths: 500,
+	},
+	{
+		Id:                                  "chan-b",
+		RemotePubkey:                        "peer-b",
+		Active:                              true,
+		LocalBalanceMsat:                    100_000_000,
+		RemoteBalanceMsat:                   900_000_000,
+		ForwardingFeeBaseMsat:               1000,
+		ForwardingFeeProportionalMillionths: 500,
+	},
+	{
+		Id:                "chan-c",
+		RemotePubkey:      "peer-c",
+		Active:            false,
+		RemoteBalanceMsat: 1_000_000_000,
+	},
+}
+
+const MockFundingTxId = "3b1a9ef0d6c4ffb3c2f22e0f3b7f6a0c5f7d3f1a9ef0d6c4ffb3c2f22e0f3b7f"
+const MockOnchainAddress = "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"
+const MockOnchainTxId = "9f2c1a7e5b3d4f6a8c0e2b4d6f8a0c2e4b6d8f0a2c4e6b8d0f2a4c6e8b0d2f4a"
+
 var MockTime = time.Unix(1693876963, 0)
 var MockTimeUnix = MockTime.Unix()
 
@@ -83,11 +126,17 @@ type MockLn struct {
 	PayInvoiceErrors           []error
 	PayKeysendResponses        []*lnclient.PayKeysendResponse
 	PayKeysendErrors           []error
+	PayOfferErrors             []error
 	PaymentDelay               *time.Duration
 	Pubkey                     string
 	MockTransaction            *lnclient.Transaction
 	LastMinCltvExpiryDelta     *uint64
 	SupportedNotificationTypes *[]string
+	OnchainTransactions        []lnclient.OnchainTransaction
+	Channels                   []lnclient.Channel
+	UpdateChannelRequests      []*lnclient.UpdateChannelRequest
+	LastCircularPaymentRequest *lnclient.CircularPaymentRequest
+	StorageDir                 string
 }
 
 func NewMockLn() (*MockLn, error) {
@@ -111,6 +160,11 @@ func (mln *MockLn) SendPaymentSync(payReq string, amountMsat *uint64) (*lnclient
 	}, nil
 }
 
+func (mln *MockLn) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	mln.LastCircularPaymentRequest = circularPaymentRequest
+	return mln.SendPaymentSync(circularPaymentRequest.PaymentRequest, nil)
+}
+
 func (mln *MockLn) SendKeysend(amountMsat uint64, destination string, custom_records []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	if len(mln.PayKeysendResponses) > 0 {
 		response := mln.PayKeysendResponses[0]
@@ -177,6 +231,9 @@ func (mln *MockLn) Shutdown() error {
 }
 
 func (mln *MockLn) ListChannels(ctx context.Context) (channels []lnclient.Channel, err error) {
+	if mln.Channels != nil {
+		return mln.Channels, nil
+	}
 	return []lnclient.Channel{}, nil
 }
 func (mln *MockLn) GetNodeConnectionInfo(ctx context.Context) (nodeConnectionInfo *lnclient.NodeConnectionInfo, err error) {
@@ -186,22 +243,22 @@ func (mln *MockLn) ConnectPeer(ctx context.Context, connectPeerRequest *lnclient
 	return nil
 }
 func (mln *MockLn) OpenChannel(ctx context.Context, openChannelRequest *lnclient.OpenChannelRequest) (*lnclient.OpenChannelResponse, error) {
-	return nil, nil
+	return &lnclient.OpenChannelResponse{FundingTxId: MockFundingTxId}, nil
 }
 func (mln *MockLn) CloseChannel(ctx context.Context, closeChannelRequest *lnclient.CloseChannelRequest) error {
 	return nil
 }
 func (mln *MockLn) GetNewOnchainAddress(ctx context.Context) (string, error) {
-	return "", nil
+	return MockOnchainAddress, nil
 }
 func (mln *MockLn) GetBalances(ctx context.Context, includeInactiveChannels bool) (*lnclient.BalancesResponse, error) {
 	return &MockLNClientBalances, nil
 }
 func (mln *MockLn) GetOnchainBalance(ctx context.Context) (*lnclient.OnchainBalanceResponse, error) {
-	return nil, nil
+	return &MockLNClientOnchainBalance, nil
 }
 func (mln *MockLn) RedeemOnchainFunds(ctx context.Context, toAddress string, amountSat uint64, feeRate *uint64, sendAll bool) (txId string, err error) {
-	return "", nil
+	return MockOnchainTxId, nil
 }
 func (mln *MockLn) ResetRouter(key string) error {
 	return nil
@@ -216,7 +273,7 @@ func (mln *MockLn) SignMessage(ctx context.Context, message string) (string, err
 	return "", nil
 }
 func (mln *MockLn) GetStorageDir() (string, error) {
-	return "", nil
+	return mln.StorageDir, nil
 }
 func (mln *MockLn) GetNodeStatus(ctx context.Context) (nodeStatus *lnclient.NodeStatus, err error) {
 	return &lnclient.NodeStatus{
@@ -234,11 +291,12 @@ func (mln *MockLn) DisconnectPeer(ctx context.Context, peerId string) error {
 }
 
 func (mln *MockLn) UpdateChannel(ctx context.Context, updateChannelRequest *lnclient.UpdateChannelRequest) error {
+	mln.UpdateChannelRequests = append(mln.UpdateChannelRequests, updateChannelRequest)
 	return nil
 }
 
 func (mln *MockLn) GetSupportedNIP47Methods() []string {
-	return []string{"pay_invoice", "pay_keysend", "get_balance", "get_budget", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message"}
+	return []string{"pay_invoice", "pay_keysend", "get_balance", "get_budget", "get_info", "make_invoice", "lookup_invoice", "list_transactions", "multi_pay_invoice", "multi_pay_keysend", "sign_message", "list_channels", "open_channel", "close_channel", "update_channel_fees", "get_onchain_balance", "get_new_address", "pay_onchain"}
 }
 func (mln *MockLn) GetSupportedNIP47NotificationTypes() []string {
 	if mln.SupportedNotificationTypes != nil {
@@ -267,6 +325,22 @@ func (mln *MockLn) MakeOffer(ctx context.Context, description string) (string, e
 	return "", errors.New("not supported")
 }
 
+func (mln *MockLn) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
+	if len(mln.PayOfferErrors) > 0 {
+		err := mln.PayOfferErrors[0]
+		mln.PayOfferErrors = mln.PayOfferErrors[1:]
+		return nil, err
+	}
+	return &lnclient.PayOfferResponse{
+		Preimage:    "123preimage",
+		PaymentHash: MockOfferPaymentHash,
+		FeeMsat:     1,
+	}, nil
+}
+
 func (mln *MockLn) ListOnchainTransactions(ctx context.Context) ([]lnclient.OnchainTransaction, error) {
+	if mln.OnchainTransactions != nil {
+		return mln.OnchainTransactions, nil
+	}
 	return nil, errors.ErrUnsupported
 }
@@ -298,6 +298,61 @@ func (_c *MockConfig_GetCurrency_Call) RunAndReturn(run func() string) *MockConf
 	return _c
 }
 
+// GetEncryptionStatus provides a mock function for the type MockConfig
+func (_mock *MockConfig) GetEncryptionStatus() (*config.EncryptionStatus, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEncryptionStatus")
+	}
+
+	var r0 *config.EncryptionStatus
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (*config.EncryptionStatus, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() *config.EncryptionStatus); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*config.EncryptionStatus)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockConfig_GetEncryptionStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetEncryptionStatus'
+type MockConfig_GetEncryptionStatus_Call struct {
+	*mock.Call
+}
+
+// GetEncryptionStatus is a helper method to define mock.On call
+func (_e *MockConfig_Expecter) GetEncryptionStatus() *MockConfig_GetEncryptionStatus_Call {
+	return &MockConfig_GetEncryptionStatus_Call{Call: _e.mock.On("GetEncryptionStatus")}
+}
+
+func (_c *MockConfig_GetEncryptionStatus_Call) Run(run func()) *MockConfig_GetEncryptionStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockConfig_GetEncryptionStatus_Call) Return(encryptionStatus *config.EncryptionStatus, err error) *MockConfig_GetEncryptionStatus_Call {
+	_c.Call.Return(encryptionStatus, err)
+	return _c
+}
+
+func (_c *MockConfig_GetEncryptionStatus_Call) RunAndReturn(run func() (*config.EncryptionStatus, error)) *MockConfig_GetEncryptionStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetEnv provides a mock function for the type MockConfig
 func (_mock *MockConfig) GetEnv() *config.AppConfig {
 	ret := _mock.Called()
@@ -635,6 +690,57 @@ func (_c *MockConfig_LoadJWTSecret_Call) RunAndReturn(run func(encryptionKey str
 	return _c
 }
 
+// RotateDataKey provides a mock function for the type MockConfig
+func (_mock *MockConfig) RotateDataKey(unlockPassword string) error {
+	ret := _mock.Called(unlockPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateDataKey")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string) error); ok {
+		r0 = returnFunc(unlockPassword)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockConfig_RotateDataKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateDataKey'
+type MockConfig_RotateDataKey_Call struct {
+	*mock.Call
+}
+
+// RotateDataKey is a helper method to define mock.On call
+//   - unlockPassword string
+func (_e *MockConfig_Expecter) RotateDataKey(unlockPassword interface{}) *MockConfig_RotateDataKey_Call {
+	return &MockConfig_RotateDataKey_Call{Call: _e.mock.On("RotateDataKey", unlockPassword)}
+}
+
+func (_c *MockConfig_RotateDataKey_Call) Run(run func(unlockPassword string)) *MockConfig_RotateDataKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockConfig_RotateDataKey_Call) Return(err error) *MockConfig_RotateDataKey_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockConfig_RotateDataKey_Call) RunAndReturn(run func(unlockPassword string) error) *MockConfig_RotateDataKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SaveUnlockPasswordCheck provides a mock function for the type MockConfig
 func (_mock *MockConfig) SaveUnlockPasswordCheck(encryptionKey string) error {
 	ret := _mock.Called(encryptionKey)
@@ -1017,3 +1123,60 @@ func (_c *MockConfig_SetupCompleted_Call) RunAndReturn(run func() (bool, error))
 	_c.Call.Return(run)
 	return _c
 }
+
+// UpgradeKDF provides a mock function for the type MockConfig
+func (_mock *MockConfig) UpgradeKDF(unlockPassword string, params config.KDFParams) error {
+	ret := _mock.Called(unlockPassword, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpgradeKDF")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, config.KDFParams) error); ok {
+		r0 = returnFunc(unlockPassword, params)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockConfig_UpgradeKDF_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpgradeKDF'
+type MockConfig_UpgradeKDF_Call struct {
+	*mock.Call
+}
+
+// UpgradeKDF is a helper method to define mock.On call
+//   - unlockPassword string
+//   - params config.KDFParams
+func (_e *MockConfig_Expecter) UpgradeKDF(unlockPassword interface{}, params interface{}) *MockConfig_UpgradeKDF_Call {
+	return &MockConfig_UpgradeKDF_Call{Call: _e.mock.On("UpgradeKDF", unlockPassword, params)}
+}
+
+func (_c *MockConfig_UpgradeKDF_Call) Run(run func(unlockPassword string, params config.KDFParams)) *MockConfig_UpgradeKDF_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 config.KDFParams
+		if args[1] != nil {
+			arg1 = args[1].(config.KDFParams)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockConfig_UpgradeKDF_Call) Return(err error) *MockConfig_UpgradeKDF_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockConfig_UpgradeKDF_Call) RunAndReturn(run func(unlockPassword string, params config.KDFParams) error) *MockConfig_UpgradeKDF_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -7,6 +7,7 @@ package mocks
 import (
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/service/keys"
 	mock "github.com/stretchr/testify/mock"
 	"github.com/tyler-smith/go-bip32"
 )
@@ -100,23 +101,25 @@ func (_c *MockKeys_DeriveKey_Call) RunAndReturn(run func(path []uint32) (*bip32.
 	return _c
 }
 
-// GetAppWalletKey provides a mock function for the type MockKeys
-func (_mock *MockKeys) GetAppWalletKey(childIndex uint) (string, error) {
+// GetAppWalletSigner provides a mock function for the type MockKeys
+func (_mock *MockKeys) GetAppWalletSigner(childIndex uint) (keys.Signer, error) {
 	ret := _mock.Called(childIndex)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAppWalletKey")
+		panic("no return value specified for GetAppWalletSigner")
 	}
 
-	var r0 string
+	var r0 keys.Signer
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(uint) (string, error)); ok {
+	if returnFunc, ok := ret.Get(0).(func(uint) (keys.Signer, error)); ok {
 		return returnFunc(childIndex)
 	}
-	if returnFunc, ok := ret.Get(0).(func(uint) string); ok {
+	if returnFunc, ok := ret.Get(0).(func(uint) keys.Signer); ok {
 		r0 = returnFunc(childIndex)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(keys.Signer)
+		}
 	}
 	if returnFunc, ok := ret.Get(1).(func(uint) error); ok {
 		r1 = returnFunc(childIndex)
@@ -126,18 +129,18 @@ func (_mock *MockKeys) GetAppWalletKey(childIndex uint) (string, error) {
 	return r0, r1
 }
 
-// MockKeys_GetAppWalletKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAppWalletKey'
-type MockKeys_GetAppWalletKey_Call struct {
+// MockKeys_GetAppWalletSigner_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAppWalletSigner'
+type MockKeys_GetAppWalletSigner_Call struct {
 	*mock.Call
 }
 
-// GetAppWalletKey is a helper method to define mock.On call
+// GetAppWalletSigner is a helper method to define mock.On call
 //   - childIndex uint
-func (_e *MockKeys_Expecter) GetAppWalletKey(childIndex interface{}) *MockKeys_GetAppWalletKey_Call {
-	return &MockKeys_GetAppWalletKey_Call{Call: _e.mock.On("GetAppWalletKey", childIndex)}
+func (_e *MockKeys_Expecter) GetAppWalletSigner(childIndex interface{}) *MockKeys_GetAppWalletSigner_Call {
+	return &MockKeys_GetAppWalletSigner_Call{Call: _e.mock.On("GetAppWalletSigner", childIndex)}
 }
 
-func (_c *MockKeys_GetAppWalletKey_Call) Run(run func(childIndex uint)) *MockKeys_GetAppWalletKey_Call {
+func (_c *MockKeys_GetAppWalletSigner_Call) Run(run func(childIndex uint)) *MockKeys_GetAppWalletSigner_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 uint
 		if args[0] != nil {
@@ -150,12 +153,12 @@ func (_c *MockKeys_GetAppWalletKey_Call) Run(run func(childIndex uint)) *MockKey
 	return _c
 }
 
-func (_c *MockKeys_GetAppWalletKey_Call) Return(s string, err error) *MockKeys_GetAppWalletKey_Call {
-	_c.Call.Return(s, err)
+func (_c *MockKeys_GetAppWalletSigner_Call) Return(signer keys.Signer, err error) *MockKeys_GetAppWalletSigner_Call {
+	_c.Call.Return(signer, err)
 	return _c
 }
 
-func (_c *MockKeys_GetAppWalletKey_Call) RunAndReturn(run func(childIndex uint) (string, error)) *MockKeys_GetAppWalletKey_Call {
+func (_c *MockKeys_GetAppWalletSigner_Call) RunAndReturn(run func(childIndex uint) (keys.Signer, error)) *MockKeys_GetAppWalletSigner_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -204,46 +207,48 @@ func (_c *MockKeys_GetNostrPublicKey_Call) RunAndReturn(run func() string) *Mock
 	return _c
 }
 
-// GetNostrSecretKey provides a mock function for the type MockKeys
-func (_mock *MockKeys) GetNostrSecretKey() string {
+// GetNostrSigner provides a mock function for the type MockKeys
+func (_mock *MockKeys) GetNostrSigner() keys.Signer {
 	ret := _mock.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetNostrSecretKey")
+		panic("no return value specified for GetNostrSigner")
 	}
 
-	var r0 string
-	if returnFunc, ok := ret.Get(0).(func() string); ok {
+	var r0 keys.Signer
+	if returnFunc, ok := ret.Get(0).(func() keys.Signer); ok {
 		r0 = returnFunc()
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(keys.Signer)
+		}
 	}
 	return r0
 }
 
-// MockKeys_GetNostrSecretKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNostrSecretKey'
-type MockKeys_GetNostrSecretKey_Call struct {
+// MockKeys_GetNostrSigner_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetNostrSigner'
+type MockKeys_GetNostrSigner_Call struct {
 	*mock.Call
 }
 
-// GetNostrSecretKey is a helper method to define mock.On call
-func (_e *MockKeys_Expecter) GetNostrSecretKey() *MockKeys_GetNostrSecretKey_Call {
-	return &MockKeys_GetNostrSecretKey_Call{Call: _e.mock.On("GetNostrSecretKey")}
+// GetNostrSigner is a helper method to define mock.On call
+func (_e *MockKeys_Expecter) GetNostrSigner() *MockKeys_GetNostrSigner_Call {
+	return &MockKeys_GetNostrSigner_Call{Call: _e.mock.On("GetNostrSigner")}
 }
 
-func (_c *MockKeys_GetNostrSecretKey_Call) Run(run func()) *MockKeys_GetNostrSecretKey_Call {
+func (_c *MockKeys_GetNostrSigner_Call) Run(run func()) *MockKeys_GetNostrSigner_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockKeys_GetNostrSecretKey_Call) Return(s string) *MockKeys_GetNostrSecretKey_Call {
-	_c.Call.Return(s)
+func (_c *MockKeys_GetNostrSigner_Call) Return(signer keys.Signer) *MockKeys_GetNostrSigner_Call {
+	_c.Call.Return(signer)
 	return _c
 }
 
-func (_c *MockKeys_GetNostrSecretKey_Call) RunAndReturn(run func() string) *MockKeys_GetNostrSecretKey_Call {
+func (_c *MockKeys_GetNostrSigner_Call) RunAndReturn(run func() keys.Signer) *MockKeys_GetNostrSigner_Call {
 	_c.Call.Return(run)
 	return _c
 }
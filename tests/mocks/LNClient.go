@@ -1659,6 +1659,86 @@ func (_c *MockLNClient_OpenChannel_Call) RunAndReturn(run func(ctx context.Conte
 	return _c
 }
 
+// PayOffer provides a mock function for the type MockLNClient
+func (_mock *MockLNClient) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error) {
+	ret := _mock.Called(ctx, offer, amountMsat, payerNote)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PayOffer")
+	}
+
+	var r0 *lnclient.PayOfferResponse
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, uint64, string) (*lnclient.PayOfferResponse, error)); ok {
+		return returnFunc(ctx, offer, amountMsat, payerNote)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, uint64, string) *lnclient.PayOfferResponse); ok {
+		r0 = returnFunc(ctx, offer, amountMsat, payerNote)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*lnclient.PayOfferResponse)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, uint64, string) error); ok {
+		r1 = returnFunc(ctx, offer, amountMsat, payerNote)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockLNClient_PayOffer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PayOffer'
+type MockLNClient_PayOffer_Call struct {
+	*mock.Call
+}
+
+// PayOffer is a helper method to define mock.On call
+//   - ctx context.Context
+//   - offer string
+//   - amountMsat uint64
+//   - payerNote string
+func (_e *MockLNClient_Expecter) PayOffer(ctx interface{}, offer interface{}, amountMsat interface{}, payerNote interface{}) *MockLNClient_PayOffer_Call {
+	return &MockLNClient_PayOffer_Call{Call: _e.mock.On("PayOffer", ctx, offer, amountMsat, payerNote)}
+}
+
+func (_c *MockLNClient_PayOffer_Call) Run(run func(ctx context.Context, offer string, amountMsat uint64, payerNote string)) *MockLNClient_PayOffer_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 uint64
+		if args[2] != nil {
+			arg2 = args[2].(uint64)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockLNClient_PayOffer_Call) Return(payOfferResponse *lnclient.PayOfferResponse, err error) *MockLNClient_PayOffer_Call {
+	_c.Call.Return(payOfferResponse, err)
+	return _c
+}
+
+func (_c *MockLNClient_PayOffer_Call) RunAndReturn(run func(ctx context.Context, offer string, amountMsat uint64, payerNote string) (*lnclient.PayOfferResponse, error)) *MockLNClient_PayOffer_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // RedeemOnchainFunds provides a mock function for the type MockLNClient
 func (_mock *MockLNClient) RedeemOnchainFunds(ctx context.Context, toAddress string, amountSat uint64, feeRate *uint64, sendAll bool) (string, error) {
 	ret := _mock.Called(ctx, toAddress, amountSat, feeRate, sendAll)
@@ -1794,6 +1874,74 @@ func (_c *MockLNClient_ResetRouter_Call) RunAndReturn(run func(key string) error
 	return _c
 }
 
+// SendCircularPayment provides a mock function for the type MockLNClient
+func (_mock *MockLNClient) SendCircularPayment(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error) {
+	ret := _mock.Called(ctx, circularPaymentRequest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendCircularPayment")
+	}
+
+	var r0 *lnclient.PayInvoiceResponse
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error)); ok {
+		return returnFunc(ctx, circularPaymentRequest)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *lnclient.CircularPaymentRequest) *lnclient.PayInvoiceResponse); ok {
+		r0 = returnFunc(ctx, circularPaymentRequest)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*lnclient.PayInvoiceResponse)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *lnclient.CircularPaymentRequest) error); ok {
+		r1 = returnFunc(ctx, circularPaymentRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockLNClient_SendCircularPayment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendCircularPayment'
+type MockLNClient_SendCircularPayment_Call struct {
+	*mock.Call
+}
+
+// SendCircularPayment is a helper method to define mock.On call
+//   - ctx context.Context
+//   - circularPaymentRequest *lnclient.CircularPaymentRequest
+func (_e *MockLNClient_Expecter) SendCircularPayment(ctx interface{}, circularPaymentRequest interface{}) *MockLNClient_SendCircularPayment_Call {
+	return &MockLNClient_SendCircularPayment_Call{Call: _e.mock.On("SendCircularPayment", ctx, circularPaymentRequest)}
+}
+
+func (_c *MockLNClient_SendCircularPayment_Call) Run(run func(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest)) *MockLNClient_SendCircularPayment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *lnclient.CircularPaymentRequest
+		if args[1] != nil {
+			arg1 = args[1].(*lnclient.CircularPaymentRequest)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockLNClient_SendCircularPayment_Call) Return(payInvoiceResponse *lnclient.PayInvoiceResponse, err error) *MockLNClient_SendCircularPayment_Call {
+	_c.Call.Return(payInvoiceResponse, err)
+	return _c
+}
+
+func (_c *MockLNClient_SendCircularPayment_Call) RunAndReturn(run func(ctx context.Context, circularPaymentRequest *lnclient.CircularPaymentRequest) (*lnclient.PayInvoiceResponse, error)) *MockLNClient_SendCircularPayment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SendKeysend provides a mock function for the type MockLNClient
 func (_mock *MockLNClient) SendKeysend(amountMsat uint64, destination string, customRecords []lnclient.TLVRecord, preimage string) (*lnclient.PayKeysendResponse, error) {
 	ret := _mock.Called(amountMsat, destination, customRecords, preimage)
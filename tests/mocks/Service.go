@@ -6,9 +6,12 @@ package mocks
 
 import (
 	"github.com/getAlby/hub/alby"
+	"github.com/getAlby/hub/backup"
 	"github.com/getAlby/hub/config"
 	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/fees"
 	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/rebalance"
 	"github.com/getAlby/hub/service"
 	"github.com/getAlby/hub/service/keys"
 	"github.com/getAlby/hub/swaps"
@@ -136,6 +139,52 @@ func (_c *MockService_GetAlbySvc_Call) RunAndReturn(run func() alby.AlbyService)
 	return _c
 }
 
+// GetBackupService provides a mock function for the type MockService
+func (_mock *MockService) GetBackupService() backup.BackupService {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBackupService")
+	}
+
+	var r0 backup.BackupService
+	if returnFunc, ok := ret.Get(0).(func() backup.BackupService); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(backup.BackupService)
+		}
+	}
+	return r0
+}
+
+// MockService_GetBackupService_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetBackupService'
+type MockService_GetBackupService_Call struct {
+	*mock.Call
+}
+
+// GetBackupService is a helper method to define mock.On call
+func (_e *MockService_Expecter) GetBackupService() *MockService_GetBackupService_Call {
+	return &MockService_GetBackupService_Call{Call: _e.mock.On("GetBackupService")}
+}
+
+func (_c *MockService_GetBackupService_Call) Run(run func()) *MockService_GetBackupService_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_GetBackupService_Call) Return(backupService backup.BackupService) *MockService_GetBackupService_Call {
+	_c.Call.Return(backupService)
+	return _c
+}
+
+func (_c *MockService_GetBackupService_Call) RunAndReturn(run func() backup.BackupService) *MockService_GetBackupService_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetConfig provides a mock function for the type MockService
 func (_mock *MockService) GetConfig() config.Config {
 	ret := _mock.Called()
@@ -274,6 +323,52 @@ func (_c *MockService_GetEventPublisher_Call) RunAndReturn(run func() events.Eve
 	return _c
 }
 
+// GetFeeManager provides a mock function for the type MockService
+func (_mock *MockService) GetFeeManager() fees.FeeManager {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFeeManager")
+	}
+
+	var r0 fees.FeeManager
+	if returnFunc, ok := ret.Get(0).(func() fees.FeeManager); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(fees.FeeManager)
+		}
+	}
+	return r0
+}
+
+// MockService_GetFeeManager_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFeeManager'
+type MockService_GetFeeManager_Call struct {
+	*mock.Call
+}
+
+// GetFeeManager is a helper method to define mock.On call
+func (_e *MockService_Expecter) GetFeeManager() *MockService_GetFeeManager_Call {
+	return &MockService_GetFeeManager_Call{Call: _e.mock.On("GetFeeManager")}
+}
+
+func (_c *MockService_GetFeeManager_Call) Run(run func()) *MockService_GetFeeManager_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_GetFeeManager_Call) Return(feeManager fees.FeeManager) *MockService_GetFeeManager_Call {
+	_c.Call.Return(feeManager)
+	return _c
+}
+
+func (_c *MockService_GetFeeManager_Call) RunAndReturn(run func() fees.FeeManager) *MockService_GetFeeManager_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetKeys provides a mock function for the type MockService
 func (_mock *MockService) GetKeys() keys.Keys {
 	ret := _mock.Called()
@@ -366,6 +461,52 @@ func (_c *MockService_GetLNClient_Call) RunAndReturn(run func() lnclient.LNClien
 	return _c
 }
 
+// GetRebalancer provides a mock function for the type MockService
+func (_mock *MockService) GetRebalancer() rebalance.Rebalancer {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRebalancer")
+	}
+
+	var r0 rebalance.Rebalancer
+	if returnFunc, ok := ret.Get(0).(func() rebalance.Rebalancer); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rebalance.Rebalancer)
+		}
+	}
+	return r0
+}
+
+// MockService_GetRebalancer_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRebalancer'
+type MockService_GetRebalancer_Call struct {
+	*mock.Call
+}
+
+// GetRebalancer is a helper method to define mock.On call
+func (_e *MockService_Expecter) GetRebalancer() *MockService_GetRebalancer_Call {
+	return &MockService_GetRebalancer_Call{Call: _e.mock.On("GetRebalancer")}
+}
+
+func (_c *MockService_GetRebalancer_Call) Run(run func()) *MockService_GetRebalancer_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockService_GetRebalancer_Call) Return(rebalancer rebalance.Rebalancer) *MockService_GetRebalancer_Call {
+	_c.Call.Return(rebalancer)
+	return _c
+}
+
+func (_c *MockService_GetRebalancer_Call) RunAndReturn(run func() rebalance.Rebalancer) *MockService_GetRebalancer_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetRelayStatuses provides a mock function for the type MockService
 func (_mock *MockService) GetRelayStatuses() []service.RelayStatus {
 	ret := _mock.Called()
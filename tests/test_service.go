@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"strconv"
 	"testing"
 
@@ -36,7 +37,7 @@ func CreateTestServiceWithMnemonic(t *testing.T, mnemonic string, unlockPassword
 	}
 
 	appConfig := &config.AppConfig{
-		Workdir: ".test",
+		Workdir: t.TempDir(),
 	}
 
 	cfg, err := config.NewConfig(
@@ -46,7 +47,7 @@ func CreateTestServiceWithMnemonic(t *testing.T, mnemonic string, unlockPassword
 	if err != nil {
 		return nil, err
 	}
-	keys := keys.NewKeys()
+	keys := keys.NewKeys(context.Background())
 
 	if mnemonic != "" {
 		if err = cfg.SetUpdate("Mnemonic", mnemonic, unlockPassword); err != nil {
@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238 defaults, which are the only parameters most authenticator apps support
+const (
+	period     = 30
+	digits     = 6
+	secretSize = 20
+	// accept codes from one step before and after the current one to allow for clock drift
+	skewSteps = 1
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random base32-encoded secret
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(secret), nil
+}
+
+// GenerateCode returns the code for the time step containing t
+func GenerateCode(secret string, t time.Time) (string, error) {
+	return generateCode(secret, timeStep(t))
+}
+
+// ProvisioningUri returns an otpauth:// URI that can be shown as a QR code
+// to add the secret to an authenticator app
+func ProvisioningUri(issuer string, accountName string, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprintf("%d", digits))
+	values.Set("period", fmt.Sprintf("%d", period))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: values.Encode(),
+	}).String()
+}
+
+// validateCode returns the time step of the code if it is valid at t
+func validateCode(secret string, code string, t time.Time) (uint64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != digits {
+		return 0, false
+	}
+	currentStep := timeStep(t)
+	for step := currentStep - skewSteps; step <= currentStep+skewSteps; step++ {
+		expectedCode, err := generateCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expectedCode), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func timeStep(t time.Time) uint64 {
+	return uint64(t.Unix()) / period
+}
+
+func generateCode(secret string, step uint64) (string, error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], step)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%1000000), nil
+}
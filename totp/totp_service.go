@@ -0,0 +1,375 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getAlby/hub/config"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	secretKey         = "TOTPSecret"
+	pendingSecretKey  = "TOTPPendingSecret"
+	recoveryCodesKey  = "TOTPRecoveryCodes"
+	recoveryCodeCount = 10
+	issuer            = "Alby Hub"
+)
+
+type codeRequiredError struct {
+}
+
+func NewCodeRequiredError() error {
+	return &codeRequiredError{}
+}
+
+func (err *codeRequiredError) Error() string {
+	return "authentication code required"
+}
+
+type invalidCodeError struct {
+}
+
+func NewInvalidCodeError() error {
+	return &invalidCodeError{}
+}
+
+func (err *invalidCodeError) Error() string {
+	return "invalid authentication code"
+}
+
+// IsCodeError returns true if the error was caused by a missing or invalid code
+func IsCodeError(err error) bool {
+	return errors.Is(err, NewCodeRequiredError()) || errors.Is(err, NewInvalidCodeError())
+}
+
+type Status struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+type Enrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioningUri"`
+}
+
+type TOTPService interface {
+	GetStatus() (*Status, error)
+	BeginEnrollment(unlockPassword string) (*Enrollment, error)
+	ConfirmEnrollment(unlockPassword string, code string) ([]string, error)
+	Disable(unlockPassword string, code string) error
+	RegenerateRecoveryCodes(unlockPassword string, code string) ([]string, error)
+	VerifyUnlock(unlockPassword string, code string) error
+	VerifyStepUp(code string) error
+}
+
+type totpService struct {
+	cfg   config.Config
+	mutex sync.Mutex
+	// the decrypted secret, kept in memory after a successful unlock so that
+	// step-up verification does not require the unlock password
+	secret string
+	// codes cannot be used twice
+	lastUsedStep uint64
+}
+
+func NewTOTPService(cfg config.Config) *totpService {
+	return &totpService{
+		cfg: cfg,
+	}
+}
+
+func (svc *totpService) GetStatus() (*Status, error) {
+	enabled, err := svc.isEnabled()
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{
+		Enabled: enabled,
+	}
+	if enabled {
+		recoveryCodeHashes, err := svc.getRecoveryCodeHashes()
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesRemaining = len(recoveryCodeHashes)
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a new secret which only takes effect once a code
+// generated from it is confirmed
+func (svc *totpService) BeginEnrollment(unlockPassword string) (*Enrollment, error) {
+	if !svc.cfg.CheckUnlockPassword(unlockPassword) {
+		return nil, errors.New("invalid unlock password")
+	}
+	enabled, err := svc.isEnabled()
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.cfg.SetUpdate(pendingSecretKey, secret, unlockPassword); err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:          secret,
+		ProvisioningUri: ProvisioningUri(issuer, "Unlock", secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication and returns the recovery codes,
+// which are only shown once
+func (svc *totpService) ConfirmEnrollment(unlockPassword string, code string) ([]string, error) {
+	if !svc.cfg.CheckUnlockPassword(unlockPassword) {
+		return nil, errors.New("invalid unlock password")
+	}
+	secret, err := svc.cfg.Get(pendingSecretKey, unlockPassword)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, errors.New("no two-factor authentication enrollment in progress")
+	}
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	step, ok := validateCode(secret, code, time.Now())
+	if !ok {
+		return nil, NewInvalidCodeError()
+	}
+
+	if err := svc.cfg.SetUpdate(secretKey, secret, unlockPassword); err != nil {
+		return nil, err
+	}
+	if err := svc.cfg.SetUpdate(pendingSecretKey, "", ""); err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := svc.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	svc.secret = secret
+	svc.lastUsedStep = step
+
+	logger.Logger.Info("Enabled two-factor authentication")
+
+	return recoveryCodes, nil
+}
+
+func (svc *totpService) Disable(unlockPassword string, code string) error {
+	if err := svc.VerifyUnlock(unlockPassword, code); err != nil {
+		return err
+	}
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	for _, key := range []string{secretKey, pendingSecretKey, recoveryCodesKey} {
+		if err := svc.cfg.SetUpdate(key, "", ""); err != nil {
+			return err
+		}
+	}
+	svc.secret = ""
+	svc.lastUsedStep = 0
+
+	logger.Logger.Info("Disabled two-factor authentication")
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all existing recovery codes
+func (svc *totpService) RegenerateRecoveryCodes(unlockPassword string, code string) ([]string, error) {
+	enabled, err := svc.isEnabled()
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	if err := svc.VerifyUnlock(unlockPassword, code); err != nil {
+		return nil, err
+	}
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	return svc.generateRecoveryCodes()
+}
+
+// VerifyUnlock checks the code when unlocking with the unlock password and keeps
+// the secret in memory for later step-up verification. It always succeeds if
+// two-factor authentication is not enabled.
+func (svc *totpService) VerifyUnlock(unlockPassword string, code string) error {
+	enabled, err := svc.isEnabled()
+	if err != nil || !enabled {
+		return err
+	}
+	if !svc.cfg.CheckUnlockPassword(unlockPassword) {
+		return errors.New("invalid unlock password")
+	}
+	secret, err := svc.cfg.Get(secretKey, unlockPassword)
+	if err != nil {
+		return err
+	}
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	if err := svc.verify(secret, code); err != nil {
+		return err
+	}
+	svc.secret = secret
+	return nil
+}
+
+// VerifyStepUp checks the code for a sensitive operation performed after the hub was
+// unlocked. It always succeeds if two-factor authentication is not enabled.
+func (svc *totpService) VerifyStepUp(code string) error {
+	enabled, err := svc.isEnabled()
+	if err != nil || !enabled {
+		return err
+	}
+
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+
+	if svc.secret == "" {
+		// a hub started with the auto-unlock password was never unlocked with a code
+		secret, err := svc.getSecretWithAutoUnlockPassword()
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			return errors.New("please unlock your hub with your authentication code first")
+		}
+		svc.secret = secret
+	}
+	return svc.verify(svc.secret, code)
+}
+
+// getSecretWithAutoUnlockPassword decrypts the secret with the stored auto-unlock
+// password, or returns an empty string if auto-unlock is not enabled
+func (svc *totpService) getSecretWithAutoUnlockPassword() (string, error) {
+	autoUnlockPassword, err := svc.cfg.Get("AutoUnlockPassword", "")
+	if err != nil || autoUnlockPassword == "" {
+		return "", err
+	}
+	secret, err := svc.cfg.Get(secretKey, autoUnlockPassword)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to decrypt two-factor authentication secret with the auto-unlock password")
+		return "", err
+	}
+	return secret, nil
+}
+
+// verify accepts either a code from the authenticator app or an unused recovery code.
+// The mutex must be held.
+func (svc *totpService) verify(secret string, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return NewCodeRequiredError()
+	}
+
+	if step, ok := validateCode(secret, code, time.Now()); ok {
+		if step <= svc.lastUsedStep {
+			logger.Logger.Warn("Rejected reused authentication code")
+			return NewInvalidCodeError()
+		}
+		svc.lastUsedStep = step
+		return nil
+	}
+
+	recoveryCodeHashes, err := svc.getRecoveryCodeHashes()
+	if err != nil {
+		return err
+	}
+	codeHash := hashRecoveryCode(code)
+	for i, recoveryCodeHash := range recoveryCodeHashes {
+		if recoveryCodeHash != codeHash {
+			continue
+		}
+		// recovery codes can only be used once
+		recoveryCodeHashes = append(recoveryCodeHashes[:i], recoveryCodeHashes[i+1:]...)
+		if err := svc.saveRecoveryCodeHashes(recoveryCodeHashes); err != nil {
+			return err
+		}
+		logger.Logger.WithField("remaining", len(recoveryCodeHashes)).Info("Used recovery code")
+		return nil
+	}
+
+	return NewInvalidCodeError()
+}
+
+func (svc *totpService) isEnabled() (bool, error) {
+	// the encrypted value is only checked for presence
+	secret, err := svc.cfg.Get(secretKey, "")
+	if err != nil {
+		return false, err
+	}
+	return secret != "", nil
+}
+
+// generateRecoveryCodes stores the hashes of new recovery codes.
+// The mutex must be held.
+func (svc *totpService) generateRecoveryCodes() ([]string, error) {
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		randomBytes := make([]byte, 10)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(base32NoPadding.EncodeToString(randomBytes))
+		recoveryCode := encoded[:8] + "-" + encoded[8:]
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		recoveryCodeHashes = append(recoveryCodeHashes, hashRecoveryCode(recoveryCode))
+	}
+	if err := svc.saveRecoveryCodeHashes(recoveryCodeHashes); err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+func (svc *totpService) getRecoveryCodeHashes() ([]string, error) {
+	recoveryCodesJson, err := svc.cfg.Get(recoveryCodesKey, "")
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodeHashes := []string{}
+	if recoveryCodesJson == "" {
+		return recoveryCodeHashes, nil
+	}
+	if err := json.Unmarshal([]byte(recoveryCodesJson), &recoveryCodeHashes); err != nil {
+		return nil, err
+	}
+	return recoveryCodeHashes, nil
+}
+
+// only hashes are stored, so recovery codes can be consumed without the unlock password
+func (svc *totpService) saveRecoveryCodeHashes(recoveryCodeHashes []string) error {
+	recoveryCodesJson, err := json.Marshal(recoveryCodeHashes)
+	if err != nil {
+		return err
+	}
+	return svc.cfg.SetUpdate(recoveryCodesKey, string(recoveryCodesJson), "")
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/tests"
+)
+
+const unlockPassword = "123"
+
+func setupTOTPService(t *testing.T) (*tests.TestService, *totpService, string) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	require.NoError(t, svc.Cfg.SaveUnlockPasswordCheck(unlockPassword))
+
+	totpSvc := NewTOTPService(svc.Cfg)
+	enrollment, err := totpSvc.BeginEnrollment(unlockPassword)
+	require.NoError(t, err)
+
+	return svc, totpSvc, enrollment.Secret
+}
+
+// codeAt returns a code for a different time step than any used before
+func codeAt(t *testing.T, secret string, offset time.Duration) string {
+	code, err := GenerateCode(secret, time.Now().Add(offset))
+	require.NoError(t, err)
+	return code
+}
+
+func TestEnrollment(t *testing.T) {
+	svc, totpSvc, secret := setupTOTPService(t)
+	defer svc.Remove()
+
+	// nothing is required until the enrollment is confirmed
+	status, err := totpSvc.GetStatus()
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	assert.NoError(t, totpSvc.VerifyStepUp(""))
+
+	// the secret is stored encrypted
+	storedSecret, err := svc.Cfg.Get("TOTPPendingSecret", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, storedSecret)
+
+	_, err = totpSvc.ConfirmEnrollment(unlockPassword, "000000")
+	assert.ErrorIs(t, err, NewInvalidCodeError())
+
+	recoveryCodes, err := totpSvc.ConfirmEnrollment(unlockPassword, codeAt(t, secret, -30*time.Second))
+	require.NoError(t, err)
+	assert.Len(t, recoveryCodes, recoveryCodeCount)
+
+	status, err = totpSvc.GetStatus()
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, recoveryCodeCount, status.RecoveryCodesRemaining)
+
+	_, err = totpSvc.BeginEnrollment(unlockPassword)
+	assert.EqualError(t, err, "two-factor authentication is already enabled")
+}
+
+func TestVerify(t *testing.T) {
+	svc, totpSvc, secret := setupTOTPService(t)
+	defer svc.Remove()
+	recoveryCodes, err := totpSvc.ConfirmEnrollment(unlockPassword, codeAt(t, secret, -30*time.Second))
+	require.NoError(t, err)
+
+	// a fresh service has not loaded the secret yet
+	totpSvc = NewTOTPService(svc.Cfg)
+	assert.EqualError(t, totpSvc.VerifyStepUp(codeAt(t, secret, 0)), "please unlock your hub with your authentication code first")
+
+	assert.ErrorIs(t, totpSvc.VerifyUnlock(unlockPassword, ""), NewCodeRequiredError())
+	assert.ErrorIs(t, totpSvc.VerifyUnlock(unlockPassword, "000000"), NewInvalidCodeError())
+	code := codeAt(t, secret, 0)
+	require.NoError(t, totpSvc.VerifyUnlock(unlockPassword, code))
+
+	// codes cannot be reused
+	assert.ErrorIs(t, totpSvc.VerifyStepUp(code), NewInvalidCodeError())
+	assert.ErrorIs(t, totpSvc.VerifyStepUp(""), NewCodeRequiredError())
+	require.NoError(t, totpSvc.VerifyStepUp(codeAt(t, secret, 30*time.Second)))
+
+	// recovery codes can be used once
+	require.NoError(t, totpSvc.VerifyStepUp(recoveryCodes[0]))
+	assert.ErrorIs(t, totpSvc.VerifyStepUp(recoveryCodes[0]), NewInvalidCodeError())
+	status, err := totpSvc.GetStatus()
+	require.NoError(t, err)
+	assert.Equal(t, recoveryCodeCount-1, status.RecoveryCodesRemaining)
+
+	newRecoveryCodes, err := totpSvc.RegenerateRecoveryCodes(unlockPassword, recoveryCodes[1])
+	require.NoError(t, err)
+	assert.ErrorIs(t, totpSvc.VerifyStepUp(recoveryCodes[2]), NewInvalidCodeError())
+	require.NoError(t, totpSvc.VerifyStepUp(newRecoveryCodes[0]))
+}
+
+func TestVerifyStepUp_AutoUnlock(t *testing.T) {
+	svc, totpSvc, secret := setupTOTPService(t)
+	defer svc.Remove()
+	_, err := totpSvc.ConfirmEnrollment(unlockPassword, codeAt(t, secret, -30*time.Second))
+	require.NoError(t, err)
+	require.NoError(t, svc.Cfg.SetAutoUnlockPassword(unlockPassword))
+
+	// the hub is started with the auto-unlock password instead of a code
+	totpSvc = NewTOTPService(svc.Cfg)
+	assert.ErrorIs(t, totpSvc.VerifyStepUp(""), NewCodeRequiredError())
+	assert.ErrorIs(t, totpSvc.VerifyStepUp("000000"), NewInvalidCodeError())
+	require.NoError(t, totpSvc.VerifyStepUp(codeAt(t, secret, 0)))
+}
+
+func TestDisable(t *testing.T) {
+	svc, totpSvc, secret := setupTOTPService(t)
+	defer svc.Remove()
+	recoveryCodes, err := totpSvc.ConfirmEnrollment(unlockPassword, codeAt(t, secret, -30*time.Second))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, totpSvc.Disable(unlockPassword, "000000"), NewInvalidCodeError())
+	require.NoError(t, totpSvc.Disable(unlockPassword, recoveryCodes[0]))
+
+	status, err := totpSvc.GetStatus()
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	assert.NoError(t, totpSvc.VerifyUnlock(unlockPassword, ""))
+	assert.NoError(t, totpSvc.VerifyStepUp(""))
+}
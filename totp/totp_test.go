@@ -0,0 +1,54 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test vectors from RFC 6238 appendix B, truncated to 6 digits
+func TestGenerateCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unixTime, expectedCode := range testCases {
+		code, err := GenerateCode(secret, time.Unix(unixTime, 0))
+		require.NoError(t, err)
+		assert.Equal(t, expectedCode, code)
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := GenerateCode(secret, now)
+	require.NoError(t, err)
+
+	_, ok := validateCode(secret, code, now)
+	assert.True(t, ok)
+	// one step of clock drift is allowed
+	_, ok = validateCode(secret, code, now.Add(period*time.Second))
+	assert.True(t, ok)
+	_, ok = validateCode(secret, code, now.Add(-period*time.Second))
+	assert.True(t, ok)
+
+	_, ok = validateCode(secret, code, now.Add(3*period*time.Second))
+	assert.False(t, ok)
+	_, ok = validateCode(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestProvisioningUri(t *testing.T) {
+	assert.Equal(t, "otpauth://totp/Alby%20Hub:Unlock?algorithm=SHA1&digits=6&issuer=Alby+Hub&period=30&secret=ABCDEF", ProvisioningUri("Alby Hub", "Unlock", "ABCDEF"))
+}
@@ -0,0 +1,534 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// ActivityItem is an entry of the unified activity feed, which merges
+// lightning payments, on-chain transactions, swaps and channel events
+type ActivityItem struct {
+	Kind               string
+	Type               string // incoming or outgoing, empty for channel events
+	State              string
+	AmountMsat         uint64
+	FeeMsat            uint64
+	Description        string
+	CreatedAt          time.Time
+	SettledAt          *time.Time
+	Transaction        *Transaction // set for payments stored as transactions
+	TxId               string
+	SwapId             string
+	CounterpartyNodeId string
+}
+
+// time returns when the activity happened, used to order the feed
+func (item *ActivityItem) time() time.Time {
+	if item.SettledAt != nil {
+		return *item.SettledAt
+	}
+	return item.CreatedAt
+}
+
+type ListActivityFilters struct {
+	Kinds []string // all kinds if empty
+	Type  *string
+	From  uint64
+	Until uint64
+}
+
+func (filters *ListActivityFilters) includes(item *ActivityItem) bool {
+	if len(filters.Kinds) > 0 && !slices.Contains(filters.Kinds, item.Kind) {
+		return false
+	}
+	if filters.Type != nil && item.Type != *filters.Type {
+		return false
+	}
+	itemTime := item.time()
+	if filters.From > 0 && itemTime.Before(time.Unix(int64(filters.From), 0)) {
+		return false
+	}
+	if filters.Until > 0 && itemTime.After(time.Unix(int64(filters.Until), 0)) {
+		return false
+	}
+	return true
+}
+
+func (filters *ListActivityFilters) includesKind(kinds ...string) bool {
+	if len(filters.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range kinds {
+		if slices.Contains(filters.Kinds, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// onchainActivityCacheTtl is how long the on-chain transactions of the node
+// wallet are reused for the following pages of the feed
+const onchainActivityCacheTtl = 2 * time.Minute
+
+// activityRow identifies an entry of the feed stored in the database
+type activityRow struct {
+	Source string
+	ID     uint
+}
+
+// activityQuery selects the rows of one source of the feed, timeColumn is
+// the expression the feed is ordered by
+type activityQuery struct {
+	tx         *gorm.DB
+	timeColumn string
+}
+
+const (
+	activitySourceTransaction  = "transaction"
+	activitySourceSwap         = "swap"
+	activitySourceChannelEvent = "channel_event"
+)
+
+// ListActivity returns the unified activity feed, newest first. Lightning
+// payments are only included once settled. On-chain transactions, swaps and
+// channel events belong to the node rather than an app, so they are left
+// out when listing the activity of an isolated app.
+func (svc *transactionsService) ListActivity(ctx context.Context, limit, offset uint64, lnClient lnclient.LNClient, appId *uint, forceFilterByAppId bool, filters *ListActivityFilters) ([]ActivityItem, uint64, error) {
+	if filters == nil {
+		filters = &ListActivityFilters{}
+	}
+
+	filterByAppId := forceFilterByAppId
+	if appId != nil && !filterByAppId {
+		var isIsolatedApp bool
+		err := svc.db.
+			Model(&db.App{}).
+			Where("id", *appId).
+			Pluck("isolated", &isIsolatedApp).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, 0, NewNotFoundError()
+			}
+			return nil, 0, err
+		}
+		filterByAppId = isIsolatedApp
+	}
+
+	queries := map[string]activityQuery{}
+	if filters.includesKind(constants.ACTIVITY_KIND_LIGHTNING, constants.ACTIVITY_KIND_ONCHAIN) {
+		tx := svc.db.Model(&db.Transaction{}).Where("state = ?", constants.TRANSACTION_STATE_SETTLED)
+		if filterByAppId {
+			tx = tx.Where("app_id = ?", *appId)
+		} else {
+			// the lightning payments of swaps are shown as part of the swap
+			tx = tx.Where("payment_hash NOT IN (?)", svc.db.Model(&db.Swap{}).Select("payment_hash").Where("payment_hash != ''"))
+		}
+		if filters.Type != nil {
+			tx = tx.Where("type = ?", *filters.Type)
+		}
+		if !filters.includesKind(constants.ACTIVITY_KIND_ONCHAIN) {
+			tx = tx.Where("onchain_tx_id = ''")
+		}
+		if !filters.includesKind(constants.ACTIVITY_KIND_LIGHTNING) {
+			tx = tx.Where("onchain_tx_id != ''")
+		}
+		if filters.From > 0 {
+			tx = tx.Where("settled_at >= ?", time.Unix(int64(filters.From), 0))
+		}
+		if filters.Until > 0 {
+			tx = tx.Where("settled_at <= ?", time.Unix(int64(filters.Until), 0))
+		}
+		queries[activitySourceTransaction] = activityQuery{tx, "settled_at"}
+	}
+
+	if !filterByAppId {
+		swapTypes := []string{}
+		if filters.includesKind(constants.ACTIVITY_KIND_SWAP_IN) && (filters.Type == nil || *filters.Type == constants.TRANSACTION_TYPE_INCOMING) {
+			swapTypes = append(swapTypes, constants.SWAP_TYPE_IN)
+		}
+		if filters.includesKind(constants.ACTIVITY_KIND_SWAP_OUT) && (filters.Type == nil || *filters.Type == constants.TRANSACTION_TYPE_OUTGOING) {
+			swapTypes = append(swapTypes, constants.SWAP_TYPE_OUT)
+		}
+		if len(swapTypes) > 0 {
+			// successful swaps are ordered by when they completed, see swapToActivityItem
+			swapTime := "CASE WHEN state = '" + constants.SWAP_STATE_SUCCESS + "' THEN updated_at ELSE created_at END"
+			tx := svc.db.Model(&db.Swap{}).Where("type IN ?", swapTypes)
+			if filters.From > 0 {
+				tx = tx.Where(swapTime+" >= ?", time.Unix(int64(filters.From), 0))
+			}
+			if filters.Until > 0 {
+				tx = tx.Where(swapTime+" <= ?", time.Unix(int64(filters.Until), 0))
+			}
+			queries[activitySourceSwap] = activityQuery{tx, swapTime}
+		}
+
+		// channel events have no direction
+		channelEventTypes := []string{}
+		if filters.includesKind(constants.ACTIVITY_KIND_CHANNEL_OPEN) {
+			channelEventTypes = append(channelEventTypes, constants.CHANNEL_EVENT_TYPE_OPEN)
+		}
+		if filters.includesKind(constants.ACTIVITY_KIND_CHANNEL_CLOSE) {
+			channelEventTypes = append(channelEventTypes, constants.CHANNEL_EVENT_TYPE_CLOSE)
+		}
+		if filters.Type == nil && len(channelEventTypes) > 0 {
+			tx := svc.db.Model(&db.ChannelEvent{}).Where("type IN ?", channelEventTypes)
+			if filters.From > 0 {
+				tx = tx.Where("created_at >= ?", time.Unix(int64(filters.From), 0))
+			}
+			if filters.Until > 0 {
+				tx = tx.Where("created_at <= ?", time.Unix(int64(filters.Until), 0))
+			}
+			queries[activitySourceChannelEvent] = activityQuery{tx, "created_at"}
+		}
+	}
+
+	var totalCount uint64
+	var unionQueries []string
+	var unionArgs []interface{}
+	for _, source := range []string{activitySourceTransaction, activitySourceSwap, activitySourceChannelEvent} {
+		query, ok := queries[source]
+		if !ok {
+			continue
+		}
+		tx := query.tx.Session(&gorm.Session{})
+		var count int64
+		if err := tx.Count(&count).Error; err != nil {
+			logger.Logger.WithError(err).WithField("source", source).Error("Failed to count activity")
+			return nil, 0, err
+		}
+		totalCount += uint64(count)
+		// sqlite does not allow parenthesized selects directly in a union
+		unionQueries = append(unionQueries, "SELECT * FROM (?) AS "+source+"_activity")
+		unionArgs = append(unionArgs, tx.Select("'"+source+"' AS source, id, "+query.timeColumn+" AS activity_time"))
+	}
+
+	// the node wallet history is not stored, so it is merged into the newest
+	// offset+limit rows of the database
+	var onchainItems []ActivityItem
+	if !filterByAppId && filters.includesKind(constants.ACTIVITY_KIND_ONCHAIN) {
+		var err error
+		onchainItems, err = svc.listOnchainActivity(ctx, lnClient, offset == 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		onchainItems = slices.DeleteFunc(onchainItems, func(item ActivityItem) bool {
+			return !filters.includes(&item)
+		})
+		totalCount += uint64(len(onchainItems))
+	}
+
+	items := []ActivityItem{}
+	if len(unionQueries) > 0 {
+		query := strings.Join(unionQueries, " UNION ALL ") + " ORDER BY activity_time DESC"
+		if limit > 0 {
+			query += " LIMIT ?"
+			unionArgs = append(unionArgs, offset+limit)
+		}
+		var rows []activityRow
+		if err := svc.db.Raw(query, unionArgs...).Scan(&rows).Error; err != nil {
+			logger.Logger.WithError(err).Error("Failed to list activity")
+			return nil, 0, err
+		}
+		var err error
+		items, err = svc.loadActivityItems(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	items = append(items, onchainItems...)
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].time().After(items[j].time())
+	})
+
+	if offset >= uint64(len(items)) {
+		return []ActivityItem{}, totalCount, nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < uint64(len(items)) {
+		items = items[:limit]
+	}
+	return items, totalCount, nil
+}
+
+// loadActivityItems loads the records of a page of the feed, keeping the order of the rows
+func (svc *transactionsService) loadActivityItems(rows []activityRow) ([]ActivityItem, error) {
+	ids := map[string][]uint{}
+	for _, row := range rows {
+		ids[row.Source] = append(ids[row.Source], row.ID)
+	}
+
+	itemsByRow := map[activityRow]ActivityItem{}
+	if len(ids[activitySourceTransaction]) > 0 {
+		var transactions []Transaction
+		if err := svc.db.Find(&transactions, ids[activitySourceTransaction]).Error; err != nil {
+			logger.Logger.WithError(err).Error("Failed to load activity transactions")
+			return nil, err
+		}
+		for i := range transactions {
+			itemsByRow[activityRow{activitySourceTransaction, transactions[i].ID}] = transactionToActivityItem(&transactions[i])
+		}
+	}
+	if len(ids[activitySourceSwap]) > 0 {
+		var swaps []db.Swap
+		if err := svc.db.Find(&swaps, ids[activitySourceSwap]).Error; err != nil {
+			logger.Logger.WithError(err).Error("Failed to load activity swaps")
+			return nil, err
+		}
+		for i := range swaps {
+			itemsByRow[activityRow{activitySourceSwap, swaps[i].ID}] = swapToActivityItem(&swaps[i])
+		}
+	}
+	if len(ids[activitySourceChannelEvent]) > 0 {
+		var channelEvents []db.ChannelEvent
+		if err := svc.db.Find(&channelEvents, ids[activitySourceChannelEvent]).Error; err != nil {
+			logger.Logger.WithError(err).Error("Failed to load activity channel events")
+			return nil, err
+		}
+		for i := range channelEvents {
+			itemsByRow[activityRow{activitySourceChannelEvent, channelEvents[i].ID}] = channelEventToActivityItem(&channelEvents[i])
+		}
+	}
+
+	items := make([]ActivityItem, 0, len(rows))
+	for _, row := range rows {
+		if item, ok := itemsByRow[row]; ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// listOnchainActivity returns the on-chain transactions of the node wallet which
+// are not already part of the feed as an NWC payment, swap or channel event.
+// The wallet history is cached between pages unless refresh is set.
+func (svc *transactionsService) listOnchainActivity(ctx context.Context, lnClient lnclient.LNClient, refresh bool) ([]ActivityItem, error) {
+	onchainTransactions, err := svc.getOnchainTransactions(ctx, lnClient, refresh)
+	if err != nil {
+		return nil, err
+	}
+	if len(onchainTransactions) == 0 {
+		return nil, nil
+	}
+
+	txIds := make([]string, 0, len(onchainTransactions))
+	for _, onchainTransaction := range onchainTransactions {
+		txIds = append(txIds, onchainTransaction.TxId)
+	}
+
+	var knownTxIds []string
+	var transactionTxIds []string
+	err = svc.db.Model(&db.Transaction{}).Where("onchain_tx_id IN ?", txIds).Pluck("onchain_tx_id", &transactionTxIds).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list on-chain payments")
+		return nil, err
+	}
+	knownTxIds = append(knownTxIds, transactionTxIds...)
+	var swaps []db.Swap
+	err = svc.db.Select("lockup_tx_id", "claim_tx_id").Where("lockup_tx_id IN ? OR claim_tx_id IN ?", txIds, txIds).Find(&swaps).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list swap transactions")
+		return nil, err
+	}
+	for _, swap := range swaps {
+		knownTxIds = append(knownTxIds, swap.LockupTxId, swap.ClaimTxId)
+	}
+	var channelEventTxIds []string
+	err = svc.db.Model(&db.ChannelEvent{}).Where("funding_tx_id IN ?", txIds).Pluck("funding_tx_id", &channelEventTxIds).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list channel funding transactions")
+		return nil, err
+	}
+	knownTxIds = append(knownTxIds, channelEventTxIds...)
+
+	items := []ActivityItem{}
+	for _, onchainTransaction := range onchainTransactions {
+		if slices.Contains(knownTxIds, onchainTransaction.TxId) {
+			continue
+		}
+		state := constants.TRANSACTION_STATE_PENDING
+		if onchainTransaction.State == "confirmed" {
+			state = constants.TRANSACTION_STATE_SETTLED
+		}
+		items = append(items, ActivityItem{
+			Kind:       constants.ACTIVITY_KIND_ONCHAIN,
+			Type:       onchainTransaction.Type,
+			State:      state,
+			AmountMsat: onchainTransaction.AmountSat * 1000,
+			CreatedAt:  time.Unix(int64(onchainTransaction.CreatedAt), 0),
+			TxId:       onchainTransaction.TxId,
+		})
+	}
+	return items, nil
+}
+
+func (svc *transactionsService) getOnchainTransactions(ctx context.Context, lnClient lnclient.LNClient, refresh bool) ([]lnclient.OnchainTransaction, error) {
+	svc.onchainTransactionsCacheMutex.Lock()
+	defer svc.onchainTransactionsCacheMutex.Unlock()
+
+	if !refresh && time.Since(svc.onchainTransactionsCachedAt) < onchainActivityCacheTtl {
+		return svc.onchainTransactionsCache, nil
+	}
+
+	onchainTransactions, err := lnClient.ListOnchainTransactions(ctx)
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, nil
+		}
+		logger.Logger.WithError(err).Error("Failed to list on-chain transactions")
+		return nil, err
+	}
+	svc.onchainTransactionsCache = onchainTransactions
+	svc.onchainTransactionsCachedAt = time.Now()
+	return onchainTransactions, nil
+}
+
+func transactionToActivityItem(transaction *Transaction) ActivityItem {
+	kind := constants.ACTIVITY_KIND_LIGHTNING
+	if transaction.OnchainTxId != "" {
+		kind = constants.ACTIVITY_KIND_ONCHAIN
+	}
+	return ActivityItem{
+		Kind:        kind,
+		Type:        transaction.Type,
+		State:       transaction.State,
+		AmountMsat:  transaction.AmountMsat,
+		FeeMsat:     transaction.FeeMsat,
+		Description: transaction.Description,
+		CreatedAt:   transaction.CreatedAt,
+		SettledAt:   transaction.SettledAt,
+		Transaction: transaction,
+		TxId:        transaction.OnchainTxId,
+	}
+}
+
+// swaps are shown with the amount that arrived and the difference to the
+// amount sent as the fee
+func swapToActivityItem(swap *db.Swap) ActivityItem {
+	item := ActivityItem{
+		Kind:       constants.ACTIVITY_KIND_SWAP_OUT,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		AmountMsat: swap.ReceiveAmountSat * 1000,
+		CreatedAt:  swap.CreatedAt,
+		TxId:       swap.ClaimTxId,
+		SwapId:     swap.SwapId,
+	}
+	if swap.Type == constants.SWAP_TYPE_IN {
+		item.Kind = constants.ACTIVITY_KIND_SWAP_IN
+		item.Type = constants.TRANSACTION_TYPE_INCOMING
+		item.TxId = swap.LockupTxId
+	}
+	if swap.SendAmountSat > swap.ReceiveAmountSat {
+		item.FeeMsat = (swap.SendAmountSat - swap.ReceiveAmountSat) * 1000
+	}
+
+	switch swap.State {
+	case constants.SWAP_STATE_SUCCESS:
+		item.State = constants.TRANSACTION_STATE_SETTLED
+		settledAt := swap.UpdatedAt
+		item.SettledAt = &settledAt
+	case constants.SWAP_STATE_PENDING:
+		item.State = constants.TRANSACTION_STATE_PENDING
+	default:
+		item.State = constants.TRANSACTION_STATE_FAILED
+	}
+	return item
+}
+
+func channelEventToActivityItem(channelEvent *db.ChannelEvent) ActivityItem {
+	kind := constants.ACTIVITY_KIND_CHANNEL_OPEN
+	if channelEvent.Type == constants.CHANNEL_EVENT_TYPE_CLOSE {
+		kind = constants.ACTIVITY_KIND_CHANNEL_CLOSE
+	}
+	settledAt := channelEvent.CreatedAt
+	return ActivityItem{
+		Kind:               kind,
+		State:              constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat:         channelEvent.AmountSat * 1000,
+		Description:        channelEvent.Reason,
+		CreatedAt:          channelEvent.CreatedAt,
+		SettledAt:          &settledAt,
+		TxId:               channelEvent.FundingTxId,
+		CounterpartyNodeId: channelEvent.CounterpartyNodeId,
+	}
+}
+
+// recordChannelEvent stores channel opens and closes so they can be shown
+// in the activity feed. The event properties differ slightly per backend.
+func (svc *transactionsService) recordChannelEvent(event *events.Event) {
+	properties, ok := event.Properties.(map[string]interface{})
+	if !ok {
+		logger.Logger.WithField("event", event).Error("Failed to cast event")
+		return
+	}
+
+	channelEvent := db.ChannelEvent{
+		Type:               constants.CHANNEL_EVENT_TYPE_OPEN,
+		CounterpartyNodeId: getStringProperty(properties, "counterparty_node_id"),
+		AmountSat:          getUintProperty(properties, "capacity"),
+		FundingTxId:        getStringProperty(properties, "funding_tx_id"),
+		Reason:             getStringProperty(properties, "reason"),
+	}
+	channelEvent.IsOutbound, _ = properties["is_outbound"].(bool)
+	channelEvent.Public, _ = properties["public"].(bool)
+	if event.Event == "nwc_channel_closed" {
+		channelEvent.Type = constants.CHANNEL_EVENT_TYPE_CLOSE
+		channelEvent.AmountSat = getUintProperty(properties, "pending_balance")
+	}
+
+	err := svc.db.Create(&channelEvent).Error
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"event":                event.Event,
+			"counterparty_node_id": channelEvent.CounterpartyNodeId,
+		}).WithError(err).Error("Failed to record channel event")
+	}
+}
+
+func getStringProperty(properties map[string]interface{}, key string) string {
+	switch value := properties[key].(type) {
+	case string:
+		return value
+	case *string:
+		if value != nil {
+			return *value
+		}
+	}
+	return ""
+}
+
+func getUintProperty(properties map[string]interface{}, key string) uint64 {
+	switch value := properties[key].(type) {
+	case uint64:
+		return value
+	case uint32:
+		return uint64(value)
+	case int64:
+		if value > 0 {
+			return uint64(value)
+		}
+	case int:
+		if value > 0 {
+			return uint64(value)
+		}
+	case float64:
+		if value > 0 {
+			return uint64(value)
+		}
+	}
+	return 0
+}
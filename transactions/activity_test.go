@@ -0,0 +1,368 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+const activitySwapPaymentHash = "a9b7d6f2c2e4c8d5fc1b7a6a5c0d9b3e8e1f4a2c6b9d0e3f5a7c8b1d2e4f6a8c"
+
+func createActivityTestData(t *testing.T, svc *tests.TestService, transactionsService *transactionsService, now time.Time) *db.App {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	settledAt := now.Add(-4 * time.Hour)
+	err = svc.DB.Create(&db.Transaction{
+		AppId:       &app.ID,
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat:  21_000,
+		PaymentHash: tests.MockPaymentHash,
+		SettledAt:   &settledAt,
+	}).Error
+	require.NoError(t, err)
+
+	// the lightning payment of the swap is not listed separately
+	swapSettledAt := now.Add(-3 * time.Hour)
+	err = svc.DB.Create(&db.Transaction{
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat:  100_000_000,
+		PaymentHash: activitySwapPaymentHash,
+		SettledAt:   &swapSettledAt,
+	}).Error
+	require.NoError(t, err)
+	err = svc.DB.Create(&db.Swap{
+		SwapId:           "swap1",
+		Type:             constants.SWAP_TYPE_OUT,
+		State:            constants.SWAP_STATE_SUCCESS,
+		SendAmountSat:    100_000,
+		ReceiveAmountSat: 99_000,
+		PaymentHash:      activitySwapPaymentHash,
+		ClaimTxId:        "claimtxid",
+		CreatedAt:        now.Add(-3 * time.Hour),
+		UpdatedAt:        now.Add(-2 * time.Hour),
+	}).Error
+	require.NoError(t, err)
+
+	transactionsService.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_channel_ready",
+		Properties: map[string]interface{}{
+			"counterparty_node_id": spendingPolicyDestination,
+			"capacity":             int64(500_000),
+			"is_outbound":          true,
+		},
+	}, nil)
+
+	svc.LNClient.(*tests.MockLn).OnchainTransactions = []lnclient.OnchainTransaction{
+		{
+			TxId:      "claimtxid",
+			Type:      "incoming",
+			State:     "confirmed",
+			AmountSat: 99_000,
+			CreatedAt: uint64(now.Add(-2 * time.Hour).Unix()),
+		},
+		{
+			TxId:      "deposittxid",
+			Type:      "incoming",
+			State:     "unconfirmed",
+			AmountSat: 50_000,
+			CreatedAt: uint64(now.Add(-1 * time.Hour).Unix()),
+		},
+	}
+
+	return app
+}
+
+func TestListActivity(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	createActivityTestData(t, svc, transactionsService, time.Now())
+
+	activityItems, totalCount, err := transactionsService.ListActivity(ctx, 0, 0, svc.LNClient, nil, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), totalCount)
+	require.Equal(t, 4, len(activityItems))
+
+	assert.Equal(t, constants.ACTIVITY_KIND_CHANNEL_OPEN, activityItems[0].Kind)
+	assert.Equal(t, uint64(500_000_000), activityItems[0].AmountMsat)
+	assert.Equal(t, spendingPolicyDestination, activityItems[0].CounterpartyNodeId)
+
+	assert.Equal(t, constants.ACTIVITY_KIND_ONCHAIN, activityItems[1].Kind)
+	assert.Equal(t, "deposittxid", activityItems[1].TxId)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, activityItems[1].State)
+
+	assert.Equal(t, constants.ACTIVITY_KIND_SWAP_OUT, activityItems[2].Kind)
+	assert.Equal(t, constants.TRANSACTION_TYPE_OUTGOING, activityItems[2].Type)
+	assert.Equal(t, uint64(99_000_000), activityItems[2].AmountMsat)
+	assert.Equal(t, uint64(1_000_000), activityItems[2].FeeMsat)
+	assert.Equal(t, "swap1", activityItems[2].SwapId)
+
+	assert.Equal(t, constants.ACTIVITY_KIND_LIGHTNING, activityItems[3].Kind)
+	assert.Equal(t, tests.MockPaymentHash, activityItems[3].Transaction.PaymentHash)
+
+	// pagination
+	activityItems, totalCount, err = transactionsService.ListActivity(ctx, 2, 1, svc.LNClient, nil, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), totalCount)
+	require.Equal(t, 2, len(activityItems))
+	assert.Equal(t, constants.ACTIVITY_KIND_ONCHAIN, activityItems[0].Kind)
+	assert.Equal(t, constants.ACTIVITY_KIND_SWAP_OUT, activityItems[1].Kind)
+
+	// filters
+	incoming := constants.TRANSACTION_TYPE_INCOMING
+	activityItems, totalCount, err = transactionsService.ListActivity(ctx, 0, 0, svc.LNClient, nil, false, &ListActivityFilters{
+		Kinds: []string{constants.ACTIVITY_KIND_LIGHTNING, constants.ACTIVITY_KIND_ONCHAIN},
+		Type:  &incoming,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), totalCount)
+	require.Equal(t, 2, len(activityItems))
+	assert.Equal(t, "deposittxid", activityItems[0].TxId)
+	assert.Equal(t, constants.ACTIVITY_KIND_LIGHTNING, activityItems[1].Kind)
+}
+
+func TestListActivity_IsolatedApp(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	app := createActivityTestData(t, svc, transactionsService, time.Now())
+	app.Isolated = true
+	svc.DB.Save(&app)
+
+	// node-wide activity is not shown to isolated apps
+	activityItems, totalCount, err := transactionsService.ListActivity(ctx, 0, 0, svc.LNClient, &app.ID, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), totalCount)
+	require.Equal(t, 1, len(activityItems))
+	assert.Equal(t, constants.ACTIVITY_KIND_LIGHTNING, activityItems[0].Kind)
+	assert.Equal(t, app.ID, *activityItems[0].Transaction.AppId)
+}
+
+func TestListActivity_Pagination(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	now := time.Now()
+	createActivityTestData(t, svc, transactionsService, now)
+
+	// pages are ordered across all sources, including the node wallet
+	activityItems, totalCount, err := transactionsService.ListActivity(ctx, 1, 0, svc.LNClient, nil, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), totalCount)
+	require.Equal(t, 1, len(activityItems))
+	assert.Equal(t, constants.ACTIVITY_KIND_CHANNEL_OPEN, activityItems[0].Kind)
+
+	kinds := []string{}
+	for offset := uint64(1); offset < 4; offset++ {
+		activityItems, _, err = transactionsService.ListActivity(ctx, 1, offset, svc.LNClient, nil, false, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(activityItems))
+		kinds = append(kinds, activityItems[0].Kind)
+	}
+	assert.Equal(t, []string{constants.ACTIVITY_KIND_ONCHAIN, constants.ACTIVITY_KIND_SWAP_OUT, constants.ACTIVITY_KIND_LIGHTNING}, kinds)
+
+	// time range filters are applied to every source
+	activityItems, totalCount, err = transactionsService.ListActivity(ctx, 0, 0, svc.LNClient, nil, false, &ListActivityFilters{
+		From:  uint64(now.Add(-150 * time.Minute).Unix()),
+		Until: uint64(now.Add(-30 * time.Minute).Unix()),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), totalCount)
+	require.Equal(t, 2, len(activityItems))
+	assert.Equal(t, "deposittxid", activityItems[0].TxId)
+	assert.Equal(t, "swap1", activityItems[1].SwapId)
+
+	activityItems, totalCount, err = transactionsService.ListActivity(ctx, 0, 0, svc.LNClient, nil, false, &ListActivityFilters{
+		Kinds: []string{constants.ACTIVITY_KIND_SWAP_IN, constants.ACTIVITY_KIND_CHANNEL_OPEN},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), totalCount)
+	require.Equal(t, 1, len(activityItems))
+	assert.Equal(t, constants.ACTIVITY_KIND_CHANNEL_OPEN, activityItems[0].Kind)
+}
+
+func TestListActivity_ChannelFundingTransaction(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transactionsService.ConsumeEvent(ctx, &events.Event{
+		Event: "nwc_channel_ready",
+		Properties: map[string]interface{}{
+			"counterparty_node_id": spendingPolicyDestination,
+			"capacity":             uint64(500_000),
+			"is_outbound":          true,
+			"funding_tx_id":        "fundingtxid",
+		},
+	}, nil)
+	svc.LNClient.(*tests.MockLn).OnchainTransactions = []lnclient.OnchainTransaction{
+		{
+			TxId:      "fundingtxid",
+			Type:      "outgoing",
+			State:     "confirmed",
+			AmountSat: 500_000,
+			CreatedAt: uint64(time.Now().Unix()),
+		},
+	}
+
+	// the funding transaction is only shown as the channel open
+	activityItems, totalCount, err := transactionsService.ListActivity(ctx, 0, 0, svc.LNClient, nil, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), totalCount)
+	require.Equal(t, 1, len(activityItems))
+	assert.Equal(t, constants.ACTIVITY_KIND_CHANNEL_OPEN, activityItems[0].Kind)
+	assert.Equal(t, "fundingtxid", activityItems[0].TxId)
+}
+
+func TestRecordChannelEvent_BackendEvents(t *testing.T) {
+	counterpartyNodeId := spendingPolicyDestination
+	testCases := []struct {
+		name              string
+		event             *events.Event
+		expectedType      string
+		expectedAmountSat uint64
+	}{
+		{
+			name: "LDK channel ready",
+			event: &events.Event{
+				Event: "nwc_channel_ready",
+				Properties: map[string]interface{}{
+					"counterparty_node_id": &counterpartyNodeId,
+					"node_type":            "LDK",
+					"public":               false,
+					"jit":                  false,
+					"capacity":             uint64(500_000),
+					"is_outbound":          true,
+					"trusted":              false,
+					"funding_tx_id":        "fundingtxid",
+				},
+			},
+			expectedType:      constants.CHANNEL_EVENT_TYPE_OPEN,
+			expectedAmountSat: 500_000,
+		},
+		{
+			name: "LDK channel closed",
+			event: &events.Event{
+				Event: "nwc_channel_closed",
+				Properties: map[string]interface{}{
+					"counterparty_node_id":  counterpartyNodeId,
+					"counterparty_node_url": "https://amboss.space/node/" + counterpartyNodeId,
+					"reason":                "CooperativeClosure",
+					"node_type":             "LDK",
+					"pending_balance":       uint64(120_000),
+					"funding_tx_id":         "fundingtxid",
+					"funding_tx_vout":       uint32(0),
+					"funding_tx_url":        "https://mempool.space/tx/fundingtxid#flow=&vout=0",
+				},
+			},
+			expectedType:      constants.CHANNEL_EVENT_TYPE_CLOSE,
+			expectedAmountSat: 120_000,
+		},
+		{
+			name: "CLN channel ready",
+			event: &events.Event{
+				Event: "nwc_channel_ready",
+				Properties: map[string]interface{}{
+					"counterparty_node_id": counterpartyNodeId,
+					"node_type":            "CLN",
+					"public":               false,
+					"capacity":             uint64(500_000),
+					"is_outbound":          true,
+					"funding_tx_id":        "fundingtxid",
+				},
+			},
+			expectedType:      constants.CHANNEL_EVENT_TYPE_OPEN,
+			expectedAmountSat: 500_000,
+		},
+		{
+			name: "CLN channel closed",
+			event: &events.Event{
+				Event: "nwc_channel_closed",
+				Properties: map[string]interface{}{
+					"counterparty_node_id":  counterpartyNodeId,
+					"counterparty_node_url": "https://amboss.space/node/" + counterpartyNodeId,
+					"reason":                "ONCHAIN",
+					"node_type":             "CLN",
+					"pending_balance":       uint64(120_000),
+					"funding_tx_id":         "fundingtxid",
+				},
+			},
+			expectedType:      constants.CHANNEL_EVENT_TYPE_CLOSE,
+			expectedAmountSat: 120_000,
+		},
+		{
+			name: "LND channel ready",
+			event: &events.Event{
+				Event: "nwc_channel_ready",
+				Properties: map[string]interface{}{
+					"counterparty_node_id": counterpartyNodeId,
+					"node_type":            "LND",
+					"public":               false,
+					"capacity":             int64(500_000),
+					"is_outbound":          true,
+					"funding_tx_id":        "fundingtxid",
+				},
+			},
+			expectedType:      constants.CHANNEL_EVENT_TYPE_OPEN,
+			expectedAmountSat: 500_000,
+		},
+		{
+			name: "LND channel closed",
+			event: &events.Event{
+				Event: "nwc_channel_closed",
+				Properties: map[string]interface{}{
+					"counterparty_node_id":  counterpartyNodeId,
+					"counterparty_node_url": "https://amboss.space/node/" + counterpartyNodeId,
+					"reason":                "COOPERATIVE_CLOSE",
+					"node_type":             "LND",
+					"pending_balance":       int64(120_000),
+					"funding_tx_id":         "fundingtxid",
+				},
+			},
+			expectedType:      constants.CHANNEL_EVENT_TYPE_CLOSE,
+			expectedAmountSat: 120_000,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, err := tests.CreateTestService(t)
+			require.NoError(t, err)
+			defer svc.Remove()
+
+			transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+			transactionsService.ConsumeEvent(context.TODO(), tc.event, nil)
+
+			var channelEvent db.ChannelEvent
+			err = svc.DB.First(&channelEvent).Error
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedType, channelEvent.Type)
+			assert.Equal(t, counterpartyNodeId, channelEvent.CounterpartyNodeId)
+			assert.Equal(t, tc.expectedAmountSat, channelEvent.AmountSat)
+			assert.Equal(t, "fundingtxid", channelEvent.FundingTxId)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package transactions
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+const (
+	offerPathsTlvType    = 16
+	offerIssuerIdTlvType = 22
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// decodeOfferNodeId returns the node pubkey a BOLT-12 offer is paid to: the issuer id
+// of the offer, or the introduction node of its first blinded path. It returns an
+// empty string if the offer only has paths starting at a short channel id.
+func decodeOfferNodeId(offer string) (string, error) {
+	tlvStream, err := decodeOfferTlvStream(offer)
+	if err != nil {
+		return "", err
+	}
+
+	var introductionNodeId string
+	for len(tlvStream) > 0 {
+		var tlvType, length uint64
+		tlvType, tlvStream, err = readBigSize(tlvStream)
+		if err != nil {
+			return "", err
+		}
+		length, tlvStream, err = readBigSize(tlvStream)
+		if err != nil {
+			return "", err
+		}
+		if uint64(len(tlvStream)) < length {
+			return "", errors.New("truncated offer TLV record")
+		}
+		value := tlvStream[:length]
+		tlvStream = tlvStream[length:]
+
+		switch tlvType {
+		case offerIssuerIdTlvType:
+			if len(value) != 33 {
+				return "", errors.New("invalid offer issuer id")
+			}
+			return hex.EncodeToString(value), nil
+		case offerPathsTlvType:
+			// a blinded path starts with a pubkey, or with a direction byte (0 or 1) and a short channel id
+			if len(value) >= 33 && (value[0] == 2 || value[0] == 3) {
+				introductionNodeId = hex.EncodeToString(value[:33])
+			}
+		}
+	}
+
+	return introductionNodeId, nil
+}
+
+// decodeOfferTlvStream decodes the bech32 data of an offer, which has no checksum
+// and may be split with "+" followed by whitespace
+func decodeOfferTlvStream(offer string) ([]byte, error) {
+	offer = strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(offer, "+", " ")), ""))
+	separatorIndex := strings.LastIndexByte(offer, '1')
+	if separatorIndex < 0 || offer[:separatorIndex] != "lno" {
+		return nil, errors.New("invalid BOLT-12 offer")
+	}
+
+	data := make([]byte, 0, len(offer)-separatorIndex-1)
+	for _, c := range offer[separatorIndex+1:] {
+		value := strings.IndexRune(bech32Charset, c)
+		if value < 0 {
+			return nil, fmt.Errorf("invalid character %q in BOLT-12 offer", c)
+		}
+		data = append(data, byte(value))
+	}
+
+	return bech32.ConvertBits(data, 5, 8, false)
+}
+
+func readBigSize(data []byte) (uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, errors.New("truncated offer TLV stream")
+	}
+	switch data[0] {
+	case 0xfd:
+		if len(data) < 3 {
+			return 0, nil, errors.New("truncated offer TLV stream")
+		}
+		return uint64(binary.BigEndian.Uint16(data[1:3])), data[3:], nil
+	case 0xfe:
+		if len(data) < 5 {
+			return 0, nil, errors.New("truncated offer TLV stream")
+		}
+		return uint64(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+	case 0xff:
+		if len(data) < 9 {
+			return 0, nil, errors.New("truncated offer TLV stream")
+		}
+		return binary.BigEndian.Uint64(data[1:9]), data[9:], nil
+	default:
+		return uint64(data[0]), data[1:], nil
+	}
+}
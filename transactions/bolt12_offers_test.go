@@ -0,0 +1,93 @@
+package transactions
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const offerIntroductionNodeId = "0324653eac434488002cc06bbfb7f10fe18991e35f9fe4302dbea6d2353dc0ab1c"
+
+func encodeTestOffer(t *testing.T, records ...[]byte) string {
+	data, err := bech32.ConvertBits(bytes.Join(records, nil), 8, 5, true)
+	require.NoError(t, err)
+	var offer strings.Builder
+	offer.WriteString("lno1")
+	for _, value := range data {
+		offer.WriteByte(bech32Charset[value])
+	}
+	return offer.String()
+}
+
+func testTlvRecord(tlvType byte, value []byte) []byte {
+	return append([]byte{tlvType, byte(len(value))}, value...)
+}
+
+func testBlindedPath(t *testing.T, introductionNode []byte) []byte {
+	pathKey, err := hex.DecodeString(offerIntroductionNodeId)
+	require.NoError(t, err)
+	path := append([]byte{}, introductionNode...)
+	path = append(path, pathKey...)
+	// one hop with a blinded node id and 2 bytes of encrypted data
+	path = append(path, 1)
+	path = append(path, pathKey...)
+	path = append(path, 0, 2, 0xab, 0xcd)
+	return path
+}
+
+func TestDecodeOfferNodeId_IssuerId(t *testing.T) {
+	// minimal offer from the BOLT-12 test vectors
+	nodeId, err := decodeOfferNodeId("lno1pgx9getnwss8vetrw3hhyuckyypwa3eyt44h6txtxquqh7lz5djge4afgfjn7k4rgrkuag0jsd5xvxg")
+	assert.NoError(t, err)
+	assert.Equal(t, "02eec7245d6b7d2ccb30380bfbe2a3648cd7a942653f5aa340edcea1f283686619", nodeId)
+}
+
+func TestDecodeOfferNodeId_SplitOffer(t *testing.T) {
+	nodeId, err := decodeOfferNodeId("LNO1PGX9GETNWSS8VETRW3HHYUCKYYPWA3EYT44H6TXTXQUQH7LZ5DJGE4AFGFJN7K4RGRKUAG0JSD5+\n  XVXG")
+	assert.NoError(t, err)
+	assert.Equal(t, "02eec7245d6b7d2ccb30380bfbe2a3648cd7a942653f5aa340edcea1f283686619", nodeId)
+}
+
+func TestDecodeOfferNodeId_BlindedPath(t *testing.T) {
+	introductionNode, err := hex.DecodeString(offerIntroductionNodeId)
+	require.NoError(t, err)
+
+	offer := encodeTestOffer(t,
+		testTlvRecord(10, []byte("test")),
+		testTlvRecord(offerPathsTlvType, testBlindedPath(t, introductionNode)),
+	)
+
+	nodeId, err := decodeOfferNodeId(offer)
+	assert.NoError(t, err)
+	assert.Equal(t, offerIntroductionNodeId, nodeId)
+}
+
+func TestDecodeOfferNodeId_ShortChannelIdPath(t *testing.T) {
+	// direction byte followed by a short channel id
+	introductionNode := []byte{0, 0, 0, 1, 0, 0, 2, 0, 3}
+
+	offer := encodeTestOffer(t,
+		testTlvRecord(offerPathsTlvType, testBlindedPath(t, introductionNode)),
+	)
+
+	nodeId, err := decodeOfferNodeId(offer)
+	assert.NoError(t, err)
+	assert.Equal(t, "", nodeId)
+}
+
+func TestDecodeOfferNodeId_Invalid(t *testing.T) {
+	_, err := decodeOfferNodeId("lnbc1invalid")
+	assert.Error(t, err)
+
+	_, err = decodeOfferNodeId("lno1bbbb")
+	assert.Error(t, err)
+
+	// issuer id record longer than the remaining data
+	_, err = decodeOfferNodeId(encodeTestOffer(t, []byte{offerIssuerIdTlvType, 33, 2}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendCircularPayment(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockPreimage := "123preimage"
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		State:          constants.TRANSACTION_STATE_PENDING,
+		Type:           constants.TRANSACTION_TYPE_INCOMING,
+		PaymentRequest: tests.MockInvoice,
+		PaymentHash:    tests.MockPaymentHash,
+		Preimage:       &mockPreimage,
+		AmountMsat:     123000,
+	}).Error)
+
+	mockLn := svc.LNClient.(*tests.MockLn)
+	mockLn.PayInvoiceResponses = []*lnclient.PayInvoiceResponse{{Preimage: mockPreimage, FeeMsat: 42}}
+	mockLn.PayInvoiceErrors = []error{nil}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendCircularPayment(ctx, tests.MockInvoice, "chan-a", "chan-b", "peer-b", 100, map[string]interface{}{"source_channel_id": "chan-a"}, svc.LNClient)
+	require.NoError(t, err)
+	assert.Equal(t, &lnclient.CircularPaymentRequest{
+		PaymentRequest:    tests.MockInvoice,
+		OutgoingChannelId: "chan-a",
+		IncomingChannelId: "chan-b",
+		LastHopPubkey:     "peer-b",
+		MaxFeeMsat:        100,
+	}, mockLn.LastCircularPaymentRequest)
+	assert.Equal(t, constants.TRANSACTION_TYPE_OUTGOING, transaction.Type)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, uint64(123000), transaction.AmountMsat)
+	assert.Equal(t, uint64(42), transaction.FeeMsat)
+	assert.True(t, transaction.SelfPayment)
+
+	var incomingTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&incomingTransaction, &db.Transaction{Type: constants.TRANSACTION_TYPE_INCOMING}).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, incomingTransaction.State)
+	assert.True(t, incomingTransaction.SelfPayment)
+}
+
+func TestSendCircularPayment_Failed(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	mockPreimage := "123preimage"
+	require.NoError(t, svc.DB.Create(&db.Transaction{
+		State:          constants.TRANSACTION_STATE_PENDING,
+		Type:           constants.TRANSACTION_TYPE_INCOMING,
+		PaymentRequest: tests.MockInvoice,
+		PaymentHash:    tests.MockPaymentHash,
+		Preimage:       &mockPreimage,
+		AmountMsat:     123000,
+	}).Error)
+
+	mockLn := svc.LNClient.(*tests.MockLn)
+	mockLn.PayInvoiceResponses = []*lnclient.PayInvoiceResponse{nil}
+	mockLn.PayInvoiceErrors = []error{errors.New("FAILURE_REASON_NO_ROUTE")}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.SendCircularPayment(ctx, tests.MockInvoice, "chan-a", "chan-b", "peer-b", 100, nil, svc.LNClient)
+	assert.EqualError(t, err, "FAILURE_REASON_NO_ROUTE")
+
+	var outgoingTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&outgoingTransaction, &db.Transaction{Type: constants.TRANSACTION_TYPE_OUTGOING}).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_FAILED, outgoingTransaction.State)
+
+	var incomingTransaction db.Transaction
+	require.NoError(t, svc.DB.First(&incomingTransaction, &db.Transaction{Type: constants.TRANSACTION_TYPE_INCOMING}).Error)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, incomingTransaction.State)
+}
+
+func TestSendCircularPayment_NotOwnInvoice(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.SendCircularPayment(ctx, tests.MockInvoice, "chan-a", "chan-b", "peer-b", 100, nil, svc.LNClient)
+	assert.EqualError(t, err, "circular payments can only pay pending invoices of this hub")
+}
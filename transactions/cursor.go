@@ -0,0 +1,66 @@
+package transactions
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type invalidCursorError struct {
+}
+
+func NewInvalidCursorError() error {
+	return &invalidCursorError{}
+}
+
+func (err *invalidCursorError) Error() string {
+	return "invalid cursor"
+}
+
+// transactionsCursor points at a transaction in the list of transactions
+// ordered by updated_at and id. It is passed to clients as an opaque string.
+type transactionsCursor struct {
+	updatedAt time.Time
+	id        uint
+}
+
+func encodeTransactionsCursor(transaction *Transaction) string {
+	value := fmt.Sprintf("%d:%d", transaction.UpdatedAt.UnixNano(), transaction.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeTransactionsCursor(cursor string) (*transactionsCursor, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, NewInvalidCursorError()
+	}
+	updatedAtString, idString, found := strings.Cut(string(value), ":")
+	if !found {
+		return nil, NewInvalidCursorError()
+	}
+	updatedAtNanos, err := strconv.ParseInt(updatedAtString, 10, 64)
+	if err != nil {
+		return nil, NewInvalidCursorError()
+	}
+	id, err := strconv.ParseUint(idString, 10, 64)
+	if err != nil {
+		return nil, NewInvalidCursorError()
+	}
+	return &transactionsCursor{
+		updatedAt: time.Unix(0, updatedAtNanos),
+		id:        uint(id),
+	}, nil
+}
+
+// NextTransactionsCursor returns the cursor to pass to ListTransactions to
+// continue after the given page. If the page is empty the cursor the page
+// was requested with is returned, so clients syncing with ChangedSince can
+// keep polling from the same position.
+func NextTransactionsCursor(transactions []Transaction, cursor string) string {
+	if len(transactions) == 0 {
+		return cursor
+	}
+	return encodeTransactionsCursor(&transactions[len(transactions)-1])
+}
@@ -722,3 +722,116 @@ func TestListTransactions_FromUntilUnpaidIncoming(t *testing.T) {
 	assert.Equal(t, "second", incomingTransactions[0].Description)
 	assert.Equal(t, constants.TRANSACTION_TYPE_INCOMING, incomingTransactions[0].Type)
 }
+
+func TestListTransactions_Cursor(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	now := time.Now()
+	// the second and third transactions share updated_at and are ordered by id
+	for i, updatedAt := range []time.Time{now.Add(-2 * time.Minute), now.Add(-1 * time.Minute), now.Add(-1 * time.Minute)} {
+		err = svc.DB.Create(&db.Transaction{
+			State:       constants.TRANSACTION_STATE_SETTLED,
+			Type:        constants.TRANSACTION_TYPE_INCOMING,
+			PaymentHash: tests.MockLNClientTransaction.PaymentHash,
+			AmountMsat:  uint64(i+1) * 1000,
+			UpdatedAt:   updatedAt,
+		}).Error
+		require.NoError(t, err)
+	}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	firstPage, totalCount, err := transactionsService.ListTransactions(ctx, 0, 0, 2, 0, false, false, svc.LNClient, nil, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), totalCount)
+	require.Equal(t, 2, len(firstPage))
+	assert.Equal(t, uint64(3000), firstPage[0].AmountMsat)
+	assert.Equal(t, uint64(2000), firstPage[1].AmountMsat)
+
+	secondPage, _, err := transactionsService.ListTransactions(ctx, 0, 0, 2, 0, false, false, svc.LNClient, nil, false, &ListTransactionsFilters{
+		Cursor: NextTransactionsCursor(firstPage, ""),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(secondPage))
+	assert.Equal(t, uint64(1000), secondPage[0].AmountMsat)
+
+	secondPageCursor := NextTransactionsCursor(secondPage, "")
+	lastPage, _, err := transactionsService.ListTransactions(ctx, 0, 0, 2, 0, false, false, svc.LNClient, nil, false, &ListTransactionsFilters{
+		Cursor: secondPageCursor,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(lastPage))
+	// an empty page keeps the position of the cursor it was requested with
+	assert.Equal(t, secondPageCursor, NextTransactionsCursor(lastPage, secondPageCursor))
+
+	_, _, err = transactionsService.ListTransactions(ctx, 0, 0, 2, 0, false, false, svc.LNClient, nil, false, &ListTransactionsFilters{
+		Cursor: "not a cursor",
+	})
+	assert.ErrorIs(t, err, NewInvalidCursorError())
+}
+
+func TestListTransactions_ChangedSince(t *testing.T) {
+	ctx := context.TODO()
+
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	now := time.Now()
+	settledTransaction := &db.Transaction{
+		State:       constants.TRANSACTION_STATE_SETTLED,
+		Type:        constants.TRANSACTION_TYPE_INCOMING,
+		PaymentHash: tests.MockLNClientTransaction.PaymentHash,
+		AmountMsat:  1000,
+		UpdatedAt:   now.Add(-2 * time.Minute),
+	}
+	require.NoError(t, svc.DB.Create(settledTransaction).Error)
+	pendingTransaction := &db.Transaction{
+		State:       constants.TRANSACTION_STATE_PENDING,
+		Type:        constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentHash: tests.MockLNClientTransaction.PaymentHash,
+		AmountMsat:  2000,
+		UpdatedAt:   now.Add(-1 * time.Minute),
+	}
+	require.NoError(t, svc.DB.Create(pendingTransaction).Error)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+
+	// an initial sync lists all transactions, oldest first
+	changedTransactions, _, err := transactionsService.ListTransactions(ctx, 0, 0, 0, 0, false, false, svc.LNClient, nil, false, &ListTransactionsFilters{
+		ChangedSince: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(changedTransactions))
+	assert.Equal(t, settledTransaction.ID, changedTransactions[0].ID)
+	assert.Equal(t, pendingTransaction.ID, changedTransactions[1].ID)
+	cursor := NextTransactionsCursor(changedTransactions, "")
+
+	changedTransactions, _, err = transactionsService.ListTransactions(ctx, 0, 0, 0, 0, false, false, svc.LNClient, nil, false, &ListTransactionsFilters{
+		Cursor:       cursor,
+		ChangedSince: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(changedTransactions))
+	cursor = NextTransactionsCursor(changedTransactions, cursor)
+
+	// the pending payment fails
+	err = svc.DB.Model(pendingTransaction).Updates(map[string]interface{}{
+		"State":     constants.TRANSACTION_STATE_FAILED,
+		"UpdatedAt": now,
+	}).Error
+	require.NoError(t, err)
+
+	changedTransactions, _, err = transactionsService.ListTransactions(ctx, 0, 0, 0, 0, false, false, svc.LNClient, nil, false, &ListTransactionsFilters{
+		Cursor:       cursor,
+		ChangedSince: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(changedTransactions))
+	assert.Equal(t, pendingTransaction.ID, changedTransactions[0].ID)
+	assert.Equal(t, constants.TRANSACTION_STATE_FAILED, changedTransactions[0].State)
+}
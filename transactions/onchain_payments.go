@@ -0,0 +1,248 @@
+package transactions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+const (
+	// size of a typical on-chain payment with a few inputs, used to reserve fees
+	onchainFeeReserveVbytes = 250
+	// fee rate in sat/vB reserved when the app does not provide a fee rate
+	defaultOnchainFeeReserveRate = 50
+	// payments which are not confirmed within the default mempool expiry of
+	// bitcoin core are considered dropped
+	onchainPaymentExpiry = 14 * 24 * time.Hour
+)
+
+// CalculateOnchainFeeReserveMsat returns the fee reserved for an on-chain payment
+// until it confirms and the actual fee is known
+func CalculateOnchainFeeReserveMsat(feeRate *uint64) uint64 {
+	rate := uint64(defaultOnchainFeeReserveRate)
+	if feeRate != nil && *feeRate > 0 {
+		rate = *feeRate
+	}
+	return rate * onchainFeeReserveVbytes * 1000
+}
+
+// PayOnchain sends funds from the on-chain wallet to a bitcoin address.
+// The transaction stays pending, including its fee reserve, until the
+// payment is confirmed (see UpdatePendingOnchainPayments).
+func (svc *transactionsService) PayOnchain(ctx context.Context, address string, amountSat uint64, feeRate *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil, errors.New("a bitcoin address must be provided")
+	}
+	if amountSat == 0 {
+		return nil, errors.New("the amount must be at least 1 satoshi")
+	}
+
+	var metadataBytes []byte
+	if metadata != nil {
+		var err error
+		metadataBytes, err = json.Marshal(metadata)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to serialize metadata")
+			return nil, err
+		}
+		if len(metadataBytes) > constants.INVOICE_METADATA_MAX_LENGTH {
+			return nil, fmt.Errorf("encoded payment metadata provided is too large. Limit: %d Received: %d", constants.INVOICE_METADATA_MAX_LENGTH, len(metadataBytes))
+		}
+	}
+
+	amountMsat := amountSat * 1000
+	feeReserveMsat := CalculateOnchainFeeReserveMsat(feeRate)
+
+	var dbTransaction db.Transaction
+
+	err := func() error {
+		balanceValidationLock.Lock()
+		defer balanceValidationLock.Unlock()
+		return svc.db.Transaction(func(tx *gorm.DB) error {
+			err := svc.validateCanPay(tx, appId, &outgoingPayment{
+				amountMsat:     amountMsat,
+				destination:    address,
+				onchain:        true,
+				feeReserveMsat: feeReserveMsat,
+			})
+			if err != nil {
+				return err
+			}
+
+			approvalRequired, err := isPaymentApprovalRequired(tx, appId, requestEventId, "", amountMsat)
+			if err != nil {
+				return err
+			}
+			if approvalRequired {
+				return NewSpendingPolicyError("payments requiring approval must be made with an invoice")
+			}
+
+			dbTransaction = db.Transaction{
+				AppId:          appId,
+				RequestEventId: requestEventId,
+				Type:           constants.TRANSACTION_TYPE_OUTGOING,
+				State:          constants.TRANSACTION_STATE_PENDING,
+				FeeReserveMsat: feeReserveMsat,
+				AmountMsat:     amountMsat,
+				OnchainAddress: address,
+				Metadata:       datatypes.JSON(metadataBytes),
+			}
+			return tx.Create(&dbTransaction).Error
+		})
+	}()
+
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"address":    address,
+			"amount_sat": amountSat,
+		}).WithError(err).Error("Failed to create DB transaction")
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":           appId,
+		"request_event_id": requestEventId,
+		"amount_sat":       amountSat,
+		"fee_rate":         feeRate,
+		"address":          address,
+	}).Info("Initiating on-chain payment")
+
+	txId, err := lnClient.RedeemOnchainFunds(ctx, address, amountSat, feeRate, false)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"address":    address,
+			"amount_sat": amountSat,
+		}).WithError(err).Error("Failed to make on-chain payment")
+
+		if _, markFailedErr := svc.markPaymentFailed(&dbTransaction, err.Error()); markFailedErr != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"address":    address,
+				"amount_sat": amountSat,
+			}).WithError(markFailedErr).Error("Failed to mark payment as failed")
+		}
+
+		return nil, err
+	}
+
+	// the transaction has been broadcast, so the payment must not be reported as failed
+	err = svc.db.Model(&dbTransaction).Update("onchain_tx_id", txId).Error
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"address": address,
+			"tx_id":   txId,
+		}).WithError(err).Error("Failed to update tx id of on-chain payment")
+	}
+	dbTransaction.OnchainTxId = txId
+
+	return &dbTransaction, nil
+}
+
+// UpdatePendingOnchainPayments settles pending on-chain payments once their
+// transaction has been confirmed, replacing the fee reserve with the actual fee.
+// Payments which are still unconfirmed or missing from the wallet after
+// onchainPaymentExpiry are marked as failed, releasing their budget.
+func (svc *transactionsService) UpdatePendingOnchainPayments(ctx context.Context, lnClient lnclient.LNClient) {
+	pendingTransactions := []db.Transaction{}
+	err := svc.db.
+		Where("state = ? AND onchain_tx_id != ''", constants.TRANSACTION_STATE_PENDING).
+		Find(&pendingTransactions).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list pending on-chain payments")
+		return
+	}
+	if len(pendingTransactions) == 0 {
+		return
+	}
+
+	onchainTransactions, err := lnClient.ListOnchainTransactions(ctx)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to list on-chain transactions")
+		return
+	}
+
+	for i := range pendingTransactions {
+		confirmed := false
+		for _, onchainTransaction := range onchainTransactions {
+			if onchainTransaction.TxId != pendingTransactions[i].OnchainTxId {
+				continue
+			}
+			if onchainTransaction.Type == "outgoing" && onchainTransaction.State == "confirmed" {
+				confirmed = true
+				err := svc.markOnchainPaymentSettled(&pendingTransactions[i], &onchainTransaction)
+				if err != nil {
+					logger.Logger.WithField("tx_id", onchainTransaction.TxId).WithError(err).Error("Failed to mark on-chain payment as settled")
+				}
+			}
+			break
+		}
+
+		if !confirmed && time.Since(pendingTransactions[i].CreatedAt) > onchainPaymentExpiry {
+			logger.Logger.WithFields(logrus.Fields{
+				"tx_id":      pendingTransactions[i].OnchainTxId,
+				"created_at": pendingTransactions[i].CreatedAt,
+			}).Warn("On-chain payment was not confirmed in time")
+			_, err := svc.markPaymentFailed(&pendingTransactions[i], "the on-chain transaction was not confirmed and has been dropped")
+			if err != nil {
+				logger.Logger.WithField("tx_id", pendingTransactions[i].OnchainTxId).WithError(err).Error("Failed to mark on-chain payment as failed")
+			}
+		}
+	}
+}
+
+func (svc *transactionsService) markOnchainPaymentSettled(dbTransaction *db.Transaction, onchainTransaction *lnclient.OnchainTransaction) error {
+	feeMsat := onchainTransaction.FeeSat * 1000
+
+	settledAt := time.Now()
+	var eventsToPublish []*events.Event
+	err := svc.db.Transaction(func(tx *gorm.DB) error {
+		// the state check is part of the update so a payment is only settled once
+		result := tx.Model(&db.Transaction{}).
+			Where("id = ? AND state = ?", dbTransaction.ID, constants.TRANSACTION_STATE_PENDING).
+			Updates(map[string]interface{}{
+				"State":          constants.TRANSACTION_STATE_SETTLED,
+				"FeeMsat":        feeMsat,
+				"FeeReserveMsat": 0,
+				"SettledAt":      &settledAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		dbTransaction.State = constants.TRANSACTION_STATE_SETTLED
+		dbTransaction.FeeMsat = feeMsat
+		dbTransaction.FeeReserveMsat = 0
+		dbTransaction.SettledAt = &settledAt
+
+		eventsToPublish = svc.afterTransactionSettled(tx, dbTransaction, &settledAt)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(eventsToPublish) > 0 {
+		logger.Logger.WithFields(logrus.Fields{
+			"tx_id":    dbTransaction.OnchainTxId,
+			"fee_msat": feeMsat,
+		}).Info("Marked on-chain payment as settled")
+	}
+	svc.publishEvents(eventsToPublish)
+	return nil
+}
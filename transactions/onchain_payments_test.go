@@ -0,0 +1,256 @@
+package transactions
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/db/queries"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+func createOnchainTestApp(t *testing.T, svc *tests.TestService, maxAmountSat int) (*db.App, *db.AppPermission, *db.RequestEvent) {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId:         app.ID,
+		App:           *app,
+		Scope:         constants.PAY_ONCHAIN_SCOPE,
+		MaxAmountSat:  maxAmountSat,
+		BudgetRenewal: constants.BUDGET_RENEWAL_NEVER,
+	}
+	err = svc.DB.Create(appPermission).Error
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	return app, appPermission, dbRequestEvent
+}
+
+func TestPayOnchain_App(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, appPermission, dbRequestEvent := createOnchainTestApp(t, svc, 100_000)
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	feeRate := uint64(2)
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOnchain(ctx, tests.MockOnchainAddress, 10_000, &feeRate, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, transaction.State)
+	assert.Equal(t, constants.TRANSACTION_TYPE_OUTGOING, transaction.Type)
+	assert.Equal(t, uint64(10_000_000), transaction.AmountMsat)
+	assert.Equal(t, uint64(500_000), transaction.FeeReserveMsat)
+	assert.Equal(t, tests.MockOnchainAddress, transaction.OnchainAddress)
+	assert.Equal(t, tests.MockOnchainTxId, transaction.OnchainTxId)
+	assert.Equal(t, app.ID, *transaction.AppId)
+
+	// the fee reserve counts towards the budget until the payment is confirmed
+	budgetUsageMsat, err := queries.GetBudgetUsageMsat(svc.DB, appPermission)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10_500_000), budgetUsageMsat)
+
+	// unconfirmed payments stay pending
+	svc.LNClient.(*tests.MockLn).OnchainTransactions = []lnclient.OnchainTransaction{
+		{
+			TxId:      tests.MockOnchainTxId,
+			Type:      "outgoing",
+			State:     "unconfirmed",
+			AmountSat: 10_000,
+			FeeSat:    200,
+		},
+	}
+	transactionsService.UpdatePendingOnchainPayments(ctx, svc.LNClient)
+	err = svc.DB.First(transaction, transaction.ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, transaction.State)
+
+	svc.LNClient.(*tests.MockLn).OnchainTransactions[0].State = "confirmed"
+	transactionsService.UpdatePendingOnchainPayments(ctx, svc.LNClient)
+	err = svc.DB.First(transaction, transaction.ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, uint64(200_000), transaction.FeeMsat)
+	assert.Equal(t, uint64(0), transaction.FeeReserveMsat)
+	assert.NotNil(t, transaction.SettledAt)
+	assert.Nil(t, transaction.Preimage)
+
+	budgetUsageMsat, err = queries.GetBudgetUsageMsat(svc.DB, appPermission)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10_200_000), budgetUsageMsat)
+
+	consumedEvents := mockEventConsumer.WaitForConsumedEvents(1)
+	assert.Equal(t, 1, len(consumedEvents))
+	assert.Equal(t, "nwc_payment_sent", consumedEvents[0].Event)
+
+	// settled payments are not updated again
+	transactionsService.UpdatePendingOnchainPayments(ctx, svc.LNClient)
+	assert.Equal(t, 1, len(mockEventConsumer.GetConsumedEvents()))
+}
+
+func TestPayOnchain_App_MissingScope(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+	err = svc.DB.Create(&db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}).Error
+	require.NoError(t, err)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOnchain(context.TODO(), tests.MockOnchainAddress, 10_000, nil, nil, svc.LNClient, &app.ID, nil)
+	assert.EqualError(t, err, "app does not have pay_onchain scope")
+	assert.Nil(t, transaction)
+}
+
+func TestPayOnchain_App_BudgetExceeded(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, dbRequestEvent := createOnchainTestApp(t, svc, 10_000)
+
+	// the amount fits in the budget, but the fee reserve does not
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOnchain(context.TODO(), tests.MockOnchainAddress, 10_000, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewQuotaExceededError())
+	assert.Nil(t, transaction)
+
+	var transactionCount int64
+	svc.DB.Model(&db.Transaction{}).Count(&transactionCount)
+	assert.Equal(t, int64(0), transactionCount)
+}
+
+func TestPayOnchain_IsolatedApp(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, dbRequestEvent := createOnchainTestApp(t, svc, 0)
+	app.Isolated = true
+	svc.DB.Save(&app)
+
+	err = svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_INCOMING,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat: 20_000_000,
+	}).Error
+	require.NoError(t, err)
+
+	feeRate := uint64(10)
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOnchain(context.TODO(), tests.MockOnchainAddress, 18_000, &feeRate, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewInsufficientBalanceError())
+	assert.Nil(t, transaction)
+
+	transaction, err = transactionsService.PayOnchain(context.TODO(), tests.MockOnchainAddress, 17_000, &feeRate, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, transaction.State)
+
+	balanceMsat, err := queries.GetIsolatedBalanceMsat(svc.DB, app.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500_000), balanceMsat)
+}
+
+func TestPayOnchain_App_Dropped(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, appPermission, dbRequestEvent := createOnchainTestApp(t, svc, 100_000)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOnchain(ctx, tests.MockOnchainAddress, 10_000, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	require.NoError(t, err)
+
+	// the transaction is no longer known by the wallet
+	svc.LNClient.(*tests.MockLn).OnchainTransactions = []lnclient.OnchainTransaction{}
+
+	// recent payments may not be listed by the wallet yet
+	transactionsService.UpdatePendingOnchainPayments(ctx, svc.LNClient)
+	err = svc.DB.First(transaction, transaction.ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, transaction.State)
+
+	err = svc.DB.Model(transaction).Update("created_at", time.Now().Add(-onchainPaymentExpiry-time.Hour)).Error
+	require.NoError(t, err)
+
+	transactionsService.UpdatePendingOnchainPayments(ctx, svc.LNClient)
+	err = svc.DB.First(transaction, transaction.ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_FAILED, transaction.State)
+	assert.Equal(t, uint64(0), transaction.FeeReserveMsat)
+
+	// the reserved budget is released
+	budgetUsageMsat, err := queries.GetBudgetUsageMsat(svc.DB, appPermission)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), budgetUsageMsat)
+}
+
+func TestPayOnchain_App_SpendingPolicy_AllowedDestinations(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, dbRequestEvent := createOnchainTestApp(t, svc, 100_000)
+	err = svc.DB.Create(&db.AppSpendingPolicy{
+		AppId:               app.ID,
+		App:                 *app,
+		AllowedDestinations: datatypes.JSON(`["` + strings.ToUpper(tests.MockOnchainAddress) + `"]`),
+	}).Error
+	require.NoError(t, err)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOnchain(context.TODO(), "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", 10_000, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Equal(t, NewSpendingPolicyError("destination is not in the list of allowed destinations").Error(), err.Error())
+	assert.Nil(t, transaction)
+
+	transaction, err = transactionsService.PayOnchain(context.TODO(), tests.MockOnchainAddress, 10_000, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, transaction.State)
+}
+
+func TestPayOnchain_App_SpendingPolicy_BlockedDestination(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, _, dbRequestEvent := createOnchainTestApp(t, svc, 100_000)
+	err = svc.DB.Create(&db.AppSpendingPolicy{
+		AppId:               app.ID,
+		App:                 *app,
+		BlockedDestinations: datatypes.JSON(`["` + tests.MockOnchainAddress + `"]`),
+	}).Error
+	require.NoError(t, err)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOnchain(context.TODO(), tests.MockOnchainAddress, 10_000, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Equal(t, NewSpendingPolicyError("destination is blocked").Error(), err.Error())
+	assert.Nil(t, transaction)
+}
@@ -0,0 +1,198 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/tests"
+)
+
+// node id of the blinded path in tests.MockOffer
+const mockOfferNodeId = "02c1d2e9369f72b8b62c6bf59e0a9e8587b9cb403fcfbbcd22ee33c5a031d62e6d"
+
+func TestPayOffer_NoApp(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOffer(context.TODO(), tests.MockOffer, 123000, "thanks", nil, svc.LNClient, nil, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, tests.MockOfferPaymentHash, transaction.PaymentHash)
+	assert.Equal(t, uint64(1), transaction.FeeMsat)
+	assert.Zero(t, transaction.FeeReserveMsat)
+}
+
+func TestPayOffer_App_SpendingPolicy_AllowedDestinations(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		AllowedDestinations: datatypes.JSON(`["` + mockOfferNodeId + `"]`),
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOffer(context.TODO(), tests.MockOffer, 123000, "", nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestPayOffer_App_SpendingPolicy_BlockedDestination(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		BlockedDestinations: datatypes.JSON(`["` + mockOfferNodeId + `"]`),
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOffer(context.TODO(), tests.MockOffer, 123000, "", nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Equal(t, NewSpendingPolicyError("destination is blocked").Error(), err.Error())
+	assert.Nil(t, transaction)
+}
+
+func TestPayOffer_PaymentPending(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	svc.LNClient.(*tests.MockLn).PayOfferErrors = []error{lnclient.NewPaymentPendingError("", "payment-id")}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.PayOffer(context.TODO(), tests.MockOffer, 123000, "", nil, svc.LNClient, nil, nil)
+	var paymentPendingError *lnclient.PaymentPendingError
+	assert.ErrorAs(t, err, &paymentPendingError)
+	assert.Nil(t, transaction)
+
+	// the payment stays pending until the node reports the result
+	var dbTransaction db.Transaction
+	result := svc.DB.Find(&dbTransaction, &db.Transaction{
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentRequest: tests.MockOffer,
+	})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, dbTransaction.State)
+	assert.Equal(t, "", dbTransaction.PaymentHash)
+
+	transactionsService.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_lnclient_payment_sent",
+		Properties: &lnclient.Transaction{
+			Type:         "outgoing",
+			AmountMsat:   123000,
+			FeesPaidMsat: 2,
+			Preimage:     "123preimage",
+			PaymentHash:  tests.MockOfferPaymentHash,
+			Metadata: map[string]interface{}{
+				"offer": map[string]interface{}{"id": "offer-id", "payment_id": "payment-id"},
+			},
+		},
+	}, map[string]interface{}{})
+
+	err = svc.DB.First(&dbTransaction, dbTransaction.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, dbTransaction.State)
+	assert.Equal(t, tests.MockOfferPaymentHash, dbTransaction.PaymentHash)
+	assert.Equal(t, uint64(2), dbTransaction.FeeMsat)
+
+	var transactionCount int64
+	err = svc.DB.Model(&db.Transaction{}).Where("type = ?", constants.TRANSACTION_TYPE_OUTGOING).Count(&transactionCount).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), transactionCount)
+}
+
+func TestPayOffer_PaymentPending_Failed(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	svc.LNClient.(*tests.MockLn).PayOfferErrors = []error{lnclient.NewPaymentPendingError("", "payment-id")}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.PayOffer(context.TODO(), tests.MockOffer, 123000, "", nil, svc.LNClient, nil, nil)
+	var paymentPendingError *lnclient.PaymentPendingError
+	assert.ErrorAs(t, err, &paymentPendingError)
+
+	// the invoice could not be fetched from the offer, so there is no payment hash
+	transactionsService.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_lnclient_payment_failed",
+		Properties: &lnclient.PaymentFailedEventProperties{
+			Transaction: &lnclient.Transaction{
+				Type:       "outgoing",
+				AmountMsat: 123000,
+				Metadata: map[string]interface{}{
+					"offer": map[string]interface{}{"id": "offer-id", "payment_id": "payment-id"},
+				},
+			},
+			Reason: "RouteNotFound",
+		},
+	}, map[string]interface{}{})
+
+	var dbTransaction db.Transaction
+	err = svc.DB.First(&dbTransaction, &db.Transaction{
+		Type:           constants.TRANSACTION_TYPE_OUTGOING,
+		PaymentRequest: tests.MockOffer,
+	}).Error
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_FAILED, dbTransaction.State)
+	assert.Equal(t, "RouteNotFound", dbTransaction.FailureReason)
+}
+
+func TestPayOffer_PaymentPending_SameAmount(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	svc.LNClient.(*tests.MockLn).PayOfferErrors = []error{
+		lnclient.NewPaymentPendingError("", "payment-id-1"),
+		lnclient.NewPaymentPendingError("", "payment-id-2"),
+	}
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.PayOffer(context.TODO(), tests.MockOffer, 123000, "first", nil, svc.LNClient, nil, nil)
+	var paymentPendingError *lnclient.PaymentPendingError
+	assert.ErrorAs(t, err, &paymentPendingError)
+	_, err = transactionsService.PayOffer(context.TODO(), tests.MockOffer, 123000, "second", nil, svc.LNClient, nil, nil)
+	assert.ErrorAs(t, err, &paymentPendingError)
+
+	// the second payment completes first
+	transactionsService.ConsumeEvent(context.TODO(), &events.Event{
+		Event: "nwc_lnclient_payment_sent",
+		Properties: &lnclient.Transaction{
+			Type:         "outgoing",
+			AmountMsat:   123000,
+			FeesPaidMsat: 2,
+			Preimage:     "123preimage",
+			PaymentHash:  tests.MockOfferPaymentHash,
+			Metadata: map[string]interface{}{
+				"offer": map[string]interface{}{"id": "offer-id", "payment_id": "payment-id-2"},
+			},
+		},
+	}, map[string]interface{}{})
+
+	var firstTransaction, secondTransaction db.Transaction
+	err = svc.DB.First(&firstTransaction, &db.Transaction{Description: "first"}).Error
+	require.NoError(t, err)
+	err = svc.DB.First(&secondTransaction, &db.Transaction{Description: "second"}).Error
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.TRANSACTION_STATE_PENDING, firstTransaction.State)
+	assert.Equal(t, "", firstTransaction.PaymentHash)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, secondTransaction.State)
+	assert.Equal(t, tests.MockOfferPaymentHash, secondTransaction.PaymentHash)
+}
@@ -0,0 +1,271 @@
+package transactions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/events"
+	"github.com/getAlby/hub/lnclient"
+	"github.com/getAlby/hub/logger"
+)
+
+// paymentApprovalMaxAge is how long a payment waits for the owner at most.
+// Approvals of invoices which expire earlier expire with the invoice.
+const paymentApprovalMaxAge = 24 * time.Hour
+
+type paymentApprovalRequiredError struct {
+}
+
+func NewPaymentApprovalRequiredError() error {
+	return &paymentApprovalRequiredError{}
+}
+
+func (err *paymentApprovalRequiredError) Error() string {
+	return "This payment is waiting to be approved in the connections page of your Alby Hub."
+}
+
+// isPaymentApprovalRequired checks if an NWC payment must be approved by the
+// owner before it can be made. Payments without a request event (e.g. made
+// by the owner from the UI) and payments that were already approved do not
+// require approval. Approvals are per invoice, as a multi_pay_invoice request
+// event contains multiple invoices.
+func isPaymentApprovalRequired(tx *gorm.DB, appId *uint, requestEventId *uint, paymentHash string, amountMsat uint64) (bool, error) {
+	if appId == nil || requestEventId == nil {
+		return false, nil
+	}
+
+	var spendingPolicy db.AppSpendingPolicy
+	result := tx.Limit(1).Find(&spendingPolicy, &db.AppSpendingPolicy{
+		AppId: *appId,
+	})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to fetch spending policy for app: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if !spendingPolicy.ApprovalRequired &&
+		(spendingPolicy.ApprovalThresholdSat == 0 || amountMsat <= spendingPolicy.ApprovalThresholdSat*1000) {
+		return false, nil
+	}
+
+	var approvedCount int64
+	err := tx.Model(&db.PaymentApproval{}).
+		Where("request_event_id = ? AND payment_hash = ? AND state = ?", *requestEventId, paymentHash, constants.PAYMENT_APPROVAL_STATE_APPROVED).
+		Count(&approvedCount).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch payment approval: %w", err)
+	}
+
+	return approvedCount == 0, nil
+}
+
+// requestPaymentApproval parks an invoice payment until the owner approves
+// or rejects it, or it expires. Requests which are re-delivered by the relay
+// reuse the existing approval of the same invoice.
+func (svc *transactionsService) requestPaymentApproval(tx *gorm.DB, appId uint, requestEventId uint, payReq string, paymentHash string, amountMsat uint64, description string, invoiceExpiry int, metadataBytes []byte) (*db.PaymentApproval, bool, error) {
+	var paymentApproval db.PaymentApproval
+	result := tx.Limit(1).Find(&paymentApproval, &db.PaymentApproval{
+		RequestEventId: &requestEventId,
+		PaymentHash:    paymentHash,
+	})
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		switch paymentApproval.State {
+		case constants.PAYMENT_APPROVAL_STATE_REJECTED:
+			return nil, false, NewSpendingPolicyError("the payment was rejected")
+		case constants.PAYMENT_APPROVAL_STATE_EXPIRED:
+			return nil, false, NewSpendingPolicyError("the payment approval expired")
+		}
+		return &paymentApproval, false, nil
+	}
+
+	expiresAt := time.Now().Add(paymentApprovalMaxAge)
+	if invoiceExpiry > 0 && time.Duration(invoiceExpiry)*time.Second < paymentApprovalMaxAge {
+		expiresAt = time.Now().Add(time.Duration(invoiceExpiry) * time.Second)
+	}
+
+	paymentApproval = db.PaymentApproval{
+		AppId:          appId,
+		RequestEventId: &requestEventId,
+		State:          constants.PAYMENT_APPROVAL_STATE_PENDING,
+		PaymentRequest: payReq,
+		PaymentHash:    paymentHash,
+		AmountMsat:     amountMsat,
+		Description:    description,
+		Metadata:       metadataBytes,
+		ExpiresAt:      expiresAt,
+	}
+	err := tx.Omit("App", "RequestEvent").Create(&paymentApproval).Error
+	if err != nil {
+		return nil, false, err
+	}
+	return &paymentApproval, true, nil
+}
+
+func (svc *transactionsService) publishPaymentApprovalRequired(paymentApproval *db.PaymentApproval) {
+	var app db.App
+	svc.db.Limit(1).Find(&app, &db.App{
+		ID: paymentApproval.AppId,
+	})
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":              paymentApproval.AppId,
+		"payment_approval_id": paymentApproval.ID,
+		"amount_msat":         paymentApproval.AmountMsat,
+	}).Info("Payment requires approval")
+
+	svc.eventPublisher.Publish(&events.Event{
+		Event: "nwc_payment_approval_required",
+		Properties: map[string]interface{}{
+			"id":          paymentApproval.ID,
+			"app_id":      paymentApproval.AppId,
+			"app_name":    app.Name,
+			"amount":      paymentApproval.AmountMsat / 1000,
+			"description": paymentApproval.Description,
+		},
+	})
+}
+
+func (svc *transactionsService) ListPaymentApprovals(ctx context.Context, state string) ([]db.PaymentApproval, error) {
+	paymentApprovals := []db.PaymentApproval{}
+	query := svc.db.Order("created_at DESC")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	err := query.Find(&paymentApprovals).Error
+	if err != nil {
+		return nil, err
+	}
+	return paymentApprovals, nil
+}
+
+// ApprovePayment marks a pending payment as approved and makes the payment
+// with the original request event linked
+func (svc *transactionsService) ApprovePayment(ctx context.Context, id uint, lnClient lnclient.LNClient) (*Transaction, error) {
+	paymentApproval, err := svc.resolvePaymentApproval(id, constants.PAYMENT_APPROVAL_STATE_APPROVED)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]interface{}
+	if paymentApproval.Metadata != nil {
+		err := json.Unmarshal(paymentApproval.Metadata, &metadata)
+		if err != nil {
+			logger.Logger.WithError(err).WithField("payment_approval_id", id).Error("Failed to deserialize payment approval metadata")
+			return nil, err
+		}
+	}
+
+	// the amount only needs to be passed for zero-amount invoices
+	var amountMsat *uint64
+	paymentRequest, err := decodepay.Decodepay(paymentApproval.PaymentRequest)
+	if err == nil && paymentRequest.MSatoshi == 0 {
+		amountMsat = &paymentApproval.AmountMsat
+	}
+
+	transaction, err := svc.SendPaymentSync(paymentApproval.PaymentRequest, amountMsat, metadata, lnClient, &paymentApproval.AppId, paymentApproval.RequestEventId)
+	if transaction != nil {
+		updateErr := svc.db.Model(paymentApproval).Update("transaction_id", transaction.ID).Error
+		if updateErr != nil {
+			logger.Logger.WithError(updateErr).WithField("payment_approval_id", id).Error("Failed to link transaction to payment approval")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (svc *transactionsService) RejectPayment(ctx context.Context, id uint) error {
+	paymentApproval, err := svc.resolvePaymentApproval(id, constants.PAYMENT_APPROVAL_STATE_REJECTED)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":              paymentApproval.AppId,
+		"payment_approval_id": paymentApproval.ID,
+	}).Info("Payment was rejected")
+
+	svc.publishPaymentApprovalRejected(paymentApproval)
+	return nil
+}
+
+// ExpirePaymentApprovals rejects pending approvals which were not resolved in time
+func (svc *transactionsService) ExpirePaymentApprovals(ctx context.Context) {
+	var expiredIds []uint
+	err := svc.db.Model(&db.PaymentApproval{}).
+		Where("state = ? AND expires_at <= ?", constants.PAYMENT_APPROVAL_STATE_PENDING, time.Now()).
+		Pluck("id", &expiredIds).Error
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to fetch expired payment approvals")
+		return
+	}
+
+	for _, id := range expiredIds {
+		paymentApproval, err := svc.resolvePaymentApproval(id, constants.PAYMENT_APPROVAL_STATE_EXPIRED)
+		if err != nil {
+			// the approval was resolved in the meantime
+			logger.Logger.WithError(err).WithField("payment_approval_id", id).Debug("Failed to expire payment approval")
+			continue
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"app_id":              paymentApproval.AppId,
+			"payment_approval_id": paymentApproval.ID,
+		}).Info("Payment approval expired")
+
+		svc.publishPaymentApprovalRejected(paymentApproval)
+	}
+}
+
+// publishPaymentApprovalRejected lets the NIP-47 notifier answer the request
+// which was held for the approval
+func (svc *transactionsService) publishPaymentApprovalRejected(paymentApproval *db.PaymentApproval) {
+	svc.eventPublisher.Publish(&events.Event{
+		Event:      "nwc_payment_approval_rejected",
+		Properties: paymentApproval,
+	})
+}
+
+// resolvePaymentApproval moves a pending approval to its final state.
+// The state check is part of the update so an approval cannot be resolved twice,
+// and expired approvals can no longer be approved.
+func (svc *transactionsService) resolvePaymentApproval(id uint, state string) (*db.PaymentApproval, error) {
+	query := svc.db.Model(&db.PaymentApproval{}).
+		Where("id = ? AND state = ?", id, constants.PAYMENT_APPROVAL_STATE_PENDING)
+	if state == constants.PAYMENT_APPROVAL_STATE_APPROVED {
+		query = query.Where("expires_at > ?", time.Now())
+	}
+	result := query.Updates(map[string]interface{}{
+		"state":       state,
+		"resolved_at": time.Now(),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("no pending payment approval found")
+	}
+
+	var paymentApproval db.PaymentApproval
+	err := svc.db.First(&paymentApproval, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paymentApproval, nil
+}
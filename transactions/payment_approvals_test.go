@@ -0,0 +1,196 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestSendPaymentSync_App_ApprovalRequired(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		ApprovalRequired: true,
+	})
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, map[string]interface{}{"a": 123}, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+
+	assert.ErrorIs(t, err, NewPaymentApprovalRequiredError())
+	assert.Nil(t, transaction)
+
+	var transactionCount int64
+	svc.DB.Model(&db.Transaction{}).Count(&transactionCount)
+	assert.Equal(t, int64(0), transactionCount)
+
+	consumedEvents := mockEventConsumer.WaitForConsumedEvents(1)
+	assert.Equal(t, 1, len(consumedEvents))
+	assert.Equal(t, "nwc_payment_approval_required", consumedEvents[0].Event)
+	assert.Equal(t, app.Name, consumedEvents[0].Properties.(map[string]interface{})["app_name"])
+
+	paymentApprovals, err := transactionsService.ListPaymentApprovals(ctx, constants.PAYMENT_APPROVAL_STATE_PENDING)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(paymentApprovals))
+	assert.Equal(t, app.ID, paymentApprovals[0].AppId)
+	assert.Equal(t, dbRequestEvent.ID, *paymentApprovals[0].RequestEventId)
+	assert.Equal(t, uint64(123000), paymentApprovals[0].AmountMsat)
+	assert.Equal(t, tests.MockLNClientTransaction.Invoice, paymentApprovals[0].PaymentRequest)
+	assert.Equal(t, tests.MockPaymentHash, paymentApprovals[0].PaymentHash)
+
+	// a re-delivered request reuses the existing approval
+	_, err = transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewPaymentApprovalRequiredError())
+	paymentApprovals, err = transactionsService.ListPaymentApprovals(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(paymentApprovals))
+
+	transaction, err = transactionsService.ApprovePayment(ctx, paymentApprovals[0].ID, svc.LNClient)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+	assert.Equal(t, app.ID, *transaction.AppId)
+	assert.Equal(t, dbRequestEvent.ID, *transaction.RequestEventId)
+	assert.JSONEq(t, `{"a": 123}`, string(transaction.Metadata))
+
+	var paymentApproval db.PaymentApproval
+	err = svc.DB.First(&paymentApproval, paymentApprovals[0].ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_APPROVED, paymentApproval.State)
+	assert.Equal(t, transaction.ID, *paymentApproval.TransactionId)
+	assert.NotNil(t, paymentApproval.ResolvedAt)
+
+	// an approval can only be resolved once
+	_, err = transactionsService.ApprovePayment(ctx, paymentApprovals[0].ID, svc.LNClient)
+	assert.Error(t, err)
+}
+
+func TestSendPaymentSync_App_ApprovalRejected(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		ApprovalThresholdSat: 100,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewPaymentApprovalRequiredError())
+
+	paymentApprovals, err := transactionsService.ListPaymentApprovals(ctx, constants.PAYMENT_APPROVAL_STATE_PENDING)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(paymentApprovals))
+
+	err = transactionsService.RejectPayment(ctx, paymentApprovals[0].ID)
+	require.NoError(t, err)
+
+	_, err = transactionsService.ApprovePayment(ctx, paymentApprovals[0].ID, svc.LNClient)
+	assert.Error(t, err)
+
+	_, err = transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+
+	var transactionCount int64
+	svc.DB.Model(&db.Transaction{}).Count(&transactionCount)
+	assert.Equal(t, int64(0), transactionCount)
+}
+
+func TestExpirePaymentApprovals(t *testing.T) {
+	ctx := context.TODO()
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		ApprovalRequired: true,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	_, err = transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewPaymentApprovalRequiredError())
+
+	paymentApprovals, err := transactionsService.ListPaymentApprovals(ctx, constants.PAYMENT_APPROVAL_STATE_PENDING)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(paymentApprovals))
+	assert.True(t, paymentApprovals[0].ExpiresAt.After(time.Now()))
+	assert.False(t, paymentApprovals[0].ExpiresAt.After(time.Now().Add(paymentApprovalMaxAge)))
+
+	// approvals which did not expire yet are kept
+	transactionsService.ExpirePaymentApprovals(ctx)
+	paymentApprovals, err = transactionsService.ListPaymentApprovals(ctx, constants.PAYMENT_APPROVAL_STATE_PENDING)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(paymentApprovals))
+
+	err = svc.DB.Model(&paymentApprovals[0]).Update("expires_at", time.Now().Add(-time.Minute)).Error
+	require.NoError(t, err)
+
+	// an expired approval can no longer be approved, even before it was swept
+	_, err = transactionsService.ApprovePayment(ctx, paymentApprovals[0].ID, svc.LNClient)
+	assert.Error(t, err)
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService.ExpirePaymentApprovals(ctx)
+
+	var paymentApproval db.PaymentApproval
+	err = svc.DB.First(&paymentApproval, paymentApprovals[0].ID).Error
+	require.NoError(t, err)
+	assert.Equal(t, constants.PAYMENT_APPROVAL_STATE_EXPIRED, paymentApproval.State)
+	assert.NotNil(t, paymentApproval.ResolvedAt)
+
+	consumedEvents := mockEventConsumer.WaitForConsumedEvents(1)
+	require.Equal(t, 1, len(consumedEvents))
+	assert.Equal(t, "nwc_payment_approval_rejected", consumedEvents[0].Event)
+	assert.Equal(t, paymentApproval.ID, consumedEvents[0].Properties.(*db.PaymentApproval).ID)
+
+	_, err = transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+
+	var transactionCount int64
+	svc.DB.Model(&db.Transaction{}).Count(&transactionCount)
+	assert.Equal(t, int64(0), transactionCount)
+}
+
+func TestSendPaymentSync_App_BelowApprovalThreshold(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		ApprovalThresholdSat: 123,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendKeysend_App_ApprovalRequired(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		ApprovalRequired: true,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendKeysend(uint64(1000), spendingPolicyDestination, nil, "", svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Nil(t, transaction)
+}
@@ -0,0 +1,164 @@
+package transactions
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+// outgoingPayment describes the payment being validated in validateCanPay
+type outgoingPayment struct {
+	amountMsat      uint64
+	destination     string // node pubkey of the recipient if known, or the address of on-chain payments
+	description     string
+	descriptionHash string
+	isInvoice       bool
+	selfPayment     bool
+	onchain         bool
+	feeReserveMsat  uint64 // only used for on-chain payments
+}
+
+type spendingPolicyError struct {
+	reason string
+}
+
+func NewSpendingPolicyError(reason string) error {
+	return &spendingPolicyError{reason: reason}
+}
+
+func (err *spendingPolicyError) Error() string {
+	return fmt.Sprintf("This payment is not allowed by the spending policy of your app: %s. Please review this app in the connections page of your Alby Hub.", err.reason)
+}
+
+// Is matches any spending policy error regardless of the reason
+func (err *spendingPolicyError) Is(target error) bool {
+	_, ok := target.(*spendingPolicyError)
+	return ok
+}
+
+// ParseSpendingPolicyDestinations decodes a JSON array of node pubkeys and
+// on-chain addresses as stored on db.AppSpendingPolicy
+func ParseSpendingPolicyDestinations(destinations []byte) ([]string, error) {
+	if len(destinations) == 0 {
+		return []string{}, nil
+	}
+	var parsedDestinations []string
+	err := json.Unmarshal(destinations, &parsedDestinations)
+	if err != nil {
+		return nil, err
+	}
+	for i := range parsedDestinations {
+		parsedDestinations[i] = normalizeDestination(parsedDestinations[i])
+	}
+	return parsedDestinations, nil
+}
+
+// ValidateSpendingPolicyDestination checks that the destination is a node pubkey
+// or an on-chain address and returns it in the form it is stored in
+func ValidateSpendingPolicyDestination(destination string) (string, error) {
+	destination = normalizeDestination(destination)
+	pubkeyBytes, err := hex.DecodeString(destination)
+	if err == nil && len(pubkeyBytes) == 33 {
+		return destination, nil
+	}
+	// the policy is not tied to the network of the node
+	for _, netParams := range []*chaincfg.Params{&chaincfg.MainNetParams, &chaincfg.TestNet3Params, &chaincfg.SigNetParams, &chaincfg.RegressionNetParams} {
+		if _, err := btcutil.DecodeAddress(destination, netParams); err == nil {
+			return destination, nil
+		}
+	}
+	return "", fmt.Errorf("invalid destination, expected a node pubkey or on-chain address: %s", destination)
+}
+
+// normalizeDestination lowercases node pubkeys and bech32 addresses. Base58
+// addresses are case-sensitive and are kept as they are.
+func normalizeDestination(destination string) string {
+	destination = strings.TrimSpace(destination)
+	lowercaseDestination := strings.ToLower(destination)
+	if _, err := hex.DecodeString(lowercaseDestination); err == nil {
+		return lowercaseDestination
+	}
+	for _, hrp := range []string{"bc1", "tb1", "bcrt1"} {
+		if strings.HasPrefix(lowercaseDestination, hrp) {
+			return lowercaseDestination
+		}
+	}
+	return destination
+}
+
+// checkSpendingPolicy returns a human-readable reason if the payment violates
+// the app's spending policy, or an empty string if the payment is allowed.
+func checkSpendingPolicy(tx *gorm.DB, policy *db.AppSpendingPolicy, payment *outgoingPayment) (string, error) {
+	if policy.MaxAmountPerPaymentSat > 0 && payment.amountMsat > policy.MaxAmountPerPaymentSat*1000 {
+		return fmt.Sprintf("payment amount exceeds the maximum of %d sats per payment", policy.MaxAmountPerPaymentSat), nil
+	}
+
+	destination := normalizeDestination(payment.destination)
+
+	blockedDestinations, err := ParseSpendingPolicyDestinations(policy.BlockedDestinations)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse blocked destinations: %w", err)
+	}
+	if destination != "" && slices.Contains(blockedDestinations, destination) {
+		return "destination is blocked", nil
+	}
+
+	allowedDestinations, err := ParseSpendingPolicyDestinations(policy.AllowedDestinations)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse allowed destinations: %w", err)
+	}
+	// payments with an unknown destination (e.g. BOLT-12 offers paid through a
+	// short channel id) cannot be matched against the allowlist and are therefore rejected
+	if len(allowedDestinations) > 0 && !slices.Contains(allowedDestinations, destination) {
+		return "destination is not in the list of allowed destinations", nil
+	}
+
+	if policy.RequireDescription && payment.isInvoice && payment.description == "" && payment.descriptionHash == "" {
+		return "invoices must have a description", nil
+	}
+
+	now := time.Now()
+	velocityLimits := []struct {
+		maxPayments uint
+		window      time.Duration
+		name        string
+	}{
+		{policy.MaxPaymentsPerMinute, time.Minute, "minute"},
+		{policy.MaxPaymentsPerHour, time.Hour, "hour"},
+	}
+	for _, limit := range velocityLimits {
+		if limit.maxPayments == 0 {
+			continue
+		}
+		var paymentCount int64
+		err := tx.Model(&db.Transaction{}).
+			Where("app_id = ? AND type = ? AND state != ? AND created_at > ?", policy.AppId, constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_FAILED, now.Add(-limit.window)).
+			Count(&paymentCount).Error
+		if err != nil {
+			return "", fmt.Errorf("failed to count recent payments: %w", err)
+		}
+		if paymentCount >= int64(limit.maxPayments) {
+			logger.Logger.WithFields(logrus.Fields{
+				"app_id":        policy.AppId,
+				"payment_count": paymentCount,
+				"max_payments":  limit.maxPayments,
+				"window":        limit.window,
+			}).Debug("App payment velocity limit reached")
+			return fmt.Sprintf("no more than %d payments can be made per %s", limit.maxPayments, limit.name), nil
+		}
+	}
+
+	return "", nil
+}
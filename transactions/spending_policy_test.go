@@ -0,0 +1,177 @@
+package transactions
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+const spendingPolicyDestination = "02e89ca9e8da72b33d896bae51d20e7e6675aa971f7557500b6591b15429e717f1"
+
+func createSpendingPolicyTestApp(t *testing.T, svc *tests.TestService, spendingPolicy *db.AppSpendingPolicy) (*db.App, *db.RequestEvent) {
+	app, _, err := tests.CreateApp(svc)
+	require.NoError(t, err)
+
+	appPermission := &db.AppPermission{
+		AppId: app.ID,
+		App:   *app,
+		Scope: constants.PAY_INVOICE_SCOPE,
+	}
+	err = svc.DB.Create(appPermission).Error
+	require.NoError(t, err)
+
+	spendingPolicy.AppId = app.ID
+	spendingPolicy.App = *app
+	err = svc.DB.Create(spendingPolicy).Error
+	require.NoError(t, err)
+
+	dbRequestEvent := &db.RequestEvent{}
+	err = svc.DB.Create(&dbRequestEvent).Error
+	require.NoError(t, err)
+
+	return app, dbRequestEvent
+}
+
+func TestSendPaymentSync_App_SpendingPolicy_MaxAmountPerPayment(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		MaxAmountPerPaymentSat: 100,
+	})
+
+	mockEventConsumer := tests.NewMockEventConsumer()
+	svc.EventPublisher.RegisterSubscriber(mockEventConsumer)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Equal(t, NewSpendingPolicyError("payment amount exceeds the maximum of 100 sats per payment").Error(), err.Error())
+	assert.Nil(t, transaction)
+
+	consumedEvents := mockEventConsumer.WaitForConsumedEvents(1)
+	assert.Equal(t, 1, len(consumedEvents))
+	assert.Equal(t, "nwc_permission_denied", consumedEvents[0].Event)
+	assert.Equal(t, constants.ERROR_RESTRICTED, consumedEvents[0].Properties.(map[string]interface{})["code"])
+}
+
+func TestSendPaymentSync_App_SpendingPolicy_MaxAmountPerPaymentNotExceeded(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		MaxAmountPerPaymentSat: 123,
+		RequireDescription:     true,
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendPaymentSync(tests.MockLNClientTransaction.Invoice, nil, nil, svc.LNClient, &app.ID, &dbRequestEvent.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendKeysend_App_SpendingPolicy_BlockedDestination(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		BlockedDestinations: datatypes.JSON(`["` + spendingPolicyDestination + `"]`),
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendKeysend(uint64(1000), spendingPolicyDestination, nil, "", svc.LNClient, &app.ID, &dbRequestEvent.ID)
+
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Equal(t, NewSpendingPolicyError("destination is blocked").Error(), err.Error())
+	assert.Nil(t, transaction)
+}
+
+func TestSendKeysend_App_SpendingPolicy_AllowedDestinations(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		AllowedDestinations: datatypes.JSON(`["` + spendingPolicyDestination + `"]`),
+	})
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendKeysend(uint64(1000), "fake destination", nil, "", svc.LNClient, &app.ID, &dbRequestEvent.ID)
+
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Equal(t, NewSpendingPolicyError("destination is not in the list of allowed destinations").Error(), err.Error())
+	assert.Nil(t, transaction)
+
+	transaction, err = transactionsService.SendKeysend(uint64(1000), spendingPolicyDestination, nil, "", svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+}
+
+func TestSendKeysend_App_SpendingPolicy_MaxPaymentsPerMinute(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	app, dbRequestEvent := createSpendingPolicyTestApp(t, svc, &db.AppSpendingPolicy{
+		MaxPaymentsPerMinute: 1,
+		MaxPaymentsPerHour:   10,
+	})
+
+	// failed payments and payments older than a minute do not count
+	err = svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		State:      constants.TRANSACTION_STATE_FAILED,
+		AmountMsat: 1000,
+	}).Error
+	require.NoError(t, err)
+	err = svc.DB.Create(&db.Transaction{
+		AppId:      &app.ID,
+		Type:       constants.TRANSACTION_TYPE_OUTGOING,
+		State:      constants.TRANSACTION_STATE_SETTLED,
+		AmountMsat: 1000,
+		CreatedAt:  time.Now().Add(-2 * time.Minute),
+	}).Error
+	require.NoError(t, err)
+
+	transactionsService := NewTransactionsService(svc.DB, svc.EventPublisher)
+	transaction, err := transactionsService.SendKeysend(uint64(1000), spendingPolicyDestination, nil, "", svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.TRANSACTION_STATE_SETTLED, transaction.State)
+
+	transaction, err = transactionsService.SendKeysend(uint64(1000), spendingPolicyDestination, nil, "", svc.LNClient, &app.ID, &dbRequestEvent.ID)
+	assert.ErrorIs(t, err, NewSpendingPolicyError(""))
+	assert.Equal(t, NewSpendingPolicyError("no more than 1 payments can be made per minute").Error(), err.Error())
+	assert.Nil(t, transaction)
+}
+
+func TestValidateSpendingPolicyDestination(t *testing.T) {
+	destination, err := ValidateSpendingPolicyDestination(" " + strings.ToUpper(spendingPolicyDestination) + " ")
+	assert.NoError(t, err)
+	assert.Equal(t, spendingPolicyDestination, destination)
+
+	destination, err = ValidateSpendingPolicyDestination("BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4")
+	assert.NoError(t, err)
+	assert.Equal(t, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", destination)
+
+	// base58 addresses are case-sensitive
+	destination, err = ValidateSpendingPolicyDestination("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2")
+	assert.NoError(t, err)
+	assert.Equal(t, "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", destination)
+
+	_, err = ValidateSpendingPolicyDestination("fake destination")
+	assert.Error(t, err)
+}
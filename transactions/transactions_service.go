@@ -33,6 +33,11 @@ import (
 type transactionsService struct {
 	db             *gorm.DB
 	eventPublisher events.EventPublisher
+
+	// on-chain wallet history of the node, reused between pages of the activity feed
+	onchainTransactionsCache      []lnclient.OnchainTransaction
+	onchainTransactionsCachedAt   time.Time
+	onchainTransactionsCacheMutex sync.Mutex
 }
 
 type TransactionsService interface {
@@ -40,13 +45,22 @@ type TransactionsService interface {
 	MakeInvoice(ctx context.Context, amountMsat uint64, description string, descriptionHash string, expiry uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint, throughNodePubkey *string) (*Transaction, error)
 	LookupTransaction(ctx context.Context, paymentHash string, transactionType *string, lnClient lnclient.LNClient, appId *uint) (*Transaction, error)
 	ListTransactions(ctx context.Context, from, until, limit, offset uint64, unpaidOutgoing bool, unpaidIncoming bool, lnClient lnclient.LNClient, appId *uint, forceFilterByAppId bool, filters *ListTransactionsFilters) (transactions []Transaction, totalCount uint64, err error)
+	ListActivity(ctx context.Context, limit, offset uint64, lnClient lnclient.LNClient, appId *uint, forceFilterByAppId bool, filters *ListActivityFilters) ([]ActivityItem, uint64, error)
 	SendPaymentSync(payReq string, amountMsat *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	SendCircularPayment(ctx context.Context, payReq string, outgoingChannelId string, incomingChannelId string, lastHopPubkey string, maxFeeMsat uint64, metadata map[string]interface{}, lnClient lnclient.LNClient) (*Transaction, error)
 	SendKeysend(amountMsat uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
 	MakeHoldInvoice(ctx context.Context, amountMsat uint64, description string, descriptionHash string, expiry uint64, paymentHash string, minCltvExpiryDelta *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
 	SettleHoldInvoice(ctx context.Context, preimage string, lnClient lnclient.LNClient) (*Transaction, error)
 	CancelHoldInvoice(ctx context.Context, paymentHash string, lnClient lnclient.LNClient) error
 	SetTransactionMetadata(ctx context.Context, id uint, metadata map[string]interface{}) error
 	SetTransactionUserLabels(ctx context.Context, id uint, labels map[string]string) error
+	PayOnchain(ctx context.Context, address string, amountSat uint64, feeRate *uint64, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error)
+	UpdatePendingOnchainPayments(ctx context.Context, lnClient lnclient.LNClient)
+	ListPaymentApprovals(ctx context.Context, state string) ([]db.PaymentApproval, error)
+	ApprovePayment(ctx context.Context, id uint, lnClient lnclient.LNClient) (*Transaction, error)
+	RejectPayment(ctx context.Context, id uint) error
+	ExpirePaymentApprovals(ctx context.Context)
 }
 
 const (
@@ -66,6 +80,11 @@ type ListTransactionsFilters struct {
 	MinAmountMsat *uint64
 	HideFailed    bool
 	SearchTerm    string
+	// Cursor continues after a page returned by a previous call (see NextTransactionsCursor)
+	Cursor string
+	// ChangedSince lists transactions in any state changed after Cursor, oldest first,
+	// so clients can sync state changes incrementally
+	ChangedSince bool
 }
 
 var paymentHashRegex = regexp.MustCompile("^[0-9a-f]{64}$")
@@ -348,6 +367,8 @@ func (svc *transactionsService) SendPaymentSync(payReq string, amountMsat *uint6
 	}
 
 	var dbTransaction db.Transaction
+	var paymentApproval *db.PaymentApproval
+	newPaymentApproval := false
 
 	paymentAmountMsat := uint64(paymentRequest.MSatoshi)
 	if amountMsat != nil && paymentRequest.MSatoshi == 0 {
@@ -384,10 +405,27 @@ func (svc *transactionsService) SendPaymentSync(payReq string, amountMsat *uint6
 				return errors.New("there is already a payment pending for this invoice")
 			}
 
-			err := svc.validateCanPay(tx, appId, paymentAmountMsat, paymentRequest.Description, selfPayment)
+			err := svc.validateCanPay(tx, appId, &outgoingPayment{
+				amountMsat:      paymentAmountMsat,
+				destination:     paymentRequest.Payee,
+				description:     paymentRequest.Description,
+				descriptionHash: paymentRequest.DescriptionHash,
+				isInvoice:       true,
+				selfPayment:     selfPayment,
+			})
+			if err != nil {
+				return err
+			}
+
+			approvalRequired, err := isPaymentApprovalRequired(tx, appId, requestEventId, paymentRequest.PaymentHash, paymentAmountMsat)
 			if err != nil {
 				return err
 			}
+			if approvalRequired {
+				// the approval is committed, and the payment is made once approved
+				paymentApproval, newPaymentApproval, err = svc.requestPaymentApproval(tx, *appId, *requestEventId, payReq, paymentRequest.PaymentHash, paymentAmountMsat, paymentRequest.Description, paymentRequest.Expiry, metadataBytes)
+				return err
+			}
 
 			var expiresAt *time.Time
 			if paymentRequest.Expiry > 0 {
@@ -421,6 +459,13 @@ func (svc *transactionsService) SendPaymentSync(payReq string, amountMsat *uint6
 		return nil, err
 	}
 
+	if paymentApproval != nil {
+		if newPaymentApproval {
+			svc.publishPaymentApprovalRequired(paymentApproval)
+		}
+		return nil, NewPaymentApprovalRequiredError()
+	}
+
 	logger.Logger.WithFields(logrus.Fields{
 		"app_id":           appId,
 		"request_event_id": requestEventId,
@@ -462,6 +507,122 @@ func (svc *transactionsService) SendPaymentSync(payReq string, amountMsat *uint6
 	return settledTransaction, nil
 }
 
+// SendCircularPayment pays an invoice created by this hub out through the outgoing
+// channel and back in through the incoming channel, instead of settling it internally, to move
+// liquidity between channels. Both sides are recorded as self payments and the routing
+// fee is the fee of the outgoing transaction.
+func (svc *transactionsService) SendCircularPayment(ctx context.Context, payReq string, outgoingChannelId string, incomingChannelId string, lastHopPubkey string, maxFeeMsat uint64, metadata map[string]interface{}, lnClient lnclient.LNClient) (*Transaction, error) {
+	var metadataBytes []byte
+	if metadata != nil {
+		var err error
+		metadataBytes, err = json.Marshal(metadata)
+		if err != nil {
+			logger.Logger.WithError(err).Error("Failed to serialize metadata")
+			return nil, err
+		}
+		if len(metadataBytes) > constants.INVOICE_METADATA_MAX_LENGTH {
+			return nil, fmt.Errorf("encoded payment metadata provided is too large. Limit: %d Received: %d", constants.INVOICE_METADATA_MAX_LENGTH, len(metadataBytes))
+		}
+	}
+
+	payReq = strings.ToLower(payReq)
+	paymentRequest, err := decodepay.Decodepay(payReq)
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11": payReq,
+		}).WithError(err).Error("Failed to decode bolt11 invoice")
+		return nil, err
+	}
+
+	var incomingTransaction db.Transaction
+	result := svc.db.Limit(1).Find(&incomingTransaction, &db.Transaction{
+		Type:           constants.TRANSACTION_TYPE_INCOMING,
+		State:          constants.TRANSACTION_STATE_PENDING,
+		PaymentHash:    paymentRequest.PaymentHash,
+		PaymentRequest: payReq,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("circular payments can only pay pending invoices of this hub")
+	}
+
+	var expiresAt *time.Time
+	if paymentRequest.Expiry > 0 {
+		expiresAtValue := time.Now().Add(time.Duration(paymentRequest.Expiry) * time.Second)
+		expiresAt = &expiresAtValue
+	}
+	dbTransaction := db.Transaction{
+		Type:            constants.TRANSACTION_TYPE_OUTGOING,
+		State:           constants.TRANSACTION_STATE_PENDING,
+		FeeReserveMsat:  maxFeeMsat,
+		AmountMsat:      uint64(paymentRequest.MSatoshi),
+		PaymentRequest:  payReq,
+		PaymentHash:     paymentRequest.PaymentHash,
+		Description:     paymentRequest.Description,
+		DescriptionHash: paymentRequest.DescriptionHash,
+		ExpiresAt:       expiresAt,
+		SelfPayment:     true,
+		Metadata:        datatypes.JSON(metadataBytes),
+	}
+	err = svc.db.Create(&dbTransaction).Error
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11": payReq,
+		}).WithError(err).Error("Failed to create DB transaction")
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"amount_msat":         dbTransaction.AmountMsat,
+		"outgoing_channel_id": outgoingChannelId,
+		"last_hop_pubkey":     lastHopPubkey,
+		"max_fee_msat":        maxFeeMsat,
+		"metadata":            metadata,
+	}).Debug("Initiating circular payment")
+
+	response, err := lnClient.SendCircularPayment(ctx, &lnclient.CircularPaymentRequest{
+		PaymentRequest:    payReq,
+		OutgoingChannelId: outgoingChannelId,
+		IncomingChannelId: incomingChannelId,
+		LastHopPubkey:     lastHopPubkey,
+		MaxFeeMsat:        maxFeeMsat,
+	})
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"bolt11": payReq,
+		}).WithError(err).Error("Failed to send circular payment")
+
+		if _, markFailedErr := svc.markPaymentFailed(&dbTransaction, err.Error()); markFailedErr != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"bolt11": payReq,
+			}).WithError(markFailedErr).Error("Failed to mark payment as failed")
+		}
+
+		return nil, err
+	}
+
+	if _, err := svc.markTransactionSettled(&incomingTransaction, response.Preimage, 0, true); err != nil {
+		logger.Logger.WithField("payment_hash", paymentRequest.PaymentHash).WithError(err).Error("Failed to mark circular payment invoice as settled")
+	}
+	settledTransaction, err := svc.markTransactionSettled(&dbTransaction, response.Preimage, response.FeeMsat, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// the payment notifications of the LNClient may have settled either side first
+	err = svc.db.Model(&db.Transaction{}).
+		Where("id IN ?", []uint{incomingTransaction.ID, dbTransaction.ID}).
+		Update("self_payment", true).Error
+	if err != nil {
+		logger.Logger.WithField("payment_hash", paymentRequest.PaymentHash).WithError(err).Error("Failed to mark circular payment as self payment")
+	}
+	settledTransaction.SelfPayment = true
+
+	return settledTransaction, nil
+}
+
 func (svc *transactionsService) SendKeysend(amountMsat uint64, destination string, customRecords []lnclient.TLVRecord, preimage string, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
 	if preimage == "" {
 		preImageBytes, err := makePreimageHex()
@@ -504,11 +665,23 @@ func (svc *transactionsService) SendKeysend(amountMsat uint64, destination strin
 		balanceValidationLock.Lock()
 		defer balanceValidationLock.Unlock()
 		return svc.db.Transaction(func(tx *gorm.DB) error {
-			err := svc.validateCanPay(tx, appId, amountMsat, "", selfPayment)
+			err := svc.validateCanPay(tx, appId, &outgoingPayment{
+				amountMsat:  amountMsat,
+				destination: destination,
+				selfPayment: selfPayment,
+			})
 			if err != nil {
 				return err
 			}
 
+			approvalRequired, err := isPaymentApprovalRequired(tx, appId, requestEventId, "", amountMsat)
+			if err != nil {
+				return err
+			}
+			if approvalRequired {
+				return NewSpendingPolicyError("payments requiring approval must be made with an invoice")
+			}
+
 			dbTransaction = db.Transaction{
 				AppId:          appId,
 				Description:    svc.getDescriptionFromCustomRecords(customRecords),
@@ -596,6 +769,163 @@ func (svc *transactionsService) SendKeysend(amountMsat uint64, destination strin
 	return settledTransaction, nil
 }
 
+func (svc *transactionsService) PayOffer(ctx context.Context, offer string, amountMsat uint64, payerNote string, metadata map[string]interface{}, lnClient lnclient.LNClient, appId *uint, requestEventId *uint) (*Transaction, error) {
+	offer = strings.TrimSpace(offer)
+	if !strings.HasPrefix(strings.ToLower(offer), "lno1") {
+		return nil, errors.New("invalid BOLT-12 offer")
+	}
+	if amountMsat == 0 {
+		return nil, errors.New("an amount must be provided to pay a BOLT-12 offer")
+	}
+
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	offerMetadata := map[string]interface{}{}
+	if payerNote != "" {
+		offerMetadata["payer_note"] = payerNote
+	}
+	metadata["offer"] = offerMetadata
+
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		logger.Logger.WithError(err).Error("Failed to serialize metadata")
+		return nil, err
+	}
+	if len(metadataBytes) > constants.INVOICE_METADATA_MAX_LENGTH {
+		return nil, fmt.Errorf("encoded payment metadata provided is too large. Limit: %d Received: %d", constants.INVOICE_METADATA_MAX_LENGTH, len(metadataBytes))
+	}
+
+	// the offer's node is used to check the spending policy destination lists
+	offerNodeId, err := decodeOfferNodeId(offer)
+	if err != nil {
+		logger.Logger.WithField("offer", offer).WithError(err).Error("Failed to decode offer")
+		return nil, err
+	}
+
+	var dbTransaction db.Transaction
+
+	err = func() error {
+		balanceValidationLock.Lock()
+		defer balanceValidationLock.Unlock()
+		return svc.db.Transaction(func(tx *gorm.DB) error {
+			err := svc.validateCanPay(tx, appId, &outgoingPayment{
+				amountMsat:  amountMsat,
+				destination: offerNodeId,
+				description: payerNote,
+			})
+			if err != nil {
+				return err
+			}
+
+			approvalRequired, err := isPaymentApprovalRequired(tx, appId, requestEventId, "", amountMsat)
+			if err != nil {
+				return err
+			}
+			if approvalRequired {
+				return NewSpendingPolicyError("payments requiring approval must be made with an invoice")
+			}
+
+			// the payment hash is unknown until the invoice has been fetched from
+			// the offer, so it is set once the node reports the payment result
+			dbTransaction = db.Transaction{
+				AppId:          appId,
+				RequestEventId: requestEventId,
+				Type:           constants.TRANSACTION_TYPE_OUTGOING,
+				State:          constants.TRANSACTION_STATE_PENDING,
+				FeeReserveMsat: CalculateFeeReserveMsat(amountMsat),
+				AmountMsat:     amountMsat,
+				PaymentRequest: offer,
+				Description:    payerNote,
+				Metadata:       datatypes.JSON(metadataBytes),
+			}
+			return tx.Create(&dbTransaction).Error
+		})
+	}()
+
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"offer": offer,
+		}).WithError(err).Error("Failed to create DB transaction")
+		return nil, err
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"app_id":           appId,
+		"request_event_id": requestEventId,
+		"amount_msat":      amountMsat,
+		"offer":            offer,
+		"metadata":         metadata,
+	}).Debug("Initiating BOLT-12 payment")
+
+	response, err := lnClient.PayOffer(ctx, offer, amountMsat, payerNote)
+	if err != nil {
+		var paymentPendingError *lnclient.PaymentPendingError
+		if errors.As(err, &paymentPendingError) {
+			// the payment may still succeed: keep it pending so that it is
+			// reconciled when the node reports the payment result
+			logger.Logger.WithFields(logrus.Fields{
+				"offer":        offer,
+				"payment_hash": paymentPendingError.PaymentHash,
+			}).Warn("BOLT-12 payment is still in flight")
+			if paymentPendingError.PaymentHash != "" {
+				err := svc.db.Model(&dbTransaction).Update("payment_hash", paymentPendingError.PaymentHash).Error
+				if err != nil {
+					logger.Logger.WithFields(logrus.Fields{
+						"offer":        offer,
+						"payment_hash": paymentPendingError.PaymentHash,
+					}).WithError(err).Error("Failed to update payment hash of offer payment")
+				}
+			} else if paymentPendingError.PaymentId != "" {
+				// the payment result is matched by the backend's payment id instead
+				offerMetadata["payment_id"] = paymentPendingError.PaymentId
+				metadataBytes, err := json.Marshal(metadata)
+				if err == nil {
+					err = svc.db.Model(&dbTransaction).Update("metadata", datatypes.JSON(metadataBytes)).Error
+				}
+				if err != nil {
+					logger.Logger.WithFields(logrus.Fields{
+						"offer":      offer,
+						"payment_id": paymentPendingError.PaymentId,
+					}).WithError(err).Error("Failed to update payment id of offer payment")
+				}
+			}
+			return nil, err
+		}
+
+		logger.Logger.WithFields(logrus.Fields{
+			"offer": offer,
+		}).WithError(err).Error("Failed to pay offer")
+
+		if _, markFailedErr := svc.markPaymentFailed(&dbTransaction, err.Error()); markFailedErr != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"offer": offer,
+			}).WithError(markFailedErr).Error("Failed to mark payment as failed")
+		}
+
+		return nil, err
+	}
+
+	err = svc.db.Model(&dbTransaction).Update("payment_hash", response.PaymentHash).Error
+	if err != nil {
+		logger.Logger.WithFields(logrus.Fields{
+			"offer":        offer,
+			"payment_hash": response.PaymentHash,
+		}).WithError(err).Error("Failed to update payment hash of offer payment")
+	}
+	// offers can be paid multiple times, so the payment hash is required to
+	// find the matching settled transaction
+	dbTransaction.PaymentHash = response.PaymentHash
+
+	// the payment definitely succeeded
+	settledTransaction, err := svc.markTransactionSettled(&dbTransaction, response.Preimage, response.FeeMsat, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return settledTransaction, nil
+}
+
 func (svc *transactionsService) LookupTransaction(ctx context.Context, paymentHash string, transactionType *string, lnClient lnclient.LNClient, appId *uint) (*Transaction, error) {
 	transaction := db.Transaction{}
 
@@ -669,18 +999,34 @@ func (svc *transactionsService) ListTransactions(ctx context.Context, from, unti
 		}
 	}
 
+	var cursor *transactionsCursor
+	changedSince := false
+	if filters != nil {
+		changedSince = filters.ChangedSince
+		if filters.Cursor != "" {
+			cursor, err = decodeTransactionsCursor(filters.Cursor)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
 	tx := svc.db
 
 	if isIsolatedApp || forceFilterByAppId {
 		tx = tx.Where("app_id = ?", *appId)
 	}
 
-	if !unpaidOutgoing && !unpaidIncoming {
-		tx = tx.Where("state = ?", constants.TRANSACTION_STATE_SETTLED)
-	} else if unpaidOutgoing && !unpaidIncoming {
-		tx = tx.Where("state = ? OR type = ?", constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_TYPE_OUTGOING)
-	} else if unpaidIncoming && !unpaidOutgoing {
-		tx = tx.Where("state = ? OR type = ?", constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_TYPE_INCOMING)
+	// when syncing changes all states are included, so clients also see
+	// pending payments becoming settled or failed
+	if !changedSince {
+		if !unpaidOutgoing && !unpaidIncoming {
+			tx = tx.Where("state = ?", constants.TRANSACTION_STATE_SETTLED)
+		} else if unpaidOutgoing && !unpaidIncoming {
+			tx = tx.Where("state = ? OR type = ?", constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_TYPE_OUTGOING)
+		} else if unpaidIncoming && !unpaidOutgoing {
+			tx = tx.Where("state = ? OR type = ?", constants.TRANSACTION_STATE_SETTLED, constants.TRANSACTION_TYPE_INCOMING)
+		}
 	}
 
 	if filters != nil {
@@ -726,15 +1072,28 @@ func (svc *transactionsService) ListTransactions(ctx context.Context, from, unti
 		tx = tx.Where("updated_at <= ?", time.Unix(int64(until), 0))
 	}
 
-	var totalCount64 int64
-	result := tx.Model(&db.Transaction{}).Count(&totalCount64)
-	if result.Error != nil {
-		logger.Logger.WithError(result.Error).Error("Failed to count DB transactions")
-		return nil, 0, result.Error
+	// counting is skipped for cursor-based requests as it is slow on large tables
+	if cursor == nil && !changedSince {
+		var totalCount64 int64
+		result := tx.Model(&db.Transaction{}).Count(&totalCount64)
+		if result.Error != nil {
+			logger.Logger.WithError(result.Error).Error("Failed to count DB transactions")
+			return nil, 0, result.Error
+		}
+		totalCount = uint64(totalCount64)
 	}
-	totalCount = uint64(totalCount64)
 
-	tx = tx.Order("updated_at desc")
+	if changedSince {
+		if cursor != nil {
+			tx = tx.Where("updated_at > ? OR (updated_at = ? AND id > ?)", cursor.updatedAt, cursor.updatedAt, cursor.id)
+		}
+		tx = tx.Order("updated_at asc, id asc")
+	} else {
+		if cursor != nil {
+			tx = tx.Where("updated_at < ? OR (updated_at = ? AND id < ?)", cursor.updatedAt, cursor.updatedAt, cursor.id)
+		}
+		tx = tx.Order("updated_at desc, id desc")
+	}
 
 	if limit > 0 {
 		tx = tx.Limit(int(limit))
@@ -743,7 +1102,7 @@ func (svc *transactionsService) ListTransactions(ctx context.Context, from, unti
 		tx = tx.Offset(int(offset))
 	}
 
-	result = tx.Find(&transactions)
+	result := tx.Find(&transactions)
 	if result.Error != nil {
 		logger.Logger.WithError(result.Error).Error("Failed to list DB transactions")
 		return nil, 0, result.Error
@@ -761,7 +1120,8 @@ func (svc *transactionsService) checkUnsettledTransactions(ctx context.Context,
 
 	// check pending payments less than a day old
 	transactions := []Transaction{}
-	result := svc.db.Where("state = ? AND created_at > ?", constants.TRANSACTION_STATE_PENDING, time.Now().Add(-24*time.Hour)).Find(&transactions)
+	// on-chain payments are settled by UpdatePendingOnchainPayments
+	result := svc.db.Where("state = ? AND created_at > ? AND onchain_address = ''", constants.TRANSACTION_STATE_PENDING, time.Now().Add(-24*time.Hour)).Find(&transactions)
 	if result.Error != nil {
 		logger.Logger.WithError(result.Error).Error("Failed to list DB transactions")
 		return
@@ -902,6 +1262,16 @@ func (svc *transactionsService) ConsumeEvent(ctx context.Context, event *events.
 			return
 		}
 
+		if result.RowsAffected == 0 && getOfferPaymentId(lnClientTransaction) != "" {
+			result = svc.findPendingOfferPayment(&dbTransaction, lnClientTransaction)
+			if result.Error != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"payment_hash": lnClientTransaction.PaymentHash,
+				}).WithError(result.Error).Error("Failed to find pending offer payment")
+				return
+			}
+		}
+
 		if result.RowsAffected == 0 {
 			// if no pending payment was found, lookup by failed, latest updated first
 			result := svc.db.Limit(1).Order("updated_at DESC").Find(&dbTransaction, &db.Transaction{
@@ -971,11 +1341,16 @@ func (svc *transactionsService) ConsumeEvent(ctx context.Context, event *events.
 		lnClientTransaction := paymentFailedAsyncProperties.Transaction
 
 		var dbTransaction db.Transaction
-		result := svc.db.Limit(1).Find(&dbTransaction, &db.Transaction{
-			Type:        constants.TRANSACTION_TYPE_OUTGOING,
-			State:       constants.TRANSACTION_STATE_PENDING,
-			PaymentHash: lnClientTransaction.PaymentHash,
-		})
+		var result *gorm.DB
+		if lnClientTransaction.PaymentHash == "" && getOfferPaymentId(lnClientTransaction) != "" {
+			result = svc.findPendingOfferPayment(&dbTransaction, lnClientTransaction)
+		} else {
+			result = svc.db.Limit(1).Find(&dbTransaction, &db.Transaction{
+				Type:        constants.TRANSACTION_TYPE_OUTGOING,
+				State:       constants.TRANSACTION_STATE_PENDING,
+				PaymentHash: lnClientTransaction.PaymentHash,
+			})
+		}
 
 		if result.RowsAffected == 0 {
 			logger.Logger.WithField("event", event).Error("Failed to find pending outgoing transaction by payment hash")
@@ -987,7 +1362,40 @@ func (svc *transactionsService) ConsumeEvent(ctx context.Context, event *events.
 				"payment_hash": lnClientTransaction.PaymentHash,
 			}).WithError(err).Error("Failed to mark payment as failed")
 		}
+	case "nwc_channel_ready", "nwc_channel_closed":
+		svc.recordChannelEvent(event)
+	}
+}
+
+// getOfferPaymentId returns the backend's id of a BOLT-12 payment, if known
+func getOfferPaymentId(lnClientTransaction *lnclient.Transaction) string {
+	offerMetadata, ok := lnClientTransaction.Metadata["offer"].(map[string]interface{})
+	if !ok {
+		return ""
 	}
+	paymentId, _ := offerMetadata["payment_id"].(string)
+	return paymentId
+}
+
+// findPendingOfferPayment finds a pending BOLT-12 payment which did not complete
+// in time for its payment hash to be known, by the backend's payment id which was
+// stored when the payment was sent. The payment hash of the event is stored on the payment.
+func (svc *transactionsService) findPendingOfferPayment(dbTransaction *db.Transaction, lnClientTransaction *lnclient.Transaction) *gorm.DB {
+	result := svc.db.Limit(1).
+		Where("type = ? AND state = ? AND payment_hash = ?", constants.TRANSACTION_TYPE_OUTGOING, constants.TRANSACTION_STATE_PENDING, "").
+		Where(datatypes.JSONQuery("metadata").Equals(getOfferPaymentId(lnClientTransaction), "offer", "payment_id")).
+		Find(dbTransaction)
+	if result.Error != nil || result.RowsAffected == 0 || lnClientTransaction.PaymentHash == "" {
+		return result
+	}
+
+	err := svc.db.Model(dbTransaction).Update("payment_hash", lnClientTransaction.PaymentHash).Error
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	dbTransaction.PaymentHash = lnClientTransaction.PaymentHash
+	return result
 }
 
 func (svc *transactionsService) markHoldInvoiceAccepted(paymentRequest string, paymentHash string, settleDeadline uint32, selfPayment bool) {
@@ -1130,12 +1538,22 @@ func (svc *transactionsService) interceptSelfHoldPayment(paymentRequest string,
 	}
 }
 
-func (svc *transactionsService) validateCanPay(tx *gorm.DB, appId *uint, amountMsat uint64, description string, selfPayment bool) error {
+func (svc *transactionsService) validateCanPay(tx *gorm.DB, appId *uint, payment *outgoingPayment) error {
+	amountMsat := payment.amountMsat
+	description := payment.description
+	selfPayment := payment.selfPayment
 	amountWithFeeReserveMsat := amountMsat
-	if !selfPayment {
+	if payment.onchain {
+		amountWithFeeReserveMsat += payment.feeReserveMsat
+	} else if !selfPayment {
 		amountWithFeeReserveMsat += CalculateFeeReserveMsat(amountMsat)
 	}
 
+	scope := constants.PAY_INVOICE_SCOPE
+	if payment.onchain {
+		scope = constants.PAY_ONCHAIN_SCOPE
+	}
+
 	// ensure balance for isolated apps
 	if appId != nil {
 		var app db.App
@@ -1149,10 +1567,41 @@ func (svc *transactionsService) validateCanPay(tx *gorm.DB, appId *uint, amountM
 		var appPermission db.AppPermission
 		result = tx.Limit(1).Find(&appPermission, &db.AppPermission{
 			AppId: *appId,
-			Scope: constants.PAY_INVOICE_SCOPE,
+			Scope: scope,
 		})
 		if result.RowsAffected == 0 {
-			return errors.New("app does not have pay_invoice scope")
+			return fmt.Errorf("app does not have %s scope", scope)
+		}
+
+		var spendingPolicy db.AppSpendingPolicy
+		result = tx.Limit(1).Find(&spendingPolicy, &db.AppSpendingPolicy{
+			AppId: *appId,
+		})
+		if result.Error != nil {
+			return fmt.Errorf("failed to fetch spending policy for app: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			reason, err := checkSpendingPolicy(tx, &spendingPolicy, payment)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				logger.Logger.WithFields(logrus.Fields{
+					"app_id":      app.ID,
+					"amount_msat": amountMsat,
+					"destination": payment.destination,
+					"reason":      reason,
+				}).Debug("Payment rejected by app spending policy")
+				svc.eventPublisher.Publish(&events.Event{
+					Event: "nwc_permission_denied",
+					Properties: map[string]interface{}{
+						"app_name": app.Name,
+						"code":     constants.ERROR_RESTRICTED,
+						"message":  NewSpendingPolicyError(reason).Error(),
+					},
+				})
+				return NewSpendingPolicyError(reason)
+			}
 		}
 
 		if app.Isolated {
@@ -1578,7 +2027,9 @@ func (svc *transactionsService) createSettledTransactionFromNotification(dbTrans
 // given payment hash on postgres, so that concurrent state changes for the
 // same payment serialize (in sqlite transactions are serializable by default).
 func (svc *transactionsService) lockTransactionsByPaymentHash(tx *gorm.DB, paymentHash string) error {
-	if tx.Dialector.Name() != "postgres" {
+	// on-chain payments and offer payments in flight have no payment hash.
+	// An empty hash would not filter the query and lock every transaction.
+	if tx.Dialector.Name() != "postgres" || paymentHash == "" {
 		return nil
 	}
 	transactionsWithPaymentHash := []db.Transaction{}
@@ -1672,13 +2123,13 @@ func (svc *transactionsService) checkBudgetUsage(app *db.App, dbTransaction *db.
 		return nil
 	}
 
+	// the budget is copied to every permission, so either payment scope can be used
 	var appPermission db.AppPermission
-	result := gormTransaction.Limit(1).Find(&appPermission, &db.AppPermission{
-		AppId: app.ID,
-		Scope: constants.PAY_INVOICE_SCOPE,
-	})
+	result := gormTransaction.Limit(1).
+		Where("app_id = ? AND scope IN ?", app.ID, []string{constants.PAY_INVOICE_SCOPE, constants.PAY_ONCHAIN_SCOPE}).
+		Find(&appPermission)
 	if result.RowsAffected == 0 {
-		logger.Logger.WithField("app_id", dbTransaction.AppId).Error("failed to find pay_invoice scope")
+		logger.Logger.WithField("app_id", dbTransaction.AppId).Error("failed to find payment scope")
 		return nil
 	}
 
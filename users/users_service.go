@@ -0,0 +1,240 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/logger"
+)
+
+const minPasswordLength = 8
+
+// compared against when the username is unknown so that failed logins take
+// the same time whether or not the user exists. It is generated on first use
+// to keep bcrypt out of package initialization.
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+func getDummyPasswordHash() []byte {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	return dummyPasswordHash
+}
+
+type invalidCredentialsError struct {
+}
+
+func NewInvalidCredentialsError() error {
+	return &invalidCredentialsError{}
+}
+
+func (err *invalidCredentialsError) Error() string {
+	return "invalid username or password"
+}
+
+type UsersService interface {
+	CreateUser(username string, password string, role string) (*db.User, error)
+	ListUsers() ([]db.User, error)
+	GetUser(id uint) (*db.User, error)
+	UpdateUser(id uint, role string, password string) (*db.User, error)
+	DeleteUser(id uint) error
+	Authenticate(username string, password string) (*db.User, error)
+}
+
+type usersService struct {
+	db *gorm.DB
+}
+
+func NewUsersService(db *gorm.DB) *usersService {
+	return &usersService{
+		db: db,
+	}
+}
+
+// GetRoles returns the roles that can be assigned to users. The owner role
+// cannot be assigned as the owner is the holder of the unlock password.
+func GetRoles() []string {
+	return []string{
+		constants.USER_ROLE_OPERATOR,
+		constants.USER_ROLE_BOOKKEEPER,
+		constants.USER_ROLE_VIEWER,
+	}
+}
+
+// GetRoleScopes returns the API scopes granted to a role
+func GetRoleScopes(role string) []string {
+	switch role {
+	case constants.USER_ROLE_OWNER:
+		return constants.GetApiScopes()
+	case constants.USER_ROLE_OPERATOR:
+		// everything except access to the unlock password, recovery phrase, API tokens and users
+		return slices.DeleteFunc(constants.GetApiScopes(), func(scope string) bool {
+			return scope == constants.API_SCOPE_ADMIN
+		})
+	case constants.USER_ROLE_BOOKKEEPER:
+		return []string{constants.API_SCOPE_READ, constants.API_SCOPE_INVOICES_CREATE, constants.API_SCOPE_TRANSACTIONS_LABEL}
+	case constants.USER_ROLE_VIEWER:
+		return []string{constants.API_SCOPE_READ}
+	}
+	return []string{}
+}
+
+func (svc *usersService) CreateUser(username string, password string, role string) (*db.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("no username provided")
+	}
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	var existingUsers int64
+	if err := svc.db.Model(&db.User{}).Where("username = ?", username).Count(&existingUsers).Error; err != nil {
+		return nil, err
+	}
+	if existingUsers > 0 {
+		return nil, fmt.Errorf("username already exists: %s", username)
+	}
+
+	user := &db.User{
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         role,
+	}
+	if err := svc.db.Create(user).Error; err != nil {
+		logger.Logger.WithError(err).Error("Failed to create user")
+		return nil, err
+	}
+
+	logger.Logger.WithField("user_id", user.ID).WithField("role", role).Info("Created user")
+
+	return user, nil
+}
+
+func (svc *usersService) ListUsers() ([]db.User, error) {
+	users := []db.User{}
+	err := svc.db.Order("username").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (svc *usersService) GetUser(id uint) (*db.User, error) {
+	var user db.User
+	err := svc.db.First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUser changes the role and/or password of a user. Empty values are left unchanged.
+func (svc *usersService) UpdateUser(id uint, role string, password string) (*db.User, error) {
+	user, err := svc.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if role != "" {
+		if err := validateRole(role); err != nil {
+			return nil, err
+		}
+		updates["role"] = role
+	}
+	if password != "" {
+		passwordHash, err := hashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		updates["password_hash"] = passwordHash
+	}
+	if len(updates) == 0 {
+		return user, nil
+	}
+
+	if err := svc.db.Model(user).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if role != "" {
+		user.Role = role
+	}
+
+	logger.Logger.WithField("user_id", user.ID).WithField("role", user.Role).Info("Updated user")
+
+	return user, nil
+}
+
+func (svc *usersService) DeleteUser(id uint) error {
+	result := svc.db.Delete(&db.User{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	logger.Logger.WithField("user_id", id).Info("Deleted user")
+	return nil
+}
+
+// Authenticate checks the password of a user and records the login time
+func (svc *usersService) Authenticate(username string, password string) (*db.User, error) {
+	var user db.User
+	result := svc.db.Limit(1).Find(&user, &db.User{
+		Username: strings.TrimSpace(username),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		bcrypt.CompareHashAndPassword(getDummyPasswordHash(), []byte(password))
+		return nil, NewInvalidCredentialsError()
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, NewInvalidCredentialsError()
+	}
+
+	now := time.Now()
+	if err := svc.db.Model(&user).Update("last_login_at", &now).Error; err != nil {
+		logger.Logger.WithField("user_id", user.ID).WithError(err).Error("Failed to update user last login time")
+	}
+	user.LastLoginAt = &now
+
+	return &user, nil
+}
+
+func validateRole(role string) error {
+	if !slices.Contains(GetRoles(), role) {
+		return fmt.Errorf("unknown role: %s. Must be one of %s", role, strings.Join(GetRoles(), ","))
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(passwordHash), nil
+}
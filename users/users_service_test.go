@@ -0,0 +1,117 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/getAlby/hub/constants"
+	"github.com/getAlby/hub/db"
+	"github.com/getAlby/hub/tests"
+)
+
+func TestCreateUser(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	usersSvc := NewUsersService(svc.DB)
+
+	user, err := usersSvc.CreateUser(" alice ", "password123", constants.USER_ROLE_BOOKKEEPER)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, constants.USER_ROLE_BOOKKEEPER, user.Role)
+
+	// the password itself is not stored
+	var dbUser db.User
+	require.NoError(t, svc.DB.First(&dbUser, user.ID).Error)
+	assert.NotContains(t, dbUser.PasswordHash, "password123")
+
+	users, err := usersSvc.ListUsers()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(users))
+	assert.Equal(t, user.ID, users[0].ID)
+}
+
+func TestCreateUser_Invalid(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	usersSvc := NewUsersService(svc.DB)
+
+	_, err = usersSvc.CreateUser("", "password123", constants.USER_ROLE_VIEWER)
+	assert.EqualError(t, err, "no username provided")
+	_, err = usersSvc.CreateUser("alice", "password123", constants.USER_ROLE_OWNER)
+	assert.ErrorContains(t, err, "unknown role: owner")
+	_, err = usersSvc.CreateUser("alice", "short", constants.USER_ROLE_VIEWER)
+	assert.EqualError(t, err, "password must be at least 8 characters")
+
+	_, err = usersSvc.CreateUser("alice", "password123", constants.USER_ROLE_VIEWER)
+	require.NoError(t, err)
+	_, err = usersSvc.CreateUser("alice", "password456", constants.USER_ROLE_OPERATOR)
+	assert.EqualError(t, err, "username already exists: alice")
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	usersSvc := NewUsersService(svc.DB)
+	user, err := usersSvc.CreateUser("alice", "password123", constants.USER_ROLE_OPERATOR)
+	require.NoError(t, err)
+
+	authenticatedUser, err := usersSvc.Authenticate("alice", "password123")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, authenticatedUser.ID)
+	assert.NotNil(t, authenticatedUser.LastLoginAt)
+
+	_, err = usersSvc.Authenticate("alice", "password456")
+	assert.ErrorIs(t, err, NewInvalidCredentialsError())
+	_, err = usersSvc.Authenticate("bob", "password123")
+	assert.ErrorIs(t, err, NewInvalidCredentialsError())
+
+	// a changed password replaces the old one
+	_, err = usersSvc.UpdateUser(user.ID, "", "password456")
+	require.NoError(t, err)
+	_, err = usersSvc.Authenticate("alice", "password123")
+	assert.ErrorIs(t, err, NewInvalidCredentialsError())
+	_, err = usersSvc.Authenticate("alice", "password456")
+	assert.NoError(t, err)
+
+	require.NoError(t, usersSvc.DeleteUser(user.ID))
+	_, err = usersSvc.Authenticate("alice", "password456")
+	assert.ErrorIs(t, err, NewInvalidCredentialsError())
+	assert.ErrorIs(t, usersSvc.DeleteUser(user.ID), gorm.ErrRecordNotFound)
+}
+
+func TestUpdateUser_Role(t *testing.T) {
+	svc, err := tests.CreateTestService(t)
+	require.NoError(t, err)
+	defer svc.Remove()
+
+	usersSvc := NewUsersService(svc.DB)
+	user, err := usersSvc.CreateUser("alice", "password123", constants.USER_ROLE_VIEWER)
+	require.NoError(t, err)
+
+	updatedUser, err := usersSvc.UpdateUser(user.ID, constants.USER_ROLE_OPERATOR, "")
+	require.NoError(t, err)
+	assert.Equal(t, constants.USER_ROLE_OPERATOR, updatedUser.Role)
+
+	_, err = usersSvc.UpdateUser(user.ID, constants.USER_ROLE_OWNER, "")
+	assert.ErrorContains(t, err, "unknown role: owner")
+	_, err = usersSvc.UpdateUser(user.ID+1, constants.USER_ROLE_VIEWER, "")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestGetRoleScopes(t *testing.T) {
+	assert.Equal(t, constants.GetApiScopes(), GetRoleScopes(constants.USER_ROLE_OWNER))
+	assert.NotContains(t, GetRoleScopes(constants.USER_ROLE_OPERATOR), constants.API_SCOPE_ADMIN)
+	assert.Contains(t, GetRoleScopes(constants.USER_ROLE_OPERATOR), constants.API_SCOPE_PAYMENTS_SEND)
+	assert.Equal(t, []string{constants.API_SCOPE_READ, constants.API_SCOPE_INVOICES_CREATE, constants.API_SCOPE_TRANSACTIONS_LABEL}, GetRoleScopes(constants.USER_ROLE_BOOKKEEPER))
+	assert.Equal(t, []string{constants.API_SCOPE_READ}, GetRoleScopes(constants.USER_ROLE_VIEWER))
+	assert.Empty(t, GetRoleScopes("superuser"))
+}
@@ -15,6 +15,8 @@ import (
 
 	"github.com/getAlby/hub/alby"
 	"github.com/getAlby/hub/api"
+	"github.com/getAlby/hub/audit"
+	"github.com/getAlby/hub/constants"
 	"github.com/getAlby/hub/logger"
 )
 
@@ -25,7 +27,8 @@ type WailsRequestRouterResponse struct {
 
 // TODO: make this match echo
 func (app *WailsApp) WailsRequestRouter(route string, method string, body string) WailsRequestRouterResponse {
-	ctx := app.ctx
+	// the desktop app is only used by the owner
+	ctx := audit.WithActor(app.ctx, constants.AUDIT_SOURCE_WAILS, constants.USER_ROLE_OWNER)
 
 	// the grouping is done to avoid other parameters like &unused=true
 	albyCallbackRegex := regexp.MustCompile(
@@ -110,13 +113,24 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 				}).WithError(err).Error("Failed to decode request to wails router")
 				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 			}
-			err = app.api.UpdateApp(dbApp, updateAppRequest)
+			err = app.api.UpdateApp(ctx, dbApp, updateAppRequest)
 			if err != nil {
 				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 			}
 			return WailsRequestRouterResponse{Body: nil, Error: ""}
 		case "DELETE":
-			err := app.api.DeleteApp(dbApp)
+			deleteAppRequest := &api.DeleteAppRequest{}
+			if body != "" {
+				err := json.Unmarshal([]byte(body), deleteAppRequest)
+				if err != nil {
+					logger.Logger.WithFields(logrus.Fields{
+						"route":  route,
+						"method": method,
+					}).WithError(err).Error("Failed to decode request to wails router")
+					return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+				}
+			}
+			err := app.api.DeleteApp(ctx, dbApp, deleteAppRequest.TotpCode)
 			if err != nil {
 				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 			}
@@ -267,6 +281,55 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		return WailsRequestRouterResponse{Body: node, Error: ""}
 	}
 
+	paymentApprovalRegex := regexp.MustCompile(
+		`/api/approvals/([0-9]+)/(approve|reject)`,
+	)
+	paymentApprovalMatch := paymentApprovalRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(paymentApprovalMatch) > 2 && method == "POST":
+		approvalID, err := strconv.ParseUint(paymentApprovalMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid payment approval ID"}
+		}
+		if paymentApprovalMatch[2] == "reject" {
+			err = app.api.RejectPayment(ctx, uint(approvalID))
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
+		paymentResponse, err := app.api.ApprovePayment(ctx, uint(approvalID))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: paymentResponse, Error: ""}
+	case strings.HasPrefix(route, "/api/forwards/analytics") && method == "GET":
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "invalid route"}
+		}
+		from, until, err := api.ParseRoutingAnalyticsRange(parsedUrl.Query())
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		analytics, err := app.api.GetRoutingAnalytics(from, until)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: analytics, Error: ""}
+	case strings.HasPrefix(route, "/api/approvals") && method == "GET":
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "invalid route"}
+		}
+		paymentApprovals, err := app.api.ListPaymentApprovals(ctx, parsedUrl.Query().Get("state"))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: paymentApprovals, Error: ""}
+	}
+
 	transactionLabelRegex := regexp.MustCompile(
 		`/api/transactions/([0-9]+)/labels`,
 	)
@@ -313,6 +376,195 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		return WailsRequestRouterResponse{Body: paymentInfo, Error: ""}
 	}
 
+	listAuditEventsRegex := regexp.MustCompile(
+		`/api/audit(\?.*)?$`,
+	)
+
+	switch {
+	case route == "/api/audit/verify":
+		verifyResult, err := app.api.VerifyAuditLog()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: verifyResult, Error: ""}
+	case listAuditEventsRegex.MatchString(route):
+		limit := uint64(20)
+		offset := uint64(0)
+
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "invalid route"}
+		}
+		query := parsedUrl.Query()
+
+		if limitParam := query.Get("limit"); limitParam != "" {
+			if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+				limit = parsedLimit
+			}
+		}
+
+		if offsetParam := query.Get("offset"); offsetParam != "" {
+			if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+				offset = parsedOffset
+			}
+		}
+
+		filters, err := api.ParseListAuditEventsFilters(query)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		auditEvents, err := app.api.ListAuditEvents(limit, offset, filters)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: auditEvents, Error: ""}
+	}
+
+	feePolicyRegex := regexp.MustCompile(
+		`/api/fees/policies/([0-9]+)`,
+	)
+	feePolicyMatch := feePolicyRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(feePolicyMatch) > 1 && method == "DELETE":
+		feePolicyId, err := strconv.ParseUint(feePolicyMatch[1], 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "Invalid fee policy ID"}
+		}
+		err = app.api.DeleteFeePolicy(ctx, uint(feePolicyId))
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case route == "/api/fees/policies":
+		switch method {
+		case "GET":
+			feePolicies, err := app.api.ListFeePolicies()
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: feePolicies, Error: ""}
+		case "POST":
+			setFeePolicyRequest := &api.SetFeePolicyRequest{}
+			err := json.Unmarshal([]byte(body), setFeePolicyRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			feePolicy, err := app.api.SetFeePolicy(ctx, setFeePolicyRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: feePolicy, Error: ""}
+		}
+	case strings.HasPrefix(route, "/api/fees/changes"):
+		limit := uint64(20)
+		offset := uint64(0)
+
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "invalid route"}
+		}
+		query := parsedUrl.Query()
+
+		if limitParam := query.Get("limit"); limitParam != "" {
+			if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+				limit = parsedLimit
+			}
+		}
+
+		if offsetParam := query.Get("offset"); offsetParam != "" {
+			if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+				offset = parsedOffset
+			}
+		}
+
+		feeChanges, err := app.api.ListFeeChanges(limit, offset)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: feeChanges, Error: ""}
+	case route == "/api/fees/status":
+		status, err := app.api.GetFeeManagerStatus()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: status, Error: ""}
+	case route == "/api/fees/preview":
+		feeChanges, err := app.api.PreviewFeeChanges(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: feeChanges, Error: ""}
+	case route == "/api/fees/run" && method == "POST":
+		feeChanges, err := app.api.RunFeeManager(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: feeChanges, Error: ""}
+	case route == "/api/rebalancer/status":
+		status, err := app.api.GetRebalancerStatus()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: status, Error: ""}
+	case route == "/api/rebalancer/preview":
+		rebalances, err := app.api.PreviewRebalances(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: rebalances, Error: ""}
+	case route == "/api/rebalancer/run" && method == "POST":
+		results, err := app.api.RunRebalancer(ctx)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: results, Error: ""}
+	}
+
+	listActivityRegex := regexp.MustCompile(
+		`/api/activity`,
+	)
+
+	switch {
+	case listActivityRegex.MatchString(route):
+		limit := uint64(20)
+		offset := uint64(0)
+
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "invalid route"}
+		}
+		query := parsedUrl.Query()
+
+		if limitParam := query.Get("limit"); limitParam != "" {
+			if parsedLimit, err := strconv.ParseUint(limitParam, 10, 64); err == nil {
+				limit = parsedLimit
+			}
+		}
+
+		if offsetParam := query.Get("offset"); offsetParam != "" {
+			if parsedOffset, err := strconv.ParseUint(offsetParam, 10, 64); err == nil {
+				offset = parsedOffset
+			}
+		}
+
+		filters, err := api.ParseListActivityFilters(query)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		activity, err := app.api.ListActivity(ctx, limit, offset, filters)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: activity, Error: ""}
+	}
+
 	listTransactionsRegex := regexp.MustCompile(
 		`/api/transactions`,
 	)
@@ -360,6 +612,38 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		return WailsRequestRouterResponse{Body: transactions, Error: ""}
 	}
 
+	payOfferRegex := regexp.MustCompile(
+		`/api/offers/([0-9a-zA-Z]+)/payments`,
+	)
+	payOfferMatch := payOfferRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(payOfferMatch) > 1:
+		offer := payOfferMatch[1]
+		payOfferRequest := &api.PayOfferRequest{}
+		if body != "" {
+			err := json.Unmarshal([]byte(body), payOfferRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+		}
+		amountMsat := uint64(0)
+		resolvedAmountMsat := api.ResolveToMsat(payOfferRequest.AmountSat, payOfferRequest.AmountMsat, nil, nil)
+		if resolvedAmountMsat != nil {
+			amountMsat = *resolvedAmountMsat
+		}
+		paymentResponse, err := app.api.PayOffer(ctx, offer, amountMsat, payOfferRequest.PayerNote, payOfferRequest.Metadata, payOfferRequest.FromAppID)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		return WailsRequestRouterResponse{Body: paymentResponse, Error: ""}
+	}
+
 	paymentRegex := regexp.MustCompile(
 		`/api/payments/([0-9a-zA-Z]+)`,
 	)
@@ -487,7 +771,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 			}
 
-			createAppResponse, err := app.api.CreateApp(createAppRequest)
+			createAppResponse, err := app.api.CreateApp(ctx, createAppRequest)
 			if err != nil {
 				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 			}
@@ -504,7 +788,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		err = app.api.ResetRouter(resetRouterRequest.Key)
+		err = app.api.ResetRouter(ctx, resetRouterRequest.Key)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
@@ -571,6 +855,21 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: rebalanceChannelResponse, Error: ""}
+	case "/api/channels/rebalance/circular":
+		circularRebalanceRequest := &api.CircularRebalanceRequest{}
+		err := json.Unmarshal([]byte(body), circularRebalanceRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		result, err := app.api.CircularRebalance(ctx, circularRebalanceRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: result, Error: ""}
 	case "/api/balances":
 		balancesResponse, err := app.api.GetBalances(ctx)
 		if err != nil {
@@ -631,7 +930,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			amountSat = *resolvedAmountSat
 		}
 
-		redeemOnchainFundsResponse, err := app.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, amountSat, redeemOnchainFundsRequest.FeeRate, redeemOnchainFundsRequest.SendAll)
+		redeemOnchainFundsResponse, err := app.api.RedeemOnchainFunds(ctx, redeemOnchainFundsRequest.ToAddress, amountSat, redeemOnchainFundsRequest.FeeRate, redeemOnchainFundsRequest.SendAll, redeemOnchainFundsRequest.TotpCode)
 		if err != nil {
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
@@ -774,7 +1073,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			}).WithError(err).Error("Failed to parse mnemonic request")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		mnemonicResponse, err := app.api.GetMnemonic(mnemonicRequest.UnlockPassword)
+		mnemonicResponse, err := app.api.GetMnemonic(mnemonicRequest.UnlockPassword, mnemonicRequest.TotpCode)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -784,6 +1083,93 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		}
 		res := WailsRequestRouterResponse{Body: *mnemonicResponse, Error: ""}
 		return res
+	case "/api/totp":
+		totpStatus, err := app.api.GetTOTPStatus()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: totpStatus, Error: ""}
+	case "/api/totp/enroll":
+		beginTOTPEnrollmentRequest := &api.BeginTOTPEnrollmentRequest{}
+		err := json.Unmarshal([]byte(body), beginTOTPEnrollmentRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		enrollment, err := app.api.BeginTOTPEnrollment(ctx, beginTOTPEnrollmentRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: enrollment, Error: ""}
+	case "/api/totp/confirm", "/api/totp/disable", "/api/totp/recovery-codes":
+		totpRequest := &api.TOTPRequest{}
+		err := json.Unmarshal([]byte(body), totpRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		switch route {
+		case "/api/totp/confirm":
+			recoveryCodesResponse, err := app.api.ConfirmTOTPEnrollment(ctx, totpRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: recoveryCodesResponse, Error: ""}
+		case "/api/totp/disable":
+			err = app.api.DisableTOTP(ctx, totpRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		default:
+			recoveryCodesResponse, err := app.api.RegenerateTOTPRecoveryCodes(ctx, totpRequest)
+			if err != nil {
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: recoveryCodesResponse, Error: ""}
+		}
+	case "/api/encryption":
+		encryptionStatus, err := app.api.GetEncryptionStatus()
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: encryptionStatus, Error: ""}
+	case "/api/encryption/kdf":
+		upgradeKDFRequest := &api.UpgradeKDFRequest{}
+		err := json.Unmarshal([]byte(body), upgradeKDFRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.UpgradeKDF(ctx, upgradeKDFRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case "/api/encryption/rotate":
+		rotateDataKeyRequest := &api.RotateDataKeyRequest{}
+		err := json.Unmarshal([]byte(body), rotateDataKeyRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		err = app.api.RotateDataKey(ctx, rotateDataKeyRequest)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: nil, Error: ""}
 	case "/api/backup-reminder":
 		backupReminderRequest := &api.BackupReminderRequest{}
 		err := json.Unmarshal([]byte(body), backupReminderRequest)
@@ -815,7 +1201,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		err = app.api.ChangeUnlockPassword(changeUnlockPasswordRequest)
+		err = app.api.ChangeUnlockPassword(ctx, changeUnlockPasswordRequest)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -835,7 +1221,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		err = app.api.UpdateSettings(updateSettingsRequest)
+		err = app.api.UpdateSettings(ctx, updateSettingsRequest)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -855,7 +1241,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		err = app.api.SetAutoUnlockPassword(autoUnlockRequest.UnlockPassword)
+		err = app.api.SetAutoUnlockPassword(ctx, autoUnlockRequest.UnlockPassword)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -875,6 +1261,15 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
+		err = app.api.VerifyUnlockTOTP(startRequest.UnlockPassword, startRequest.TotpCode)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to verify authentication code")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
 		go app.api.Start(startRequest)
 
 		return WailsRequestRouterResponse{Body: nil, Error: ""}
@@ -931,7 +1326,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 
 		defer backupFile.Close()
 
-		err = app.api.CreateBackup(backupRequest.UnlockPassword, backupFile)
+		err = app.api.CreateBackup(backupRequest.UnlockPassword, backupRequest.TotpCode, backupFile)
 
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
@@ -975,7 +1370,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 
 		defer backupFile.Close()
 
-		err = app.api.RestoreBackup(restoreRequest.UnlockPassword, backupFile)
+		err = app.api.RestoreBackup(ctx, restoreRequest.UnlockPassword, backupFile)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -984,6 +1379,49 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 		return WailsRequestRouterResponse{Body: nil, Error: ""}
+	case "/api/backup/verify":
+		verifyRequest := &api.BasicRestoreWailsRequest{}
+		err := json.Unmarshal([]byte(body), verifyRequest)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to decode request to wails router")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		backupFilePath, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+			Title:           "Select Backup File",
+			DefaultFilename: "albyhub.bkp",
+		})
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to open file dialog")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		backupFile, err := os.Open(backupFilePath)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to open backup file")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+
+		defer backupFile.Close()
+
+		verificationResult, err := app.api.VerifyBackup(verifyRequest.UnlockPassword, backupFile)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to verify backup")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: verificationResult, Error: ""}
 	case "/api/health":
 		nodeHealth, err := app.api.Health(ctx)
 		if err != nil {
@@ -1074,7 +1512,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			}
 			return WailsRequestRouterResponse{Body: nil, Error: ""}
 		case "DELETE":
-			err := app.api.DisableAutoSwap()
+			err := app.api.DisableAutoSwap(ctx)
 			if err != nil {
 				logger.Logger.WithFields(logrus.Fields{
 					"route":  route,
@@ -1084,6 +1522,48 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			}
 			return WailsRequestRouterResponse{Body: nil, Error: ""}
 		}
+	case "/api/autoswap/in":
+		switch method {
+		case "GET":
+			autoSwapInConfig, err := app.api.GetAutoSwapInConfig()
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to get auto swap in configuration")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: autoSwapInConfig, Error: ""}
+		case "POST":
+			enableAutoSwapInRequest := &api.EnableAutoSwapInRequest{}
+			err := json.Unmarshal([]byte(body), enableAutoSwapInRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to decode request to wails router")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			err = app.api.EnableAutoSwapIn(ctx, enableAutoSwapInRequest)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to enable auto swap in")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		case "DELETE":
+			err := app.api.DisableAutoSwapIn(ctx)
+			if err != nil {
+				logger.Logger.WithFields(logrus.Fields{
+					"route":  route,
+					"method": method,
+				}).WithError(err).Error("Failed to disable auto swap in")
+				return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+			}
+			return WailsRequestRouterResponse{Body: nil, Error: ""}
+		}
 	case "/api/swaps/out/info":
 		swapOutInfo, err := app.api.GetSwapOutInfo()
 		if err != nil {
@@ -1152,7 +1632,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			}).WithError(err).Error("Failed to decode request to wails router")
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
-		err = app.api.RefundSwap(refundSwapRequest)
+		err = app.api.RefundSwap(ctx, refundSwapRequest)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -1175,7 +1655,7 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
 		}
 
-		err = app.api.SetNodeAlias(setNodeAliasRequest.NodeAlias)
+		err = app.api.SetNodeAlias(ctx, setNodeAliasRequest.NodeAlias)
 		if err != nil {
 			logger.Logger.WithFields(logrus.Fields{
 				"route":  route,
@@ -1251,6 +1731,37 @@ func (app *WailsApp) WailsRequestRouter(route string, method string, body string
 		}
 	}
 
+	swapQuoteRegex := regexp.MustCompile(
+		`/api/swaps/(in|out)/quote`,
+	)
+	swapQuoteMatch := swapQuoteRegex.FindStringSubmatch(route)
+
+	switch {
+	case len(swapQuoteMatch) > 1:
+		parsedUrl, err := url.Parse(route)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: "invalid route"}
+		}
+		amountSat, err := strconv.ParseUint(parsedUrl.Query().Get("amountSat"), 10, 64)
+		if err != nil {
+			return WailsRequestRouterResponse{Body: nil, Error: fmt.Sprintf("invalid amountSat: %s", err.Error())}
+		}
+
+		getSwapQuote := app.api.GetSwapOutQuote
+		if swapQuoteMatch[1] == "in" {
+			getSwapQuote = app.api.GetSwapInQuote
+		}
+		swapQuote, err := getSwapQuote(amountSat)
+		if err != nil {
+			logger.Logger.WithFields(logrus.Fields{
+				"route":  route,
+				"method": method,
+			}).WithError(err).Error("Failed to get swap quote")
+			return WailsRequestRouterResponse{Body: nil, Error: err.Error()}
+		}
+		return WailsRequestRouterResponse{Body: swapQuote, Error: ""}
+	}
+
 	// Swap lookup and listing is shifted to the bottom so it
 	// doesn't interfere with other swap endpoints
 	swapRegex := regexp.MustCompile(